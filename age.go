@@ -0,0 +1,182 @@
+// age.go filters models by how long it's been since they were last used, e.g. "modified:>90d" or -older-than 90d.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sammcj/gollama/history"
+	"github.com/sammcj/gollama/logging"
+)
+
+// Where a model's age came from, a recorded run is a better measure of use than the modified time
+const (
+	ageSourceLastRun  = "last run"
+	ageSourceModified = "modified"
+)
+
+// ageFilterPrefix is the structured filter term used in the TUI, e.g. "modified:>90d"
+const ageFilterPrefix = "modified:"
+
+// relativeAge is a duration such as 90d, 2w or 3mo, months are calendar months
+type relativeAge struct {
+	n    int
+	unit string // "d", "w", "mo" or "y"
+}
+
+// parseAge parses a relative age like 90d, 2w, 3mo or 1y
+func parseAge(s string) (relativeAge, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return relativeAge{}, fmt.Errorf("invalid age %q, expected a number followed by d, w, mo or y (e.g. 90d)", s)
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return relativeAge{}, fmt.Errorf("invalid age %q: %w", s, err)
+	}
+	unit := s[i:]
+	switch unit {
+	case "d", "w", "mo", "y":
+	case "m":
+		return relativeAge{}, fmt.Errorf("invalid age %q, use mo for months", s)
+	default:
+		return relativeAge{}, fmt.Errorf("invalid age %q, unknown unit %q (use d, w, mo or y)", s, unit)
+	}
+	return relativeAge{n: n, unit: unit}, nil
+}
+
+// cutoff returns the time the age reaches back to from now. Months and years that land past the end of a shorter month
+// are clamped to its last day, so 1mo before 31 March is the end of February rather than early March
+func (a relativeAge) cutoff(now time.Time) time.Time {
+	switch a.unit {
+	case "d":
+		return now.AddDate(0, 0, -a.n)
+	case "w":
+		return now.AddDate(0, 0, -7*a.n)
+	}
+
+	months := a.n
+	if a.unit == "y" {
+		months *= 12
+	}
+	year, month, day := now.Date()
+	first := time.Date(year, month-time.Month(months), 1, now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), now.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// ageFilter matches models older (or newer) than an age
+type ageFilter struct {
+	newer bool // true for "<" i.e. used within the age
+	age   relativeAge
+}
+
+// parseAgeFilter parses ">90d" (older than, the default when there's no comparison) or "<7d" (newer than)
+func parseAgeFilter(s string) (ageFilter, error) {
+	var f ageFilter
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "<"):
+		f.newer = true
+		s = s[1:]
+	case strings.HasPrefix(s, ">"):
+		s = s[1:]
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return f, err
+	}
+	f.age = age
+	return f, nil
+}
+
+// matches reports whether something last used at t passes the filter
+func (f ageFilter) matches(t, now time.Time) bool {
+	cutoff := f.age.cutoff(now)
+	if f.newer {
+		return !t.Before(cutoff)
+	}
+	return t.Before(cutoff)
+}
+
+// recordRun records that gollama launched a model so its age can be based on use rather than the modified time
+func recordRun(modelName string) {
+	recordHistory("run", modelName, "")
+}
+
+// lastRuns returns when each model was last launched by gollama
+func lastRuns() map[string]time.Time {
+	runs := make(map[string]time.Time)
+	events, err := history.Load()
+	if err != nil {
+		logging.ErrorLogger.Printf("Error reading history for last run times: %v\n", err)
+		return runs
+	}
+	for _, event := range events {
+		if event.Action == "run" && event.Time.After(runs[event.Model]) {
+			runs[event.Model] = event.Time
+		}
+	}
+	return runs
+}
+
+// modelAge returns when a model was last used and where that came from, preferring a recorded run
+func modelAge(model Model, runs map[string]time.Time) (time.Time, string) {
+	if t, ok := runs[model.Name]; ok && t.After(model.Modified) {
+		return t, ageSourceLastRun
+	}
+	return model.Modified, ageSourceModified
+}
+
+// filterModelsByAge returns the models matching the filter
+func filterModelsByAge(models []Model, f ageFilter, runs map[string]time.Time, now time.Time) []Model {
+	var filtered []Model
+	for _, model := range models {
+		if t, _ := modelAge(model, runs); f.matches(t, now) {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
+// listModelsByAge prints the models matching the -older-than flag with the age of each and where it came from,
+// or just the models in a machine readable format when format is set
+func listModelsByAge(models []Model, olderThan, format string) error {
+	age, err := parseAge(strings.TrimPrefix(olderThan, ">"))
+	if err != nil {
+		return err
+	}
+	f := ageFilter{age: age}
+
+	now := time.Now()
+	runs := lastRuns()
+	filtered := filterModelsByAge(models, f, runs, now)
+	if format != "" {
+		return writeModels(os.Stdout, filtered, format)
+	}
+	if len(filtered) == 0 {
+		fmt.Printf("No models older than %s\n", strings.TrimPrefix(olderThan, ">"))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tSize\tLast used\tAge\tSource")
+	var total int64
+	for _, model := range filtered {
+		t, source := modelAge(model, runs)
+		total += model.Size
+		fmt.Fprintf(w, "%s\t%.2fGB\t%s\t%dd\t%s\n", model.Name, model.SizeGB(), t.Format("2006-01-02"), int(now.Sub(t).Hours()/24), source)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d models, %.2fGB\n", len(filtered), float64(total)/(1024*1024*1024))
+	return nil
+}
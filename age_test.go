@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    relativeAge
+		wantErr bool
+	}{
+		{"90d", relativeAge{90, "d"}, false},
+		{"2W", relativeAge{2, "w"}, false},
+		{"3mo", relativeAge{3, "mo"}, false},
+		{"1y", relativeAge{1, "y"}, false},
+		{"3m", relativeAge{}, true},
+		{"d", relativeAge{}, true},
+		{"90", relativeAge{}, true},
+		{"90h", relativeAge{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseAge(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseAge(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeAgeCutoff(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		age  string
+		now  time.Time
+		want time.Time
+	}{
+		{"days", "90d", date(2024, 6, 1), date(2024, 3, 3)},
+		{"weeks", "2w", date(2024, 3, 5), date(2024, 2, 20)},
+		{"month from the 15th", "1mo", date(2024, 3, 15), date(2024, 2, 15)},
+		{"month from the 31st into a leap February", "1mo", date(2024, 3, 31), date(2024, 2, 29)},
+		{"month from the 31st into February", "1mo", date(2023, 3, 31), date(2023, 2, 28)},
+		{"month from the 31st into a 30 day month", "1mo", date(2024, 5, 31), date(2024, 4, 30)},
+		{"months across a year boundary", "3mo", date(2024, 1, 31), date(2023, 10, 31)},
+		{"year from a leap day", "1y", date(2024, 2, 29), date(2023, 2, 28)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			age, err := parseAge(tt.age)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := age.cutoff(tt.now); !got.Equal(tt.want) {
+				t.Errorf("cutoff(%s) from %s = %s, want %s", tt.age, tt.now.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestFilterModelsByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	model := func(name string, modified time.Time) Model {
+		m := Model{}
+		m.Name, m.Modified = name, modified
+		return m
+	}
+	models := []Model{
+		model("old", now.AddDate(0, -6, 0)),
+		model("old-but-run", now.AddDate(0, -6, 0)),
+		model("new", now.AddDate(0, 0, -3)),
+	}
+	runs := map[string]time.Time{"old-but-run": now.AddDate(0, 0, -1)}
+
+	older, _ := parseAgeFilter(">90d")
+	got := filterModelsByAge(models, older, runs, now)
+	if len(got) != 1 || got[0].Name != "old" {
+		t.Errorf("older than 90d = %v, want [old]", got)
+	}
+
+	newer, _ := parseAgeFilter("<7d")
+	got = filterModelsByAge(models, newer, runs, now)
+	if len(got) != 2 || got[0].Name != "old-but-run" || got[1].Name != "new" {
+		t.Errorf("newer than 7d = %v, want [old-but-run new]", got)
+	}
+
+	if _, source := modelAge(models[1], runs); source != ageSourceLastRun {
+		t.Errorf("modelAge source = %s, want %s", source, ageSourceLastRun)
+	}
+	if _, source := modelAge(models[0], runs); source != ageSourceModified {
+		t.Errorf("modelAge source = %s, want %s", source, ageSourceModified)
+	}
+}
@@ -4,12 +4,14 @@ package main
 import (
 	"context"
 	"fmt"
-	"sort"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,19 +19,31 @@ import (
 	"github.com/ollama/ollama/api"
 
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pins"
+	"github.com/sammcj/gollama/pkg/gollama"
+	"github.com/sammcj/gollama/showcache"
+	"github.com/sammcj/gollama/styles"
 )
 
 const (
 	MainView View = iota
 	TopView
 	HelpView
+	DashboardView
+	ThemeView
+	HostsView
+	DupesView
 )
 
 func (m *AppModel) Init() tea.Cmd {
+	load := tea.Batch(m.startModelsLoad(), m.checkServerVersion())
 	if m.showTop {
-		return m.startTopTicker()
+		return tea.Batch(load, m.startTopTicker(), m.scheduleModelsDirCheck(modelsDirCheckInterval), m.nextContextLengthFetch())
 	}
-	return nil
+	if m.view == DashboardView {
+		return tea.Batch(load, m.fetchDashboardData(), m.scheduleModelsDirCheck(modelsDirCheckInterval), m.nextContextLengthFetch())
+	}
+	return tea.Batch(load, m.scheduleModelsDirCheck(modelsDirCheckInterval), m.nextContextLengthFetch())
 }
 
 func (m *AppModel) FilterValue() string {
@@ -46,14 +60,16 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			if m.newModelPull {
+				if cmd, ok := m.handleTagCompletionKey(msg); ok {
+					return m, cmd
+				}
 				switch msg.Type {
 				case tea.KeyEnter:
+					name := m.pullInput.Value()
+					m.pulling = false
 					m.newModelPull = false
-					m.pullProgress = 0.01 // Start progress immediately
-					return m, tea.Batch(
-						m.startPullModel(m.pullInput.Value()),
-						m.updateProgressCmd(),
-					)
+					m.pullInput.Reset()
+					return m, m.pullModel(name)
 				case tea.KeyCtrlC, tea.KeyEsc:
 					m.pulling = false
 					m.newModelPull = false
@@ -64,11 +80,28 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.pullInput, cmd = m.pullInput.Update(msg)
 				return m, cmd
 			} else {
-				if msg.Type == tea.KeyCtrlC {
-					m.pulling = false
-					m.pullProgress = 0
+				if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc && !m.comparingModelfile {
+					m.cancelRunningPull()
 					return m, nil
 				}
+				// The layer panel is toggled with d and scrolled while it's open
+				if m.pullLayers != nil {
+					switch msg.String() {
+					case "d":
+						m.pullLayers.expanded = !m.pullLayers.expanded
+						return m, nil
+					case "up", "k":
+						if m.pullLayers.expanded {
+							m.pullLayers.scroll(-1, m.pullLayerRows())
+							return m, nil
+						}
+					case "down", "j":
+						if m.pullLayers.expanded {
+							m.pullLayers.scroll(1, m.pullLayerRows())
+							return m, nil
+						}
+					}
+				}
 			}
 			if m.comparingModelfile {
 				switch msg.String() {
@@ -85,24 +118,13 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handlePullSuccessMsg(msg)
 		case pullErrorMsg:
 			return m.handlePullErrorMsg(msg)
-		case progressMsg:
-			if m.pullProgress < 1.0 {
-				return m, tea.Batch(
-					m.updateProgressCmd(),
-					func() tea.Msg {
-						return progressMsg{
-							modelName: msg.modelName,
-							progress:  m.pullProgress,
-						}
-					},
-				)
-			}
-			return m, nil
 		}
 	}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
+	case runExternalMsg:
+		return m.handleRunExternalMsg(msg)
 	case runFinishedMessage:
 		return m.handleRunFinishedMessage(msg)
 	case progressMsg:
@@ -113,12 +135,65 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handlePushSuccessMsg(msg)
 	case pushErrorMsg:
 		return m.handlePushErrorMsg(msg)
+	case transferMsg:
+		return m.handleTransferMsg(msg)
+	case inspectRuntimeMsg:
+		return m.handleInspectRuntimeMsg(msg)
+	case inspectFilesMsg:
+		return m.handleInspectFilesMsg(msg)
+	case inspectLicenceMsg:
+		return m.handleInspectLicenceMsg(msg)
+	case pullConfirmMsg:
+		return m.handlePullConfirmMsg(msg)
+	case copyCheckMsg:
+		return m.handleCopyCheckMsg(msg)
+	case copyResultMsg:
+		return m.handleCopyResultMsg(msg)
+	case keepConfigPullMsg:
+		return m.handleKeepConfigPullMsg(msg)
+	case updateCheckMsg:
+		return m.handleUpdateCheckMsg(msg)
+	case fitCheckMsg:
+		return m.handleFitCheckMsg(msg)
+	case pullProgressTickMsg:
+		return m.handlePullProgressTick(msg)
+	case restoreMsg:
+		return m.handleRestoreMsg(msg)
+	case modelfileCompareMsg:
+		return m.handleModelfileCompareMsg(msg)
+	case registryTagsMsg:
+		return m.handleRegistryTagsMsg(msg)
 	case genericMsg:
 		return m.handleGenericMsg(msg)
+	case runningModelsMsg:
+		return m.handleRunningModelsMsg(msg)
+	case keepAliveMsg:
+		return m.handleKeepAliveMsg(msg)
+	case configChangedMsg:
+		return m.handleConfigChangedMsg(msg)
+	case dashboardMsg:
+		return m.handleDashboardMsg(msg)
+	case dashboardTickMsg:
+		return m.handleDashboardTickMsg()
+	case modelsDirMsg:
+		return m.handleModelsDirMsg(msg)
+	case hostCompareMsg:
+		return m.handleHostCompareMsg(msg)
+	case benchmarkMsg:
+		return m.handleBenchmarkMsg(msg)
+	case contextLengthMsg:
+		return m.handleContextLengthMsg(msg)
+	case modelsLoadedMsg:
+		return m.handleModelsLoadedMsg(msg)
+	case spinner.TickMsg:
+		return m.handleModelsSpinnerTick(msg)
+	case serverVersionMsg:
+		return m.handleServerVersionMsg(msg)
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		m.list.SetSize(m.width, m.height)
+		m.updateColumnLayout()
 		return m, nil
 	default:
 		m.list, cmd = m.list.Update(msg)
@@ -127,10 +202,16 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *AppModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Printf("Received key: %s\n", msg.String())
+	logging.TraceLogger.Printf("Received key: %s\n", msg.String())
 
 	// Log the current filter state
-	logging.DebugLogger.Printf("Current filter state: %v\n", m.list.FilterState())
+	logging.TraceLogger.Printf("Current filter state: %v\n", m.list.FilterState())
+
+	if m.pushBatch != nil {
+		if cmd, ok := m.handlePushBatchKey(msg); ok {
+			return m, cmd
+		}
+	}
 
 	// Handle the space key separately to ensure it works even when filtering
 	if key.Matches(msg, m.keys.Space) {
@@ -157,41 +238,105 @@ func (m *AppModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if m.view == DashboardView {
+		if model, cmd, handled := m.handleDashboardNavigation(msg); handled {
+			return model, cmd
+		}
+	}
+
+	if m.view == ThemeView {
+		return m.handleThemePickerKey(msg)
+	}
+
+	if m.view == HostsView && msg.String() != "ctrl+c" {
+		return m.handleHostsViewKey(msg)
+	}
+
+	if m.view == DupesView && msg.String() != "ctrl+c" {
+		return m.handleDupesViewKey(msg)
+	}
+
+	if m.comparingModelfile && msg.String() != "ctrl+c" {
+		return m.handleModelfileCompareKey(msg)
+	}
+
+	if m.inspecting && m.view == MainView && msg.String() != "ctrl+c" {
+		if model, cmd, handled := m.handleInspectKey(msg); handled {
+			return model, cmd
+		}
+	}
+
+	if m.view == TopView && msg.String() != "ctrl+c" {
+		if model, cmd, handled := m.handleTopViewKey(msg); handled {
+			return model, cmd
+		}
+	}
+
+	if m.copyConflict != nil {
+		return m.handleCopyConflictKey(msg)
+	}
+
+	if m.settingsCopy != nil {
+		return m.handleSettingsCopyKey(msg)
+	}
+
+	if m.pullConfirm != nil {
+		return m.handlePullConfirmKey(msg)
+	}
+
+	if m.runOverride != nil {
+		return m.handleRunOverrideKey(msg)
+	}
+
+	if m.modelsStatusShown() && m.modelsErr != nil && msg.String() == "r" {
+		return m, m.startModelsLoad()
+	}
+
 	// Handle other keys
 	switch msg.String() {
 	case "ctrl+c":
 		if m.pulling {
-			m.pulling = false
-			m.pullProgress = 0
-			m.pullInput.Reset()
+			m.cancelRunningPull()
+			return m, nil
+		}
+		if m.showProgress && m.pushBatch == nil {
+			m.cancelRunningPush()
 			return m, nil
 		}
 		if m.editing {
 			m.editing = false
 			return m, nil
 		}
-		return m, tea.Quit
+		return m, m.quit()
 	case "q":
 		if m.list.FilterState() == list.FilterApplied {
 			logging.DebugLogger.Println("Clearing filter with 'q' key")
 			m.list.ResetFilter()
 			return m, nil
 		}
-		if m.view == TopView || m.inspecting || m.view == HelpView {
+		if m.view == TopView || m.inspecting || m.view == HelpView || m.view == DashboardView {
 			m.view = MainView
 			m.inspecting = false
 			m.editing = false
 			return m, nil
 		} else {
-			return m, tea.Quit
+			return m, m.quit()
 		}
 	case "esc":
+		if m.pulling {
+			m.cancelRunningPull()
+			return m, nil
+		}
+		if m.showProgress && m.pushBatch == nil {
+			m.cancelRunningPush()
+			return m, nil
+		}
 		if m.list.FilterState() == list.FilterApplied {
 			logging.DebugLogger.Println("Clearing filter with 'esc' key")
 			m.list.ResetFilter()
 			return m, nil
 		}
-		if m.view == TopView || m.inspecting || m.view == HelpView {
+		if m.view == TopView || m.inspecting || m.view == HelpView || m.view == DashboardView {
 			m.view = MainView
 			m.inspecting = false
 			m.editing = false
@@ -205,26 +350,52 @@ func (m *AppModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		switch {
 		case key.Matches(msg, m.keys.ConfirmYes):
 			logging.DebugLogger.Println("ConfirmYes key matched")
+			// Pinned models need a second confirmation before they are deleted
+			if !m.confirmPinnedDeletion && len(pinnedModels(m.selectedModels)) > 0 {
+				m.confirmPinnedDeletion = true
+				return m, nil
+			}
+			// Carry on past failures and only remove the models that were deleted from the list
+			var deleted []Model
+			failures := make(map[string]error)
+			unloadFailures := make(map[string]error)
 			for _, selectedModel := range m.selectedModels {
+				// Unload running models first so their vRAM isn't held until they expire, deleting anyway if that fails
+				if m.deleteRunning[selectedModel.Name] && m.cfg.UnloadBeforeDelete {
+					if _, err := unloadModel(m.client, m.cfg.OllamaAPIURL, selectedModel.Name); err != nil {
+						unloadFailures[selectedModel.Name] = err
+					}
+				}
 				logging.InfoLogger.Printf("Attempting to delete model: %s\n", selectedModel.Name)
 				err := deleteModel(m.client, selectedModel.Name)
 				if err != nil {
 					logging.ErrorLogger.Println("Error deleting model:", err)
+					failures[selectedModel.Name] = err
+					continue
 				}
+				deleted = append(deleted, selectedModel)
 			}
-			m.models = removeModels(m.models, m.selectedModels)
+			m.message = deleteResultSummary(len(deleted), failures) + unloadFailureSummary(unloadFailures)
+			m.models = removeModels(m.models, deleted)
+			markDuplicateModels(m.models)
 			m.refreshList()
 			m.confirmDeletion = false
+			m.confirmPinnedDeletion = false
 			m.selectedModels = nil
 		case key.Matches(msg, m.keys.ConfirmNo):
 			logging.DebugLogger.Println("ConfirmNo key matched")
 			logging.InfoLogger.Println("Deletion cancelled by user")
 			m.confirmDeletion = false
+			m.confirmPinnedDeletion = false
 			m.selectedModels = nil
 		}
 		return m, nil
 	}
 
+	if model, cmd, handled := m.handleGroupKey(msg); handled {
+		return model, cmd
+	}
+
 	var cmd tea.Cmd // Define the cmd variable
 	switch {
 	case key.Matches(msg, m.keys.Delete):
@@ -255,12 +426,28 @@ func (m *AppModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleLinkAllModelsKey()
 	case key.Matches(msg, m.keys.CopyModel):
 		return m.handleCopyModelKey()
+	case key.Matches(msg, m.keys.CopySettings):
+		return m.handleCopySettingsKey()
 	case key.Matches(msg, m.keys.PushModel):
 		return m.handlePushModelKey()
+	case key.Matches(msg, m.keys.TransferModel):
+		return m.handleTransferModelKey()
 	case key.Matches(msg, m.keys.PullModel):
 		return m.handlePullModelKey()
 	case key.Matches(msg, m.keys.RenameModel):
 		return m.handleRenameModelKey()
+	case key.Matches(msg, m.keys.FitCheck):
+		return m.handleFitCheckKey()
+	case key.Matches(msg, m.keys.GroupBy):
+		return m.handleGroupByKey()
+	case key.Matches(msg, m.keys.RunWithOptions):
+		return m.handleRunWithOptionsKey()
+	case key.Matches(msg, m.keys.PullKeepConfig):
+		return m.handlePullKeepConfigKey()
+	case key.Matches(msg, m.keys.UpdateAll):
+		return m.handleUpdateAllKey()
+	case key.Matches(msg, m.keys.RetryRestore):
+		return m.handleRetryRestoreKey()
 	case key.Matches(msg, m.keys.PullNewModel):
 		return m.handlePullNewModelKey()
 	case key.Matches(msg, m.keys.InspectModel):
@@ -269,6 +456,24 @@ func (m *AppModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleTopKey()
 	case key.Matches(msg, m.keys.Help):
 		return m.handleHelpKey()
+	case key.Matches(msg, m.keys.Dashboard):
+		return m.handleDashboardKey()
+	case key.Matches(msg, m.keys.PinModel):
+		return m.handlePinModelKey()
+	case key.Matches(msg, m.keys.Theme):
+		return m.handleThemeKey()
+	case key.Matches(msg, m.keys.CompareHosts):
+		return m.handleCompareHostsKey()
+	case key.Matches(msg, m.keys.Benchmark):
+		return m.handleBenchmarkKey()
+	case key.Matches(msg, m.keys.Dupes):
+		return m.handleDupesKey()
+	case key.Matches(msg, m.keys.SelectAll):
+		return m.handleSelectAllKey()
+	case key.Matches(msg, m.keys.InvertSelection):
+		return m.handleInvertSelectionKey()
+	case key.Matches(msg, m.keys.DeselectAll):
+		return m.handleDeselectAllKey()
   case key.Matches(msg, m.keys.CompareModelfile):
     return m.handleCompareModelfile()
 	default:
@@ -340,10 +545,30 @@ func (m *AppModel) handleRunFinishedMessage(msg runFinishedMessage) (tea.Model,
 
 // TODO: Refactor: Look into making generic handler functions
 
+// handleProgressMsg redraws the push progress every progress_refresh_ms until the push finishes, ticks for an earlier
+// push are dropped
 func (m *AppModel) handleProgressMsg(msg progressMsg) (tea.Model, tea.Cmd) {
-	return m, tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
-		return progressMsg{modelName: msg.modelName}
-	})
+	if !m.showProgress || msg.feed == nil || msg.feed != m.pushFeed {
+		return m, nil
+	}
+	if progress, ok := msg.feed.latest(); ok {
+		m.pushProgress = progress
+	}
+	return m, m.pushProgressTick()
+}
+
+// finishPush hides the push progress bar and returns the last status Ollama reported
+func (m *AppModel) finishPush() string {
+	if m.cancelPush != nil {
+		m.cancelPush()
+		m.cancelPush = nil
+	}
+	status := m.pushLayers.lastStatus()
+	m.showProgress = false
+	m.pushLayers = nil
+	m.pushFeed = nil
+	m.pushProgress = 0
+	return status
 }
 
 func (m *AppModel) handleHelpKey() (tea.Model, tea.Cmd) {
@@ -368,7 +593,11 @@ func (m *AppModel) handleEditorFinishedMsg(msg editorFinishedMsg) (tea.Model, te
 		return m, nil
 	}
 	if item, ok := m.list.SelectedItem().(Model); ok {
-		newModelName := promptForNewName(item.Name)
+		newModelName, ok := promptForNewName(item.Name, m.modelNameFeedback)
+		if !ok {
+			m.message = "Cancelled"
+			return m, nil
+		}
 		modelfilePath := fmt.Sprintf("Modelfile-%s", strings.ReplaceAll(newModelName, " ", "_"))
 		err := createModelFromModelfile(newModelName, modelfilePath, m.client)
 		if err != nil {
@@ -383,49 +612,60 @@ func (m *AppModel) handleEditorFinishedMsg(msg editorFinishedMsg) (tea.Model, te
 }
 
 func (m *AppModel) handlePushSuccessMsg(msg pushSuccessMsg) (tea.Model, tea.Cmd) {
+	if msg.index > 0 && m.pushBatch != nil {
+		recordHistory("push", msg.modelName, "")
+		return m.handlePushBatchResult(nil)
+	}
 	m.message = fmt.Sprintf("Successfully pushed model: %s\n", msg.modelName)
-	m.showProgress = false // Hide progress bar
+	recordHistory("push", msg.modelName, "")
+	m.finishPush()
 	return m, nil
 }
 
 func (m *AppModel) handlePushErrorMsg(msg pushErrorMsg) (tea.Model, tea.Cmd) {
+	if msg.index > 0 && m.pushBatch != nil {
+		return m.handlePushBatchResult(msg.err)
+	}
 	logging.ErrorLogger.Printf("Error pushing model: %v\n", msg.err)
-	m.message = fmt.Sprintf("Error pushing model: %v\n", msg.err)
-	m.showProgress = false // Hide progress bar
+	m.message = pushErrorMessage(msg.err, m.finishPush())
 	return m, nil
 }
 
 func (m *AppModel) handlePullSuccessMsg(msg pullSuccessMsg) (tea.Model, tea.Cmd) {
+	m.endPull()
 	m.pulling = false
 	m.newModelPull = false
 	m.pullProgress = 0
+	m.pullLayers = nil // collapse the layer panel now the pull is done
 	m.message = fmt.Sprintf("Successfully pulled model: %s", msg.modelName)
+	m.clearStale(msg.modelName)
+	next := m.continueUpdateAll(nil)
+	client := m.client
+	showcache.Invalidate(client, msg.modelName)
 	return m, tea.Batch(
-		m.refreshModelsAfterPull(),
+		func() tea.Msg {
+			recordPull(client, msg.modelName)
+			return nil
+		},
+		m.fetchModels(),
 		func() tea.Msg {
 			// This will force a refresh of the main view
 			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
 		},
+		next,
 	)
 }
 
 func (m *AppModel) handlePullErrorMsg(msg pullErrorMsg) (tea.Model, tea.Cmd) {
+	m.endPull()
 	m.pulling = false
 	m.pullProgress = 0
+	m.pullLayers = nil
 	m.message = fmt.Sprintf("Error pulling model: %v", msg.err)
-	return m, func() tea.Msg {
+	return m, tea.Batch(func() tea.Msg {
 		// This will force a refresh of the main view
 		return tea.WindowSizeMsg{Width: m.width, Height: m.height}
-	}
-}
-
-func (m *AppModel) updateProgressCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
-		return progressMsg{
-			modelName: m.pullInput.Value(),
-			progress:  m.pullProgress,
-		}
-	})
+	}, m.continueUpdateAll(msg.err))
 }
 
 func (m *AppModel) handleGenericMsg(msg genericMsg) (tea.Model, tea.Cmd) {
@@ -439,15 +679,23 @@ func (m *AppModel) handleDeleteKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("Delete key matched")
 	logging.InfoLogger.Println("Delete key pressed")
 
-	// Collect all selected models for deletion
-	var selectedModels []Model
-	for _, item := range m.list.Items() {
-		if model, ok := item.(Model); ok && model.Selected {
-			selectedModels = append(selectedModels, model)
-		}
-	}
+	// Collect all selected models for deletion, including those in collapsed groups
+	selectedModels := m.selectedListModels()
 
 	if len(selectedModels) > 0 {
+		// Pinned models are never part of a multi-delete, they have to be deleted one at a time
+		var skipped []string
+		for _, model := range pinnedModels(selectedModels) {
+			skipped = append(skipped, model.Name)
+		}
+		if len(skipped) > 0 {
+			selectedModels = removeModels(selectedModels, pinnedModels(selectedModels))
+			m.message = fmt.Sprintf("Skipped pinned models: %s", strings.Join(skipped, ", "))
+			logging.InfoLogger.Printf("Excluded pinned models from deletion: %v\n", skipped)
+		}
+		if len(selectedModels) == 0 {
+			return m, nil
+		}
 		m.selectedModels = selectedModels
 		logging.InfoLogger.Printf("Selected models for deletion: %+v\n", m.selectedModels)
 		m.confirmDeletion = true
@@ -456,56 +704,46 @@ func (m *AppModel) handleDeleteKey() (tea.Model, tea.Cmd) {
 		logging.InfoLogger.Printf("Selected model for deletion: %+v\n", m.selectedModels)
 		m.confirmDeletion = true
 	}
+	if m.confirmDeletion {
+		m.deleteRunning = runningModelNames(context.Background(), m.client, m.selectedModels)
+	}
 	return m, nil
 }
 
-func (m *AppModel) handleSortByNameKey() (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Println("SortByName key matched")
-	m.cfg.SortOrder = "name"
-	sort.Slice(m.models, func(i, j int) bool {
-		return m.models[i].Name < m.models[j].Name
-	})
-	m.refreshList()
-	return m, nil
-}
-
-func (m *AppModel) handleSortBySizeKey() (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Println("SortBySize key matched")
-	m.cfg.SortOrder = "size"
-	sort.Slice(m.models, func(i, j int) bool {
-		return m.models[i].Size > m.models[j].Size
-	})
-	m.refreshList()
-	return m, nil
-}
+func (m *AppModel) handlePinModelKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("PinModel key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
 
-func (m *AppModel) handleSortByModifiedKey() (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Println("SortByModified key matched")
-	m.cfg.SortOrder = "modified"
-	sort.Slice(m.models, func(i, j int) bool {
-		return m.models[i].Modified.After(m.models[j].Modified)
-	})
-	m.refreshList()
-	return m, nil
-}
+	pinned, err := pins.Toggle(item.Digest, item.Name)
+	if err != nil {
+		logging.ErrorLogger.Printf("Error toggling pin for model %s: %v\n", item.Name, err)
+		m.message = fmt.Sprintf("Error pinning model %s: %v", item.Name, err)
+		return m, nil
+	}
 
-func (m *AppModel) handleSortByQuantKey() (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Println("SortByQuant key matched")
-	m.cfg.SortOrder = "quant"
-	sort.Slice(m.models, func(i, j int) bool {
-		return m.models[i].QuantizationLevel < m.models[j].QuantizationLevel
-	})
-	m.refreshList()
-	return m, nil
-}
+	// Pins are keyed by digest so every name sharing the digest changes with it
+	items := m.list.Items()
+	for i, listItem := range items {
+		if model, ok := listItem.(Model); ok && model.Digest == item.Digest {
+			model.Pinned = pinned
+			m.list.SetItem(i, model)
+		}
+	}
+	for i, model := range m.models {
+		if model.Digest == item.Digest {
+			m.models[i].Pinned = pinned
+		}
+	}
 
-func (m *AppModel) handleSortByFamilyKey() (tea.Model, tea.Cmd) {
-	logging.DebugLogger.Println("SortByFamily key matched")
-	m.cfg.SortOrder = "family"
-	sort.Slice(m.models, func(i, j int) bool {
-		return m.models[i].Family < m.models[j].Family
-	})
-	m.refreshList()
+	if pinned {
+		m.message = fmt.Sprintf("Pinned %s", item.Name)
+	} else {
+		m.message = fmt.Sprintf("Unpinned %s", item.Name)
+	}
+	logging.InfoLogger.Println(m.message)
 	return m, nil
 }
 
@@ -513,7 +751,8 @@ func (m *AppModel) handleRunModelKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("RunModel key matched")
 	if item, ok := m.list.SelectedItem().(Model); ok {
 		logging.InfoLogger.Printf("Running model: %s\n", item.Name)
-		return m, runModel(item.Name, m.cfg)
+		recordRun(item.Name)
+		return m, m.startRun(item.Name, nil)
 	}
 	return m, nil
 }
@@ -541,7 +780,10 @@ func (m *AppModel) handleClearScreenKey() (tea.Model, tea.Cmd) {
 func (m *AppModel) handleTopKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("Top key matched")
 	m.view = TopView
-	return m.ToggleTop()
+	m.topGeneration++ // a refresh already scheduled by an earlier visit stops rescheduling itself
+	m.topMessage = ""
+	model, cmd := m.ToggleTop()
+	return model, tea.Batch(cmd, m.fetchRunningModels(0))
 }
 
 func (m *AppModel) handleUpdateModelKey() (tea.Model, tea.Cmd) {
@@ -573,13 +815,14 @@ func (m *AppModel) handleUnloadModelsKey() (tea.Model, tea.Cmd) {
 
 		// unload the models
 		var unloadedModels []string
-		for _, model := range loadedModels.Models {
-			_, err := unloadModel(m.client, model.Name)
+		for _, model := range sanitiseRunningModels(loadedModels) {
+			_, err := unloadModel(m.client, m.cfg.OllamaAPIURL, model.Name)
 			if err != nil {
 				return genericMsg{message: lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render(fmt.Sprintf("Error unloading model %s: %v", model.Name, err))}
 			} else {
 				unloadedModels = append(unloadedModels, lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB6C1")).Render(model.Name))
 				logging.InfoLogger.Printf("Model %s unloaded\n", model.Name)
+				recordHistory("unload", model.Name, "")
 			}
 		}
 		return genericMsg{message: lipgloss.NewStyle().Foreground(lipgloss.Color("#EE82EE")).Render(fmt.Sprintf("Models unloaded: %v", unloadedModels))}
@@ -593,8 +836,13 @@ func (m *AppModel) handleLinkModelKey() (tea.Model, tea.Cmd) {
 		m.message = msg
 		return m, nil
 	}
+	// Linking needs the model blobs on disk
+	if msg := m.modelsDirUnavailable(); msg != "" {
+		m.message = msg
+		return m, nil
+	}
 	if item, ok := m.list.SelectedItem().(Model); ok {
-		message, err := linkModel(item.Name, m.lmStudioModelsDir, m.noCleanup, false, m.client)
+		message, err := linkModel(item.Name, m.ollamaModelsDir, m.lmStudioModelsDir, cleanupMode(m.noCleanup, m.cfg.CleanupReportOnly), false, m.client)
 		if err != nil {
 			m.message = fmt.Sprintf("Error linking model: %v", err)
 		} else if message != "" {
@@ -613,9 +861,14 @@ func (m *AppModel) handleLinkAllModelsKey() (tea.Model, tea.Cmd) {
 		m.message = msg
 		return m, nil
 	}
+	// Linking needs the model blobs on disk
+	if msg := m.modelsDirUnavailable(); msg != "" {
+		m.message = msg
+		return m, nil
+	}
 	var messages []string
 	for _, model := range m.models {
-		message, err := linkModel(model.Name, m.lmStudioModelsDir, m.noCleanup, false, m.client)
+		message, err := linkModel(model.Name, m.ollamaModelsDir, m.lmStudioModelsDir, cleanupMode(m.noCleanup, m.cfg.CleanupReportOnly), false, m.client)
 		if err != nil {
 			messages = append(messages, fmt.Sprintf("Error linking model %s: %v", model.Name, err))
 		} else if message != "" {
@@ -630,28 +883,225 @@ func (m *AppModel) handleLinkAllModelsKey() (tea.Model, tea.Cmd) {
 }
 
 func (m *AppModel) handleCopyModelKey() (tea.Model, tea.Cmd) {
-	defer func() {
-		m.refreshList()
-	}()
 	logging.DebugLogger.Println("CopyModel key matched")
 	if item, ok := m.list.SelectedItem().(Model); ok {
-		newName := promptForNewName(item.Name) // Pass the selected item as the model
-		if newName == "" {
-			m.message = "Error: name can't be empty"
+		newName, ok := promptForNewName(item.Name, m.modelNameFeedback)
+		if !ok {
+			m.message = "Cancelled"
+			return m, nil
+		}
+		return m.copyOrRenameModel(item, newName, false)
+	}
+	return m, nil
+}
+
+// modelNameFeedback checks a new model name as it's typed, warning about names that are taken, which are confirmed
+// afterwards, or that a registry will reject when they're pushed
+func (m *AppModel) modelNameFeedback(name string) (string, error) {
+	if err := gollama.ValidateModelName(name); err != nil {
+		return "", err
+	}
+	for _, model := range m.models {
+		if normaliseModelName(model.Name) == normaliseModelName(name) {
+			return fmt.Sprintf("%s already exists, you'll be asked whether to overwrite it", model.Name), nil
+		}
+	}
+	return gollama.ModelNameWarning(name), nil
+}
+
+// copyConflict holds a copy or rename that is waiting on the user because the destination already exists
+type copyConflict struct {
+	source           Model
+	destination      api.ListModelResponse
+	rename           bool
+	confirmOverwrite bool
+}
+
+// copyCheckMsg is the destination of a copy or rename looked up in the background, existing is nil when it's free
+type copyCheckMsg struct {
+	source   Model
+	newName  string
+	rename   bool
+	existing *api.ListModelResponse
+	err      error
+}
+
+// copyResultMsg is the result of a copy or rename made in the background
+type copyResultMsg struct {
+	source  Model
+	newName string
+	rename  bool
+	err     error
+}
+
+// copyOrRenameModel copies or renames a model, asking the user what to do if the new name is already taken
+func (m *AppModel) copyOrRenameModel(source Model, newName string, rename bool) (tea.Model, tea.Cmd) {
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000"))
+	if newName == "" {
+		m.message = errorStyle.Render("Error: name can't be empty")
+		return m, nil
+	}
+	if normaliseModelName(newName) == normaliseModelName(source.Name) {
+		m.message = errorStyle.Render("Error: the new name is the same as the current name")
+		return m, nil
+	}
+	if err := gollama.ValidateModelName(newName); err != nil {
+		m.message = errorStyle.Render(fmt.Sprintf("Error: %v", err))
+		return m, nil
+	}
+
+	client := m.client
+	return m, func() tea.Msg {
+		existing, err := findModel(client, newName)
+		return copyCheckMsg{source: source, newName: newName, rename: rename, existing: existing, err: err}
+	}
+}
+
+// handleCopyCheckMsg asks the user what to do when the destination is taken, otherwise it copies or renames the model
+func (m *AppModel) handleCopyCheckMsg(msg copyCheckMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render(fmt.Sprintf("Error: %v", msg.err))
+		return m, nil
+	}
+	if msg.existing != nil {
+		logging.InfoLogger.Printf("Destination model %s already exists, asking the user how to proceed\n", msg.newName)
+		m.copyConflict = &copyConflict{source: msg.source, destination: *msg.existing, rename: msg.rename}
+		return m, nil
+	}
+	return m.finishCopyOrRename(msg.source, msg.newName, msg.rename, false)
+}
+
+// finishCopyOrRename copies or renames the model in the background
+func (m *AppModel) finishCopyOrRename(source Model, newName string, rename bool, overwrite bool) (tea.Model, tea.Cmd) {
+	action := "Copying"
+	if rename {
+		action = "Renaming"
+	}
+	m.message = fmt.Sprintf("%s %s to %s...", action, source.Name, newName)
+	client := m.client
+	return m, func() tea.Msg {
+		var err error
+		if rename {
+			err = renameModel(client, source.Name, newName, overwrite)
 		} else {
-			copyModel(m, m.client, item.Name, newName)
-			m.message = fmt.Sprintf("Model %s copied to %s", item.Name, newName)
+			err = copyModel(client, source.Name, newName, overwrite)
+		}
+		return copyResultMsg{source: source, newName: newName, rename: rename, err: err}
+	}
+}
+
+func (m *AppModel) handleCopyResultMsg(msg copyResultMsg) (tea.Model, tea.Cmd) {
+	source, newName := msg.source, msg.newName
+	if msg.rename {
+		if msg.err != nil {
+			m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render(fmt.Sprintf("Error renaming model: %v", msg.err))
+			return m, nil
+		}
+		m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#EE82EE")).Render(fmt.Sprintf("Model %s renamed to %s", source.Name, newName) + m.droppedPrefixNote(source.Name, newName))
+		return m, m.fetchModels()
+	}
+
+	if msg.err != nil {
+		m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render(fmt.Sprintf("Error copying model: %v", msg.err))
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Model %s copied to %s", source.Name, newName) + m.droppedPrefixNote(source.Name, newName)
+	return m, m.fetchModels()
+}
+
+// droppedPrefixNote points out a copy or rename that left out the strip_string prefix the list hides, empty otherwise
+func (m *AppModel) droppedPrefixNote(source, newName string) string {
+	if m.cfg == nil {
+		return ""
+	}
+	if prefix := droppedPrefix(source, newName, m.cfg.StripString); prefix != "" {
+		return fmt.Sprintf(" (without the %s prefix the list hides)", prefix)
+	}
+	return ""
+}
+
+// handleCopyConflictKey handles the overwrite / new name / cancel choice for a copy or rename onto an existing model
+func (m *AppModel) handleCopyConflictKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	conflict := m.copyConflict
+	if conflict.confirmOverwrite {
+		switch {
+		case key.Matches(msg, m.keys.ConfirmYes):
+			m.copyConflict = nil
+			return m.finishCopyOrRename(conflict.source, conflict.destination.Name, conflict.rename, true)
+		case key.Matches(msg, m.keys.ConfirmNo), msg.String() == "esc":
+			conflict.confirmOverwrite = false
 		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "o":
+		conflict.confirmOverwrite = true
+	case "r":
+		m.copyConflict = nil
+		newName, ok := promptForName(conflict.source.Name, conflict.destination.Name, m.modelNameFeedback)
+		if !ok {
+			m.message = "Cancelled"
+			return m, nil
+		}
+		return m.copyOrRenameModel(conflict.source, newName, conflict.rename)
+	case "n", "esc", "q":
+		logging.InfoLogger.Println("Copy cancelled by user")
+		m.copyConflict = nil
+		m.message = "Cancelled"
 	}
 	return m, nil
 }
 
+func (m *AppModel) copyConflictView() string {
+	conflict := m.copyConflict
+	action := "copy"
+	if conflict.rename {
+		action = "rename"
+	}
+	existingSize := float64(conflict.destination.Size) / (1024 * 1024 * 1024)
+	details := fmt.Sprintf("Source:      %s  %.2f GB  %s\nDestination: %s  %.2f GB  %s",
+		conflict.source.Name, conflict.source.SizeGB(), truncate(conflict.source.Digest, 12),
+		conflict.destination.Name, existingSize, truncate(conflict.destination.Digest, 12))
+
+	if conflict.confirmOverwrite {
+		return fmt.Sprintf("\nAre you sure you want to overwrite %s? (Y/N)\n\n%s\n",
+			conflict.destination.Name, details)
+	}
+	return fmt.Sprintf("\nCan't %s %s, a model named %s already exists.\n\n%s\n\n%s",
+		action, conflict.source.Name, conflict.destination.Name, details,
+		"o: overwrite, r: choose a different name, n/esc: cancel")
+}
+
 func (m *AppModel) handlePushModelKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("PushModel key matched")
+	// With several models selected they're pushed as a batch under the same prefix
+	if selected := m.selectedListModels(); len(selected) > 1 {
+		prefix, ok := promptForPushRegistry(fmt.Sprintf("%d models", len(selected)), m.cfg.DefaultPushRegistry)
+		if !ok {
+			m.message = "Push cancelled"
+			return m, nil
+		}
+		m.rememberPushRegistry(prefix)
+		m.pushBatch = newPushBatch(batchPush, selected)
+		m.pushBatch.prefix = prefix
+		return m, nil
+	}
 	if item, ok := m.list.SelectedItem().(Model); ok {
+		prefix, ok := promptForPushRegistry(item.Name, m.cfg.DefaultPushRegistry)
+		if !ok {
+			m.message = "Push cancelled"
+			return m, nil
+		}
+		m.rememberPushRegistry(prefix)
+		if destination := registryDestination(prefix, item.Name); destination != item.Name {
+			m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Pushing model %s as %s\n", item.Name, destination))
+			m.showProgress = true // Show progress bar
+			return m, m.startPushModel(destination, m.pushToRegistryCmd(item.Name, destination))
+		}
 		m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Pushing model: %s\n", item.Name))
 		m.showProgress = true // Show progress bar
-		return m, m.startPushModel(item.Name)
+		return m, m.startPushModel(item.Name, m.pushModelCmd(item.Name))
 	}
 	return m, nil
 }
@@ -659,10 +1109,7 @@ func (m *AppModel) handlePushModelKey() (tea.Model, tea.Cmd) {
 func (m *AppModel) handlePullModelKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("PullModel key matched")
 	if item, ok := m.list.SelectedItem().(Model); ok {
-		m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Pulling model: %s\n", item.Name))
-		m.pulling = true
-		m.pullProgress = 0
-		return m, m.startPullModel(item.Name)
+		return m, m.pullModel(item.Name)
 	}
 	return m, nil
 }
@@ -671,6 +1118,8 @@ func (m *AppModel) handlePullNewModelKey() (tea.Model, tea.Cmd) {
 	m.pullInput = textinput.New()
 	m.pullInput.Placeholder = "Enter model name (e.g. llama3:8b-instruct)"
 	m.pullInput.Focus()
+	m.tagCompletion.model = ""
+	m.message = ""
 	m.pulling = true
 	m.newModelPull = true
 	return m, textinput.Blink
@@ -684,6 +1133,7 @@ func (m *AppModel) updatePullInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyEnter:
+			m.pullLayers = newPullLayers()
 			return m, m.startPullModel(m.pullInput.Value())
 		case tea.KeyEsc:
 			m.pulling = false
@@ -695,21 +1145,6 @@ func (m *AppModel) updatePullInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *AppModel) startPullNewModel(modelName string) tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		req := &api.PullRequest{Name: modelName}
-		err := m.client.Pull(ctx, req, func(resp api.ProgressResponse) error {
-			m.pullProgress = float64(resp.Completed) / float64(resp.Total)
-			return nil
-		})
-		if err != nil {
-			return pullErrorMsg{err}
-		}
-		return pullSuccessMsg{modelName}
-	}
-}
-
 func (m *AppModel) handleInspectModelKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("InspectModel key matched")
 	selectedItem := m.list.SelectedItem()
@@ -719,8 +1154,15 @@ func (m *AppModel) handleInspectModelKey() (tea.Model, tea.Cmd) {
 			return m, nil // This should never happen
 		}
 		m.inspecting = true
+		m.inspectEdit = inspectEdit{}
+		m.inspectLicence = inspectLicence{}
+		m.message = ""
 		m.inspectedModel = model                                     // Ensure inspectedModel is set correctly
-		logging.DebugLogger.Printf("Inspecting model: %+v\n", model) // Log the inspected model
+		logging.TraceLogger.Printf("Inspecting model: %+v\n", model) // Log the inspected model
+		m.inspectRuntime = inspectRuntime{}
+		m.inspectFiles = inspectFiles{}
+		m.loadInspectLayers(model.Name)
+		return m, tea.Batch(m.fetchInspectRuntime(model.Name), m.fetchInspectFiles(model.Name), m.fetchInspectLicence(model.Name))
 	}
 	return m, nil
 }
@@ -728,17 +1170,12 @@ func (m *AppModel) handleInspectModelKey() (tea.Model, tea.Cmd) {
 func (m *AppModel) handleRenameModelKey() (tea.Model, tea.Cmd) {
 	logging.DebugLogger.Println("RenameModel key matched")
 	if item, ok := m.list.SelectedItem().(Model); ok {
-		newName := promptForNewName(item.Name)
-		if newName == "" {
-			m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render("Error: name can't be empty")
-		} else {
-			err := renameModel(m, item.Name, newName)
-			if err != nil {
-				m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#8B0000")).Render(fmt.Sprintf("Error renaming model: %v", err))
-			} else {
-				m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("#EE82EE")).Render(fmt.Sprintf("Model %s renamed to %s", item.Name, newName))
-			}
+		newName, ok := promptForNewName(item.Name, m.modelNameFeedback)
+		if !ok {
+			m.message = "Cancelled"
+			return m, nil
 		}
+		return m.copyOrRenameModel(item, newName, true)
 	}
 	return m, nil
 }
@@ -753,13 +1190,7 @@ func (m *AppModel) ToggleTop() (*AppModel, tea.Cmd) {
 
 	topRunning = true
 	m.list.SetSize(m.width, m.height-5) // Adjust list size when top is running
-	return m, tea.Tick(1*time.Second, func(t time.Time) tea.Msg {
-		running, err := showRunningModels(m.client)
-		if err != nil {
-			return fmt.Sprintf("Error showing running models: %v", err)
-		}
-		return running
-	})
+	return m, nil
 }
 
 func (m *AppModel) View() string {
@@ -768,7 +1199,33 @@ func (m *AppModel) View() string {
 		return m.topView()
 	case HelpView:
 		return m.printFullHelp()
+	case DashboardView:
+		return m.dashboardView()
+	case ThemeView:
+		return m.themePickerView()
+	case HostsView:
+		return m.hostsView()
+	case DupesView:
+		return m.dupesView()
 	default:
+		if m.copyConflict != nil {
+			return m.copyConflictView()
+		}
+		if m.settingsCopy != nil {
+			return m.settingsCopyView()
+		}
+		if m.pullConfirm != nil {
+			return m.pullConfirmView()
+		}
+		if m.runOverride != nil {
+			return m.runOverrideView()
+		}
+		if m.modelsStatusShown() && !m.pulling {
+			return m.modelsStatusView()
+		}
+		if m.pushBatch != nil && m.pushBatch.confirming {
+			return m.pushBatchView()
+		}
 		if m.confirmDeletion {
 			return m.confirmDeletionView()
 		}
@@ -784,28 +1241,54 @@ func (m *AppModel) View() string {
 
 		if m.pulling {
 			if m.newModelPull && m.pullProgress == 0 {
-				return fmt.Sprintf(
-					"%s\n%s",
+				view := fmt.Sprintf(
+					"%s\n%s\n%s",
 					"Enter model name to pull:",
 					m.pullInput.View(),
+					m.tagCompletionView(),
 				)
+				if m.message != "" {
+					view += "\n" + m.message
+				}
+				return view
 			}
 			return fmt.Sprintf(
-				"Pulling model: %.0f%%\n%s\n%s",
+				"Pulling model: %.0f%%\n%s\n%s\n%s\n%s",
 				m.pullProgress*100,
 				m.progress.ViewAs(m.pullProgress),
-				"Press Ctrl+C to cancel - Note there is currently bug where you might need to hold a key (e.g. arrow key) to refresh the progress bar",
+				m.pullLayers.rateSummary(m.pullProgress),
+				m.pullLayersView(),
+				"Press Ctrl+C or esc to cancel",
 			)
 		}
 
 		view := m.list.View()
 
+		if banner := m.modelsDirUnavailable(); banner != "" {
+			view = lipgloss.NewStyle().Foreground(styles.Current().Colours.Warning.TerminalColour()).Bold(true).Render("⚠ "+banner) + "\n" + view
+		}
+
+		if summary := selectionSummary(m.models); summary != "" {
+			view += "\n" + summary
+		}
+
+		if m.versionWarning != "" {
+			view += "\n" + lipgloss.NewStyle().Foreground(styles.Current().Colours.Warning.TerminalColour()).Render("⚠ "+m.versionWarning)
+		}
+
 		if m.message != "" && m.view != HelpView {
-			view += "\n\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(m.message)
+			view += "\n\n" + lipgloss.NewStyle().Foreground(styles.Current().Colours.Message.TerminalColour()).Render(m.message)
 		}
 
 		if m.showProgress {
-			view += "\n" + m.progress.View()
+			label := "Pushing"
+			switch {
+			case m.pushBatch != nil:
+				label = m.pushBatch.label()
+			case m.transferHost != "":
+				label = "Copying to " + m.transferHost
+			}
+			view += fmt.Sprintf("\n%s: %.0f%%\n%s\n%s", label, m.pushProgress*100, m.progress.ViewAs(m.pushProgress), m.pushLayersView())
 		}
 
 		return view
@@ -817,20 +1300,27 @@ func (m *AppModel) confirmDeletionView() string {
 		m.refreshList()
 	}()
 	logging.DebugLogger.Println("Confirm deletion function")
+	if m.confirmPinnedDeletion {
+		var names []string
+		for _, model := range pinnedModels(m.selectedModels) {
+			names = append(names, model.Name)
+		}
+		return fmt.Sprintf("\n🔒 The following models are pinned, are you really sure you want to delete them? (Y/N)\n\n%s\n\n%s\n%s",
+			strings.Join(names, "\n"),
+			m.keys.ConfirmYes.Help().Key,
+			m.keys.ConfirmNo.Help().Key)
+	}
 	return fmt.Sprintf("\nAre you sure you want to delete the selected models? (Y/N)\n\n%s\n\n%s\n%s",
-		strings.Join(m.selectedModelNames(), "\n"),
+		deletionSummary(m.models, m.selectedModels, m.deleteRunning, m.cfg.UnloadBeforeDelete),
 		m.keys.ConfirmYes.Help().Key,
 		m.keys.ConfirmNo.Help().Key)
 }
 
-func (m *AppModel) inspectModelView(model Model) string {
-	logging.DebugLogger.Printf("Inspecting model view: %+v\n", model) // Log the model being inspected
-
-	columns := []table.Column{
-		{Title: "Property", Width: 20},
-		{Title: "Value", Width: 50},
-	}
+// inspectPullHistory is the number of recent pulls shown in the inspect view
+const inspectPullHistory = 5
 
+// inspectRows returns the rows of the inspect view and which of them are parameters
+func (m *AppModel) inspectRows(model Model) ([]table.Row, map[string]bool) {
 	// Use getModelParams to get the model parameters and add them to the rows
 	modelParams, _, err := getModelParams(model.Name, m.client)
 	if err != nil {
@@ -841,17 +1331,64 @@ func (m *AppModel) inspectModelView(model Model) string {
 	rows := []table.Row{
 		{"Name", model.Name},
 		{"ID", model.ID},
-		{"Size (GB)", fmt.Sprintf("%.2f", model.Size)},
+		{"Size (GB)", fmt.Sprintf("%.2f", model.SizeGB())},
 		{"quantisation Level", model.QuantizationLevel},
 		{"Modified", model.Modified.Format("2006-01-02")},
 		{"Family", model.Family},
 	}
+	if files := m.inspectFiles.files; m.inspectFiles.model == model.Name && files.Vision() {
+		rows = append(rows,
+			table.Row{"Capabilities", "📷 vision"},
+			table.Row{"Model file (GB)", fmt.Sprintf("%.2f", float64(files.ModelSize())/(1<<30))},
+			table.Row{"Projector (GB)", fmt.Sprintf("%.2f", float64(files.ProjectorSize())/(1<<30))},
+		)
+	}
+	if status := m.verifyStatus(model.Name); status != "" {
+		rows = append(rows, table.Row{"Integrity", status})
+	}
+	if profile := runProfileLabel(m.cfg, model.Name); profile != "" {
+		rows = append(rows, table.Row{"Run profile", profile})
+	}
+	if licence := detectLicence(m.licenceText()); licence != "" {
+		rows = append(rows, table.Row{"Licence", licence + " (L to read)"})
+	}
 
-	// getModelParams returns a map of model parameters, so we need to iterate over the map and add the parameters to the rows
-	for key, value := range modelParams {
-		rows = append(rows, []string{key, value})
+	// getModelParams returns a map of model parameters, sort them so the rows keep their order between renders
+	params := make(map[string]bool, len(modelParams))
+	for _, key := range slices.Sorted(maps.Keys(modelParams)) {
+		rows = append(rows, []string{key, modelParams[key]})
+		params[key] = true
 	}
 
+	// Show the digests recent pulls resolved to so changes to tags like latest are visible
+	pulls, err := pullHistory(model.Name, inspectPullHistory)
+	if err != nil {
+		logging.ErrorLogger.Printf("Error reading pull history: %v\n", err)
+	}
+	for i, pull := range pulls {
+		label := ""
+		if i == 0 {
+			label = "Pull history"
+		}
+		rows = append(rows, []string{label, fmt.Sprintf("%s  %s", pull.Time.Format("2006-01-02"), truncate(pull.Digest, 12))})
+	}
+	return rows, params
+}
+
+func (m *AppModel) inspectModelView(model Model) string {
+	logging.TraceLogger.Printf("Inspecting model view: %+v\n", model) // Log the model being inspected
+	if m.inspectLicence.shown {
+		return m.inspectLicenceView()
+	}
+
+	columns := []table.Column{
+		{Title: "Property", Width: 20},
+		{Title: "Value", Width: 50},
+	}
+	rows, _ := m.inspectRows(model)
+	m.inspectEdit.rows = len(rows)
+	m.inspectEdit.cursor = min(m.inspectEdit.cursor, max(len(rows)-1, 0))
+
 	// Log the rows to ensure they are being populated correctly
 	for _, row := range rows {
 		logging.DebugLogger.Printf("Row: %v\n", row)
@@ -871,9 +1408,19 @@ func (m *AppModel) inspectModelView(model Model) string {
 	s.Selected = s.Selected.Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
 	t.SetStyles(s)
 	t.Focus()
+	t.SetCursor(m.inspectEdit.cursor)
 
 	// Render the table view
-	return "\n" + t.View() + "\nPress 'q' or `esc` to return to the main view."
+	view := "\n" + t.View() + m.inspectRuntimeView() + m.inspectLayersView()
+	if m.inspectEdit.param != "" {
+		view += "\n" + m.inspectEdit.input.View() + "\nPress enter to apply or `esc` to cancel."
+	} else {
+		view += "\nPress 'e' to edit the selected parameter, 'L' to read the licence, 'q' or `esc` to return to the main view."
+	}
+	if m.message != "" {
+		view += "\n" + m.message
+	}
+	return view
 }
 
 func (m *AppModel) filterView() string {
@@ -881,21 +1428,40 @@ func (m *AppModel) filterView() string {
 	return m.list.View()
 }
 
-func (m *AppModel) selectedModelNames() []string {
-	var names []string
-	for _, model := range m.selectedModels {
-		names = append(names, model.Name)
+// refreshList updates the list view with the current models, under their family headers when the list is grouped. An
+// active filter is applied to the new items straight away and the cursor stays on the model it was on while it's
+// still listed, so refreshing after a pull, copy or delete doesn't lose the user's place.
+func (m *AppModel) refreshList() {
+	current, index := "", m.list.Index()
+	if model, ok := m.list.SelectedItem().(Model); ok {
+		current = model.Name
 	}
-	return names
-}
 
-// refreshList updates the list view with the current models
-func (m *AppModel) refreshList() {
-	items := make([]list.Item, len(m.models))
-	for i, model := range m.models {
-		items[i] = model
+	var items []list.Item
+	if m.grouped() {
+		items = groupedItems(m.models, m.collapsedGroups)
+	} else {
+		items = make([]list.Item, len(m.models))
+		for i, model := range m.models {
+			items[i] = model
+		}
+	}
+	if cmd := m.list.SetItems(items); cmd != nil {
+		// SetItems filters the new items in a command, the list shows nothing until its result is applied
+		m.list, _ = m.list.Update(cmd())
+	}
+
+	visible := m.list.VisibleItems()
+	if len(visible) == 0 {
+		return
 	}
-	m.list.SetItems(items)
+	for i, item := range visible {
+		if model, ok := item.(Model); ok && current != "" && model.Name == current {
+			m.list.Select(i)
+			return
+		}
+	}
+	m.list.Select(min(index, len(visible)-1))
 }
 
 func (m *AppModel) clearScreen() tea.Model {
@@ -912,51 +1478,15 @@ func (m *AppModel) filtering() bool {
 }
 
 func (m *AppModel) startTopTicker() tea.Cmd {
-	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-		running, err := showRunningModels(m.client)
-		if err != nil {
-			return fmt.Sprintf("Error showing running models: %v", err)
-		}
-		return running
-	})
-}
-
-func (m *AppModel) topView() string {
-	runningModels, err := showRunningModels(m.client)
-	if err != nil {
-		return fmt.Sprintf("Error showing running models: %v", err)
-	}
-
-	columns := []table.Column{
-		{Title: "Name", Width: 40},
-		{Title: "Size (GB)", Width: 10},
-		{Title: "VRAM (GB)", Width: 10},
-		{Title: "Until", Width: 20},
-	}
-
-	t := table.New(
-		table.WithColumns(columns),
-		table.WithRows(runningModels),
-		table.WithFocused(true),
-		table.WithHeight(len(runningModels)+1),
-	)
-
-	// Set the table styles
-	s := table.DefaultStyles()
-	s.Header = s.Header.BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240"))
-	s.Selected = s.Selected.Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
-	t.SetStyles(s)
-
-	// Render the table view
-	return "\n" + t.View() + "\nPress 'q' or `esc` to return to the main view."
+	return m.fetchRunningModels(2 * time.Second)
 }
 
 // FullHelp returns keybindings for the expanded help view. It's part of the key.Map interface.
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Space, k.Delete, k.RunModel, k.LinkModel, k.LinkAllModels, k.CopyModel, k.PushModel}, // first column
-		{k.SortByName, k.SortBySize, k.SortByModified, k.SortByQuant, k.SortByFamily},           // second column
-		{k.Top, k.EditModel, k.InspectModel, k.Quit},                                            // third column
+		{k.Space, k.Delete, k.RunModel, k.RunWithOptions, k.LinkModel, k.LinkAllModels, k.CopyModel, k.CopySettings, k.PushModel, k.TransferModel},                                 // first column
+		{k.SortByName, k.SortBySize, k.SortByModified, k.SortByQuant, k.SortByFamily, k.SelectAll, k.InvertSelection, k.DeselectAll},                                               // second column
+		{k.Top, k.Dashboard, k.EditModel, k.InspectModel, k.PinModel, k.Theme, k.CompareHosts, k.Benchmark, k.Dupes, k.PullKeepConfig, k.UpdateAll, k.FitCheck, k.GroupBy, k.Quit}, // third column
 	}
 }
 
@@ -997,17 +1527,3 @@ func (m *AppModel) printFullHelp() string {
 	return "\n" + t.View() + "\nPress 'q' or `esc` to return to the main view."
 
 }
-
-// Add this method to refresh the model list after pulling:
-func (m *AppModel) refreshModelsAfterPull() tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		resp, err := m.client.List(ctx)
-		if err != nil {
-			return pullErrorMsg{err}
-		}
-		m.models = parseAPIResponse(resp)
-		m.refreshList()
-		return nil
-	}
-}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestModelNameFeedback(t *testing.T) {
+	existing := Model{}
+	existing.Name = "qwen2:latest"
+	m := &AppModel{models: []Model{existing}}
+
+	if _, err := m.modelNameFeedback("qwen2:7b:q4"); err == nil {
+		t.Error("an invalid name wasn't an error")
+	}
+	if warning, err := m.modelNameFeedback("qwen2"); err != nil || !strings.Contains(warning, "already exists") {
+		t.Errorf("taken name gave warning %q, error %v", warning, err)
+	}
+	if warning, err := m.modelNameFeedback("Qwen2-Copy"); err != nil || !strings.Contains(warning, "uppercase") {
+		t.Errorf("uppercase name gave warning %q, error %v", warning, err)
+	}
+
+	// enter doesn't accept a name with an error, and the prompt shows why
+	input := textinput.New()
+	input.SetValue("bad name")
+	prompt := &textInputModel{textInput: input, feedback: m.modelNameFeedback}
+	if _, cmd := prompt.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || prompt.quitting {
+		t.Error("enter accepted an invalid name")
+	}
+	if view := prompt.View(); !strings.Contains(view, "can't contain spaces") {
+		t.Errorf("prompt view = %q, want the error", view)
+	}
+	prompt.textInput.SetValue("qwen2-copy")
+	if _, cmd := prompt.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || !prompt.quitting {
+		t.Error("enter didn't accept a valid name")
+	}
+}
+
+func TestCopyConflictInBackground(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := &fakeOllama{models: []string{"llama3:8b", "llama3:copy"}}
+	server := httptest.NewServer(http.HandlerFunc(fake.handler))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{}, keys: *NewKeyMap()}
+	source := Model{}
+	source.Name = "llama3:8b"
+
+	// The destination is looked up by a command rather than while handling the key
+	_, cmd := m.copyOrRenameModel(source, "llama3:copy", false)
+	if cmd == nil || m.copyConflict != nil {
+		t.Fatal("copy checked the destination before its command ran")
+	}
+	m.Update(cmd())
+	if m.copyConflict == nil || m.copyConflict.destination.Name != "llama3:copy" {
+		t.Fatalf("copy onto an existing model didn't ask what to do, message %q", m.message)
+	}
+
+	m.handleCopyConflictKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	_, cmd = m.handleCopyConflictKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil || m.message != "Copying llama3:8b to llama3:copy..." {
+		t.Fatalf("overwriting = %q, want the copy made in the background", m.message)
+	}
+	m.Update(cmd())
+	if m.message != "Model llama3:8b copied to llama3:copy" {
+		t.Errorf("message = %q, want the copy reported", m.message)
+	}
+
+	fake.copyFails = true
+	_, cmd = m.finishCopyOrRename(source, "llama3:other", true, false)
+	m.Update(cmd())
+	if !strings.Contains(m.message, "Error renaming model") || !slices.Contains(fake.models, "llama3:8b") {
+		t.Errorf("failed rename = %q with models %v, want the error and the source kept", m.message, fake.models)
+	}
+}
@@ -0,0 +1,244 @@
+// benchmark.go measures the latency of the Ollama API to tell a slow connection apart from a slow gollama.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// benchmarkRuns is how many times each endpoint is called
+const benchmarkRuns = 10
+
+// Median latencies of the list call up to which a connection is rated good or ok, anything slower is slow
+const (
+	goodLatency = 50 * time.Millisecond
+	okLatency   = 250 * time.Millisecond
+)
+
+// latencyStats summarises the calls made to one endpoint
+type latencyStats struct {
+	Min    time.Duration `json:"min"`
+	Median time.Duration `json:"median"`
+	P95    time.Duration `json:"p95"`
+	Bytes  int64         `json:"bytes"` // response size of the last call
+}
+
+// benchmarkResult is one run of the connection benchmark
+type benchmarkResult struct {
+	Time    time.Time    `json:"time"`
+	URL     string       `json:"url"`
+	Version latencyStats `json:"version"`
+	List    latencyStats `json:"list"`
+	Rating  string       `json:"rating"`
+}
+
+type benchmarkMsg struct {
+	result   benchmarkResult
+	previous *benchmarkResult
+	err      error
+}
+
+// calculateLatencyStats returns the min, median and 95th percentile (nearest rank) of durations
+func calculateLatencyStats(durations []time.Duration) latencyStats {
+	if len(durations) == 0 {
+		return latencyStats{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	rank := (95*n + 99) / 100
+	return latencyStats{Min: sorted[0], Median: median, P95: sorted[rank-1]}
+}
+
+// rateLatency classifies a connection by the median latency of the list call, which is what the TUI waits on most
+func rateLatency(median time.Duration) string {
+	switch {
+	case median <= goodLatency:
+		return "good"
+	case median <= okLatency:
+		return "ok"
+	default:
+		return "slow"
+	}
+}
+
+// timeEndpoint calls an API endpoint runs times in sequence and returns the latency of each call and the size of the last response
+func timeEndpoint(ctx context.Context, httpClient *http.Client, endpoint string, runs int) ([]time.Duration, int64, error) {
+	durations := make([]time.Duration, 0, runs)
+	var size int64
+	for i := 0; i < runs; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		start := time.Now()
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		size, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, 0, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+		}
+		durations = append(durations, time.Since(start))
+	}
+	return durations, size, nil
+}
+
+// runBenchmark times sequential version and list calls against the API at baseURL
+func runBenchmark(ctx context.Context, baseURL string, runs int) (benchmarkResult, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	result := benchmarkResult{Time: time.Now(), URL: baseURL}
+	httpClient := &http.Client{Timeout: 30 * time.Second, Transport: apiHTTPClient.Transport}
+
+	durations, size, err := timeEndpoint(ctx, httpClient, baseURL+"/api/version", runs)
+	if err != nil {
+		return result, fmt.Errorf("error benchmarking version calls: %w", err)
+	}
+	result.Version = calculateLatencyStats(durations)
+	result.Version.Bytes = size
+
+	durations, size, err = timeEndpoint(ctx, httpClient, baseURL+"/api/tags", runs)
+	if err != nil {
+		return result, fmt.Errorf("error benchmarking list calls: %w", err)
+	}
+	result.List = calculateLatencyStats(durations)
+	result.List.Bytes = size
+
+	result.Rating = rateLatency(result.List.Median)
+	return result, nil
+}
+
+// benchmarkPath returns where the last benchmark result is kept
+func benchmarkPath() string {
+	return filepath.Join(utils.GetConfigDir(), "benchmark.json")
+}
+
+// loadLastBenchmark returns the previous benchmark result, or nil if there isn't one
+func loadLastBenchmark() *benchmarkResult {
+	data, err := os.ReadFile(benchmarkPath())
+	if err != nil {
+		return nil
+	}
+	var result benchmarkResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		logging.ErrorLogger.Printf("Ignoring unreadable benchmark result %s: %v\n", benchmarkPath(), err)
+		return nil
+	}
+	return &result
+}
+
+func saveBenchmark(result benchmarkResult) error {
+	path := benchmarkPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// benchmarkAndRecord runs the benchmark and stores the result, returning the result it replaced for comparison
+func benchmarkAndRecord(ctx context.Context, baseURL string) (benchmarkResult, *benchmarkResult, error) {
+	previous := loadLastBenchmark()
+	result, err := runBenchmark(ctx, baseURL, benchmarkRuns)
+	if err != nil {
+		return result, previous, err
+	}
+	if err := saveBenchmark(result); err != nil {
+		logging.ErrorLogger.Printf("Error saving benchmark result: %v\n", err)
+	}
+	return result, previous, nil
+}
+
+// benchmarkTrend compares the list latency with the previous run against the same URL
+func benchmarkTrend(result benchmarkResult, previous *benchmarkResult) string {
+	if previous == nil || previous.URL != result.URL {
+		return ""
+	}
+	return fmt.Sprintf("was %s median on %s, %s now", roundLatency(previous.List.Median), previous.Time.Format("2006-01-02"), roundLatency(result.List.Median))
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	if d >= 10*time.Millisecond {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
+
+// benchmarkAdvice suggests what to do about a slow connection
+func benchmarkAdvice(result benchmarkResult) string {
+	if result.Rating != "slow" {
+		return ""
+	}
+	return "Every model list, inspect and refresh waits on the API, so the TUI will feel sluggish over this connection. " +
+		"Check for a VPN or proxy in the way, or run gollama on the Ollama host."
+}
+
+// formatBenchmark renders a benchmark result for the doctor report
+func formatBenchmark(result benchmarkResult, previous *benchmarkResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Connection:  %s (%d calls each to %s)\n", result.Rating, benchmarkRuns, result.URL)
+	for _, row := range []struct {
+		name  string
+		stats latencyStats
+	}{{"Version", result.Version}, {"List", result.List}} {
+		fmt.Fprintf(&b, "%-12s min %s, median %s, p95 %s, %s\n", row.name+":", roundLatency(row.stats.Min), roundLatency(row.stats.Median),
+			roundLatency(row.stats.P95), formatBytes(row.stats.Bytes))
+	}
+	if trend := benchmarkTrend(result, previous); trend != "" {
+		fmt.Fprintf(&b, "Trend:       %s\n", trend)
+	}
+	if advice := benchmarkAdvice(result); advice != "" {
+		fmt.Fprintf(&b, "Advice:      %s\n", advice)
+	}
+	return b.String()
+}
+
+func (m *AppModel) handleBenchmarkKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("Benchmark key matched")
+	m.message = "Benchmarking the connection to " + m.cfg.OllamaAPIURL + "..."
+	baseURL := m.cfg.OllamaAPIURL
+	return m, func() tea.Msg {
+		result, previous, err := benchmarkAndRecord(context.Background(), baseURL)
+		return benchmarkMsg{result: result, previous: previous, err: err}
+	}
+}
+
+func (m *AppModel) handleBenchmarkMsg(msg benchmarkMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Benchmark failed: %v", msg.err)
+		return m, nil
+	}
+	result := msg.result
+	m.message = fmt.Sprintf("Connection %s: list median %s (p95 %s, %s), version median %s",
+		result.Rating, roundLatency(result.List.Median), roundLatency(result.List.P95), formatBytes(result.List.Bytes), roundLatency(result.Version.Median))
+	if trend := benchmarkTrend(result, msg.previous); trend != "" {
+		m.message += " - " + trend
+	}
+	return m, nil
+}
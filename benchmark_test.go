@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCalculateLatencyStats(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      latencyStats
+	}{
+		{"empty", nil, latencyStats{}},
+		{"odd", []time.Duration{30 * ms, 10 * ms, 20 * ms}, latencyStats{Min: 10 * ms, Median: 20 * ms, P95: 30 * ms}},
+		{"even", []time.Duration{40 * ms, 10 * ms, 20 * ms, 30 * ms}, latencyStats{Min: 10 * ms, Median: 25 * ms, P95: 40 * ms}},
+		{"ten", []time.Duration{1 * ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms, 6 * ms, 7 * ms, 8 * ms, 9 * ms, 100 * ms}, latencyStats{Min: 1 * ms, Median: 5500 * time.Microsecond, P95: 100 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateLatencyStats(tt.durations); got != tt.want {
+				t.Errorf("calculateLatencyStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		wantRating string
+	}{
+		{"fast", 0, "good"},
+		{"slow", 300 * time.Millisecond, "slow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listCalls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/version":
+					w.Write([]byte(`{"version":"0.5.7"}`))
+				case "/api/tags":
+					listCalls++
+					time.Sleep(tt.delay)
+					w.Write([]byte(`{"models":[]}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			result, err := runBenchmark(context.Background(), server.URL, 3)
+			if err != nil {
+				t.Fatalf("runBenchmark() error = %v", err)
+			}
+			if listCalls != 3 {
+				t.Errorf("runBenchmark() made %d list calls, want 3", listCalls)
+			}
+			if result.Rating != tt.wantRating {
+				t.Errorf("runBenchmark() rating = %q, want %q", result.Rating, tt.wantRating)
+			}
+			if result.List.Min < tt.delay || result.List.Median < result.List.Min || result.List.P95 < result.List.Median {
+				t.Errorf("runBenchmark() list stats = %+v, inconsistent with a %s delay", result.List, tt.delay)
+			}
+			if result.List.Bytes != int64(len(`{"models":[]}`)) {
+				t.Errorf("runBenchmark() list bytes = %d", result.List.Bytes)
+			}
+		})
+	}
+}
+
+func TestBenchmarkTrend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	result := benchmarkResult{URL: "http://host:11434", List: latencyStats{Median: 300 * time.Millisecond}}
+	if trend := benchmarkTrend(result, loadLastBenchmark()); trend != "" {
+		t.Errorf("benchmarkTrend() with no previous run = %q, want empty", trend)
+	}
+	previous := benchmarkResult{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), URL: "http://host:11434", List: latencyStats{Median: 40 * time.Millisecond}}
+	if err := saveBenchmark(previous); err != nil {
+		t.Fatalf("saveBenchmark() error = %v", err)
+	}
+	want := "was 40ms median on 2024-03-01, 300ms now"
+	if trend := benchmarkTrend(result, loadLastBenchmark()); trend != want {
+		t.Errorf("benchmarkTrend() = %q, want %q", trend, want)
+	}
+}
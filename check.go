@@ -0,0 +1,114 @@
+// check.go implements -check, which verifies the blobs of every local model in one go and prints a table of the
+// broken ones, so it can run from cron after a disk incident.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// checkStatus describes a layer problem for the table, missing blobs apart from the ones that are there but wrong
+func checkStatus(problem layerProblem) string {
+	if problem.Issue == "missing" {
+		return "missing"
+	}
+	return "corrupt"
+}
+
+// printCheckTable writes a row for each healthy model and each broken layer, returning the number of broken models
+func printCheckTable(out io.Writer, results []verifyResult) int {
+	broken := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Model\tStatus\tLayer\tDetail")
+	for _, result := range results {
+		if result.OK() {
+			fmt.Fprintf(w, "%s\tok\t-\t-\n", result.Model)
+			continue
+		}
+		broken++
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s\tbroken\t-\t%s\n", result.Model, result.Error)
+		}
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "%s\t%s\t%s %s\t%s\n", result.Model, checkStatus(problem), layerKind(problem.MediaType), problem.Digest, problem.Issue)
+		}
+	}
+	w.Flush()
+	return broken
+}
+
+// checkProgress returns the progress callback for hashing the model at index, drawn on one line of out and only
+// redrawn when the percentage changes
+func checkProgress(out io.Writer, index, models int, modelName string) hashProgress {
+	lastPercent := -1
+	return func(layer, layers int, hashed, size int64) {
+		percent := 100
+		if size > 0 {
+			percent = int(hashed * 100 / size)
+		}
+		if percent == lastPercent {
+			return
+		}
+		lastPercent = percent
+		fmt.Fprintf(out, "\r\033[KHashing %d/%d %s, layer %d/%d: %d%%", index+1, models, modelName, layer, layers, percent)
+	}
+}
+
+// runCheck implements the -check flag, returning the process exit code. hashes also hashes every blob against its
+// digest, which reads all of them so it's slow. ctrl+c stops it, printing the models checked so far.
+func runCheck(ctx context.Context, modelsDir string, local bool, names []string, hashes bool) int {
+	if !local {
+		fmt.Fprintln(os.Stderr, "-check reads the blobs directly so it needs a local Ollama host, use -verify --all to check a remote host")
+		return 1
+	}
+	showProgress := hashes && term.IsTerminal(int(os.Stderr.Fd()))
+
+	var results []verifyResult
+	for i, name := range names {
+		var progress hashProgress
+		if showProgress {
+			progress = checkProgress(os.Stderr, i, len(names), name)
+		}
+		result := verifyModelBlobsContext(ctx, modelsDir, name, hashes, progress)
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, result)
+	}
+	if showProgress {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+
+	broken := printCheckTable(os.Stdout, results)
+	if ctx.Err() != nil {
+		fmt.Printf("\nCheck was cancelled after %d of %d models\n", len(results), len(names))
+		return 130
+	}
+	method := "sizes"
+	if hashes {
+		method = "sizes and hashes"
+	}
+	fmt.Printf("\n%d of %d models OK (checked blob %s)\n", len(results)-broken, len(results), method)
+	if broken > 0 {
+		fmt.Printf("Repair a broken model with gollama -verify <model>, which offers to re-pull it: %s\n", strings.Join(brokenModels(results), ", "))
+		return 1
+	}
+	return 0
+}
+
+// brokenModels returns the names of the models that didn't check out
+func brokenModels(results []verifyResult) []string {
+	var names []string
+	for _, result := range results {
+		if !result.OK() {
+			names = append(names, result.Model)
+		}
+	}
+	return names
+}
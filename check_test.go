@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckModels(t *testing.T) {
+	dir := t.TempDir()
+	writeModel := func(model string, blobs ...[]byte) []manifestLayer {
+		var layers []manifestLayer
+		for _, data := range blobs {
+			sum := sha256.Sum256(data)
+			layer := manifestLayer{MediaType: "application/vnd.ollama.image.model", Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+			path := blobPath(dir, layer.Digest)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			layers = append(layers, layer)
+		}
+		path := manifestPath(dir, model)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		data, _ := json.Marshal(modelManifest{Layers: layers})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return layers
+	}
+	writeModel("intact:latest", []byte("intact weights"))
+	missing := writeModel("missing:latest", []byte("missing weights"))
+	os.Remove(blobPath(dir, missing[0].Digest))
+	corrupt := writeModel("corrupt:latest", []byte("corrupt weights"))
+	os.WriteFile(blobPath(dir, corrupt[0].Digest), []byte("CORRUPT WEIGHTS"), 0644)
+
+	var reported []int
+	var results []verifyResult
+	for _, name := range []string{"intact", "missing", "corrupt"} {
+		results = append(results, verifyModelBlobsContext(context.Background(), dir, name, true, func(layer, layers int, hashed, size int64) {
+			reported = append(reported, int(hashed*100/size))
+		}))
+	}
+	if len(reported) == 0 || reported[len(reported)-1] != 100 {
+		t.Errorf("hash progress reported %v, want it to finish at 100%%", reported)
+	}
+
+	var out bytes.Buffer
+	if broken := printCheckTable(&out, results); broken != 2 {
+		t.Errorf("printCheckTable() broken = %d, want 2", broken)
+	}
+	table := out.String()
+	for _, want := range []string{
+		"intact   ok",
+		"missing  missing  model " + missing[0].Digest,
+		"corrupt  corrupt  model " + corrupt[0].Digest + "  hash mismatch",
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("check table is missing %q:\n%s", want, table)
+		}
+	}
+
+	// A cancelled check stops hashing rather than reporting the model as corrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := verifyModelBlobsContext(ctx, dir, "intact", true, nil); len(result.Problems) != 0 {
+		t.Errorf("cancelled check reported problems %+v", result.Problems)
+	}
+	if code := runCheck(context.Background(), dir, false, []string{"intact"}, false); code != 1 {
+		t.Errorf("runCheck() on a remote host = %d, want 1", code)
+	}
+}
@@ -1,19 +1,34 @@
+// compare.go compares a model's template, system prompt and parameters with the version the public registry ships,
+// so local customisations can be checked before pulling over them.
 package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/ollama/ollama/api"
+
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// Media types of the manifest layers holding a model's template, system prompt and parameters
+const (
+	templateMediaType = "application/vnd.ollama.image.template"
+	systemMediaType   = "application/vnd.ollama.image.system"
+	paramsMediaType   = "application/vnd.ollama.image.params"
 )
 
+// errNotInRegistry is returned when the registry doesn't have a model
+var errNotInRegistry = errors.New("not found in the public registry")
+
 type ModelfileDiff struct {
 	Command string
 	Current string
@@ -21,384 +36,235 @@ type ModelfileDiff struct {
 	Type    string // "modified", "added", or "removed"
 }
 
-func fetchLatestModelfile(modelName string) (string, error) {
-	// Split the model name into components
-	parts := strings.Split(modelName, ":")
-	name := parts[0]
-	tag := "latest"
-	if len(parts) > 1 {
-		tag = parts[1]
-	}
-	// Clean the model name
-	name = strings.ToLower(name)
-	name = strings.TrimPrefix(name, "library/")
-	// Handle special cases for common model naming patterns
-	name = strings.ReplaceAll(name, ".", "-") // e.g., llama2.7b -> llama2-7b
-	name = strings.ReplaceAll(name, "_", "-") // Replace underscores with hyphens
-	// Normalize registry path
-	path := name
-	if !strings.Contains(path, "/") {
-		path = "library/" + path
-	}
-	url := fmt.Sprintf("https://registry.ollama.ai/v2/%s/manifests/%s", path, tag)
-	logging.DebugLogger.Printf("Fetching manifest from URL: %s", url)
-	// First get the manifest
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	// Add required OCI registry headers
-	req.Header.Set("Accept", "application/vnd.oci.image.manifest.v1+json")
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	logging.DebugLogger.Printf("Response status code: %d for URL: %s", resp.StatusCode, url)
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch manifest: status %d", resp.StatusCode)
-	}
-	// Read and log the manifest response
-	manifestBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading manifest body: %v", err)
+// modelfileCompareMsg is the result of comparing a model with the registry, note explains why it couldn't be compared
+type modelfileCompareMsg struct {
+	model string
+	diffs []ModelfileDiff
+	note  string
+	err   error
+}
+
+// isRegistryModel reports whether a model name refers to the public registry rather than another host
+func isRegistryModel(modelName string) bool {
+	host, _, found := strings.Cut(modelName, "/")
+	if !found || host == defaultRegistry {
+		return true
 	}
-	logging.DebugLogger.Printf("Manifest response: %s", string(manifestBody))
-	// Parse the manifest to get the config blob
-	var manifest struct {
-		SchemaVersion int `json:"schemaVersion"`
-		Config        struct {
-			MediaType string `json:"mediaType"`
-			Digest    string `json:"digest"`
-			Size      int    `json:"size"`
-		} `json:"config"`
-		Layers []struct {
-			MediaType string `json:"mediaType"`
-			Digest    string `json:"digest"`
-			Size      int    `json:"size"`
-		} `json:"layers"`
+	return !strings.ContainsAny(host, ".:") && host != "localhost"
+}
+
+// fetchRegistryModelfileValues returns the template, system prompt and parameters the registry ships for a model,
+// keyed as modelfileValues keys them
+func fetchRegistryModelfileValues(ctx context.Context, client *http.Client, baseURL, modelName string) (map[string][]string, error) {
+	repository := registryRepository(modelName)
+	_, tag := splitModelTag(modelName)
+	if tag == "" {
+		tag = "latest"
 	}
-	if err := json.Unmarshal(manifestBody, &manifest); err != nil {
-		return "", fmt.Errorf("error decoding manifest: %v", err)
+	var manifest modelManifest
+	if err := getRegistryJSON(ctx, client, fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, repository, tag), &manifest); err != nil {
+		return nil, err
 	}
-	logging.DebugLogger.Printf("Parsed manifest: %+v", manifest)
 
-	// Find the TEMPLATE and PARAMETER layers
-	var templateDigest, paramsDigest string
+	values := make(map[string][]string)
 	for _, layer := range manifest.Layers {
+		url := fmt.Sprintf("%s/v2/%s/blobs/%s", baseURL, repository, layer.Digest)
 		switch layer.MediaType {
-		case "application/vnd.ollama.image.template":
-			templateDigest = layer.Digest
-		case "application/vnd.ollama.image.params":
-			paramsDigest = layer.Digest
+		case templateMediaType, systemMediaType:
+			text, err := getRegistryBlob(ctx, client, url)
+			if err != nil {
+				return nil, fmt.Errorf("error fetching the %s layer: %w", layer.MediaType, err)
+			}
+			field := "template"
+			if layer.MediaType == systemMediaType {
+				field = "system"
+			}
+			values[field] = []string{text}
+		case paramsMediaType:
+			var params map[string]any
+			if err := getRegistryJSON(ctx, client, url, &params); err != nil {
+				return nil, fmt.Errorf("error fetching the parameters: %w", err)
+			}
+			for key, value := range params {
+				if list, ok := value.([]any); ok {
+					for _, item := range list {
+						values[key] = append(values[key], fmt.Sprint(item))
+					}
+					continue
+				}
+				values[key] = []string{fmt.Sprint(value)}
+			}
 		}
 	}
+	return values, nil
+}
 
-	if templateDigest == "" || paramsDigest == "" {
-		return "", fmt.Errorf("template or parameter layer not found in manifest")
-	}
-
-	// Fetch the TEMPLATE layer
-	templateURL := fmt.Sprintf("https://registry.ollama.ai/v2/%s/blobs/%s", path, templateDigest)
-	logging.DebugLogger.Printf("Fetching template from URL: %s", templateURL)
-	req, err = http.NewRequest("GET", templateURL, nil)
+// getRegistryBlob returns the content of a blob in the registry
+func getRegistryBlob(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Accept", "application/vnd.ollama.image.template")
-	resp, err = httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch template: status %d", resp.StatusCode)
+		return "", fmt.Errorf("registry returned %s", resp.Status)
 	}
-	templateBody, err := io.ReadAll(resp.Body)
-	if err != nil {
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
 
-		return "", fmt.Errorf("error reading template body: %v", err)
+// compareModelfiles returns the differences between a model's values and the registry's, the template first, then
+// the system prompt and the parameters by name. Added values are only in the registry and removed ones only local.
+func compareModelfiles(local, registry map[string][]string) []ModelfileDiff {
+	fields := make(map[string]bool)
+	for field := range local {
+		fields[field] = true
 	}
-	logging.DebugLogger.Printf("Template response: %s", string(templateBody))
-
-	// Fetch the PARAMETER layer
-	paramsURL := fmt.Sprintf("https://registry.ollama.ai/v2/%s/blobs/%s", path, paramsDigest)
-	logging.DebugLogger.Printf("Fetching parameters from URL: %s", paramsURL)
-	req, err = http.NewRequest("GET", paramsURL, nil)
-	if err != nil {
-		return "", err
+	for field := range registry {
+		fields[field] = true
 	}
-	req.Header.Set("Accept", "application/vnd.ollama.image.params")
-	resp, err = httpClient.Do(req)
-	if err != nil {
-		return "", err
+	order := func(field string) int {
+		switch field {
+		case "template":
+			return 0
+		case "system":
+			return 1
+		}
+		return 2
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch parameters: status %d", resp.StatusCode)
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
 	}
-	paramsBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading parameters body: %v", err)
+	sort.Slice(names, func(i, j int) bool {
+		if order(names[i]) != order(names[j]) {
+			return order(names[i]) < order(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	var diffs []ModelfileDiff
+	for _, field := range names {
+		current, latest := strings.Join(local[field], "\n"), strings.Join(registry[field], "\n")
+		if current == latest {
+			continue
+		}
+		diff := ModelfileDiff{Command: modelfileCommand(field), Current: current, Latest: latest, Type: "modified"}
+		switch {
+		case len(local[field]) == 0:
+			diff.Type = "added"
+		case len(registry[field]) == 0:
+			diff.Type = "removed"
+		}
+		diffs = append(diffs, diff)
 	}
-	logging.DebugLogger.Printf("Parameters response: %s", string(paramsBody))
+	return diffs
+}
 
-	// Parse the PARAMETER layer
-	var params map[string]interface{}
-	if err := json.Unmarshal(paramsBody, &params); err != nil {
-		return "", fmt.Errorf("error decoding parameters: %v", err)
+// modelfileCommand is the modelfile instruction setting a field, e.g. TEMPLATE or PARAMETER num_ctx
+func modelfileCommand(field string) string {
+	switch field {
+	case "template", "system":
+		return strings.ToUpper(field)
 	}
+	return "PARAMETER " + field
+}
 
-	// Construct the modelfile content
-	var modelfile strings.Builder
-	modelfile.WriteString(fmt.Sprintf("TEMPLATE \"\"\"%s\"\"\"\n", templateBody))
-	for key, value := range params {
-		switch v := value.(type) {
-		case string:
-			modelfile.WriteString(fmt.Sprintf("PARAMETER %s %s\n", key, v))
-		case []interface{}:
-			for _, item := range v {
-				modelfile.WriteString(fmt.Sprintf("PARAMETER %s %s\n", key, item))
-			}
-		default:
-			logging.DebugLogger.Printf("Unknown type for parameter %s: %v", key, value)
-		}
+func (m *AppModel) handleCompareModelfile() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("CompareModelfile key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Comparing %s with the registry...", item.Name)
+	client := m.client
+	return m, func() tea.Msg {
+		return compareWithRegistry(context.Background(), client, &http.Client{Timeout: registryTimeout}, registryURL, item.Name)
 	}
-
-	return modelfile.String(), nil
 }
 
-func compareModelfiles(currentTemplate string, currentParams map[string]string, latestTemplate string, latestParams map[string]string) []ModelfileDiff {
-	diffs := []ModelfileDiff{}
-
-	// Compare TEMPLATE
-	if currentTemplate != latestTemplate {
-		diffs = append(diffs, ModelfileDiff{
-			Command: "TEMPLATE",
-			Current: currentTemplate,
-			Latest:  latestTemplate,
-			Type:    "modified",
-		})
+// compareWithRegistry compares a model's modelfile with the one the registry ships for the same name
+func compareWithRegistry(ctx context.Context, client *api.Client, registryClient *http.Client, baseURL, modelName string) modelfileCompareMsg {
+	if !isRegistryModel(modelName) {
+		return modelfileCompareMsg{model: modelName, note: modelName + " isn't from the public registry, so there's nothing to compare it with"}
 	}
-
-	// Compare PARAMETERs
-	for cmd, latestValue := range latestParams {
-		if currentValue, exists := currentParams[cmd]; exists {
-			if currentValue != latestValue {
-				diffs = append(diffs, ModelfileDiff{
-					Command: cmd,
-					Current: currentValue,
-					Latest:  latestValue,
-					Type:    "modified",
-				})
-			}
-		} else {
-			diffs = append(diffs, ModelfileDiff{
-				Command: cmd,
-				Latest:  latestValue,
-				Type:    "added",
-			})
-		}
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return modelfileCompareMsg{model: modelName, err: fmt.Errorf("error fetching the modelfile of %s: %w", modelName, err)}
 	}
-
-	// Check for removed PARAMETERs
-	for cmd, currentValue := range currentParams {
-		if _, exists := latestParams[cmd]; !exists {
-			diffs = append(diffs, ModelfileDiff{
-				Command: cmd,
-				Current: currentValue,
-				Type:    "removed",
-			})
-		}
+	registry, err := fetchRegistryModelfileValues(ctx, registryClient, baseURL, modelName)
+	if errors.Is(err, errNotInRegistry) {
+		return modelfileCompareMsg{model: modelName, note: modelName + " isn't in the public registry, it may be a custom or renamed model"}
 	}
-
-	return diffs
+	if err != nil {
+		return modelfileCompareMsg{model: modelName, err: fmt.Errorf("error fetching %s from the registry: %w", modelName, err)}
+	}
+	return modelfileCompareMsg{model: modelName, diffs: compareModelfiles(modelfileValues(show.Modelfile), registry)}
 }
 
-func (m *AppModel) handleCompareModelfile() (tea.Model, tea.Cmd) {
-	if item, ok := m.list.SelectedItem().(Model); ok {
-		// Get current modelfile
-		_, err := m.client.Show(context.Background(), &api.ShowRequest{Name: item.Name})
-		if err != nil {
-			m.message = fmt.Sprintf("Error fetching current modelfile: %v", err)
-			return m, nil
-		}
-		currentParams, currentTemplate, err := getModelParams(item.Name, m.client)
-		if err != nil {
-			m.message = fmt.Sprintf("Error parsing current modelfile: %v", err)
-			return m, nil
-		}
-
-		// Get latest modelfile
-		latestModelfile, err := fetchLatestModelfile(item.Name)
-		if err != nil {
-			if strings.Contains(err.Error(), "failed to fetch") {
-				m.message = fmt.Sprintf("Model '%s' not found in public registry - it might be a private or custom model", item.Name)
-			} else {
-				m.message = fmt.Sprintf("Error fetching latest modelfile: %v", err)
-			}
-			return m, nil
-		}
-
-		// Parse the latest modelfile to extract TEMPLATE and PARAMETERs
-		latestLines := strings.Split(latestModelfile, "\n")
-		var latestTemplate string
-		latestParams := make(map[string]string)
-
-		for _, line := range latestLines {
-			if strings.HasPrefix(line, "TEMPLATE") {
-				latestTemplate = strings.TrimPrefix(line, "TEMPLATE ")
-				latestTemplate = strings.Trim(latestTemplate, "\"")
-			} else if strings.HasPrefix(line, "PARAMETER") {
-				parts := strings.SplitN(line, " ", 2)
-				if len(parts) == 2 {
-					key := parts[1]
-					value := strings.TrimSpace(parts[1])
-					latestParams[key] = value
-				}
-			}
-		}
+func (m *AppModel) handleModelfileCompareMsg(msg modelfileCompareMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Println(msg.err)
+		m.message = msg.err.Error()
+		return m, nil
+	}
+	m.message = ""
+	m.comparingModelfile = true
+	m.modelfileCompare = msg
+	return m, nil
+}
 
-		// Compare modelfiles
-		m.modelfileDiffs = compareModelfiles(currentTemplate, currentParams, latestTemplate, latestParams)
-		if len(m.modelfileDiffs) == 0 {
-			m.message = "No differences found between local and registry modelfiles"
-			return m, nil
-		}
-		m.comparingModelfile = true
+// handleModelfileCompareKey closes the comparison with q or esc
+func (m *AppModel) handleModelfileCompareKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.comparingModelfile = false
 	}
 	return m, nil
 }
 
+// modelfileDiffView shows the differences as a unified diff, the local values marked - and the registry's +
 func (m *AppModel) modelfileDiffView() string {
 	if !m.comparingModelfile {
 		return ""
 	}
 
-	// Define styles using the application's colour scheme
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FF00FF")).
-		MarginBottom(1).
-		Padding(0, 1)
-
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FFFFFF")).
-		Background(lipgloss.Color("#333333")).
-		Padding(0, 1)
-
-	commandStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#9932CC")).
-		Padding(0, 1)
-
-	localStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#60BFFF")).
-		Padding(0, 1)
-
-	remoteStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00CED1")).
-		Padding(0, 1)
-
-	modifiedLocalStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFFF00")).
-		Padding(0, 1)
-
-	modifiedRemoteStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFA500")).
-		Padding(0, 1)
-
-	addedStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FF00")).
-		Padding(0, 1)
-
-	removedStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF0000")).
-		Padding(0, 1)
-
-	// Calculate column widths
-	commandWidth := 20
-	valueWidth := 30
-
-	for _, diff := range m.modelfileDiffs {
-		commandLen := len(diff.Command)
-		if commandLen > commandWidth {
-			commandWidth = commandLen
-		}
-		currentLen := len(diff.Current)
-		latestLen := len(diff.Latest)
-		if currentLen > valueWidth {
-			valueWidth = currentLen
-		}
-		if latestLen > valueWidth {
-			valueWidth = latestLen
-		}
-	}
-
-	// Build the table
-	var rows []string
+	colours := styles.Current().Colours
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.Title.TerminalColour())
+	addedStyle := lipgloss.NewStyle().Foreground(colours.CompareAdded.TerminalColour())
+	removedStyle := lipgloss.NewStyle().Foreground(colours.CompareRemoved.TerminalColour())
+	modifiedStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.CompareModified.TerminalColour())
+	faintStyle := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
 
-	// Add header
-	header := lipgloss.JoinHorizontal(lipgloss.Left,
-		headerStyle.Width(commandWidth).Render("Command"),
-		headerStyle.Width(valueWidth).Render("Local Value"),
-		headerStyle.Width(valueWidth).Render("Remote Value"),
-	)
-
-	rows = append(rows, header)
-
-	// Add separator
-	separator := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#333333")).
-		Render(strings.Repeat("─", commandWidth+valueWidth*2+2))
-	rows = append(rows, separator)
-
-	// Add data rows
-	for _, diff := range m.modelfileDiffs {
-		var current, latest string
-		var currentStyle, latestStyle lipgloss.Style
-
-		switch diff.Type {
-		case "modified":
-			currentStyle = modifiedLocalStyle
-			latestStyle = modifiedRemoteStyle
-			current = diff.Current
-			latest = diff.Latest
-		case "added":
-			currentStyle = localStyle
-			latestStyle = addedStyle
-			current = "undefined"
-			latest = diff.Latest
-		case "removed":
-			currentStyle = localStyle
-			latestStyle = removedStyle
-			current = diff.Current
-			latest = "undefined"
-		default:
-			currentStyle = localStyle
-			latestStyle = remoteStyle
-			current = diff.Current
-			latest = diff.Latest
+	compare := m.modelfileCompare
+	var b strings.Builder
+	b.WriteString("\n" + titleStyle.Render(fmt.Sprintf("%s compared with the registry", compare.model)) + "\n\n")
+	switch {
+	case compare.note != "":
+		b.WriteString(compare.note + "\n")
+	case len(compare.diffs) == 0:
+		b.WriteString("The template, system prompt and parameters match the registry\n")
+	default:
+		b.WriteString(faintStyle.Render("- local   + registry") + "\n")
+		for _, diff := range compare.diffs {
+			header := map[string]string{"modified": "~ ", "added": "+ ", "removed": "- "}[diff.Type] + diff.Command
+			b.WriteString("\n" + modifiedStyle.Render(header) + "\n")
+			if diff.Current != "" {
+				for _, line := range strings.Split(diff.Current, "\n") {
+					b.WriteString(removedStyle.Render("- "+line) + "\n")
+				}
+			}
+			if diff.Latest != "" {
+				for _, line := range strings.Split(diff.Latest, "\n") {
+					b.WriteString(addedStyle.Render("+ "+line) + "\n")
+				}
+			}
 		}
-
-		row := lipgloss.JoinHorizontal(lipgloss.Left,
-			commandStyle.Width(commandWidth).Render(diff.Command),
-			currentStyle.Width(valueWidth).Render(current),
-			latestStyle.Width(valueWidth).Render(latest),
-		)
-		rows = append(rows, row)
 	}
-
-	// Build the final view
-	var b strings.Builder
-	b.WriteString(titleStyle.Render("Modelfile Comparison"))
-	b.WriteString("\n\n")
-	b.WriteString(strings.Join(rows, "\n"))
-	b.WriteString("\n\n")
-	b.WriteString(lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
-		Render("Press 'q' or 'esc' to return to the main view"))
-
+	b.WriteString("\n" + faintStyle.Render("Press 'q' or 'esc' to return to the main view"))
 	return b.String()
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+)
+
+func TestCompareWithRegistry(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/library/llama3/manifests/8b":
+			json.NewEncoder(w).Encode(modelManifest{Layers: []manifestLayer{
+				{MediaType: "application/vnd.ollama.image.model", Digest: "sha256:model"},
+				{MediaType: templateMediaType, Digest: "sha256:template"},
+				{MediaType: paramsMediaType, Digest: "sha256:params"},
+			}})
+		case "/v2/library/llama3/blobs/sha256:template":
+			io.WriteString(w, "{{ .System }}\n{{ .Prompt }}")
+		case "/v2/library/llama3/blobs/sha256:params":
+			io.WriteString(w, `{"stop": ["<|eot_id|>", "<|end|>"], "num_ctx": 8192, "temperature": 0.6}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM /blobs/sha256-model\nTEMPLATE \"\"\"{{ .Prompt }}\"\"\"\nSYSTEM \"You are terse\"\nPARAMETER stop <|eot_id|>\nPARAMETER stop <|end|>\nPARAMETER num_ctx 16384\n"})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	client := api.NewClient(u, ollama.Client())
+
+	msg := compareWithRegistry(context.Background(), client, registry.Client(), registry.URL, "llama3:8b")
+	if msg.err != nil || msg.note != "" {
+		t.Fatalf("compareWithRegistry() error = %v, note %q", msg.err, msg.note)
+	}
+	want := []ModelfileDiff{
+		{Command: "TEMPLATE", Current: "{{ .Prompt }}", Latest: "{{ .System }}\n{{ .Prompt }}", Type: "modified"},
+		{Command: "SYSTEM", Current: "You are terse", Type: "removed"},
+		{Command: "PARAMETER num_ctx", Current: "16384", Latest: "8192", Type: "modified"},
+		{Command: "PARAMETER temperature", Latest: "0.6", Type: "added"},
+	}
+	if !reflect.DeepEqual(msg.diffs, want) {
+		t.Errorf("diffs = %+v, want %+v", msg.diffs, want)
+	}
+
+	m := &AppModel{}
+	m.handleModelfileCompareMsg(msg)
+	view := m.modelfileDiffView()
+	for _, line := range []string{"~ TEMPLATE", "+ {{ .System }}", "- You are terse", "+ PARAMETER temperature"} {
+		if !strings.Contains(view, line) {
+			t.Errorf("diff view doesn't show %q:\n%s", line, view)
+		}
+	}
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.comparingModelfile {
+		t.Error("esc didn't close the comparison")
+	}
+
+	// Models that aren't from the public registry are explained rather than treated as errors
+	for _, name := range []string{"registry.internal:5000/team/llama3:8b", "my-custom-model:latest"} {
+		msg := compareWithRegistry(context.Background(), client, registry.Client(), registry.URL, name)
+		if msg.err != nil || !strings.Contains(msg.note, "public registry") {
+			t.Errorf("compareWithRegistry(%s) = %v, %q, want a note that it isn't in the public registry", name, msg.err, msg.note)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnNames are the columns the model list can show
+var ColumnNames = []string{"name", "size", "params", "quant", "family", "context", "modified", "id"}
+
+// Column is a column of the model list and its width in characters, 0 sizes it to the terminal
+type Column struct {
+	Name  string
+	Width int
+}
+
+// ParseColumns reads the columns setting, a list of column names each optionally followed by ":auto" or ":<width>",
+// e.g. ["name:40", "size", "quant:auto"]. Names are case-insensitive and an empty list is the default layout.
+func ParseColumns(specs []string) ([]Column, error) {
+	if len(specs) == 0 {
+		specs = defaultConfig.Columns
+	}
+	columns := make([]Column, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		name, width, hasWidth := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), ":")
+		if !isColumnName(name) {
+			return nil, fmt.Errorf("unknown column %q in columns, use %s", spec, strings.Join(ColumnNames, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %s is listed more than once in columns", name)
+		}
+		seen[name] = true
+
+		column := Column{Name: name}
+		if hasWidth && width != "auto" {
+			n, err := strconv.Atoi(width)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid width %q for column %s in columns, use auto or a number of characters", width, name)
+			}
+			column.Width = n
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func isColumnName(name string) bool {
+	for _, column := range ColumnNames {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -12,68 +19,184 @@ import (
 )
 
 type Config struct {
-	Columns           []string `mapstructure:"columns"`
-	OllamaAPIKey      string   `mapstructure:"ollama_api_key"`
-	OllamaAPIURL      string   `mapstructure:"ollama_api_url"`
-	LMStudioFilePaths string   `mapstructure:"lm_studio_file_paths"`
-	LogLevel          string   `mapstructure:"log_level"`
-	LogFilePath       string   `mapstructure:"log_file_path"`
-	SortOrder         string   `mapstructure:"sort_order"`   // Current sort order
-	StripString       string   `mapstructure:"strip_string"` // Optional string to strip from model names in the TUI (e.g. a private registry URL)
-	Editor            string   `mapstructure:"editor"`
-	DockerContainer   string   `mapstructure:"docker_container"` // Optionally specify a docker container to run the ollama commands in
-	modified          bool     // Internal flag to track if the config has been modified
+	Columns             []string              `mapstructure:"columns"`            // Columns of the model list in order, each optionally with ":auto" or ":<width>"
+	OllamaAPIKey        string                `mapstructure:"ollama_api_key"`     // Sent as a bearer token to the Ollama API, e.g. behind an authenticating proxy
+	OllamaAuthHeader    string                `mapstructure:"ollama_auth_header"` // Authorization header sent to the Ollama API instead of the key (e.g. "Basic dXNlcjpwYXNz")
+	OllamaTLSCACert     string                `mapstructure:"ollama_tls_ca_cert"` // Path to a PEM CA certificate to trust for an https Ollama API
+	OllamaAPIURL        string                `mapstructure:"ollama_api_url"`
+	LMStudioFilePaths   string                `mapstructure:"lm_studio_file_paths"`
+	OllamaModelsDir     string                `mapstructure:"ollama_models_dir"` // Models directory of the local Ollama server, cached when it's detected
+	LogLevel            string                `mapstructure:"log_level"`
+	LogFilePath         string                `mapstructure:"log_file_path"`
+	LogMaxSizeMB        int                   `mapstructure:"log_max_size_mb"`  // Size the log file is rotated at
+	LogMaxBackups       int                   `mapstructure:"log_max_backups"`  // Number of rotated log files kept
+	LogMaxAgeDays       int                   `mapstructure:"log_max_age_days"` // Days rotated log files are kept for
+	SortOrder           string                `mapstructure:"sort_order"`       // Current sort order
+	SortDirection       string                `mapstructure:"sort_direction"`   // "asc" or "desc", empty for the sort order's default
+	StripString         string                `mapstructure:"strip_string"`     // Optional string to strip from model names in the TUI (e.g. a private registry URL)
+	Editor              string                `mapstructure:"editor"`
+	DockerContainer     string                `mapstructure:"docker_container"`      // Optionally specify a docker container to run the ollama commands in
+	RunCommandTemplate  string                `mapstructure:"run_command_template"`  // Command models are run with, {{model}} is replaced with the model's name
+	RunExternal         string                `mapstructure:"run_external"`          // Where models are run: "suspend", "tmux", "kitty", "wezterm" or a template with {{command}}
+	GroupBy             string                `mapstructure:"group_by"`              // Group the model list, "family" or empty for a flat list
+	DefaultView         string                `mapstructure:"default_view"`          // The view shown when the TUI starts ("main" or "dashboard")
+	Theme               string                `mapstructure:"theme"`                 // Name of a built-in theme or a theme file in the themes directory
+	Hosts               map[string]string     `mapstructure:"hosts"`                 // Other Ollama hosts by name (e.g. "server": "http://server:11434") to compare models with
+	ShowContextLength   bool                  `mapstructure:"show_context_length"`   // Show each model's native context length in the list, fetched in the background
+	DefaultPushRegistry string                `mapstructure:"default_push_registry"` // Registry prefix last pushed to (e.g. registry.internal:5000/team), offered when pushing
+	DeletePushCopy      bool                  `mapstructure:"delete_push_copy"`      // Delete the copy made to push a model to another registry once it's pushed
+	RemoteHosts         []string              `mapstructure:"remote_hosts"`          // Ollama host URLs models were last copied to, most recent first
+	UnloadBeforeDelete  bool                  `mapstructure:"unload_before_delete"`  // Unload models that are running before deleting them
+	ProgressRefreshMs   int                   `mapstructure:"progress_refresh_ms"`   // How often pull and push progress bars are redrawn, in milliseconds
+	ConfirmRepull       bool                  `mapstructure:"confirm_repull"`        // Ask before pulling a model that already exists locally
+	CleanupReportOnly   bool                  `mapstructure:"cleanup_report_only"`   // Only log the broken symlinks found after linking rather than removing them
+	RunProfiles         map[string]RunProfile `mapstructure:"run_profiles"`          // Arguments and environment to run models with, by model name or glob (e.g. "qwen2*")
+	modified            bool                  // Internal flag to track if the config has been modified
 }
 
 var defaultConfig = Config{
-	Columns:           []string{"Name", "Size", "Quant", "Family", "Modified", "ID"},
-	OllamaAPIKey:      "",
-	OllamaAPIURL:      getAPIUrl(),
-	LMStudioFilePaths: "",
-	LogLevel:          "info",
-	SortOrder:         "modified",
-	StripString:       "",
-	Editor:            "/usr/bin/vim",
-	DockerContainer:   "",
-}
-
-// getAPIUrl determines the API URL based on environment variables.
-func getAPIUrl() string {
-	if apiUrl := os.Getenv("OLLAMA_API_URL"); apiUrl != "" {
-		return apiUrl
+	Columns:             []string{"Name", "Size", "Quant", "Family", "Modified", "ID"},
+	OllamaAPIKey:        "",
+	OllamaAuthHeader:    "",
+	OllamaTLSCACert:     "",
+	OllamaAPIURL:        DefaultAPIURL,
+	LMStudioFilePaths:   "",
+	OllamaModelsDir:     "",
+	LogLevel:            "info",
+	LogMaxSizeMB:        2,
+	LogMaxBackups:       3,
+	LogMaxAgeDays:       60,
+	SortOrder:           "modified",
+	SortDirection:       "",
+	StripString:         "",
+	Editor:              "/usr/bin/vim",
+	DockerContainer:     "",
+	RunCommandTemplate:  "",
+	RunExternal:         "suspend",
+	GroupBy:             "",
+	DefaultView:         "main",
+	Theme:               "default",
+	Hosts:               map[string]string{},
+	ShowContextLength:   false,
+	DefaultPushRegistry: "",
+	DeletePushCopy:      true,
+	RemoteHosts:         []string{},
+	UnloadBeforeDelete:  true,
+	ProgressRefreshMs:   250,
+	ConfirmRepull:       true,
+	CleanupReportOnly:   false,
+	RunProfiles:         map[string]RunProfile{},
+}
+
+// DefaultAPIURL is the Ollama API URL used when neither the flags, the config file nor the environment set one
+const DefaultAPIURL = "http://127.0.0.1:11434"
+
+// NormaliseHost turns an OLLAMA_HOST style value (host, host:port, :port or a URL) into an API URL.
+// The scheme defaults to http and the port to 11434, or 443 for https.
+func NormaliseHost(host string) string {
+	host = strings.TrimSpace(host)
+	scheme := "http"
+	if i := strings.Index(host, "://"); i >= 0 {
+		scheme, host = strings.ToLower(host[:i]), host[i+3:]
 	}
-	if host := os.Getenv("OLLAMA_HOST"); host != "" {
-		// Check if the host already starts with http:// or https://
-		if len(host) >= 7 && host[:7] == "http://" || len(host) >= 8 && host[:8] == "https://" {
-			return host
+	host, path, _ := strings.Cut(host, "/")
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// No port, the whole value is the host name
+		hostname, port = strings.Trim(host, "[]"), ""
+	}
+	if hostname == "" {
+		hostname = "127.0.0.1"
+	}
+	if port == "" {
+		port = "11434"
+		if scheme == "https" {
+			port = "443"
 		}
-		// If not, prepend http://
-		return "http://" + host
 	}
-	return "http://127.0.0.1:11434"
+
+	normalised := scheme + "://" + net.JoinHostPort(hostname, port)
+	if path = strings.TrimSuffix(path, "/"); path != "" {
+		normalised += "/" + path
+	}
+	return normalised
 }
 
-func CreateDefaultConfig() error {
-	// Set default values
-	viper.SetDefault("columns", defaultConfig.Columns)
-	viper.SetDefault("ollama_api_key", defaultConfig.OllamaAPIKey)
-	viper.SetDefault("ollama_api_url", defaultConfig.OllamaAPIURL)
-	viper.SetDefault("lm_studio_file_paths", defaultConfig.LMStudioFilePaths)
-	viper.SetDefault("log_level", defaultConfig.LogLevel)
-	viper.SetDefault("log_file_path", defaultConfig.LogFilePath)
-	viper.SetDefault("sort_order", defaultConfig.SortOrder)
-	viper.SetDefault("strip_string", defaultConfig.StripString)
-	viper.SetDefault("editor", defaultConfig.Editor)
-	viper.SetDefault("docker_container", defaultConfig.DockerContainer)
+// isDefaultAPIURL reports whether url is the default local API URL, which the config file holds unless it was changed
+func isDefaultAPIURL(url string) bool {
+	switch strings.TrimSuffix(url, "/") {
+	case "", DefaultAPIURL, "http://localhost:11434":
+		return true
+	}
+	return false
+}
+
+// ResolveAPIURL returns the API URL to use and where it came from. The -h flag wins, then a value set in the
+// config file, then OLLAMA_API_URL and OLLAMA_HOST, then the default.
+func ResolveAPIURL(flagValue, configValue string) (string, string) {
+	if flagValue != "" {
+		return NormaliseHost(flagValue), "the -h flag"
+	}
+	if !isDefaultAPIURL(configValue) {
+		return configValue, "the config file"
+	}
+	if apiURL := os.Getenv("OLLAMA_API_URL"); apiURL != "" {
+		return NormaliseHost(apiURL), "OLLAMA_API_URL"
+	}
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		return NormaliseHost(host), "OLLAMA_HOST"
+	}
+	return DefaultAPIURL, "the default"
+}
 
+func CreateDefaultConfig() error {
+	setDefaults(viper.GetViper())
 	return SaveConfig(defaultConfig)
 }
 
+// setDefaults registers the default values for every config key on v so missing keys in an existing file still load
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("columns", defaultConfig.Columns)
+	v.SetDefault("ollama_api_key", defaultConfig.OllamaAPIKey)
+	v.SetDefault("ollama_auth_header", defaultConfig.OllamaAuthHeader)
+	v.SetDefault("ollama_tls_ca_cert", defaultConfig.OllamaTLSCACert)
+	v.SetDefault("ollama_api_url", defaultConfig.OllamaAPIURL)
+	v.SetDefault("lm_studio_file_paths", defaultConfig.LMStudioFilePaths)
+	v.SetDefault("ollama_models_dir", defaultConfig.OllamaModelsDir)
+	v.SetDefault("log_level", defaultConfig.LogLevel)
+	v.SetDefault("log_file_path", defaultConfig.LogFilePath)
+	v.SetDefault("log_max_size_mb", defaultConfig.LogMaxSizeMB)
+	v.SetDefault("log_max_backups", defaultConfig.LogMaxBackups)
+	v.SetDefault("log_max_age_days", defaultConfig.LogMaxAgeDays)
+	v.SetDefault("sort_order", defaultConfig.SortOrder)
+	v.SetDefault("sort_direction", defaultConfig.SortDirection)
+	v.SetDefault("strip_string", defaultConfig.StripString)
+	v.SetDefault("editor", defaultConfig.Editor)
+	v.SetDefault("docker_container", defaultConfig.DockerContainer)
+	v.SetDefault("run_command_template", defaultConfig.RunCommandTemplate)
+	v.SetDefault("run_external", defaultConfig.RunExternal)
+	v.SetDefault("group_by", defaultConfig.GroupBy)
+	v.SetDefault("default_view", defaultConfig.DefaultView)
+	v.SetDefault("theme", defaultConfig.Theme)
+	v.SetDefault("hosts", defaultConfig.Hosts)
+	v.SetDefault("show_context_length", defaultConfig.ShowContextLength)
+	v.SetDefault("default_push_registry", defaultConfig.DefaultPushRegistry)
+	v.SetDefault("delete_push_copy", defaultConfig.DeletePushCopy)
+	v.SetDefault("remote_hosts", defaultConfig.RemoteHosts)
+	v.SetDefault("unload_before_delete", defaultConfig.UnloadBeforeDelete)
+	v.SetDefault("progress_refresh_ms", defaultConfig.ProgressRefreshMs)
+	v.SetDefault("confirm_repull", defaultConfig.ConfirmRepull)
+	v.SetDefault("cleanup_report_only", defaultConfig.CleanupReportOnly)
+	v.SetDefault("run_profiles", defaultConfig.RunProfiles)
+}
+
 func LoadConfig() (Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("json")
 	// Dir of config file
 	viper.AddConfigPath(utils.GetConfigDir())
+	setDefaults(viper.GetViper())
 
 	// Read the config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -104,31 +227,129 @@ func LoadConfig() (Config, error) {
 	}
 
 	var config Config
-	config.OllamaAPIURL = viper.GetString("ollama_api_url")
-	config.LogLevel = viper.GetString("log_level")
+	if err := viper.Unmarshal(&config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := validate(config); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// validate checks the settings gollama can't run with, for both LoadConfig and Watch
+func validate(config Config) error {
+	if err := ValidateRunCommandTemplate(config.RunCommandTemplate); err != nil {
+		return err
+	}
+	if _, err := ParseColumns(config.Columns); err != nil {
+		return err
+	}
+	return ValidateRunExternal(config.RunExternal)
+}
+
+// lastWrite is the hash of the config file as gollama last wrote it, so Watch can ignore gollama's own changes
+var (
+	lastWriteMu sync.Mutex
+	lastWrite   [sha256.Size]byte
+)
+
+func rememberWrite(data []byte) {
+	lastWriteMu.Lock()
+	lastWrite = sha256.Sum256(data)
+	lastWriteMu.Unlock()
+}
 
+// writtenByGollama reports whether data is what gollama last wrote to the config file
+func writtenByGollama(data []byte) bool {
+	lastWriteMu.Lock()
+	defer lastWriteMu.Unlock()
+	return sha256.Sum256(data) == lastWrite
+}
+
+// Watch calls fn with the previous and new config whenever the config file is changed by something other than gollama,
+// e.g. the user editing it while the TUI is running. A change LoadConfig would refuse is passed as the error instead,
+// and the next change is compared with the last config that was used. gollama's own writes aren't passed to fn but are
+// what the next change is compared with. It must be called after LoadConfig and only once.
+func Watch(fn func(previous, current Config, err error)) {
+	var previous Config
+	if err := viper.Unmarshal(&previous); err != nil {
+		return
+	}
 	viper.OnConfigChange(func(e fsnotify.Event) {
-		fmt.Println("Config file changed:", e.Name)
+		data, err := os.ReadFile(utils.GetConfigPath())
+		if err != nil {
+			return
+		}
+		var current Config
+		if err := viper.Unmarshal(&current); err != nil {
+			fn(previous, Config{}, fmt.Errorf("failed to parse config: %w", err))
+			return
+		}
+		if writtenByGollama(data) {
+			previous = current
+			return
+		}
+		if err := validate(current); err != nil {
+			fn(previous, Config{}, err)
+			return
+		}
+		fn(previous, current, nil)
+		previous = current
 	})
 	viper.WatchConfig()
-
-	return config, nil
 }
 
+// SaveConfig writes the sort order when it's been modified, otherwise it creates the config file with the defaults
+// and fails when it already exists
 func SaveConfig(config Config) error {
 	if config.modified {
-		viper.Set("sort_order", config.SortOrder)
+		return SetValues(map[string]interface{}{"sort_order": config.SortOrder, "sort_direction": config.SortDirection})
+	}
+	configPath := utils.GetConfigPath()
+	if _, err := os.Stat(configPath); err == nil {
+		return fmt.Errorf("failed to write config file: %w", viper.ConfigFileAlreadyExistsError(configPath))
 	}
+	return writeConfig(nil)
+}
+
+// SetValue updates a single config key and writes the config file
+func SetValue(key string, value interface{}) error {
+	return SetValues(map[string]interface{}{key: value})
+}
 
+// SetValues updates several config keys and writes the config file once
+func SetValues(values map[string]interface{}) error {
+	return writeConfig(values)
+}
+
+// writeConfig writes the config file with values changed. It's read into its own viper instance so the values aren't
+// set as overrides on the global one, which would hide later edits to the file from Watch.
+func writeConfig(values map[string]interface{}) error {
 	configPath := utils.GetConfigPath()
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := viper.SafeWriteConfigAs(configPath); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("json")
+	setDefaults(v)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	for key, value := range values {
+		v.Set(key, value)
 	}
 
+	data, err := json.MarshalIndent(v.AllSettings(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	rememberWrite(data)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
 	return nil
 }
 
@@ -3,11 +3,17 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+	"github.com/spf13/viper"
 )
 
 func TestGenerateDefaultConfig(t *testing.T) {
@@ -244,3 +250,308 @@ func saveConfigToPath(path string, config Config) error {
 func generateDefaultConfig(path string) error {
 	return saveConfigToPath(path, defaultConfig)
 }
+
+func TestNormaliseHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"server", "http://server:11434"},
+		{"server:8080", "http://server:8080"},
+		{":11435", "http://127.0.0.1:11435"},
+		{"0.0.0.0", "http://0.0.0.0:11434"},
+		{"http://server:11434/", "http://server:11434"},
+		{"https://ollama.example.com", "https://ollama.example.com:443"},
+		{"HTTPS://ollama.example.com:8443/ollama", "https://ollama.example.com:8443/ollama"},
+		{"[::1]:11434", "http://[::1]:11434"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := NormaliseHost(tt.host); got != tt.expected {
+				t.Errorf("NormaliseHost(%q) = %q, want %q", tt.host, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveAPIURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		flagValue      string
+		configValue    string
+		ollamaHost     string
+		expectedURL    string
+		expectedSource string
+	}{
+		{"flag wins", "server:1234", "http://configured:11434", "envhost", "http://server:1234", "the -h flag"},
+		{"config value beats OLLAMA_HOST", "", "http://configured:11434", "envhost", "http://configured:11434", "the config file"},
+		{"OLLAMA_HOST beats the default config value", "", DefaultAPIURL, "envhost:5000", "http://envhost:5000", "OLLAMA_HOST"},
+		{"localhost config value counts as the default", "", "http://localhost:11434", "https://envhost", "https://envhost:443", "OLLAMA_HOST"},
+		{"default", "", DefaultAPIURL, "", DefaultAPIURL, "the default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OLLAMA_API_URL", "")
+			t.Setenv("OLLAMA_HOST", tt.ollamaHost)
+			gotURL, gotSource := ResolveAPIURL(tt.flagValue, tt.configValue)
+			if gotURL != tt.expectedURL || gotSource != tt.expectedSource {
+				t.Errorf("ResolveAPIURL(%q, %q) = %q, %q, want %q, %q", tt.flagValue, tt.configValue, gotURL, gotSource, tt.expectedURL, tt.expectedSource)
+			}
+		})
+	}
+}
+
+func TestWrittenByGollama(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SetValue("theme", "ocean"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(utils.GetConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !writtenByGollama(data) {
+		t.Error("writtenByGollama() = false for the file SetValue just wrote")
+	}
+	if writtenByGollama(append(data, '\n')) {
+		t.Error("writtenByGollama() = true for a file changed since gollama wrote it")
+	}
+}
+
+func TestSetValuesKeepsReloading(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValues(map[string]interface{}{"sort_order": "size", "theme": "ocean"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(utils.GetConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written map[string]interface{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written["sort_order"] != "size" || written["theme"] != "ocean" || written["editor"] != defaultConfig.Editor {
+		t.Errorf("written config = %v, want the new values alongside the rest", written)
+	}
+
+	// Editing the file afterwards changes what's read, the values gollama wrote aren't held as overrides
+	edited := strings.Replace(string(data), `"ocean"`, `"forest"`, 1)
+	if err := os.WriteFile(utils.GetConfigPath(), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var reloaded Config
+	if err := viper.Unmarshal(&reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Theme != "forest" {
+		t.Errorf("theme after editing the file = %q, want forest", reloaded.Theme)
+	}
+}
+
+func TestNewHTTPClientAuthorization(t *testing.T) {
+	var got []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+	})
+	api := httptest.NewServer(handler)
+	defer api.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	t.Setenv("OLLAMA_API_KEY", "from-env")
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "env", cfg: Config{}, want: "Bearer from-env"},
+		{name: "config key", cfg: Config{OllamaAPIKey: "secret"}, want: "Bearer secret"},
+		{name: "header", cfg: Config{OllamaAPIKey: "secret", OllamaAuthHeader: "Basic dXNlcjpwYXNz"}, want: "Basic dXNlcjpwYXNz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			tt.cfg.OllamaAPIURL = api.URL
+			client, err := NewHTTPClient(&tt.cfg, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range []string{api.URL, other.URL} {
+				resp, err := client.Get(u + "/api/tags")
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			// The credentials only go to the API host
+			if len(got) != 2 || got[0] != tt.want || got[1] != "" {
+				t.Errorf("Authorization headers = %q, want %q to the API only", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewHTTPClient(&Config{OllamaTLSCACert: filepath.Join(t.TempDir(), "missing.pem")}, 0); err == nil {
+		t.Error("NewHTTPClient() with a missing CA certificate didn't fail")
+	}
+	if err := AuthError(http.StatusUnauthorized); err == nil || !strings.Contains(err.Error(), "authentication") {
+		t.Errorf("AuthError(401) = %v", err)
+	}
+	if err := AuthError(http.StatusNotFound); err != nil {
+		t.Errorf("AuthError(404) = %v, want nil", err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected string
+	}{
+		{"ollama", Config{}, "ollama run qwen2:7b"},
+		{"docker container", Config{DockerContainer: "ollama"}, "docker exec -it ollama ollama run qwen2:7b"},
+		{"docker container false", Config{DockerContainer: "false"}, "ollama run qwen2:7b"},
+		{"template", Config{RunCommandTemplate: "docker compose -f /srv/ai/compose.yml exec ollama ollama run {{model}}"}, "docker compose -f /srv/ai/compose.yml exec ollama ollama run qwen2:7b"},
+		{"template over docker container", Config{DockerContainer: "ollama", RunCommandTemplate: "/opt/ollama/bin/ollama run {{model}}"}, "/opt/ollama/bin/ollama run qwen2:7b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(tt.cfg.RunCommand("qwen2:7b"), " "); got != tt.expected {
+				t.Errorf("RunCommand() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	for template, valid := range map[string]bool{
+		"": true,
+		"podman exec ollama ollama run {{model}}": true,
+		"ollama run":    false,
+		"{{model}} run": false,
+	} {
+		if err := ValidateRunCommandTemplate(template); (err == nil) != valid {
+			t.Errorf("ValidateRunCommandTemplate(%q) = %v, want valid %v", template, err, valid)
+		}
+	}
+
+	for value, valid := range map[string]bool{
+		"":                                 true,
+		"tmux":                             true,
+		"wezterm":                          true,
+		"alacritty -e {{command}}":         true,
+		"screen":                           false,
+		"{{command}}":                      false,
+		"tmux split-window {{command}}":    true,
+		`sh -c "{{command}}"`:              true,
+		`alacritty -e sh -c '{{command}}'`: true,
+		`"{{command}}" --new-window`:       false,
+		`sh -c '{{command}}`:               false,
+	} {
+		if err := ValidateRunExternal(value); (err == nil) != valid {
+			t.Errorf("ValidateRunExternal(%q) = %v, want valid %v", value, err, valid)
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		want     []TemplateWord
+	}{
+		{"alacritty -e {{command}}", []TemplateWord{{Text: "alacritty"}, {Text: "-e"}, {Text: "{{command}}"}}},
+		{`sh  -c '{{command}}'`, []TemplateWord{{Text: "sh"}, {Text: "-c"}, {Text: "{{command}}", Quoted: true}}},
+		{`xterm -T "a \"b\" \c"`, []TemplateWord{{Text: "xterm"}, {Text: "-T"}, {Text: `a "b" \c`, Quoted: true}}},
+		{`--title=it\'s ''`, []TemplateWord{{Text: "--title=it's"}, {Text: "", Quoted: true}}},
+	}
+	for _, tt := range tests {
+		got, err := SplitTemplate(tt.template)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitTemplate(%q) = %+v, %v, want %+v", tt.template, got, err, tt.want)
+		}
+	}
+	for _, template := range []string{`sh -c '{{command}}`, `sh -c "{{command}}`, `sh -c \`} {
+		if _, err := SplitTemplate(template); err == nil {
+			t.Errorf("SplitTemplate(%q) succeeded", template)
+		}
+	}
+}
+
+func TestRunProfiles(t *testing.T) {
+	profiles := map[string]RunProfile{
+		"qwen2*":           {Args: []string{"--verbose"}},
+		"qwen2:7b*":        {Args: []string{"--nowordwrap"}, Env: []string{"OLLAMA_NUM_PARALLEL=2"}},
+		"llama3":           {Env: []string{"OLLAMA_DEBUG=1"}},
+		"*":                {Args: []string{"--keepalive", "5m"}},
+		"mistral:7b-q4_0":  {Args: []string{"--insecure"}},
+		"mistral:7b-q4_0*": {Args: []string{"--verbose"}},
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		model string
+		key   string
+		argv  string
+		env   string
+	}{
+		{"longest glob wins", Config{RunProfiles: profiles}, "qwen2:7b-instruct", "qwen2:7b*", "ollama run qwen2:7b-instruct --nowordwrap", "OLLAMA_NUM_PARALLEL=2"},
+		{"shorter glob", Config{RunProfiles: profiles}, "qwen2:72b", "qwen2*", "ollama run qwen2:72b --verbose", ""},
+		{"name without a tag is latest", Config{RunProfiles: profiles}, "llama3:latest", "llama3", "ollama run llama3:latest", "OLLAMA_DEBUG=1"},
+		{"name beats a longer glob", Config{RunProfiles: profiles}, "mistral:7b-q4_0", "mistral:7b-q4_0", "ollama run mistral:7b-q4_0 --insecure", ""},
+		{"catch all glob", Config{RunProfiles: profiles}, "phi3:mini", "*", "ollama run phi3:mini --keepalive 5m", ""},
+		{"docker passes the environment with -e", Config{DockerContainer: "ollama", RunProfiles: profiles}, "qwen2:7b", "qwen2:7b*", "docker exec -e OLLAMA_NUM_PARALLEL=2 -it ollama ollama run qwen2:7b --nowordwrap", ""},
+		{"template keeps the environment", Config{RunCommandTemplate: "podman exec ollama ollama run {{model}}", RunProfiles: profiles}, "llama3", "llama3", "podman exec ollama ollama run llama3", "OLLAMA_DEBUG=1"},
+		{"no profiles", Config{}, "qwen2:7b", "", "ollama run qwen2:7b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, key := tt.cfg.RunProfile(tt.model)
+			if key != tt.key {
+				t.Errorf("RunProfile() key = %q, want %q", key, tt.key)
+			}
+			args, env := tt.cfg.RunCommandWith(tt.model, profile)
+			if got := strings.Join(args, " "); got != tt.argv {
+				t.Errorf("RunCommandWith() argv = %q, want %q", got, tt.argv)
+			}
+			if got := strings.Join(env, " "); got != tt.env {
+				t.Errorf("RunCommandWith() env = %q, want %q", got, tt.env)
+			}
+		})
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns([]string{"Name:40", "size", "params:auto", "QUANT:8"})
+	if err != nil {
+		t.Fatalf("ParseColumns() error = %v", err)
+	}
+	want := []Column{{"name", 40}, {"size", 0}, {"params", 0}, {"quant", 8}}
+	if fmt.Sprint(columns) != fmt.Sprint(want) {
+		t.Errorf("ParseColumns() = %v, want %v", columns, want)
+	}
+
+	// No columns is the default layout
+	columns, err = ParseColumns(nil)
+	if err != nil || len(columns) != len(defaultConfig.Columns) || columns[0].Name != "name" || columns[len(columns)-1].Name != "id" {
+		t.Errorf("ParseColumns(nil) = %v, %v, want the default layout", columns, err)
+	}
+
+	for specs, want := range map[string]string{
+		"nmae":        `unknown column "nmae" in columns, use name, size, params`,
+		"size,size":   "more than once",
+		"name:wide":   `invalid width "wide" for column name`,
+		"modified:-3": `invalid width "-3" for column modified`,
+	} {
+		if _, err := ParseColumns(strings.Split(specs, ",")); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseColumns(%q) error = %v, want %q", specs, err, want)
+		}
+	}
+}
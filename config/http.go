@@ -0,0 +1,86 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// APIAuthorization returns the Authorization header sent to the Ollama API: ollama_auth_header as is (e.g. "Basic
+// dXNlcjpwYXNz"), otherwise ollama_api_key or the OLLAMA_API_KEY environment variable as a bearer token. It's empty
+// when none are set.
+func (c *Config) APIAuthorization() string {
+	if c.OllamaAuthHeader != "" {
+		return c.OllamaAuthHeader
+	}
+	key := c.OllamaAPIKey
+	if key == "" {
+		key = os.Getenv("OLLAMA_API_KEY")
+	}
+	if key == "" {
+		return ""
+	}
+	return "Bearer " + key
+}
+
+// authTransport adds the Authorization header to requests to the Ollama API host, and only that host so the
+// credentials are never sent to the registry or other hosts
+type authTransport struct {
+	host          string
+	authorization string
+	base          http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", t.authorization)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// NewHTTPClient returns a client for the Ollama API that authenticates as APIAuthorization says and trusts the CA
+// certificate in ollama_tls_ca_cert as well as the system ones. A timeout of 0 means none.
+func NewHTTPClient(cfg *Config, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.OllamaTLSCACert != "" {
+		pem, err := os.ReadFile(cfg.OllamaTLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the Ollama API CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", cfg.OllamaTLSCACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	authorization := cfg.APIAuthorization()
+	if authorization == "" {
+		return client, nil
+	}
+	apiURL, err := url.Parse(cfg.OllamaAPIURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %s: %w", cfg.OllamaAPIURL, err)
+	}
+	client.Transport = &authTransport{host: apiURL.Host, authorization: authorization, base: transport}
+	return client, nil
+}
+
+// AuthError returns an error saying authentication failed for a 401 or 403 status from the Ollama API, otherwise nil
+func AuthError(statusCode int) error {
+	if statusCode != http.StatusUnauthorized && statusCode != http.StatusForbidden {
+		return nil
+	}
+	return fmt.Errorf("authentication with the Ollama API failed (%d %s), check ollama_api_key, ollama_auth_header or OLLAMA_API_KEY",
+		statusCode, strings.ToLower(http.StatusText(statusCode)))
+}
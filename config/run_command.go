@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ModelPlaceholder is replaced with the model's name in run_command_template
+const ModelPlaceholder = "{{model}}"
+
+// CommandPlaceholder is replaced with the command to run the model with in a run_external template
+const CommandPlaceholder = "{{command}}"
+
+// RunExternalModes are the run_external values other than a template, suspend runs models in gollama's terminal
+var RunExternalModes = []string{"suspend", "tmux", "kitty", "wezterm"}
+
+// ValidateRunExternal checks run_external is one of RunExternalModes or a template that starts with a command and has
+// somewhere to put the run command, empty is the same as suspend
+func ValidateRunExternal(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	for _, mode := range RunExternalModes {
+		if value == mode {
+			return nil
+		}
+	}
+	if !strings.Contains(value, CommandPlaceholder) {
+		return fmt.Errorf("run_external %q must be %s or a template containing %s", value, strings.Join(RunExternalModes, ", "), CommandPlaceholder)
+	}
+	words, err := SplitTemplate(value)
+	if err != nil {
+		return fmt.Errorf("run_external %q: %w", value, err)
+	}
+	if strings.Contains(words[0].Text, CommandPlaceholder) {
+		return fmt.Errorf("run_external %q must start with the command that opens the window, not %s", value, CommandPlaceholder)
+	}
+	return nil
+}
+
+// TemplateWord is a word of a run_external template, Quoted when any of it was in quotes
+type TemplateWord struct {
+	Text   string
+	Quoted bool
+}
+
+// SplitTemplate splits a run_external template into words the way a POSIX shell does, honouring single and double
+// quotes and backslash escapes, without expanding anything. It fails on an unterminated quote or a trailing backslash.
+func SplitTemplate(template string) ([]TemplateWord, error) {
+	var words []TemplateWord
+	var word strings.Builder
+	inWord, quoted := false, false
+	endWord := func() {
+		if inWord {
+			words = append(words, TemplateWord{Text: word.String(), Quoted: quoted})
+		}
+		word.Reset()
+		inWord, quoted = false, false
+	}
+
+	runes := []rune(template)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'':
+			// Nothing is special inside single quotes
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated single quote")
+			}
+			inWord, quoted = true, true
+		case r == '"':
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				// Inside double quotes a backslash only escapes the characters that are special there
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord, quoted = true, true
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endWord()
+	return words, nil
+}
+
+// ValidateRunCommandTemplate checks a run_command_template starts with a command and has somewhere to put the model,
+// an empty template is valid and runs models with ollama
+func ValidateRunCommandTemplate(template string) error {
+	fields := strings.Fields(template)
+	if len(fields) == 0 {
+		return nil
+	}
+	if strings.Contains(fields[0], ModelPlaceholder) {
+		return fmt.Errorf("run_command_template %q must start with the command to run, not %s", template, ModelPlaceholder)
+	}
+	if !strings.Contains(template, ModelPlaceholder) {
+		return fmt.Errorf("run_command_template %q doesn't contain %s for the model name", template, ModelPlaceholder)
+	}
+	return nil
+}
+
+// RunCommand returns the command and arguments to run a model with. run_command_template is split on whitespace, so
+// arguments can't contain spaces. Without a template docker_container runs the model with docker exec in the
+// container, otherwise it's run with ollama.
+func (c *Config) RunCommand(model string) []string {
+	args, _ := c.RunCommandWith(model, RunProfile{})
+	return args
+}
+
+// RunProfile is the extra arguments and environment models matching a run_profiles key are run with
+type RunProfile struct {
+	Args       []string `mapstructure:"args" json:"args,omitempty"`               // Added after the model, e.g. ["--verbose"]
+	Env        []string `mapstructure:"env" json:"env,omitempty"`                 // KEY=value pairs, e.g. ["OLLAMA_NUM_PARALLEL=2"]
+	PromptFile string   `mapstructure:"prompt_file" json:"prompt_file,omitempty"` // File whose contents are sent as the prompt
+}
+
+// Empty reports whether the profile changes nothing about how a model is run
+func (p RunProfile) Empty() bool {
+	return len(p.Args) == 0 && len(p.Env) == 0 && p.PromptFile == ""
+}
+
+// RunProfile returns the run profile for a model and the run_profiles key it's under, an empty key when there isn't
+// one. A key naming the model wins over globs, and of the globs matching the model the longest wins. Keys are matched
+// case insensitively as the config keys are lowercased when they're loaded, and a name without a tag is :latest.
+func (c *Config) RunProfile(model string) (RunProfile, string) {
+	name := strings.ToLower(model)
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	keys := make([]string, 0, len(c.RunProfiles))
+	for key := range c.RunProfiles {
+		keys = append(keys, key)
+	}
+	// Longest first, then by name so the same key always wins
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		pattern := strings.ToLower(key)
+		if pattern == name || pattern+":latest" == name {
+			return c.RunProfiles[key], key
+		}
+	}
+	for _, key := range keys {
+		if matched, _ := path.Match(strings.ToLower(key), name); matched {
+			return c.RunProfiles[key], key
+		}
+	}
+	return RunProfile{}, ""
+}
+
+// RunCommandWith returns the command to run a model with the profile's arguments added after the model, and the
+// environment to add for it. docker exec is given the environment with -e as it wouldn't reach the container
+// otherwise, the environment is returned for ollama and run_command_template. The prompt file is left to the caller.
+func (c *Config) RunCommandWith(model string, profile RunProfile) ([]string, []string) {
+	env := profile.Env
+	template := strings.TrimSpace(c.RunCommandTemplate)
+	if template == "" {
+		template = "ollama run " + ModelPlaceholder
+		if c.DockerContainer != "" && !strings.EqualFold(c.DockerContainer, "false") {
+			template = fmt.Sprintf("docker exec%s -it %s ollama run %s", dockerEnvFlags(env), c.DockerContainer, ModelPlaceholder)
+			env = nil
+		}
+	}
+	args := strings.Fields(template)
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, ModelPlaceholder, model)
+	}
+	return append(args, profile.Args...), env
+}
+
+// dockerEnvFlags returns the -e flags passing the environment to docker exec, with a leading space
+func dockerEnvFlags(env []string) string {
+	var flags strings.Builder
+	for _, variable := range env {
+		flags.WriteString(" -e " + variable)
+	}
+	return flags.String()
+}
@@ -0,0 +1,116 @@
+// config_reload.go applies changes made to the config file while the TUI is running.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// configChangedMsg is sent when the config file is edited outside gollama
+type configChangedMsg struct {
+	previous config.Config
+	current  config.Config
+	err      error // the edited config is invalid, so it's not used
+}
+
+// restartRequired returns the config keys that changed but are only read when gollama starts
+func restartRequired(previous, current config.Config) []string {
+	var keys []string
+	if previous.OllamaAPIURL != current.OllamaAPIURL {
+		keys = append(keys, "ollama_api_url")
+	}
+	if previous.LogFilePath != current.LogFilePath {
+		keys = append(keys, "log_file_path")
+	}
+	if previous.LMStudioFilePaths != current.LMStudioFilePaths {
+		keys = append(keys, "lm_studio_file_paths")
+	}
+	if previous.ShowContextLength != current.ShowContextLength {
+		keys = append(keys, "show_context_length")
+	}
+	return keys
+}
+
+// handleConfigChangedMsg applies the theme, sort order and log level from the edited config straight away, and
+// tells the user which other changes need a restart
+func (m *AppModel) handleConfigChangedMsg(msg configChangedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error reloading the config: %v\n", msg.err)
+		m.message = fmt.Sprintf("Config not reloaded, fix it and save again: %v", msg.err)
+		return m, nil
+	}
+	previous, current := msg.previous, msg.current
+	var applied, problems []string
+
+	if current.Theme != previous.Theme {
+		if err := styles.InitTheme(current.Theme); err != nil {
+			problems = append(problems, fmt.Sprintf("theme %s: %v", current.Theme, err))
+		} else {
+			applied = append(applied, "theme "+current.Theme)
+		}
+	}
+	if current.LogLevel != previous.LogLevel {
+		if err := logging.SetLevel(current.LogLevel); err != nil {
+			problems = append(problems, fmt.Sprintf("log level %s: %v", current.LogLevel, err))
+		} else {
+			applied = append(applied, "log level "+current.LogLevel)
+		}
+	}
+	if current.SortOrder != previous.SortOrder || current.SortDirection != previous.SortDirection {
+		if _, ok := sortFields[current.SortOrder]; ok {
+			m.setSort(current.SortOrder, sortDescending(&current))
+			applied = append(applied, "sort order "+current.SortOrder)
+		} else {
+			problems = append(problems, fmt.Sprintf("unknown sort order %q", current.SortOrder))
+		}
+	}
+
+	columnsChanged := strings.Join(current.Columns, ",") != strings.Join(previous.Columns, ",")
+	if columnsChanged {
+		if _, err := config.ParseColumns(current.Columns); err != nil {
+			problems = append(problems, err.Error())
+			current.Columns = m.cfg.Columns
+			columnsChanged = false
+		} else {
+			applied = append(applied, "columns")
+		}
+	}
+
+	// Settings read as they're used take effect as is, those only read at startup keep their current values
+	restart := restartRequired(previous, current)
+	updated := current
+	updated.OllamaAPIURL = m.cfg.OllamaAPIURL
+	updated.LogFilePath = m.cfg.LogFilePath
+	updated.LMStudioFilePaths = m.cfg.LMStudioFilePaths
+	updated.ShowContextLength = m.cfg.ShowContextLength
+	hadContext := showsContextLength(m.cfg)
+	*m.cfg = updated
+	var cmd tea.Cmd
+	if columnsChanged {
+		m.updateColumnLayout()
+		if !hadContext {
+			// A context column that's just been added fills in as the lengths are fetched
+			cmd = m.nextContextLengthFetch()
+		}
+	}
+
+	message := "Config reloaded"
+	if len(applied) > 0 {
+		message += ": " + strings.Join(applied, ", ")
+	}
+	if len(problems) > 0 {
+		message += ". Couldn't apply " + strings.Join(problems, ", ")
+	}
+	if len(restart) > 0 {
+		message += ". Restart gollama to use the new " + strings.Join(restart, ", ")
+	}
+	logging.InfoLogger.Println(message)
+	m.message = message
+	return m, cmd
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/styles"
+)
+
+func TestHandleConfigChangedMsg(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer styles.InitTheme(styles.DefaultThemeName)
+
+	previous := config.Config{Theme: "default", SortOrder: "size", LogLevel: "info", OllamaAPIURL: "http://127.0.0.1:11434"}
+	current := previous
+	current.Theme, current.SortOrder, current.OllamaAPIURL, current.StripString = "ocean", "name", "http://server:11434", "registry/"
+
+	model := func(name string, size int64) Model {
+		m := Model{}
+		m.Name, m.Size = name, size
+		return m
+	}
+	cfg := previous
+	m := &AppModel{cfg: &cfg, list: list.New(nil, list.NewDefaultDelegate(), 0, 0)}
+	m.models = []Model{model("b", 2), model("a", 1)}
+
+	m.handleConfigChangedMsg(configChangedMsg{previous: previous, current: current})
+	if styles.Current().Name != "ocean" {
+		t.Errorf("theme = %s, want ocean", styles.Current().Name)
+	}
+	if m.models[0].Name != "a" || m.cfg.SortOrder != "name" {
+		t.Errorf("models = %v sorted by %s, want them sorted by name", m.models, m.cfg.SortOrder)
+	}
+	if m.cfg.StripString != "registry/" {
+		t.Errorf("strip_string = %q, want the new value applied", m.cfg.StripString)
+	}
+	if m.cfg.OllamaAPIURL != previous.OllamaAPIURL || !strings.Contains(m.message, "Restart gollama to use the new ollama_api_url") {
+		t.Errorf("API URL = %s, message %q, want the old URL kept until a restart", m.cfg.OllamaAPIURL, m.message)
+	}
+
+	m.handleConfigChangedMsg(configChangedMsg{previous: current, current: config.Config{Theme: "ocean", SortOrder: "sideways", OllamaAPIURL: current.OllamaAPIURL}})
+	if !strings.Contains(m.message, `unknown sort order "sideways"`) {
+		t.Errorf("message = %q, want the unknown sort order reported", m.message)
+	}
+
+	// An invalid config is reported and nothing is applied
+	m.handleConfigChangedMsg(configChangedMsg{previous: current, err: fmt.Errorf("unknown column \"Colour\"")})
+	if !strings.HasPrefix(m.message, "Config not reloaded") || m.cfg.Theme != "ocean" {
+		t.Errorf("message = %q with theme %s, want the error reported and the config kept", m.message, m.cfg.Theme)
+	}
+}
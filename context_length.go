@@ -0,0 +1,121 @@
+// context_length.go fetches each model's native context length in the background for the optional list column,
+// caching the lengths by digest so later runs don't have to ask again.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+	"github.com/sammcj/gollama/utils"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+// contextLengthMsg is the context length of a model, zero if the model doesn't report one
+type contextLengthMsg struct {
+	digest string
+	length int
+	err    error
+}
+
+func contextLengthCachePath() string {
+	return filepath.Join(utils.GetConfigDir(), "context-lengths.json")
+}
+
+// loadContextLengths returns the cached context lengths keyed by model digest
+func loadContextLengths() map[string]int {
+	lengths := make(map[string]int)
+	data, err := os.ReadFile(contextLengthCachePath())
+	if err != nil {
+		return lengths
+	}
+	if err := json.Unmarshal(data, &lengths); err != nil {
+		logging.ErrorLogger.Printf("Ignoring unreadable context length cache %s: %v\n", contextLengthCachePath(), err)
+		return make(map[string]int)
+	}
+	return lengths
+}
+
+func saveContextLengths(lengths map[string]int) error {
+	path := contextLengthCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// Lengths that failed to fetch are negative and only kept for this run
+	saved := make(map[string]int, len(lengths))
+	for digest, length := range lengths {
+		if length >= 0 {
+			saved[digest] = length
+		}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// fetchContextLength returns a model's native context length from the *.context_length key of the show API
+func fetchContextLength(ctx context.Context, client *api.Client, modelName string) (int, error) {
+	show, err := showcache.For(client).Show(ctx, modelName)
+	if err != nil {
+		return 0, err
+	}
+	info := vramestimator.OllamaModelInfo{ModelInfo: show.ModelInfo}
+	return info.ContextLength(), nil
+}
+
+// nextContextLengthFetch returns a command fetching the context length of the first model that isn't cached yet,
+// or nil once every model has one. Models are fetched one at a time so startup isn't slowed down.
+func (m *AppModel) nextContextLengthFetch() tea.Cmd {
+	if !showsContextLength(m.cfg) {
+		return nil
+	}
+	for _, model := range m.models {
+		if _, ok := m.contextLengths[model.Digest]; ok || model.Digest == "" {
+			continue
+		}
+		name, digest, client := model.Name, model.Digest, m.client
+		return func() tea.Msg {
+			length, err := fetchContextLength(context.Background(), client, name)
+			return contextLengthMsg{digest: digest, length: length, err: err}
+		}
+	}
+	return nil
+}
+
+func (m *AppModel) handleContextLengthMsg(msg contextLengthMsg) (tea.Model, tea.Cmd) {
+	m.contextLengths[msg.digest] = msg.length
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error fetching context length: %v\n", msg.err)
+		// Don't retry it this run, but do next time
+		m.contextLengths[msg.digest] = -1
+	}
+	if err := saveContextLengths(m.contextLengths); err != nil {
+		logging.ErrorLogger.Printf("Error saving context lengths: %v\n", err)
+	}
+	return m, m.nextContextLengthFetch()
+}
+
+// contextLengthLabel formats a context length for the list column, blank until it has been fetched
+func contextLengthLabel(length int, fetched bool) string {
+	switch {
+	case !fetched:
+		return ""
+	case length <= 0:
+		return "-"
+	case length%1024 == 0:
+		return fmt.Sprintf("%dK", length/1024)
+	case length >= 1000 && length%1000 == 0:
+		return fmt.Sprintf("%dk", length/1000)
+	default:
+		return fmt.Sprintf("%d", length)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestContextLengths(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{ModelInfo: map[string]any{"llama.context_length": 131072, "llama.block_count": 32}})
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	length, err := fetchContextLength(context.Background(), client, "llama3.1:8b")
+	if err != nil || length != 131072 {
+		t.Fatalf("fetchContextLength() = %d, %v, want 131072", length, err)
+	}
+
+	if err := saveContextLengths(map[string]int{"sha256:a": length, "sha256:b": 0, "sha256:failed": -1}); err != nil {
+		t.Fatalf("saveContextLengths() error: %v", err)
+	}
+	loaded := loadContextLengths()
+	if len(loaded) != 2 || loaded["sha256:a"] != 131072 {
+		t.Errorf("loadContextLengths() = %v, want the two fetched lengths without the failure", loaded)
+	}
+
+	for _, tt := range []struct {
+		length   int
+		fetched  bool
+		expected string
+	}{
+		{131072, true, "128K"},
+		{32000, true, "32k"},
+		{2500, true, "2500"},
+		{0, true, "-"},
+		{0, false, ""},
+	} {
+		if got := contextLengthLabel(tt.length, tt.fetched); got != tt.expected {
+			t.Errorf("contextLengthLabel(%d, %v) = %q, want %q", tt.length, tt.fetched, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// copy_settings.go applies one model's template, system prompt and parameters to another model without copying its weights.
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// settingsCopy is a copy of settings onto a model that already has different values, waiting for the user to confirm
+// overwriting them
+type settingsCopy struct {
+	source    string
+	target    string
+	values    map[string][]string // the target's values once the source's are merged in
+	conflicts []modelfileChange   // Old is the target's value, New the source's
+}
+
+// mergeModelfileValues merges the source's template, system prompt and parameters into the target's, keeping any the
+// source doesn't set. It returns the merged values, the fields where the target already has a different value, and
+// whether the merge changes anything.
+func mergeModelfileValues(source, target map[string][]string) (map[string][]string, []modelfileChange, bool) {
+	merged := make(map[string][]string, len(target)+len(source))
+	for field, values := range target {
+		merged[field] = values
+	}
+	var conflicts []modelfileChange
+	changed := false
+	for field, values := range source {
+		existing, ok := target[field]
+		if ok && slices.Equal(existing, values) {
+			continue
+		}
+		if ok {
+			conflicts = append(conflicts, modelfileChange{Field: field, Old: existing, New: values})
+		}
+		merged[field] = values
+		changed = true
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Field < conflicts[j].Field })
+	return merged, conflicts, changed
+}
+
+// planSettingsCopy reads the template, system prompt and parameters of both models and merges the source's into the target's
+func planSettingsCopy(ctx context.Context, client *api.Client, source, target string) (*settingsCopy, bool, error) {
+	sourceShow, err := client.Show(ctx, &api.ShowRequest{Name: source})
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching modelfile for %s: %w", source, err)
+	}
+	targetShow, err := client.Show(ctx, &api.ShowRequest{Name: target})
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching modelfile for %s: %w", target, err)
+	}
+	values, conflicts, changed := mergeModelfileValues(modelfileValues(sourceShow.Modelfile), modelfileValues(targetShow.Modelfile))
+	return &settingsCopy{source: source, target: target, values: values, conflicts: conflicts}, changed, nil
+}
+
+func (m *AppModel) handleCopySettingsKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("CopySettings key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	target, ok := promptForValue(fmt.Sprintf("Apply the template, system prompt and parameters of %s to model: ", item.Name), "")
+	target = strings.TrimSpace(target)
+	if !ok || target == "" {
+		m.message = "Cancelled"
+		return m, nil
+	}
+	if normaliseModelName(target) == normaliseModelName(item.Name) {
+		m.message = "Error: choose a different model to copy the settings to"
+		return m, nil
+	}
+
+	existing, err := findModel(m.client, target)
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return m, nil
+	}
+	if existing == nil {
+		m.message = fmt.Sprintf("Error: model %s not found", target)
+		return m, nil
+	}
+
+	plan, changed, err := planSettingsCopy(context.Background(), m.client, item.Name, existing.Name)
+	if err != nil {
+		m.message = err.Error()
+		return m, nil
+	}
+	if !changed {
+		m.message = fmt.Sprintf("%s already has the same template, system prompt and parameters as %s", existing.Name, item.Name)
+		return m, nil
+	}
+	if len(plan.conflicts) > 0 {
+		m.settingsCopy = plan
+		return m, nil
+	}
+	return m, m.applySettingsCopy(plan)
+}
+
+// handleSettingsCopyKey confirms or cancels overwriting the target's values
+func (m *AppModel) handleSettingsCopyKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	plan := m.settingsCopy
+	switch {
+	case key.Matches(msg, m.keys.ConfirmYes):
+		m.settingsCopy = nil
+		return m, m.applySettingsCopy(plan)
+	case key.Matches(msg, m.keys.ConfirmNo), msg.String() == "esc", msg.String() == "q":
+		logging.InfoLogger.Println("Copying settings cancelled by user")
+		m.settingsCopy = nil
+		m.message = "Cancelled"
+	}
+	return m, nil
+}
+
+func (m *AppModel) settingsCopyView() string {
+	plan := m.settingsCopy
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s already has different values for:\n\n", plan.target)
+	for _, conflict := range plan.conflicts {
+		fmt.Fprintf(&b, "  %s: %s -> %s\n", conflict.Field, formatModelfileValue(conflict.Old), formatModelfileValue(conflict.New))
+	}
+	fmt.Fprintf(&b, "\nOverwrite them with the values from %s? (Y/N)\n", plan.source)
+	return b.String()
+}
+
+// applySettingsCopy re-creates the target from itself with the merged values in the background
+func (m *AppModel) applySettingsCopy(plan *settingsCopy) tea.Cmd {
+	m.message = fmt.Sprintf("Copying settings from %s to %s...", plan.source, plan.target)
+	return func() tea.Msg {
+		if err := applyEdit(context.Background(), m.client, pendingEdit{Model: plan.target, Values: plan.values}); err != nil {
+			logging.ErrorLogger.Printf("Error copying settings from %s to %s: %v\n", plan.source, plan.target, err)
+			return genericMsg{message: fmt.Sprintf("Error copying settings to %s: %v", plan.target, err)}
+		}
+		logging.InfoLogger.Printf("Copied settings from %s to %s\n", plan.source, plan.target)
+		recordHistory("edit", plan.target, "settings copied from "+plan.source)
+		return genericMsg{message: fmt.Sprintf("Copied the template, system prompt and parameters of %s to %s", plan.source, plan.target)}
+	}
+}
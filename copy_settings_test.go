@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeModelfileValues(t *testing.T) {
+	source := map[string][]string{
+		"system":      {"You are a pirate"},
+		"temperature": {"0.2"},
+		"stop":        {"<|im_end|>"},
+	}
+	target := map[string][]string{
+		"template":    {"{{ .Prompt }}"},
+		"temperature": {"0.8"},
+		"stop":        {"<|im_end|>"},
+	}
+
+	merged, conflicts, changed := mergeModelfileValues(source, target)
+	if !changed {
+		t.Error("changed = false, want true")
+	}
+	want := map[string][]string{
+		"template":    {"{{ .Prompt }}"},
+		"system":      {"You are a pirate"},
+		"temperature": {"0.2"},
+		"stop":        {"<|im_end|>"},
+	}
+	if len(merged) != len(want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+	for field, values := range want {
+		if !slices.Equal(merged[field], values) {
+			t.Errorf("merged[%s] = %v, want %v", field, merged[field], values)
+		}
+	}
+	if len(conflicts) != 1 || conflicts[0].Field != "temperature" || conflicts[0].Old[0] != "0.8" || conflicts[0].New[0] != "0.2" {
+		t.Errorf("conflicts = %+v, want only temperature 0.8 -> 0.2", conflicts)
+	}
+	if target["temperature"][0] != "0.8" {
+		t.Error("mergeModelfileValues() modified the target's values")
+	}
+
+	if _, conflicts, changed := mergeModelfileValues(map[string][]string{"stop": {"<|im_end|>"}}, target); changed || len(conflicts) != 0 {
+		t.Errorf("merging values the target already has = %v, %v, want no change", changed, conflicts)
+	}
+}
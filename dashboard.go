@@ -0,0 +1,261 @@
+// dashboard.go contains the dashboard view, a compact summary of running models, recent activity and disk usage.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/sammcj/gollama/history"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+const (
+	dashboardRecentEvents = 6
+	// lowDiskPercent is the free space percentage below which the dashboard warns about disk space
+	lowDiskPercent = 10.0
+	// dashboardTwoColumnWidth is the terminal width above which the dashboard panels are laid out side by side
+	dashboardTwoColumnWidth = 100
+)
+
+type dashboardData struct {
+	running   []runningModel
+	runErr    error
+	events    []history.Event
+	disk      *disk.UsageStat
+	diskErr   error
+	updatedAt time.Time
+}
+
+type dashboardMsg struct {
+	data dashboardData
+}
+
+type dashboardTickMsg struct{}
+
+func (m *AppModel) handleDashboardKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("Dashboard key matched")
+	m.view = DashboardView
+	m.dashboardCursor = 0
+	return m, m.fetchDashboardData()
+}
+
+// fetchDashboardData collects the dashboard data in the background, it only uses the same calls the top view makes
+func (m *AppModel) fetchDashboardData() tea.Cmd {
+	apiURL := m.cfg.OllamaAPIURL
+	modelsDir := m.ollamaModelsDir
+	local := m.isRemoteHost() == ""
+	return func() tea.Msg {
+		var data dashboardData
+		var err error
+		data.running, data.runErr = listRunningModels(context.Background(), apiURL)
+
+		data.events, err = history.Recent(dashboardRecentEvents)
+		if err != nil {
+			logging.ErrorLogger.Printf("Error reading history: %v\n", err)
+		}
+
+		// Disk stats are only meaningful when the models live on this machine
+		if local {
+			data.disk, data.diskErr = disk.Usage(modelsDir)
+		}
+		data.updatedAt = time.Now()
+		return dashboardMsg{data: data}
+	}
+}
+
+func (m *AppModel) handleDashboardMsg(msg dashboardMsg) (tea.Model, tea.Cmd) {
+	m.dashboard = msg.data
+	if m.dashboardCursor >= len(m.dashboard.running) {
+		m.dashboardCursor = 0
+	}
+	if m.view != DashboardView {
+		return m, nil
+	}
+	// Keep the running models fresh while the dashboard is open
+	return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
+		return dashboardTickMsg{}
+	})
+}
+
+func (m *AppModel) handleDashboardTickMsg() (tea.Model, tea.Cmd) {
+	if m.view != DashboardView {
+		return m, nil
+	}
+	return m, m.fetchDashboardData()
+}
+
+// handleDashboardNavigation handles the keys specific to the dashboard, returning false if the key wasn't handled
+func (m *AppModel) handleDashboardNavigation(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "up", "k":
+		if m.dashboardCursor > 0 {
+			m.dashboardCursor--
+		}
+		return m, nil, true
+	case "down", "j":
+		if m.dashboardCursor < len(m.dashboard.running)-1 {
+			m.dashboardCursor++
+		}
+		return m, nil, true
+	case "enter":
+		if len(m.dashboard.running) == 0 {
+			return m, nil, true
+		}
+		name := m.dashboard.running[m.dashboardCursor].Name
+		m.view = MainView
+		if !m.selectModelByName(name) {
+			m.message = fmt.Sprintf("Running model %s not found in the model list", name)
+		}
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// selectModelByName moves the list cursor to the named model, clearing any filter that would hide it. The list selects
+// by the index among the visible items, so a filtered model is looked up again once the filter is cleared.
+func (m *AppModel) selectModelByName(name string) bool {
+	for i, item := range m.list.VisibleItems() {
+		if model, ok := item.(Model); ok && model.Name == name {
+			m.list.Select(i)
+			return true
+		}
+	}
+	for i, item := range m.list.Items() {
+		if model, ok := item.(Model); ok && model.Name == name {
+			m.list.ResetFilter()
+			m.list.Select(i)
+			return true
+		}
+	}
+	return false
+}
+
+// dashboardWarnings returns any conditions worth flagging to the user, including the models the last ctrl+u update check
+// found outdated that haven't been pulled since
+func (m *AppModel) dashboardWarnings() []string {
+	var warnings []string
+	d := m.dashboard
+	if d.runErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Could not fetch running models: %v", d.runErr))
+	}
+	if d.diskErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Models directory %s is not available: %v", m.ollamaModelsDir, d.diskErr))
+	}
+	if d.disk != nil && d.disk.Total > 0 {
+		freePercent := 100 - d.disk.UsedPercent
+		if freePercent < lowDiskPercent {
+			warnings = append(warnings, fmt.Sprintf("Low disk space on models volume: %.1f%% free", freePercent))
+		}
+	}
+	if len(m.staleModels) > 0 {
+		names := make([]string, len(m.staleModels))
+		for i, update := range m.staleModels {
+			names[i] = update.Model
+		}
+		warnings = append(warnings, fmt.Sprintf("Outdated at the %s update check: %s", m.updateCheckedAt.Format("01-02 15:04"), strings.Join(names, ", ")))
+	}
+	return warnings
+}
+
+func (m *AppModel) dashboardView() string {
+	width := m.width
+	if width <= 0 {
+		width = 80
+	}
+	panelWidth := width - 4
+	twoColumns := width >= dashboardTwoColumnWidth
+	if twoColumns {
+		panelWidth = width/2 - 4
+	}
+
+	colours := styles.Current().Colours
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colours.SelectedBorder.TerminalColour()).
+		Padding(0, 1).
+		Width(panelWidth)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.Title.TerminalColour())
+	faintStyle := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
+	selectedStyle := lipgloss.NewStyle().Foreground(colours.Highlight.TerminalColour()).Background(colours.HighlightBG.TerminalColour())
+	warningStyle := lipgloss.NewStyle().Foreground(colours.Warning.TerminalColour())
+
+	// Running models
+	var running strings.Builder
+	running.WriteString(titleStyle.Render("Running Models") + "\n")
+	if len(m.dashboard.running) == 0 {
+		running.WriteString(faintStyle.Render("No models loaded"))
+	}
+	for i, model := range m.dashboard.running {
+		line := fmt.Sprintf("%s  %s VRAM  %s", model.Name, formatRunningSize(model.SizeVRAM), model.processor())
+		if i == m.dashboardCursor {
+			line = selectedStyle.Render(line)
+		}
+		running.WriteString(line + "\n")
+	}
+
+	// Recent activity
+	var activity strings.Builder
+	activity.WriteString(titleStyle.Render("Recent Activity") + "\n")
+	if len(m.dashboard.events) == 0 {
+		activity.WriteString(faintStyle.Render("No recorded activity"))
+	}
+	for _, event := range m.dashboard.events {
+		line := fmt.Sprintf("%s  %-7s %s", event.Time.Format("01-02 15:04"), event.Action, event.Model)
+		if event.Detail != "" {
+			line += " → " + event.Detail
+		}
+		activity.WriteString(line + "\n")
+	}
+
+	// Disk
+	var diskPanel strings.Builder
+	diskPanel.WriteString(titleStyle.Render("Disk") + "\n")
+	var totalSize float64
+	for _, model := range m.models {
+		totalSize += model.SizeGB()
+	}
+	diskPanel.WriteString(fmt.Sprintf("%d models (%d pinned), %.2f GB\n", len(m.models), len(pinnedModels(m.models)), totalSize))
+	if d := m.dashboard.disk; d != nil {
+		diskPanel.WriteString(fmt.Sprintf("%.2f GB free of %.2f GB (%s)", float64(d.Free)/1024/1024/1024, float64(d.Total)/1024/1024/1024, m.ollamaModelsDir))
+	} else if m.isRemoteHost() != "" {
+		diskPanel.WriteString(faintStyle.Render("Disk usage not available on remote hosts"))
+	}
+
+	// Warnings
+	var warningsPanel strings.Builder
+	warningsPanel.WriteString(titleStyle.Render("Warnings") + "\n")
+	warnings := m.dashboardWarnings()
+	if len(warnings) == 0 {
+		warningsPanel.WriteString(faintStyle.Render("None"))
+	}
+	for _, warning := range warnings {
+		warningsPanel.WriteString(warningStyle.Render(warning) + "\n")
+	}
+
+	panels := []string{
+		panelStyle.Render(strings.TrimRight(running.String(), "\n")),
+		panelStyle.Render(strings.TrimRight(activity.String(), "\n")),
+		panelStyle.Render(strings.TrimRight(diskPanel.String(), "\n")),
+		panelStyle.Render(strings.TrimRight(warningsPanel.String(), "\n")),
+	}
+
+	var body string
+	if twoColumns {
+		body = lipgloss.JoinVertical(lipgloss.Left,
+			lipgloss.JoinHorizontal(lipgloss.Top, panels[0], panels[1]),
+			lipgloss.JoinHorizontal(lipgloss.Top, panels[2], panels[3]),
+		)
+	} else {
+		body = lipgloss.JoinVertical(lipgloss.Left, panels...)
+	}
+
+	footer := faintStyle.Render("↑/↓ select a running model, enter to jump to it in the list, 'q' or `esc` to return to the main view.")
+	return "\n" + body + "\n" + footer
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+// newDashboardModel returns an app with the named models in its list and the running ones on the dashboard
+func newDashboardModel(names []string, running ...string) *AppModel {
+	models := make([]Model, len(names))
+	items := make([]list.Item, len(names))
+	for i, name := range names {
+		models[i].Name = name
+		items[i] = models[i]
+	}
+	m := &AppModel{
+		cfg:    &config.Config{OllamaAPIURL: "http://localhost:11434"},
+		models: models,
+		list:   list.New(items, list.NewDefaultDelegate(), 80, 40),
+		view:   DashboardView,
+	}
+	for _, name := range running {
+		m.dashboard.running = append(m.dashboard.running, runningModel{ProcessModelResponse: api.ProcessModelResponse{Name: name}})
+	}
+	return m
+}
+
+func TestDashboardViewReflow(t *testing.T) {
+	m := newDashboardModel([]string{"llama3:8b"}, "llama3:8b")
+	sameLine := func(view string) bool {
+		for _, line := range strings.Split(view, "\n") {
+			if strings.Contains(line, "Running Models") && strings.Contains(line, "Recent Activity") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, width := range []int{60, 80, dashboardTwoColumnWidth, 140} {
+		m.width = width
+		view := m.dashboardView()
+		twoColumns := width >= dashboardTwoColumnWidth
+		if sameLine(view) != twoColumns {
+			t.Errorf("width %d: panels side by side = %v, want %v", width, !twoColumns, twoColumns)
+		}
+		for _, line := range strings.Split(view, "\n") {
+			if got := lipgloss.Width(line); got > width && !strings.Contains(line, "select a running model") {
+				t.Errorf("width %d: line is %d wide: %q", width, got, line)
+			}
+		}
+		if !strings.Contains(view, "llama3:8b") {
+			t.Errorf("width %d: running model missing from the view", width)
+		}
+	}
+}
+
+func TestDashboardNavigation(t *testing.T) {
+	m := newDashboardModel([]string{"llama3:8b", "qwen2:7b", "mistral:7b"}, "qwen2:7b", "mistral:7b")
+	key := func(k string) bool {
+		var msg tea.KeyMsg
+		if k == "enter" {
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		} else {
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		_, _, handled := m.handleDashboardNavigation(msg)
+		return handled
+	}
+
+	key("j")
+	key("j")
+	if m.dashboardCursor != 1 {
+		t.Fatalf("cursor after moving down past the end = %d, want 1", m.dashboardCursor)
+	}
+	if key("x") {
+		t.Error("an unrelated key was handled by the dashboard")
+	}
+	if !key("enter") || m.view != MainView {
+		t.Fatalf("enter left the view at %v, want the main view", m.view)
+	}
+	if selected, ok := m.list.SelectedItem().(Model); !ok || selected.Name != "mistral:7b" {
+		t.Errorf("selected %v after enter, want mistral:7b", m.list.SelectedItem())
+	}
+
+	// A filter hiding the model is cleared so it can be selected
+	m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("llama")})
+	m.list, _ = m.list.Update(m.list.SetItems(m.list.Items())())
+	m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.list.VisibleItems()) != 1 {
+		t.Fatalf("filter shows %d models, want 1", len(m.list.VisibleItems()))
+	}
+	if !m.selectModelByName("qwen2:7b") || m.list.FilterValue() != "" {
+		t.Errorf("selectModelByName() with the model filtered out left the filter %q", m.list.FilterValue())
+	}
+	if selected, ok := m.list.SelectedItem().(Model); !ok || selected.Name != "qwen2:7b" {
+		t.Errorf("selected %v, want qwen2:7b", m.list.SelectedItem())
+	}
+
+	// A running model that isn't in the list says so instead of moving the cursor
+	m = newDashboardModel([]string{"llama3:8b"}, "gone:latest")
+	key("enter")
+	if m.view != MainView || !strings.Contains(m.message, "gone:latest not found") {
+		t.Errorf("enter on a missing model: view %v, message %q", m.view, m.message)
+	}
+}
+
+func TestDashboardWarnsAboutOutdatedModels(t *testing.T) {
+	m := newDashboardModel(nil)
+	if warnings := m.dashboardWarnings(); len(warnings) != 0 {
+		t.Fatalf("warnings before any update check = %q", warnings)
+	}
+
+	// A pull in progress keeps ctrl+u from starting, so the stale models stay outdated
+	m.pulling = true
+	m.handleUpdateCheckMsg(updateCheckMsg{updates: []modelUpdate{
+		{Model: "llama3:8b", Stale: true},
+		{Model: "qwen2:7b", Stale: true, Customised: []string{"SYSTEM"}},
+		{Model: "mistral:7b"},
+		{Model: "phi3:latest", Err: errors.New("registry unreachable")},
+	}})
+	warnings := strings.Join(m.dashboardWarnings(), "\n")
+	if !strings.Contains(warnings, "Outdated at the") || !strings.Contains(warnings, "update check: llama3:8b, qwen2:7b") {
+		t.Errorf("warnings = %q, want llama3:8b and qwen2:7b outdated", warnings)
+	}
+
+	m.clearStale("llama3:8b")
+	m.width = 120
+	view := m.dashboardView()
+	if !strings.Contains(view, "update check: qwen2:7b") || strings.Contains(view, "llama3:8b") {
+		t.Errorf("dashboard after pulling llama3:8b:\n%s", view)
+	}
+}
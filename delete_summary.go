@@ -0,0 +1,144 @@
+// delete_summary.go works out how much disk space deleting the selected models frees, which of them are running and
+// summarises the result.
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// deletionPlan is the space deleting a set of models frees, allowing for models that share blobs or are symlinked
+type deletionPlan struct {
+	Total  int64             // apparent size of the selected models
+	Freed  int64             // bytes freed once every selected model is deleted
+	Shared map[string]string // why a selected model frees less than its size, keyed by model name
+}
+
+// planDeletion works out the space freed by deleting selected from all. Models with the same digest share their blobs,
+// so they're only freed once and not at all if a model that isn't being deleted still uses them. Symlinked models
+// free nothing, their blob lives elsewhere.
+func planDeletion(all, selected []Model) deletionPlan {
+	plan := deletionPlan{Shared: make(map[string]string)}
+	selectedNames := make(map[string]bool, len(selected))
+	for _, model := range selected {
+		selectedNames[model.Name] = true
+	}
+	kept := make(map[string][]string) // digest -> models not being deleted
+	for _, model := range all {
+		if !selectedNames[model.Name] && model.Digest != "" {
+			kept[model.Digest] = append(kept[model.Digest], model.Name)
+		}
+	}
+
+	counted := make(map[string]string) // digest -> first selected model counted for it
+	for _, model := range selected {
+		plan.Total += model.Size
+		if model.Symlinked {
+			plan.Shared[model.Name] = "symlinked, frees nothing"
+			continue
+		}
+		if model.Digest != "" {
+			if names := kept[model.Digest]; len(names) > 0 {
+				plan.Shared[model.Name] = "blobs still used by " + strings.Join(names, ", ")
+				continue
+			}
+			if first, ok := counted[model.Digest]; ok {
+				plan.Shared[model.Name] = "same blobs as " + first
+				continue
+			}
+			counted[model.Digest] = model.Name
+		}
+		plan.Freed += model.Size
+	}
+	return plan
+}
+
+// runningModelNames returns the names of the selected models that are loaded. When they can't be listed none are
+// reported as running and deleting carries on as it did before.
+func runningModelNames(ctx context.Context, client *api.Client, selected []Model) map[string]bool {
+	resp, err := client.ListRunning(ctx)
+	if err != nil {
+		logging.ErrorLogger.Printf("Error checking which models are running before deleting: %v\n", err)
+		return nil
+	}
+	loaded := make(map[string]bool)
+	for _, model := range sanitiseRunningModels(resp) {
+		loaded[model.Name] = true
+	}
+	running := make(map[string]bool)
+	for _, model := range selected {
+		if loaded[model.Name] || loaded[normaliseModelName(model.Name)] {
+			running[model.Name] = true
+		}
+	}
+	return running
+}
+
+// deletionSummary lists the models to delete with their sizes and the total space freed, marking those that are
+// running and whether they'll be unloaded first
+func deletionSummary(all, selected []Model, running map[string]bool, unload bool) string {
+	plan := planDeletion(all, selected)
+	nameWidth := 0
+	for _, model := range selected {
+		nameWidth = max(nameWidth, len(model.Name))
+	}
+	var b strings.Builder
+	for _, model := range selected {
+		fmt.Fprintf(&b, "%-*s  %9s", nameWidth, model.Name, model.SizeLabel())
+		if reason, ok := plan.Shared[model.Name]; ok {
+			fmt.Fprintf(&b, "  (%s)", reason)
+		}
+		if running[model.Name] {
+			if unload {
+				b.WriteString("  (currently running — will be unloaded first)")
+			} else {
+				b.WriteString("  (currently running)")
+			}
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n%d model(s), %.2fGB in total, %.2fGB reclaimable", len(selected), float64(plan.Total)/(1024*1024*1024), float64(plan.Freed)/(1024*1024*1024))
+	return b.String()
+}
+
+// deleteResultSummary reports how many models were deleted and why any failed
+func deleteResultSummary(deleted int, failures map[string]error) string {
+	summary := fmt.Sprintf("%d deleted", deleted)
+	if len(failures) == 0 {
+		return summary
+	}
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var reasons []string
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("%s: %v", name, failures[name]))
+	}
+	return fmt.Sprintf("%s, %d failed (%s)", summary, len(failures), strings.Join(reasons, "; "))
+}
+
+// unloadFailureSummary reports the running models that couldn't be unloaded before they were deleted, empty when
+// there weren't any
+func unloadFailureSummary(failures map[string]error) string {
+	if len(failures) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var reasons []string
+	for _, name := range names {
+		reasons = append(reasons, fmt.Sprintf("%s: %v", name, failures[name]))
+	}
+	return fmt.Sprintf(", %d couldn't be unloaded first (%s)", len(failures), strings.Join(reasons, "; "))
+}
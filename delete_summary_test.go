@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestPlanDeletion(t *testing.T) {
+	model := func(name, digest string, size int64) Model {
+		m := Model{}
+		m.Name, m.Digest, m.Size = name, digest, size
+		return m
+	}
+	all := []Model{
+		model("a", "sha256:1", 100),
+		model("a-copy", "sha256:1", 100),
+		model("b", "sha256:2", 200),
+		model("b-copy", "sha256:2", 200),
+		model("c", "sha256:3", 300),
+	}
+
+	// a is still used by a-copy, b and b-copy share blobs so they're freed once
+	plan := planDeletion(all, []Model{all[0], all[2], all[3], all[4]})
+	if plan.Freed != 500 {
+		t.Errorf("Freed = %d, want 500", plan.Freed)
+	}
+	if plan.Shared["a"] != "blobs still used by a-copy" || plan.Shared["b-copy"] != "same blobs as b" || len(plan.Shared) != 2 {
+		t.Errorf("Shared = %v", plan.Shared)
+	}
+	if summary := deletionSummary(all, []Model{all[4]}, nil, true); !strings.Contains(summary, "1 model(s)") {
+		t.Errorf("deletionSummary() = %q", summary)
+	}
+
+	if got := deleteResultSummary(7, map[string]error{"x": errors.New("not found")}); got != "7 deleted, 1 failed (x: not found)" {
+		t.Errorf("deleteResultSummary() = %q", got)
+	}
+	if got := deleteResultSummary(2, nil); got != "2 deleted" {
+		t.Errorf("deleteResultSummary() = %q", got)
+	}
+}
+
+func TestDeleteRunningModel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ps":
+			json.NewEncoder(w).Encode(api.ProcessResponse{Models: []api.ProcessModelResponse{{Name: "llama3:latest"}, {Name: "qwen2:7b"}}})
+		case "/api/show":
+			json.NewEncoder(w).Encode(map[string]any{"capabilities": []string{"completion"}})
+		case "/api/generate":
+			var req api.GenerateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			calls = append(calls, "unload "+req.Model)
+			if req.Model == "qwen2:7b" {
+				http.Error(w, `{"error":"unload failed"}`, http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(api.GenerateResponse{Model: req.Model, Done: true})
+		case "/api/delete":
+			var req api.DeleteRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			calls = append(calls, "delete "+req.Name)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	newModel := func(name string) Model {
+		model := Model{Selected: true}
+		model.Name, model.Size = name, 1<<30
+		return model
+	}
+	for _, unload := range []bool{true, false} {
+		calls = nil
+		models := []Model{newModel("llama3:latest"), newModel("qwen2:7b"), newModel("phi3:mini")}
+		items := make([]list.Item, len(models))
+		for i, model := range models {
+			items[i] = model
+		}
+		m := &AppModel{
+			client: api.NewClient(u, server.Client()),
+			cfg:    &config.Config{OllamaAPIURL: server.URL, UnloadBeforeDelete: unload},
+			models: models,
+			list:   list.New(items, list.NewDefaultDelegate(), 80, 40),
+			keys:   *NewKeyMap(),
+		}
+
+		m.handleDeleteKey()
+		summary := deletionSummary(m.models, m.selectedModels, m.deleteRunning, unload)
+		if want := "currently running — will be unloaded first"; unload && strings.Count(summary, want) != 2 {
+			t.Errorf("deletion summary = %q, want the 2 running models marked %q", summary, want)
+		}
+		if !unload && (strings.Count(summary, "(currently running)") != 2 || strings.Contains(summary, "unloaded")) {
+			t.Errorf("deletion summary without unloading = %q, want the running models marked", summary)
+		}
+
+		m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+		want := []string{"delete llama3:latest", "delete qwen2:7b", "delete phi3:mini"}
+		if unload {
+			want = []string{"unload llama3:latest", "delete llama3:latest", "unload qwen2:7b", "delete qwen2:7b", "delete phi3:mini"}
+		}
+		if !slices.Equal(calls, want) {
+			t.Errorf("unload %v: calls = %v, want %v", unload, calls, want)
+		}
+		if unload && !strings.HasPrefix(m.message, "3 deleted, 1 couldn't be unloaded first (qwen2:7b:") {
+			t.Errorf("message = %q, want the unload failure reported", m.message)
+		}
+		if !unload && m.message != "3 deleted" {
+			t.Errorf("message = %q, want 3 deleted", m.message)
+		}
+	}
+}
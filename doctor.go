@@ -0,0 +1,47 @@
+// doctor.go prints a report of gollama's setup and its connection to Ollama for diagnosing problems.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/utils"
+)
+
+// runDoctor implements the -doctor flag, returning the process exit code. It doesn't need the API to be reachable
+// since that's one of the things it checks.
+func runDoctor(ctx context.Context, cfg config.Config, client *api.Client, modelsDir string) int {
+	fmt.Printf("gollama:     %s\n", Version)
+	fmt.Printf("Config:      %s\n", utils.GetConfigPath())
+	fmt.Printf("API URL:     %s\n", cfg.OllamaAPIURL)
+
+	if isLocalhost(cfg.OllamaAPIURL) {
+		info := checkModelsDir(modelsDir)
+		status := "available"
+		if !info.Available {
+			status = "not available"
+			if info.Err != nil {
+				status += fmt.Sprintf(" (%v)", info.Err)
+			}
+		}
+		fmt.Printf("Models dir:  %s, %s\n", modelsDir, status)
+	}
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		fmt.Printf("Ollama:      unreachable (%v)\n", err)
+		return 1
+	}
+	fmt.Printf("Ollama:      %s\n", version)
+
+	result, previous, err := benchmarkAndRecord(ctx, cfg.OllamaAPIURL)
+	if err != nil {
+		fmt.Printf("Connection:  benchmark failed (%v)\n", err)
+		return 1
+	}
+	fmt.Print(formatBenchmark(result, previous))
+	return 0
+}
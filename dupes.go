@@ -0,0 +1,139 @@
+// dupes.go finds models that are tags of the same blobs, e.g. mistral:latest, mistral:7b and my-mistral, so the extra
+// tags can be selected for deletion in one step.
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// duplicateGroup is a set of models sharing a digest, the first is the one kept when selecting duplicates
+type duplicateGroup struct {
+	Digest string
+	ID     string // the short ID shown for the digest
+	Size   int64
+	Models []Model
+}
+
+// duplicateGroups groups the models that share a digest with at least one other model, largest first. The digest is
+// compared rather than the short ID, which two different models can share, as the deletion summary does. Within a
+// group pinned models come first, then the shortest name, so the tag kept is the one most likely to be wanted.
+func duplicateGroups(models []Model) []duplicateGroup {
+	byDigest := make(map[string][]Model)
+	for _, model := range models {
+		if model.Digest != "" {
+			byDigest[model.Digest] = append(byDigest[model.Digest], model)
+		}
+	}
+
+	var groups []duplicateGroup
+	for digest, group := range byDigest {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Pinned != group[j].Pinned {
+				return group[i].Pinned
+			}
+			if len(group[i].Name) != len(group[j].Name) {
+				return len(group[i].Name) < len(group[j].Name)
+			}
+			return group[i].Name < group[j].Name
+		})
+		groups = append(groups, duplicateGroup{Digest: digest, ID: group[0].ID, Size: group[0].Size, Models: group})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Digest < groups[j].Digest
+	})
+	return groups
+}
+
+// markDuplicateModels sets Duplicates on each model to the number of models sharing its digest, 0 when it has none
+func markDuplicateModels(models []Model) {
+	counts := make(map[string]int)
+	for _, model := range models {
+		counts[model.Digest]++
+	}
+	for i, model := range models {
+		models[i].Duplicates = 0
+		if count := counts[model.Digest]; count > 1 && model.Digest != "" {
+			models[i].Duplicates = count
+		}
+	}
+}
+
+// extraDuplicates returns the models in each group other than the one kept, leaving out pinned models
+func extraDuplicates(groups []duplicateGroup) []Model {
+	var extras []Model
+	for _, group := range groups {
+		for _, model := range group.Models[1:] {
+			if !model.Pinned {
+				extras = append(extras, model)
+			}
+		}
+	}
+	return extras
+}
+
+func (m *AppModel) handleDupesKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("Dupes key matched")
+	if len(duplicateGroups(m.models)) == 0 {
+		m.message = "No models share the same blobs"
+		return m, nil
+	}
+	m.view = DupesView
+	return m, nil
+}
+
+// handleDupesViewKey handles keys in the duplicates view, a selects every duplicate but the first of each group
+func (m *AppModel) handleDupesViewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.view = MainView
+	case "a":
+		extras := extraDuplicates(duplicateGroups(m.models))
+		selected := make(map[string]bool, len(extras))
+		for _, model := range extras {
+			selected[model.Name] = true
+		}
+		m.view = MainView
+		m.message = fmt.Sprintf("Selected %d duplicate tags, press D to delete them", len(extras))
+		return m, m.setSelection(selected)
+	}
+	return m, nil
+}
+
+func (m *AppModel) dupesView() string {
+	colours := styles.Current().Colours
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.Title.TerminalColour())
+	faintStyle := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
+
+	groups := duplicateGroups(m.models)
+	var b strings.Builder
+	b.WriteString("\n" + titleStyle.Render("Models sharing the same blobs") + "\n")
+	for _, group := range groups {
+		fmt.Fprintf(&b, "\n%s  %s shared by %d tags\n", group.ID, formatBytes(group.Size), len(group.Models))
+		for i, model := range group.Models {
+			line := "  " + model.Name
+			if model.Pinned {
+				line += " 🔒"
+			}
+			if i == 0 {
+				line += faintStyle.Render("  (kept)")
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("\nPress 'a' to select every tag but the kept one for deletion, 'q' or `esc` to return to the main view.")
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDuplicateGroups(t *testing.T) {
+	newModel := func(name, digest string, size int64, pinned bool) Model {
+		model := Model{Pinned: pinned}
+		model.Name, model.Digest, model.ID, model.Size = name, digest, digest[:7], size
+		return model
+	}
+	models := []Model{
+		newModel("mistral:latest", "f974a74aa", 4<<30, false),
+		newModel("my-mistral:latest", "f974a74aa", 4<<30, false),
+		newModel("mistral:7b", "f974a74aa", 4<<30, false),
+		newModel("qwen2:72b", "c5e8a1baa", 40<<30, false),
+		newModel("qwen-pinned:latest", "c5e8a1baa", 40<<30, true),
+		newModel("phi3:mini", "d184c91aa", 2<<30, false),
+		// The same short ID with different digests aren't duplicates
+		newModel("gemma:2b", "a1b2c3daa", 1<<30, false),
+		newModel("gemma-tuned:2b", "a1b2c3dbb", 1<<30, false),
+	}
+
+	groups := duplicateGroups(models)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(groups), groups)
+	}
+	// Largest first, pinned models then the shortest name kept
+	if groups[0].ID != "c5e8a1b" || groups[0].Models[0].Name != "qwen-pinned:latest" {
+		t.Errorf("first group = %+v, want the qwen models with the pinned one kept", groups[0])
+	}
+	var names []string
+	for _, model := range groups[1].Models {
+		names = append(names, model.Name)
+	}
+	if !slices.Equal(names, []string{"mistral:7b", "mistral:latest", "my-mistral:latest"}) {
+		t.Errorf("mistral group = %v", names)
+	}
+
+	var extras []string
+	for _, model := range extraDuplicates(groups) {
+		extras = append(extras, model.Name)
+	}
+	if !slices.Equal(extras, []string{"qwen2:72b", "mistral:latest", "my-mistral:latest"}) {
+		t.Errorf("extraDuplicates() = %v", extras)
+	}
+
+	markDuplicateModels(models)
+	if models[0].IDLabel() != "f974a74 ×3" || models[3].IDLabel() != "c5e8a1b ×2" || models[5].IDLabel() != "d184c91" || models[6].IDLabel() != "a1b2c3d" {
+		t.Errorf("IDLabel() = %q, %q, %q, %q", models[0].IDLabel(), models[3].IDLabel(), models[5].IDLabel(), models[6].IDLabel())
+	}
+	markDuplicateModels(models[:1])
+	if models[0].Duplicates != 0 {
+		t.Errorf("Duplicates = %d once the other tags are gone, want 0", models[0].Duplicates)
+	}
+}
@@ -0,0 +1,258 @@
+// edit_batch.go lets -e take a glob pattern, editing the first matching model and applying the same change to the rest.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+
+	"github.com/sammcj/gollama/pkg/gollama"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// modelfileChange is a change to the template, system prompt or a parameter, values are empty when unset
+type modelfileChange struct {
+	Field string // "template", "system" or the parameter name
+	Old   []string
+	New   []string
+}
+
+// modelfileValues returns the template, system prompt and parameters of a modelfile keyed the same way as modelfileChange.Field
+func modelfileValues(modelfile string) map[string][]string {
+	values := make(map[string][]string)
+	template, system := gollama.ExtractTemplateAndSystem(modelfile)
+	if template != "" {
+		values["template"] = []string{template}
+	}
+	if system != "" {
+		values["system"] = []string{system}
+	}
+	for key, params := range gollama.ExtractParameters(modelfile) {
+		values[key] = params
+	}
+	return values
+}
+
+// modelfileDelta returns the template, system prompt and parameter changes between two versions of a modelfile
+func modelfileDelta(original, edited string) []modelfileChange {
+	before, after := modelfileValues(original), modelfileValues(edited)
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	var changes []modelfileChange
+	for field := range fields {
+		if !slices.Equal(before[field], after[field]) {
+			changes = append(changes, modelfileChange{Field: field, Old: before[field], New: after[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// applyModelfileDelta applies changes to a model's current values. It fails if any value the changes replace differs
+// from the original it was changed from, or if a change would remove a parameter (re-creating from a model keeps its parameters).
+func applyModelfileDelta(current map[string][]string, changes []modelfileChange) (map[string][]string, error) {
+	updated := make(map[string][]string, len(current))
+	for field, values := range current {
+		updated[field] = values
+	}
+	for _, change := range changes {
+		if !slices.Equal(current[change.Field], change.Old) {
+			return nil, fmt.Errorf("%s is %s, not %s", change.Field, formatModelfileValue(current[change.Field]), formatModelfileValue(change.Old))
+		}
+		if len(change.New) == 0 {
+			return nil, fmt.Errorf("removing %s isn't supported", change.Field)
+		}
+		updated[change.Field] = change.New
+	}
+	return updated, nil
+}
+
+func formatModelfileValue(values []string) string {
+	if len(values) == 0 {
+		return "unset"
+	}
+	value := strings.Join(values, ", ")
+	if len(value) > 40 || strings.Contains(value, "\n") {
+		return fmt.Sprintf("%q", truncate(strings.ReplaceAll(value, "\n", " "), 40))
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// matchModelNames returns the model names matching a glob pattern in their original order, or the pattern
+// itself if it has no glob characters so a single model still works as before
+func matchModelNames(pattern string, names []string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+	var matches []string
+	for _, name := range names {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		// Allow patterns without a tag to match tagged names, e.g. mycoder-* matches mycoder-7b:latest
+		if !ok && !strings.Contains(pattern, ":") {
+			ok, _ = path.Match(pattern, strings.SplitN(name, ":", 2)[0])
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	return matches, nil
+}
+
+// pendingEdit is a model the edit will be applied to with the values it will end up with
+type pendingEdit struct {
+	Model  string
+	Values map[string][]string
+}
+
+// runBatchEdit implements -e with a pattern: the first match is edited in the editor and the template, system and
+// parameter changes are then offered for the other matches. It returns the process exit code.
+func runBatchEdit(ctx context.Context, client *api.Client, pattern string, names []string) int {
+	matches, err := matchModelNames(pattern, names)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if len(matches) == 0 {
+		fmt.Printf("No models match %s\n", pattern)
+		return 1
+	}
+	if len(matches) == 1 {
+		if _, err := editModelfile(client, matches[0]); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return 0
+	}
+
+	first, rest := matches[0], matches[1:]
+	fmt.Printf("%d models match %s, editing %s first\n", len(matches), pattern, first)
+	show, err := client.Show(ctx, &api.ShowRequest{Name: first})
+	if err != nil {
+		fmt.Printf("Error fetching modelfile for %s: %v\n", first, err)
+		return 1
+	}
+	edited, err := openInEditor(first, show.Modelfile)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if edited == show.Modelfile {
+		fmt.Printf("No changes made to model %s\n", first)
+		return 0
+	}
+	if err := updateModelfile(ctx, client, first, edited); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	recordHistory("edit", first, "")
+	fmt.Printf("Model %s updated successfully\n", first)
+
+	changes := modelfileDelta(show.Modelfile, edited)
+	if len(changes) == 0 {
+		fmt.Println("The edit didn't change the template, system prompt or parameters, so there's nothing to apply to the other models")
+		return 0
+	}
+
+	// Preview the change for each model, skipping those it doesn't apply to cleanly
+	var pending []pendingEdit
+	for _, name := range rest {
+		show, err := client.Show(ctx, &api.ShowRequest{Name: name})
+		if err != nil {
+			fmt.Printf("%s: skipped, error fetching modelfile: %v\n", name, err)
+			continue
+		}
+		values, err := applyModelfileDelta(modelfileValues(show.Modelfile), changes)
+		if err != nil {
+			fmt.Printf("%s: skipped, %v\n", name, err)
+			continue
+		}
+		fmt.Printf("%s:\n", name)
+		for _, change := range changes {
+			fmt.Printf("  %s: %s -> %s\n", change.Field, formatModelfileValue(change.Old), formatModelfileValue(change.New))
+		}
+		pending = append(pending, pendingEdit{Model: name, Values: values})
+	}
+	if len(pending) == 0 {
+		fmt.Println("The change doesn't apply cleanly to any of the other models")
+		return 0
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Run from a terminal to confirm applying the change to the other models")
+		return 1
+	}
+	fmt.Printf("Apply the same change to %d model(s)? [y/N] ", len(pending))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Println("No other models changed")
+		return 0
+	}
+
+	var updated []string
+	failed := false
+	for _, edit := range pending {
+		if err := applyEdit(ctx, client, edit); err != nil {
+			fmt.Printf("%s: failed, %v\n", edit.Model, err)
+			failed = true
+			continue
+		}
+		recordHistory("edit", edit.Model, "same change as "+first)
+		updated = append(updated, edit.Model)
+		fmt.Printf("%s: updated\n", edit.Model)
+	}
+	recordHistory("batch-edit", first, fmt.Sprintf("%s applied to %s", pattern, strings.Join(updated, ", ")))
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// applyEdit re-creates a model from itself with its new template, system prompt and parameters
+func applyEdit(ctx context.Context, client *api.Client, edit pendingEdit) error {
+	if err := client.Create(ctx, modelfileCreateRequest(edit.Model, edit.Values), func(api.ProgressResponse) error { return nil }); err != nil {
+		return err
+	}
+	showcache.Invalidate(client, edit.Model)
+	return nil
+}
+
+// modelfileCreateRequest builds a request re-creating a model from itself with the template, system prompt and
+// parameters in values, keyed like modelfileValues
+func modelfileCreateRequest(modelName string, values map[string][]string) *api.CreateRequest {
+	params := make(map[string][]string)
+	for field, value := range values {
+		if field != "template" && field != "system" {
+			params[field] = value
+		}
+	}
+	req := &api.CreateRequest{
+		Model:      modelName,
+		From:       modelName,
+		Parameters: typedParameters(params),
+	}
+	if template := values["template"]; len(template) > 0 {
+		req.Template = template[0]
+	}
+	if system := values["system"]; len(system) > 0 {
+		req.System = system[0]
+	}
+	return req
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchModelNames(t *testing.T) {
+	names := []string{"mycoder-7b:latest", "mycoder-14b:q8_0", "llama3:8b", "mycoder:latest"}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"mycoder-*", []string{"mycoder-7b:latest", "mycoder-14b:q8_0"}},
+		{"mycoder-*:latest", []string{"mycoder-7b:latest"}},
+		{"llama3:8b", []string{"llama3:8b"}},
+		{"qwen*", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := matchModelNames(tt.pattern, names)
+			if err != nil {
+				t.Fatalf("matchModelNames() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("matchModelNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelfileDelta(t *testing.T) {
+	original := "FROM mycoder\nTEMPLATE \"{{ .Prompt }}\"\nPARAMETER num_ctx 4096\nPARAMETER temperature 0.2\n"
+	edited := "FROM mycoder\nTEMPLATE \"<s>{{ .Prompt }}\"\nPARAMETER num_ctx 8192\nPARAMETER temperature 0.2\n"
+	changes := modelfileDelta(original, edited)
+	if len(changes) != 2 || changes[0].Field != "num_ctx" || changes[1].Field != "template" {
+		t.Fatalf("modelfileDelta() = %+v, want num_ctx and template changes", changes)
+	}
+
+	tests := []struct {
+		name      string
+		modelfile string
+		wantErr   bool
+	}{
+		{"same originals", "FROM other\nTEMPLATE \"{{ .Prompt }}\"\nPARAMETER num_ctx 4096\nPARAMETER temperature 0.7\n", false},
+		{"different template", "FROM other\nTEMPLATE \"[INST]{{ .Prompt }}\"\nPARAMETER num_ctx 4096\n", true},
+		{"parameter unset", "FROM other\nTEMPLATE \"{{ .Prompt }}\"\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := applyModelfileDelta(modelfileValues(tt.modelfile), changes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyModelfileDelta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (values["num_ctx"][0] != "8192" || values["template"][0] != "<s>{{ .Prompt }}" || values["temperature"][0] != "0.7") {
+				t.Errorf("applyModelfileDelta() = %v", values)
+			}
+		})
+	}
+}
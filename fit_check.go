@@ -0,0 +1,137 @@
+// fit_check.go checks whether a model will fit alongside the models that are already running, from the memory they
+// use, an estimate of the model at its own quantisation and a chosen context, and the memory available.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+// fitCheckMsg is the result of checking whether a model fits alongside the running ones, sizes are in GB
+type fitCheckMsg struct {
+	model     string
+	context   int
+	resident  float64 // used by the other running models
+	needed    float64 // estimated for the model at its quantisation and the context
+	available float64
+	systemRAM bool // available is system RAM as GPU detection is unavailable
+	loaded    bool // the model is already running, so it isn't counted in resident
+	err       error
+}
+
+// residentMemory sums the vRAM the running models other than exclude use
+func residentMemory(running []runningModel, exclude string) (gb float64, excluded bool) {
+	var bytes int64
+	for _, model := range running {
+		if normaliseModelName(model.Name) == normaliseModelName(exclude) {
+			excluded = true
+			continue
+		}
+		bytes += model.SizeVRAM
+	}
+	return float64(bytes) / (1024 * 1024 * 1024), excluded
+}
+
+// headroom is the memory left once the model is loaded alongside the running ones, negative when it doesn't fit
+func (f fitCheckMsg) headroom() float64 {
+	return f.available - f.resident - f.needed
+}
+
+// summary reports the check, e.g. "qwen2:7b at 8K needs 5.1GB, running models use 12.4GB of 24.0GB. Fits: yes, 6.5GB headroom"
+func (f fitCheckMsg) summary() string {
+	memory := "vRAM"
+	if f.systemRAM {
+		memory = "memory"
+	}
+	summary := fmt.Sprintf("%s at %s context needs %.1fGB, running models use %.1fGB of %.1fGB %s. ",
+		f.model, contextLabel(f.context), f.needed, f.resident, f.available, memory)
+	if headroom := f.headroom(); headroom >= 0 {
+		summary += fmt.Sprintf("Fits: yes, %.1fGB headroom", headroom)
+	} else {
+		summary += fmt.Sprintf("Fits: no, %.1fGB short", -headroom)
+	}
+	var notes []string
+	if f.loaded {
+		notes = append(notes, "it's already running and not counted twice")
+	}
+	if f.systemRAM {
+		notes = append(notes, "GPU detection unavailable, estimated against system RAM")
+	}
+	if len(notes) > 0 {
+		summary += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return summary
+}
+
+func (m *AppModel) handleFitCheckKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("FitCheck key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	input, ok := promptForValue(fmt.Sprintf("Context size to check %s at (e.g. 8k, 32k): ", item.Name), "8k")
+	input = strings.TrimSpace(input)
+	if !ok || input == "" {
+		m.message = "Cancelled"
+		return m, nil
+	}
+	numCtx, err := parseContextSize(input)
+	if err != nil || numCtx <= 0 {
+		m.message = fmt.Sprintf("Error: %q isn't a context size, use a number such as 8192 or 8k", input)
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Checking whether %s fits alongside the running models...", item.Name)
+	return m, m.fitCheckCmd(item.Name, numCtx)
+}
+
+// fitCheckCmd estimates the model and measures the running models and available memory in the background. The memory
+// is this machine's, so the check is refused for remote hosts.
+func (m *AppModel) fitCheckCmd(modelName string, numCtx int) tea.Cmd {
+	apiURL, modelsDir, client := m.cfg.OllamaAPIURL, m.ollamaModelsDir, m.client
+	return func() tea.Msg {
+		msg := fitCheckMsg{model: modelName, context: numCtx}
+		if !isLocalhost(apiURL) {
+			msg.err = fmt.Errorf("the fit check measures this machine's memory, it isn't available for the remote host %s", apiURL)
+			return msg
+		}
+		running, err := listRunningModels(context.Background(), apiURL)
+		if err != nil {
+			msg.err = fmt.Errorf("error listing the running models: %w", err)
+			return msg
+		}
+		info, err := vramestimator.FetchOllamaModelInfo(apiHTTPClient, apiURL, modelName)
+		if err != nil {
+			msg.err = fmt.Errorf("error fetching the details of %s: %w", modelName, err)
+			return msg
+		}
+		if files, err := getModelFiles(modelName, modelsDir, client); err == nil {
+			info.ProjectorSize = files.ProjectorSize()
+		}
+		if msg.needed, err = vramestimator.CalculateVRAM(modelName, 0, numCtx, "", info); err != nil {
+			msg.err = fmt.Errorf("error estimating %s: %w", modelName, err)
+			return msg
+		}
+		if msg.available, msg.systemRAM, err = vramestimator.DetectAvailableMemory(); err != nil {
+			msg.err = fmt.Errorf("error detecting the available memory: %w", err)
+			return msg
+		}
+		msg.resident, msg.loaded = residentMemory(running, modelName)
+		return msg
+	}
+}
+
+func (m *AppModel) handleFitCheckMsg(msg fitCheckMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error checking whether %s fits: %v\n", msg.model, msg.err)
+		m.message = msg.err.Error()
+		return m, nil
+	}
+	m.message = msg.summary()
+	return m, nil
+}
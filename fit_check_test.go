@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestFitCheck(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	running := []runningModel{
+		{ProcessModelResponse: api.ProcessModelResponse{Name: "llama3:latest", Size: 8 * gb, SizeVRAM: 6 * gb}},
+		{ProcessModelResponse: api.ProcessModelResponse{Name: "qwen2:7b", Size: 5 * gb, SizeVRAM: 5 * gb}},
+	}
+
+	resident, loaded := residentMemory(running, "mistral")
+	if resident != 11 || loaded {
+		t.Errorf("residentMemory() = %v, %v, want the vRAM of both models", resident, loaded)
+	}
+	// The candidate isn't counted twice when it's already running
+	resident, loaded = residentMemory(running, "llama3")
+	if resident != 5 || !loaded {
+		t.Errorf("residentMemory() excluding llama3 = %v, %v, want 5 and loaded", resident, loaded)
+	}
+
+	fits := fitCheckMsg{model: "mistral:7b", context: 8192, resident: 11, needed: 9.8, available: 24}
+	if got := fits.headroom(); math.Abs(got-3.2) > 1e-9 {
+		t.Errorf("headroom() = %v, want 3.2", got)
+	}
+	if want := "mistral:7b at 8K context needs 9.8GB, running models use 11.0GB of 24.0GB vRAM. Fits: yes, 3.2GB headroom"; fits.summary() != want {
+		t.Errorf("summary() = %q, want %q", fits.summary(), want)
+	}
+
+	short := fitCheckMsg{model: "llama3:70b", context: 32768, resident: 5, needed: 40.5, available: 32, systemRAM: true, loaded: true}
+	if summary := short.summary(); !strings.Contains(summary, "Fits: no, 13.5GB short") || !strings.Contains(summary, "not counted twice") || !strings.Contains(summary, "GPU detection unavailable") {
+		t.Errorf("summary() = %q, want it short by 13.5GB with both notes", summary)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	m := &AppModel{cfg: &config.Config{OllamaAPIURL: server.URL}}
+	m.handleFitCheckMsg(m.fitCheckCmd("mistral:7b", 8192)().(fitCheckMsg))
+	if !strings.Contains(m.message, "error listing the running models") {
+		t.Errorf("message = %q, want the running models error", m.message)
+	}
+
+	// This machine's memory says nothing about a remote host
+	m.cfg.OllamaAPIURL = "http://gpu-box:11434"
+	m.handleFitCheckMsg(m.fitCheckCmd("mistral:7b", 8192)().(fitCheckMsg))
+	if !strings.Contains(m.message, "isn't available for the remote host") {
+		t.Errorf("message = %q, want the check refused for a remote host", m.message)
+	}
+}
@@ -3,11 +3,15 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/history"
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pins"
+	"github.com/sammcj/gollama/pkg/gollama"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/ollama/ollama/api"
@@ -17,24 +21,178 @@ import (
 func parseAPIResponse(resp *api.ListResponse) []Model {
 	logging.DebugLogger.Println("Fetching models from API")
 
-	models := make([]Model, len(resp.Models))
-	for i, modelResp := range resp.Models {
-		modelName := lipgloss.NewStyle().Foreground(lipgloss.Color("white")).Render(modelResp.Name)
-		models[i] = Model{
-			Name:              modelName,
-			ID:                truncate(modelResp.Digest, 7),                  // Truncate the ID
-			Size:              float64(modelResp.Size) / (1024 * 1024 * 1024), // Convert bytes to GB
-			QuantizationLevel: modelResp.Details.QuantizationLevel,
-			Family:            modelResp.Details.Family,
-			Modified:          modelResp.ModifiedAt,
-		}
+	pinned, err := pins.Load()
+	if err != nil {
+		logging.ErrorLogger.Printf("Error loading pinned models: %v\n", err)
+	}
+
+	canonical := gollama.ModelsFromResponse(resp)
+	models := make([]Model, len(canonical))
+	for i, model := range canonical {
+		_, isPinned := pinned[model.Digest]
+		model.Name = lipgloss.NewStyle().Foreground(lipgloss.Color("white")).Render(model.Name)
+		models[i] = Model{Model: model, Pinned: isPinned}
 	}
 	logging.DebugLogger.Println("Models:", models)
 	return models
 }
 
-func normalizeSize(size float64) float64 {
-	return size // Sizes are already in GB in the API response
+// recordHistory records an operation in the history store, failures are logged but never interrupt the operation
+func recordHistory(action, modelName, detail string) {
+	if err := history.Record(action, modelName, detail); err != nil {
+		logging.ErrorLogger.Printf("Error recording %s of %s in history: %v\n", action, modelName, err)
+	}
+}
+
+// recordPull records a successful pull along with the digest the model resolved to
+func recordPull(client *api.Client, modelName string) {
+	model, err := findModel(client, modelName)
+	if err != nil || model == nil {
+		logging.ErrorLogger.Printf("Error looking up digest of pulled model %s: %v\n", modelName, err)
+		recordHistory("pull", modelName, "")
+		return
+	}
+	if err := history.RecordPull(modelName, model.Digest); err != nil {
+		logging.ErrorLogger.Printf("Error recording pull of %s in history: %v\n", modelName, err)
+	}
+}
+
+// modelHistory returns the recorded events for a model, newest first
+func modelHistory(modelName string) ([]history.Event, error) {
+	events, err := history.Load()
+	if err != nil {
+		return nil, err
+	}
+	var matching []history.Event
+	for i := len(events) - 1; i >= 0; i-- {
+		if normaliseModelName(events[i].Model) == normaliseModelName(modelName) {
+			matching = append(matching, events[i])
+		}
+	}
+	return matching, nil
+}
+
+// pullHistory returns up to n recorded pulls of a model that include a digest, newest first
+func pullHistory(modelName string, n int) ([]history.Event, error) {
+	events, err := modelHistory(modelName)
+	if err != nil {
+		return nil, err
+	}
+	var pulls []history.Event
+	for _, event := range events {
+		if event.Action == "pull" && event.Digest != "" && len(pulls) < n {
+			pulls = append(pulls, event)
+		}
+	}
+	return pulls, nil
+}
+
+// printModelHistory prints the recorded history of a model for the -history flag
+func printModelHistory(modelName string) {
+	events, err := modelHistory(modelName)
+	if err != nil {
+		fmt.Println("Error reading history:", err)
+		os.Exit(1)
+	}
+	if len(events) == 0 {
+		fmt.Printf("No recorded history for %s\n", modelName)
+		return
+	}
+
+	var previousDigest string
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		line := fmt.Sprintf("%s  %-7s", event.Time.Format("2006-01-02 15:04"), event.Action)
+		if event.Digest != "" {
+			line += "  " + truncate(event.Digest, 12)
+			if previousDigest != "" && event.Digest != previousDigest {
+				line += "  (changed)"
+			}
+			previousDigest = event.Digest
+		}
+		if event.Detail != "" {
+			line += "  " + event.Detail
+		}
+		fmt.Println(line)
+	}
+}
+
+// pinnedModels returns the pinned models in models
+func pinnedModels(models []Model) []Model {
+	var pinned []Model
+	for _, model := range models {
+		if model.Pinned {
+			pinned = append(pinned, model)
+		}
+	}
+	return pinned
+}
+
+// listPinnedModels prints the pinned models, including pins whose model is no longer installed
+func listPinnedModels(models []Model) {
+	pinned, err := pins.Load()
+	if err != nil {
+		fmt.Println("Error loading pinned models:", err)
+		os.Exit(1)
+	}
+	if len(pinned) == 0 {
+		fmt.Println("No pinned models.")
+		return
+	}
+
+	for _, digest := range pins.Digests(pinned) {
+		var names []string
+		for _, model := range models {
+			if model.Digest == digest {
+				names = append(names, model.Name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Printf("%s  %s (not installed)\n", truncate(digest, 7), pinned[digest].Name)
+			continue
+		}
+		fmt.Printf("%s  %s\n", truncate(digest, 7), strings.Join(names, ", "))
+	}
+}
+
+// setModelPin pins or unpins a model by name for the -pin and -unpin flags
+func setModelPin(models []Model, pinName, unpinName string) error {
+	name := pinName
+	if name == "" {
+		name = unpinName
+	}
+	for _, model := range models {
+		if model.Name != name {
+			continue
+		}
+		if pinName != "" {
+			if err := pins.Pin(model.Digest, model.Name); err != nil {
+				return err
+			}
+			fmt.Printf("Pinned %s\n", model.Name)
+			return nil
+		}
+		if err := pins.Unpin(model.Digest); err != nil {
+			return err
+		}
+		fmt.Printf("Unpinned %s\n", model.Name)
+		return nil
+	}
+
+	// Allow removing pins for models that have since been deleted
+	if unpinName != "" {
+		pinned, err := pins.Load()
+		if err != nil {
+			return err
+		}
+		for digest, pin := range pinned {
+			if pin.Name == unpinName {
+				fmt.Printf("Unpinned %s\n", pin.Name)
+				return pins.Unpin(digest)
+			}
+		}
+	}
+	return fmt.Errorf("model %s not found", name)
 }
 
 func calculateColumnWidths(totalWidth int) (nameWidth, sizeWidth, quantWidth, modifiedWidth, idWidth, familyWidth int) {
@@ -134,13 +292,16 @@ func listModels(models []Model) {
 		fmt.Println("Error loading config:", err)
 		os.Exit(1)
 	}
+	printModelTable(os.Stdout, models, cfg.StripString)
+}
 
+// printModelTable writes the models as the coloured table -l prints
+func printModelTable(w io.Writer, models []Model, stripString string) {
 	if len(models) == 0 {
-		fmt.Println("No models available to display.")
+		fmt.Fprintln(w, "No models available to display.")
 		return
 	}
 
-	stripString := cfg.StripString
 	nameWidth, sizeWidth, quantWidth, modifiedWidth, idWidth, familyWidth := calculateColumnWidthsTerminal()
 
 	// Add extra spacing between columns
@@ -156,17 +317,11 @@ func listModels(models []Model) {
 		modifiedWidth+colSpacing, "Modified",
 		idWidth, "ID")
 
-	// if stripString is set, replace the model name with the stripped string
-	if stripString != "" {
-		for i, model := range models {
-			models[i].Name = strings.Replace(model.Name, stripString, "", 1)
-		}
-	}
-
-	// Prepare columns for padding
+	// Prepare columns for padding, the names without stripString
 	var names, sizes, quants, families, modified, ids []string
 	var longestName int
 	for _, model := range models {
+		model.Name = model.DisplayName(stripString)
 		if len(model.Name) > longestName {
 			longestName = len(model.Name)
 		}
@@ -175,7 +330,7 @@ func listModels(models []Model) {
 			model.Name = model.Name[:longestNameAllowed] + "..."
 		}
 		names = append(names, model.Name)
-		sizes = append(sizes, fmt.Sprintf("%.2fGB", model.Size))
+		sizes = append(sizes, model.SizeLabel())
 		quants = append(quants, model.QuantizationLevel)
 		families = append(families, model.Family)
 		modified = append(modified, model.Modified.Format("2006-01-02"))
@@ -208,7 +363,7 @@ func listModels(models []Model) {
 	}
 
 	// Print the header
-	fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(header))
+	fmt.Fprintln(w, lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(header))
 
 	modelList := []string{}
 
@@ -217,7 +372,7 @@ func listModels(models []Model) {
 		nameColours := []lipgloss.Color{lipgloss.Color("#FFFFFF"), lipgloss.Color("#818BA9")}
 		name := lipgloss.NewStyle().Foreground(nameColours[index%len(nameColours)]).Render(names[index])
 		id := lipgloss.NewStyle().Foreground(lipgloss.Color("254")).Faint(true).Render(ids[index])
-		size := lipgloss.NewStyle().Foreground(sizeColour(model.Size)).Render(sizes[index])
+		size := lipgloss.NewStyle().Foreground(sizeColour(model.SizeGB())).Render(sizes[index])
 		family := lipgloss.NewStyle().Foreground(familyColour(model.Family, 0)).Render(families[index])
 		quant := lipgloss.NewStyle().Foreground(quantColour(model.QuantizationLevel)).Render(quants[index])
 		modified := lipgloss.NewStyle().Foreground(lipgloss.Color("254")).Render(modified[index])
@@ -234,6 +389,6 @@ func listModels(models []Model) {
 
 	// Print the models with proper spacing
 	for _, row := range modelList {
-		fmt.Printf("%s\n", row)
+		fmt.Fprintf(w, "%s\n", row)
 	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/styles"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+// listFixture is a recorded /api/tags response
+const listFixture = `{"models":[
+{"name":"llama3.2:latest","model":"llama3.2:latest","modified_at":"2024-11-02T10:21:33.123456+11:00","size":2019393189,"digest":"a80c4f17acd55265feec403c7aef86be0c25983ab279d83f3bcd3abbcb5b8b72","details":{"parent_model":"","format":"gguf","family":"llama","families":["llama"],"parameter_size":"3.2B","quantization_level":"Q4_K_M"}},
+{"name":"nomic-embed-text:latest","model":"nomic-embed-text:latest","modified_at":"2024-06-14T08:00:00Z","size":274302450,"digest":"0a109f422b47e3a30ba2b10eca18548e944e8a23073ee3f3e947efcf3c45e59f","details":{"format":"gguf","family":"nomic-bert","parameter_size":"137M","quantization_level":"F16"}},
+{"name":"short","model":"short","modified_at":"0001-01-01T00:00:00Z","size":0,"digest":"abc","details":{}}
+]}`
+
+// legacyModel and legacyParseAPIResponse are the list view's parsing before it was built on gollama.Model,
+// kept to check the refactor didn't change what the TUI shows
+type legacyModel struct {
+	Name              string
+	ID                string
+	Size              float64
+	QuantizationLevel string
+	Modified          time.Time
+	Family            string
+	Digest            string
+}
+
+func legacyParseAPIResponse(resp *api.ListResponse) []legacyModel {
+	models := make([]legacyModel, len(resp.Models))
+	for i, modelResp := range resp.Models {
+		models[i] = legacyModel{
+			Name:              lipgloss.NewStyle().Foreground(lipgloss.Color("white")).Render(modelResp.Name),
+			ID:                truncate(modelResp.Digest, 7),
+			Size:              float64(modelResp.Size) / (1024 * 1024 * 1024),
+			QuantizationLevel: modelResp.Details.QuantizationLevel,
+			Family:            modelResp.Details.Family,
+			Modified:          modelResp.ModifiedAt,
+			Digest:            modelResp.Digest,
+		}
+	}
+	return models
+}
+
+func TestParseAPIResponseEquivalence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var resp api.ListResponse
+	if err := json.Unmarshal([]byte(listFixture), &resp); err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+
+	want := legacyParseAPIResponse(&resp)
+	got := parseAPIResponse(&resp)
+	if len(got) != len(want) {
+		t.Fatalf("parseAPIResponse() returned %d models, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g := legacyModel{
+			Name:              got[i].Name,
+			ID:                got[i].ID,
+			Size:              got[i].SizeGB(),
+			QuantizationLevel: got[i].QuantizationLevel,
+			Modified:          got[i].Modified,
+			Family:            got[i].Family,
+			Digest:            got[i].Digest,
+		}
+		if !g.Modified.Equal(want[i].Modified) {
+			t.Errorf("model %d Modified = %v, want %v", i, g.Modified, want[i].Modified)
+		}
+		g.Modified = want[i].Modified
+		if g != want[i] {
+			t.Errorf("model %d = %+v, want %+v", i, g, want[i])
+		}
+		if got[i].Selected || got[i].Pinned {
+			t.Errorf("model %d has view state set: selected %v, pinned %v", i, got[i].Selected, got[i].Pinned)
+		}
+	}
+}
+
+func TestPlainOutput(t *testing.T) {
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.TrueColor)
+
+	model := Model{}
+	model.Name, model.ID, model.Size = "qwen2:7b", "abc1234", 4400000000
+	model.QuantizationLevel, model.Family = "Q4_0", "qwen2"
+	model.Modified = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	table := vramestimator.QuantResultTable{
+		ModelID:  "qwen2",
+		FitsVRAM: 24,
+		Results:  []vramestimator.QuantResult{{QuantType: "Q4_0", BPW: 4.55, Contexts: map[int]vramestimator.ContextVRAM{32768: {VRAM: 9, VRAMQ8_0: 7, VRAMQ4_0: 6}}}},
+	}
+	render := func() string {
+		var out strings.Builder
+		printModelTable(&out, []Model{model}, "")
+		return out.String() + formatVRAMTable(table)
+	}
+
+	if out := render(); !strings.Contains(out, "\x1b[") {
+		t.Fatalf("output with colours has no escape sequences:\n%s", out)
+	}
+
+	styles.SetPlain(true)
+	defer styles.SetPlain(false)
+	out := render()
+	if strings.Contains(out, "\x1b") {
+		t.Errorf("plain output contains escape sequences:\n%q", out)
+	}
+	if !strings.Contains(out, "qwen2:7b") || !strings.Contains(out, "9.0(7.0,6.0)") {
+		t.Errorf("plain output is missing the models or estimates:\n%s", out)
+	}
+	if !styles.Colourless() {
+		t.Error("Colourless() = false with plain output on")
+	}
+}
@@ -0,0 +1,142 @@
+// history.go records the operations gollama performs on models so they can be shown later (e.g. in the dashboard).
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gollama/utils"
+)
+
+// Event is a single recorded operation against a model
+type Event struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // e.g. "pull", "push", "delete", "copy"
+	Model  string    `json:"model"`
+	Detail string    `json:"detail,omitempty"`
+	Digest string    `json:"digest,omitempty"` // the model's manifest digest after the operation, recorded for pulls
+}
+
+// maxEvents is the number of events kept in the history file, older events are dropped when it is rewritten
+const maxEvents = 500
+
+var mu sync.Mutex
+
+// Path returns the location of the history file
+func Path() string {
+	return filepath.Join(utils.GetConfigDir(), "history.jsonl")
+}
+
+// Record appends an event to the history file
+func Record(action, model, detail string) error {
+	return record(Event{Time: time.Now(), Action: action, Model: model, Detail: detail})
+}
+
+// RecordPull records a successful pull along with the manifest digest it resolved to,
+// so changes to tags such as latest can be seen over time
+func RecordPull(model, digest string) error {
+	return record(Event{Time: time.Now(), Action: "pull", Model: model, Digest: digest})
+}
+
+func record(event Event) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history event: %w", err)
+	}
+	return nil
+}
+
+// Load returns all events in the history file, oldest first
+func Load() ([]Event, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+func load() ([]Event, error) {
+	f, err := os.Open(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		// Skip lines we can't parse rather than failing the whole history
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep the file from growing forever
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+		if err := rewrite(events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func rewrite(events []Event) error {
+	tmpPath := Path() + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, Path())
+}
+
+// Recent returns the n most recent events, newest first
+func Recent(n int) ([]Event, error) {
+	events, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var recent []Event
+	for i := len(events) - 1; i >= 0 && len(recent) < n; i-- {
+		recent = append(recent, events[i])
+	}
+	return recent, nil
+}
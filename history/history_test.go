@@ -0,0 +1,96 @@
+package history
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRecordAndRecent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	events, err := Recent(5)
+	if err != nil {
+		t.Fatalf("Recent() on missing file error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Recent() on missing file = %v, want empty", events)
+	}
+
+	for _, model := range []string{"a", "b", "c"} {
+		if err := Record("pull", model, ""); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	events, err = Recent(2)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Model != "c" || events[1].Model != "b" {
+		t.Errorf("Recent(2) = %+v, want c then b", events)
+	}
+}
+
+func TestLoadSkipsMalformedLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := Record("delete", "a", ""); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	f, err := os.OpenFile(Path(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open history file: %v", err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+	if err := Record("copy", "b", "b-copy"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	events, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("Load() returned %d events, want 2", len(events))
+	}
+}
+
+func TestLoadTrimsHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for i := 0; i < maxEvents+10; i++ {
+		if err := Record("pull", "a", ""); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	events, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(events) != maxEvents {
+		t.Errorf("Load() returned %d events, want %d", len(events), maxEvents)
+	}
+}
+
+func TestRecordPull(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := RecordPull("llama3:latest", "sha256:aaa"); err != nil {
+		t.Fatalf("RecordPull() error = %v", err)
+	}
+	if err := RecordPull("llama3:latest", "sha256:bbb"); err != nil {
+		t.Fatalf("RecordPull() error = %v", err)
+	}
+
+	events, err := Recent(5)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Recent() returned %d events, want 2", len(events))
+	}
+	if events[0].Action != "pull" || events[0].Digest != "sha256:bbb" || events[1].Digest != "sha256:aaa" {
+		t.Errorf("Recent() = %+v, want pulls of sha256:bbb then sha256:aaa", events)
+	}
+}
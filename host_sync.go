@@ -0,0 +1,201 @@
+// host_sync.go implements -compare-hosts, which lists the models only on this host, only on another one and on both.
+// Models are matched by digest so copies under other names are found, falling back to the name and tag. With
+// -sync-missing it prints the pull commands that fill in each side, and with -execute copies the models only on this
+// host to the other one as t does in the TUI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// hostPair is a model in the union of two hosts' models, Local or Remote is nil when only the other host has it
+type hostPair struct {
+	Local  *api.ListModelResponse
+	Remote *api.ListModelResponse
+}
+
+// Name is the model's name on this host, or on the other one when only it has the model
+func (p hostPair) Name() string {
+	if p.Local != nil {
+		return p.Local.Name
+	}
+	return p.Remote.Name
+}
+
+// hostDiff is the models of two hosts split by where they are
+type hostDiff struct {
+	LocalOnly  []hostPair
+	RemoteOnly []hostPair
+	Both       []hostPair
+}
+
+// diffHosts matches the models of two hosts. A model is matched to one on the other host with its digest, preferring
+// the same name when there are copies, then to one with the same name and tag whose digest differs. Each model on the
+// other host is matched at most once.
+func diffHosts(local, remote []api.ListModelResponse) hostDiff {
+	var diff hostDiff
+	matched := make([]bool, len(remote))
+	find := func(match func(api.ListModelResponse) bool) int {
+		for i, model := range remote {
+			if !matched[i] && match(model) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i := range local {
+		model := &local[i]
+		name := normaliseModelName(model.Name)
+		j := find(func(r api.ListModelResponse) bool {
+			return r.Digest == model.Digest && normaliseModelName(r.Name) == name
+		})
+		if j < 0 {
+			j = find(func(r api.ListModelResponse) bool { return r.Digest == model.Digest })
+		}
+		if j < 0 {
+			j = find(func(r api.ListModelResponse) bool { return normaliseModelName(r.Name) == name })
+		}
+		if j < 0 {
+			diff.LocalOnly = append(diff.LocalOnly, hostPair{Local: model})
+			continue
+		}
+		matched[j] = true
+		diff.Both = append(diff.Both, hostPair{Local: model, Remote: &remote[j]})
+	}
+	for i := range remote {
+		if !matched[i] {
+			diff.RemoteOnly = append(diff.RemoteOnly, hostPair{Remote: &remote[i]})
+		}
+	}
+
+	for _, pairs := range [][]hostPair{diff.LocalOnly, diff.RemoteOnly, diff.Both} {
+		sort.Slice(pairs, func(a, b int) bool { return pairs[a].Name() < pairs[b].Name() })
+	}
+	return diff
+}
+
+// resolveHost returns the URL of a host given by its name under hosts in the config, or as a URL or OLLAMA_HOST style
+// value
+func resolveHost(cfg config.Config, host string) string {
+	if hostURL, ok := cfg.Hosts[host]; ok {
+		return config.NormaliseHost(hostURL)
+	}
+	return config.NormaliseHost(host)
+}
+
+// writeHostDiff prints the three lists with each model's size, noting models matched under another name or with a
+// different digest
+func writeHostDiff(out io.Writer, diff hostDiff, localURL, remoteURL string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	section := func(title string, pairs []hostPair) {
+		fmt.Fprintf(w, "%s (%d):\n", title, len(pairs))
+		if len(pairs) == 0 {
+			fmt.Fprintln(w, "  none")
+		}
+		for _, pair := range pairs {
+			model := pair.Local
+			if model == nil {
+				model = pair.Remote
+			}
+			var notes []string
+			if pair.Local != nil && pair.Remote != nil {
+				if normaliseModelName(pair.Remote.Name) != normaliseModelName(pair.Local.Name) {
+					notes = append(notes, "as "+pair.Remote.Name)
+				}
+				if pair.Remote.Digest != pair.Local.Digest {
+					notes = append(notes, fmt.Sprintf("different digest %s here, %s there", truncate(pair.Local.Digest, 12), truncate(pair.Remote.Digest, 12)))
+				}
+			}
+			fmt.Fprintf(w, "  %s\t%.2fGB\t%s\n", model.Name, float64(model.Size)/(1024*1024*1024), strings.Join(notes, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	section("Only on "+localURL, diff.LocalOnly)
+	section("Only on "+remoteURL, diff.RemoteOnly)
+	section("On both", diff.Both)
+	return w.Flush()
+}
+
+// syncCommands returns the ollama pull commands that give each host the models only the other one has
+func syncCommands(diff hostDiff, localURL, remoteURL string) []string {
+	var commands []string
+	for _, pair := range diff.LocalOnly {
+		commands = append(commands, fmt.Sprintf("OLLAMA_HOST=%s ollama pull %s", remoteURL, pair.Local.Name))
+	}
+	for _, pair := range diff.RemoteOnly {
+		commands = append(commands, fmt.Sprintf("OLLAMA_HOST=%s ollama pull %s", localURL, pair.Remote.Name))
+	}
+	return commands
+}
+
+// runCompareHosts implements -compare-hosts and returns the exit code. The other host is reached with httpClient, which
+// has the configured CA certificate like the client for the Ollama API.
+func runCompareHosts(ctx context.Context, client *api.Client, httpClient *http.Client, cfg config.Config, modelsDir, host string, syncMissing, execute bool) int {
+	remoteURL := resolveHost(cfg, host)
+	remoteHost, err := url.Parse(remoteURL)
+	if err != nil || remoteHost.Host == "" {
+		fmt.Fprintf(os.Stderr, "Invalid host %q\n", host)
+		return 1
+	}
+
+	local, err := client.List(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing the models on %s: %v\n", cfg.OllamaAPIURL, err)
+		return 1
+	}
+	listCtx, cancel := context.WithTimeout(ctx, hostQueryTimeout)
+	remote, err := api.NewClient(remoteHost, httpClient).List(listCtx)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing the models on %s: %v\n", remoteURL, err)
+		return 1
+	}
+
+	diff := diffHosts(local.Models, remote.Models)
+	if err := writeHostDiff(os.Stdout, diff, cfg.OllamaAPIURL, remoteURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the comparison: %v\n", err)
+		return 1
+	}
+	if !syncMissing {
+		return 0
+	}
+
+	if !execute {
+		for _, command := range syncCommands(diff, cfg.OllamaAPIURL, remoteURL) {
+			fmt.Println(command)
+		}
+		return 0
+	}
+
+	// Copies only go from this host, so the other host's models are still left as pull commands
+	code := 0
+	for _, pair := range diff.LocalOnly {
+		fmt.Printf("Copying %s to %s...\n", pair.Local.Name, remoteURL)
+		err := transferModel(ctx, client, httpClient, pair.Local.Name, modelsDir, remoteURL, func(api.ProgressResponse) error { return ctx.Err() })
+		if err != nil {
+			logging.ErrorLogger.Printf("Error copying %s to %s: %v\n", pair.Local.Name, remoteURL, err)
+			fmt.Fprintf(os.Stderr, "Error copying %s: %v\n", pair.Local.Name, err)
+			code = 1
+			continue
+		}
+		recordHistory("transfer", pair.Local.Name, remoteURL)
+	}
+	for _, command := range syncCommands(hostDiff{RemoteOnly: diff.RemoteOnly}, cfg.OllamaAPIURL, remoteURL) {
+		fmt.Println(command)
+	}
+	return code
+}
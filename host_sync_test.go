@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestDiffHosts(t *testing.T) {
+	local := []api.ListModelResponse{
+		{Name: "llama3:8b", Digest: "aaa", Size: 4 << 30},
+		{Name: "qwen2:7b", Digest: "bbb"},
+		{Name: "mine:latest", Digest: "ccc"},
+		{Name: "mistral:7b", Digest: "ddd"},
+		{Name: "only-here:latest", Digest: "eee"},
+	}
+	remote := []api.ListModelResponse{
+		{Name: "llama3:8b", Digest: "aaa"},
+		{Name: "copy-of-llama3:latest", Digest: "aaa"},
+		{Name: "renamed:latest", Digest: "ccc"},
+		{Name: "mistral", Digest: "fff"},
+		{Name: "only-there:latest", Digest: "ggg"},
+		{Name: "qwen2:7b-instruct", Digest: "hhh"},
+	}
+	diff := diffHosts(local, remote)
+
+	names := func(pairs []hostPair, remoteName bool) []string {
+		var out []string
+		for _, pair := range pairs {
+			if remoteName {
+				out = append(out, pair.Local.Name+"="+pair.Remote.Name)
+			} else {
+				out = append(out, pair.Name())
+			}
+		}
+		return out
+	}
+	// The same digest is matched by name before a copy under another name, mistral:7b and mistral:latest aren't matched
+	if got, want := names(diff.Both, true), []string{"llama3:8b=llama3:8b", "mine:latest=renamed:latest"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("on both = %q, want %q", got, want)
+	}
+	if got, want := names(diff.LocalOnly, false), []string{"mistral:7b", "only-here:latest", "qwen2:7b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("only local = %q, want %q", got, want)
+	}
+	if got, want := names(diff.RemoteOnly, false), []string{"copy-of-llama3:latest", "mistral", "only-there:latest", "qwen2:7b-instruct"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("only remote = %q, want %q", got, want)
+	}
+
+	// A name and tag match with a different digest counts as on both
+	diff = diffHosts([]api.ListModelResponse{{Name: "phi3", Digest: "111"}}, []api.ListModelResponse{{Name: "phi3:latest", Digest: "222"}})
+	if len(diff.Both) != 1 || len(diff.LocalOnly) != 0 || len(diff.RemoteOnly) != 0 {
+		t.Errorf("diffHosts() by name = %+v", diff)
+	}
+	var out strings.Builder
+	if err := writeHostDiff(&out, diff, "http://here:11434", "http://there:11434"); err != nil || !strings.Contains(out.String(), "different digest 111 here, 222 there") {
+		t.Errorf("writeHostDiff() = %q, %v", out.String(), err)
+	}
+
+	diff = diffHosts(local[4:], remote[4:5])
+	want := []string{"OLLAMA_HOST=http://there:11434 ollama pull only-here:latest", "OLLAMA_HOST=http://here:11434 ollama pull only-there:latest"}
+	if got := syncCommands(diff, "http://here:11434", "http://there:11434"); !reflect.DeepEqual(got, want) {
+		t.Errorf("syncCommands() = %q, want %q", got, want)
+	}
+
+	// The other host is listed with the configured client, here the only one that trusts its certificate
+	localServer := httptest.NewServer(http.HandlerFunc((&fakeOllama{models: []string{"llama3:8b"}}).handler))
+	defer localServer.Close()
+	remoteServer := httptest.NewTLSServer(http.HandlerFunc((&fakeOllama{models: []string{"llama3:8b"}}).handler))
+	defer remoteServer.Close()
+	u, _ := url.Parse(localServer.URL)
+	cfg := config.Config{OllamaAPIURL: localServer.URL}
+	if code := runCompareHosts(context.Background(), api.NewClient(u, http.DefaultClient), remoteServer.Client(), cfg, t.TempDir(), remoteServer.URL, false, false); code != 0 {
+		t.Errorf("runCompareHosts() with the configured client = %d, want 0", code)
+	}
+}
@@ -0,0 +1,219 @@
+// hosts.go compares the model under the cursor with models of the same name on the other configured Ollama hosts.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// hostQueryTimeout bounds how long each host is given to answer so an unreachable one doesn't hold up the rest
+const hostQueryTimeout = 3 * time.Second
+
+// Comparison states for a model on another host
+const (
+	hostMatch       = "match"
+	hostMismatch    = "different"
+	hostMissing     = "not present"
+	hostUnreachable = "unreachable"
+)
+
+// hostModel is a model of the same name on another host
+type hostModel struct {
+	Host     string
+	URL      string
+	Status   string
+	Digest   string
+	Size     int64
+	Modified time.Time
+	Err      error
+}
+
+type hostCompareMsg struct {
+	model   string
+	results []hostModel
+}
+
+// hostCompare is the state of the host comparison view
+type hostCompare struct {
+	model   Model
+	results []hostModel
+	loading bool
+	cache   map[string][]hostModel // session cache keyed by model name
+}
+
+// otherHosts returns the configured hosts other than the one gollama is connected to, sorted by name
+func otherHosts(hosts map[string]string, current string) []string {
+	var names []string
+	for name, hostURL := range hosts {
+		if strings.TrimSuffix(hostURL, "/") == strings.TrimSuffix(current, "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compareAcrossHosts looks up modelName on each host concurrently and compares it to the local digest.
+// httpClient carries the configured CA, the API credentials are only sent to the configured API host.
+func compareAcrossHosts(ctx context.Context, httpClient *http.Client, hosts map[string]string, names []string, modelName, digest string) []hostModel {
+	results := make([]hostModel, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = queryHost(ctx, httpClient, name, hosts[name], modelName, digest)
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+func queryHost(ctx context.Context, httpClient *http.Client, name, hostURL, modelName, digest string) hostModel {
+	result := hostModel{Host: name, URL: hostURL}
+
+	u, err := url.Parse(hostURL)
+	if err != nil {
+		result.Status, result.Err = hostUnreachable, err
+		return result
+	}
+	ctx, cancel := context.WithTimeout(ctx, hostQueryTimeout)
+	defer cancel()
+
+	resp, err := api.NewClient(u, httpClient).List(ctx)
+	if err != nil {
+		logging.DebugLogger.Printf("Error listing models on host %s (%s): %v\n", name, hostURL, err)
+		result.Status, result.Err = hostUnreachable, err
+		return result
+	}
+
+	want := normaliseModelName(modelName)
+	for _, model := range resp.Models {
+		if normaliseModelName(model.Name) != want {
+			continue
+		}
+		result.Digest, result.Size, result.Modified = model.Digest, model.Size, model.ModifiedAt
+		if model.Digest == digest {
+			result.Status = hostMatch
+		} else {
+			result.Status = hostMismatch
+		}
+		return result
+	}
+	result.Status = hostMissing
+	return result
+}
+
+func (m *AppModel) handleCompareHostsKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("CompareHosts key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	if len(otherHosts(m.cfg.Hosts, m.cfg.OllamaAPIURL)) == 0 {
+		m.message = "No other hosts configured, add them under hosts in the config file"
+		return m, nil
+	}
+
+	if m.hostCompare.cache == nil {
+		m.hostCompare.cache = make(map[string][]hostModel)
+	}
+	m.hostCompare.model = item
+	m.view = HostsView
+	if results, ok := m.hostCompare.cache[item.Name]; ok {
+		m.hostCompare.results, m.hostCompare.loading = results, false
+		return m, nil
+	}
+	return m, m.fetchHostComparison(item)
+}
+
+// fetchHostComparison queries the other hosts in the background
+func (m *AppModel) fetchHostComparison(item Model) tea.Cmd {
+	m.hostCompare.results, m.hostCompare.loading = nil, true
+	hosts := m.cfg.Hosts
+	names := otherHosts(hosts, m.cfg.OllamaAPIURL)
+	return func() tea.Msg {
+		return hostCompareMsg{model: item.Name, results: compareAcrossHosts(context.Background(), apiHTTPClient, hosts, names, item.Name, item.Digest)}
+	}
+}
+
+func (m *AppModel) handleHostCompareMsg(msg hostCompareMsg) (tea.Model, tea.Cmd) {
+	m.hostCompare.cache[msg.model] = msg.results
+	if m.hostCompare.model.Name == msg.model {
+		m.hostCompare.results, m.hostCompare.loading = msg.results, false
+	}
+	return m, nil
+}
+
+// handleHostsViewKey handles keys in the host comparison view, r queries the hosts again
+func (m *AppModel) handleHostsViewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.view = MainView
+	case "r":
+		if !m.hostCompare.loading {
+			return m, m.fetchHostComparison(m.hostCompare.model)
+		}
+	}
+	return m, nil
+}
+
+func (m *AppModel) hostsView() string {
+	model := m.hostCompare.model
+	title := lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("%s across hosts", model.Name))
+	if m.hostCompare.loading {
+		return "\n" + title + "\n\nQuerying other hosts..."
+	}
+
+	colours := styles.Current().Colours
+	statusStyles := map[string]lipgloss.Style{
+		hostMatch:       lipgloss.NewStyle().Foreground(colours.Message.TerminalColour()),
+		hostMismatch:    lipgloss.NewStyle().Foreground(colours.Warning.TerminalColour()),
+		hostMissing:     lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour()),
+		hostUnreachable: lipgloss.NewStyle().Foreground(colours.Error.TerminalColour()),
+	}
+
+	rows := []table.Row{{"this host", truncate(model.Digest, 12), fmt.Sprintf("%.2fGB", model.SizeGB()), model.Modified.Format("2006-01-02"), "-"}}
+	for _, result := range m.hostCompare.results {
+		row := table.Row{result.Host, "", "", "", statusStyles[result.Status].Render(result.Status)}
+		if result.Status == hostMatch || result.Status == hostMismatch {
+			row[1] = truncate(result.Digest, 12)
+			row[2] = fmt.Sprintf("%.2fGB", float64(result.Size)/(1024*1024*1024))
+			row[3] = result.Modified.Format("2006-01-02")
+		}
+		rows = append(rows, row)
+	}
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Host", Width: 20},
+			{Title: "Digest", Width: 14},
+			{Title: "Size", Width: 10},
+			{Title: "Modified", Width: 12},
+			{Title: "Compared", Width: 14},
+		}),
+		table.WithRows(rows),
+		table.WithHeight(len(rows)+1),
+	)
+	s := table.DefaultStyles()
+	s.Header = s.Header.BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240"))
+	s.Selected = lipgloss.NewStyle()
+	t.SetStyles(s)
+
+	return "\n" + title + "\n\n" + t.View() + "\nPress 'r' to refresh, 'q' or `esc` to return to the main view."
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestCompareAcrossHosts(t *testing.T) {
+	listServer := func(models ...api.ListModelResponse) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(api.ListResponse{Models: models})
+		}))
+	}
+	same := listServer(api.ListModelResponse{Name: "llama3:latest", Digest: "abc", Size: 1 << 30})
+	defer same.Close()
+	different := listServer(api.ListModelResponse{Name: "llama3", Digest: "def"})
+	defer different.Close()
+	missing := listServer(api.ListModelResponse{Name: "mistral:latest", Digest: "abc"})
+	defer missing.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	hosts := map[string]string{
+		"current":   "http://127.0.0.1:11434",
+		"same":      same.URL,
+		"different": different.URL,
+		"missing":   missing.URL,
+		"down":      down.URL,
+	}
+	names := otherHosts(hosts, "http://127.0.0.1:11434/")
+	if len(names) != 4 || names[0] != "different" {
+		t.Fatalf("otherHosts() = %v, want the four other hosts sorted", names)
+	}
+
+	want := map[string]string{"same": hostMatch, "different": hostMismatch, "missing": hostMissing, "down": hostUnreachable}
+	for _, result := range compareAcrossHosts(context.Background(), http.DefaultClient, hosts, names, "llama3:latest", "abc") {
+		if result.Status != want[result.Host] {
+			t.Errorf("host %s status = %q, want %q", result.Host, result.Status, want[result.Host])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// inspect_edit.go lets a parameter be edited in place from the inspect view rather than in the full modelfile editor.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// inspectEdit is the state of the inspect view's row cursor and the parameter being edited
+type inspectEdit struct {
+	cursor int
+	param  string // parameter being edited, empty when not editing
+	input  textinput.Model
+	rows   int // number of rows last rendered, to keep the cursor in range
+}
+
+// integerParameters and floatParameters are the modelfile parameters that must be numbers
+var (
+	integerParameters = map[string]bool{
+		"num_ctx": true, "num_predict": true, "num_keep": true, "num_batch": true, "num_gpu": true, "num_thread": true,
+		"repeat_last_n": true, "seed": true, "top_k": true, "mirostat": true,
+	}
+	floatParameters = map[string]bool{
+		"temperature": true, "top_p": true, "min_p": true, "typical_p": true, "tfs_z": true, "repeat_penalty": true,
+		"presence_penalty": true, "frequency_penalty": true, "mirostat_eta": true, "mirostat_tau": true,
+	}
+)
+
+// validateParameter checks a new value for a known numeric parameter is a number
+func validateParameter(param, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s can't be empty", param)
+	}
+	if integerParameters[param] {
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be a whole number, not %q", param, value)
+		}
+	}
+	if floatParameters[param] {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number, not %q", param, value)
+		}
+	}
+	return nil
+}
+
+// parameterValues splits an edited value back into the values of a parameter, stop may have several separated by commas
+func parameterValues(param, value string) []string {
+	if param != "stop" {
+		return []string{value}
+	}
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// updateModelParameter re-creates a model from itself with one parameter changed, keeping its template, system prompt and other parameters
+func updateModelParameter(ctx context.Context, client *api.Client, modelName, param, value string) error {
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return fmt.Errorf("error fetching modelfile for %s: %w", modelName, err)
+	}
+	values := modelfileValues(show.Modelfile)
+	values[param] = parameterValues(param, value)
+	if err := applyEdit(ctx, client, pendingEdit{Model: modelName, Values: values}); err != nil {
+		return fmt.Errorf("error updating %s: %w", modelName, err)
+	}
+	return nil
+}
+
+// handleInspectKey moves the inspect view's cursor, edits the parameter under it with e and opens the licence with L
+func (m *AppModel) handleInspectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if m.inspectLicence.shown {
+		return m.handleInspectLicenceKey(msg)
+	}
+	edit := &m.inspectEdit
+	if edit.param != "" {
+		switch msg.String() {
+		case "esc":
+			edit.param = ""
+			m.message = ""
+			return m, nil, true
+		case "enter":
+			return m, m.applyInspectEdit(), true
+		}
+		var cmd tea.Cmd
+		edit.input, cmd = edit.input.Update(msg)
+		return m, cmd, true
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if edit.cursor > 0 {
+			edit.cursor--
+		}
+		return m, nil, true
+	case "down", "j":
+		if edit.cursor < edit.rows-1 {
+			edit.cursor++
+		}
+		return m, nil, true
+	case "e":
+		return m.startInspectEdit()
+	case "L":
+		return m.toggleInspectLicence()
+	}
+	return m, nil, false
+}
+
+// startInspectEdit opens an input for the parameter in the row under the cursor
+func (m *AppModel) startInspectEdit() (tea.Model, tea.Cmd, bool) {
+	rows, params := m.inspectRows(m.inspectedModel)
+	if m.inspectEdit.cursor >= len(rows) || !params[rows[m.inspectEdit.cursor][0]] {
+		m.message = "Only parameters can be edited, move to a parameter row and press e"
+		return m, nil, true
+	}
+
+	row := rows[m.inspectEdit.cursor]
+	input := textinput.New()
+	input.Prompt = row[0] + ": "
+	input.SetValue(row[1])
+	input.CursorEnd()
+	input.Focus()
+	m.inspectEdit.param = row[0]
+	m.inspectEdit.input = input
+	m.message = ""
+	return m, textinput.Blink, true
+}
+
+// applyInspectEdit validates the edited value and applies it in the background
+func (m *AppModel) applyInspectEdit() tea.Cmd {
+	param, value := m.inspectEdit.param, strings.TrimSpace(m.inspectEdit.input.Value())
+	if err := validateParameter(param, value); err != nil {
+		m.message = err.Error()
+		return nil
+	}
+	m.inspectEdit.param = ""
+	modelName := m.inspectedModel.Name
+	m.message = fmt.Sprintf("Setting %s to %s on %s...", param, value, modelName)
+	return func() tea.Msg {
+		if err := updateModelParameter(context.Background(), m.client, modelName, param, value); err != nil {
+			logging.ErrorLogger.Printf("Error setting %s on %s: %v\n", param, modelName, err)
+			return genericMsg{message: fmt.Sprintf("Error setting %s: %v", param, err)}
+		}
+		logging.InfoLogger.Printf("Set %s to %s on %s\n", param, value, modelName)
+		recordHistory("edit", modelName, fmt.Sprintf("%s %s", param, value))
+		return genericMsg{message: fmt.Sprintf("Set %s to %s on %s", param, value, modelName)}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestUpdateModelParameter(t *testing.T) {
+	for _, tt := range []struct {
+		param, value string
+		wantErr      bool
+	}{
+		{"num_ctx", "8192", false},
+		{"num_ctx", "8k", true},
+		{"temperature", "0.7", false},
+		{"temperature", "warm", true},
+		{"stop", "<|im_end|>", false},
+		{"num_ctx", "", true},
+	} {
+		if err := validateParameter(tt.param, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("validateParameter(%q, %q) error = %v, wantErr %v", tt.param, tt.value, err, tt.wantErr)
+		}
+	}
+
+	var created api.CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM llama3\nTEMPLATE \"\"\"{{ .Prompt }}\"\"\"\nPARAMETER num_ctx 2048\nPARAMETER stop <|eot_id|>\nPARAMETER temperature 0.2\n"})
+		case "/api/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"status":"success"}` + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	if err := updateModelParameter(context.Background(), client, "mine:latest", "num_ctx", "8192"); err != nil {
+		t.Fatalf("updateModelParameter() error: %v", err)
+	}
+	if created.Model != "mine:latest" || created.From != "mine:latest" || created.Template != "{{ .Prompt }}" {
+		t.Errorf("create request = %+v", created)
+	}
+	if created.Parameters["num_ctx"] != float64(8192) || created.Parameters["temperature"] != 0.2 {
+		t.Errorf("create parameters = %v, want num_ctx 8192 and temperature kept", created.Parameters)
+	}
+
+	if got := parameterValues("stop", "<|eot_id|>, <|end|>"); len(got) != 2 || got[1] != "<|end|>" {
+		t.Errorf("parameterValues(stop) = %v", got)
+	}
+}
+
+func TestStartInspectEditRemote(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM llama3\nPARAMETER temperature 0.2\n"})
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	// The edit re-creates the model from itself on the server, so it works on a remote host too
+	m := &AppModel{cfg: &config.Config{OllamaAPIURL: "http://gpu-box:11434"}, client: api.NewClient(u, http.DefaultClient)}
+	m.inspectedModel.Name = "remote:latest"
+	rows, _ := m.inspectRows(m.inspectedModel)
+	m.inspectEdit.cursor = len(rows) - 1
+	m.startInspectEdit()
+	if m.inspectEdit.param != "temperature" {
+		t.Errorf("editing a parameter on a remote host opened %q with message %q, want temperature", m.inspectEdit.param, m.message)
+	}
+}
@@ -0,0 +1,96 @@
+// inspect_layers.go lists the blobs behind a model in the inspect view, with their digests and where they are on disk,
+// so a missing blob explains a "no such file" failure straight away.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/styles"
+)
+
+// inspectLayer is a manifest layer of the inspected model and the blob it resolves to
+type inspectLayer struct {
+	MediaType string
+	Digest    string
+	Size      int64
+	Path      string
+	Exists    bool
+}
+
+// inspectLayers are the layers of the inspected model, read from its manifest when the inspect view opens
+type inspectLayers struct {
+	model  string
+	layers []inspectLayer
+	remote bool // the host isn't local, so its manifests aren't on this machine
+	err    error
+}
+
+// readInspectLayers reads a model's manifest from the models directory, its config first, and checks each blob exists
+func readInspectLayers(modelsDir, modelName string) ([]inspectLayer, error) {
+	manifest, err := readManifest(modelsDir, modelName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the manifest of %s: %w", modelName, err)
+	}
+	var layers []inspectLayer
+	for _, layer := range append([]manifestLayer{manifest.Config}, manifest.Layers...) {
+		if layer.Digest == "" {
+			continue
+		}
+		path := blobPath(modelsDir, layer.Digest)
+		_, err := os.Stat(path)
+		layers = append(layers, inspectLayer{MediaType: layer.MediaType, Digest: layer.Digest, Size: layer.Size, Path: path, Exists: err == nil})
+	}
+	return layers, nil
+}
+
+// loadInspectLayers reads the layers of the model being inspected, only local hosts have their blobs on this machine
+func (m *AppModel) loadInspectLayers(modelName string) {
+	m.inspectLayers = inspectLayers{model: modelName}
+	if !isLocalhost(m.cfg.OllamaAPIURL) {
+		m.inspectLayers.remote = true
+		return
+	}
+	m.inspectLayers.layers, m.inspectLayers.err = readInspectLayers(m.ollamaModelsDir, modelName)
+}
+
+// layerKind shortens a layer's media type, e.g. application/vnd.ollama.image.model to model
+func layerKind(mediaType string) string {
+	if i := strings.LastIndex(mediaType, "."); i >= 0 {
+		return mediaType[i+1:]
+	}
+	return mediaType
+}
+
+// inspectLayersView renders the layers section of the inspect view with missing blobs in the error colour
+func (m *AppModel) inspectLayersView() string {
+	layers := m.inspectLayers
+	if layers.model != m.inspectedModel.Name {
+		return ""
+	}
+	title := "\n" + lipgloss.NewStyle().Bold(true).Render("Layers") + "\n"
+	if layers.remote {
+		return title + "  Not shown for remote hosts, their blobs aren't on this machine.\n"
+	}
+	colours := styles.Current().Colours
+	errorStyle := lipgloss.NewStyle().Foreground(colours.Error.TerminalColour())
+	if layers.err != nil {
+		return title + errorStyle.Render("  "+layers.err.Error()) + "\n"
+	}
+
+	faint := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
+	var b strings.Builder
+	b.WriteString(title)
+	for _, layer := range layers.layers {
+		fmt.Fprintf(&b, "  %-10s %s %10s\n", layerKind(layer.MediaType), layer.Digest, formatBytes(layer.Size))
+		if layer.Exists {
+			b.WriteString(faint.Render("    "+layer.Path) + "\n")
+		} else {
+			b.WriteString(errorStyle.Render("    "+layer.Path+" (missing)") + "\n")
+		}
+	}
+	return b.String()
+}
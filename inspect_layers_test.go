@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestInspectLayers(t *testing.T) {
+	dir := t.TempDir()
+	manifest := modelManifest{
+		Config: manifestLayer{MediaType: "application/vnd.docker.container.image.v1+json", Digest: "sha256:config", Size: 10},
+		Layers: []manifestLayer{
+			{MediaType: "application/vnd.ollama.image.model", Digest: "sha256:weights", Size: 4 << 30},
+			{MediaType: "application/vnd.ollama.image.template", Digest: "sha256:template", Size: 100},
+		},
+	}
+	path := manifestPath(dir, "llama3")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	data, _ := json.Marshal(manifest)
+	os.WriteFile(path, data, 0o644)
+	os.MkdirAll(filepath.Join(dir, "blobs"), 0o755)
+	for _, digest := range []string{"sha256:config", "sha256:template"} {
+		os.WriteFile(blobPath(dir, digest), nil, 0o644)
+	}
+
+	layers, err := readInspectLayers(dir, "llama3:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []string
+	for _, layer := range layers {
+		if !layer.Exists {
+			missing = append(missing, layer.Digest)
+		}
+	}
+	if len(layers) != 3 || layers[0].Digest != "sha256:config" || !slices.Equal(missing, []string{"sha256:weights"}) {
+		t.Errorf("readInspectLayers() = %+v", layers)
+	}
+
+	m := &AppModel{cfg: &config.Config{OllamaAPIURL: "http://localhost:11434"}, ollamaModelsDir: dir}
+	m.inspectedModel.Name = "llama3:latest"
+	m.loadInspectLayers("llama3:latest")
+	view := m.inspectLayersView()
+	if !strings.Contains(view, blobPath(dir, "sha256:weights")+" (missing)") || !strings.Contains(view, "template") || strings.Contains(view, blobPath(dir, "sha256:template")+" (missing)") {
+		t.Errorf("layers view =\n%s", view)
+	}
+
+	m.cfg.OllamaAPIURL = "http://server:11434"
+	m.loadInspectLayers("llama3:latest")
+	if view := m.inspectLayersView(); !strings.Contains(view, "remote hosts") || strings.Contains(view, "sha256") {
+		t.Errorf("remote layers view =\n%s", view)
+	}
+}
@@ -0,0 +1,146 @@
+// inspect_runtime.go compares how a running model was actually loaded with what its modelfile declares, e.g. a client
+// that loaded it with a larger num_ctx, for the inspect view.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// ollamaDefaultNumCtx is the context Ollama loads a model with when neither the modelfile nor the request sets num_ctx
+const ollamaDefaultNumCtx = 2048
+
+// runtimeValue is a runtime value of a running model next to the modelfile's, Modelfile is empty when it doesn't set one
+type runtimeValue struct {
+	Property  string
+	Runtime   string
+	Modelfile string
+	Mismatch  bool
+}
+
+// inspectRuntime is the running state of the inspected model, running is nil when it isn't loaded
+type inspectRuntime struct {
+	model   string
+	running *runningModel
+	params  map[string]string
+	err     error
+}
+
+type inspectRuntimeMsg inspectRuntime
+
+// findRunningModel returns the running instance of a model, if it's loaded
+func findRunningModel(running []runningModel, modelName string) *runningModel {
+	for i := range running {
+		if normaliseModelName(running[i].Name) == normaliseModelName(modelName) {
+			return &running[i]
+		}
+	}
+	return nil
+}
+
+// compareRuntime lists the runtime values of a running model against its modelfile parameters. Values the server
+// doesn't report or the modelfile doesn't set are shown as such rather than counted as a mismatch, except num_ctx
+// which falls back to Ollama's default.
+func compareRuntime(params map[string]string, running runningModel, now time.Time) []runtimeValue {
+	var values []runtimeValue
+
+	contextLength := runtimeValue{Property: "Context", Runtime: "not reported"}
+	declared, err := strconv.Atoi(params["num_ctx"])
+	if err != nil || declared <= 0 {
+		declared = ollamaDefaultNumCtx
+		contextLength.Modelfile = fmt.Sprintf("%d (Ollama default)", declared)
+	} else {
+		contextLength.Modelfile = fmt.Sprintf("%d", declared)
+	}
+	if running.ContextLength > 0 {
+		contextLength.Runtime = fmt.Sprintf("%d", running.ContextLength)
+		contextLength.Mismatch = running.ContextLength != declared
+	}
+	values = append(values, contextLength)
+
+	keepAlive := runtimeValue{Property: "Keep-alive", Runtime: "not reported"}
+	if !running.ExpiresAt.IsZero() {
+		if remaining := running.ExpiresAt.Sub(now); remaining > 100*365*24*time.Hour {
+			keepAlive.Runtime = "forever"
+		} else {
+			keepAlive.Runtime = fmt.Sprintf("expires %s (in %s)", running.ExpiresAt.Local().Format("15:04:05"), remaining.Round(time.Second))
+		}
+	}
+	values = append(values, keepAlive)
+
+	vram := runtimeValue{Property: "VRAM", Runtime: fmt.Sprintf("%s of %s (%s)", formatBytes(running.SizeVRAM), formatBytes(running.Size), running.processor())}
+	if numGPU, ok := params["num_gpu"]; ok {
+		vram.Modelfile = "num_gpu " + numGPU
+	}
+	// Anything not on the GPU was offloaded to the CPU, unless the modelfile asked for no GPU layers
+	vram.Mismatch = running.Size > 0 && running.SizeVRAM < running.Size && params["num_gpu"] != "0"
+	values = append(values, vram)
+
+	return values
+}
+
+// fetchInspectRuntime looks up whether the model is running and its modelfile parameters in the background
+func (m *AppModel) fetchInspectRuntime(modelName string) tea.Cmd {
+	apiURL := m.cfg.OllamaAPIURL
+	return func() tea.Msg {
+		msg := inspectRuntimeMsg{model: modelName}
+		running, err := listRunningModels(context.Background(), apiURL)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		msg.running = findRunningModel(running, modelName)
+		if msg.running == nil {
+			return msg
+		}
+		msg.params, _, msg.err = getModelParams(modelName, m.client)
+		return msg
+	}
+}
+
+func (m *AppModel) handleInspectRuntimeMsg(msg inspectRuntimeMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error fetching the running state of %s: %v\n", msg.model, msg.err)
+	}
+	if m.inspecting && m.inspectedModel.Name == msg.model {
+		m.inspectRuntime = inspectRuntime(msg)
+	}
+	return m, nil
+}
+
+// inspectRuntimeView renders the running instance section of the inspect view, empty when the model isn't loaded
+func (m *AppModel) inspectRuntimeView() string {
+	runtime := m.inspectRuntime
+	if runtime.model != m.inspectedModel.Name || runtime.running == nil {
+		return ""
+	}
+	if runtime.err != nil {
+		return "\nRunning, but the modelfile parameters couldn't be read: " + runtime.err.Error() + "\n"
+	}
+
+	warning := lipgloss.NewStyle().Foreground(styles.Current().Colours.Warning.TerminalColour())
+	var b strings.Builder
+	b.WriteString("\n" + lipgloss.NewStyle().Bold(true).Render("Running instance") + "\n")
+	fmt.Fprintf(&b, "  %-12s %-40s %s\n", "", "Runtime", "Modelfile")
+	for _, value := range compareRuntime(runtime.params, *runtime.running, time.Now()) {
+		modelfile := value.Modelfile
+		if modelfile == "" {
+			modelfile = "-"
+		}
+		line := fmt.Sprintf("  %-12s %-40s %s", value.Property, value.Runtime, modelfile)
+		if value.Mismatch {
+			line = warning.Render(line + "  (differs)")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareRuntime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	running := runningModel{ContextLength: 8192}
+	running.Size, running.SizeVRAM, running.ExpiresAt = 10<<30, 10<<30, now.Add(5*time.Minute)
+
+	byProperty := func(values []runtimeValue) map[string]runtimeValue {
+		m := make(map[string]runtimeValue)
+		for _, v := range values {
+			m[v.Property] = v
+		}
+		return m
+	}
+
+	// num_ctx absent from the modelfile falls back to Ollama's default
+	values := byProperty(compareRuntime(nil, running, now))
+	if v := values["Context"]; !v.Mismatch || v.Modelfile != "2048 (Ollama default)" || v.Runtime != "8192" {
+		t.Errorf("Context without num_ctx = %+v", v)
+	}
+	if v := values["Keep-alive"]; !strings.Contains(v.Runtime, "in 5m0s") || v.Mismatch {
+		t.Errorf("Keep-alive = %+v", v)
+	}
+	if v := values["VRAM"]; v.Mismatch || v.Modelfile != "" {
+		t.Errorf("VRAM fully on the GPU = %+v", v)
+	}
+
+	values = byProperty(compareRuntime(map[string]string{"num_ctx": "8192"}, running, now))
+	if v := values["Context"]; v.Mismatch || v.Modelfile != "8192" {
+		t.Errorf("Context matching num_ctx = %+v", v)
+	}
+
+	// Values the server doesn't report aren't mismatches
+	partial := runningModel{}
+	partial.Size, partial.SizeVRAM = 10<<30, 4<<30
+	values = byProperty(compareRuntime(map[string]string{"num_ctx": "4096", "num_gpu": "20"}, partial, now))
+	if v := values["Context"]; v.Mismatch || v.Runtime != "not reported" {
+		t.Errorf("Context not reported = %+v", v)
+	}
+	if v := values["Keep-alive"]; v.Runtime != "not reported" {
+		t.Errorf("Keep-alive not reported = %+v", v)
+	}
+	if v := values["VRAM"]; !v.Mismatch || v.Modelfile != "num_gpu 20" {
+		t.Errorf("VRAM partially offloaded = %+v", v)
+	}
+
+	// A modelfile that asks for the CPU isn't a mismatch when the model isn't on the GPU
+	if v := byProperty(compareRuntime(map[string]string{"num_gpu": "0"}, partial, now))["VRAM"]; v.Mismatch {
+		t.Errorf("VRAM with num_gpu 0 = %+v", v)
+	}
+
+	if findRunningModel([]runningModel{running}, "other") != nil {
+		t.Error("findRunningModel() matched a model that isn't running")
+	}
+}
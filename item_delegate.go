@@ -5,9 +5,10 @@ package main
 import (
 	"fmt"
 	"io"
-	"strings"
+	"unicode/utf8"
 
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -28,7 +29,7 @@ func (d itemDelegate) Spacing() int { return 0 }
 func (d itemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		logging.DebugLogger.Printf("itemDelegate received key: %s\n", msg.String())
+		logging.TraceLogger.Printf("itemDelegate received key: %s\n", msg.String())
 		if msg.String() == " " { // space key pressed
 			i, ok := m.SelectedItem().(Model)
 			if ok {
@@ -54,32 +55,53 @@ func (d itemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 }
 
 func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if header, ok := item.(familyHeader); ok {
+		d.renderFamilyHeader(w, m, index, header)
+		return
+	}
 	model, ok := item.(Model)
 	if !ok {
 		return
 	}
 
+	colours := styles.Current().Colours
+
 	// Alternate colours for model names
-	nameColours := []lipgloss.Color{
-		lipgloss.Color("#FFFFFF"),
-		lipgloss.Color("#818FA1"),
+	nameColours := []lipgloss.TerminalColor{
+		colours.Name.TerminalColour(),
+		colours.NameAlt.TerminalColour(),
+	}
+
+	// Work out what the filter matched before the name is changed for display
+	var match modelMatch
+	if m.FilterState() != list.Unfiltered {
+		match, _ = parseFilterQuery(m.FilterValue()).match(model)
 	}
 
 	// If StripString is set in the config, strip it from the model name
-	if d.appModel.cfg.StripString != "" {
-		model.Name = strings.Replace(model.Name, d.appModel.cfg.StripString, "", 1)
+	if stripped := model.DisplayName(d.appModel.cfg.StripString); stripped != model.Name {
+		match.name = nil // the indexes no longer line up with the name shown
+		model.Name = stripped
+	}
+
+	if model.Pinned {
+		const pinPrefix = "🔒 "
+		model.Name = pinPrefix + model.Name
+		for i := range match.name {
+			match.name[i] += utf8.RuneCountInString(pinPrefix)
+		}
 	}
 
 	nameStyle := lipgloss.NewStyle().Foreground(nameColours[index%len(nameColours)])
-	idStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("254")).Faint(true)
-	sizeStyle := lipgloss.NewStyle().Foreground(sizeColour(model.Size))
+	idStyle := lipgloss.NewStyle().Foreground(colours.ID.TerminalColour()).Faint(true)
+	sizeStyle := lipgloss.NewStyle().Foreground(sizeColour(model.SizeGB()))
 	familyStyle := lipgloss.NewStyle().Foreground(familyColour(model.Family, index))
 	quantStyle := lipgloss.NewStyle().Foreground(quantColour(model.QuantizationLevel))
-	modifiedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("254"))
+	modifiedStyle := lipgloss.NewStyle().Foreground(colours.Modified.TerminalColour())
 
 	if index == m.Index() {
 		// set the name border to pink
-		nameStyle = nameStyle.Bold(true).BorderLeft(true).BorderStyle(lipgloss.InnerHalfBlockBorder()).BorderForeground(lipgloss.Color("125")).PaddingLeft(1)
+		nameStyle = nameStyle.Bold(true).BorderLeft(true).BorderStyle(lipgloss.InnerHalfBlockBorder()).BorderForeground(colours.SelectedBorder.TerminalColour()).PaddingLeft(1)
 		sizeStyle = sizeStyle.Bold(true).BorderLeft(true).PaddingLeft(-2).PaddingRight(-2)
 		quantStyle = quantStyle.Bold(true).BorderLeft(true).PaddingLeft(-2).PaddingRight(-2)
 		familyStyle = familyStyle.Bold(true).BorderLeft(true).PaddingLeft(-2).PaddingRight(-2)
@@ -99,9 +121,15 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Ite
 		}
 	}
 
-	if isSelected {
+	if isSelected && styles.Colourless() {
+		// Without colours the selected background wouldn't show, and on some terminals hides the text, so mark the name instead
+		model.Name = "* " + model.Name
+		for i := range match.name {
+			match.name[i] += 2
+		}
+	} else if isSelected {
 		// de-indent to allow for selection border
-		selectedStyle := lipgloss.NewStyle().Background(lipgloss.Color("92")).Bold(true).Italic(true)
+		selectedStyle := lipgloss.NewStyle().Background(colours.SelectedBackground.TerminalColour()).Bold(true).Italic(true)
 		nameStyle = nameStyle.Inherit(selectedStyle)
 		idStyle = idStyle.Inherit(selectedStyle)
 		sizeStyle = sizeStyle.Inherit(selectedStyle)
@@ -110,15 +138,37 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Ite
 		modifiedStyle = modifiedStyle.Inherit(selectedStyle)
 	}
 
-	nameWidth, sizeWidth, quantWidth, modifiedWidth, idWidth, familyWidth := calculateColumnWidths(m.Width())
+	if d.appModel.columnLayout == nil {
+		d.appModel.updateColumnLayout()
+	}
 
-	// Ensure the text fits within the terminal width
-	name := wrapText(nameStyle.Width(nameWidth).Render(truncate(model.Name, nameWidth)), nameWidth)
-	size := wrapText(sizeStyle.Width(sizeWidth).Render(fmt.Sprintf("%.2fGB", model.Size)), sizeWidth)
-	quant := wrapText(quantStyle.Width(quantWidth).Render(truncate(model.QuantizationLevel, quantWidth)), quantWidth)
-	family := wrapText(familyStyle.Width(familyWidth).Render(model.Family), familyWidth)
-	modified := wrapText(modifiedStyle.Width(modifiedWidth).Render(model.Modified.Format("2006-01-02")), modifiedWidth)
-	id := wrapText(idStyle.Width(idWidth).Render(model.ID), idWidth)
+	// Ensure the text fits within the terminal width, long names lose their middle so the tag stays visible
+	columns := make([]string, 0, len(d.appModel.columnLayout))
+	for _, column := range d.appModel.columnLayout {
+		width := column.width
+		var cell string
+		switch column.name {
+		case "name":
+			name := truncateMiddle(model.Name, width)
+			cell = nameStyle.Width(width).Render(highlightMatches(name, truncatedMatches(match.name, utf8.RuneCountInString(model.Name), width), nameStyle))
+		case "size":
+			cell = sizeStyle.Width(width).Render(model.SizeLabel())
+		case "params":
+			cell = modifiedStyle.Width(width).Render(truncate(model.ParameterSize, width))
+		case "quant":
+			cell = quantStyle.Width(width).Render(highlightMatches(truncate(model.QuantizationLevel, width), match.quant, quantStyle))
+		case "family":
+			cell = familyStyle.Width(width).Render(highlightMatches(model.Family, match.family, familyStyle))
+		case "context":
+			length, fetched := d.appModel.contextLengths[model.Digest]
+			cell = modifiedStyle.Width(width).Render(contextLengthLabel(length, fetched))
+		case "modified":
+			cell = modifiedStyle.Width(width).Render(model.Modified.Format("2006-01-02"))
+		case "id":
+			cell = idStyle.Width(width).Render(model.IDLabel())
+		}
+		columns = append(columns, wrapText(cell, width))
+	}
 
-	fmt.Fprint(w, lipgloss.JoinHorizontal(lipgloss.Top, name, size, quant, family, modified, id))
+	fmt.Fprint(w, lipgloss.JoinHorizontal(lipgloss.Top, columns...))
 }
@@ -0,0 +1,93 @@
+// keep_alive.go sets how long a running model stays loaded from the top view, e.g. to keep it in memory indefinitely.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pkg/gollama"
+)
+
+// keepAliveMsg is the result of setting a running model's keep-alive
+type keepAliveMsg struct {
+	modelName string
+	keepAlive time.Duration
+	err       error
+}
+
+// parseKeepAlive parses a keep-alive like 30m, 2h or 1h30m, a plain number of seconds, or -1 to keep the model
+// loaded until Ollama stops
+func parseKeepAlive(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("no keep-alive given")
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return -1, nil
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid keep-alive %q, use a duration like 30m or 2h, or -1 to keep the model loaded", s)
+	}
+	if d < 0 {
+		return -1, nil
+	}
+	return d, nil
+}
+
+func formatKeepAlive(d time.Duration) string {
+	if d < 0 {
+		return "until Ollama stops"
+	}
+	return "for " + d.String()
+}
+
+// handleKeepAliveKey prompts for a keep-alive for the model under the cursor in the top view and sends it in the background
+func (m *AppModel) handleKeepAliveKey() (tea.Model, tea.Cmd) {
+	if m.topCursor >= len(m.topModels) {
+		return m, nil
+	}
+	modelName := m.topModels[m.topCursor].Name
+	value, ok := promptForValue(fmt.Sprintf("Keep %s loaded for (e.g. 30m, 2h, -1 to keep it loaded): ", modelName), "")
+	if !ok || strings.TrimSpace(value) == "" {
+		m.topMessage = "Cancelled"
+		return m, nil
+	}
+	keepAlive, err := parseKeepAlive(value)
+	if err != nil {
+		m.topMessage = err.Error()
+		return m, nil
+	}
+
+	m.topMessage = fmt.Sprintf("Setting the keep-alive of %s...", modelName)
+	client, apiURL := m.client, m.cfg.OllamaAPIURL
+	return m, func() tea.Msg {
+		err := gollama.SetKeepAlive(context.Background(), client, apiHTTPClient, apiURL, modelName, keepAlive)
+		return keepAliveMsg{modelName: modelName, keepAlive: keepAlive, err: err}
+	}
+}
+
+// handleKeepAliveMsg reports the result and refreshes the running models straight away so Until shows the new expiry
+func (m *AppModel) handleKeepAliveMsg(msg keepAliveMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error setting the keep-alive of %s: %v\n", msg.modelName, msg.err)
+		m.topMessage = fmt.Sprintf("Error setting the keep-alive of %s: %v", msg.modelName, msg.err)
+	} else {
+		logging.InfoLogger.Printf("Set the keep-alive of %s to %s\n", msg.modelName, msg.keepAlive)
+		m.topMessage = fmt.Sprintf("%s will stay loaded %s", msg.modelName, formatKeepAlive(msg.keepAlive))
+	}
+	if m.view != TopView {
+		return m, nil
+	}
+	m.topGeneration++ // replaces the scheduled refresh rather than adding a second one
+	return m, m.fetchRunningModels(0)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{" 2h ", 2 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"-1", -1, false},
+		{"-5m", -1, false},
+		{"600", 10 * time.Minute, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"forever", 0, true},
+		{"2 hours", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseKeepAlive(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseKeepAlive(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseKeepAlive(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
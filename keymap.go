@@ -32,6 +32,23 @@ type KeyMap struct {
 	Help             key.Binding
 	RenameModel      key.Binding
 	PullNewModel     key.Binding
+	Dashboard        key.Binding
+	PinModel         key.Binding
+	Theme            key.Binding
+	CompareHosts     key.Binding
+	Benchmark        key.Binding
+	CopySettings     key.Binding
+	TransferModel    key.Binding
+	SelectAll        key.Binding
+	InvertSelection  key.Binding
+	DeselectAll      key.Binding
+	Dupes            key.Binding
+	PullKeepConfig   key.Binding
+	UpdateAll        key.Binding
+	RetryRestore     key.Binding
+	FitCheck         key.Binding
+	GroupBy          key.Binding
+	RunWithOptions   key.Binding
 	SortOrder        string
 }
 
@@ -42,12 +59,23 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func NewKeyMap() *KeyMap {
 	return &KeyMap{
 		Space:            key.NewBinding(key.WithKeys("space"), key.WithHelp("space", "select")),
+		SelectAll:        key.NewBinding(key.WithKeys("ctrl+a"), key.WithHelp("ctrl+a", "select all shown")),
+		InvertSelection:  key.NewBinding(key.WithKeys("ctrl+i", "tab"), key.WithHelp("ctrl+i", "invert selection")),
+		DeselectAll:      key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "deselect all shown")),
 		AltScreen:        key.NewBinding(key.WithKeys("A")),
 		ClearScreen:      key.NewBinding(key.WithKeys("C")),
 		ConfirmNo:        key.NewBinding(key.WithKeys("n")),
 		ConfirmYes:       key.NewBinding(key.WithKeys("y")),
 		CompareModelfile: key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "compare modelfile")),
+		CompareHosts:     key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "compare across hosts")),
+		Benchmark:        key.NewBinding(key.WithKeys("B"), key.WithHelp("B", "benchmark connection")),
 		CopyModel:        key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		CopySettings:     key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "copy settings to a model")),
+		Dupes:            key.NewBinding(key.WithKeys("G"), key.WithHelp("G", "duplicate tags")),
+		Dashboard:        key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "dashboard")),
+		RetryRestore:     key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "retry config restore")),
+		FitCheck:         key.NewBinding(key.WithKeys("V"), key.WithHelp("V", "fits alongside running")),
+		GroupBy:          key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "group by family")),
 		RenameModel:      key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename")),
 		Delete:           key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "delete")),
 		Help:             key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "help")),
@@ -57,15 +85,21 @@ func NewKeyMap() *KeyMap {
 		LinkModel:        key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "link (L=all)")),
 		PushModel:        key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "push")),
 		PullModel:        key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pull")),
+		PullKeepConfig:   key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("ctrl+k", "pull (keep config)")),
+		UpdateAll:        key.NewBinding(key.WithKeys("ctrl+u"), key.WithHelp("ctrl+u", "update all")),
 		PullNewModel:     key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "pull new model")),
+		PinModel:         key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "pin/unpin")),
 		Quit:             key.NewBinding(key.WithKeys("q")),
 		RunModel:         key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run")),
+		RunWithOptions:   key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "run with one-off options")),
 		SortByFamily:     key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "^family")),
 		SortByModified:   key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "^modified")),
 		SortByName:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "^name")),
 		SortByQuant:      key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "^quant")),
 		SortBySize:       key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "^size")),
+		Theme:            key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "theme")),
 		Top:              key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "top")),
+		TransferModel:    key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "copy to another host")),
 		UnloadModels:     key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "unload all")),
 	}
 }
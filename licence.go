@@ -0,0 +1,193 @@
+// licence.go works out which licence a model is under from the licence text the show API returns, as an SPDX
+// identifier where there is one, and shows the full text in a scrollable section of the inspect view with L.
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// otherLicence is shown for licence text none of the heuristics recognise
+const otherLicence = "Other"
+
+// spdxIdentifier matches an SPDX-License-Identifier line, which is used as it is when a licence has one
+var spdxIdentifier = regexp.MustCompile(`(?i)spdx-license-identifier:\s*([A-Za-z0-9.+-]+)`)
+
+// licencePattern is a licence recognised by all of its phrases being in the text, which is lower cased with its
+// whitespace collapsed first
+type licencePattern struct {
+	ID      string
+	Phrases []string
+}
+
+// licencePatterns are checked in order, so the specific licences come before the ones whose wording they reuse, e.g.
+// the Llama licences before Apache's and the lesser and affero GPLs before the GPL
+var licencePatterns = []licencePattern{
+	{"Llama-3.3", []string{"llama 3.3 community license"}},
+	{"Llama-3.2", []string{"llama 3.2 community license"}},
+	{"Llama-3.1", []string{"llama 3.1 community license"}},
+	{"Llama-3", []string{"llama 3 community license"}},
+	{"Llama-2", []string{"llama 2 community license"}},
+	{"Gemma", []string{"gemma terms of use"}},
+	{"Qwen-Research", []string{"qwen research license"}},
+	{"Qwen", []string{"qwen license agreement"}},
+	{"Qwen", []string{"tongyi qianwen license"}},
+	{"DeepSeek", []string{"deepseek license agreement"}},
+	{"CC-BY-NC-SA-4.0", []string{"attribution-noncommercial-sharealike 4.0"}},
+	{"CC-BY-NC-4.0", []string{"attribution-noncommercial 4.0"}},
+	{"CC-BY-SA-4.0", []string{"attribution-sharealike 4.0"}},
+	{"CC-BY-4.0", []string{"creative commons", "attribution 4.0 international"}},
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"MIT", []string{"mit license"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"Unlicense", []string{"free and unencumbered software released into the public domain"}},
+	{"OpenRAIL-M", []string{"openrail-m"}},
+}
+
+// detectLicence returns the identifier of the licence in a model's licence text, the SPDX identifier where there is
+// one, Other when it isn't recognised and nothing when there's no licence. Models with several licences, like the
+// Llama models with their acceptable use policy, are identified by the first recognised one.
+func detectLicence(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	if match := spdxIdentifier.FindStringSubmatch(text); match != nil {
+		return match[1]
+	}
+	normalised := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	for _, pattern := range licencePatterns {
+		found := true
+		for _, phrase := range pattern.Phrases {
+			if !strings.Contains(normalised, phrase) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return pattern.ID
+		}
+	}
+	return otherLicence
+}
+
+// inspectLicence is the inspected model's licence, fetched when the inspect view opens, and the state of its section.
+// The text is scrolled by offset lines.
+type inspectLicence struct {
+	model  string // the model the text is of, empty until it's fetched
+	text   string
+	shown  bool
+	offset int
+}
+
+// inspectLicenceMsg is the licence text of a model, empty when it doesn't have one or it couldn't be fetched
+type inspectLicenceMsg struct {
+	model string
+	text  string
+}
+
+// fetchInspectLicence fetches the licence text of the inspected model in the background
+func (m *AppModel) fetchInspectLicence(modelName string) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		show, err := showcache.For(client).Show(context.Background(), modelName)
+		if err != nil {
+			logging.ErrorLogger.Printf("Error fetching the licence of %s: %v\n", modelName, err)
+			return inspectLicenceMsg{model: modelName}
+		}
+		return inspectLicenceMsg{model: modelName, text: show.License}
+	}
+}
+
+func (m *AppModel) handleInspectLicenceMsg(msg inspectLicenceMsg) (tea.Model, tea.Cmd) {
+	if m.inspecting && m.inspectedModel.Name == msg.model {
+		m.inspectLicence = inspectLicence{model: msg.model, text: msg.text}
+	}
+	return m, nil
+}
+
+// licenceText is the inspected model's licence text, empty until it's fetched
+func (m *AppModel) licenceText() string {
+	if m.inspectLicence.model != m.inspectedModel.Name {
+		return ""
+	}
+	return m.inspectLicence.text
+}
+
+// licenceLines wraps the licence text to the terminal's width
+func (m *AppModel) licenceLines(text string) []string {
+	width := max(m.width-4, 20)
+	return strings.Split(lipgloss.NewStyle().Width(width).Render(strings.TrimSpace(text)), "\n")
+}
+
+// licenceHeight is how many lines of the licence are shown at once
+func (m *AppModel) licenceHeight() int {
+	return max(m.height-6, 5)
+}
+
+// handleInspectLicenceKey scrolls the licence section, L, q and esc close it
+func (m *AppModel) handleInspectLicenceKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	licence := &m.inspectLicence
+	last := max(len(m.licenceLines(m.licenceText()))-m.licenceHeight(), 0)
+	switch msg.String() {
+	case "up", "k":
+		licence.offset--
+	case "down", "j":
+		licence.offset++
+	case "pgup", "b":
+		licence.offset -= m.licenceHeight()
+	case "pgdown", "f", " ":
+		licence.offset += m.licenceHeight()
+	case "home", "g":
+		licence.offset = 0
+	case "end", "G":
+		licence.offset = last
+	case "L", "q", "esc":
+		licence.shown, licence.offset = false, 0
+	}
+	licence.offset = min(max(licence.offset, 0), last)
+	return m, nil, true
+}
+
+// toggleInspectLicence opens the licence section of the inspected model, when it has a licence
+func (m *AppModel) toggleInspectLicence() (tea.Model, tea.Cmd, bool) {
+	if m.inspectLicence.model != m.inspectedModel.Name {
+		m.message = "The licence of " + m.inspectedModel.Name + " is still being fetched"
+		return m, nil, true
+	}
+	if m.inspectLicence.text == "" {
+		m.message = m.inspectedModel.Name + " doesn't have a licence"
+		return m, nil, true
+	}
+	m.inspectLicence.shown, m.inspectLicence.offset = true, 0
+	m.message = ""
+	return m, nil, true
+}
+
+// inspectLicenceView renders the visible part of the inspected model's licence
+func (m *AppModel) inspectLicenceView() string {
+	text := m.licenceText()
+	lines := m.licenceLines(text)
+	height := m.licenceHeight()
+	start := min(m.inspectLicence.offset, max(len(lines)-height, 0))
+	end := min(start+height, len(lines))
+
+	title := lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("Licence of %s: %s", m.inspectedModel.Name, detectLicence(text)))
+	position := fmt.Sprintf("Lines %d-%d of %d, up/down and pgup/pgdown to scroll, 'L', 'q' or `esc` to return to the inspect view.", start+1, end, len(lines))
+	return "\n" + title + "\n\n" + strings.Join(lines[start:end], "\n") + "\n\n" + position
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestInspectLicence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{License: strings.Repeat("Apache License\nVersion 2.0, January 2004\n", 40)})
+		default:
+			json.NewEncoder(w).Encode(api.ProcessResponse{})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	model := Model{}
+	model.Name = "qwen2:7b"
+	m := &AppModel{
+		cfg:    &config.Config{OllamaAPIURL: server.URL},
+		client: api.NewClient(u, http.DefaultClient),
+		list:   list.New([]list.Item{model}, list.NewDefaultDelegate(), 80, 40),
+		width:  80,
+		height: 30,
+	}
+
+	_, cmd := m.handleInspectModelKey()
+	m.toggleInspectLicence()
+	if m.inspectLicence.shown || !strings.Contains(m.message, "still being fetched") {
+		t.Errorf("licence shown = %v with message %q before it was fetched", m.inspectLicence.shown, m.message)
+	}
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(inspectLicenceMsg); ok {
+			m.Update(msg)
+		}
+	}
+	rows, _ := m.inspectRows(m.inspectedModel)
+	if !slices.ContainsFunc(rows, func(row table.Row) bool { return row[0] == "Licence" && row[1] == "Apache-2.0 (L to read)" }) {
+		t.Errorf("inspect rows = %v, want the licence identified", rows)
+	}
+
+	m.toggleInspectLicence()
+	m.handleInspectLicenceKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	if !m.inspectLicence.shown || m.inspectLicence.offset == 0 || !strings.Contains(m.inspectLicenceView(), "Licence of qwen2:7b: Apache-2.0") {
+		t.Errorf("licence section = %+v, want it shown and scrolled to the end", m.inspectLicence)
+	}
+	// Closing the section keeps the text so it can be opened again without fetching it
+	m.handleInspectLicenceKey(tea.KeyMsg{Type: tea.KeyEsc})
+	m.toggleInspectLicence()
+	if !m.inspectLicence.shown || m.inspectLicence.offset != 0 {
+		t.Errorf("reopened licence section = %+v, want it shown from the top", m.inspectLicence)
+	}
+}
+
+func TestDetectLicence(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "  \n", ""},
+		{"apache", `                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/
+
+   TERMS AND CONDITIONS FOR USE, REPRODUCTION, AND DISTRIBUTION`, "Apache-2.0"},
+		{"mit", `MIT License
+
+Copyright (c) Microsoft Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software")`, "MIT"},
+		{"mit without its title", "Copyright 2024\n\nPermission is hereby granted,\nfree of charge, to any person", "MIT"},
+		{"llama 3", "META LLAMA 3 COMMUNITY LICENSE AGREEMENT\nMeta Llama 3 Version Release Date: April 18, 2024", "Llama-3"},
+		{"llama 3.1", "LLAMA 3.1 COMMUNITY LICENSE AGREEMENT\nLlama 3.1 Version Release Date: July 23, 2024", "Llama-3.1"},
+		{"llama 3.2 with its policy", "LLAMA 3.2 COMMUNITY LICENSE AGREEMENT\n...\nLlama 3.2 Acceptable Use Policy", "Llama-3.2"},
+		{"llama 2", "LLAMA 2 COMMUNITY LICENSE AGREEMENT\nLlama 2 Version Release Date: July 18, 2023", "Llama-2"},
+		{"gemma", "Gemma Terms of Use\n\nLast modified: February 21, 2024", "Gemma"},
+		{"qwen", "Qwen LICENSE AGREEMENT\n\nQwen LICENSE AGREEMENT Release Date: September 19, 2024", "Qwen"},
+		{"qwen research", "Qwen RESEARCH LICENSE AGREEMENT\n\nRelease Date: September 19, 2024", "Qwen-Research"},
+		{"cc by-nc", "Attribution-NonCommercial 4.0 International\n\nCreative Commons Corporation", "CC-BY-NC-4.0"},
+		{"gpl 3", "GNU GENERAL PUBLIC LICENSE\n   Version 3, 29 June 2007", "GPL-3.0"},
+		{"lgpl 2.1", "GNU LESSER GENERAL PUBLIC LICENSE\n   Version 2.1, February 1999", "LGPL-2.1"},
+		{"bsd 3", "Redistribution and use in source and binary forms, with or without modification...\n3. Neither the name of the copyright holder", "BSD-3-Clause"},
+		{"spdx identifier", "SPDX-License-Identifier: MPL-2.0\nsomething else entirely", "MPL-2.0"},
+		{"unrecognised", "You may use this model for research only.", "Other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLicence(tt.text); got != tt.want {
+				t.Errorf("detectLicence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	models := make([]Model, 2)
+	models[0].Name, models[1].Name = "llama3:8b", "nomic-embed-text:latest"
+	infos := []wideModelInfo{{Licence: "Llama-3"}, {}}
+	var buf bytes.Buffer
+	printWideModelTable(&buf, models, infos, "", 200, true)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.HasSuffix(lines[0], "Licence") || !strings.HasSuffix(lines[1], "Llama-3") || !strings.HasSuffix(lines[2], "-") {
+		t.Errorf("wide table with licences = %q, want a Licence column", buf.String())
+	}
+}
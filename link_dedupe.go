@@ -0,0 +1,174 @@
+// link_dedupe.go links one model per unique blob when linking to LM Studio, recording the other names in a sidecar mapping file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// linkMapFile is the sidecar mapping file written to the LM Studio models directory
+const linkMapFile = ".gollama-links.json"
+
+// linkMapEntry records which model name was linked for a blob and which other names share it
+type linkMapEntry struct {
+	Canonical string   `json:"canonical"`
+	Aliases   []string `json:"aliases,omitempty"`
+}
+
+// linkMap is keyed by blob digest
+type linkMap map[string]linkMapEntry
+
+func loadLinkMap(lmStudioModelsDir string) (linkMap, error) {
+	links := make(linkMap)
+	data, err := os.ReadFile(filepath.Join(lmStudioModelsDir, linkMapFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return links, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", linkMapFile, err)
+	}
+	return links, nil
+}
+
+func saveLinkMap(lmStudioModelsDir string, links linkMap) error {
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(lmStudioModelsDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(lmStudioModelsDir, linkMapFile), data, 0644)
+}
+
+// canonicalModelName picks the name to link for a blob: the previously linked name if it still exists,
+// otherwise the shortest name, alphabetically on ties
+func canonicalModelName(names []string, previous string) string {
+	for _, name := range names {
+		if name == previous {
+			return previous
+		}
+	}
+	sorted := append([]string(nil), names...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) < len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted[0]
+}
+
+// groupModelsByBlob groups model names by the blob they're built from, blobOf returns a model's blob path
+func groupModelsByBlob(names []string, blobOf func(string) (string, error)) (map[string][]string, []error) {
+	groups := make(map[string][]string)
+	var errs []error
+	for _, name := range names {
+		blob, err := blobOf(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error getting model path for %s: %w", name, err))
+			continue
+		}
+		digest := filepath.Base(blob)
+		groups[digest] = append(groups[digest], name)
+	}
+	return groups, errs
+}
+
+// removeLink removes a model's LM Studio symlink and its directory if that leaves it empty
+func removeLink(modelName, lmStudioModelsDir string) error {
+	dir, path := lmStudioLinkPath(modelName, lmStudioModelsDir)
+	if fi, err := os.Lstat(path); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+	return nil
+}
+
+// linkModelsDeduped links one model per unique blob, returning messages to show and the number of duplicate links avoided
+func linkModelsDeduped(names []string, ollamaModelsDir, lmStudioModelsDir string, cleanup symlinkCleanup, dryRun bool, client *api.Client) ([]string, int, error) {
+	previous, err := loadLinkMap(lmStudioModelsDir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups, errs := groupModelsByBlob(names, func(name string) (string, error) { return getModelPath(name, ollamaModelsDir, client) })
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	digests := make([]string, 0, len(groups))
+	for digest := range groups {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	links := make(linkMap, len(groups))
+	var avoided int
+	for _, digest := range digests {
+		names := groups[digest]
+		canonical := canonicalModelName(names, previous[digest].Canonical)
+
+		// A renamed canonical model moves the existing link rather than adding a second one
+		if old := previous[digest].Canonical; old != "" && old != canonical {
+			if dryRun {
+				messages = append(messages, fmt.Sprintf("[DRY RUN] Would replace the link for %s with %s", old, canonical))
+			} else if err := removeLink(old, lmStudioModelsDir); err != nil {
+				logging.ErrorLogger.Printf("Error removing link for %s: %v\n", old, err)
+			} else {
+				messages = append(messages, fmt.Sprintf("Replaced the link for %s with %s", old, canonical))
+			}
+		}
+
+		// Links made for other names before deduping would stop the canonical name being linked
+		for _, name := range names {
+			if name == canonical || dryRun {
+				continue
+			}
+			if err := removeLink(name, lmStudioModelsDir); err != nil {
+				logging.ErrorLogger.Printf("Error removing duplicate link for %s: %v\n", name, err)
+			}
+		}
+
+		message, err := linkModel(canonical, ollamaModelsDir, lmStudioModelsDir, cleanup, dryRun, client)
+		if err != nil {
+			return messages, avoided, err
+		}
+		if message != "" {
+			messages = append(messages, message)
+		}
+
+		entry := linkMapEntry{Canonical: canonical}
+		for _, name := range names {
+			if name != canonical {
+				entry.Aliases = append(entry.Aliases, name)
+			}
+		}
+		sort.Strings(entry.Aliases)
+		avoided += len(entry.Aliases)
+		links[digest] = entry
+	}
+
+	if !dryRun {
+		if err := saveLinkMap(lmStudioModelsDir, links); err != nil {
+			return messages, avoided, fmt.Errorf("error writing %s: %w", linkMapFile, err)
+		}
+	}
+	return messages, avoided, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalModelName(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		previous string
+		want     string
+	}{
+		{"shortest wins", []string{"qwen2.5:14b-instruct-q4_K_M", "qwen-coder:latest"}, "", "qwen-coder:latest"},
+		{"alphabetical on ties", []string{"b:latest", "a:latest"}, "", "a:latest"},
+		{"previous canonical kept", []string{"qwen2.5:14b-instruct-q4_K_M", "qwen-coder:latest"}, "qwen2.5:14b-instruct-q4_K_M", "qwen2.5:14b-instruct-q4_K_M"},
+		{"renamed previous canonical replaced", []string{"qwen2.5:14b-instruct-q4_K_M", "coder:latest"}, "qwen-coder:latest", "coder:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalModelName(tt.names, tt.previous); got != tt.want {
+				t.Errorf("canonicalModelName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupModelsByBlob(t *testing.T) {
+	blobs := map[string]string{
+		"qwen2.5:14b-instruct-q4_K_M": "/models/blobs/sha256-aaa",
+		"qwen-coder:latest":           "/models/blobs/sha256-aaa",
+		"llama3:8b":                   "/models/blobs/sha256-bbb",
+	}
+	names := []string{"qwen2.5:14b-instruct-q4_K_M", "qwen-coder:latest", "llama3:8b", "missing"}
+	groups, errs := groupModelsByBlob(names, func(name string) (string, error) {
+		if blob, ok := blobs[name]; ok {
+			return blob, nil
+		}
+		return "", errors.New("not found")
+	})
+	if len(errs) != 1 {
+		t.Errorf("groupModelsByBlob() errors = %v, want one for the missing model", errs)
+	}
+	if len(groups) != 2 || len(groups["sha256-aaa"]) != 2 || len(groups["sha256-bbb"]) != 1 {
+		t.Errorf("groupModelsByBlob() = %v", groups)
+	}
+}
+
+func TestLinkMapRoundTripAndRemoveLink(t *testing.T) {
+	dir := t.TempDir()
+	links := linkMap{"sha256-aaa": {Canonical: "qwen-coder:latest", Aliases: []string{"qwen2.5:14b"}}}
+	if err := saveLinkMap(dir, links); err != nil {
+		t.Fatalf("saveLinkMap() error = %v", err)
+	}
+	loaded, err := loadLinkMap(dir)
+	if err != nil {
+		t.Fatalf("loadLinkMap() error = %v", err)
+	}
+	if loaded["sha256-aaa"].Canonical != "qwen-coder:latest" || len(loaded["sha256-aaa"].Aliases) != 1 {
+		t.Errorf("loadLinkMap() = %+v", loaded)
+	}
+
+	linkDir, linkPath := lmStudioLinkPath("qwen-coder:latest", dir)
+	if err := os.MkdirAll(linkDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "blob"), linkPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeLink("qwen-coder:latest", dir); err != nil {
+		t.Fatalf("removeLink() error = %v", err)
+	}
+	if _, err := os.Stat(linkDir); !os.IsNotExist(err) {
+		t.Errorf("removeLink() left the empty link directory behind")
+	}
+}
@@ -0,0 +1,117 @@
+// list_columns.go lays out the columns of the model list from the columns setting. The widths are worked out when the
+// terminal is resized rather than for every item, auto columns taking a share of the width and fixed ones their own.
+package main
+
+import (
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// listColumn is a column of the model list and the width it's drawn at
+type listColumn struct {
+	name  string
+	width int
+}
+
+// autoColumnWidths is the share of the terminal width each auto column takes and the least it's drawn at
+var autoColumnWidths = map[string]struct {
+	share float64
+	min   int
+}{
+	"name":     {0.45, minNameWidth},
+	"size":     {0.05, minSizeWidth},
+	"params":   {0.05, minParamsWidth},
+	"quant":    {0.05, minQuantWidth},
+	"family":   {0.05, minFamilyWidth},
+	"context":  {0, contextLengthWidth},
+	"modified": {0.05, minModifiedWidth},
+	"id":       {0.02, minIDWidth},
+}
+
+// listColumns returns the columns setting with the context column added before modified when show_context_length is
+// set and the setting doesn't list it. The setting is checked when the config loads, so a bad one falls back to the
+// default layout.
+func listColumns(cfg *config.Config) []config.Column {
+	columns, err := config.ParseColumns(cfg.Columns)
+	if err != nil {
+		logging.ErrorLogger.Printf("Using the default columns: %v\n", err)
+		columns, _ = config.ParseColumns(nil)
+	}
+	if !cfg.ShowContextLength || hasColumn(columns, "context") {
+		return columns
+	}
+	at := len(columns)
+	for i, column := range columns {
+		if column.Name == "modified" || column.Name == "id" {
+			at = i
+			break
+		}
+	}
+	return append(columns[:at], append([]config.Column{{Name: "context"}}, columns[at:]...)...)
+}
+
+func hasColumn(columns []config.Column, name string) bool {
+	for _, column := range columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// showsContextLength reports whether the list has a context column, so the context lengths need fetching
+func showsContextLength(cfg *config.Config) bool {
+	return cfg != nil && hasColumn(listColumns(cfg), "context")
+}
+
+// layoutColumns works out the width of each column for a terminal totalWidth wide. When the columns don't fit an auto
+// name column gives up the difference, as the names are shortened in the middle to fit.
+func layoutColumns(columns []config.Column, totalWidth int) []listColumn {
+	layout := make([]listColumn, len(columns))
+	used, nameIndex := 0, -1
+	for i, column := range columns {
+		width := column.Width
+		if width == 0 {
+			auto := autoColumnWidths[column.Name]
+			width = max(int(auto.share*float64(totalWidth)), auto.min)
+			if column.Name == "name" {
+				nameIndex = i
+			}
+		}
+		layout[i] = listColumn{name: column.Name, width: width}
+		used += width
+	}
+	if nameIndex >= 0 && used > totalWidth {
+		layout[nameIndex].width = max(layout[nameIndex].width-(used-totalWidth), 1)
+	}
+	return layout
+}
+
+// updateColumnLayout lays out the list's columns for its current width, it's called when the terminal is resized or
+// the columns setting changes
+func (m *AppModel) updateColumnLayout() {
+	m.columnLayout = layoutColumns(listColumns(m.cfg), m.list.Width())
+}
+
+// truncatedMatches moves the indexes of the filter's matches in text length runes long to where they are once
+// truncateMiddle has shortened it to width, dropping those in the part replaced with "…"
+func truncatedMatches(indexes []int, length, width int) []int {
+	if length <= width || len(indexes) == 0 {
+		return indexes
+	}
+	head := max((width-1)/2, 0)
+	tail := width - 1 - head
+	if width < 2 {
+		head, tail = max(width, 0), 0
+	}
+	var moved []int
+	for _, i := range indexes {
+		switch {
+		case i < head:
+			moved = append(moved, i)
+		case tail > 0 && i >= length-tail:
+			moved = append(moved, i-(length-tail)+head+1)
+		}
+	}
+	return moved
+}
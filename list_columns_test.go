@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestListColumnLayout(t *testing.T) {
+	cfg := &config.Config{Columns: []string{"Name", "Size", "Quant", "Family", "Modified", "ID"}}
+	nameWidth, sizeWidth, quantWidth, modifiedWidth, idWidth, familyWidth := calculateColumnWidths(200)
+	want := fmt.Sprint([]listColumn{{"name", nameWidth}, {"size", sizeWidth}, {"quant", quantWidth}, {"family", familyWidth}, {"modified", modifiedWidth}, {"id", idWidth}})
+	if got := fmt.Sprint(layoutColumns(listColumns(cfg), 200)); got != want {
+		t.Errorf("default layout = %s, want today's widths %s", got, want)
+	}
+
+	// show_context_length adds the context column before modified unless the columns place it
+	cfg.ShowContextLength = true
+	if got := listColumns(cfg); got[4].Name != "context" || got[5].Name != "modified" {
+		t.Errorf("columns with show_context_length = %v, want context before modified", got)
+	}
+	cfg.Columns = []string{"context", "name"}
+	if got := listColumns(cfg); len(got) != 2 || got[0].Name != "context" {
+		t.Errorf("columns = %v, want the context column where it's listed", got)
+	}
+
+	// A narrow terminal takes the difference from the name, fixed widths are kept
+	layout := layoutColumns([]config.Column{{Name: "name"}, {Name: "size", Width: 30}, {Name: "quant"}}, 50)
+	if layout[0].width != 10 || layout[1].width != 30 || layout[2].width != minQuantWidth {
+		t.Errorf("narrow layout = %v, want the name shrunk to fit", layout)
+	}
+
+	m := &AppModel{cfg: &config.Config{Columns: []string{"name:20", "quant"}}, list: list.New(nil, list.NewDefaultDelegate(), 100, 20)}
+	model := Model{}
+	model.Name, model.QuantizationLevel = "registry.example.com/library/qwen2.5-coder:Q4_K_M", "Q4_K_M"
+	var out strings.Builder
+	NewItemDelegate(m).Render(&out, m.list, 1, model)
+	if row := out.String(); !strings.Contains(row, "registry.…der:Q4_K_M") || strings.Contains(row, "GB") {
+		t.Errorf("row = %q, want the name shortened in the middle and only the name and quant columns", row)
+	}
+
+	// The filter's matches move with the name
+	if got := truncatedMatches([]int{0, 10, 45, 49}, 50, 20); fmt.Sprint(got) != "[0 15 19]" {
+		t.Errorf("truncatedMatches() = %v, want [0 15 19]", got)
+	}
+}
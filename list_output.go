@@ -0,0 +1,78 @@
+// list_output.go prints the -l and -s results as JSON or TSV for scripts rather than as a coloured table.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Machine readable output formats for -l and -s
+const (
+	outputJSON = "json"
+	outputTSV  = "tsv"
+)
+
+// modelListEntry is a model as printed by -l and -s in JSON and TSV output
+type modelListEntry struct {
+	Name              string `json:"name"`
+	ID                string `json:"id"`
+	SizeBytes         int64  `json:"size_bytes"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+	Family            string `json:"family"`
+	Modified          string `json:"modified"`
+}
+
+// outputFormat returns the machine readable format selected by -o and -json, or "" for the normal table
+func outputFormat(format string, jsonOutput bool) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if jsonOutput && format == "" {
+		format = outputJSON
+	}
+	switch format {
+	case "", outputJSON, outputTSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, use json or tsv", format)
+}
+
+func modelListEntries(models []Model) []modelListEntry {
+	entries := make([]modelListEntry, 0, len(models))
+	for _, model := range models {
+		entries = append(entries, modelListEntry{
+			Name:              model.Name,
+			ID:                model.ID,
+			SizeBytes:         model.Size,
+			ParameterSize:     model.ParameterSize,
+			QuantizationLevel: model.QuantizationLevel,
+			Family:            model.Family,
+			Modified:          model.Modified.Format(time.RFC3339),
+		})
+	}
+	return entries
+}
+
+// writeModels writes models to out in a machine readable format, with full unstripped names and no colours
+func writeModels(out io.Writer, models []Model, format string) error {
+	entries := modelListEntries(models)
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case outputTSV:
+		if _, err := fmt.Fprintln(out, "name\tid\tsize_bytes\tparameter_size\tquantization_level\tfamily\tmodified"); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if _, err := fmt.Fprintf(out, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", e.Name, e.ID, e.SizeBytes, e.ParameterSize, e.QuantizationLevel, e.Family, e.Modified); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
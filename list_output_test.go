@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteModels(t *testing.T) {
+	model := Model{}
+	model.Name, model.ID, model.Size = "qwen2:7b", "abc1234", 4400000000
+	model.ParameterSize, model.QuantizationLevel, model.Family = "7.6B", "Q4_0", "qwen2"
+	model.Modified = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	models, _ := matchingModels([]Model{model}, "qwen")
+
+	var out strings.Builder
+	if err := writeModels(&out, models, outputJSON); err != nil {
+		t.Fatalf("writeModels(json) error: %v", err)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &entries); err != nil {
+		t.Fatalf("writeModels(json) isn't valid JSON: %v\n%s", err, out.String())
+	}
+	if len(entries) != 1 || entries[0]["name"] != "qwen2:7b" || entries[0]["size_bytes"] != float64(4400000000) || entries[0]["modified"] != "2024-06-01T12:00:00Z" {
+		t.Errorf("writeModels(json) = %v", entries)
+	}
+
+	out.Reset()
+	if err := writeModels(&out, nil, outputJSON); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("writeModels(json) with no models = %q, %v, want []", out.String(), err)
+	}
+
+	out.Reset()
+	if err := writeModels(&out, models, outputTSV); err != nil {
+		t.Fatalf("writeModels(tsv) error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || lines[1] != "qwen2:7b\tabc1234\t4400000000\t7.6B\tQ4_0\tqwen2\t2024-06-01T12:00:00Z" {
+		t.Errorf("writeModels(tsv) = %q", out.String())
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("writeModels(tsv) output contains colour codes")
+	}
+
+	for _, tt := range []struct {
+		format   string
+		json     bool
+		expected string
+		wantErr  bool
+	}{
+		{"", false, "", false},
+		{"", true, outputJSON, false},
+		{"TSV", false, outputTSV, false},
+		{"tsv", true, outputTSV, false},
+		{"xml", false, "", true},
+	} {
+		got, err := outputFormat(tt.format, tt.json)
+		if got != tt.expected || (err != nil) != tt.wantErr {
+			t.Errorf("outputFormat(%q, %v) = %q, %v", tt.format, tt.json, got, err)
+		}
+	}
+}
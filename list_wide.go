@@ -0,0 +1,192 @@
+// list_wide.go prints the -l -wide table, which adds each model's context length, embedding length, vocabulary size
+// and capabilities from the show API, and its licence with -show-licence. The models are shown concurrently so long
+// lists don't take minutes.
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pkg/gollama"
+)
+
+// wideListWorkers is how many models are shown at once for the wide list
+const wideListWorkers = 8
+
+// wideModelInfo is the show API data the wide list adds to a model, zero values are unknown
+type wideModelInfo struct {
+	Context      int
+	Embedding    int
+	Vocab        int
+	Capabilities []string
+	Licence      string
+}
+
+// modelInfoInt returns the model_info value whose key ends with suffix, e.g. ".context_length" for
+// llama.context_length, or 0 when the model doesn't report one
+func modelInfoInt(info map[string]any, suffix string) int {
+	for key, value := range info {
+		if strings.HasSuffix(key, suffix) {
+			if v, ok := value.(float64); ok {
+				return int(v)
+			}
+		}
+	}
+	return 0
+}
+
+// wideInfoFromShow returns the wide list's data for a model. caps are the capabilities the server reports, when it's
+// new enough to, otherwise they're inferred: embedding is as gollama.EmbeddingCapability decides it, so the list agrees
+// with unloading, vision models have a projector, and tool and fill in the middle support show in the template.
+func wideInfoFromShow(show *api.ShowResponse, caps []string, embedding bool) wideModelInfo {
+	info := wideModelInfo{
+		Context:   modelInfoInt(show.ModelInfo, ".context_length"),
+		Embedding: modelInfoInt(show.ModelInfo, ".embedding_length"),
+		Vocab:     modelInfoInt(show.ModelInfo, ".vocab_size"),
+		Licence:   detectLicence(show.License),
+	}
+	if len(caps) > 0 {
+		info.Capabilities = caps
+		return info
+	}
+	if embedding {
+		info.Capabilities = append(info.Capabilities, "embedding")
+	} else {
+		info.Capabilities = append(info.Capabilities, "completion")
+	}
+	if len(show.ProjectorInfo) > 0 {
+		info.Capabilities = append(info.Capabilities, "vision")
+	}
+	if strings.Contains(show.Template, ".Tools") {
+		info.Capabilities = append(info.Capabilities, "tools")
+	}
+	if strings.Contains(show.Template, ".Suffix") {
+		info.Capabilities = append(info.Capabilities, "insert")
+	}
+	return info
+}
+
+// fetchWideModelInfo shows the models with a pool of workers, returning their data in the same order. A model that
+// can't be shown is logged and left blank.
+func fetchWideModelInfo(ctx context.Context, client *api.Client, apiURL string, models []Model, workers int) []wideModelInfo {
+	infos := make([]wideModelInfo, len(models))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(models)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				show, err := client.Show(ctx, &api.ShowRequest{Name: models[i].Name})
+				if err != nil {
+					logging.ErrorLogger.Printf("Error showing %s for the wide list: %v\n", models[i].Name, err)
+					continue
+				}
+				embedding, _ := gollama.EmbeddingCapability(ctx, apiHTTPClient, apiURL, models[i].Name)
+				caps, _ := gollama.Capabilities(ctx, apiHTTPClient, apiURL, models[i].Name) // cached by EmbeddingCapability
+				infos[i] = wideInfoFromShow(show, caps, embedding)
+			}
+		}()
+	}
+	for i := range models {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return infos
+}
+
+// truncateMiddle shortens text to width by replacing its middle with "…", keeping the start and the tag at the end
+func truncateMiddle(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width < 2 {
+		return string(runes[:max(width, 0)])
+	}
+	head := (width - 1) / 2
+	tail := width - 1 - head
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+// printWideModelTable writes the -l table with the show API columns, and the licence column when licence is set,
+// fitting it to width by truncating names in the middle
+func printWideModelTable(w io.Writer, models []Model, infos []wideModelInfo, stripString string, width int, licence bool) {
+	if len(models) == 0 {
+		fmt.Fprintln(w, "No models available to display.")
+		return
+	}
+
+	count := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+	header := []string{"Name", "Size", "Quant", "Family", "Modified", "ID", "Context", "Embedding", "Vocab", "Capabilities"}
+	rows := make([][]string, len(models))
+	for i, model := range models {
+		rows[i] = []string{model.DisplayName(stripString), model.SizeLabel(), model.QuantizationLevel, model.Family, model.Modified.Format("2006-01-02"),
+			model.ID, count(infos[i].Context), count(infos[i].Embedding), count(infos[i].Vocab), strings.Join(infos[i].Capabilities, ",")}
+		if licence {
+			rows[i] = append(rows[i], cmp.Or(infos[i].Licence, "-"))
+		}
+	}
+	if licence {
+		header = append(header, "Licence")
+	}
+
+	const colSpacing = 2
+	widths := make([]int, len(header))
+	for col := range header {
+		widths[col] = lipgloss.Width(header[col])
+		for _, row := range rows {
+			widths[col] = max(widths[col], lipgloss.Width(row[col]))
+		}
+	}
+	// The name gets whatever the other columns leave, but never less than its header
+	others := 0
+	for _, w := range widths[1:] {
+		others += w + colSpacing
+	}
+	widths[0] = max(min(widths[0], width-others-colSpacing), len(header[0]))
+
+	format := func(cells []string) string {
+		var b strings.Builder
+		for col, cell := range cells {
+			if col == 0 {
+				cell = truncateMiddle(cell, widths[0])
+			}
+			if col < len(cells)-1 {
+				cell += strings.Repeat(" ", widths[col]-lipgloss.Width(cell)+colSpacing)
+			}
+			b.WriteString(cell)
+		}
+		return b.String()
+	}
+	fmt.Fprintln(w, lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(format(header)))
+	for _, row := range rows {
+		fmt.Fprintln(w, format(row))
+	}
+}
+
+// listModelsWide prints the wide -l table to the terminal
+func listModelsWide(ctx context.Context, client *api.Client, apiURL string, models []Model, stripString string, licence bool) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width = 160
+	}
+	printWideModelTable(os.Stdout, models, fetchWideModelInfo(ctx, client, apiURL, models, wideListWorkers), stripString, width, licence)
+}
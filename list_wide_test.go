@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+)
+
+func TestWideList(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ShowRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		// The capabilities lookup sends the newer model field
+		name := req.Name
+		if name == "" {
+			name = req.Model
+		}
+		switch {
+		case strings.HasPrefix(name, "bge"):
+			// Reported capabilities win over the pooling type and name guess
+			json.NewEncoder(w).Encode(map[string]any{
+				"details":      map[string]any{"family": "bert"},
+				"model_info":   map[string]any{"bert.embedding_length": 1024},
+				"capabilities": []string{"embedding"},
+			})
+		case strings.HasPrefix(name, "llava"):
+			json.NewEncoder(w).Encode(api.ShowResponse{
+				Details:       api.ModelDetails{Family: "llama"},
+				Template:      "{{ .Prompt }}",
+				ModelInfo:     map[string]any{"llama.context_length": 4096, "llama.embedding_length": 4096, "llama.vocab_size": 32000},
+				ProjectorInfo: map[string]any{"general.architecture": "clip"},
+			})
+		case strings.HasPrefix(name, "nomic"):
+			json.NewEncoder(w).Encode(api.ShowResponse{
+				Details:   api.ModelDetails{Family: "nomic-bert"},
+				ModelInfo: map[string]any{"nomic-bert.context_length": 2048, "nomic-bert.embedding_length": 768, "nomic-bert.pooling_type": 1},
+			})
+		case strings.HasPrefix(name, "missing"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			json.NewEncoder(w).Encode(api.ShowResponse{
+				Details:   api.ModelDetails{Family: "qwen2"},
+				Template:  "{{ if .Tools }}{{ .Tools }}{{ end }}{{ if .Suffix }}{{ .Suffix }}{{ end }}",
+				ModelInfo: map[string]any{"qwen2.context_length": 32768},
+			})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, server.Client())
+
+	newModel := func(name string) Model {
+		model := Model{}
+		model.Name, model.ID, model.Size = name, "abc1234", 1<<30
+		return model
+	}
+	models := []Model{newModel("llava:7b"), newModel("nomic-embed-text:latest"), newModel("missing:latest"), newModel("bge-m3:latest")}
+	for i := range 20 {
+		models = append(models, newModel(fmt.Sprintf("qwen2.5-coder:%d", i)))
+	}
+	infos := fetchWideModelInfo(context.Background(), client, server.URL, models, wideListWorkers)
+	if peak > wideListWorkers {
+		t.Errorf("%d models were shown at once, want at most %d", peak, wideListWorkers)
+	}
+
+	want := []wideModelInfo{
+		{Context: 4096, Embedding: 4096, Vocab: 32000, Capabilities: []string{"completion", "vision"}},
+		{Context: 2048, Embedding: 768, Capabilities: []string{"embedding"}},
+		{},
+		{Embedding: 1024, Capabilities: []string{"embedding"}},
+		{Context: 32768, Capabilities: []string{"completion", "tools", "insert"}},
+	}
+	for i, w := range want {
+		if got := infos[i]; !reflect.DeepEqual(got, w) {
+			t.Errorf("info of %s = %+v, want %+v", models[i].Name, got, w)
+		}
+	}
+
+	var buf bytes.Buffer
+	printWideModelTable(&buf, models[:3], infos[:3], "", 110, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], "Capabilities") {
+		t.Fatalf("wide table = %q, want a header and 3 rows", buf.String())
+	}
+	for _, line := range lines {
+		if width := lipgloss.Width(line); width > 110 {
+			t.Errorf("line %q is %d wide, want at most 110", line, width)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "nomic-em…xt:latest ") || !strings.Contains(lines[2], "768") || !strings.Contains(lines[2], "embedding") {
+		t.Errorf("nomic row = %q, want its name truncated in the middle and its info", lines[2])
+	}
+	if !strings.Contains(lines[3], "-") {
+		t.Errorf("row of a model that couldn't be shown = %q, want blank info", lines[3])
+	}
+
+	if got := truncateMiddle("registry.internal/team/llama3:8b-q4_K_M", 15); got != "registr…-q4_K_M" {
+		t.Errorf("truncateMiddle() = %q, want registr…-q4_K_M", got)
+	}
+}
@@ -0,0 +1,130 @@
+// create.go registers LM Studio model files with Ollama through its API.
+package lmstudio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// files returns the model file followed by its vision projector, if it has one
+func (m Model) files() []string {
+	files := []string{m.Path}
+	if m.VisionPath != "" {
+		files = append(files, m.VisionPath)
+	}
+	return files
+}
+
+// PercentProgress adapts fn into a HashProgressFunc that's only called each time a file passes another 10%,
+// calls are serialised as files are hashed concurrently
+func PercentProgress(fn func(path string, percent int)) HashProgressFunc {
+	var (
+		mu   sync.Mutex
+		last = make(map[string]int)
+	)
+	return func(path string, done, total int64) {
+		percent := 100
+		if total > 0 {
+			percent = int(done * 100 / total)
+		}
+		percent -= percent % 10
+
+		// fn is called under the lock so it doesn't need to be safe for concurrent use
+		mu.Lock()
+		defer mu.Unlock()
+		if prev, seen := last[path]; seen && percent <= prev {
+			return
+		}
+		last[path] = percent
+		fn(path, percent)
+	}
+}
+
+// blobExists reports whether the Ollama server already has a blob with the given digest
+func blobExists(ctx context.Context, httpClient *http.Client, base *url.URL, digest string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, base.JoinPath("api", "blobs", digest).String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// CreateOllamaModel hashes the model's files, uploads any blobs the Ollama server doesn't already have
+// and creates the model from them. Hashes are cached as they complete, so a cancelled run picks up where it left off.
+// httpClient makes the requests, give it one that authenticates when the server needs it (see config.NewHTTPClient).
+func CreateOllamaModel(ctx context.Context, httpClient *http.Client, model Model, ollamaHost string, progress HashProgressFunc) error {
+	base, err := url.Parse(ollamaHost)
+	if err != nil {
+		return fmt.Errorf("invalid Ollama host %s: %w", ollamaHost, err)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client := api.NewClient(base, httpClient)
+
+	paths := model.files()
+	digests, err := hashFiles(ctx, paths, progress)
+	if err != nil {
+		return fmt.Errorf("failed to hash model files for %s: %w", model.Name, err)
+	}
+
+	files := make(map[string]string, len(paths))
+	for _, path := range paths {
+		digest := "sha256:" + digests[path]
+		files[filepath.Base(path)] = digest
+
+		exists, err := blobExists(ctx, httpClient, base, digest)
+		if err != nil {
+			return fmt.Errorf("failed to check for blob %s: %w", digest, err)
+		}
+		if exists {
+			logging.DebugLogger.Printf("Blob %s for %s already exists\n", digest, path)
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		logging.DebugLogger.Printf("Uploading %s as blob %s\n", path, digest)
+		err = client.CreateBlob(ctx, digest, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", path, err)
+		}
+	}
+
+	req := &api.CreateRequest{
+		Model:      model.Name,
+		Files:      files,
+		Template:   modelTemplate,
+		Parameters: modelParameters,
+	}
+	var lastStatus string
+	err = client.Create(ctx, req, func(resp api.ProgressResponse) error {
+		if resp.Status != lastStatus {
+			logging.DebugLogger.Printf("Creating %s: %s\n", model.Name, resp.Status)
+			lastStatus = resp.Status
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Ollama model %s: %w", model.Name, err)
+	}
+	logging.DebugLogger.Printf("Successfully created Ollama model %s", model.Name)
+	return nil
+}
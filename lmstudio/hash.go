@@ -0,0 +1,206 @@
+// hash.go calculates and caches the SHA256 digests Ollama needs to register model files.
+package lmstudio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// hashChunkSize is how much of a file is read between progress callbacks
+const hashChunkSize = 16 * 1024 * 1024
+
+// maxHashWorkers bounds how many files are hashed at once, hashing is disk bound so more doesn't help
+const maxHashWorkers = 2
+
+// HashProgressFunc is called as a file is hashed with the number of bytes processed so far and the file size
+type HashProgressFunc func(path string, done, total int64)
+
+// hashCacheEntry is a previously computed digest, only valid while the file's size and mtime are unchanged
+type hashCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+var hashCacheMu sync.Mutex
+
+// hashCachePath returns the location of the hash cache file
+func hashCachePath() string {
+	return filepath.Join(utils.GetConfigDir(), "lmstudio-hashes.json")
+}
+
+func loadHashCache() (map[string]hashCacheEntry, error) {
+	cache := make(map[string]hashCacheEntry)
+	data, err := os.ReadFile(hashCachePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		// A corrupt cache only costs a re-hash
+		logging.ErrorLogger.Printf("Ignoring unreadable hash cache %s: %v\n", hashCachePath(), err)
+		return make(map[string]hashCacheEntry), nil
+	}
+	return cache, nil
+}
+
+func saveHashCache(cache map[string]hashCacheEntry) error {
+	path := hashCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create hash cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hash cache: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// cachedSHA256 returns the cached digest for path if the file hasn't changed since it was hashed
+func cachedSHA256(path string) (string, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+
+	hashCacheMu.Lock()
+	defer hashCacheMu.Unlock()
+	cache, err := loadHashCache()
+	if err != nil {
+		return "", false
+	}
+	entry, ok := cache[path]
+	if !ok || entry.Size != fi.Size() || !entry.ModTime.Equal(fi.ModTime()) {
+		return "", false
+	}
+	return entry.SHA256, true
+}
+
+func storeSHA256(path string, fi os.FileInfo, digest string) error {
+	hashCacheMu.Lock()
+	defer hashCacheMu.Unlock()
+	cache, err := loadHashCache()
+	if err != nil {
+		return err
+	}
+	cache[path] = hashCacheEntry{Size: fi.Size(), ModTime: fi.ModTime(), SHA256: digest}
+	return saveHashCache(cache)
+}
+
+// calculateSHA256 hashes the file at path in chunks, calling progress (if set) after each chunk and stopping if ctx is cancelled
+func calculateSHA256(ctx context.Context, path string, progress HashProgressFunc) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	total := fi.Size()
+
+	h := sha256.New()
+	var done int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("stopped hashing %s: %w", path, err)
+		}
+		n, err := io.CopyN(h, f, hashChunkSize)
+		done += n
+		if n > 0 && progress != nil {
+			progress(path, done, total)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the SHA256 digest of path, reusing the cached digest when the file is unchanged
+func hashFile(ctx context.Context, path string, progress HashProgressFunc) (string, error) {
+	if digest, ok := cachedSHA256(path); ok {
+		logging.DebugLogger.Printf("Using cached hash for %s\n", path)
+		return digest, nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	digest, err := calculateSHA256(ctx, path, progress)
+	if err != nil {
+		return "", err
+	}
+	// Only cache if the file didn't change while it was being hashed
+	if after, err := os.Stat(path); err == nil && after.Size() == fi.Size() && after.ModTime().Equal(fi.ModTime()) {
+		if err := storeSHA256(path, fi, digest); err != nil {
+			logging.ErrorLogger.Printf("Failed to cache hash for %s: %v\n", path, err)
+		}
+	}
+	return digest, nil
+}
+
+// hashFiles hashes paths concurrently with at most maxHashWorkers at a time and returns their digests keyed by path.
+// The first file that fails stops the others rather than leaving them to finish hashing for nothing, and its error is
+// the one returned.
+func hashFiles(ctx context.Context, paths []string, progress HashProgressFunc) (map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		digests  = make(map[string]string, len(paths))
+		sem      = make(chan struct{}, maxHashWorkers)
+	)
+
+	for _, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			digest, err := hashFile(ctx, path, progress)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			digests[path] = digest
+		}(path)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return digests, nil
+}
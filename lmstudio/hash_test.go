@@ -0,0 +1,186 @@
+package lmstudio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFixture(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCalculateSHA256Progress(t *testing.T) {
+	data := make([]byte, hashChunkSize+100)
+	path := writeFixture(t, t.TempDir(), "model.gguf", data)
+
+	var calls []int64
+	digest, err := calculateSHA256(context.Background(), path, func(p string, done, total int64) {
+		if p != path {
+			t.Errorf("progress path = %s, want %s", p, path)
+		}
+		if total != int64(len(data)) {
+			t.Errorf("progress total = %d, want %d", total, len(data))
+		}
+		calls = append(calls, done)
+	})
+	if err != nil {
+		t.Fatalf("calculateSHA256() error = %v", err)
+	}
+	if digest != sha256Hex(data) {
+		t.Errorf("calculateSHA256() = %s, want %s", digest, sha256Hex(data))
+	}
+	want := []int64{hashChunkSize, int64(len(data))}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] {
+		t.Errorf("progress calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCalculateSHA256Cancelled(t *testing.T) {
+	data := make([]byte, 2*hashChunkSize+1)
+	path := writeFixture(t, t.TempDir(), "model.gguf", data)
+
+	// Cancelling after the first chunk stops hashing before the next one is read
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	_, err := calculateSHA256(ctx, path, func(string, int64, int64) {
+		calls++
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), path) {
+		t.Errorf("calculateSHA256() error = %v, want a cancellation naming %s", err, path)
+	}
+	if calls != 1 {
+		t.Errorf("progress called %d times, want 1 before the cancellation", calls)
+	}
+}
+
+func TestHashFileCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeFixture(t, t.TempDir(), "model.gguf", []byte("first"))
+
+	if _, ok := cachedSHA256(path); ok {
+		t.Fatal("cachedSHA256() hit before the file was hashed")
+	}
+	if _, err := hashFile(context.Background(), path, nil); err != nil {
+		t.Fatalf("hashFile() error = %v", err)
+	}
+
+	// An unchanged file is served from the cache without calling progress
+	digest, err := hashFile(context.Background(), path, func(string, int64, int64) {
+		t.Error("progress called for a cached hash")
+	})
+	if err != nil {
+		t.Fatalf("hashFile() error = %v", err)
+	}
+	if digest != sha256Hex([]byte("first")) {
+		t.Errorf("cached digest = %s, want %s", digest, sha256Hex([]byte("first")))
+	}
+
+	// Same size, new contents and mtime: the cache entry is stale
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cachedSHA256(path); ok {
+		t.Error("cachedSHA256() hit after the mtime changed")
+	}
+	var hashed bool
+	digest, err = hashFile(context.Background(), path, func(string, int64, int64) { hashed = true })
+	if err != nil {
+		t.Fatalf("hashFile() error = %v", err)
+	}
+	if !hashed {
+		t.Error("stale cache entry was not re-hashed")
+	}
+	if digest != sha256Hex([]byte("other")) {
+		t.Errorf("digest = %s, want %s", digest, sha256Hex([]byte("other")))
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	contents := map[string][]byte{
+		"model.gguf":  []byte("model"),
+		"mmproj.gguf": []byte("projector"),
+		"other.gguf":  []byte("other"),
+	}
+	var paths []string
+	for name, data := range contents {
+		paths = append(paths, writeFixture(t, dir, name, data))
+	}
+
+	digests, err := hashFiles(context.Background(), paths, nil)
+	if err != nil {
+		t.Fatalf("hashFiles() error = %v", err)
+	}
+	for _, path := range paths {
+		if want := sha256Hex(contents[filepath.Base(path)]); digests[path] != want {
+			t.Errorf("digest for %s = %s, want %s", path, digests[path], want)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing.gguf")
+	if _, err := hashFiles(context.Background(), []string{paths[0], missing}, nil); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("hashFiles() error = %v, want one naming %s", err, missing)
+	}
+}
+
+func TestPercentProgress(t *testing.T) {
+	var got []int
+	progress := PercentProgress(func(path string, percent int) { got = append(got, percent) })
+	for _, done := range []int64{5, 12, 15, 50, 100} {
+		progress("a", done, 100)
+	}
+	want := []int{0, 10, 50, 100}
+	if len(got) != len(want) {
+		t.Fatalf("PercentProgress reported %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PercentProgress reported %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestScanModelsVisionProjector(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "publisher", "model")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, dir, "model-Q4_K_M.gguf", []byte("model"))
+	projector := writeFixture(t, dir, "mmproj-model-f16.gguf", []byte("projector"))
+
+	models, err := ScanModels(filepath.Dir(filepath.Dir(dir)))
+	if err != nil {
+		t.Fatalf("ScanModels() error = %v", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("ScanModels() found %d models, want 1", len(models))
+	}
+	if models[0].VisionPath != projector {
+		t.Errorf("VisionPath = %s, want %s", models[0].VisionPath, projector)
+	}
+}
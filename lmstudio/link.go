@@ -1,61 +1,46 @@
 package lmstudio
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"text/template"
 
 	"github.com/sammcj/gollama/logging"
 	"github.com/sammcj/gollama/utils"
 )
 
 type Model struct {
-	Name     string
-	Path     string
-	FileType string // e.g., "gguf", "bin", etc.
+	Name       string
+	Path       string
+	FileType   string // e.g., "gguf", "bin", etc.
+	VisionPath string // multimodal projector (mmproj) found alongside the model, if any
 }
 
-// ModelfileTemplate contains the default template for creating Modelfiles
-// TODO: Make the default Modelfile template configurable
-const ModelfileTemplate = `### MODEL IMPORTED FROM LM-STUDIO BY GOLLAMA ###
-
-# Tune the below inference, model load parameters and template to your needs
-# The template and stop parameters are currently set to the default for models that use the ChatML format
-# If required update these match the prompt format your model expects
-# You can look at existing similar models on the Ollama model hub for examples
-# See https://github.com/ollama/ollama/blob/main/docs/modelfile.md for a complete reference
-
-FROM {{.ModelPath}}
-
-### Model Load Parameters ###
-PARAMETER num_ctx 4096
-
-### Inference Parameters ####
-PARAMETER temperature 0.4
-PARAMETER top_p 0.6
-
-### Chat Template Parameters ###
-
-TEMPLATE """
-{{.Prompt}}
-"""
+// isProjector reports whether a file is a vision projector rather than a model in its own right
+func isProjector(path string) bool {
+	return strings.HasPrefix(strings.ToLower(filepath.Base(path)), "mmproj")
+}
 
-PARAMETER stop "<|im_start|>"
-PARAMETER stop "<|im_end|>"
-`
+// modelTemplate and modelParameters are the defaults models are registered with,
+// they suit models that use the ChatML format and can be tuned afterwards with a Modelfile
+const modelTemplate = "{{ .Prompt }}"
 
-type ModelfileData struct {
-	ModelPath string
-  Prompt string
+var modelParameters = map[string]any{
+	"num_ctx":     4096,
+	"temperature": 0.4,
+	"top_p":       0.6,
+	"stop":        []string{"<|im_start|>", "<|im_end|>"},
 }
 
 // ScanModels scans the given directory for LM Studio model files
 func ScanModels(dirPath string) ([]Model, error) {
 	var models []Model
+	projectors := make(map[string]string) // directory -> projector path
 
 	// First check if directory exists
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
@@ -79,6 +64,11 @@ func ScanModels(dirPath string) ([]Model, error) {
 
 		// Check for model file extensions
 		ext := strings.ToLower(filepath.Ext(path))
+		if ext == ".gguf" && isProjector(path) {
+			logging.DebugLogger.Printf("Found vision projector: %s", path)
+			projectors[filepath.Dir(path)] = path
+			return nil
+		}
 		if ext == ".gguf" || ext == ".bin" {
 			name := strings.TrimSuffix(filepath.Base(path), ext)
 
@@ -105,6 +95,10 @@ func ScanModels(dirPath string) ([]Model, error) {
 		return nil, fmt.Errorf("error scanning directory %s: %w", dirPath, err)
 	}
 
+	for i := range models {
+		models[i].VisionPath = projectors[filepath.Dir(models[i].Path)]
+	}
+
 	if len(models) == 0 {
 		logging.InfoLogger.Printf("No models found in directory: %s", dirPath)
 	} else {
@@ -136,52 +130,10 @@ func modelExists(modelName string) bool {
 	return strings.Contains(string(output), modelName)
 }
 
-// createModelfile creates a Modelfile for the given model
-func createModelfile(modelName string, modelPath string) error {
-	modelfilePath := filepath.Join(filepath.Dir(modelPath), fmt.Sprintf("Modelfile.%s", modelName))
-
-	// Check if Modelfile already exists
-	if _, err := os.Stat(modelfilePath); err == nil {
-		logging.InfoLogger.Printf("Modelfile already exists for %s, skipping creation", modelName)
-		return nil
-	}
-
-	tmpl, err := template.New("modelfile").Parse(ModelfileTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse Modelfile template: %w", err)
-	}
-
-	data := ModelfileData{
-		ModelPath: modelPath, // Use full path instead of just the base name
-    Prompt: "{{.Prompt}}", // Preserve this as a template variable for Ollama
-	}
-
-	file, err := os.OpenFile(modelfilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to create Modelfile: %w", err)
-	}
-	defer file.Close()
-
-	logging.DebugLogger.Printf("Creating Modelfile at: %s with model path: %s", modelfilePath, data.ModelPath)
-
-	if err := tmpl.Execute(file, data); err != nil {
-		return fmt.Errorf("failed to write Modelfile template: %w", err)
-	}
-
-	// Log the content of the created Modelfile for debugging
-	content, err := os.ReadFile(modelfilePath)
-	if err != nil {
-		logging.ErrorLogger.Printf("Warning: Could not read back Modelfile for verification: %v", err)
-	} else {
-		logging.DebugLogger.Printf("Created Modelfile content:\n%s", string(content))
-	}
-
-	return nil
-}
-
 // LinkModelToOllama links an LM Studio model to Ollama
 // If dryRun is true, it will only print what would happen without making any changes
-func LinkModelToOllama(model Model, dryRun bool, ollamaHost string) error {
+// progress, if set, is called while the model files are hashed, and httpClient makes the requests to Ollama
+func LinkModelToOllama(ctx context.Context, httpClient *http.Client, model Model, dryRun bool, ollamaHost string, progress HashProgressFunc) error {
 	// Check if we're connecting to a local Ollama instance
 	if !utils.IsLocalhost(ollamaHost) {
 		return fmt.Errorf("linking LM Studio models to Ollama is only supported when connecting to a local Ollama instance (got %s)", ollamaHost)
@@ -212,35 +164,17 @@ func LinkModelToOllama(model Model, dryRun bool, ollamaHost string) error {
 		return nil
 	}
 
-	// Create model-specific Modelfile
-	modelfilePath := filepath.Join(filepath.Dir(targetPath), fmt.Sprintf("Modelfile.%s", model.Name))
 	if dryRun {
-		logging.InfoLogger.Printf("[DRY RUN] Would create Modelfile at: %s", modelfilePath)
-		logging.InfoLogger.Printf("[DRY RUN] Would create Ollama model: %s using Modelfile", model.Name)
+		for _, path := range model.files() {
+			if _, ok := cachedSHA256(path); ok {
+				logging.InfoLogger.Printf("[DRY RUN] Hash for %s is cached", path)
+			} else {
+				logging.InfoLogger.Printf("[DRY RUN] Would calculate hash for %s", path)
+			}
+		}
+		logging.InfoLogger.Printf("[DRY RUN] Would create Ollama model: %s", model.Name)
 		return nil
 	}
 
-	if err := createModelfile(model.Name, targetPath); err != nil {
-		return fmt.Errorf("failed to create Modelfile for %s: %w", model.Name, err)
-	}
-
-	// Create the model in Ollama
-	logging.DebugLogger.Printf("Creating Ollama model %s using Modelfile at: %s", model.Name, modelfilePath)
-	cmd := exec.Command("ollama", "create", model.Name, "-f", modelfilePath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Log the error output for debugging
-		logging.ErrorLogger.Printf("Ollama create command output: %s", string(output))
-		// Clean up Modelfile on failure
-		os.Remove(modelfilePath)
-		return fmt.Errorf("failed to create Ollama model %s: %s - %w", model.Name, string(output), err)
-	}
-	logging.DebugLogger.Printf("Successfully created Ollama model %s", model.Name)
-
-	// Clean up the Modelfile after successful creation
-	if err := os.Remove(modelfilePath); err != nil {
-		logging.ErrorLogger.Printf("Warning: Could not remove temporary Modelfile %s: %v", modelfilePath, err)
-	}
-
-	return nil
+	return CreateOllamaModel(ctx, httpClient, model, ollamaHost, progress)
 }
@@ -1,6 +1,9 @@
 package logging
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,17 +14,96 @@ import (
 )
 
 var (
+	TraceLogger zerolog.Logger
 	DebugLogger zerolog.Logger
 	InfoLogger  zerolog.Logger
 	ErrorLogger zerolog.Logger
 )
 
+// Rotation is when the log file is rotated by size and how many rotated files are kept, zero values use the
+// defaults
+type Rotation struct {
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+}
+
+// ErrStderrFallback is returned when the log file can't be created and the loggers write to stderr instead
+var ErrStderrFallback = errors.New("logging to stderr instead")
+
+// DefaultRotation rotates the log at 2MB, keeping 3 old files for up to 60 days
+var DefaultRotation = Rotation{MaxSizeMB: 2, MaxBackups: 3, MaxAgeDays: 60}
+
+func (r Rotation) withDefaults() Rotation {
+	if r.MaxSizeMB <= 0 {
+		r.MaxSizeMB = DefaultRotation.MaxSizeMB
+	}
+	if r.MaxBackups <= 0 {
+		r.MaxBackups = DefaultRotation.MaxBackups
+	}
+	if r.MaxAgeDays <= 0 {
+		r.MaxAgeDays = DefaultRotation.MaxAgeDays
+	}
+	return r
+}
+
+// Init sets up the loggers to write to a log file rotated with the default rotation
 func Init(logLevel string, logFilePath string) error {
+	return InitWithRotation(logLevel, logFilePath, DefaultRotation)
+}
+
+// InitWithRotation sets up the loggers to write to a log file that's rotated by size. If the log file can't be
+// created the loggers write to stderr instead and an ErrStderrFallback error is returned, so the caller can warn and
+// carry on.
+// The trace level adds the very chatty debug output such as every key press and model info dumps.
+func InitWithRotation(logLevel string, logFilePath string, rotation Rotation) error {
+	// Set the log level
+	level, err := zerolog.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(level)
+
+	logFilePath, err = openLogFile(logFilePath)
+	var writer io.Writer = os.Stderr
+	if err == nil {
+		// Configure log rotation with lumberjack
+		rotation = rotation.withDefaults()
+		writer = &lumberjack.Logger{
+			Filename:   logFilePath,
+			MaxSize:    rotation.MaxSizeMB,
+			MaxBackups: rotation.MaxBackups,
+			MaxAge:     rotation.MaxAgeDays,
+			Compress:   false, // disabled by default
+		}
+	}
+
+	SetOutput(writer)
+
+	if err != nil {
+		return fmt.Errorf("can't write to the log file %s, %w: %w", logFilePath, ErrStderrFallback, err)
+	}
+	DebugLogger.Printf("Logging to: %s\n", logFilePath)
+	return nil
+}
+
+// SetOutput points the loggers at w. Once logging has fallen back to stderr the TUI sets it to io.Discard, as writing
+// to stderr would draw over the screen.
+func SetOutput(w io.Writer) {
+	log.Logger = zerolog.New(zerolog.MultiLevelWriter(w)).With().Timestamp().Logger()
+	TraceLogger = log.Logger.Level(zerolog.TraceLevel)
+	DebugLogger = log.Logger.Level(zerolog.DebugLevel)
+	InfoLogger = log.Logger.Level(zerolog.InfoLevel)
+	ErrorLogger = log.Logger.Level(zerolog.ErrorLevel)
+}
+
+// openLogFile expands the log file path, using the default when it's empty, and checks the file can be created
+func openLogFile(logFilePath string) (string, error) {
 	// Set default log file path if none is provided
 	if logFilePath == "" {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			return err
+			return logFilePath, err
 		}
 		logFilePath = filepath.Join(homeDir, ".config", "gollama", "gollama.log")
 	}
@@ -30,44 +112,29 @@ func Init(logLevel string, logFilePath string) error {
 	if strings.HasPrefix(logFilePath, "~") {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			return err
+			return logFilePath, err
 		}
 		logFilePath = filepath.Join(homeDir, logFilePath[1:])
 	}
 
 	// Create the directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(logFilePath), 0755); err != nil {
-		return err
+		return logFilePath, err
 	}
-
-	// Configure log rotation with lumberjack
-	rotate := &lumberjack.Logger{
-		Filename:   logFilePath,
-		MaxSize:    2,     // megabytes
-		MaxBackups: 3,     // number of files
-		MaxAge:     60,    // days
-		Compress:   false, // disabled by default
+	// lumberjack only opens the file on the first write, so make sure it can be
+	f, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return logFilePath, err
 	}
+	return logFilePath, f.Close()
+}
 
-	// Set the log level
+// SetLevel changes the log level of the running loggers, e.g. when the config is reloaded
+func SetLevel(logLevel string) error {
 	level, err := zerolog.ParseLevel(logLevel)
 	if err != nil {
 		return err
 	}
 	zerolog.SetGlobalLevel(level)
-
-	// Use lumberjack for logging to a file
-	fileWriter := zerolog.MultiLevelWriter(rotate)
-
-	// Initialize loggers
-	log.Logger = zerolog.New(fileWriter).With().Timestamp().Logger()
-	DebugLogger = log.Logger.Level(zerolog.DebugLevel)
-	InfoLogger = log.Logger.Level(zerolog.InfoLevel)
-	ErrorLogger = log.Logger.Level(zerolog.ErrorLevel)
-
-	if logLevel == "debug" {
-		DebugLogger.Printf("Logging to: %s\n", logFilePath)
-	}
-
 	return nil
 }
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -119,3 +121,47 @@ func TestInit(t *testing.T) {
 		})
 	}
 }
+
+func TestInitWithRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	// trace adds the chatty output that debug leaves out
+	for level, wantTrace := range map[string]bool{"trace": true, "debug": false} {
+		path := filepath.Join(dir, level+".log")
+		if err := InitWithRotation(level, path, Rotation{MaxSizeMB: 1}); err != nil {
+			t.Fatalf("InitWithRotation(%s) error = %v", level, err)
+		}
+		TraceLogger.Trace().Msg("a trace message")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(data), "a trace message"); got != wantTrace {
+			t.Errorf("log level %s wrote the trace message: %v, want %v", level, got, wantTrace)
+		}
+	}
+
+	// A log file that can't be created falls back to stderr rather than failing
+	blocker := filepath.Join(dir, "not-a-directory")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := InitWithRotation("info", filepath.Join(blocker, "gollama.log"), DefaultRotation)
+	if !errors.Is(err, ErrStderrFallback) {
+		t.Errorf("InitWithRotation() with an unusable path error = %v, want ErrStderrFallback", err)
+	}
+	// The TUI moves the fallback off stderr, where it would draw over the screen
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	ErrorLogger.Error().Msg("an error")
+	if !strings.Contains(buf.String(), "an error") {
+		t.Errorf("SetOutput() logged %q, want the error written to the new output", buf.String())
+	}
+	if err := InitWithRotation("loud", filepath.Join(dir, "loud.log"), DefaultRotation); err == nil || errors.Is(err, ErrStderrFallback) {
+		t.Errorf("InitWithRotation() with an invalid level error = %v, want a level error", err)
+	}
+
+	if got := (Rotation{MaxBackups: 7}).withDefaults(); got != (Rotation{MaxSizeMB: 2, MaxBackups: 7, MaxAgeDays: 60}) {
+		t.Errorf("withDefaults() = %+v", got)
+	}
+}
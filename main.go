@@ -3,18 +3,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -25,56 +28,105 @@ import (
 	"github.com/sammcj/gollama/config"
 	"github.com/sammcj/gollama/lmstudio"
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
 	"github.com/sammcj/gollama/utils"
 	"github.com/sammcj/gollama/vramestimator"
 )
 
 type AppModel struct {
-	width              int
-	height             int
-	ollamaModelsDir    string
-	cfg                *config.Config
-	inspectedModel     Model
-	list               list.Model
-	models             []Model
-	selectedModels     []Model
-	confirmDeletion    bool
-	inspecting         bool
-	editing            bool
-	message            string
-	keys               KeyMap
-	client             *api.Client
-	lmStudioModelsDir  string
-	noCleanup          bool
-	table              table.Model
-	filterInput        tea.Model
-	showTop            bool
-	progress           progress.Model
-	altScreenActive    bool
-	view               View
-	showProgress       bool
-	pullInput          textinput.Model
-	pulling            bool
-	pullProgress       float64
-	newModelPull       bool
-	comparingModelfile bool
-	modelfileDiffs     []ModelfileDiff
+	width                 int
+	height                int
+	ollamaModelsDir       string
+	cfg                   *config.Config
+	inspectedModel        Model
+	list                  list.Model
+	models                []Model
+	selectedModels        []Model
+	confirmDeletion       bool
+	deleteRunning         map[string]bool // the models awaiting deletion that are loaded
+	confirmPinnedDeletion bool
+	inspecting            bool
+	editing               bool
+	message               string
+	keys                  KeyMap
+	client                *api.Client
+	lmStudioModelsDir     string
+	noCleanup             bool
+	table                 table.Model
+	filterInput           tea.Model
+	showTop               bool
+	progress              progress.Model
+	altScreenActive       bool
+	view                  View
+	showProgress          bool
+	pullInput             textinput.Model
+	pulling               bool
+	pullProgress          float64
+	pullFeed              progressFeed       // progress of the current pull waiting for the next tick, nil when not pulling
+	cancelPull            context.CancelFunc // cancels the current pull
+	cancelPush            context.CancelFunc // cancels the push or copy to another host in flight
+	newModelPull          bool
+	comparingModelfile    bool
+	modelfileCompare      modelfileCompareMsg // the last comparison of a model with the registry, shown while comparingModelfile
+	dashboard             dashboardData
+	dashboardCursor       int
+	copyConflict          *copyConflict
+	topModels             []runningModel // running models shown in the top view, refreshed while it's open
+	topCursor             int
+	topErr                error
+	topGeneration         int
+	topMessage            string        // the result of the last action taken in the top view
+	settingsCopy          *settingsCopy // settings copy waiting for the user to confirm overwriting the target's values
+	transferHost          string        // host a model is being copied to, shown with the progress bar
+	modelsDir             modelsDirInfo
+	hostCompare           hostCompare
+	pullLayers            *pullLayers  // per layer progress of the current pull, nil when not pulling
+	pushLayers            *pullLayers  // per layer progress of the current push, nil when not pushing
+	pushFeed              progressFeed // progress of the current push until the next tick takes it
+	pushProgress          float64
+	themePicker           themePicker
+	inspectEdit           inspectEdit
+	inspectRuntime        inspectRuntime  // how the inspected model is running compared to its modelfile
+	inspectFiles          inspectFiles    // files the inspected model loads, for its vision projector
+	inspectLayers         inspectLayers   // manifest layers of the inspected model and their blobs
+	inspectLicence        inspectLicence  // licence text of the inspected model and its scroll position, when it's shown
+	pushBatch             *pushBatch      // selected models being pushed or copied to another host, nil when there's no batch
+	pendingRestore        *pendingRestore // a modelfile snapshot whose restore after a pull failed, retried with ctrl+r
+	contextLengths        map[string]int  // native context length by model digest, for the optional list column
+	columnLayout          []listColumn    // widths of the list's columns, worked out when the terminal is resized
+	updateAll             *updateAllRun   // the models ctrl+u is updating, nil when it isn't
+	staleModels           []modelUpdate   // models the last update check found stale that haven't been pulled since
+	updateCheckedAt       time.Time       // when ctrl+u last checked the registry models for updates
+	serverVersion         string          // version of the connected server, unknown when it couldn't be fetched
+	versionWarning        string          // shown in the footer when the server and client library versions differ
+	tagCompletion         tagCompletion   // tags offered in the pull new model prompt
+	collapsedGroups       map[string]bool // families collapsed in the list grouped by family
+	pullConfirm           *pullConfirm    // pull of a model that exists locally, waiting for the user to confirm re-pulling it
+	modelsLoading         bool            // a fetch of the model list is in flight
+	modelsLoaded          bool            // the first fetch of the model list has finished
+	modelsErr             error           // why the last fetch of the model list failed
+	modelsGeneration      int             // counts the model list fetches so only the latest one's result is used
+	modelsSpinner         spinner.Model   // shown in place of the list until the first fetch finishes
+	pendingSession        *sessionState   // the last session's selection and filter, restored once the models first load
+	runOverride           *runOverride    // one-off options being typed to run a model with
 }
 
 // TODO: Refactor: we don't need unique message types for every single action
+// progressMsg redraws the progress of the push feeding feed
 type progressMsg struct {
-	modelName string
-	progress  float64
+	feed progressFeed
 }
 
 type runFinishedMessage struct{ err error }
 
 type pushSuccessMsg struct {
 	modelName string
+	index     int // position of the model in a batch push counting from 1, 0 when it's pushed on its own
 }
 
 type pushErrorMsg struct {
-	err error
+	err   error
+	index int
 }
 
 type pullSuccessMsg struct {
@@ -104,38 +156,109 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = logging.Init(cfg.LogLevel, cfg.LogFilePath)
-	if err != nil {
-		fmt.Println("Error initializing logging:", err)
-		os.Exit(1)
-	}
-
 	listFlag := flag.Bool("l", false, "List all available Ollama models and exit")
-	linkFlag := flag.Bool("L", false, "Link Ollama models to LM Studio")
+	plainFlag := flag.Bool("plain", false, "Print and draw everything without colours or other escape sequences, also set by the NO_COLOR environment variable")
+	linkFlag := flag.Bool("L", false, "Link Ollama models to LM Studio, all of them or just those given as arguments")
 	linkLMStudioFlag := flag.Bool("link-lmstudio", false, "Link LM Studio models to Ollama")
-	dryRunFlag := flag.Bool("dry-run", false, "Show what would be linked without making any changes (use with -L or -link-lmstudio)")
-	ollamaDirFlag := flag.String("ollama-dir", cfg.OllamaAPIKey, "Custom Ollama models directory")
+	dryRunFlag := flag.Bool("dry-run", false, "Show what would be linked or updated without making any changes (use with -L, -link-lmstudio or -update-all)")
+	ollamaDirFlag := flag.String("ollama-dir", "", "Custom Ollama models directory, or auto to detect it again (detected from OLLAMA_MODELS, the running ollama process, the systemd unit or docker_container by default)")
 	lmStudioDirFlag := flag.String("lm-dir", cfg.LMStudioFilePaths, "Custom LM Studio models directory")
+	dedupeByDigestFlag := flag.Bool("dedupe-by-digest", false, "With -L, link one model per unique blob and record the other names in a mapping file")
 	noCleanupFlag := flag.Bool("no-cleanup", false, "Don't cleanup broken symlinks")
 	cleanupFlag := flag.Bool("cleanup", false, "Remove all symlinked models and empty directories and exit")
-	searchFlag := flag.String("s", "", "Search - return a list of models that contain the search term in their name")
-	unloadModelsFlag := flag.Bool("u", false, "Unload all models and exit")
+	cleanupDryRunFlag := flag.Bool("cleanup-dry-run", false, "List the broken symlinks in the LM Studio models directory with their targets and ages, and remove them once confirmed")
+	exportModelfileFlag := flag.String("export-modelfile", "", "Write a model's modelfile to a file, ./<model>.modelfile unless a path is given as an argument, and exit")
+	makeModelfileFlag := flag.String("make-modelfile", "", "Write a modelfile that builds the model on another machine with ollama create, to stdout unless a path is given as an argument, and exit")
+	compareHostsFlag := flag.String("compare-hosts", "", "List the models only on this host, only on another host (a URL or a name under hosts in the config) and on both, then exit")
+	syncMissingFlag := flag.Bool("sync-missing", false, "With -compare-hosts, print the ollama pull commands that give each host the models only the other has")
+	executeFlag := flag.Bool("execute", false, "With -compare-hosts -sync-missing, copy the models only on this host to the other one instead of printing their pull commands")
+	exportAllFlag := flag.String("export-all", "", "Write the modelfile of every model to a directory and exit")
+	importModelfileFlag := flag.String("import-modelfile", "", "Create or update a model from an exported modelfile given as an argument and exit (usage: gollama -import-modelfile <model> <path>)")
+	pruneFlag := flag.Bool("prune", false, "List blobs in the models directory that no manifest references with their sizes and exit")
+	pruneDeleteFlag := flag.Bool("prune-delete", false, "Like -prune, then remove the orphaned blobs after confirmation")
+	searchFlag := flag.String("s", "", "Search - return a list of models that contain the search term in their name, & and | combine terms and size>20GB, modified<2024-01-01 or re:<regexp> filter")
+	unloadModelsFlag := flag.Bool("u", false, "Unload all models, or only the model named as an argument (e.g. gollama -u qwen2:72b), and exit")
 	versionFlag := flag.Bool("v", false, "Print the version and exit")
-	hostFlag := flag.String("h", "", "Override the config file to set the Ollama API host (e.g. http://localhost:11434)")
+	hostFlag := flag.String("h", "", "Override the config file and OLLAMA_HOST to set the Ollama API host (e.g. http://localhost:11434)")
 	localHostFlag := flag.Bool("H", false, "Shortcut to connect to http://localhost:11434")
-	editFlag := flag.Bool("e", false, "Edit a model's modelfile")
+	editFlag := flag.Bool("e", false, "Edit a model's modelfile, a glob pattern edits the first match and offers to apply the change to the rest")
+	setParamFlag := flag.String("set-param", "", "Set parameters of a model given as key=value arguments without opening an editor and exit (usage: gollama -set-param <model> num_ctx=32768 [key=value...])")
+	systemFlag := flag.String("system", "", "With -set-param, set the model's system prompt")
+	templateFileFlag := flag.String("template-file", "", "With -set-param, set the model's template to the contents of a file")
+	pinsFlag := flag.Bool("pins", false, "List pinned models and exit")
+	pinFlag := flag.String("pin", "", "Pin a model to protect it from deletion and exit")
+	unpinFlag := flag.String("unpin", "", "Unpin a model and exit")
+	copyFlag := flag.Bool("copy", false, "Copy a model and exit (usage: gollama -copy <source> <destination>)")
+	renameFlag := flag.Bool("rename", false, "Rename a model and exit (usage: gollama -rename <source> <destination>)")
+	modelsDirStatusFlag := flag.Bool("models-dir-status", false, "Print the resolved Ollama models directory, whether it's a symlink, its target and whether it's available, then exit")
+	historyFlag := flag.String("history", "", "Show the recorded history (pulls with their digests, copies, deletes etc.) of a model and exit")
+	wideFlag := flag.Bool("wide", false, "With -l, add each model's context length, embedding length, vocabulary size and capabilities")
+	showLicenceFlag := flag.Bool("show-licence", false, "With -l, add the licence of each model to the -wide table, e.g. Apache-2.0, MIT or Llama-3")
+	olderThanFlag := flag.String("older-than", "", "With -l, only list models not used or modified within an age such as 90d, 2w or 3mo")
+	verifyFlag := flag.String("verify", "", "Check a model's layers are present and intact (use --all for every model) and exit non-zero if any aren't")
+	deepFlag := flag.Bool("deep", false, "With -verify, also hash each layer and compare it to its digest")
+	checkFlag := flag.Bool("check", false, "Check the blobs of every local model, print a table of any missing or corrupt layers and exit non-zero if there are")
+	checkHashesFlag := flag.Bool("check-hashes", false, "With -check, also hash each blob and compare it to its digest (slow)")
+	jsonFlag := flag.Bool("json", false, "With -verify, -l or -s, print the results as JSON")
+	outputFlag := flag.String("o", "", "With -l or -s, print the models in a machine readable format: json or tsv")
+	rewriteFromFlag := flag.String("rewrite-from", "", "Re-create a model whose modelfile FROM is a missing absolute blob path from the matching local blob and exit")
+	scanFlag := flag.Bool("scan", false, "Find every model with a dangling FROM path and re-create them from local blobs after confirmation, then exit")
+	pullFlag := flag.Bool("pull", false, "Pull the models given as arguments and exit non-zero if any fail (usage: gollama -pull <model> [model...])")
+	parallelFlag := flag.Int("parallel", 1, "With -pull, how many models to pull at once")
+	updateAllFlag := flag.Bool("update-all", false, "Pull the registry models that have a newer version in the registry one at a time, skipping customised ones, and exit")
+	keepConfigFlag := flag.Bool("keep-config", false, "With -update-all, also update customised models and restore their template, system prompt and parameters afterwards")
+	doctorFlag := flag.Bool("doctor", false, "Check the config, models directory and connection to Ollama, benchmark the API latency and exit")
+	overwriteFlag := flag.Bool("overwrite", false, "Allow -copy and -rename to replace an existing destination model")
 	// vRAM estimation flags
 	// flag.Float64Var(&fitsVRAM, "fits", 0, "Highlight quant sizes and context sizes that fit in this amount of vRAM (in GB)")
 	vramFlag := flag.String("vram", "", "Model to estimate VRAM usage for (e.g., 'qwen2:q4_0' or 'meta-llama/Llama-2-7b')")
-	fitsVRAMFlag := flag.Float64("fits", 0, "Target VRAM constraint in GB (default: auto-detect)")
+	fitsVRAMFlag := flag.Float64("fits", 0, "Target VRAM constraint in GB, the combined VRAM when using -gpus (default: auto-detect)")
+	gpusFlag := flag.Int("gpus", 1, "Number of GPUs to split the model across for vRAM estimation")
+	tensorSplitFlag := flag.String("tensor-split", "", "Share of the model on each GPU for vRAM estimation, like llama.cpp's --tensor-split (e.g. '24,24' or '3,1')")
 	contextFlag := flag.String("context", "", "Maximum context length (e.g., '32k' or '128k')")
 	quantFlag := flag.String("quant", "", "Specific quantisation level (e.g., 'Q4_0', 'Q5_K_M')")
-	vramToNthFlag := flag.String("vram-to-nth", "65536", "Top context length to search for (e.g., 65536, 32k, 2m)")
+	kvQuantFlag := flag.String("kv-quant", "", "Only estimate vRAM with this k/v cache quantisation (fp16, q8_0 or q4_0), showing one value per context size")
+	logFileFlag := flag.String("log-file", "", "Write the log to this file for this run instead of log_file_path from the config")
+	logLevelFlag := flag.String("log-level", "", "Log level for this run instead of log_level from the config: trace, debug, info, warn or error")
+	vramToNthFlag := flag.String("vram-to-nth", "", "Top context length to search for (e.g., 65536, 32k, 2m), defaults to the model's maximum context up to 256k")
 
 	flag.Parse()
 
-	if *versionFlag {
-		fmt.Println(Version)
+	logFilePath, logLevel := cfg.LogFilePath, cfg.LogLevel
+	if *logFileFlag != "" {
+		logFilePath = *logFileFlag
+	}
+	if *logLevelFlag != "" {
+		logLevel = *logLevelFlag
+	}
+	rotation := logging.Rotation{MaxSizeMB: cfg.LogMaxSizeMB, MaxBackups: cfg.LogMaxBackups, MaxAgeDays: cfg.LogMaxAgeDays}
+	logErr := logging.InitWithRotation(logLevel, logFilePath, rotation)
+	if errors.Is(logErr, logging.ErrStderrFallback) {
+		// Without a log file gollama still runs, logging to stderr
+		fmt.Fprintln(os.Stderr, "Warning:", logErr)
+	} else if logErr != nil {
+		fmt.Println("Error initializing logging:", logErr)
+		os.Exit(1)
+	}
+
+	if err := styles.InitTheme(cfg.Theme); err != nil {
+		logging.ErrorLogger.Printf("Error loading theme, using the default theme: %v\n", err)
+	}
+
+	if *plainFlag || styles.NoColourRequested() {
+		styles.SetPlain(true)
+	}
+
+	if *historyFlag != "" {
+		printModelHistory(*historyFlag)
+		os.Exit(0)
+	}
+
+	modelsDir, modelsDirSource := resolveModelsDir(context.Background(), *ollamaDirFlag, &cfg)
+	logging.DebugLogger.Printf("Using Ollama models directory %s from %s\n", modelsDir, modelsDirSource)
+
+	if *modelsDirStatusFlag {
+		printModelsDirStatus(modelsDir, modelsDirSource)
 		os.Exit(0)
 	}
 
@@ -143,13 +266,12 @@ func main() {
 		*hostFlag = "http://localhost:11434"
 	}
 
-	if *hostFlag != "" {
-		cfg.OllamaAPIURL = *hostFlag
-	}
+	apiURL, source := config.ResolveAPIURL(*hostFlag, cfg.OllamaAPIURL)
+	logging.DebugLogger.Printf("Using Ollama API URL %s from %s\n", apiURL, source)
+	cfg.OllamaAPIURL = apiURL
 
 	// Initialise the API client
 	ctx := context.Background()
-	httpClient := &http.Client{}
 	url, err := url.Parse(cfg.OllamaAPIURL)
 
 	if err != nil {
@@ -159,13 +281,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Every client talking to the API authenticates and trusts the configured CA the same way
+	httpClient, err := config.NewHTTPClient(&cfg, 0)
+	if err != nil {
+		logging.ErrorLogger.Println(err)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	apiHTTPClient.Transport = httpClient.Transport
+
+	// -v asks the server for its version too, so it's handled once the client can be made
+	if *versionFlag {
+		printVersions(ctx, api.NewClient(url, httpClient), cfg.OllamaAPIURL)
+		os.Exit(0)
+	}
+
+	if *doctorFlag {
+		doctorCtx, stopSignals := signalContext(ctx, "the doctor checks", nil)
+		code := runDoctor(doctorCtx, cfg, api.NewClient(url, httpClient), modelsDir)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	// gollama serve runs the HTTP API instead of the TUI
+	if flag.Arg(0) == "serve" {
+		os.Exit(runServe(ctx, api.NewClient(url, httpClient), cfg, flag.Args()[1:]))
+	}
+
+	// gollama report writes the models as a markdown table or CSV
+	if flag.Arg(0) == "report" {
+		os.Exit(runReport(ctx, api.NewClient(url, httpClient), apiURL, flag.Args()[1:]))
+	}
+
 	// Handle --vram flag
 	if *vramFlag != "" {
 		modelName := *vramFlag
 		logging.DebugLogger.Printf("Processing vRAM estimation for model: %s", modelName)
 
-		// Parse the model identifier and quantisation level
-		baseModel, quantLevel, err := vramestimator.ParseModelIdentifier(modelName)
+		// Parse the model identifier and quantisation level, a GGUF file is estimated from its own metadata
+		isGGUFFile := vramestimator.IsGGUFPath(modelName)
+		baseModel, quantLevel := modelName, ""
+		if !isGGUFFile {
+			baseModel, quantLevel, err = vramestimator.ParseModelIdentifier(modelName)
+		}
 		if err != nil {
 			fmt.Printf("Error parsing model identifier: %v\n", err)
 			os.Exit(1)
@@ -179,21 +337,18 @@ func main() {
 			quantLevel = *quantFlag
 		}
 
-		var isHuggingFaceModel = strings.Contains(baseModel, "/")
-		var isOllamaModel = !isHuggingFaceModel
+		var isHuggingFaceModel = !isGGUFFile && strings.Contains(baseModel, "/")
+		var isOllamaModel = !isGGUFFile && !isHuggingFaceModel
 
-		// Parse the context size
+		// Parse the context size, without a flag the model's own maximum is used once its details are fetched
 		var topContext int
 		var contextSource string
-		if *contextFlag != "" && *contextFlag != "65536" {
+		if *contextFlag != "" {
 			topContext, err = parseContextSize(*contextFlag)
 			contextSource = "context"
 		} else if *vramToNthFlag != "" {
 			topContext, err = parseContextSize(*vramToNthFlag)
 			contextSource = "vram-to-nth"
-		} else {
-			topContext = 65536
-			contextSource = "default"
 		}
 
 		if err != nil {
@@ -201,8 +356,6 @@ func main() {
 			os.Exit(1)
 		}
 
-		logging.DebugLogger.Printf("Using context size %d from --%s", topContext, contextSource)
-
 		// If a specific quantisation level is provided, verify it exists
 		if quantLevel != "" {
 			if _, exists := vramestimator.GGUFMapping[strings.ToUpper(quantLevel)]; !exists {
@@ -219,75 +372,109 @@ func main() {
 			}
 		}
 
+		var kvCacheQuant vramestimator.KVCacheQuantisation
+		if *kvQuantFlag != "" {
+			kvCacheQuant, err = vramestimator.ParseKVCacheQuantisation(*kvQuantFlag)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		gpus := vramestimator.GPUSetup{Count: *gpusFlag}
+		if *tensorSplitFlag != "" {
+			gpus.Split, err = vramestimator.ParseTensorSplit(*tensorSplitFlag)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if *gpusFlag > 1 && *gpusFlag != len(gpus.Split) {
+				fmt.Printf("Error: -tensor-split has %d values but -gpus is %d\n", len(gpus.Split), *gpusFlag)
+				os.Exit(1)
+			}
+		}
+
 		// Fetch model information from appropriate source
 		var ollamaModelInfo *vramestimator.OllamaModelInfo
-		if isOllamaModel {
+		if isGGUFFile {
+			logging.DebugLogger.Printf("Reading model info from the GGUF metadata of %s", baseModel)
+			ollamaModelInfo, err = vramestimator.ReadGGUFModelInfo(baseModel)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else if isOllamaModel {
 			logging.DebugLogger.Printf("Fetching model info from Ollama API for %s", baseModel)
-			ollamaModelInfo, err = vramestimator.FetchOllamaModelInfo(cfg.OllamaAPIURL, modelName)
+			ollamaModelInfo, err = vramestimator.FetchOllamaModelInfo(httpClient, cfg.OllamaAPIURL, modelName)
 			if err != nil {
 				fmt.Printf("Error: Could not fetch Ollama model info: %v\n", err)
 				os.Exit(1)
 			}
+			// A vision model's projector is loaded alongside it, the files are only readable when the API is local
+			if files, err := getModelFiles(modelName, modelsDir, api.NewClient(url, httpClient)); err == nil {
+				ollamaModelInfo.ProjectorSize = files.ProjectorSize()
+			} else {
+				logging.DebugLogger.Printf("Not including a projector in the estimate: %v\n", err)
+			}
 		} else {
 			logging.DebugLogger.Printf("Using HuggingFace model ID: %s", baseModel)
 		}
 
+		// A running model's k/v cache, when the server reports it, is the default for the single k/v cache table
+		var kvCacheNote string
+		if isOllamaModel {
+			var detected vramestimator.KVCacheQuantisation
+			detected, kvCacheNote = runningKVCache(ctx, cfg.OllamaAPIURL, modelName)
+			if kvCacheQuant == "" {
+				kvCacheQuant = detected
+			}
+		}
+
+		if contextSource == "" {
+			topContext, contextSource = vramestimator.ModelContextLimit(baseModel, ollamaModelInfo)
+		} else {
+			contextSource = "--" + contextSource
+		}
+		logging.DebugLogger.Printf("Using context size %d from %s", topContext, contextSource)
+
 		// Generate and display the table
-		table, err := vramestimator.GenerateQuantTable(baseModel, *fitsVRAMFlag, ollamaModelInfo, topContext)
+		table, err := vramestimator.GenerateQuantTableForKVCache(baseModel, *fitsVRAMFlag, ollamaModelInfo, topContext, gpus, kvCacheQuant)
 		if err != nil {
 			fmt.Printf("Error generating VRAM estimation table: %v\n", err)
 			os.Exit(1)
 		}
+		table.ContextSource = contextSource
 
-		fmt.Println(vramestimator.PrintFormattedTable(table))
+		fmt.Println(formatVRAMTable(table))
+		if kvCacheNote != "" {
+			fmt.Println(kvCacheNote)
+		}
+
+		// With a known quantisation and k/v cache, show the largest context that fits (the search assumes a single GPU)
+		if kvCacheQuant != "" && quantLevel != "" && gpus.NumGPUs() == 1 {
+			bpw := vramestimator.GGUFMapping[strings.ToUpper(quantLevel)]
+			maxContext, err := vramestimator.CalculateContext(baseModel, table.FitsVRAM, bpw, kvCacheQuant, ollamaModelInfo, topContext)
+			if err != nil {
+				fmt.Printf("Error calculating the largest context: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Largest context for %s with a %s k/v cache in %.1f GB: %d\n", strings.ToUpper(quantLevel), kvCacheQuant, table.FitsVRAM, maxContext)
+		}
 		os.Exit(0)
 	}
 
 	client := api.NewClient(url, httpClient)
 
-	resp, err := client.List(ctx)
-	if err != nil {
-		message := fmt.Sprintf("Error fetching models:\n- Error: %v\n- Configured API URL: %v", err, cfg.OllamaAPIURL)
-		logging.ErrorLogger.Println(message)
-		fmt.Println(message)
-		os.Exit(1)
-	}
-
-	models := parseAPIResponse(resp)
-
-	modelMap := make(map[string][]Model)
-	for _, model := range models {
-		model.Size = normalizeSize(model.Size)
-		modelMap[model.ID] = append(modelMap[model.ID], model)
-	}
-
-	groupedModels := make([]Model, 0)
-	for _, group := range modelMap {
-		groupedModels = append(groupedModels, group...)
-	}
-
-	switch cfg.SortOrder {
-	case "name":
-		sort.Slice(groupedModels, func(i, j int) bool {
-			return groupedModels[i].Name < groupedModels[j].Name
-		})
-	case "size":
-		sort.Slice(groupedModels, func(i, j int) bool {
-			return groupedModels[i].Size > groupedModels[j].Size
-		})
-	case "modified":
-		sort.Slice(groupedModels, func(i, j int) bool {
-			return groupedModels[i].Modified.After(groupedModels[j].Modified)
-		})
-	case "family":
-		sort.Slice(groupedModels, func(i, j int) bool {
-			return groupedModels[i].Family < groupedModels[j].Family
-		})
-	}
-
-	items := make([]list.Item, len(groupedModels))
-	for i, model := range groupedModels {
-		items[i] = model
+	// The CLI flags fetch the models when they need them, the TUI fetches them once it's drawn so it starts straight away
+	fetchModels := func() []Model {
+		models, err := loadModels(ctx, client, cfg.OllamaAPIURL, modelsDir)
+		if err != nil {
+			message := fmt.Sprintf("Error fetching models:\n- Error: %v\n- Configured API URL: %v", err, cfg.OllamaAPIURL)
+			logging.ErrorLogger.Println(message)
+			fmt.Println(message)
+			os.Exit(1)
+		}
+		return models
 	}
 
 	keys := NewKeyMap()
@@ -300,10 +487,9 @@ func main() {
 	app := AppModel{
 		client:            client,
 		keys:              *keys,
-		models:            groupedModels,
 		width:             width,
 		height:            height,
-		ollamaModelsDir:   *ollamaDirFlag,
+		ollamaModelsDir:   modelsDir,
 		lmStudioModelsDir: *lmStudioDirFlag,
 		noCleanup:         *noCleanupFlag,
 		cfg:               &cfg,
@@ -311,32 +497,187 @@ func main() {
 		pullInput:         textinput.New(),
 		pulling:           false,
 		pullProgress:      0,
+		contextLengths:    make(map[string]int),
+	}
+	if showsContextLength(&cfg) {
+		app.contextLengths = loadContextLengths()
 	}
 
-	if *ollamaDirFlag == "" {
-		app.ollamaModelsDir = filepath.Join(utils.GetHomeDir(), ".ollama", "models")
+	if cfg.DefaultView == "dashboard" {
+		app.view = DashboardView
 	}
+
 	if *lmStudioDirFlag == "" {
 		app.lmStudioModelsDir = filepath.Join(utils.GetHomeDir(), ".lmstudio", "models")
 	}
 
+	app.modelsDir = checkModelsDir(app.ollamaModelsDir)
+
+	format, err := outputFormat(*outputFlag, *jsonFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	if *listFlag {
+		models := fetchModels()
+		if *olderThanFlag != "" {
+			if err := listModelsByAge(models, *olderThanFlag, format); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if format != "" {
+			if err := writeModels(os.Stdout, models, format); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing models: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if warning := versionWarning(fetchServerVersion(ctx, client), clientLibraryVersion()); warning != "" {
+			fmt.Println(lipgloss.NewStyle().Foreground(styles.Current().Colours.Warning.TerminalColour()).Bold(true).Render("⚠ " + warning))
+		}
+		if *wideFlag || *showLicenceFlag {
+			listModelsWide(ctx, client, apiURL, models, cfg.StripString, *showLicenceFlag)
+			os.Exit(0)
+		}
 		listModels(models)
 		os.Exit(0)
 	}
 
+	if *verifyFlag != "" {
+		names := []string{*verifyFlag}
+		if *verifyFlag == "--all" || *verifyFlag == "-all" {
+			names = names[:0]
+			for _, model := range fetchModels() {
+				names = append(names, model.Name)
+			}
+		}
+		verifyCtx, stopSignals := signalContext(ctx, "verifying models", reloadConfig(cfg))
+		code := runVerify(verifyCtx, client, app.ollamaModelsDir, isLocalhost(cfg.OllamaAPIURL), names, *deepFlag, *jsonFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *checkFlag {
+		models := fetchModels()
+		names := make([]string, 0, len(models))
+		for _, model := range models {
+			names = append(names, model.Name)
+		}
+		checkCtx, stopSignals := signalContext(ctx, "checking models", reloadConfig(cfg))
+		code := runCheck(checkCtx, app.ollamaModelsDir, isLocalhost(cfg.OllamaAPIURL), names, *checkHashesFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *pullFlag {
+		pullCtx, stopSignals := signalContext(ctx, "pulling models", reloadConfig(cfg))
+		code := runBatchPull(pullCtx, client, flag.Args(), *parallelFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *updateAllFlag {
+		models := fetchModels()
+		updateCtx, stopSignals := signalContext(ctx, "updating models", reloadConfig(cfg))
+		code := runUpdateAll(updateCtx, client, &cfg, models, modelsDir, *dryRunFlag, *keepConfigFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *rewriteFromFlag != "" || *scanFlag {
+		if !isLocalhost(cfg.OllamaAPIURL) {
+			fmt.Println("Error: Rewriting FROM paths is only supported on localhost")
+			os.Exit(1)
+		}
+		names := []string{*rewriteFromFlag}
+		if *scanFlag {
+			names = names[:0]
+			for _, model := range fetchModels() {
+				names = append(names, model.Name)
+			}
+		}
+		rewriteCtx, stopSignals := signalContext(ctx, "rewriting FROM paths", reloadConfig(cfg))
+		code := runRewriteFrom(rewriteCtx, client, app.ollamaModelsDir, names, *scanFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *exportModelfileFlag != "" {
+		os.Exit(runExportModelfile(ctx, client, *exportModelfileFlag, flag.Arg(0)))
+	}
+
+	if *makeModelfileFlag != "" {
+		os.Exit(runMakeModelfile(ctx, client, *makeModelfileFlag, flag.Arg(0)))
+	}
+
+	if *compareHostsFlag != "" {
+		compareCtx, stopSignals := signalContext(ctx, "comparing hosts", nil)
+		code := runCompareHosts(compareCtx, client, httpClient, cfg, modelsDir, *compareHostsFlag, *syncMissingFlag, *executeFlag)
+		stopSignals()
+		os.Exit(code)
+	}
+
+	if *exportAllFlag != "" {
+		models := fetchModels()
+		names := make([]string, len(models))
+		for i, model := range models {
+			names[i] = model.Name
+		}
+		os.Exit(runExportAll(ctx, client, names, *exportAllFlag))
+	}
+
+	if *importModelfileFlag != "" {
+		os.Exit(runImportModelfile(ctx, client, *importModelfileFlag, flag.Arg(0)))
+	}
+
+	if *setParamFlag != "" {
+		os.Exit(runSetParam(ctx, client, *setParamFlag, flag.Args(), *systemFlag, *templateFileFlag))
+	}
+
+	if *pruneFlag || *pruneDeleteFlag {
+		// The blobs of a remote host aren't on this machine, so every local blob would look orphaned
+		if !isLocalAPIURL(cfg.OllamaAPIURL) {
+			fmt.Println("Error: Pruning blobs is only supported on localhost")
+			os.Exit(1)
+		}
+		os.Exit(runPrune(app.ollamaModelsDir, *pruneDeleteFlag))
+	}
+
+	if *cleanupDryRunFlag {
+		os.Exit(runCleanupDryRun(app.lmStudioModelsDir, app.ollamaModelsDir, os.Stdin, os.Stdout))
+	}
+
 	if *cleanupFlag {
 		cleanupSymlinkedModels(app.lmStudioModelsDir)
 		os.Exit(0)
 	}
 
 	if *searchFlag != "" {
+		models := fetchModels()
 		searchTerms := flag.Args()
 		// If no additional arguments are provided, use the searchFlag value
 		if len(searchTerms) == 0 {
 			searchTerms = []string{*searchFlag}
 		}
-		searchModels(models, searchTerms...)
+		if format != "" {
+			results, err := matchingModels(models, searchTerms...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeModels(os.Stdout, results, format); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing models: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if err := searchModels(models, searchTerms...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -358,9 +699,40 @@ func main() {
 			fmt.Printf("%sWould link Ollama models to LM Studio\n", prefix)
 		}
 
+		models := fetchModels()
+		// Only link the models named as arguments, e.g. gollama -L llava:7b
+		if len(flag.Args()) > 0 {
+			named, err := modelsNamed(models, flag.Args())
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			models = named
+		}
+
+		if *dedupeByDigestFlag {
+			names := make([]string, len(models))
+			for i, model := range models {
+				names[i] = model.Name
+			}
+			messages, avoided, err := linkModelsDeduped(names, app.ollamaModelsDir, cfg.LMStudioFilePaths, cleanupMode(*noCleanupFlag, cfg.CleanupReportOnly), *dryRunFlag, client)
+			for _, message := range messages {
+				logging.InfoLogger.Println(message)
+				fmt.Printf("%s%s\n", prefix, message)
+			}
+			if err != nil {
+				logging.ErrorLogger.Printf("Error linking models: %v\n", err)
+				fmt.Printf("Error: Linking models failed. Please check if you are running without Administrator on Windows.\n")
+				fmt.Printf("Error detail: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%sAvoided %d duplicate links to models that share a blob (see %s)\n", prefix, avoided, filepath.Join(cfg.LMStudioFilePaths, linkMapFile))
+			os.Exit(0)
+		}
+
 		// link all models
 		for _, model := range models {
-			message, err := linkModel(model.Name, cfg.LMStudioFilePaths, false, *dryRunFlag, client)
+			message, err := linkModel(model.Name, app.ollamaModelsDir, cfg.LMStudioFilePaths, cleanupMode(*noCleanupFlag, cfg.CleanupReportOnly), *dryRunFlag, client)
 			if message != "" {
 				logging.InfoLogger.Println(message)
 				fmt.Printf("%s%s\n", prefix, message)
@@ -401,9 +773,27 @@ func main() {
 		fmt.Printf("%sFound %d LM Studio models\n", prefix, len(models))
 		var successCount, failCount int
 
+		// Hashing large models takes a while, so report progress on lines under the model being processed
+		var hashed bool
+		hashProgress := lmstudio.PercentProgress(func(path string, percent int) {
+			hashed = true
+			fmt.Printf("\n  hashing %s: %d%%", filepath.Base(path), percent)
+		})
+		linkCtx, stopSignals := signalContext(ctx, "linking LM Studio models", reloadConfig(cfg))
+		var cancelled bool
 		for _, model := range models {
+			hashed = false
 			fmt.Printf("%sProcessing model %s... ", prefix, model.Name)
-			if err := lmstudio.LinkModelToOllama(model, *dryRunFlag, cfg.OllamaAPIURL); err != nil {
+			err := lmstudio.LinkModelToOllama(linkCtx, httpClient, model, *dryRunFlag, cfg.OllamaAPIURL, hashProgress)
+			if hashed {
+				fmt.Println()
+			}
+			if linkCtx.Err() != nil {
+				fmt.Println("cancelled")
+				cancelled = true
+				break
+			}
+			if err != nil {
 				logging.ErrorLogger.Printf("Error linking model %s: %v\n", model.Name, err)
 				fmt.Printf("failed: %v\n", err)
 				failCount++
@@ -419,6 +809,11 @@ func main() {
 		} else {
 			fmt.Printf("\nSummary: %d models linked successfully, %d failed\n", successCount, failCount)
 		}
+		stopSignals()
+		if cancelled {
+			fmt.Println("Linking was cancelled, run it again to continue. Files that were already hashed won't be hashed again.")
+			os.Exit(130)
+		}
 		if failCount > 0 {
 			os.Exit(1)
 		}
@@ -426,49 +821,67 @@ func main() {
 	}
 
 	if *unloadModelsFlag {
-		// get any loaded models
-		client := app.client
+		os.Exit(runUnload(ctx, app.client, cfg.OllamaAPIURL, flag.Arg(0), os.Stdout))
+	}
 
-		ctx := context.Background()
-		loadedModels, err := client.ListRunning(ctx)
-		if err != nil {
-			logging.ErrorLogger.Printf("Error fetching running models: %v", err)
+	if *pinsFlag {
+		models := fetchModels()
+		listPinnedModels(models)
+		os.Exit(0)
+	}
+
+	if *pinFlag != "" || *unpinFlag != "" {
+		if err := setModelPin(fetchModels(), *pinFlag, *unpinFlag); err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		os.Exit(0)
+	}
 
-		// unload the models
-		var unloadedModels []string
-		for _, model := range loadedModels.Models {
-			_, err := unloadModel(client, model.Name)
-			if err != nil {
-				logging.ErrorLogger.Printf("Error unloading model %s: %v\n", model.Name, err)
-			} else {
-				unloadedModels = append(unloadedModels, lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB6C1")).Render(model.Name))
-				logging.InfoLogger.Printf("Model %s unloaded\n", model.Name)
-			}
+	if *copyFlag || *renameFlag {
+		if flag.NArg() != 2 {
+			fmt.Println("Usage: gollama -copy|-rename [-overwrite] <source> <destination>")
+			os.Exit(1)
+		}
+		source, destination := flag.Arg(0), flag.Arg(1)
+		if *renameFlag {
+			err = renameModelTo(client, source, destination, *overwriteFlag)
+		} else {
+			err = copyModelTo(client, source, destination, *overwriteFlag)
+		}
+		if errors.Is(err, errModelExists) {
+			fmt.Printf("Error: %v (use -overwrite to replace it)\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
-		if len(unloadedModels) == 0 {
-			fmt.Println("No models to unload")
+		if *renameFlag {
+			fmt.Printf("Renamed %s to %s\n", source, destination)
 		} else {
-			logging.InfoLogger.Printf("Unloaded models: %v\n", unloadedModels)
-			fmt.Printf("Unloaded models: %v\n", unloadedModels)
+			fmt.Printf("Copied %s to %s\n", source, destination)
 		}
 		os.Exit(0)
 	}
 
 	if *editFlag {
+		models := fetchModels()
 		if flag.NArg() == 0 {
-			fmt.Println("Usage: gollama -e <model_name>")
+			fmt.Println("Usage: gollama -e <model_name|pattern>")
 			os.Exit(1)
 		}
-		modelName := flag.Args()[0]
-		editModelfile(client, modelName)
-		os.Exit(0)
+		names := make([]string, len(models))
+		for i, model := range models {
+			names[i] = model.Name
+		}
+		os.Exit(runBatchEdit(ctx, client, flag.Args()[0], names))
 	}
 
-	// TUI App
-	l := list.New(items, NewItemDelegate(&app), width, height-5)
-	l.Title = "Ollama Models"
+	// TUI App, the models are fetched once it has started
+	l := list.New(nil, NewItemDelegate(&app), width, height-5)
+	l.Title = sortTitle(cfg.SortOrder, sortDescending(&cfg))
+	l.Filter = app.modelFilter()
 	l.Help.Styles.ShortDesc.Bold(true)
 	l.Help.Styles.ShortDesc.UnsetFaint()
 	l.Help.Styles.ShortDesc.Foreground(lipgloss.Color("#FF00FF"))
@@ -501,8 +914,19 @@ func main() {
 	}
 
 	app.list = l
+	session := loadSessionState()
+	app.pendingSession = &session
+
+	if logErr != nil {
+		// Logging to stderr would draw over the TUI, so the logs are dropped and the error is shown in the footer
+		logging.SetOutput(io.Discard)
+		app.message = "Warning: " + logErr.Error()
+	}
 
 	p := tea.NewProgram(&app, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	config.Watch(func(previous, current config.Config, err error) {
+		p.Send(configChangedMsg{previous: previous, current: current, err: err})
+	})
 	if _, err := p.Run(); err != nil {
 		logging.ErrorLogger.Printf("Error: %v", err)
 	} else {
@@ -0,0 +1,226 @@
+// make_modelfile.go implements -make-modelfile, which writes a modelfile that ollama create can build a model from on
+// another machine. The show API's modelfile loads blobs by their path on this machine, so FROM is rewritten to the
+// upstream model when the registry has the same weights under its name, or to a file copied next to the modelfile.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+)
+
+// hfRegistryURL is the registry of the models Ollama pulls from HuggingFace with hf.co/ names
+var hfRegistryURL = "https://hf.co"
+
+// portableModelfile is a modelfile that builds a model on another machine
+type portableModelfile struct {
+	Modelfile string
+	Upstream  string      // the model FROM names, empty when the weights are loaded from a copied file
+	Copies    [][2]string // blobs to copy next to the modelfile, as the blob's path and the file the modelfile loads
+	Note      string      // why the upstream model couldn't be found, empty when it was
+}
+
+// makePortableModelfile rewrites a model's modelfile so it builds elsewhere. The upstream model is looked for under
+// the name the model was created from and then its own name, and only used when its weights have the same digest as
+// the blob the model loads. Otherwise the blobs are loaded from files next to the modelfile, with the commands to copy
+// them there in its header. The template, system prompt, parameters and other instructions are kept as they are.
+func makePortableModelfile(ctx context.Context, client *api.Client, registryClient *http.Client, modelName string) (portableModelfile, error) {
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return portableModelfile{}, fmt.Errorf("error fetching the modelfile of %s: %w", modelName, err)
+	}
+	parsed, err := parser.ParseFile(strings.NewReader(show.Modelfile))
+	if err != nil {
+		return portableModelfile{}, fmt.Errorf("error parsing the modelfile of %s: %w", modelName, err)
+	}
+
+	var result portableModelfile
+	var digest string
+	for _, cmd := range parsed.Commands {
+		if match := blobNamePattern.FindStringSubmatch(filepath.Base(cmd.Args)); cmd.Name == "model" && match != nil {
+			digest = "sha256:" + match[1]
+			break
+		}
+	}
+	if digest != "" {
+		var reasons []string
+		result.Upstream, reasons = findUpstreamModel(ctx, registryClient, []string{show.Details.ParentModel, modelName}, digest)
+		if result.Upstream == "" {
+			result.Note = fmt.Sprintf("Couldn't find the upstream model of %s (%s), so the modelfile loads its weights from a copy next to it instead", modelName, strings.Join(reasons, "; "))
+		}
+	}
+
+	var lines []string
+	models, adapters := 0, 0
+	for _, cmd := range parsed.Commands {
+		isBlob := blobNamePattern.MatchString(filepath.Base(cmd.Args))
+		switch {
+		case cmd.Name == "model" && isBlob && result.Upstream != "":
+			// The upstream model brings its projectors with it
+			if models++; models > 1 {
+				continue
+			}
+			cmd.Args = result.Upstream
+		case cmd.Name == "model" && isBlob:
+			local := localBlobName("model", models)
+			if models > 0 {
+				local = localBlobName("projector", models-1)
+			}
+			models++
+			result.Copies = append(result.Copies, [2]string{cmd.Args, local})
+			cmd.Args = local
+		case cmd.Name == "adapter" && isBlob:
+			local := localBlobName("adapter", adapters)
+			adapters++
+			result.Copies = append(result.Copies, [2]string{cmd.Args, local})
+			cmd.Args = local
+		}
+		line, err := formatModelfileCommand(cmd)
+		if err != nil {
+			return portableModelfile{}, fmt.Errorf("error writing the modelfile of %s: %w", modelName, err)
+		}
+		lines = append(lines, line)
+	}
+
+	header := []string{fmt.Sprintf("# Modelfile for %s made by gollama -make-modelfile", modelName)}
+	if len(result.Copies) > 0 {
+		header = append(header, "# Copy these files from the machine it was made on next to this modelfile first:")
+		for _, file := range result.Copies {
+			header = append(header, fmt.Sprintf("#   cp %s %s", file[0], file[1]))
+		}
+	}
+	header = append(header, fmt.Sprintf("# Then create the model with: ollama create %s -f <this file>", modelName))
+	result.Modelfile = strings.Join(header, "\n") + "\n\n" + strings.Join(lines, "\n") + "\n"
+	return result, nil
+}
+
+// localBlobName is the file a blob is copied to, e.g. ./model.gguf, or ./projector-2.gguf for the second projector
+func localBlobName(kind string, index int) string {
+	if index == 0 {
+		return "./" + kind + ".gguf"
+	}
+	return fmt.Sprintf("./%s-%d.gguf", kind, index+1)
+}
+
+// findUpstreamModel returns the first candidate whose manifest in the public registry or HuggingFace has a model layer
+// with the digest, or the reasons none of them has
+func findUpstreamModel(ctx context.Context, registryClient *http.Client, candidates []string, digest string) (string, []string) {
+	var reasons []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		url, ok := upstreamManifestURL(candidate)
+		if !ok {
+			reasons = append(reasons, candidate+" isn't from the public registry or HuggingFace")
+			continue
+		}
+		var manifest modelManifest
+		err := getRegistryJSON(ctx, registryClient, url, &manifest)
+		if errors.Is(err, errNotInRegistry) {
+			reasons = append(reasons, candidate+" isn't in the registry")
+			continue
+		}
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("couldn't check %s with the registry: %v", candidate, err))
+			continue
+		}
+		for _, layer := range manifest.Layers {
+			if layer.MediaType == modelLayerMediaType && layer.Digest == digest {
+				return candidate, nil
+			}
+		}
+		reasons = append(reasons, candidate+" in the registry has different weights")
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "it wasn't created from a named model")
+	}
+	return "", reasons
+}
+
+// upstreamManifestURL returns the manifest URL of a model in the public registry or on HuggingFace, false for other
+// registries
+func upstreamManifestURL(modelName string) (string, bool) {
+	name, tag := splitModelTag(modelName)
+	if tag == "" {
+		tag = "latest"
+	}
+	for _, prefix := range []string{"hf.co/", "huggingface.co/"} {
+		if repository, ok := strings.CutPrefix(name, prefix); ok {
+			return fmt.Sprintf("%s/v2/%s/manifests/%s", hfRegistryURL, repository, tag), true
+		}
+	}
+	if !isRegistryModel(modelName) {
+		return "", false
+	}
+	return fmt.Sprintf("%s/v2/%s/manifests/%s", registryURL, registryRepository(modelName), tag), true
+}
+
+// formatModelfileCommand writes an instruction as Ollama reads it back, quoting values that span lines, have spaces at
+// either end or start with a quote
+func formatModelfileCommand(cmd parser.Command) (string, error) {
+	switch cmd.Name {
+	case "model":
+		return "FROM " + cmd.Args, nil
+	case "message":
+		role, message, _ := strings.Cut(cmd.Args, ": ")
+		quoted, err := quoteModelfileValue(message)
+		return "MESSAGE " + role + " " + quoted, err
+	case "license", "template", "system", "adapter":
+		quoted, err := quoteModelfileValue(cmd.Args)
+		return strings.ToUpper(cmd.Name) + " " + quoted, err
+	}
+	quoted, err := quoteModelfileValue(cmd.Args)
+	return "PARAMETER " + cmd.Name + " " + quoted, err
+}
+
+func quoteModelfileValue(value string) (string, error) {
+	if value != "" && !strings.Contains(value, "\n") && strings.TrimSpace(value) == value && !strings.HasPrefix(value, `"`) {
+		return value, nil
+	}
+	if !strings.Contains(value, `"`) {
+		return `"` + value + `"`, nil
+	}
+	if strings.Contains(value, `"""`) {
+		return "", fmt.Errorf("%q can't be quoted in a modelfile", truncate(value, 40))
+	}
+	return `"""` + value + `"""`, nil
+}
+
+// runMakeModelfile implements -make-modelfile, writing the modelfile to path or stdout and returning the exit code
+func runMakeModelfile(ctx context.Context, client *api.Client, modelName, path string) int {
+	result, err := makePortableModelfile(ctx, client, &http.Client{Timeout: registryTimeout}, modelName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if result.Note != "" {
+		fmt.Fprintln(os.Stderr, result.Note)
+		for _, file := range result.Copies {
+			fmt.Fprintf(os.Stderr, "  cp %s %s\n", file[0], file[1])
+		}
+	}
+	if path == "" {
+		fmt.Print(result.Modelfile)
+		return 0
+	}
+	if err := os.WriteFile(path, []byte(result.Modelfile), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		return 1
+	}
+	if result.Upstream != "" {
+		fmt.Fprintf(os.Stderr, "Wrote the modelfile of %s to %s, built FROM %s\n", modelName, path, result.Upstream)
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote the modelfile of %s to %s\n", modelName, path)
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+
+	"github.com/sammcj/gollama/pkg/gollama"
+)
+
+func TestMakePortableModelfile(t *testing.T) {
+	blob := "/root/.ollama/models/blobs/sha256-" + strings.Repeat("a", 64)
+	projector := "/root/.ollama/models/blobs/sha256-" + strings.Repeat("b", 64)
+	template := "{{ if .System }}<|im_start|>system\n{{ .System }}<|im_end|>{{ end }}\n<|im_start|>user\n{{ .Prompt }}"
+	system := "You are \"Bob\".\nAnswer in rhyme,\n  and end with \"arr\""
+	original := parser.Modelfile{Commands: []parser.Command{
+		{Name: "model", Args: blob},
+		{Name: "model", Args: projector},
+		{Name: "template", Args: template},
+		{Name: "system", Args: system},
+		{Name: "stop", Args: "<|im_start|>"},
+		{Name: "stop", Args: "<|im_end|>"},
+		{Name: "num_ctx", Args: "32768"},
+	}}
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{
+			Modelfile: "# Modelfile generated by \"ollama show\"\n# FROM pirate:latest\n\n" + original.String(),
+			Details:   api.ModelDetails{ParentModel: "qwen2:7b"},
+		})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	client := api.NewClient(u, ollama.Client())
+
+	upstreamDigest := "sha256:" + strings.Repeat("a", 64)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/library/qwen2/manifests/7b" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(modelManifest{Layers: []manifestLayer{{MediaType: modelLayerMediaType, Digest: upstreamDigest}}})
+	}))
+	defer registry.Close()
+	defer func(base string) { registryURL = base }(registryURL)
+	registryURL = registry.URL
+
+	check := func(modelfile string) {
+		t.Helper()
+		if _, err := parser.ParseFile(strings.NewReader(modelfile)); err != nil {
+			t.Errorf("ollama can't parse the modelfile: %v\n%s", err, modelfile)
+		}
+		gotTemplate, gotSystem := gollama.ExtractTemplateAndSystem(modelfile)
+		if gotTemplate != template || gotSystem != system {
+			t.Errorf("template %q and system %q read back, want %q and %q", gotTemplate, gotSystem, template, system)
+		}
+		want := map[string][]string{"stop": {"<|im_start|>", "<|im_end|>"}, "num_ctx": {"32768"}}
+		if params := gollama.ExtractParameters(modelfile); !reflect.DeepEqual(params, want) {
+			t.Errorf("parameters read back = %v, want %v", params, want)
+		}
+		if strings.Contains(modelfile, "\nFROM /root") {
+			t.Errorf("the modelfile still loads a blob path:\n%s", modelfile)
+		}
+	}
+
+	// The parent model has the same weights in the registry, so the modelfile builds from it with its projector
+	result, err := makePortableModelfile(context.Background(), client, registry.Client(), "pirate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(result.Modelfile)
+	if result.Upstream != "qwen2:7b" || result.Note != "" || strings.Count(result.Modelfile, "FROM ") != 1 || !strings.Contains(result.Modelfile, "\nFROM qwen2:7b\n") {
+		t.Errorf("modelfile with an upstream model =\n%s", result.Modelfile)
+	}
+
+	// With different weights upstream the blobs are copied next to the modelfile and the tool says why
+	upstreamDigest = "sha256:" + strings.Repeat("c", 64)
+	result, err = makePortableModelfile(context.Background(), client, registry.Client(), "pirate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(result.Modelfile)
+	if result.Upstream != "" || !strings.Contains(result.Note, "qwen2:7b in the registry has different weights") || !strings.Contains(result.Note, "pirate isn't in the registry") {
+		t.Errorf("note = %q, want the reasons the upstream model wasn't found", result.Note)
+	}
+	for _, want := range []string{"\nFROM ./model.gguf\nFROM ./projector.gguf\n", "#   cp " + blob + " ./model.gguf", "#   cp " + projector + " ./projector.gguf"} {
+		if !strings.Contains(result.Modelfile, want) {
+			t.Errorf("modelfile without an upstream model doesn't contain %q:\n%s", want, result.Modelfile)
+		}
+	}
+
+	for value, want := range map[string]string{"0.7": "0.7", " padded": `" padded"`, `"quoted"`: `""""quoted""""`, "": `""`} {
+		if got, err := quoteModelfileValue(value); err != nil || got != want {
+			t.Errorf("quoteModelfileValue(%q) = %s, %v, want %s", value, got, err, want)
+		}
+	}
+	if _, err := quoteModelfileValue("two\nlines with \"\"\" in them"); err == nil {
+		t.Error(`quoteModelfileValue() of a value containing """ succeeded`)
+	}
+}
@@ -3,17 +3,18 @@ package main
 
 import (
 	"fmt"
-	"time"
+	"strings"
+
+	"github.com/sammcj/gollama/pkg/gollama"
 )
 
+// Model is the list view's presentation of a gollama.Model, view state is kept alongside the canonical fields
 type Model struct {
-	Name              string
-	ID                string
-	Size              float64
-	QuantizationLevel string
-	Modified          time.Time
-	Selected          bool
-	Family            string
+	gollama.Model
+	Selected   bool
+	Pinned     bool
+	Symlinked  bool // the main blob is a symlink into another directory, so deleting the model frees nothing
+	Duplicates int  // the number of models sharing this model's blobs, including itself, 0 when there are no others
 }
 
 func (m Model) SelectedStr() string {
@@ -24,9 +25,43 @@ func (m Model) SelectedStr() string {
 }
 
 func (m Model) Description() string {
-	return fmt.Sprintf("ID: %s, Size: %.2f GB, Quant: %s, Modified: %s", m.ID, m.Size, m.QuantizationLevel, m.Modified.Format("2006-01-02"))
+	return fmt.Sprintf("ID: %s, Size: %.2f GB, Quant: %s, Modified: %s", m.ID, m.SizeGB(), m.QuantizationLevel, m.Modified.Format("2006-01-02"))
+}
+
+// IDLabel is the ID shown in the list, followed by e.g. ×3 when three models share its blobs
+func (m Model) IDLabel() string {
+	if m.Duplicates > 1 {
+		return fmt.Sprintf("%s ×%d", m.ID, m.Duplicates)
+	}
+	return m.ID
+}
+
+// SizeLabel is the size shown in the list, marked with ↗ when the model's blob is a symlink
+func (m Model) SizeLabel() string {
+	if m.Symlinked {
+		return fmt.Sprintf("%.2fGB ↗", m.SizeGB())
+	}
+	return fmt.Sprintf("%.2fGB", m.SizeGB())
 }
 
 func (m Model) FilterValue() string {
 	return m.Name
 }
+
+// DisplayName is the name shown in the model lists, with strip_string (e.g. a private registry prefix) removed. It's
+// only for display, prompts, confirmations, messages and the API always use Name so the prefix is never lost.
+func (m Model) DisplayName(stripString string) string {
+	if stripString == "" {
+		return m.Name
+	}
+	return strings.Replace(m.Name, stripString, "", 1)
+}
+
+// droppedPrefix returns the strip_string a new name is missing that the model it was copied or renamed from has, so
+// a name typed as it's shown in the list doesn't silently create a model without the prefix
+func droppedPrefix(source, newName, stripString string) string {
+	if stripString != "" && strings.Contains(source, stripString) && !strings.Contains(newName, stripString) {
+		return stripString
+	}
+	return ""
+}
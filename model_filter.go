@@ -0,0 +1,193 @@
+// model_filter.go filters the TUI list by name, family and quantisation, with "family:", "quant:" and "modified:" terms
+// to target a single column.
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Column filter prefixes, e.g. "family:qwen" or "quant:q8"
+const (
+	familyFilterPrefix = "family:"
+	quantFilterPrefix  = "quant:"
+)
+
+// filterQuery is a parsed filter, every column term and age must match and the remaining text is fuzzy matched
+// against the name, family or quantisation
+type filterQuery struct {
+	ages   []ageFilter
+	family []string
+	quant  []string
+	text   string
+}
+
+// modelMatch holds the rune indexes matched in each column so only the column that matched is highlighted
+type modelMatch struct {
+	name   []int
+	family []int
+	quant  []int
+}
+
+func parseFilterQuery(term string) filterQuery {
+	var q filterQuery
+	var rest []string
+	for _, field := range strings.Fields(term) {
+		lower := strings.ToLower(field)
+		if spec, ok := strings.CutPrefix(lower, ageFilterPrefix); ok {
+			if f, err := parseAgeFilter(spec); err == nil {
+				q.ages = append(q.ages, f)
+				continue
+			}
+		}
+		if value, ok := strings.CutPrefix(lower, familyFilterPrefix); ok && value != "" {
+			q.family = append(q.family, value)
+			continue
+		}
+		if value, ok := strings.CutPrefix(lower, quantFilterPrefix); ok && value != "" {
+			q.quant = append(q.quant, value)
+			continue
+		}
+		rest = append(rest, field)
+	}
+	q.text = strings.Join(rest, " ")
+	return q
+}
+
+// substringIndexes returns the rune indexes of the first case insensitive occurrence of substr in s
+func substringIndexes(s, substr string) ([]int, bool) {
+	lower := strings.ToLower(s)
+	i := strings.Index(lower, strings.ToLower(substr))
+	if i < 0 {
+		return nil, false
+	}
+	start := utf8.RuneCountInString(lower[:i])
+	indexes := make([]int, utf8.RuneCountInString(substr))
+	for j := range indexes {
+		indexes[j] = start + j
+	}
+	return indexes, true
+}
+
+// fuzzyIndexes fuzzy matches pattern against s the same way as the list's default filter
+func fuzzyIndexes(pattern, s string) ([]int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	ranks := list.DefaultFilter(pattern, []string{s})
+	if len(ranks) == 0 {
+		return nil, false
+	}
+	return ranks[0].MatchedIndexes, true
+}
+
+// matchColumns reports whether the model matches every family: and quant: term
+func (q filterQuery) matchColumns(model Model, match *modelMatch) bool {
+	for _, family := range q.family {
+		indexes, ok := substringIndexes(model.Family, family)
+		if !ok {
+			return false
+		}
+		match.family = append(match.family, indexes...)
+	}
+	for _, quant := range q.quant {
+		indexes, ok := substringIndexes(model.QuantizationLevel, quant)
+		if !ok {
+			return false
+		}
+		match.quant = append(match.quant, indexes...)
+	}
+	return true
+}
+
+// match reports whether the model matches the column terms and text, ages are checked separately as they depend on the run history
+func (q filterQuery) match(model Model) (modelMatch, bool) {
+	var match modelMatch
+	if !q.matchColumns(model, &match) {
+		return match, false
+	}
+	if q.text == "" {
+		return match, true
+	}
+	if indexes, ok := fuzzyIndexes(q.text, model.Name); ok {
+		match.name = indexes
+		return match, true
+	}
+	if indexes, ok := fuzzyIndexes(q.text, model.Family); ok {
+		match.family = append(match.family, indexes...)
+		return match, true
+	}
+	if indexes, ok := fuzzyIndexes(q.text, model.QuantizationLevel); ok {
+		match.quant = append(match.quant, indexes...)
+		return match, true
+	}
+	return match, false
+}
+
+// filterModels returns the list ranks of the models matching term. Name matches come first in the order the default
+// filter ranks them, followed by models that only match on family or quantisation in list order.
+func filterModels(term string, targets []string, items []list.Item, runs map[string]time.Time, now time.Time) []list.Rank {
+	q := parseFilterQuery(term)
+	keep := func(i int) (modelMatch, bool) {
+		if i >= len(items) {
+			return modelMatch{}, false
+		}
+		model, ok := items[i].(Model)
+		if !ok {
+			return modelMatch{}, false
+		}
+		t, _ := modelAge(model, runs)
+		for _, f := range q.ages {
+			if !f.matches(t, now) {
+				return modelMatch{}, false
+			}
+		}
+		return q.match(model)
+	}
+
+	var ranks []list.Rank
+	seen := make(map[int]bool)
+	if q.text != "" {
+		for _, rank := range list.DefaultFilter(q.text, targets) {
+			seen[rank.Index] = true
+			if _, ok := keep(rank.Index); ok {
+				ranks = append(ranks, rank)
+			}
+		}
+	}
+	for i := range targets {
+		if seen[i] {
+			continue
+		}
+		if match, ok := keep(i); ok {
+			ranks = append(ranks, list.Rank{Index: i, MatchedIndexes: match.name})
+		}
+	}
+	return ranks
+}
+
+// modelFilter is the list filter used by the TUI, see filterModels
+func (m *AppModel) modelFilter() list.FilterFunc {
+	return func(term string, targets []string) []list.Rank {
+		var runs map[string]time.Time
+		if len(parseFilterQuery(term).ages) > 0 {
+			runs = lastRuns()
+		}
+		// targets are in the same order as the list's items
+		return filterModels(term, targets, m.list.Items(), runs, time.Now())
+	}
+}
+
+// highlightMatches underlines the matched runes of text in style, leaving the style's width, padding and border to
+// the caller so the column still lines up
+func highlightMatches(text string, indexes []int, style lipgloss.Style) string {
+	if len(indexes) == 0 {
+		return text
+	}
+	inline := style.UnsetWidth().UnsetPadding().UnsetBorderStyle().UnsetBorderLeft()
+	return lipgloss.StyleRunes(text, indexes, inline.Underline(true), inline)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestFilterModels(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	model := func(name, family, quant string) Model {
+		m := Model{}
+		m.Name, m.Family, m.QuantizationLevel, m.Modified = name, family, quant, now.AddDate(0, 0, -1)
+		return m
+	}
+	models := []Model{
+		model("llama3:8b", "llama", "Q4_K_M"),
+		model("mygemma:latest", "gemma2", "Q8_0"),
+		model("qwen2.5-coder:7b", "qwen2", "Q4_0"),
+		model("custom:latest", "", "F16"),
+	}
+	items := make([]list.Item, len(models))
+	targets := make([]string, len(models))
+	for i, m := range models {
+		items[i], targets[i] = m, m.FilterValue()
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"llama3", []string{"llama3:8b"}},
+		{"GEMMA2", []string{"mygemma:latest"}},
+		{"q4_k", []string{"llama3:8b"}},
+		{"F16", []string{"custom:latest"}},
+		{"family:QWEN", []string{"qwen2.5-coder:7b"}},
+		{"family:l", []string{"llama3:8b"}},
+		{"quant:q4", []string{"llama3:8b", "qwen2.5-coder:7b"}},
+		{"Quant:Q4 family:qwen", []string{"qwen2.5-coder:7b"}},
+		{"family:qwen modified:>90d", nil},
+		{"family:custom", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			var got []string
+			for _, rank := range filterModels(tt.term, targets, items, nil, now) {
+				got = append(got, models[rank.Index].Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterModels(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+
+	match, ok := parseFilterQuery("quant:q8").match(models[1])
+	if !ok || !slices.Equal(match.quant, []int{0, 1}) || match.name != nil || match.family != nil {
+		t.Errorf("quant:q8 match = %+v, want only the first two runes of the quant", match)
+	}
+	match, ok = parseFilterQuery("gemma2").match(models[1])
+	if !ok || match.family == nil || match.quant != nil {
+		t.Errorf("gemma2 match = %+v, want only the family highlighted", match)
+	}
+	if _, ok := parseFilterQuery("gemma2").match(models[3]); ok {
+		t.Error("a model with no family matched a family term")
+	}
+}
+
+func TestFilterSurvivesPullRefresh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	names := []string{"llama3:8b", "llama3.1:8b", "qwen2:7b"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		var resp api.ListResponse
+		for _, name := range names {
+			resp.Models = append(resp.Models, api.ListModelResponse{Name: name, Model: name, Digest: name})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{SortOrder: "name"}, list: list.New(nil, list.NewDefaultDelegate(), 80, 40)}
+	m.Update(m.fetchModels()())
+	m.restoreSession(sessionState{Filter: "llama", Selected: "llama3:8b"})
+	if m.list.FilterState() != list.FilterApplied || len(m.list.VisibleItems()) != 2 {
+		t.Fatalf("filter %v shows %d models, want the 2 llama models", m.list.FilterState(), len(m.list.VisibleItems()))
+	}
+
+	// A pull finishing refreshes the list, which keeps the filter and the model under the cursor
+	names = append(names, "llama3.2:3b")
+	m.pulling = true
+	_, cmd := m.Update(pullSuccessMsg{modelName: "llama3.2:3b"})
+	for _, c := range cmd().(tea.BatchMsg) {
+		if c == nil {
+			continue
+		}
+		if msg, ok := c().(modelsLoadedMsg); ok {
+			m.Update(msg)
+		}
+	}
+	if m.list.FilterState() != list.FilterApplied || m.list.FilterValue() != "llama" {
+		t.Errorf("filter after the refresh = %v %q, want llama applied", m.list.FilterState(), m.list.FilterValue())
+	}
+	if got := len(m.list.VisibleItems()); got != 3 {
+		t.Errorf("%d models shown after the refresh, want the 3 llama models", got)
+	}
+	if item, ok := m.list.SelectedItem().(Model); !ok || item.Name != "llama3:8b" {
+		t.Errorf("selected %v after the refresh, want llama3:8b", m.list.SelectedItem())
+	}
+}
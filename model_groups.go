@@ -0,0 +1,180 @@
+// model_groups.go groups the model list under a header for each family showing how many models it has and their
+// total size. Groups collapse and expand with enter or left and right, and the sort keys sort the models within
+// each group.
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// groupByFamily is the group_by value that groups the list by family
+const groupByFamily = "family"
+
+// familyHeader heads a family's models in the grouped list
+type familyHeader struct {
+	Family    string
+	Count     int
+	Size      int64
+	Collapsed bool
+}
+
+// FilterValue is empty so the filter only ever matches models
+func (h familyHeader) FilterValue() string {
+	return ""
+}
+
+// groupFamily is the group a model is listed under, models without a family share one
+func groupFamily(model Model) string {
+	if model.Family == "" {
+		return "unknown"
+	}
+	return model.Family
+}
+
+// groupedItems returns the list items for models grouped by family: a header for each family in name order, followed
+// by its models in the order they're sorted in unless the family is collapsed
+func groupedItems(models []Model, collapsed map[string]bool) []list.Item {
+	byFamily := make(map[string][]Model)
+	var families []string
+	for _, model := range models {
+		family := groupFamily(model)
+		if _, ok := byFamily[family]; !ok {
+			families = append(families, family)
+		}
+		byFamily[family] = append(byFamily[family], model)
+	}
+	sort.Strings(families)
+
+	items := make([]list.Item, 0, len(models)+len(families))
+	for _, family := range families {
+		header := familyHeader{Family: family, Count: len(byFamily[family]), Collapsed: collapsed[family]}
+		for _, model := range byFamily[family] {
+			header.Size += model.Size
+		}
+		items = append(items, header)
+		if header.Collapsed {
+			continue
+		}
+		for _, model := range byFamily[family] {
+			items = append(items, model)
+		}
+	}
+	return items
+}
+
+// grouped reports whether the list is grouped by family
+func (m *AppModel) grouped() bool {
+	return m.cfg != nil && m.cfg.GroupBy == groupByFamily
+}
+
+// selectListItem moves the cursor to the first item matching, reporting whether there was one
+func (m *AppModel) selectListItem(matches func(list.Item) bool) bool {
+	for i, item := range m.list.VisibleItems() {
+		if matches(item) {
+			m.list.Select(i)
+			return true
+		}
+	}
+	return false
+}
+
+// selectModelOrGroup selects a model, or the header of its group when the group is collapsed
+func (m *AppModel) selectModelOrGroup(model Model) {
+	if m.selectListItem(func(item list.Item) bool { i, ok := item.(Model); return ok && i.Name == model.Name }) {
+		return
+	}
+	m.selectListItem(func(item list.Item) bool { h, ok := item.(familyHeader); return ok && h.Family == groupFamily(model) })
+}
+
+// handleGroupByKey switches between the flat and grouped list, keeping the selected model and saving the choice
+func (m *AppModel) handleGroupByKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("GroupBy key matched")
+	selected, hasSelection := m.list.SelectedItem().(Model)
+	header, onHeader := m.list.SelectedItem().(familyHeader)
+
+	if m.grouped() {
+		m.cfg.GroupBy = ""
+		m.message = "Ungrouped the models"
+	} else {
+		m.cfg.GroupBy = groupByFamily
+		m.message = "Grouped the models by family"
+	}
+	m.refreshList()
+
+	switch {
+	case hasSelection:
+		m.selectModelOrGroup(selected)
+	case onHeader:
+		m.selectListItem(func(item list.Item) bool { i, ok := item.(Model); return ok && groupFamily(i) == header.Family })
+	}
+	if err := config.SetValues(map[string]any{"group_by": m.cfg.GroupBy}); err != nil {
+		logging.ErrorLogger.Printf("Error saving group_by to the config: %v\n", err)
+	}
+	return m, nil
+}
+
+// setGroupCollapsed collapses or expands a family and leaves the cursor on its header
+func (m *AppModel) setGroupCollapsed(family string, collapsed bool) {
+	if m.collapsedGroups == nil {
+		m.collapsedGroups = make(map[string]bool)
+	}
+	m.collapsedGroups[family] = collapsed
+	m.refreshList()
+	m.selectListItem(func(item list.Item) bool { h, ok := item.(familyHeader); return ok && h.Family == family })
+}
+
+// handleGroupKey collapses and expands groups in the grouped list: enter toggles the group under the cursor, left
+// collapses it, also from one of its models, and right expands it. It reports whether it used the key.
+func (m *AppModel) handleGroupKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if !m.grouped() || m.list.FilterState() != list.Unfiltered {
+		return m, nil, false
+	}
+	switch item := m.list.SelectedItem().(type) {
+	case familyHeader:
+		switch msg.String() {
+		case "enter":
+			m.setGroupCollapsed(item.Family, !item.Collapsed)
+		case "left":
+			m.setGroupCollapsed(item.Family, true)
+		case "right":
+			m.setGroupCollapsed(item.Family, false)
+		default:
+			return m, nil, false
+		}
+		return m, nil, true
+	case Model:
+		if msg.String() == "left" {
+			m.setGroupCollapsed(groupFamily(item), true)
+			return m, nil, true
+		}
+	}
+	return m, nil, false
+}
+
+// renderFamilyHeader draws a group's header, e.g. "▾ qwen2 (4 models, 31.2 GiB)"
+func (d itemDelegate) renderFamilyHeader(w io.Writer, m list.Model, index int, header familyHeader) {
+	colours := styles.Current().Colours
+	style := lipgloss.NewStyle().Bold(true).Foreground(familyColour(header.Family, index))
+	if index == m.Index() {
+		style = style.BorderLeft(true).BorderStyle(lipgloss.InnerHalfBlockBorder()).BorderForeground(colours.SelectedBorder.TerminalColour()).PaddingLeft(1)
+	}
+	arrow := "▾"
+	if header.Collapsed {
+		arrow = "▸"
+	}
+	noun := "models"
+	if header.Count == 1 {
+		noun = "model"
+	}
+	fmt.Fprint(w, style.Render(fmt.Sprintf("%s %s (%d %s, %s)", arrow, header.Family, header.Count, noun, formatBytes(header.Size))))
+}
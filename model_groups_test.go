@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestModelGroups(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newModel := func(name, family string, size int64) Model {
+		model := Model{}
+		model.Name, model.Family, model.Size = name, family, size
+		return model
+	}
+	models := []Model{
+		newModel("qwen2:7b", "qwen2", 4),
+		newModel("llama3:8b", "llama", 5),
+		newModel("qwen2:72b", "qwen2", 40),
+		newModel("nomic-embed-text", "", 1),
+	}
+	describe := func(items []list.Item) []string {
+		var rows []string
+		for _, item := range items {
+			switch item := item.(type) {
+			case familyHeader:
+				rows = append(rows, fmt.Sprintf("%s %d %d %v", item.Family, item.Count, item.Size, item.Collapsed))
+			case Model:
+				rows = append(rows, "  "+item.Name)
+			}
+		}
+		return rows
+	}
+
+	// Families are in name order and their models keep the sorted order
+	want := []string{"llama 1 5 false", "  llama3:8b", "qwen2 2 44 false", "  qwen2:7b", "  qwen2:72b", "unknown 1 1 false", "  nomic-embed-text"}
+	if got := describe(groupedItems(models, nil)); !slices.Equal(got, want) {
+		t.Errorf("groupedItems() = %q, want %q", got, want)
+	}
+	want = []string{"llama 1 5 false", "  llama3:8b", "qwen2 2 44 true", "unknown 1 1 false", "  nomic-embed-text"}
+	if got := describe(groupedItems(models, map[string]bool{"qwen2": true})); !slices.Equal(got, want) {
+		t.Errorf("groupedItems() with qwen2 collapsed = %q, want %q", got, want)
+	}
+
+	m := &AppModel{
+		cfg:    &config.Config{},
+		models: slices.Clone(models),
+		list:   list.New(nil, list.NewDefaultDelegate(), 80, 40),
+	}
+	m.refreshList()
+	m.list.Select(0) // qwen2:7b
+	m.handleGroupByKey()
+	if !m.grouped() || m.cfg.GroupBy != "family" {
+		t.Fatalf("g didn't group the list, group_by = %q", m.cfg.GroupBy)
+	}
+	if item, ok := m.list.SelectedItem().(Model); !ok || item.Name != "qwen2:7b" {
+		t.Errorf("selection after grouping = %v, want qwen2:7b", m.list.SelectedItem())
+	}
+
+	key := func(k tea.KeyType) tea.KeyMsg { return tea.KeyMsg{Type: k} }
+	// left on a model collapses its group and leaves the cursor on the header
+	if _, _, handled := m.handleGroupKey(key(tea.KeyLeft)); !handled {
+		t.Fatal("left on a model in a group wasn't handled")
+	}
+	if header, ok := m.list.SelectedItem().(familyHeader); !ok || header.Family != "qwen2" || !header.Collapsed {
+		t.Errorf("selection after collapsing = %v, want the collapsed qwen2 header", m.list.SelectedItem())
+	}
+	if len(m.list.Items()) != 5 {
+		t.Errorf("collapsed list has %d items, want 5", len(m.list.Items()))
+	}
+	// enter on a header toggles it, right expands it
+	m.handleGroupKey(key(tea.KeyEnter))
+	if len(m.list.Items()) != 7 {
+		t.Errorf("list has %d items after enter on a collapsed header, want 7", len(m.list.Items()))
+	}
+	m.handleGroupKey(key(tea.KeyEnter))
+	m.handleGroupKey(key(tea.KeyRight))
+	if header := m.list.SelectedItem().(familyHeader); header.Collapsed || len(m.list.Items()) != 7 {
+		t.Errorf("right didn't expand qwen2, header %+v with %d items", header, len(m.list.Items()))
+	}
+	// enter on a model is left for running it
+	m.list.Select(1)
+	if _, _, handled := m.handleGroupKey(key(tea.KeyEnter)); handled {
+		t.Error("enter on a model was handled as a group key")
+	}
+
+	// Sorting sorts within the groups
+	m.setSort("size", true)
+	if got := describe(m.list.Items())[2:5]; !slices.Equal(got, []string{"qwen2 2 44 false", "  qwen2:72b", "  qwen2:7b"}) {
+		t.Errorf("qwen2 group sorted by size = %q", got)
+	}
+
+	// Ungrouping keeps the selected model
+	m.list.Select(4)
+	m.handleGroupByKey()
+	if m.grouped() || len(m.list.Items()) != 4 {
+		t.Fatalf("g didn't ungroup the list, %d items", len(m.list.Items()))
+	}
+	if item := m.list.SelectedItem().(Model); item.Name != "qwen2:7b" {
+		t.Errorf("selection after ungrouping = %s, want qwen2:7b", item.Name)
+	}
+}
+
+func TestDeleteSelectedInCollapsedGroup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := &fakeOllama{}
+	server := httptest.NewServer(http.HandlerFunc(fake.handler))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	var models []Model
+	for _, name := range []string{"qwen2:7b", "qwen2:72b", "llama3:8b"} {
+		model := Model{}
+		model.Name, model.Family = name, strings.TrimSuffix(strings.Split(name, ":")[0], "3")
+		models = append(models, model)
+	}
+	m := &AppModel{
+		cfg:    &config.Config{GroupBy: groupByFamily},
+		client: api.NewClient(u, http.DefaultClient),
+		models: models,
+		list:   list.New(nil, list.NewDefaultDelegate(), 80, 40),
+	}
+	m.refreshList()
+	m.selectListItem(func(item list.Item) bool { model, ok := item.(Model); return ok && model.Name == "qwen2:72b" })
+	m.handleSpaceKey()
+	m.setGroupCollapsed("qwen2", true)
+	m.selectListItem(func(item list.Item) bool { model, ok := item.(Model); return ok && model.Name == "llama3:8b" })
+
+	// The selected model is deleted although its collapsed group hides it, not the one under the cursor
+	m.handleDeleteKey()
+	if !m.confirmDeletion || len(m.selectedModels) != 1 || m.selectedModels[0].Name != "qwen2:72b" {
+		t.Errorf("deleting with qwen2 collapsed selected %v, want qwen2:72b", m.selectedModels)
+	}
+	if selected := m.selectedListModels(); len(selected) != 1 || selected[0].Name != "qwen2:72b" {
+		t.Errorf("selectedListModels() = %v, want qwen2:72b", selected)
+	}
+}
@@ -0,0 +1,133 @@
+// model_loading.go fetches the model list in a command, so the TUI draws straight away with a spinner while a host with
+// many models or a slow connection answers, and shows a failed fetch in the TUI with a retry rather than exiting.
+// Refreshes after pulls, copies and renames go through the same command instead of blocking Update on the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+	"github.com/sammcj/gollama/styles"
+)
+
+// modelsLoadedMsg is the result of a model list fetch, generation drops the results of fetches a newer one replaced
+type modelsLoadedMsg struct {
+	generation int
+	models     []Model
+	err        error
+}
+
+// loadModels lists the models on the host, marking the symlinked ones on a local host and the duplicates
+func loadModels(ctx context.Context, client *api.Client, apiURL, modelsDir string) ([]Model, error) {
+	resp, err := client.List(ctx)
+	if err != nil {
+		var statusErr api.StatusError
+		if errors.As(err, &statusErr) && config.AuthError(statusErr.StatusCode) != nil {
+			err = config.AuthError(statusErr.StatusCode)
+		}
+		return nil, err
+	}
+	models := parseAPIResponse(resp)
+	digests := make(map[string]string, len(models))
+	for _, model := range models {
+		digests[model.Name] = model.Digest
+	}
+	showcache.For(client).SetDigests(digests)
+	if isLocalhost(apiURL) {
+		markSymlinkedModels(models, modelsDir)
+	}
+	markDuplicateModels(models)
+	return models, nil
+}
+
+// fetchModels returns the command that fetches the model list, replacing any fetch still in flight
+func (m *AppModel) fetchModels() tea.Cmd {
+	m.modelsGeneration++
+	generation, client, modelsDir := m.modelsGeneration, m.client, m.ollamaModelsDir
+	apiURL := ""
+	if m.cfg != nil {
+		apiURL = m.cfg.OllamaAPIURL
+	}
+	return func() tea.Msg {
+		models, err := loadModels(context.Background(), client, apiURL, modelsDir)
+		return modelsLoadedMsg{generation: generation, models: models, err: err}
+	}
+}
+
+// startModelsLoad shows the spinner and fetches the models for the first time, or again after the fetch failed
+func (m *AppModel) startModelsLoad() tea.Cmd {
+	m.modelsLoading = true
+	m.modelsErr = nil
+	if m.modelsSpinner.Spinner.Frames == nil {
+		m.modelsSpinner = spinner.New(spinner.WithSpinner(spinner.Dot))
+	}
+	return tea.Batch(m.modelsSpinner.Tick, m.fetchModels())
+}
+
+func (m *AppModel) handleModelsLoadedMsg(msg modelsLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.generation != m.modelsGeneration {
+		return m, nil
+	}
+	m.modelsLoading = false
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error fetching models: %v\n", msg.err)
+		m.modelsErr = msg.err
+		if m.modelsLoaded {
+			m.message = fmt.Sprintf("Error refreshing models: %v", msg.err)
+		}
+		return m, nil
+	}
+	m.modelsErr = nil
+	m.models = msg.models
+	if m.cfg != nil {
+		sortModels(m.models, m.cfg.SortOrder, sortDescending(m.cfg))
+	}
+	m.refreshList()
+	if m.modelsLoaded {
+		return m, nil
+	}
+	m.modelsLoaded = true
+	if m.pendingSession != nil {
+		// Reopen on the model and filter the last session quit with
+		m.restoreSession(*m.pendingSession)
+		m.pendingSession = nil
+	}
+	return m, m.nextContextLengthFetch()
+}
+
+// handleModelsSpinnerTick animates the spinner until the first fetch finishes
+func (m *AppModel) handleModelsSpinnerTick(msg spinner.TickMsg) (tea.Model, tea.Cmd) {
+	if !m.modelsLoading {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.modelsSpinner, cmd = m.modelsSpinner.Update(msg)
+	return m, cmd
+}
+
+// modelsStatusShown reports whether the view shows the loading spinner or the fetch error in place of the list
+func (m *AppModel) modelsStatusShown() bool {
+	return !m.modelsLoaded && (m.modelsLoading || m.modelsErr != nil)
+}
+
+func (m *AppModel) modelsStatusView() string {
+	apiURL := ""
+	if m.cfg != nil {
+		apiURL = m.cfg.OllamaAPIURL
+	}
+	if m.modelsErr == nil {
+		return fmt.Sprintf("\n %s Loading models from %s…\n", m.modelsSpinner.View(), apiURL)
+	}
+	errorStyle := lipgloss.NewStyle().Foreground(styles.Current().Colours.Error.TerminalColour())
+	return fmt.Sprintf("\n%s\n- Error: %v\n- Configured API URL: %s\n\nPress r to retry or q to quit\n",
+		errorStyle.Render("Error fetching models:"), m.modelsErr, apiURL)
+}
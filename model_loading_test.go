@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestModelsLoadInBackground(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "loading", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{{Name: "b:latest"}, {Name: "a:latest"}, {Name: "c:latest"}}})
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	m := &AppModel{
+		client:         api.NewClient(u, server.Client()),
+		cfg:            &config.Config{OllamaAPIURL: server.URL, SortOrder: "name", SortDirection: "asc"},
+		list:           list.New(nil, list.NewDefaultDelegate(), 80, 40),
+		keys:           *NewKeyMap(),
+		contextLengths: make(map[string]int),
+		pendingSession: &sessionState{Selected: "b:latest"},
+	}
+	load := func(cmd tea.Cmd) {
+		if _, cmd := m.Update(cmd()); cmd != nil {
+			t.Errorf("handling the models gave a command")
+		}
+	}
+
+	// The TUI draws a spinner until the models arrive, then shows a failed fetch with a retry
+	m.startModelsLoad()
+	if view := m.View(); !strings.Contains(view, "Loading models from "+server.URL) {
+		t.Errorf("view while loading = %q", view)
+	}
+	load(m.fetchModels())
+	if view := m.View(); !strings.Contains(view, "Error fetching models:") || !strings.Contains(view, "Press r to retry") {
+		t.Errorf("view after a failed fetch = %q", view)
+	}
+	if _, cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")}); cmd == nil || !m.modelsLoading || m.modelsErr != nil {
+		t.Error("r didn't fetch the models again")
+	}
+
+	// Only the latest fetch is used
+	failing = false
+	stale := m.fetchModels()
+	latest := m.fetchModels()
+	failing = true
+	load(stale)
+	if m.modelsLoaded || !m.modelsLoading {
+		t.Fatal("a replaced fetch's result was used")
+	}
+	failing = false
+	load(latest)
+	if !m.modelsLoaded || m.modelsLoading || m.modelsStatusShown() {
+		t.Fatalf("loaded %v, loading %v after the fetch", m.modelsLoaded, m.modelsLoading)
+	}
+	var names []string
+	for _, item := range m.list.Items() {
+		names = append(names, item.(Model).Name)
+	}
+	if got := strings.Join(names, ","); got != "a:latest,b:latest,c:latest" {
+		t.Errorf("list = %s, want the models sorted by name", got)
+	}
+	if selected := m.list.SelectedItem().(Model).Name; selected != "b:latest" || m.pendingSession != nil {
+		t.Errorf("selected %s after loading, want the last session's b:latest", selected)
+	}
+
+	// A failed refresh keeps the list and reports the error
+	failing = true
+	load(m.fetchModels())
+	if len(m.list.Items()) != 3 || !strings.Contains(m.message, "Error refreshing models") {
+		t.Errorf("failed refresh left %d models, message %q", len(m.list.Items()), m.message)
+	}
+}
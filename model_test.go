@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestStripStringDisplayOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	const prefix = "registry.internal:5000/team/"
+	fake := &fakeOllama{models: []string{prefix + "llama3:8b"}}
+	server := httptest.NewServer(http.HandlerFunc(fake.handler))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	source := Model{}
+	source.Name, source.Size = prefix+"llama3:8b", 1<<30
+	if got := source.DisplayName(prefix); got != "llama3:8b" {
+		t.Errorf("DisplayName() = %q, want llama3:8b", got)
+	}
+	if got := source.DisplayName(""); got != source.Name {
+		t.Errorf("DisplayName() without a strip string = %q, want the full name", got)
+	}
+
+	// The -l table shows the stripped name without changing the models it's given
+	models := []Model{source}
+	var out bytes.Buffer
+	printModelTable(&out, models, prefix)
+	if strings.Contains(out.String(), prefix) || !strings.Contains(out.String(), "llama3:8b") || models[0].Name != source.Name {
+		t.Errorf("table = %q with the model named %s, want the name stripped for display only", out.String(), models[0].Name)
+	}
+
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{StripString: prefix}}
+	m.models = models
+
+	// Copies and renames are made from and to the full names, in the background
+	copyOrRename := func(source Model, newName string, rename bool) {
+		_, cmd := m.copyOrRenameModel(source, newName, rename)
+		for cmd != nil {
+			msg := cmd()
+			switch msg.(type) {
+			case copyCheckMsg, copyResultMsg:
+				_, cmd = m.Update(msg)
+			default:
+				cmd = nil
+			}
+		}
+	}
+	copyOrRename(source, prefix+"llama3:copy", false)
+	if !slices.Contains(fake.models, prefix+"llama3:copy") || strings.Contains(m.message, "without") {
+		t.Errorf("models = %v, message %q, want the prefixed copy made", fake.models, m.message)
+	}
+	copyOrRename(source, "llama3:local", false)
+	if !slices.Contains(fake.models, "llama3:local") || !strings.Contains(m.message, "without the "+prefix+" prefix") {
+		t.Errorf("message = %q, want the missing prefix pointed out", m.message)
+	}
+	renamed := Model{}
+	renamed.Name = prefix + "llama3:copy"
+	copyOrRename(renamed, prefix+"llama3:renamed", true)
+	if !slices.Contains(fake.deleted, prefix+"llama3:copy") || !slices.Contains(fake.models, prefix+"llama3:renamed") {
+		t.Errorf("deleted %v, models %v, want the prefixed model renamed", fake.deleted, fake.models)
+	}
+
+	// The delete confirmation lists, and deletes, the full name
+	if summary := deletionSummary(m.models, models, nil, false); !strings.Contains(summary, source.Name) {
+		t.Errorf("deletion summary = %q, want the full name", summary)
+	}
+	if err := deleteModel(m.client, source.Name); err != nil || !slices.Contains(fake.deleted, source.Name) {
+		t.Errorf("deleteModel() = %v, deleted %v, want %s deleted", err, fake.deleted, source.Name)
+	}
+}
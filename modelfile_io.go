@@ -0,0 +1,176 @@
+// modelfile_io.go exports modelfiles to disk, e.g. to keep tuned models in git, and imports them back.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// modelfileFileName is the file a model's modelfile is exported to, e.g. sammcj/qwen2:7b becomes sammcj_qwen2-7b.modelfile
+func modelfileFileName(modelName string) string {
+	return strings.NewReplacer(":", "-", "/", "_").Replace(modelName) + ".modelfile"
+}
+
+// exportModelfile writes a model's full modelfile to path
+func exportModelfile(ctx context.Context, client *api.Client, modelName, path string) error {
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return fmt.Errorf("error fetching modelfile for %s: %w", modelName, err)
+	}
+	if err := os.WriteFile(path, []byte(show.Modelfile), 0644); err != nil {
+		return fmt.Errorf("error writing modelfile for %s: %w", modelName, err)
+	}
+	logging.InfoLogger.Printf("Exported the modelfile of %s to %s\n", modelName, path)
+	return nil
+}
+
+// exportAllModelfiles writes the modelfile of each model to dir, carrying on past failures
+func exportAllModelfiles(ctx context.Context, client *api.Client, names []string, dir string) []error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return []error{fmt.Errorf("error creating %s: %w", dir, err)}
+	}
+	var errs []error
+	for _, name := range names {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if err := exportModelfile(ctx, client, name, filepath.Join(dir, modelfileFileName(name))); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// setModelfileBase sets what a new model is built from to every FROM and ADAPTER line of a modelfile, as
+// makePortableModelfile keeps them. An exported FROM or ADAPTER is a blob path on the machine it came from, so blobs are
+// sent by their digest for the server to find. A FROM naming a model brings its projectors with it, so the blobs
+// after it are left out as they are by makePortableModelfile.
+func setModelfileBase(req *api.CreateRequest, modelfile string) error {
+	parsed, err := parser.ParseFile(strings.NewReader(modelfile))
+	if err != nil {
+		return fmt.Errorf("error parsing modelfile: %w", err)
+	}
+	models, adapters := 0, 0
+	for _, cmd := range parsed.Commands {
+		if cmd.Name != "model" && cmd.Name != "adapter" {
+			continue
+		}
+		match := blobNamePattern.FindStringSubmatch(filepath.Base(cmd.Args))
+		switch {
+		case cmd.Name == "adapter" && match != nil:
+			if req.Adapters == nil {
+				req.Adapters = make(map[string]string)
+			}
+			req.Adapters[strings.TrimPrefix(localBlobName("adapter", adapters), "./")] = "sha256:" + match[1]
+			adapters++
+		case cmd.Name == "adapter":
+			return fmt.Errorf("ADAPTER %s isn't a blob the server can find by its digest", cmd.Args)
+		case req.From != "":
+			// The named model brings its projectors
+		case match != nil:
+			if req.Files == nil {
+				req.Files = make(map[string]string)
+			}
+			local := localBlobName("model", models)
+			if models > 0 {
+				local = localBlobName("projector", models-1)
+			}
+			req.Files[strings.TrimPrefix(local, "./")] = "sha256:" + match[1]
+			models++
+		case models > 0:
+			return fmt.Errorf("FROM %s can't be added to the blobs before it, only a blob can", cmd.Args)
+		default:
+			req.From = cmd.Args
+			models++
+		}
+	}
+	if models == 0 {
+		return errors.New("the modelfile has no FROM line")
+	}
+	return nil
+}
+
+// importModelfile creates or updates a model from an exported modelfile. Rather than sending the file as is, its
+// template, system prompt and parameters are applied to the existing model, or to the base its FROM and ADAPTER lines
+// name, so it works against a remote host where a blob path from another machine wouldn't resolve.
+func importModelfile(ctx context.Context, client *api.Client, modelName, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading modelfile: %w", err)
+	}
+	modelfile := string(data)
+	req := modelfileCreateRequest(modelName, modelfileValues(modelfile))
+
+	existing, err := findModel(client, modelName)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		req.From = ""
+		if err := setModelfileBase(req, modelfile); err != nil {
+			return fmt.Errorf("can't create %s from %s: %w", modelName, path, err)
+		}
+	}
+
+	err = client.Create(ctx, req, func(resp api.ProgressResponse) error {
+		logging.DebugLogger.Printf("Importing %s: %s\n", modelName, resp.Status)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error importing %s: %w", modelName, err)
+	}
+	showcache.Invalidate(client, modelName)
+	recordHistory("import", modelName, path)
+	return nil
+}
+
+// runExportModelfile implements -export-modelfile, returning the process exit code
+func runExportModelfile(ctx context.Context, client *api.Client, modelName, path string) int {
+	if path == "" {
+		path = modelfileFileName(modelName)
+	}
+	if err := exportModelfile(ctx, client, modelName, path); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("Exported the modelfile of %s to %s\n", modelName, path)
+	return 0
+}
+
+// runExportAll implements -export-all, returning the process exit code
+func runExportAll(ctx context.Context, client *api.Client, names []string, dir string) int {
+	errs := exportAllModelfiles(ctx, client, names, dir)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	fmt.Printf("Exported %d of %d modelfiles to %s\n", len(names)-len(errs), len(names), dir)
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runImportModelfile implements -import-modelfile, returning the process exit code
+func runImportModelfile(ctx context.Context, client *api.Client, modelName, path string) int {
+	if path == "" {
+		fmt.Println("Usage: gollama -import-modelfile <model> <path>")
+		return 1
+	}
+	if err := importModelfile(ctx, client, modelName, path); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("Imported %s from %s\n", modelName, path)
+	return 0
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+)
+
+func TestModelfileExportImportRoundTrip(t *testing.T) {
+	blobPath := func(digest string) string {
+		return "/home/someone/.ollama/models/blobs/" + strings.Replace(digest, ":", "-", 1)
+	}
+	weights, projector, adapter := "sha256:"+strings.Repeat("ab", 32), "sha256:"+strings.Repeat("cd", 32), "sha256:"+strings.Repeat("ef", 32)
+	modelfiles := make(map[string]string)
+	// render stands in for the server, writing the modelfile Show would return for a created model from its FROM and
+	// ADAPTER lines
+	render := func(base []string, req api.CreateRequest) string {
+		var b strings.Builder
+		for _, line := range base {
+			fmt.Fprintln(&b, line)
+		}
+		if req.Template != "" {
+			fmt.Fprintf(&b, "TEMPLATE \"\"\"%s\"\"\"\n", req.Template)
+		}
+		if req.System != "" {
+			fmt.Fprintf(&b, "SYSTEM \"\"\"%s\"\"\"\n", req.System)
+		}
+		keys := make([]string, 0, len(req.Parameters))
+		for key := range req.Parameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if values, ok := req.Parameters[key].([]any); ok {
+				for _, value := range values {
+					fmt.Fprintf(&b, "PARAMETER %s \"%v\"\n", key, value)
+				}
+			} else {
+				fmt.Fprintf(&b, "PARAMETER %s %v\n", key, req.Parameters[key])
+			}
+		}
+		return b.String()
+	}
+	var creates []api.CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			var list api.ListResponse
+			for name := range modelfiles {
+				list.Models = append(list.Models, api.ListModelResponse{Name: name})
+			}
+			json.NewEncoder(w).Encode(list)
+		case "/api/show":
+			var req api.ShowRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfiles[req.Name]})
+		case "/api/create":
+			var req api.CreateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			creates = append(creates, req)
+			var base []string
+			if req.From != "" {
+				for _, line := range strings.Split(modelfiles[req.From], "\n") {
+					if strings.HasPrefix(line, "FROM ") || strings.HasPrefix(line, "ADAPTER ") {
+						base = append(base, line)
+					}
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(req.Files)) {
+				base = append(base, "FROM "+blobPath(req.Files[name]))
+			}
+			for _, name := range slices.Sorted(maps.Keys(req.Adapters)) {
+				base = append(base, "ADAPTER "+blobPath(req.Adapters[name]))
+			}
+			modelfiles[req.Model] = render(base, req)
+			w.Write([]byte(`{"status":"success"}` + "\n"))
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	modelfiles["tuned:latest"] = render([]string{"FROM " + blobPath(weights), "FROM " + blobPath(projector), "ADAPTER " + blobPath(adapter)}, api.CreateRequest{
+		Template:   "{{ .Prompt }}",
+		System:     "Be brief.",
+		Parameters: map[string]any{"num_ctx": 8192, "stop": []any{"<|eot_id|>", "<|end|>"}, "temperature": 0.2},
+	})
+	original := modelfiles["tuned:latest"]
+
+	dir := t.TempDir()
+	if errs := exportAllModelfiles(context.Background(), client, []string{"tuned:latest"}, dir); len(errs) > 0 {
+		t.Fatalf("exportAllModelfiles() errors: %v", errs)
+	}
+	path := filepath.Join(dir, "tuned-latest.modelfile")
+	if data, err := os.ReadFile(path); err != nil || string(data) != original {
+		t.Fatalf("exported modelfile = %q, %v, want %q", data, err, original)
+	}
+
+	if err := importModelfile(context.Background(), client, "tuned:latest", path); err != nil {
+		t.Fatalf("importModelfile() error: %v", err)
+	}
+	if modelfiles["tuned:latest"] != original {
+		t.Errorf("modelfile after importing into the same model = %q, want no change from %q", modelfiles["tuned:latest"], original)
+	}
+
+	if err := importModelfile(context.Background(), client, "copy:latest", path); err != nil {
+		t.Fatalf("importModelfile() of a new model error: %v", err)
+	}
+	if modelfiles["copy:latest"] != original {
+		t.Errorf("modelfile of the imported model = %q, want %q", modelfiles["copy:latest"], original)
+	}
+	wantFiles := map[string]string{"model.gguf": weights, "projector.gguf": projector}
+	if last := creates[len(creates)-1]; last.From != "" || !maps.Equal(last.Files, wantFiles) || !maps.Equal(last.Adapters, map[string]string{"adapter.gguf": adapter}) {
+		t.Errorf("create request for a new model = %+v, want every blob by digest rather than its path", last)
+	}
+
+	// A FROM naming a model brings its projectors, and a file path from another machine can't be imported
+	named := filepath.Join(dir, "named.modelfile")
+	os.WriteFile(named, []byte("FROM llava:7b\nFROM "+blobPath(projector)+"\nPARAMETER num_ctx 4096\n"), 0644)
+	if err := importModelfile(context.Background(), client, "named:latest", named); err != nil {
+		t.Fatalf("importModelfile() from a named model error: %v", err)
+	}
+	if last := creates[len(creates)-1]; last.From != "llava:7b" || last.Files != nil {
+		t.Errorf("create request from a named model = %+v, want only the name", last)
+	}
+	local := filepath.Join(dir, "local.modelfile")
+	os.WriteFile(local, []byte("FROM "+blobPath(weights)+"\nADAPTER /home/someone/lora.gguf\n"), 0644)
+	if err := importModelfile(context.Background(), client, "local:latest", local); err == nil || !strings.Contains(err.Error(), "lora.gguf") {
+		t.Errorf("importModelfile() with an adapter file path error = %v, want it rejected", err)
+	}
+
+	if got := modelfileFileName("sammcj/qwen2:7b"); got != "sammcj_qwen2-7b.modelfile" {
+		t.Errorf("modelfileFileName() = %q", got)
+	}
+}
+
+func TestModelfileSystemRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	system := "You are a pirate called \"Bob\".\nAlways answer in rhyme,\n  and end with \"arr\""
+	modelfile := parser.Modelfile{Commands: []parser.Command{
+		{Name: "model", Args: "/root/.ollama/models/blobs/sha256-abc"},
+		{Name: "system", Args: system},
+		{Name: "stop", Args: "<|eot_id|>"},
+	}}
+	var created api.CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfile.String()})
+		case "/api/tags":
+			json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{{Name: "pirate:latest"}}})
+		case "/api/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, server.Client())
+
+	// Exporting and importing the modelfile unchanged re-creates the model with the same system prompt
+	path := filepath.Join(t.TempDir(), "pirate.modelfile")
+	if err := exportModelfile(context.Background(), client, "pirate", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := importModelfile(context.Background(), client, "pirate", path); err != nil {
+		t.Fatal(err)
+	}
+	if created.System != system {
+		t.Errorf("re-created system prompt = %q, want %q", created.System, system)
+	}
+	if stop := fmt.Sprint(created.Parameters["stop"]); stop != "[<|eot_id|>]" {
+		t.Errorf("re-created stop = %v, want the original", stop)
+	}
+	if changes := modelfileDelta(modelfile.String(), modelfile.String()); len(changes) != 0 {
+		t.Errorf("modelfileDelta() of an unchanged modelfile = %v, want no changes", changes)
+	}
+}
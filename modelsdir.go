@@ -0,0 +1,223 @@
+// modelsdir.go detects when the Ollama models directory is unavailable, e.g. a symlink to an external drive that isn't mounted.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/disk"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// modelsDirCheckInterval is how often the models directory is rechecked while the TUI is running
+const modelsDirCheckInterval = 30 * time.Second
+
+// modelsDirInfo describes the state of the models directory
+type modelsDirInfo struct {
+	Path       string
+	IsSymlink  bool
+	Target     string // resolved symlink target, empty if Path isn't a symlink
+	Available  bool
+	MountPoint string // mount point the directory (or its target) lives on, if known
+	Empty      bool   // the directory exists without manifests or blobs, as a drive's mount point does when it isn't mounted
+	Err        error
+}
+
+type modelsDirMsg struct {
+	info modelsDirInfo
+}
+
+// checkModelsDir resolves the models directory and reports whether it, and any symlink target, is available. A directory
+// without manifests or blobs isn't, an unmounted drive's mount point is an empty directory on the filesystem above it.
+func checkModelsDir(path string) modelsDirInfo {
+	info := modelsDirInfo{Path: path}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+
+	resolved := path
+	if fi.Mode()&os.ModeSymlink != 0 {
+		info.IsSymlink = true
+		target, err := os.Readlink(path)
+		if err != nil {
+			info.Err = err
+			return info
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		info.Target = target
+		resolved = target
+	}
+
+	info.MountPoint = mountPointOf(resolved)
+
+	st, err := os.Stat(resolved)
+	if err != nil {
+		info.Err = err
+		return info
+	}
+	if !st.IsDir() {
+		info.Err = fmt.Errorf("%s is not a directory", resolved)
+		return info
+	}
+	if !hasModelStore(resolved) {
+		info.Empty = true
+		info.Err = fmt.Errorf("%s has no manifests or blobs, the drive it's on may not be mounted", resolved)
+		if info.MountPoint == "/" {
+			info.Err = fmt.Errorf("%s has no manifests or blobs and is on the root filesystem, the drive it's on may not be mounted", resolved)
+		}
+		return info
+	}
+	info.Available = true
+	return info
+}
+
+// hasModelStore reports whether dir has the manifests or blobs directory Ollama keeps its models in
+func hasModelStore(dir string) bool {
+	for _, name := range []string{"manifests", "blobs"} {
+		if st, err := os.Stat(filepath.Join(dir, name)); err == nil && st.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// mountPointOf returns the longest mounted partition path containing path, or an empty string if none is found
+func mountPointOf(path string) string {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		logging.DebugLogger.Printf("Error listing partitions: %v\n", err)
+		return ""
+	}
+	var best string
+	for _, partition := range partitions {
+		mount := partition.Mountpoint
+		if mount == "/" || path == mount || strings.HasPrefix(path, strings.TrimSuffix(mount, string(os.PathSeparator))+string(os.PathSeparator)) {
+			if len(mount) > len(best) {
+				best = mount
+			}
+		}
+	}
+	return best
+}
+
+// banner returns the message shown in the TUI while a symlinked or empty models directory is unavailable,
+// a plain directory that doesn't exist is left alone as Ollama may be running somewhere else (e.g. in a container)
+func (i modelsDirInfo) banner() string {
+	switch {
+	case i.Available:
+		return ""
+	case i.Empty:
+		return fmt.Sprintf("Models directory %s has no models, the drive it's on may not be mounted", i.Path)
+	case i.IsSymlink:
+		return fmt.Sprintf("Models directory points to %s which is not available", i.Target)
+	}
+	return ""
+}
+
+// printModelsDirStatus prints the models directory state for the -models-dir-status flag
+func printModelsDirStatus(path, source string) {
+	info := checkModelsDir(path)
+	fmt.Printf("Path:        %s\n", info.Path)
+	fmt.Printf("Source:      %s\n", source)
+	fmt.Printf("Symlink:     %v\n", info.IsSymlink)
+	if info.IsSymlink {
+		fmt.Printf("Target:      %s\n", info.Target)
+	}
+	if info.MountPoint != "" {
+		fmt.Printf("Mount point: %s\n", info.MountPoint)
+	}
+	fmt.Printf("Available:   %v\n", info.Available)
+	if info.Err != nil {
+		fmt.Printf("Error:       %v\n", info.Err)
+	}
+}
+
+func (m *AppModel) handleModelsDirMsg(msg modelsDirMsg) (tea.Model, tea.Cmd) {
+	if msg.info.Available != m.modelsDir.Available {
+		if msg.info.Available {
+			logging.InfoLogger.Printf("Models directory %s is available again\n", msg.info.Path)
+		} else {
+			logging.ErrorLogger.Printf("Models directory %s is not available: %v\n", msg.info.Path, msg.info.Err)
+		}
+	}
+	m.modelsDir = msg.info
+	return m, m.scheduleModelsDirCheck(modelsDirCheckInterval)
+}
+
+// scheduleModelsDirCheck rechecks the models directory in the background after delay, it's a no-op on remote hosts where the directory isn't ours
+func (m *AppModel) scheduleModelsDirCheck(delay time.Duration) tea.Cmd {
+	if m.isRemoteHost() != "" {
+		return nil
+	}
+	path := m.ollamaModelsDir
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return modelsDirMsg{info: checkModelsDir(path)}
+	})
+}
+
+// modelsDirUnavailable returns a message if features that need the model blobs on disk can't be used
+func (m *AppModel) modelsDirUnavailable() string {
+	if m.isRemoteHost() != "" {
+		return ""
+	}
+	return m.modelsDir.banner()
+}
+
+// systemModelsDir is where the Linux install script's ollama service user keeps its models
+const systemModelsDir = "/usr/share/ollama/.ollama/models"
+
+// blobSearchDirs returns the models directories a blob may be in, in the order they're checked: OLLAMA_MODELS,
+// the configured directory, the per user directory and the system directory
+func blobSearchDirs(configured string) []string {
+	candidates := []string{
+		os.Getenv("OLLAMA_MODELS"),
+		configured,
+		filepath.Join(utils.GetHomeDir(), ".ollama", "models"),
+	}
+	if runtime.GOOS == "linux" {
+		candidates = append(candidates, systemModelsDir)
+	}
+
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range candidates {
+		if dir == "" || seen[filepath.Clean(dir)] {
+			continue
+		}
+		seen[filepath.Clean(dir)] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// resolveBlobPath returns path if it exists, otherwise the first of dirs that has a blob with the same name.
+// The error lists every directory searched.
+func resolveBlobPath(path string, dirs []string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	blob := filepath.Base(path)
+	searched := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, "blobs", blob)
+		if _, err := os.Stat(candidate); err == nil {
+			logging.DebugLogger.Printf("Blob %s not found at %s, using %s\n", blob, path, candidate)
+			return candidate, nil
+		}
+		searched = append(searched, filepath.Join(dir, "blobs"))
+	}
+	return "", fmt.Errorf("blob %s not found at %s or in any of: %s", blob, path, strings.Join(searched, ", "))
+}
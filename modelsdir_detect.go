@@ -0,0 +1,232 @@
+// modelsdir_detect.go works out which models directory the local Ollama server uses when -ollama-dir isn't given.
+// The per user ~/.ollama/models is often wrong on Linux, where the install script runs Ollama as its own user and
+// OLLAMA_MODELS is commonly set in the systemd unit or a container's volume.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// autoModelsDir is the -ollama-dir value that detects the models directory again rather than using the cached one
+const autoModelsDir = "auto"
+
+// detectCommandTimeout bounds how long systemctl and docker get to answer
+const detectCommandTimeout = 5 * time.Second
+
+// procDir and runDetectCommand are replaced in tests
+var (
+	procDir          = "/proc"
+	runDetectCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, name, args...).Output()
+	}
+)
+
+// resolveModelsDir returns the models directory and where it came from: the -ollama-dir flag, OLLAMA_MODELS, the
+// directory cached in the config by an earlier detection while it still exists, or a new detection. A detected
+// directory is cached in the config so the detection only runs once.
+func resolveModelsDir(ctx context.Context, flagValue string, cfg *config.Config) (string, string) {
+	if flagValue != "" && flagValue != autoModelsDir {
+		return flagValue, "-ollama-dir"
+	}
+	if dir := os.Getenv("OLLAMA_MODELS"); dir != "" {
+		return dir, "OLLAMA_MODELS"
+	}
+	if flagValue != autoModelsDir && cfg.OllamaModelsDir != "" && isDir(cfg.OllamaModelsDir) {
+		return cfg.OllamaModelsDir, "ollama_models_dir in the config"
+	}
+
+	dir, source := detectModelsDir(ctx, cfg.DockerContainer)
+	if source == "" {
+		return defaultModelsDir(), "the default location"
+	}
+	logging.InfoLogger.Printf("Detected the Ollama models directory %s from %s\n", dir, source)
+	if dir != cfg.OllamaModelsDir {
+		cfg.OllamaModelsDir = dir
+		if err := config.SetValues(map[string]any{"ollama_models_dir": dir}); err != nil {
+			logging.ErrorLogger.Printf("Error caching the models directory in the config: %v\n", err)
+		}
+	}
+	return dir, source
+}
+
+// detectModelsDir looks for the models directory of a running or installed Ollama server, returning an empty source
+// when none of the checks find it
+func detectModelsDir(ctx context.Context, dockerContainer string) (string, string) {
+	if runtime.GOOS == "linux" {
+		if dir, pid := processModelsDir(); dir != "" {
+			return dir, "the environment of the running ollama process (pid " + pid + ")"
+		}
+		if dir := systemdModelsDir(ctx); dir != "" {
+			return dir, "the ollama systemd unit"
+		}
+	}
+	if dockerContainer != "" && !strings.EqualFold(dockerContainer, "false") {
+		if dir := dockerModelsDir(ctx, dockerContainer); dir != "" {
+			return dir, "the volumes of the " + dockerContainer + " docker container"
+		}
+	}
+	return "", ""
+}
+
+// defaultModelsDir is the per user models directory, or the install script's system directory on Linux when only
+// that one exists
+func defaultModelsDir() string {
+	dir := filepath.Join(utils.GetHomeDir(), ".ollama", "models")
+	if runtime.GOOS == "linux" && !isDir(dir) && isDir(systemModelsDir) {
+		return systemModelsDir
+	}
+	return dir
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// processModelsDir reads the models directory from the environment of a running ollama process. Only processes of the
+// same user (or every process for root) can be read, the others are skipped.
+func processModelsDir() (dir, pid string) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return "", ""
+	}
+	for _, entry := range entries {
+		pid := entry.Name()
+		if strings.Trim(pid, "0123456789") != "" {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(procDir, pid, "comm"))
+		if err != nil || strings.TrimSpace(string(comm)) != "ollama" {
+			continue
+		}
+		environ, err := os.ReadFile(filepath.Join(procDir, pid, "environ"))
+		if err != nil {
+			logging.DebugLogger.Printf("Can't read the environment of ollama process %s: %v\n", pid, err)
+			continue
+		}
+		env := make(map[string]string)
+		for _, variable := range bytes.Split(environ, []byte{0}) {
+			if key, value, ok := strings.Cut(string(variable), "="); ok {
+				env[key] = value
+			}
+		}
+		if env["OLLAMA_MODELS"] != "" {
+			return env["OLLAMA_MODELS"], pid
+		}
+		if env["HOME"] != "" {
+			return filepath.Join(env["HOME"], ".ollama", "models"), pid
+		}
+	}
+	return "", ""
+}
+
+// systemdModelsDir reads OLLAMA_MODELS from the ollama systemd unit, falling back to the install script's directory
+// when the unit runs as the ollama user without it
+func systemdModelsDir(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, detectCommandTimeout)
+	defer cancel()
+	out, err := runDetectCommand(ctx, "systemctl", "show", "ollama", "-p", "Environment", "-p", "User", "-p", "LoadState")
+	if err != nil {
+		logging.DebugLogger.Printf("Can't read the ollama systemd unit: %v\n", err)
+		return ""
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	if props["LoadState"] != "loaded" {
+		return ""
+	}
+	// Environment is space separated, values with spaces in them are quoted
+	for _, variable := range splitQuotedFields(props["Environment"]) {
+		if value, ok := strings.CutPrefix(variable, "OLLAMA_MODELS="); ok && value != "" {
+			return value
+		}
+	}
+	if props["User"] == "ollama" {
+		return systemModelsDir
+	}
+	return ""
+}
+
+// splitQuotedFields splits on spaces, keeping the spaces inside double quotes and dropping the quotes
+func splitQuotedFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// dockerModelsDir maps the models directory inside a container, OLLAMA_MODELS or the image's default, to the host
+// directory mounted over it
+func dockerModelsDir(ctx context.Context, container string) string {
+	ctx, cancel := context.WithTimeout(ctx, detectCommandTimeout)
+	defer cancel()
+	out, err := runDetectCommand(ctx, "docker", "inspect", container)
+	if err != nil {
+		logging.DebugLogger.Printf("Can't inspect the %s docker container: %v\n", container, err)
+		return ""
+	}
+	var inspect []struct {
+		Config struct {
+			Env []string
+		}
+		Mounts []struct {
+			Source      string
+			Destination string
+		}
+	}
+	if err := json.Unmarshal(out, &inspect); err != nil || len(inspect) == 0 {
+		logging.DebugLogger.Printf("Can't read the inspection of the %s docker container: %v\n", container, err)
+		return ""
+	}
+
+	inside := "/root/.ollama/models"
+	for _, variable := range inspect[0].Config.Env {
+		if value, ok := strings.CutPrefix(variable, "OLLAMA_MODELS="); ok && value != "" {
+			inside = value
+		}
+	}
+	// The deepest mount containing the directory is the one it's on
+	var dir string
+	longest := -1
+	for _, mount := range inspect[0].Mounts {
+		destination := strings.TrimSuffix(mount.Destination, "/")
+		rel, err := filepath.Rel(destination, inside)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") || len(destination) <= longest {
+			continue
+		}
+		dir, longest = filepath.Join(mount.Source, rel), len(destination)
+	}
+	return dir
+}
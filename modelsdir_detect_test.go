@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestDetectModelsDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OLLAMA_MODELS", "")
+	defer func(dir string, run func(context.Context, string, ...string) ([]byte, error)) {
+		procDir, runDetectCommand = dir, run
+	}(procDir, runDetectCommand)
+
+	// A running ollama process's environment
+	procDir = t.TempDir()
+	writeProc := func(pid, comm, environ string) {
+		os.MkdirAll(filepath.Join(procDir, pid), 0755)
+		os.WriteFile(filepath.Join(procDir, pid, "comm"), []byte(comm+"\n"), 0644)
+		os.WriteFile(filepath.Join(procDir, pid, "environ"), []byte(environ), 0644)
+	}
+	writeProc("12", "bash", "OLLAMA_MODELS=/wrong\x00")
+	writeProc("34", "ollama", "HOME=/home/me\x00OLLAMA_MODELS=/data/ollama\x00")
+	if dir, pid := processModelsDir(); dir != "/data/ollama" || pid != "34" {
+		t.Errorf("processModelsDir() = %q, %q, want /data/ollama from pid 34", dir, pid)
+	}
+	writeProc("34", "ollama", "HOME=/home/me\x00")
+	if dir, _ := processModelsDir(); dir != "/home/me/.ollama/models" {
+		t.Errorf("processModelsDir() without OLLAMA_MODELS = %q, want the process's home directory", dir)
+	}
+	os.RemoveAll(filepath.Join(procDir, "34"))
+
+	// The systemd unit, then the docker container's volumes
+	outputs := map[string]string{
+		"systemctl": "LoadState=loaded\nUser=ollama\nEnvironment=\"PATH=/usr/bin /bin\" OLLAMA_MODELS=/srv/models OLLAMA_HOST=0.0.0.0\n",
+		"docker":    `[{"Config":{"Env":["OLLAMA_MODELS=/models/ollama"]},"Mounts":[{"Source":"/var/lib/docker/volumes/x","Destination":"/root"},{"Source":"/mnt/big","Destination":"/models"}]}]`,
+	}
+	runDetectCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if out, ok := outputs[name]; ok {
+			return []byte(out), nil
+		}
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	if dir := systemdModelsDir(context.Background()); dir != "/srv/models" {
+		t.Errorf("systemdModelsDir() = %q, want OLLAMA_MODELS from the unit", dir)
+	}
+	outputs["systemctl"] = "LoadState=loaded\nUser=ollama\nEnvironment=OLLAMA_HOST=0.0.0.0\n"
+	if dir := systemdModelsDir(context.Background()); dir != systemModelsDir {
+		t.Errorf("systemdModelsDir() without OLLAMA_MODELS = %q, want the ollama user's directory", dir)
+	}
+	outputs["systemctl"] = "LoadState=not-found\nUser=\nEnvironment=\n"
+	if dir := systemdModelsDir(context.Background()); dir != "" {
+		t.Errorf("systemdModelsDir() without a unit = %q, want nothing", dir)
+	}
+	if dir := dockerModelsDir(context.Background(), "ollama"); dir != "/mnt/big/ollama" {
+		t.Errorf("dockerModelsDir() = %q, want the host side of the volume", dir)
+	}
+
+	// A detected directory is cached in the config and used while it exists
+	cached := t.TempDir()
+	outputs["docker"] = fmt.Sprintf(`[{"Config":{"Env":[]},"Mounts":[{"Source":%q,"Destination":"/root/.ollama"}]}]`, filepath.Dir(cached))
+	cfg := &config.Config{DockerContainer: "ollama"}
+	want := filepath.Join(filepath.Dir(cached), "models")
+	os.MkdirAll(want, 0755)
+	if dir, source := resolveModelsDir(context.Background(), "", cfg); dir != want || !strings.Contains(source, "docker") || cfg.OllamaModelsDir != want {
+		t.Errorf("resolveModelsDir() = %q, %q, want %s from docker cached in the config", dir, source, want)
+	}
+	delete(outputs, "docker")
+	if dir, source := resolveModelsDir(context.Background(), "", cfg); dir != want || !strings.Contains(source, "config") {
+		t.Errorf("resolveModelsDir() with a cached directory = %q, %q, want the cached one", dir, source)
+	}
+	if dir, source := resolveModelsDir(context.Background(), autoModelsDir, cfg); dir == want || source != "the default location" {
+		t.Errorf("resolveModelsDir(auto) = %q, %q, want it detected again", dir, source)
+	}
+	if dir, _ := resolveModelsDir(context.Background(), "/custom", cfg); dir != "/custom" {
+		t.Errorf("resolveModelsDir() with -ollama-dir = %q, want the flag's value", dir)
+	}
+	t.Setenv("OLLAMA_MODELS", "/env")
+	if dir, source := resolveModelsDir(context.Background(), "", cfg); dir != "/env" || source != "OLLAMA_MODELS" {
+		t.Errorf("resolveModelsDir() with OLLAMA_MODELS = %q, %q", dir, source)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckModelsDir(t *testing.T) {
+	root := t.TempDir()
+	realDir := filepath.Join(root, "models")
+	if err := os.MkdirAll(filepath.Join(realDir, "manifests"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An unmounted drive leaves its mount point as an empty directory
+	unmounted := filepath.Join(root, "ssd")
+	if err := os.Mkdir(unmounted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	linkedUnmounted := filepath.Join(root, "linked-ssd")
+	if err := os.Symlink(unmounted, linkedUnmounted); err != nil {
+		t.Fatal(err)
+	}
+	linked := filepath.Join(root, "linked")
+	if err := os.Symlink(realDir, linked); err != nil {
+		t.Fatal(err)
+	}
+	dangling := filepath.Join(root, "dangling")
+	if err := os.Symlink(filepath.Join(root, "unmounted", "ollama"), dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		wantAvailable bool
+		wantSymlink   bool
+		wantEmpty     bool
+		wantBanner    string
+	}{
+		{name: "Plain directory", path: realDir, wantAvailable: true},
+		{name: "Symlink to existing directory", path: linked, wantAvailable: true, wantSymlink: true},
+		{
+			name:        "Dangling symlink",
+			path:        dangling,
+			wantSymlink: true,
+			wantBanner:  "Models directory points to " + filepath.Join(root, "unmounted", "ollama") + " which is not available",
+		},
+		{name: "Missing directory", path: filepath.Join(root, "missing")},
+		{
+			name:       "Empty mount point",
+			path:       unmounted,
+			wantEmpty:  true,
+			wantBanner: "Models directory " + unmounted + " has no models, the drive it's on may not be mounted",
+		},
+		{
+			name:        "Symlink to an empty mount point",
+			path:        linkedUnmounted,
+			wantSymlink: true,
+			wantEmpty:   true,
+			wantBanner:  "Models directory " + linkedUnmounted + " has no models, the drive it's on may not be mounted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := checkModelsDir(tt.path)
+			if info.Available != tt.wantAvailable {
+				t.Errorf("Available = %v, want %v (err: %v)", info.Available, tt.wantAvailable, info.Err)
+			}
+			if info.IsSymlink != tt.wantSymlink {
+				t.Errorf("IsSymlink = %v, want %v", info.IsSymlink, tt.wantSymlink)
+			}
+			if info.Empty != tt.wantEmpty {
+				t.Errorf("Empty = %v, want %v", info.Empty, tt.wantEmpty)
+			}
+			if got := info.banner(); got != tt.wantBanner {
+				t.Errorf("banner() = %q, want %q", got, tt.wantBanner)
+			}
+		})
+	}
+}
+
+func TestResolveBlobPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	envDir, configured := t.TempDir(), t.TempDir()
+	t.Setenv("OLLAMA_MODELS", envDir)
+
+	dirs := blobSearchDirs(configured)
+	if len(dirs) < 3 || dirs[0] != envDir || dirs[1] != configured || dirs[2] != filepath.Join(home, ".ollama", "models") {
+		t.Fatalf("blobSearchDirs() = %v, want OLLAMA_MODELS, the configured dir then the home dir", dirs)
+	}
+
+	blob := "sha256-" + strings.Repeat("a", 64)
+	if err := os.MkdirAll(filepath.Join(configured, "blobs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	found := filepath.Join(configured, "blobs", blob)
+	if err := os.WriteFile(found, []byte("gguf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"path exists", found, found, false},
+		{"found in another directory", "/usr/share/ollama/models/blobs/" + blob, found, false},
+		{"not found anywhere", "/data/ollama/models/blobs/sha256-" + strings.Repeat("b", 64), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveBlobPath(tt.path, dirs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveBlobPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveBlobPath() = %q, want %q", got, tt.want)
+			}
+			if err != nil && (!strings.Contains(err.Error(), envDir) || !strings.Contains(err.Error(), configured)) {
+				t.Errorf("resolveBlobPath() error %q doesn't list the directories searched", err)
+			}
+		})
+	}
+}
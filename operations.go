@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -19,44 +20,33 @@ import (
 	"github.com/ollama/ollama/api"
 	"github.com/sammcj/gollama/config"
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pkg/gollama"
+	"github.com/sammcj/gollama/showcache"
 	"github.com/sammcj/gollama/utils"
 )
 
-func runModel(model string, cfg *config.Config) tea.Cmd {
-	// if config is set to run in docker container, run the mode using runDocker
-	if cfg.DockerContainer != "" && strings.ToLower(cfg.DockerContainer) != "false" {
-		return runDocker(cfg.DockerContainer, model)
-	}
-
-	ollamaPath, err := exec.LookPath("ollama")
+// runModel runs the model interactively with the command from the config, ollama run unless run_command_template or
+// docker_container say otherwise, and the model's run profile or the override when it isn't nil
+func runModel(model string, cfg *config.Config, override *config.RunProfile) tea.Cmd {
+	args, env, err := runCommandFor(model, cfg, override)
 	if err != nil {
-		logging.ErrorLogger.Printf("error finding ollama binary: %v\n", err)
-		logging.ErrorLogger.Printf("If you're running Ollama in a container, make sure you updated the config file with the container name\n")
-		return nil
+		logging.ErrorLogger.Println(err)
+		return func() tea.Msg { return runFinishedMessage{err} }
 	}
-	c := exec.Command(ollamaPath, "run", model)
-	return tea.ExecProcess(c, func(err error) tea.Msg {
-		if err != nil {
-			logging.ErrorLogger.Printf("error running model: %v\n", err)
-		}
-		return runFinishedMessage{err}
-	})
-}
-
-func runDocker(container string, model string) tea.Cmd {
-	dockerPath, err := exec.LookPath("docker")
+	path, err := exec.LookPath(args[0])
 	if err != nil {
-		logging.ErrorLogger.Printf("error finding docker binary: %v\n", err)
-		return nil
+		err = fmt.Errorf("can't run %s, %s isn't installed or in the PATH. If Ollama runs in a container, set docker_container or run_command_template in the config", model, args[0])
+		logging.ErrorLogger.Println(err)
+		return func() tea.Msg { return runFinishedMessage{err} }
+	}
+	logging.DebugLogger.Printf("Running %s with %v and environment %v\n", model, args, env)
+	c := exec.Command(path, args[1:]...)
+	if len(env) > 0 {
+		c.Env = append(os.Environ(), env...)
 	}
-
-	// parse the params into a list of arguments to supply to docker exec
-	args := []string{"exec", "-it", container, "ollama", "run", model}
-
-	c := exec.Command(dockerPath, args...)
 	return tea.ExecProcess(c, func(err error) tea.Msg {
 		if err != nil {
-			logging.ErrorLogger.Printf("error running model in docker container: %v\n", err)
+			logging.ErrorLogger.Printf("error running model: %v\n", err)
 		}
 		return runFinishedMessage{err}
 	})
@@ -72,125 +62,99 @@ func deleteModel(client *api.Client, name string) error {
 		logging.ErrorLogger.Printf("Error deleting model %s: %v\n", name, err)
 		return fmt.Errorf("error deleting model %s: %v", name, err)
 	}
+	showcache.Invalidate(client, name)
 
 	logging.InfoLogger.Printf("Successfully deleted model: %s\n", name)
+	recordHistory("delete", name, "")
 	return nil
 }
 
-func (m *AppModel) startPushModel(modelName string) tea.Cmd {
+// startPushModel starts the progress bar for pushing a model and runs push, which does the push. push is made with
+// the progress state of beginPush.
+func (m *AppModel) startPushModel(modelName string, push tea.Cmd) tea.Cmd {
 	logging.InfoLogger.Printf("Pushing model: %s\n", modelName)
 
 	// Initialize the progress model
 	m.progress = progress.New(progress.WithDefaultGradient())
 
-	return tea.Batch(
-		tea.Tick(time.Millisecond*100, func(t time.Time) tea.Msg {
-			return progressMsg{modelName: modelName}
-		}),
-		m.pushModelCmd(modelName),
-	)
+	return tea.Batch(m.pushProgressTick(), push)
 }
 
-func (m *AppModel) startPullModel(modelName string) tea.Cmd {
-	return func() tea.Msg {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		progressChan := make(chan float64)
-		errChan := make(chan error)
-
-		go func() {
-			req := &api.PullRequest{Name: modelName}
-			err := m.client.Pull(ctx, req, func(resp api.ProgressResponse) error {
-				if !m.pulling {
-					return context.Canceled
-				}
-				progress := float64(resp.Completed) / float64(resp.Total)
-				m.pullProgress = progress
-				progressChan <- progress
-				return nil
-			})
-
-			if err == context.Canceled {
-				errChan <- fmt.Errorf("pull cancelled")
-				return
-			}
-			if err != nil {
-				errChan <- err
-				return
-			}
-			close(progressChan)
-		}()
+// beginPush sets up the progress of a new push or copy to another host, cancelling the one before. It returns the
+// context to push in, which ctrl+c or esc cancel, and the progress callback, which only ever updates this push's
+// layers and feed so a cancelled push can't change the progress of the next one.
+func (m *AppModel) beginPush() (context.Context, api.PushProgressFunc) {
+	if m.cancelPush != nil {
+		m.cancelPush()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelPush = cancel
+	m.pushLayers = newPushLayers()
+	m.pushFeed = newProgressFeed()
+	m.pushProgress = 0
+	return ctx, trackPush(m.pushLayers, m.pushFeed)
+}
 
-		// Start a ticker to send progress updates
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
+// pushProgressTick schedules the next redraw of the current push's progress
+func (m *AppModel) pushProgressTick() tea.Cmd {
+	feed := m.pushFeed
+	return tea.Tick(m.progressRefresh(), func(time.Time) tea.Msg {
+		return progressMsg{feed: feed}
+	})
+}
 
-		for {
-			select {
-			case err := <-errChan:
-				if err != nil {
-					return pullErrorMsg{err}
-				}
-				return pullSuccessMsg{modelName}
-			case <-ticker.C:
-				return progressMsg{
-					modelName: modelName,
-					progress:  m.pullProgress,
-				}
-			case progress := <-progressChan:
-				if progress >= 1.0 {
-					return pullSuccessMsg{modelName}
-				}
-			}
-		}
-	}
+// cancelRunningPush cancels the push or copy in flight, its command drops its result once it returns
+func (m *AppModel) cancelRunningPush() {
+	logging.InfoLogger.Println("Push cancelled by user")
+	m.finishPush()
+	m.transferHost = ""
+	m.message = "Push cancelled"
 }
 
-func (m *AppModel) pushModelCmd(modelName string) tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		req := &api.PushRequest{Name: modelName}
-		err := m.client.Push(ctx, req, func(resp api.ProgressResponse) error {
-			m.progress.SetPercent(float64(resp.Completed) / float64(resp.Total))
+// startPullModel pulls a model, feeding its progress to the bar until it finishes or ctrl+c cancels it
+func (m *AppModel) startPullModel(modelName string) tea.Cmd {
+	ctx, tick := m.beginPull()
+	client, layers, feed := m.client, m.pullLayers, m.pullFeed
+	return tea.Batch(tick, func() tea.Msg {
+		err := client.Pull(ctx, &api.PullRequest{Name: modelName}, trackPull(layers, feed))
+		if ctx.Err() != nil {
+			// Whatever cancelled the pull has already reset the pull state, which may now belong to another pull
+			logging.InfoLogger.Printf("Pull of %s cancelled\n", modelName)
 			return nil
-		})
+		}
 		if err != nil {
-			return pushErrorMsg{err}
+			return pullErrorMsg{err}
 		}
-		return pushSuccessMsg{modelName}
-	}
+		return pullSuccessMsg{modelName}
+	})
 }
 
-func (m *AppModel) pullModelCmd(modelName string) tea.Cmd {
+func (m *AppModel) pushModelCmd(modelName string) tea.Cmd {
+	ctx, track := m.beginPush()
+	client := m.client
 	return func() tea.Msg {
-		ctx := context.Background()
-		req := &api.PullRequest{Name: modelName}
-		err := m.client.Pull(ctx, req, func(resp api.ProgressResponse) error {
-			m.pullProgress = float64(resp.Completed) / float64(resp.Total)
+		req := &api.PushRequest{Name: modelName}
+		err := client.Push(ctx, req, track)
+		if ctx.Err() != nil {
+			logging.InfoLogger.Printf("Push of %s cancelled\n", modelName)
 			return nil
-		})
+		}
 		if err != nil {
-			return pullErrorMsg{err}
+			return pushErrorMsg{err: pushError(modelName, err)}
 		}
-		return pullSuccessMsg{modelName}
+		return pushSuccessMsg{modelName: modelName}
 	}
 }
 
-func linkModel(modelName, lmStudioModelsDir string, noCleanup bool, dryRun bool, client *api.Client) (string, error) {
-	modelPath, err := getModelPath(modelName, client)
+func linkModel(modelName, ollamaModelsDir, lmStudioModelsDir string, cleanup symlinkCleanup, dryRun bool, client *api.Client) (string, error) {
+	modelPaths, err := getModelPaths(modelName, ollamaModelsDir, client)
 	if err != nil {
 		return "", fmt.Errorf("error getting model path for %s: %v", modelName, err)
 	}
+	// Vision models have their projector in a second FROM line
+	modelPath, projectorPaths := modelPaths[0], modelPaths[1:]
 
-	parts := strings.Split(modelName, ":")
-	author := "unknown"
-	if len(parts) > 1 {
-		author = strings.ReplaceAll(parts[0], "/", "-")
-	}
-
-	lmStudioModelName := strings.ReplaceAll(strings.ReplaceAll(modelName, ":", "-"), "_", "-")
-	lmStudioModelDir := filepath.Join(lmStudioModelsDir, author, lmStudioModelName+"-GGUF")
+	lmStudioModelDir, lmStudioModelPath := lmStudioLinkPath(modelName, lmStudioModelsDir)
 
 	// Check if the model path is a valid file
 	fileInfo, err := os.Stat(modelPath)
@@ -199,12 +163,14 @@ func linkModel(modelName, lmStudioModelsDir string, noCleanup bool, dryRun bool,
 	}
 
 	// Check if the symlink already exists and is valid
-	lmStudioModelPath := filepath.Join(lmStudioModelDir, filepath.Base(lmStudioModelName)+".gguf")
 	if _, err := os.Lstat(lmStudioModelPath); err == nil {
 		if isValidSymlink(lmStudioModelPath, modelPath) {
+			if err := linkProjectors(projectorPaths, lmStudioModelPath, dryRun); err != nil {
+				return "", err
+			}
 			message := "Model %s is already symlinked to %s"
 			logging.InfoLogger.Printf(message+"\n", modelName, lmStudioModelPath)
-			return "", nil
+			return fmt.Sprintf(message, modelName, lmStudioModelPath), nil
 		}
 		// Remove the invalid symlink
 		err = os.Remove(lmStudioModelPath)
@@ -268,103 +234,135 @@ func linkModel(modelName, lmStudioModelsDir string, noCleanup bool, dryRun bool,
 			logging.ErrorLogger.Printf(message+"\n", modelName, err)
 			return "", fmt.Errorf(message, modelName, err)
 		}
-		if !noCleanup {
-			cleanBrokenSymlinks(lmStudioModelsDir)
+		if err := linkProjectors(projectorPaths, lmStudioModelPath, false); err != nil {
+			return "", err
 		}
+		cleanBrokenSymlinks(lmStudioModelsDir, ollamaModelsDir, cleanup)
 		message := "Symlinked %s to %s"
 		logging.InfoLogger.Printf(message+"\n", modelName, lmStudioModelPath)
 		return "", nil
 	}
 }
 
-func getModelPath(modelName string, client *api.Client) (string, error) {
-	ctx := context.Background()
-	req := &api.ShowRequest{Name: modelName}
-	resp, err := client.Show(ctx, req)
+// modelsNamed returns the models with the given names in the order given, failing if any isn't found
+func modelsNamed(models []Model, names []string) ([]Model, error) {
+	byName := make(map[string]Model, len(models))
+	for _, model := range models {
+		byName[model.Name] = model
+	}
+	named := make([]Model, 0, len(names))
+	for _, name := range names {
+		model, ok := byName[name]
+		if !ok {
+			model, ok = byName[name+":latest"]
+		}
+		if !ok {
+			return nil, fmt.Errorf("model %s not found", name)
+		}
+		named = append(named, model)
+	}
+	return named, nil
+}
+
+// lmStudioLinkPath returns the directory and symlink path a model is linked to under the LM Studio models directory
+func lmStudioLinkPath(modelName, lmStudioModelsDir string) (string, string) {
+	parts := strings.Split(modelName, ":")
+	author := "unknown"
+	if len(parts) > 1 {
+		author = strings.ReplaceAll(parts[0], "/", "-")
+	}
+
+	lmStudioModelName := strings.ReplaceAll(strings.ReplaceAll(modelName, ":", "-"), "_", "-")
+	lmStudioModelDir := filepath.Join(lmStudioModelsDir, author, lmStudioModelName+"-GGUF")
+	return lmStudioModelDir, filepath.Join(lmStudioModelDir, filepath.Base(lmStudioModelName)+".gguf")
+}
+
+// projectorLinkPath returns the path a vision projector is linked to next to the model's link, LM Studio
+// recognises projectors by the mmproj prefix
+func projectorLinkPath(lmStudioModelPath string, index int) string {
+	name := "mmproj-" + filepath.Base(lmStudioModelPath)
+	if index > 0 {
+		name = fmt.Sprintf("mmproj-%d-%s", index+1, filepath.Base(lmStudioModelPath))
+	}
+	return filepath.Join(filepath.Dir(lmStudioModelPath), name)
+}
+
+// linkProjectors symlinks a model's vision projectors next to its link in the LM Studio models directory,
+// leaving any that are already linked alone
+func linkProjectors(projectorPaths []string, lmStudioModelPath string, dryRun bool) error {
+	for i, projectorPath := range projectorPaths {
+		linkPath := projectorLinkPath(lmStudioModelPath, i)
+		if _, err := os.Lstat(linkPath); err == nil {
+			if isValidSymlink(linkPath, projectorPath) {
+				continue
+			}
+			if err := os.Remove(linkPath); err != nil {
+				return fmt.Errorf("failed to remove invalid symlink %s: %v", linkPath, err)
+			}
+		}
+		if dryRun {
+			logging.InfoLogger.Printf("[DRY RUN] Would symlink projector %s to %s\n", projectorPath, linkPath)
+			continue
+		}
+		if err := os.Symlink(projectorPath, linkPath); err != nil {
+			message := "failed to symlink projector %s: %v"
+			logging.ErrorLogger.Printf(message+"\n", projectorPath, err)
+			return fmt.Errorf(message, projectorPath, err)
+		}
+		logging.InfoLogger.Printf("Symlinked projector %s to %s\n", projectorPath, linkPath)
+	}
+	return nil
+}
+
+// getModelPath returns the local path of a model's blob from the FROM line of its modelfile. The path Ollama reports
+// is where the server stores it, so if it doesn't exist here the blob is looked for in the other models directories.
+func getModelPath(modelName, ollamaModelsDir string, client *api.Client) (string, error) {
+	paths, err := getModelPaths(modelName, ollamaModelsDir, client)
 	if err != nil {
 		return "", err
 	}
+	return paths[0], nil
+}
 
-	output := []byte(resp.Modelfile)
+// getModelPaths returns the local paths of every FROM line of a model's modelfile, the model's blob first followed by
+// any vision projectors
+func getModelPaths(modelName, ollamaModelsDir string, client *api.Client) ([]string, error) {
+	ctx := context.Background()
+	resp, err := showcache.For(client).Show(ctx, modelName)
+	if err != nil {
+		return nil, err
+	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "FROM ") {
-			return strings.TrimSpace(line[5:]), nil
+	var paths []string
+	for _, from := range fromPaths(resp.Modelfile) {
+		path, err := resolveBlobPath(from, blobSearchDirs(ollamaModelsDir))
+		if err != nil {
+			return nil, err
 		}
+		paths = append(paths, path)
 	}
-	message := "failed to get model path for %s: no 'FROM' line in output"
-	logging.ErrorLogger.Printf(message+"\n", modelName)
-	return "", fmt.Errorf(message, modelName)
+	if len(paths) == 0 {
+		message := "failed to get model path for %s: no 'FROM' line in output"
+		logging.ErrorLogger.Printf(message+"\n", modelName)
+		return nil, fmt.Errorf(message, modelName)
+	}
+	return paths, nil
 }
 
 func getModelParams(modelName string, client *api.Client) (map[string]string, string, error) {
-  logging.InfoLogger.Printf("Getting parameters for model: %s\n", modelName)
-  ctx := context.Background()
-  req := &api.ShowRequest{Name: modelName}
-  resp, err := client.Show(ctx, req)
-  if err != nil {
-      logging.ErrorLogger.Printf("Error getting parameters for model %s: %v\n", modelName, err)
-      return nil, "", err
-  }
-  output := []byte(resp.Modelfile)
-  lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-  params := make(map[string]string)
-  var template string
-
-  for _, line := range lines {
-      if strings.HasPrefix(line, "TEMPLATE") {
-          // Extract the TEMPLATE content
-          template = strings.TrimPrefix(line, "TEMPLATE ")
-          template = strings.Trim(template, "\"")
-      } else if strings.HasPrefix(line, "PARAMETER") {
-          parts := strings.SplitN(line, " ", 2)
-          if len(parts) == 2 {
-              key := parts[1]
-              value := strings.TrimSpace(parts[1])
-              params[key] = value
-          }
-      }
-  }
-  return params, template, nil
-}
-
-func cleanBrokenSymlinks(lmStudioModelsDir string) {
-	err := filepath.Walk(lmStudioModelsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			files, err := os.ReadDir(path)
-			if err != nil {
-				return err
-			}
-			if len(files) == 0 {
-				logging.InfoLogger.Printf("Removing empty directory: %s\n", path)
-				err = os.Remove(path)
-				if err != nil {
-					return err
-				}
-			}
-		} else if info.Mode()&os.ModeSymlink != 0 {
-			linkPath, err := os.Readlink(path)
-			if err != nil {
-				return err
-			}
-			if !isValidSymlink(path, linkPath) {
-				logging.InfoLogger.Printf("Removing invalid symlink: %s\n", path)
-				err = os.Remove(path)
-				if err != nil {
-					return err
-				}
-			}
-		}
-		return nil
-	})
+	logging.InfoLogger.Printf("Getting parameters for model: %s\n", modelName)
+	resp, err := showcache.For(client).Show(context.Background(), modelName)
 	if err != nil {
-		logging.ErrorLogger.Printf("Error walking LM Studio models directory: %v\n", err)
-		return
+		logging.ErrorLogger.Printf("Error getting parameters for model %s: %v\n", modelName, err)
+		return nil, "", err
+	}
+
+	params := make(map[string]string)
+	for key, values := range gollama.ExtractParameters(resp.Modelfile) {
+		params[key] = strings.Join(values, ", ")
 	}
+	template, _ := gollama.ExtractTemplateAndSystem(resp.Modelfile)
+	return params, template, nil
 }
 
 func isValidSymlink(symlinkPath, targetPath string) bool {
@@ -428,38 +426,95 @@ func cleanupSymlinkedModels(lmStudioModelsDir string) {
 	}
 }
 
-func copyModel(m *AppModel, client *api.Client, oldName string, newName string) {
+// errModelExists is returned when a copy or rename would overwrite an existing model without being asked to
+var errModelExists = errors.New("model already exists")
+
+// normaliseModelName adds the implicit :latest tag so names can be compared the way Ollama resolves them
+func normaliseModelName(name string) string {
+	if !strings.Contains(name, ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// findModel returns the installed model with the given name, or nil if there isn't one
+func findModel(client *api.Client, name string) (*api.ListModelResponse, error) {
+	resp, err := client.List(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching models: %v", err)
+	}
+	for _, model := range resp.Models {
+		if normaliseModelName(model.Name) == normaliseModelName(name) {
+			return &model, nil
+		}
+	}
+	return nil, nil
+}
+
+// copyModelTo copies a model, refusing to replace an existing destination unless overwrite is set,
+// and checks the destination exists afterwards as some server versions don't report failed copies
+func copyModelTo(client *api.Client, oldName, newName string, overwrite bool) error {
+	if normaliseModelName(oldName) == normaliseModelName(newName) {
+		return fmt.Errorf("source and destination are the same model: %s", oldName)
+	}
+
+	existing, err := findModel(client, newName)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !overwrite {
+		return fmt.Errorf("%w: %s", errModelExists, newName)
+	}
+
 	ctx := context.Background()
 	req := &api.CopyRequest{
 		Source:      oldName,
 		Destination: newName,
 	}
-	err := client.Copy(ctx, req)
-	if err != nil {
+	if err := client.Copy(ctx, req); err != nil {
 		logging.ErrorLogger.Printf("Error copying model: %v\n", err)
-		return
+		return fmt.Errorf("error copying model %s to %s: %v", oldName, newName, err)
 	}
+	showcache.Invalidate(client, newName)
 
-	logging.InfoLogger.Printf("Successfully copied model: %s to %s\n", oldName, newName)
-
-	// Although the model has been copied, the model list has not been updated as the API does not return the new model which is annoying
-	resp, err := client.List(ctx)
+	copied, err := findModel(client, newName)
 	if err != nil {
-		logging.ErrorLogger.Printf("Error fetching models: %v\n", err)
-		return
+		return err
+	}
+	if copied == nil {
+		return fmt.Errorf("copy of %s to %s did not complete: destination not found", oldName, newName)
 	}
-	m.models = parseAPIResponse(resp)
-	m.refreshList()
 
+	logging.InfoLogger.Printf("Successfully copied model: %s to %s\n", oldName, newName)
+	recordHistory("copy", oldName, newName)
+	return nil
 }
 
-// A function that returns a list of models that contain a search term (case insensitive) in their name, for use by the cli flag -s
-func searchModels(models []Model, searchTerms ...string) {
-	logging.InfoLogger.Printf("Searching for models with terms: %v\n", searchTerms)
+// renameModelTo copies a model to its new name and only deletes the original once the copy has succeeded
+func renameModelTo(client *api.Client, oldName, newName string, overwrite bool) error {
+	if newName == "" {
+		return fmt.Errorf("no new name provided")
+	}
+	if err := copyModelTo(client, oldName, newName, overwrite); err != nil {
+		return err
+	}
+	return deleteModel(client, oldName)
+}
 
+func copyModel(client *api.Client, oldName string, newName string, overwrite bool) error {
+	return copyModelTo(client, oldName, newName, overwrite)
+}
+
+// matchingModels returns the models that match the search terms, sorted by name. Besides case insensitive substrings of
+// the name, terms can be predicates such as size>20GB, modified<2024-01-01 or re:llama.?3, see parseSearchQuery.
+func matchingModels(models []Model, searchTerms ...string) ([]Model, error) {
+	query, err := parseSearchQuery(searchTerms...)
+	if err != nil {
+		return nil, err
+	}
 	var searchResults []Model
 	for _, model := range models {
-		if containsAllTerms(model.Name, searchTerms...) {
+		if query.matches(model) {
 			searchResults = append(searchResults, model)
 		}
 	}
@@ -468,6 +523,18 @@ func searchModels(models []Model, searchTerms ...string) {
 	sort.Slice(searchResults, func(i, j int) bool {
 		return strings.ToLower(searchResults[i].Name) < strings.ToLower(searchResults[j].Name)
 	})
+	return searchResults, nil
+}
+
+// A function that prints the models that match the search terms, highlighting the plain text terms, for use by the cli flag -s
+func searchModels(models []Model, searchTerms ...string) error {
+	logging.InfoLogger.Printf("Searching for models with terms: %v\n", searchTerms)
+
+	searchResults, err := matchingModels(models, searchTerms...)
+	if err != nil {
+		return err
+	}
+	query, _ := parseSearchQuery(searchTerms...)
 
 	// Define adaptive styles
 	baseStyle := lipgloss.NewStyle().
@@ -477,14 +544,9 @@ func searchModels(models []Model, searchTerms ...string) {
 	headerStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#AAEE9A"})
 
-	// Colorize the matching parts of the model name
+	// Colorize the matching parts of the model name, predicates only filter so aren't highlighted
 	for i, model := range searchResults {
-		colorizedName := model.Name
-		for _, term := range searchTerms {
-			andTerms := strings.Split(term, "&")
-			colorizedName = highlightTerms(colorizedName, baseStyle, highlightStyle, andTerms)
-		}
-		searchResults[i].Name = colorizedName
+		searchResults[i].Name = highlightTerms(model.Name, baseStyle, highlightStyle, query.textTerms())
 	}
 
 	fmt.Println(headerStyle.Render("Search results for: " + highlightStyle.Render(strings.Join(searchTerms, " "))))
@@ -498,6 +560,7 @@ func searchModels(models []Model, searchTerms ...string) {
 		}
 		logging.InfoLogger.Printf("Found %d matching models\n", len(searchResults))
 	}
+	return nil
 }
 
 func highlightTerms(modelName string, baseStyle, highlightStyle lipgloss.Style, searchTerms []string) string {
@@ -543,49 +606,10 @@ func highlightTerms(modelName string, baseStyle, highlightStyle lipgloss.Style,
 	return result.String()
 }
 
-// Helper function to check if a string contains all search terms
-func containsAllTerms(s string, terms ...string) bool {
-	lowercaseS := strings.ToLower(s)
-	for _, term := range terms {
-		andTerms := strings.Split(term, "&")
-		if !containsAllAndTerms(lowercaseS, andTerms...) {
-			return false
-		}
-	}
-	return true
-}
-
-func containsAllAndTerms(s string, terms ...string) bool {
-	for _, term := range terms {
-		orTerms := strings.Split(term, "|")
-		if !containsAnyTerm(s, orTerms...) {
-			return false
-		}
-	}
-	return true
-}
-
-func containsAnyTerm(s string, terms ...string) bool {
-	for _, term := range terms {
-		if strings.Contains(s, strings.ToLower(term)) {
-			return true
-		}
-	}
-	return false
-}
-
-// A renameModel function that takes a selected model, prompts for a new name then calls copyModel, then deleteModel
-func renameModel(m *AppModel, oldName string, newName string) error {
-	if newName == "" {
-		return fmt.Errorf("no new name provided")
-	}
-	copyModel(m, m.client, oldName, newName)
-	deleteModel(m.client, oldName)
-	for i, model := range m.models {
-		if model.Name == oldName {
-			m.models = append(m.models[:i], m.models[i+1:]...)
-			break
-		}
+// A renameModel function that takes a selected model and a new name then copies and deletes it, leaving the original in place if the copy fails
+func renameModel(client *api.Client, oldName string, newName string, overwrite bool) error {
+	if err := renameModelTo(client, oldName, newName, overwrite); err != nil {
+		return err
 	}
 
 	message := fmt.Sprintf("Successfully renamed model %s to %s", oldName, newName)
@@ -593,28 +617,85 @@ func renameModel(m *AppModel, oldName string, newName string) error {
 	return nil
 }
 
-// Adding a new function get use client to get the running models
-func showRunningModels(client *api.Client) ([]table.Row, error) {
+// showRunningModels returns a table row for each running model
+func showRunningModels(apiURL string) ([]table.Row, error) {
 	ctx := context.Background()
-	resp, err := client.ListRunning(ctx)
+	models, err := listRunningModels(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching running models: %v", err)
 	}
 
 	var runningModels []table.Row
-	for _, model := range resp.Models {
+	for _, model := range models {
 		name := model.Name
-		size := float64(model.Size) / 1024 / 1024 / 1024
-		vram := float64(model.SizeVRAM) / 1024 / 1024 / 1024
-		until := model.ExpiresAt.Format("2006-01-02 15:04:05")
-
-		runningModels = append(runningModels, table.Row{name, fmt.Sprintf("%.2f GB", size), fmt.Sprintf("%.2f GB", vram), until})
+		runningModels = append(runningModels, runningModelRow(model))
 		logging.DebugLogger.Printf("Running model: %s\n", name)
 	}
 
 	return runningModels, nil
 }
 
+// minValidExpiry is the earliest ExpiresAt treated as real, Ollama reports the zero time or the epoch while a model is still loading
+var minValidExpiry = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sanitiseRunningModels cleans up the ListRunning response, which can be partial or inconsistent while models are
+// loading or after an OOM: negative sizes are clamped to zero, bogus expiry times are cleared and duplicate entries dropped
+func sanitiseRunningModels(resp *api.ProcessResponse) []api.ProcessModelResponse {
+	if resp == nil {
+		return nil
+	}
+
+	sanitised := false
+	seen := make(map[string]bool)
+	models := make([]api.ProcessModelResponse, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		key := model.Digest
+		if key == "" {
+			key = model.Name
+		}
+		if seen[key] {
+			sanitised = true
+			continue
+		}
+		seen[key] = true
+
+		if model.Size < 0 {
+			model.Size = 0
+			sanitised = true
+		}
+		if model.SizeVRAM < 0 {
+			model.SizeVRAM = 0
+			sanitised = true
+		}
+		if !model.ExpiresAt.IsZero() && model.ExpiresAt.Before(minValidExpiry) {
+			model.ExpiresAt = time.Time{}
+			sanitised = true
+		}
+		models = append(models, model)
+	}
+
+	if sanitised {
+		logging.DebugLogger.Printf("Sanitised malformed ListRunning response: %+v\n", resp.Models)
+	}
+	return models
+}
+
+// formatRunningSize formats a running model's size in GB, or n/a when Ollama didn't report one
+func formatRunningSize(size int64) string {
+	if size <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f GB", float64(size)/1024/1024/1024)
+}
+
+// formatExpiresAt formats when a running model will be unloaded, or unknown when Ollama didn't report it
+func formatExpiresAt(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "unknown"
+	}
+	return expiresAt.Format("2006-01-02 15:04:05")
+}
+
 func copyModelfile(modelName, newModelName string, client *api.Client) (string, error) {
 	logging.InfoLogger.Printf("Copying modelfile for model: %s\n", modelName)
 
@@ -694,51 +775,21 @@ func createModelFromModelfile(modelName, modelfilePath string, client *api.Clien
 		logging.ErrorLogger.Printf("Error creating model from modelfile %s: %v\n", modelfilePath, err)
 		return fmt.Errorf("error creating model from modelfile %s: %v", modelfilePath, err)
 	}
+	showcache.Invalidate(client, modelName)
 	logging.InfoLogger.Printf("Successfully created model from modelfile: %s\n", modelfilePath)
 	return nil
 
 }
 
-func unloadModel(client *api.Client, modelName string) (string, error) {
+func unloadModel(client *api.Client, apiURL, modelName string) (string, error) {
 	if client == nil {
 		return "", fmt.Errorf("invalid API client: client is nil")
 	}
-
-	ctx := context.Background()
-
-	// if the model is an embedding model, we can't call generaterequest on it, we have to call embeddingrequest
-	if strings.Contains(modelName, "embed") {
-		req := &api.EmbeddingRequest{
-			Model:     modelName,
-			KeepAlive: &api.Duration{Duration: 0},
-		}
-		logging.DebugLogger.Printf("Attempting to unload embedding model: %s\n", modelName)
-
-		_, err := client.Embeddings(ctx, req)
-		if err != nil {
-			logging.ErrorLogger.Printf("Failed to unload embedding model: %v\n", err)
-			return modelName, err
-		}
-	} else {
-
-		req := &api.GenerateRequest{
-			Model:     modelName,
-			Prompt:    "",
-			KeepAlive: &api.Duration{Duration: 0},
-		}
-		logging.DebugLogger.Printf("Attempting to unload model: %s\n", modelName)
-
-		response := func(resp api.GenerateResponse) error {
-			logging.DebugLogger.Printf("done")
-			return nil
-		}
-		err := client.Generate(ctx, req, response)
-		if err != nil {
-			logging.ErrorLogger.Printf("Failed to unload model: %v\n", err)
-			return "", err
-		}
+	logging.DebugLogger.Printf("Attempting to unload model: %s\n", modelName)
+	if err := gollama.Unload(context.Background(), client, apiHTTPClient, apiURL, modelName); err != nil {
+		logging.ErrorLogger.Printf("Failed to unload model: %v\n", err)
+		return "", err
 	}
-
 	return modelName, nil
 }
 
@@ -756,6 +807,30 @@ func editModelfile(client *api.Client, modelName string) (string, error) {
 	}
 	modelfileContent := showResp.Modelfile
 
+	newModelfileContent, err := openInEditor(modelName, modelfileContent)
+	if err != nil {
+		return "", err
+	}
+
+	// If there were no changes, return early
+	if newModelfileContent == modelfileContent {
+		return fmt.Sprintf("No changes made to model %s", modelName), nil
+	}
+
+	if err := updateModelfile(ctx, client, modelName, newModelfileContent); err != nil {
+		return "", err
+	}
+
+	recordHistory("edit", modelName, "")
+
+	// log to the console if we're not in a tea app
+	fmt.Printf("Model %s updated successfully\n", modelName)
+
+	return fmt.Sprintf("Model %s updated successfully, Press 'q' to return to the models list", modelName), nil
+}
+
+// openInEditor writes a modelfile to a temporary file, opens it in the user's editor and returns the saved content
+func openInEditor(modelName, modelfileContent string) (string, error) {
 	// Get editor from environment or config
 	editor := getEditor()
 	if editor == "" {
@@ -767,7 +842,7 @@ func editModelfile(client *api.Client, modelName string) (string, error) {
 	// Write the fetched content to a temporary file
 	tempDir := os.TempDir()
 	newModelfilePath := filepath.Join(tempDir, fmt.Sprintf("%s_modelfile.txt", modelName))
-	err = os.WriteFile(newModelfilePath, []byte(modelfileContent), 0644)
+	err := os.WriteFile(newModelfilePath, []byte(modelfileContent), 0644)
 	if err != nil {
 		return "", fmt.Errorf("error writing modelfile to temp file: %v", err)
 	}
@@ -788,32 +863,27 @@ func editModelfile(client *api.Client, modelName string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error reading edited modelfile: %v", err)
 	}
+	return string(newModelfileContent), nil
+}
 
-	// If there were no changes, return early
-	if string(newModelfileContent) == modelfileContent {
-		return fmt.Sprintf("No changes made to model %s", modelName), nil
-	}
-
-	// Update the model on the server with the new modelfile content
+// updateModelfile updates the model on the server with new modelfile content
+func updateModelfile(ctx context.Context, client *api.Client, modelName, modelfileContent string) error {
 	createReq := &api.CreateRequest{
 		Model: modelName,
 		Files: map[string]string{
-			"modelfile": string(newModelfileContent),
+			"modelfile": modelfileContent,
 		},
 	}
 
-	err = client.Create(ctx, createReq, func(resp api.ProgressResponse) error {
+	err := client.Create(ctx, createReq, func(resp api.ProgressResponse) error {
 		logging.InfoLogger.Printf("Create progress: %s\n", resp.Status)
 		return nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("error updating model with new modelfile: %v", err)
+		return fmt.Errorf("error updating model with new modelfile: %v", err)
 	}
-
-	// log to the console if we're not in a tea app
-	fmt.Printf("Model %s updated successfully\n", modelName)
-
-	return fmt.Sprintf("Model %s updated successfully, Press 'q' to return to the models list", modelName), nil
+	showcache.Invalidate(client, modelName)
+	return nil
 }
 
 func isLocalhost(url string) bool {
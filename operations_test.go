@@ -1,8 +1,19 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
 
 	"github.com/sammcj/gollama/config"
 )
@@ -47,7 +58,7 @@ func TestRunModel(t *testing.T) {
 			t.Skip("Skipping test in CI environment")
 		} else {
 			t.Run(tt.name, func(t *testing.T) {
-				cmd := runModel(tt.model, tt.cfg)
+				cmd := runModel(tt.model, tt.cfg, nil)
 				if (cmd == nil) != tt.expectError {
 					t.Errorf("runModel() error = %v, expectError %v", cmd == nil, tt.expectError)
 					t.Logf("cmd: %v", cmd)
@@ -58,3 +69,368 @@ func TestRunModel(t *testing.T) {
 		}
 	}
 }
+
+// fakeOllama is a minimal Ollama API server for testing copy and rename
+type fakeOllama struct {
+	models    []string
+	copyFails bool // return an error from /api/copy
+	copyLies  bool // report success from /api/copy without creating the destination
+	copied    bool
+	deleted   []string
+}
+
+func (f *fakeOllama) handler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/tags":
+		var resp api.ListResponse
+		for _, name := range f.models {
+			resp.Models = append(resp.Models, api.ListModelResponse{Name: name, Model: name, Digest: "sha256:" + name})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/api/copy":
+		var req api.CopyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.copied = true
+		if f.copyFails {
+			http.Error(w, `{"error":"copy failed"}`, http.StatusInternalServerError)
+			return
+		}
+		if !f.copyLies {
+			f.models = append(f.models, req.Destination)
+		}
+	case "/api/delete":
+		var req api.DeleteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.deleted = append(f.deleted, req.Name)
+		var remaining []string
+		for _, name := range f.models {
+			if name != req.Name {
+				remaining = append(remaining, name)
+			}
+		}
+		f.models = remaining
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRenameModelTo(t *testing.T) {
+	tests := []struct {
+		name         string
+		fake         fakeOllama
+		newName      string
+		overwrite    bool
+		wantErr      error
+		expectErr    bool
+		expectCopy   bool
+		expectDelete bool
+	}{
+		{
+			name:         "Rename succeeds",
+			fake:         fakeOllama{models: []string{"src:latest"}},
+			newName:      "dst:latest",
+			expectCopy:   true,
+			expectDelete: true,
+		},
+		{
+			name:       "Copy fails, source kept",
+			fake:       fakeOllama{models: []string{"src:latest"}, copyFails: true},
+			newName:    "dst:latest",
+			expectErr:  true,
+			expectCopy: true,
+		},
+		{
+			name:       "Copy reports success but destination missing, source kept",
+			fake:       fakeOllama{models: []string{"src:latest"}, copyLies: true},
+			newName:    "dst:latest",
+			expectErr:  true,
+			expectCopy: true,
+		},
+		{
+			name:      "Destination exists without overwrite, nothing copied or deleted",
+			fake:      fakeOllama{models: []string{"src:latest", "dst:latest"}},
+			newName:   "dst",
+			wantErr:   errModelExists,
+			expectErr: true,
+		},
+		{
+			name:         "Destination exists with overwrite",
+			fake:         fakeOllama{models: []string{"src:latest", "dst:latest"}},
+			newName:      "dst:latest",
+			overwrite:    true,
+			expectCopy:   true,
+			expectDelete: true,
+		},
+		{
+			name:      "Same name",
+			fake:      fakeOllama{models: []string{"src:latest"}},
+			newName:   "src",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			fake := tt.fake
+			server := httptest.NewServer(http.HandlerFunc(fake.handler))
+			defer server.Close()
+			u, _ := url.Parse(server.URL)
+			client := api.NewClient(u, server.Client())
+
+			err := renameModelTo(client, "src:latest", tt.newName, tt.overwrite)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("renameModelTo() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("renameModelTo() error = %v, want %v", err, tt.wantErr)
+			}
+			if fake.copied != tt.expectCopy {
+				t.Errorf("copy called = %v, want %v", fake.copied, tt.expectCopy)
+			}
+			if deleted := len(fake.deleted) > 0; deleted != tt.expectDelete {
+				t.Errorf("source deleted = %v, want %v (deleted: %v)", deleted, tt.expectDelete, fake.deleted)
+			}
+		})
+	}
+}
+
+func TestSanitiseRunningModels(t *testing.T) {
+	future := time.Now().Add(5 * time.Minute)
+
+	tests := []struct {
+		name       string
+		resp       *api.ProcessResponse
+		wantNames  []string
+		wantSize   string
+		wantVRAM   string
+		wantExpiry string
+	}{
+		{
+			name: "Well formed entry is unchanged",
+			resp: &api.ProcessResponse{Models: []api.ProcessModelResponse{
+				{Name: "llama3:8b", Digest: "a", Size: 2 * 1024 * 1024 * 1024, SizeVRAM: 1024 * 1024 * 1024, ExpiresAt: future},
+			}},
+			wantNames:  []string{"llama3:8b"},
+			wantSize:   "2.00 GB",
+			wantVRAM:   "1.00 GB",
+			wantExpiry: future.Format("2006-01-02 15:04:05"),
+		},
+		{
+			name: "Zero and negative sizes show n/a",
+			resp: &api.ProcessResponse{Models: []api.ProcessModelResponse{
+				{Name: "llama3:8b", Digest: "a", Size: -1, SizeVRAM: 0, ExpiresAt: future},
+			}},
+			wantNames:  []string{"llama3:8b"},
+			wantSize:   "n/a",
+			wantVRAM:   "n/a",
+			wantExpiry: future.Format("2006-01-02 15:04:05"),
+		},
+		{
+			name: "Epoch expiry is unknown",
+			resp: &api.ProcessResponse{Models: []api.ProcessModelResponse{
+				{Name: "llama3:8b", Digest: "a", Size: 1024 * 1024 * 1024, SizeVRAM: 1024 * 1024 * 1024, ExpiresAt: time.Unix(0, 0)},
+			}},
+			wantNames:  []string{"llama3:8b"},
+			wantSize:   "1.00 GB",
+			wantVRAM:   "1.00 GB",
+			wantExpiry: "unknown",
+		},
+		{
+			name: "Zero expiry is unknown",
+			resp: &api.ProcessResponse{Models: []api.ProcessModelResponse{
+				{Name: "llama3:8b", Digest: "a", Size: 1024 * 1024 * 1024},
+			}},
+			wantNames:  []string{"llama3:8b"},
+			wantSize:   "1.00 GB",
+			wantVRAM:   "n/a",
+			wantExpiry: "unknown",
+		},
+		{
+			name: "Duplicate digests are dropped",
+			resp: &api.ProcessResponse{Models: []api.ProcessModelResponse{
+				{Name: "llama3:8b", Digest: "a", Size: 1024 * 1024 * 1024, ExpiresAt: future},
+				{Name: "llama3:8b", Digest: "a", Size: 0},
+				{Name: "qwen2:7b", Digest: "b", Size: 1024 * 1024 * 1024, ExpiresAt: future},
+			}},
+			wantNames:  []string{"llama3:8b", "qwen2:7b"},
+			wantSize:   "1.00 GB",
+			wantVRAM:   "n/a",
+			wantExpiry: future.Format("2006-01-02 15:04:05"),
+		},
+		{
+			name:      "Nil response",
+			resp:      nil,
+			wantNames: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := sanitiseRunningModels(tt.resp)
+			if len(models) != len(tt.wantNames) {
+				t.Fatalf("sanitiseRunningModels() returned %d models, want %d", len(models), len(tt.wantNames))
+			}
+			for i, name := range tt.wantNames {
+				if models[i].Name != name {
+					t.Errorf("model %d = %s, want %s", i, models[i].Name, name)
+				}
+			}
+			if len(models) == 0 {
+				return
+			}
+			if got := formatRunningSize(models[0].Size); got != tt.wantSize {
+				t.Errorf("size = %q, want %q", got, tt.wantSize)
+			}
+			if got := formatRunningSize(models[0].SizeVRAM); got != tt.wantVRAM {
+				t.Errorf("vram = %q, want %q", got, tt.wantVRAM)
+			}
+			if got := formatExpiresAt(models[0].ExpiresAt); got != tt.wantExpiry {
+				t.Errorf("expiry = %q, want %q", got, tt.wantExpiry)
+			}
+		})
+	}
+}
+
+func TestLinkModelWithProjector(t *testing.T) {
+	t.Setenv("OLLAMA_MODELS", "")
+	blobs := t.TempDir()
+	modelBlob := filepath.Join(blobs, "sha256-model")
+	projectorBlob := filepath.Join(blobs, "sha256-projector")
+	for _, path := range []string{modelBlob, projectorBlob} {
+		if err := os.WriteFile(path, []byte("gguf"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM " + modelBlob + "\nFROM " + projectorBlob + "\n"})
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	lmStudioDir := t.TempDir()
+	if _, err := linkModel("llava:7b", blobs, lmStudioDir, cleanupSkip, false, client); err != nil {
+		t.Fatalf("linkModel() error: %v", err)
+	}
+	_, linkPath := lmStudioLinkPath("llava:7b", lmStudioDir)
+	if target, err := os.Readlink(linkPath); err != nil || target != modelBlob {
+		t.Errorf("model link = %q, %v, want %s", target, err, modelBlob)
+	}
+	if target, err := os.Readlink(projectorLinkPath(linkPath, 0)); err != nil || target != projectorBlob {
+		t.Errorf("projector link = %q, %v, want %s", target, err, projectorBlob)
+	}
+
+	message, err := linkModel("llava:7b", blobs, lmStudioDir, cleanupSkip, false, client)
+	if err != nil || !strings.Contains(message, "already symlinked") {
+		t.Errorf("linking again = %q, %v, want an already linked message", message, err)
+	}
+
+	if _, err := modelsNamed([]Model{}, []string{"missing"}); err == nil {
+		t.Error("modelsNamed() expected an error for a missing model")
+	}
+}
+
+func TestPushProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"status":"retrieving manifest"}
+{"status":"pushing sha256:aaaa","digest":"sha256:aaaa","total":300,"completed":0}
+{"status":"pushing sha256:bbbb","digest":"sha256:bbbb","total":100,"completed":0}
+{"status":"pushing sha256:aaaa","digest":"sha256:aaaa","total":300,"completed":300}
+{"status":"pushing sha256:bbbb","digest":"sha256:bbbb","total":100,"completed":50}
+{"error":"connection reset"}
+`))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{}, showProgress: true}
+
+	cmd := m.startPushModel("mistral:7b", m.pushModelCmd("mistral:7b"))
+	if cmd == nil {
+		t.Fatal("startPushModel() returned no command")
+	}
+	msg := m.pushModelCmd("mistral:7b")()
+	if _, cmd := m.handleProgressMsg(progressMsg{feed: m.pushFeed}); cmd == nil || m.pushProgress != 0.875 {
+		t.Errorf("pushProgress = %v after a tick, want 0.875 and another tick", m.pushProgress)
+	}
+	layers := m.pushLayers.snapshot()
+	if len(layers) != 2 || layers[0].Status != layerDone || layers[1].Status != layerPushing {
+		t.Errorf("push layers = %+v, want the first done and the second pushing", layers)
+	}
+
+	pushErr, ok := msg.(pushErrorMsg)
+	if !ok {
+		t.Fatalf("pushModelCmd() = %#v, want a pushErrorMsg", msg)
+	}
+	m.handlePushErrorMsg(pushErr)
+	if !strings.Contains(m.message, "connection reset") || !strings.Contains(m.message, "last status: pushing sha256:bbbb") {
+		t.Errorf("message = %q, want the error and the last status", m.message)
+	}
+	if m.showProgress || m.pushLayers != nil {
+		t.Error("the progress bar is still shown after the push failed")
+	}
+	if _, cmd := m.handleProgressMsg(progressMsg{feed: m.pushFeed}); cmd != nil {
+		t.Error("handleProgressMsg() kept ticking after the push finished")
+	}
+}
+
+func TestCancelPush(t *testing.T) {
+	serverCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(serverCancelled)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{}, showProgress: true}
+
+	push := m.pushModelCmd("mistral:7b")
+	feed := m.pushFeed
+	done := make(chan tea.Msg, 1)
+	go func() { done <- push() }()
+	<-time.After(50 * time.Millisecond)
+
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.showProgress || m.message != "Push cancelled" {
+		t.Errorf("after esc showProgress = %v, message %q", m.showProgress, m.message)
+	}
+	select {
+	case msg := <-done:
+		if msg != nil {
+			t.Errorf("cancelled push returned %#v, want no message", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the push wasn't cancelled")
+	}
+	select {
+	case <-serverCancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("the push request wasn't cancelled")
+	}
+	if _, cmd := m.handleProgressMsg(progressMsg{feed: feed}); cmd != nil {
+		t.Error("handleProgressMsg() kept ticking after the push was cancelled")
+	}
+
+	// Progress from a push that's been replaced, like one still returning after being cancelled, only updates its
+	// own state and its ticks are dropped
+	ctx, track := m.beginPush()
+	oldFeed := m.pushFeed
+	m.showProgress = true
+	m.beginPush()
+	track(api.ProgressResponse{Status: "pushing", Digest: "sha256:a", Total: 100, Completed: 50})
+	if ctx.Err() == nil {
+		t.Error("starting another push didn't cancel the one before")
+	}
+	if layers := m.pushLayers.snapshot(); len(layers) != 0 {
+		t.Errorf("the replaced push updated the layers of the new one: %+v", layers)
+	}
+	if _, ok := m.pushFeed.latest(); ok {
+		t.Error("the replaced push fed its progress to the new one")
+	}
+	if _, cmd := m.handleProgressMsg(progressMsg{feed: oldFeed}); cmd != nil || m.pushProgress != 0 {
+		t.Errorf("a tick of the replaced push set the progress to %v", m.pushProgress)
+	}
+}
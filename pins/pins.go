@@ -0,0 +1,130 @@
+// pins.go stores the models the user has pinned to protect them from deletion.
+package pins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gollama/utils"
+)
+
+// Entry is a single pinned model, pins are keyed by digest so they survive renames and copies
+type Entry struct {
+	Name     string    `json:"name"` // the name the model had when it was pinned, for display only
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+var mu sync.Mutex
+
+// Path returns the location of the pins file
+func Path() string {
+	return filepath.Join(utils.GetConfigDir(), "pins.json")
+}
+
+// Load returns all pins keyed by model digest
+func Load() (map[string]Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+func load() (map[string]Entry, error) {
+	pins := make(map[string]Entry)
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pins, nil
+		}
+		return nil, fmt.Errorf("failed to read pins file: %w", err)
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pins file: %w", err)
+	}
+	return pins, nil
+}
+
+func save(pins map[string]Entry) error {
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create pins directory: %w", err)
+	}
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pins file: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// IsPinned reports whether the model with the given digest is pinned
+func IsPinned(digest string) (bool, error) {
+	pins, err := Load()
+	if err != nil {
+		return false, err
+	}
+	_, ok := pins[digest]
+	return ok, nil
+}
+
+// Pin pins the model with the given digest, pinning an already pinned model is a no-op
+func Pin(digest, name string) error {
+	if digest == "" {
+		return fmt.Errorf("cannot pin %s: missing digest", name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	pins, err := load()
+	if err != nil {
+		return err
+	}
+	if _, ok := pins[digest]; ok {
+		return nil
+	}
+	pins[digest] = Entry{Name: name, PinnedAt: time.Now()}
+	return save(pins)
+}
+
+// Unpin removes the pin for the model with the given digest
+func Unpin(digest string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	pins, err := load()
+	if err != nil {
+		return err
+	}
+	if _, ok := pins[digest]; !ok {
+		return nil
+	}
+	delete(pins, digest)
+	return save(pins)
+}
+
+// Toggle pins or unpins the model with the given digest, returning whether it is now pinned
+func Toggle(digest, name string) (bool, error) {
+	pinned, err := IsPinned(digest)
+	if err != nil {
+		return false, err
+	}
+	if pinned {
+		return false, Unpin(digest)
+	}
+	return true, Pin(digest, name)
+}
+
+// Digests returns the pinned digests in a stable order
+func Digests(pins map[string]Entry) []string {
+	digests := make([]string, 0, len(pins))
+	for digest := range pins {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	return digests
+}
@@ -0,0 +1,86 @@
+package pins
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPinAndUnpin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	pinned, err := IsPinned("sha256:abc")
+	if err != nil {
+		t.Fatalf("IsPinned() on missing file error = %v", err)
+	}
+	if pinned {
+		t.Fatalf("IsPinned() on missing file = true, want false")
+	}
+
+	if err := Pin("sha256:abc", "llama3:8b"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	// Pinning twice keeps the original entry
+	if err := Pin("sha256:abc", "renamed:8b"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	all, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(all) != 1 || all["sha256:abc"].Name != "llama3:8b" {
+		t.Errorf("Load() = %+v, want a single llama3:8b pin", all)
+	}
+
+	if err := Unpin("sha256:abc"); err != nil {
+		t.Fatalf("Unpin() error = %v", err)
+	}
+	if pinned, _ := IsPinned("sha256:abc"); pinned {
+		t.Errorf("IsPinned() after Unpin() = true, want false")
+	}
+}
+
+func TestToggle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "First toggle pins", want: true},
+		{name: "Second toggle unpins", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Toggle("sha256:def", "qwen2:7b")
+			if err != nil {
+				t.Fatalf("Toggle() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Toggle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinRequiresDigest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := Pin("", "llama3:8b"); err == nil {
+		t.Errorf("Pin() with empty digest error = nil, want error")
+	}
+}
+
+func TestLoadCorruptFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := Pin("sha256:abc", "llama3:8b"); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	if err := os.WriteFile(Path(), []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Errorf("Load() of corrupt file error = nil, want error")
+	}
+}
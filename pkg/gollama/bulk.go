@@ -0,0 +1,91 @@
+// bulk.go applies an operation to several models at once, the way the TUI does for the models selected in it.
+package gollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/pins"
+)
+
+// Bulk actions
+const (
+	ActionDelete = "delete"
+	ActionUnload = "unload"
+	ActionUpdate = "update" // pull the model again to pick up a newer version
+)
+
+// BulkResult is the outcome of a bulk action for one model, Error is empty on success
+type BulkResult struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// Unload unloads a model from memory, with an embeddings request for embedding models. httpClient is used to read the
+// model's capabilities, as for Capabilities.
+func Unload(ctx context.Context, client *api.Client, httpClient *http.Client, apiURL, name string) error {
+	if err := SetKeepAlive(ctx, client, httpClient, apiURL, name, 0); err != nil {
+		return fmt.Errorf("error unloading model %s: %w", name, err)
+	}
+	return nil
+}
+
+// BulkAction applies action to each named model in turn, carrying on past failures so every model gets a result.
+// Pinned models are never deleted. apiURL is the URL client uses and httpClient the HTTP client it uses, which unloading
+// needs to read the models' capabilities. It only returns an error if action is unknown or the models can't be listed.
+func BulkAction(ctx context.Context, client *api.Client, httpClient *http.Client, apiURL, action string, names []string) ([]BulkResult, error) {
+	var apply func(name string) error
+	switch action {
+	case ActionDelete:
+		pinned, err := pinnedNames(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		apply = func(name string) error {
+			if pinned[name] {
+				return fmt.Errorf("%s is pinned, unpin it before deleting", name)
+			}
+			return Delete(ctx, client, name)
+		}
+	case ActionUnload:
+		apply = func(name string) error { return Unload(ctx, client, httpClient, apiURL, name) }
+	case ActionUpdate:
+		apply = func(name string) error { return Pull(ctx, client, name, nil) }
+	default:
+		return nil, fmt.Errorf("unknown bulk action %q", action)
+	}
+
+	results := make([]BulkResult, 0, len(names))
+	for _, name := range names {
+		result := BulkResult{Name: name}
+		if err := ctx.Err(); err != nil {
+			result.Error = err.Error()
+		} else if err := apply(name); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// pinnedNames returns the names of the models that are pinned
+func pinnedNames(ctx context.Context, client *api.Client) (map[string]bool, error) {
+	models, err := ListModels(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	pinnedDigests, err := pins.Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, model := range models {
+		if _, ok := pinnedDigests[model.Digest]; ok {
+			names[model.Name] = true
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,77 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/pins"
+)
+
+func TestBulkActionDelete(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := pins.Pin("sha256:pinned", "keep:latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{
+				{Name: "old:latest", Digest: "sha256:old"},
+				{Name: "keep:latest", Digest: "sha256:pinned"},
+			}})
+		case "/api/delete":
+			var req api.DeleteRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model != "old:latest" && req.Name != "old:latest" {
+				http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+				return
+			}
+			deleted = append(deleted, "old:latest")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := BulkAction(context.Background(), client, nil, server.URL, ActionDelete, []string{"old:latest", "keep:latest", "missing:latest"})
+	if err != nil {
+		t.Fatalf("BulkAction() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("BulkAction() returned %d results, want 3", len(results))
+	}
+	if results[0].Error != "" {
+		t.Errorf("deleting old:latest failed: %s", results[0].Error)
+	}
+	if results[1].Error == "" {
+		t.Errorf("deleting the pinned keep:latest succeeded")
+	}
+	if results[2].Error == "" {
+		t.Errorf("deleting missing:latest succeeded")
+	}
+	if !reflect.DeepEqual(deleted, []string{"old:latest"}) {
+		t.Errorf("deleted %v, want only old:latest", deleted)
+	}
+}
+
+func TestBulkActionUnknown(t *testing.T) {
+	client, err := NewClient("http://localhost:11434")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BulkAction(context.Background(), client, nil, "http://localhost:11434", "explode", []string{"a"}); err == nil {
+		t.Error("BulkAction() with an unknown action didn't return an error")
+	}
+}
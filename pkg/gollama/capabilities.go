@@ -0,0 +1,113 @@
+// capabilities.go reads the capabilities newer Ollama versions report for a model, which the api package doesn't decode
+// yet, and uses them to send keep alive requests, including unloads, to the endpoint the model supports.
+package gollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+// defaultHTTPClient makes the show requests made without the api package when the caller doesn't pass a client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// capabilities caches the capabilities of each model, keyed by API URL and model name. Capabilities don't change
+// without the model changing so they're kept for the life of the program.
+var (
+	capabilitiesMu sync.Mutex
+	capabilities   = make(map[string][]string)
+)
+
+// Capabilities returns the capabilities of a model (e.g. "completion", "embedding"), which older Ollama versions don't
+// report. httpClient should be the client the api.Client uses, e.g. from config.NewHTTPClient, so the request
+// authenticates the same way; nil uses a plain client.
+func Capabilities(ctx context.Context, httpClient *http.Client, apiURL, name string) ([]string, error) {
+	key := apiURL + "|" + name
+	capabilitiesMu.Lock()
+	cached, ok := capabilities[key]
+	capabilitiesMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(apiURL, "/")+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := config.AuthError(resp.StatusCode); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama API returned %s", resp.Status)
+	}
+
+	var show struct {
+		Capabilities []string `json:"capabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("error decoding show response: %w", err)
+	}
+
+	capabilitiesMu.Lock()
+	capabilities[key] = show.Capabilities
+	capabilitiesMu.Unlock()
+	return show.Capabilities, nil
+}
+
+// EmbeddingCapability reports whether a model only supports embeddings from the capabilities the show API reports, and
+// whether that's known. When the request fails or an older server doesn't report capabilities it guesses from "embed"
+// being in the name, which misses embedding models with other names, so known is false.
+func EmbeddingCapability(ctx context.Context, httpClient *http.Client, apiURL, name string) (embedding, known bool) {
+	caps, err := Capabilities(ctx, httpClient, apiURL, name)
+	if err == nil && len(caps) > 0 {
+		return slices.Contains(caps, "embedding") && !slices.Contains(caps, "completion"), true
+	}
+	return strings.Contains(strings.ToLower(name), "embed"), false
+}
+
+// SetKeepAlive sets how long a model stays loaded, zero unloads it and a negative duration keeps it loaded until
+// Ollama stops. Embedding models can't take a generate request so they're sent an embeddings request instead. When the
+// server didn't report the model's capabilities and the guessed request fails, the other one is tried.
+func SetKeepAlive(ctx context.Context, client *api.Client, httpClient *http.Client, apiURL, name string, keepAlive time.Duration) error {
+	embedding, known := EmbeddingCapability(ctx, httpClient, apiURL, name)
+	err := keepAliveRequest(ctx, client, name, keepAlive, embedding)
+	if err != nil && !known {
+		if keepAliveRequest(ctx, client, name, keepAlive, !embedding) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// keepAliveRequest sends the keep alive with an embeddings request or an empty generate request
+func keepAliveRequest(ctx context.Context, client *api.Client, name string, keepAlive time.Duration, embedding bool) error {
+	if embedding {
+		_, err := client.Embeddings(ctx, &api.EmbeddingRequest{Model: name, KeepAlive: &api.Duration{Duration: keepAlive}})
+		return err
+	}
+	req := &api.GenerateRequest{Model: name, KeepAlive: &api.Duration{Duration: keepAlive}}
+	return client.Generate(ctx, req, func(api.GenerateResponse) error { return nil })
+}
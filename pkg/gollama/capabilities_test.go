@@ -0,0 +1,130 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestEmbeddingCapability(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Model {
+		case "bge-m3:latest":
+			w.Write([]byte(`{"capabilities":["embedding"]}`))
+		case "embedder-chat:latest":
+			w.Write([]byte(`{"capabilities":["completion"]}`))
+		case "embed-and-chat:latest", "bert-custom:latest":
+			w.Write([]byte(`{"capabilities":["completion","embedding"]}`))
+		case "broken-embed:latest", "broken:latest":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			// Older servers don't report capabilities
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		model string
+		want  bool
+		known bool
+	}{
+		{"bge-m3:latest", true, true},
+		{"embedder-chat:latest", false, true},
+		{"nomic-embed-text:latest", true, false},
+		{"My-Embed:latest", true, false},
+		{"llama3:8b", false, false},
+		// Models that do both are unloaded with a generate request whatever their name
+		{"embed-and-chat:latest", false, true},
+		{"bert-custom:latest", false, true},
+		// The name is only used when the show request fails
+		{"broken-embed:latest", true, false},
+		{"broken:latest", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, known := EmbeddingCapability(context.Background(), nil, server.URL, tt.model)
+			if got != tt.want || known != tt.known {
+				t.Errorf("EmbeddingCapability(%s) = %v, %v, want %v, %v", tt.model, got, known, tt.want, tt.known)
+			}
+		})
+	}
+}
+
+func TestSetKeepAlive(t *testing.T) {
+	var paths, keepAlives []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/api/show":
+			if strings.Contains(string(body["model"]), "embed") {
+				w.Write([]byte(`{"capabilities":["embedding"]}`))
+			} else {
+				w.Write([]byte(`{"capabilities":["completion"]}`))
+			}
+			return
+		case "/api/embeddings":
+			w.Write([]byte(`{"embedding":[]}`))
+		default:
+			w.Write([]byte(`{"done":true}`))
+		}
+		paths = append(paths, r.URL.Path)
+		keepAlives = append(keepAlives, string(body["keep_alive"]))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	if err := SetKeepAlive(context.Background(), client, nil, server.URL, "llama3:8b", -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetKeepAlive(context.Background(), client, nil, server.URL, "nomic-embed-text:latest", 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(paths, []string{"/api/generate", "/api/embeddings"}) {
+		t.Errorf("requests = %v, want a generate then an embeddings request", paths)
+	}
+	if !slices.Equal(keepAlives, []string{"-1", `"2h0m0s"`}) {
+		t.Errorf("keep_alive = %v, want -1 then 2h", keepAlives)
+	}
+}
+
+func TestSetKeepAliveGuessedWrong(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			// An older server that doesn't report capabilities, so the model is guessed to be a generation model
+			w.Write([]byte(`{}`))
+			return
+		case "/api/generate":
+			http.Error(w, `{"error":"\"nomic-bert-custom\" does not support generate"}`, http.StatusBadRequest)
+		default:
+			w.Write([]byte(`{"embedding":[]}`))
+		}
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	if err := SetKeepAlive(context.Background(), client, nil, server.URL, "nomic-bert-custom:latest", 0); err != nil {
+		t.Fatalf("SetKeepAlive() error = %v, want the embeddings request to unload it", err)
+	}
+	if !slices.Equal(paths, []string{"/api/generate", "/api/embeddings"}) {
+		t.Errorf("requests = %v, want a generate request then an embeddings request", paths)
+	}
+}
@@ -0,0 +1,45 @@
+// client.go contains helpers for constructing an Ollama API client.
+package gollama
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+// NewClient returns an Ollama API client for the given API URL (e.g. http://localhost:11434). It doesn't authenticate,
+// use NewClientFromConfig for a server that needs an API key or a custom CA.
+func NewClient(apiURL string) (*api.Client, error) {
+	return newClient(apiURL, http.DefaultClient)
+}
+
+// NewClientFromConfig returns an Ollama API client for the API URL in a gollama config, falling back to OLLAMA_HOST
+// when the config has the default URL. It authenticates with the config's API key or auth header and trusts its CA
+// certificate, the HTTP client it uses is returned too for the functions that take one.
+func NewClientFromConfig(cfg config.Config) (*api.Client, *http.Client, error) {
+	cfg.OllamaAPIURL, _ = config.ResolveAPIURL("", cfg.OllamaAPIURL)
+	httpClient, err := config.NewHTTPClient(&cfg, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := newClient(cfg.OllamaAPIURL, httpClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, httpClient, nil
+}
+
+func newClient(apiURL string, httpClient *http.Client) (*api.Client, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing API URL %q: %w", apiURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q: expected scheme and host", apiURL)
+	}
+	return api.NewClient(u, httpClient), nil
+}
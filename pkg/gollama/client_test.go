@@ -0,0 +1,52 @@
+package gollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestNewClientFromConfigAuthenticates(t *testing.T) {
+	var listAuth, showAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			listAuth = r.Header.Get("Authorization")
+			json.NewEncoder(w).Encode(api.ListResponse{})
+		case "/api/show":
+			showAuth = r.Header.Get("Authorization")
+			json.NewEncoder(w).Encode(map[string]any{"capabilities": []string{"completion"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Config{OllamaAPIURL: server.URL, OllamaAPIKey: "secret"}
+	client, httpClient, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig() error = %v", err)
+	}
+	if _, err := client.List(context.Background()); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if _, err := Capabilities(context.Background(), httpClient, server.URL, "auth-test:latest"); err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if listAuth != "Bearer secret" || showAuth != "Bearer secret" {
+		t.Errorf("Authorization headers = %q, %q, want the API key on both", listAuth, showAuth)
+	}
+}
+
+func TestNewClientFromConfigBadCA(t *testing.T) {
+	cfg := config.Config{OllamaAPIURL: "https://ollama.example.com", OllamaTLSCACert: "/nonexistent/ca.pem"}
+	if _, _, err := NewClientFromConfig(cfg); err == nil {
+		t.Error("NewClientFromConfig() with a missing CA certificate didn't return an error")
+	}
+}
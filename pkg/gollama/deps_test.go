@@ -0,0 +1,65 @@
+package gollama
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tuiPackages must never be imported by this package, directly or transitively
+var tuiPackages = []string{
+	"github.com/charmbracelet/",
+	"github.com/olekukonko/tablewriter",
+}
+
+// TestNoTUIDependencies builds a small program that only imports this package and checks its dependencies
+func TestNoTUIDependencies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping dependency check in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	// The program has to live inside the module so the import resolves to this tree
+	dir, err := os.MkdirTemp(".", "headless-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	program := `package main
+
+import "github.com/sammcj/gollama/pkg/gollama"
+
+func main() {
+	_, _ = gollama.NewClient("http://localhost:11434")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	build := exec.Command(goBin, "build", "-o", os.DevNull, ".")
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building headless program failed: %v\n%s", err, out)
+	}
+
+	list := exec.Command(goBin, "list", "-deps", ".")
+	list.Dir = dir
+	out, err := list.Output()
+	if err != nil {
+		t.Fatalf("go list failed: %v", err)
+	}
+	for _, dep := range strings.Split(string(out), "\n") {
+		for _, tui := range tuiPackages {
+			if strings.HasPrefix(dep, tui) {
+				t.Errorf("headless program depends on TUI package %s", dep)
+			}
+		}
+	}
+}
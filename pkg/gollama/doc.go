@@ -0,0 +1,6 @@
+// Package gollama exposes gollama's model management logic for use in other Go programs.
+//
+// It wraps the Ollama API client with the helpers the gollama TUI is built on (listing, pulling,
+// pushing, copying, deleting and bulk actions on models, parsing Modelfiles and estimating vRAM usage) without
+// depending on any terminal UI packages.
+package gollama
@@ -0,0 +1,66 @@
+// estimate.go exposes the vRAM estimator.
+package gollama
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+// EstimateOptions configures a vRAM estimate
+type EstimateOptions struct {
+	// Model is an Ollama model name (e.g. qwen2:7b-instruct-q4_0), a HuggingFace model ID (e.g. meta-llama/Llama-2-7b)
+	// or the path of a local .gguf file
+	Model string
+	// APIURL is the Ollama API URL used to look up Ollama models
+	APIURL string
+	// HTTPClient is used to reach the Ollama API, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// FitsVRAM is the available vRAM in GB, 0 to skip the constraint
+	FitsVRAM float64
+	// MaxContext is the largest context size to estimate, defaults to the model's own maximum (up to 256k)
+	MaxContext int
+	// GPUs is the GPUs the model is split across, FitsVRAM is then their combined memory. Defaults to a single GPU
+	GPUs vramestimator.GPUSetup
+	// KVCacheQuant limits the estimates to one k/v cache quantisation, every one of them when empty
+	KVCacheQuant vramestimator.KVCacheQuantisation
+}
+
+// EstimateVRAM estimates the vRAM needed to run a model at each quantisation and context size
+func EstimateVRAM(opts EstimateOptions) (vramestimator.QuantResultTable, error) {
+	var ollamaModelInfo *vramestimator.OllamaModelInfo
+	var err error
+	if vramestimator.IsGGUFPath(opts.Model) {
+		ollamaModelInfo, err = vramestimator.ReadGGUFModelInfo(opts.Model)
+		if err != nil {
+			return vramestimator.QuantResultTable{}, err
+		}
+		return estimateVRAM(opts, opts.Model, ollamaModelInfo)
+	}
+	baseModel, _, err := vramestimator.ParseModelIdentifier(opts.Model)
+	if err != nil {
+		return vramestimator.QuantResultTable{}, err
+	}
+	if !strings.Contains(baseModel, "/") {
+		ollamaModelInfo, err = vramestimator.FetchOllamaModelInfo(opts.HTTPClient, opts.APIURL, opts.Model)
+		if err != nil {
+			return vramestimator.QuantResultTable{}, fmt.Errorf("error fetching Ollama model info: %w", err)
+		}
+	}
+	return estimateVRAM(opts, baseModel, ollamaModelInfo)
+}
+
+func estimateVRAM(opts EstimateOptions, baseModel string, ollamaModelInfo *vramestimator.OllamaModelInfo) (vramestimator.QuantResultTable, error) {
+	source := "MaxContext"
+	if opts.MaxContext == 0 {
+		opts.MaxContext, source = vramestimator.ModelContextLimit(baseModel, ollamaModelInfo)
+	}
+	table, err := vramestimator.GenerateQuantTableForKVCache(baseModel, opts.FitsVRAM, ollamaModelInfo, opts.MaxContext, opts.GPUs, opts.KVCacheQuant)
+	if err != nil {
+		return vramestimator.QuantResultTable{}, err
+	}
+	table.ContextSource = source
+	return table, nil
+}
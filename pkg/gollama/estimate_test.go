@@ -0,0 +1,67 @@
+package gollama
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeGGUF writes a tiny llama GGUF file with a 4096 token context, see vramestimator's gguf_test.go for the layout
+func writeGGUF(t *testing.T) string {
+	t.Helper()
+	const (
+		ggufUint32 = uint32(4)
+		ggufInt32  = uint32(5)
+		ggufString = uint32(8)
+		ggufArray  = uint32(9)
+	)
+	var b bytes.Buffer
+	write := func(vs ...any) {
+		for _, v := range vs {
+			if s, ok := v.(string); ok {
+				binary.Write(&b, binary.LittleEndian, uint64(len(s)))
+				b.WriteString(s)
+				continue
+			}
+			binary.Write(&b, binary.LittleEndian, v)
+		}
+	}
+	write([]byte("GGUF"), uint32(3), uint64(2), uint64(8))
+	write("general.architecture", ggufString, "llama")
+	write("general.file_type", ggufUint32, uint32(15))
+	write("llama.block_count", ggufUint32, uint32(2))
+	write("llama.context_length", ggufUint32, uint32(4096))
+	write("llama.embedding_length", ggufUint32, uint32(64))
+	write("llama.attention.head_count", ggufUint32, uint32(4))
+	write("tokenizer.ggml.tokens", ggufArray, ggufString, uint64(3), "<s>", "</s>", "hello")
+	write("tokenizer.ggml.token_type", ggufArray, ggufInt32, uint64(3), int32(3), int32(3), int32(1))
+	write("token_embd.weight", uint32(2), uint64(64), uint64(3), uint32(12), uint64(0))
+	write("blk.0.attn_q.weight", uint32(2), uint64(64), uint64(64), uint32(12), uint64(192))
+	b.Write(make([]byte, 32))
+
+	path := filepath.Join(t.TempDir(), "tiny.gguf")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEstimateVRAMGGUF(t *testing.T) {
+	// The file is read locally, nothing is fetched from the API
+	table, err := EstimateVRAM(EstimateOptions{Model: writeGGUF(t), APIURL: "http://127.0.0.1:1", FitsVRAM: 8})
+	if err != nil {
+		t.Fatalf("EstimateVRAM() error = %v", err)
+	}
+	if len(table.Results) == 0 {
+		t.Error("EstimateVRAM() returned no results")
+	}
+	if table.ContextSource != "the model's context_length" {
+		t.Errorf("ContextSource = %q, want the GGUF's context_length", table.ContextSource)
+	}
+
+	if _, err := EstimateVRAM(EstimateOptions{Model: filepath.Join(t.TempDir(), "missing.gguf")}); err == nil {
+		t.Error("EstimateVRAM() of a missing GGUF file succeeded")
+	}
+}
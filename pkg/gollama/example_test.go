@@ -0,0 +1,65 @@
+package gollama_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sammcj/gollama/pkg/gollama"
+)
+
+func ExampleListModels() {
+	client, err := gollama.NewClient("http://localhost:11434")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	models, err := gollama.ListModels(context.Background(), client)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, model := range models {
+		fmt.Printf("%s %.2fGB %s\n", model.Name, model.SizeGB(), model.QuantizationLevel)
+	}
+}
+
+func ExamplePull() {
+	client, err := gollama.NewClient("http://localhost:11434")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = gollama.Pull(context.Background(), client, "llama3.2:1b", func(p gollama.Progress) error {
+		fmt.Printf("%s %.0f%%\n", p.Status, p.Fraction()*100)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleEstimateVRAM() {
+	table, err := gollama.EstimateVRAM(gollama.EstimateOptions{
+		Model:    "llama3.1:8b-instruct-q4_K_M",
+		APIURL:   "http://localhost:11434",
+		FitsVRAM: 24,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, result := range table.Results {
+		fmt.Printf("%s: %.1fGB at 8K context\n", result.QuantType, result.Contexts[8192].VRAM)
+	}
+}
+
+func ExampleExtractTemplateAndSystem() {
+	modelfile := "FROM llama3\nSYSTEM \"\"\"You are a helpful assistant.\"\"\"\nPARAMETER num_ctx 8192\n"
+
+	_, system := gollama.ExtractTemplateAndSystem(modelfile)
+	params := gollama.ExtractParameters(modelfile)
+	fmt.Println(system)
+	fmt.Println(params["num_ctx"][0])
+	// Output:
+	// You are a helpful assistant.
+	// 8192
+}
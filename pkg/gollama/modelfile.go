@@ -0,0 +1,108 @@
+// modelfile.go contains helpers for parsing Modelfiles.
+package gollama
+
+import (
+	"strings"
+)
+
+// instruction is a Modelfile instruction with its value unquoted, Param is the parameter's name for PARAMETER
+type instruction struct {
+	Name  string // upper case
+	Param string
+	Value string
+}
+
+// parseInstructions splits a Modelfile into its instructions the way Ollama reads them: a value in double or triple
+// quotes runs until the end of the first line where it's closed, so it can span lines and contain quotes of its own.
+// Lines that aren't instructions, such as comments, are skipped.
+func parseInstructions(modelfile string) []instruction {
+	lines := strings.Split(modelfile, "\n")
+	var instructions []instruction
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, ok := cutField(line)
+		if !ok {
+			continue
+		}
+		inst := instruction{Name: strings.ToUpper(name)}
+		if inst.Name == "PARAMETER" {
+			if inst.Param, rest, ok = cutField(rest); !ok {
+				continue
+			}
+		}
+
+		value := rest
+		for {
+			if unquoted, ok := unquote(strings.TrimSpace(value)); ok {
+				inst.Value = unquoted
+				break
+			}
+			if i+1 >= len(lines) {
+				// Never closed, take the rest of the Modelfile
+				inst.Value = strings.TrimLeft(value, `"`)
+				break
+			}
+			i++
+			value += "\n" + lines[i]
+		}
+		instructions = append(instructions, inst)
+	}
+	return instructions
+}
+
+// cutField splits the first space or tab separated field from a line, reporting whether there's a value after it
+func cutField(line string) (field, rest string, ok bool) {
+	end := strings.IndexAny(line, " \t")
+	if end < 0 {
+		return line, "", false
+	}
+	rest = strings.TrimSpace(line[end:])
+	return line[:end], rest, rest != ""
+}
+
+// unquote removes the double or triple quotes around a value, reporting false when the value is opened but not
+// closed yet. The newline after the opening triple quotes of a block is dropped.
+func unquote(s string) (string, bool) {
+	if strings.HasPrefix(s, `"""`) {
+		if len(s) >= 6 && strings.HasSuffix(s, `"""`) {
+			return strings.TrimPrefix(s[3:len(s)-3], "\n"), true
+		}
+		return "", false
+	}
+	if strings.HasPrefix(s, `"`) {
+		if len(s) >= 2 && strings.HasSuffix(s, `"`) {
+			return s[1 : len(s)-1], true
+		}
+		return "", false
+	}
+	return s, true
+}
+
+// ExtractTemplateAndSystem returns the TEMPLATE and SYSTEM values from a Modelfile, handling values in double or
+// triple quotes that span multiple lines or contain quotes
+func ExtractTemplateAndSystem(modelfile string) (template, system string) {
+	for _, inst := range parseInstructions(modelfile) {
+		switch inst.Name {
+		case "TEMPLATE":
+			template = inst.Value
+		case "SYSTEM":
+			system = inst.Value
+		}
+	}
+	return template, system
+}
+
+// ExtractParameters returns the PARAMETER values from a Modelfile keyed by parameter name,
+// parameters that appear more than once (such as stop) have all of their values kept in order
+func ExtractParameters(modelfile string) map[string][]string {
+	params := make(map[string][]string)
+	for _, inst := range parseInstructions(modelfile) {
+		if inst.Name == "PARAMETER" {
+			params[inst.Param] = append(params[inst.Param], inst.Value)
+		}
+	}
+	return params
+}
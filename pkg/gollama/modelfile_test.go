@@ -0,0 +1,141 @@
+package gollama
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/parser"
+)
+
+func TestExtractTemplateAndSystem(t *testing.T) {
+	tests := []struct {
+		name         string
+		modelfile    string
+		wantTemplate string
+		wantSystem   string
+	}{
+		{
+			name:         "Single line values",
+			modelfile:    "FROM llama3\nTEMPLATE \"{{ .Prompt }}\"\nSYSTEM \"You are helpful\"\n",
+			wantTemplate: "{{ .Prompt }}",
+			wantSystem:   "You are helpful",
+		},
+		{
+			name:         "Triple quoted on one line",
+			modelfile:    "TEMPLATE \"\"\"{{ .Prompt }}\"\"\"\n",
+			wantTemplate: "{{ .Prompt }}",
+		},
+		{
+			name:         "Multi-line triple quoted",
+			modelfile:    "FROM llama3\nTEMPLATE \"\"\"\n{{ .System }}\n{{ .Prompt }}\"\"\"\nSYSTEM \"\"\"Line one\nLine two\n\"\"\"\nPARAMETER stop \"<|eot_id|>\"\n",
+			wantTemplate: "{{ .System }}\n{{ .Prompt }}",
+			wantSystem:   "Line one\nLine two\n",
+		},
+		{
+			name:       "Multi-line double quoted",
+			modelfile:  "FROM llama3\nSYSTEM \"You are a pirate.\nAlways answer in rhyme.\"\nPARAMETER num_ctx 4096\n",
+			wantSystem: "You are a pirate.\nAlways answer in rhyme.",
+		},
+		{
+			name:       "Quotes inside the value",
+			modelfile:  "SYSTEM \"\"\"Say \"ahoy\" first.\nThen answer with \"\"\"quotes\"\".\"\"\"\nSYSTEM_UNUSED x\n",
+			wantSystem: "Say \"ahoy\" first.\nThen answer with \"\"\"quotes\"\".",
+		},
+		{
+			name:       "Unquoted value keeps its quotes",
+			modelfile:  "SYSTEM You are \"Bob\"\n",
+			wantSystem: "You are \"Bob\"",
+		},
+		{
+			name:      "Lower case instructions",
+			modelfile: "system be brief\n",
+			// Instructions are case insensitive in Modelfiles
+			wantSystem: "be brief",
+		},
+		{
+			name:      "No template or system",
+			modelfile: "FROM llama3\nPARAMETER num_ctx 4096\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, system := ExtractTemplateAndSystem(tt.modelfile)
+			if template != tt.wantTemplate {
+				t.Errorf("template = %q, want %q", template, tt.wantTemplate)
+			}
+			if system != tt.wantSystem {
+				t.Errorf("system = %q, want %q", system, tt.wantSystem)
+			}
+		})
+	}
+}
+
+func TestExtractParameters(t *testing.T) {
+	tests := []struct {
+		name      string
+		modelfile string
+		want      map[string][]string
+	}{
+		{
+			name:      "Single and repeated parameters",
+			modelfile: "FROM llama3\nPARAMETER num_ctx 4096\nPARAMETER stop \"<|start_header_id|>\"\nPARAMETER stop \"<|eot_id|>\"\n",
+			want: map[string][]string{
+				"num_ctx": {"4096"},
+				"stop":    {"<|start_header_id|>", "<|eot_id|>"},
+			},
+		},
+		{
+			name:      "Ignores malformed lines and other instructions",
+			modelfile: "PARAMETER\nPARAMETER temperature\nPARAMETERS foo 1\nTEMPLATE \"PARAMETER x 1\"\n",
+			want:      map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractParameters(tt.modelfile)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestModelfileRoundTrip checks values written by Ollama's own Modelfile formatting, which is what the show API
+// returns, are read back unchanged
+func TestModelfileRoundTrip(t *testing.T) {
+	systems := []string{
+		"You are helpful",
+		" Leading and trailing spaces ",
+		"Line one\nLine two",
+		"Say \"ahoy\" first.\nThen answer.",
+		"Ends with a quote: \"done\"",
+		"Line one\nends with a \"quote\"",
+		"You are \"Bob\"",
+		"PARAMETER num_ctx 1\nis not a parameter",
+	}
+	for _, system := range systems {
+		f := parser.Modelfile{Commands: []parser.Command{
+			{Name: "model", Args: "/blobs/sha256-abc"},
+			{Name: "template", Args: "{{ .System }}\n{{ .Prompt }}"},
+			{Name: "system", Args: system},
+			{Name: "stop", Args: "<|eot_id|>"},
+		}}
+		modelfile := f.String()
+		template, got := ExtractTemplateAndSystem(modelfile)
+		if got != system {
+			t.Errorf("system read back from\n%s\n= %q, want %q", modelfile, got, system)
+		}
+		if template != "{{ .System }}\n{{ .Prompt }}" {
+			t.Errorf("template read back from\n%s\n= %q", modelfile, template)
+		}
+		if params := ExtractParameters(modelfile); !reflect.DeepEqual(params, map[string][]string{"stop": {"<|eot_id|>"}}) {
+			t.Errorf("parameters read back from\n%s\n= %v", modelfile, params)
+		}
+		if strings.Count(modelfile, "SYSTEM") != 1 {
+			t.Fatalf("unexpected modelfile:\n%s", modelfile)
+		}
+	}
+}
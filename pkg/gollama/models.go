@@ -0,0 +1,77 @@
+// models.go contains the Model struct and functions for listing and looking up models.
+package gollama
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Model is a model available in Ollama
+type Model struct {
+	Name              string
+	ID                string // short digest, as shown by `ollama list`
+	Digest            string
+	Size              int64 // bytes
+	QuantizationLevel string
+	Family            string
+	ParameterSize     string
+	Modified          time.Time
+}
+
+// SizeGB returns the size of the model in GB
+func (m Model) SizeGB() float64 {
+	return float64(m.Size) / (1024 * 1024 * 1024)
+}
+
+// ModelFromResponse converts an API list entry to a Model
+func ModelFromResponse(resp api.ListModelResponse) Model {
+	id := resp.Digest
+	if len(id) > 7 {
+		id = id[:7]
+	}
+	return Model{
+		Name:              resp.Name,
+		ID:                id,
+		Digest:            resp.Digest,
+		Size:              resp.Size,
+		QuantizationLevel: resp.Details.QuantizationLevel,
+		Family:            resp.Details.Family,
+		ParameterSize:     resp.Details.ParameterSize,
+		Modified:          resp.ModifiedAt,
+	}
+}
+
+// ListModels returns all models available in Ollama
+func ListModels(ctx context.Context, client *api.Client) ([]Model, error) {
+	resp, err := client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing models: %w", err)
+	}
+	return ModelsFromResponse(resp), nil
+}
+
+// ModelsFromResponse converts an API list response to Models
+func ModelsFromResponse(resp *api.ListResponse) []Model {
+	models := make([]Model, len(resp.Models))
+	for i, modelResp := range resp.Models {
+		models[i] = ModelFromResponse(modelResp)
+	}
+	return models
+}
+
+// GetModel returns the named model, or an error if it doesn't exist
+func GetModel(ctx context.Context, client *api.Client, name string) (*Model, error) {
+	models, err := ListModels(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range models {
+		if model.Name == name {
+			return &model, nil
+		}
+	}
+	return nil, fmt.Errorf("model %s not found", name)
+}
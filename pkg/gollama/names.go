@@ -0,0 +1,87 @@
+// names.go checks model names against Ollama's naming rules before they're sent to the API.
+package gollama
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/types/model"
+)
+
+// Length limits of the parts of a model name, as Ollama checks them
+const (
+	maxHostLength = 350
+	maxPartLength = 80
+)
+
+// ValidateModelName checks a name against Ollama's model name grammar, [host/][namespace/]model[:tag], so a bad name
+// is caught before a copy or create starts. Each part starts with a letter, digit or underscore and is followed by
+// those or "-" and "_". "." is allowed outside the namespace and ":" in the host for its port. Parts are at most 80
+// characters, the host 350.
+func ValidateModelName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("the name can't be empty")
+	}
+	if strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("%q can't contain spaces", name)
+	}
+	parsed := model.ParseNameBare(name)
+	parts := []struct {
+		kind  string
+		value string
+	}{
+		{"host", parsed.Host},
+		{"namespace", parsed.Namespace},
+		{"model", parsed.Model},
+		{"tag", parsed.Tag},
+	}
+	for _, part := range parts {
+		// Only the model is required, the other parts are only checked when the name has them
+		if part.value == "" && part.kind != "model" {
+			continue
+		}
+		if err := validateNamePart(part.kind, part.value); err != nil {
+			return fmt.Errorf("invalid model name %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateNamePart(kind, value string) error {
+	if value == "" || value == model.MissingPart {
+		return fmt.Errorf("the %s is missing", kind)
+	}
+	limit := maxPartLength
+	if kind == "host" {
+		limit = maxHostLength
+	}
+	if len(value) > limit {
+		return fmt.Errorf("the %s is longer than %d characters", kind, limit)
+	}
+	for i, r := range value {
+		switch {
+		case isNameAlphanumeric(r):
+		case i == 0:
+			return fmt.Errorf("the %s %q must start with a letter, digit or underscore", kind, value)
+		case r == '-':
+		case r == '.' && kind != "namespace":
+		case r == ':' && kind == "host":
+		default:
+			return fmt.Errorf("the %s %q can't contain %q", kind, value, r)
+		}
+	}
+	return nil
+}
+
+func isNameAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_'
+}
+
+// ModelNameWarning returns a warning about a valid name that will cause trouble later, empty when there's nothing to
+// warn about. Ollama accepts uppercase letters locally but registries reject them when the model is pushed.
+func ModelNameWarning(name string) string {
+	if name != strings.ToLower(name) {
+		return fmt.Sprintf("%s has uppercase letters, which registries reject when it's pushed", name)
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package gollama
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateModelName(t *testing.T) {
+	valid := []string{
+		"llama3",
+		"llama3:8b-instruct-q4_K_M",
+		"sammcj/llama3:latest",
+		"registry.internal:5000/team/qwen2.5:7b",
+		"_private/model_1:v1.2",
+		"Llama3:8B",
+	}
+	for _, name := range valid {
+		if err := ValidateModelName(name); err != nil {
+			t.Errorf("ValidateModelName(%q) = %v, want nil", name, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"":                                  "can't be empty",
+		"my model":                          "can't contain spaces",
+		"llama3:":                           "the tag is missing",
+		"/llama3":                           "the namespace is missing",
+		"-llama3":                           "must start with a letter, digit or underscore",
+		"llama3:8b:q4":                      `the model "llama3:8b" can't contain ':'`,
+		"my.team/llama3":                    `the namespace "my.team" can't contain '.'`,
+		"llama3@sha256":                     `can't contain '@'`,
+		"llama3:" + strings.Repeat("q", 81): "the tag is longer than 80 characters",
+		strings.Repeat("m", 81):             "the model is longer than 80 characters",
+	}
+	for name, want := range invalid {
+		err := ValidateModelName(name)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateModelName(%q) = %v, want an error containing %q", name, err, want)
+		}
+	}
+
+	if warning := ModelNameWarning("Llama3:8B"); !strings.Contains(warning, "uppercase") {
+		t.Errorf("ModelNameWarning() = %q, want an uppercase warning", warning)
+	}
+	if warning := ModelNameWarning("llama3:8b"); warning != "" {
+		t.Errorf("ModelNameWarning() = %q for a lowercase name", warning)
+	}
+}
@@ -0,0 +1,80 @@
+// operations.go contains the functions for pulling, pushing, copying and deleting models.
+package gollama
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/showcache"
+)
+
+// Progress is a progress update from a pull or push
+type Progress struct {
+	Status    string
+	Digest    string
+	Completed int64
+	Total     int64
+}
+
+// Fraction returns the completed fraction (0-1) of the current layer, or 0 if the total isn't known yet
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// ProgressFunc is called with each progress update, returning an error cancels the operation
+type ProgressFunc func(Progress) error
+
+func progressCallback(fn ProgressFunc) func(api.ProgressResponse) error {
+	return func(resp api.ProgressResponse) error {
+		if fn == nil {
+			return nil
+		}
+		return fn(Progress{
+			Status:    resp.Status,
+			Digest:    resp.Digest,
+			Completed: resp.Completed,
+			Total:     resp.Total,
+		})
+	}
+}
+
+// Pull pulls a model from its registry, progress may be nil. Like the other operations it drops the cached show
+// response of the model it changes.
+func Pull(ctx context.Context, client *api.Client, name string, progress ProgressFunc) error {
+	if err := client.Pull(ctx, &api.PullRequest{Name: name}, progressCallback(progress)); err != nil {
+		return fmt.Errorf("error pulling model %s: %w", name, err)
+	}
+	showcache.Invalidate(client, name)
+	return nil
+}
+
+// Push pushes a model to its registry, progress may be nil
+func Push(ctx context.Context, client *api.Client, name string, progress ProgressFunc) error {
+	if err := client.Push(ctx, &api.PushRequest{Name: name}, progressCallback(progress)); err != nil {
+		return fmt.Errorf("error pushing model %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete deletes a model
+func Delete(ctx context.Context, client *api.Client, name string) error {
+	if err := client.Delete(ctx, &api.DeleteRequest{Name: name}); err != nil {
+		return fmt.Errorf("error deleting model %s: %w", name, err)
+	}
+	showcache.Invalidate(client, name)
+	return nil
+}
+
+// Copy copies a model to a new name
+func Copy(ctx context.Context, client *api.Client, source, destination string) error {
+	if err := client.Copy(ctx, &api.CopyRequest{Source: source, Destination: destination}); err != nil {
+		return fmt.Errorf("error copying model %s to %s: %w", source, destination, err)
+	}
+	showcache.Invalidate(client, destination)
+	return nil
+}
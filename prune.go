@@ -0,0 +1,202 @@
+// prune.go finds blobs in the Ollama models directory that no manifest references, e.g. left behind by deleting models
+// outside Ollama, and optionally removes them.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// orphanBlob is a blob no manifest references
+type orphanBlob struct {
+	Path    string
+	Digest  string
+	Size    int64 // zero for symlinks as removing them frees nothing
+	Symlink bool
+}
+
+// referencedDigests returns the digest of every config and layer blob referenced by a manifest. It fails if any
+// manifest can't be read, as the blobs it references would otherwise look orphaned.
+func referencedDigests(modelsDir string) (map[string]bool, error) {
+	digests := make(map[string]bool)
+	err := filepath.WalkDir(filepath.Join(modelsDir, "manifests"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var manifest modelManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("error decoding manifest %s: %w", path, err)
+		}
+		if manifest.Config.Digest != "" {
+			digests[manifest.Config.Digest] = true
+		}
+		for _, layer := range manifest.Layers {
+			digests[layer.Digest] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifests: %w", err)
+	}
+	return digests, nil
+}
+
+// findOrphanBlobs lists the blobs in the models directory that no manifest references, largest first. Files that
+// aren't named like a blob, such as partial downloads, are left alone.
+func findOrphanBlobs(modelsDir string) ([]orphanBlob, error) {
+	referenced, err := referencedDigests(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	blobsDir := filepath.Join(modelsDir, "blobs")
+	entries, err := os.ReadDir(blobsDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading blobs: %w", err)
+	}
+
+	var orphans []orphanBlob
+	for _, entry := range entries {
+		match := blobNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil || entry.IsDir() {
+			continue
+		}
+		digest := "sha256:" + match[1]
+		if referenced[digest] {
+			continue
+		}
+		orphan := orphanBlob{Path: filepath.Join(blobsDir, entry.Name()), Digest: digest}
+		fi, err := os.Lstat(orphan.Path)
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			orphan.Symlink = true
+		} else {
+			orphan.Size = fi.Size()
+		}
+		orphans = append(orphans, orphan)
+	}
+	sort.SliceStable(orphans, func(i, j int) bool { return orphans[i].Size > orphans[j].Size })
+	return orphans, nil
+}
+
+// removeOrphanBlobs removes the orphaned blobs and returns the space freed. Symlinks are removed themselves,
+// their targets are never touched.
+func removeOrphanBlobs(orphans []orphanBlob) (int64, []error) {
+	var freed int64
+	var errs []error
+	for _, orphan := range orphans {
+		if err := os.Remove(orphan.Path); err != nil {
+			errs = append(errs, fmt.Errorf("error removing %s: %w", orphan.Path, err))
+			continue
+		}
+		logging.InfoLogger.Printf("Removed orphaned blob %s\n", orphan.Path)
+		freed += orphan.Size
+	}
+	return freed, errs
+}
+
+// isLocalAPIURL reports whether every address the API URL's host resolves to is this machine's: a loopback or
+// unspecified address or one of its interfaces'. Pruning reads the local blobs, so a remote API would make every one of
+// them look orphaned. A host that can't be resolved isn't local.
+func isLocalAPIURL(apiURL string) bool {
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		logging.DebugLogger.Printf("Couldn't resolve the API host %s: %v\n", u.Hostname(), err)
+		return false
+	}
+
+	interfaceIPs := make(map[string]bool)
+	if ifaceAddrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range ifaceAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				interfaceIPs[ipNet.IP.String()] = true
+			}
+		}
+	}
+	for _, addr := range addrs {
+		if !addr.IP.IsLoopback() && !addr.IP.IsUnspecified() && !interfaceIPs[addr.IP.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// runPrune implements -prune and -prune-delete, returning the process exit code
+func runPrune(modelsDir string, deleteBlobs bool) int {
+	orphans, err := findOrphanBlobs(modelsDir)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned blobs found")
+		return 0
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Blob\tSize")
+	for _, orphan := range orphans {
+		size := formatBytes(orphan.Size)
+		if orphan.Symlink {
+			size = "symlink"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", filepath.Base(orphan.Path), size)
+		total += orphan.Size
+	}
+	w.Flush()
+	fmt.Printf("\n%d orphaned blob(s), %s\n", len(orphans), formatBytes(total))
+	if !deleteBlobs {
+		return 0
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Run -prune-delete from a terminal to confirm removing these blobs")
+		return 1
+	}
+	fmt.Printf("Remove %d orphaned blob(s)? [y/N] ", len(orphans))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Println("No blobs removed")
+		return 0
+	}
+
+	freed, errs := removeOrphanBlobs(orphans)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	fmt.Printf("Reclaimed %s\n", formatBytes(freed))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindOrphanBlobs(t *testing.T) {
+	modelsDir := t.TempDir()
+	blobsDir := filepath.Join(modelsDir, "blobs")
+	digest := func(c byte) string { return "sha256:" + strings.Repeat(string(c), 64) }
+	write := func(path string, size int) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest, _ := json.Marshal(modelManifest{
+		Config: manifestLayer{Digest: digest('a')},
+		Layers: []manifestLayer{{Digest: digest('b')}},
+	})
+	write(manifestPath(modelsDir, "llama3:8b"), 0) // creates the directories
+	if err := os.WriteFile(manifestPath(modelsDir, "llama3:8b"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	write(blobPath(modelsDir, digest('a')), 10)
+	write(blobPath(modelsDir, digest('b')), 20)
+	write(blobPath(modelsDir, digest('c')), 30)
+	write(blobPath(modelsDir, digest('e'))+"-partial", 40)
+	// A symlinked blob, like those created when linking LM Studio models, whose target must survive pruning
+	target := filepath.Join(t.TempDir(), "model.gguf")
+	write(target, 50)
+	if err := os.Symlink(target, blobPath(modelsDir, digest('d'))); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := findOrphanBlobs(modelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 2 || orphans[0].Digest != digest('c') || orphans[0].Size != 30 ||
+		orphans[1].Digest != digest('d') || !orphans[1].Symlink || orphans[1].Size != 0 {
+		t.Fatalf("findOrphanBlobs() = %+v, want the unreferenced blob and symlink", orphans)
+	}
+
+	freed, errs := removeOrphanBlobs(orphans)
+	if freed != 30 || len(errs) != 0 {
+		t.Errorf("removeOrphanBlobs() = %d, %v, want 30 bytes freed", freed, errs)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("symlink target was removed: %v", err)
+	}
+	entries, _ := os.ReadDir(blobsDir)
+	if len(entries) != 3 {
+		t.Errorf("%d files left in blobs, want the two referenced blobs and the partial download", len(entries))
+	}
+
+	write(manifestPath(modelsDir, "broken:latest"), 0)
+	if _, err := findOrphanBlobs(modelsDir); err == nil {
+		t.Error("findOrphanBlobs() with an unreadable manifest succeeded, want an error")
+	}
+}
+
+func TestIsLocalAPIURL(t *testing.T) {
+	tests := []struct {
+		apiURL string
+		want   bool
+	}{
+		{"http://localhost:11434", true},
+		{"http://127.0.0.1:11434", true},
+		{"http://[::1]:11434", true},
+		{"http://0.0.0.0:11434", true},
+		{"http://192.0.2.10:11434", false},
+		{"http://localhost.invalid:11434", false},
+		{"http://127.0.0.1.invalid:11434", false},
+		{"localhost:11434", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalAPIURL(tt.apiURL); got != tt.want {
+			t.Errorf("isLocalAPIURL(%q) = %v, want %v", tt.apiURL, got, tt.want)
+		}
+	}
+
+	// An address of one of this machine's interfaces is local too
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && !ipNet.IP.IsLoopback() {
+			apiURL := "http://" + ipNet.IP.String() + ":11434"
+			if !isLocalAPIURL(apiURL) {
+				t.Errorf("isLocalAPIURL(%q) = false for a local interface address", apiURL)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// pull_batch.go pulls several models from the command line without the TUI, e.g. for scripted refreshes on a headless machine.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// batchPullResult is the outcome of pulling one model
+type batchPullResult struct {
+	Model    string
+	Err      error
+	Duration time.Duration
+	Size     int64
+}
+
+// pullModels pulls each model, at most parallel at a time, writing a progress line to out every 10% of each pull
+func pullModels(ctx context.Context, client *api.Client, names []string, parallel int, out io.Writer) []batchPullResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]batchPullResult, len(names))
+	var outMu sync.Mutex
+	printf := func(format string, args ...any) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		fmt.Fprintf(out, format, args...)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = pullOne(ctx, client, name, printf)
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+func pullOne(ctx context.Context, client *api.Client, name string, printf func(string, ...any)) batchPullResult {
+	result := batchPullResult{Model: name}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+
+	printf("%s: starting pull\n", name)
+	start := time.Now()
+	layers := newPullLayers()
+	progress, lastStep := 0.0, -1
+	err := client.Pull(ctx, &api.PullRequest{Name: name}, func(resp api.ProgressResponse) error {
+		progress = layers.progress(resp, progress)
+		if step := int(progress * 10); step != lastStep {
+			lastStep = step
+			printf("%s: %s %d%%\n", name, resp.Status, int(progress*100))
+		}
+		return nil
+	})
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = err
+		printf("%s: failed: %v\n", name, err)
+		return result
+	}
+
+	recordPull(client, name)
+	if model, err := findModel(client, name); err == nil && model != nil {
+		result.Size = model.Size
+	}
+	printf("%s: done in %s\n", name, result.Duration.Round(time.Second))
+	return result
+}
+
+// printPullSummary writes a table of the pull results and returns the number that failed
+func printPullSummary(out io.Writer, results []batchPullResult) int {
+	failed := 0
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Model\tStatus\tDuration\tSize")
+	for _, result := range results {
+		status, size := "ok", fmt.Sprintf("%.2fGB", float64(result.Size)/(1024*1024*1024))
+		if result.Err != nil {
+			failed++
+			status, size = "failed: "+result.Err.Error(), "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Model, status, result.Duration.Round(time.Second), size)
+	}
+	w.Flush()
+	return failed
+}
+
+// runBatchPull implements the -pull flag, returning the process exit code
+func runBatchPull(ctx context.Context, client *api.Client, names []string, parallel int) int {
+	if len(names) == 0 {
+		fmt.Println("Usage: gollama -pull [-parallel N] <model> [model...]")
+		return 1
+	}
+	results := pullModels(ctx, client, names, parallel, os.Stdout)
+	fmt.Println()
+	failed := printPullSummary(os.Stdout, results)
+	if ctx.Err() != nil {
+		fmt.Println("Pulling was cancelled, run the same command again to resume")
+		return 130
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d pulls failed\n", failed, len(results))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestPullModels(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/pull":
+			var req api.PullRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model == "missing:latest" || req.Name == "missing:latest" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"pull model manifest: file does not exist"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":50}` + "\n"))
+			w.Write([]byte(`{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":100}` + "\n"))
+			w.Write([]byte(`{"status":"success"}` + "\n"))
+		case "/api/tags":
+			json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{{Name: "llama3:latest", Digest: "sha256:abc", Size: 100}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	var out strings.Builder
+	results := pullModels(context.Background(), client, []string{"llama3:latest", "missing:latest"}, 2, &out)
+	if len(results) != 2 || results[0].Err != nil || results[0].Size != 100 || results[1].Err == nil {
+		t.Fatalf("pullModels() = %+v", results)
+	}
+	if !strings.Contains(out.String(), "llama3:latest: pulling abc 50%") {
+		t.Errorf("pullModels() output missing progress lines:\n%s", out.String())
+	}
+
+	var summary strings.Builder
+	if failed := printPullSummary(&summary, results); failed != 1 {
+		t.Errorf("printPullSummary() = %d failed, want 1", failed)
+	}
+}
@@ -0,0 +1,93 @@
+// pull_confirm.go asks before pulling a model that already exists locally, as a re-pull can download tens of GB
+// again. The check lists the models afresh so it's right after models are pulled or deleted outside gollama.
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// pullConfirm is a pull of a model that exists locally, waiting for the user to confirm re-pulling it
+type pullConfirm struct {
+	name     string
+	size     int64
+	modified time.Time
+}
+
+// prompt asks to confirm the re-pull, e.g. "qwen2:7b exists locally (18.2GB, modified 2025-01-03). Re-pull? y/N"
+func (p pullConfirm) prompt() string {
+	return fmt.Sprintf("%s exists locally (%.1fGB, modified %s). Re-pull? y/N",
+		p.name, float64(p.size)/(1024*1024*1024), p.modified.Format("2006-01-02"))
+}
+
+// pullConfirmMsg is the result of checking whether a model about to be pulled exists locally, confirm is nil when it
+// doesn't
+type pullConfirmMsg struct {
+	name    string
+	confirm *pullConfirm
+	err     error
+}
+
+// pullModel starts pulling a model, or with confirm_repull on checks whether it exists locally in the background first
+func (m *AppModel) pullModel(name string) tea.Cmd {
+	if m.cfg == nil || !m.cfg.ConfirmRepull || m.client == nil {
+		return m.startPull(name)
+	}
+	m.message = fmt.Sprintf("Checking whether %s exists locally...", name)
+	client := m.client
+	return func() tea.Msg {
+		existing, err := findModel(client, name)
+		if err != nil {
+			return pullConfirmMsg{name: name, err: fmt.Errorf("error checking whether %s exists: %w", name, err)}
+		}
+		if existing == nil {
+			return pullConfirmMsg{name: name}
+		}
+		return pullConfirmMsg{name: name, confirm: &pullConfirm{name: existing.Name, size: existing.Size, modified: existing.ModifiedAt}}
+	}
+}
+
+// startPull shows the progress of a pull and starts it
+func (m *AppModel) startPull(name string) tea.Cmd {
+	m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Pulling model: %s\n", name))
+	m.pulling = true
+	m.pullProgress = 0
+	m.pullLayers = newPullLayers()
+	return m.startPullModel(name)
+}
+
+// handlePullConfirmMsg asks to confirm pulling a model that exists locally, and pulls one that doesn't straight away
+func (m *AppModel) handlePullConfirmMsg(msg pullConfirmMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.message = msg.err.Error()
+		return m, nil
+	}
+	if msg.confirm != nil {
+		m.pullConfirm = msg.confirm
+		m.message = ""
+		return m, nil
+	}
+	return m, m.startPull(msg.name)
+}
+
+// handlePullConfirmKey re-pulls the model with y, any other key cancels
+func (m *AppModel) handlePullConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	confirm := m.pullConfirm
+	m.pullConfirm = nil
+	if !key.Matches(msg, m.keys.ConfirmYes) {
+		logging.InfoLogger.Printf("Re-pulling %s cancelled by user\n", confirm.name)
+		m.message = "Cancelled"
+		return m, nil
+	}
+	return m, m.startPull(confirm.name)
+}
+
+func (m *AppModel) pullConfirmView() string {
+	return "\n" + m.pullConfirm.prompt() + "\n"
+}
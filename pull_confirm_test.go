@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestPullConfirm(t *testing.T) {
+	var lists, pulls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			lists++
+			modified := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC)
+			json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{{Name: "llama3:latest", Size: 18_200 << 20, ModifiedAt: modified}}})
+		case "/api/pull":
+			pulls++
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	model := Model{}
+	model.Name = "llama3:latest"
+	m := &AppModel{
+		client: api.NewClient(u, server.Client()),
+		cfg:    &config.Config{ConfirmRepull: true},
+		list:   list.New([]list.Item{model}, list.NewDefaultDelegate(), 80, 40),
+		keys:   *NewKeyMap(),
+	}
+	press := func(k string) tea.Cmd {
+		_, cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		return cmd
+	}
+	// The models are listed in the background, the check's result starts the pull or asks to confirm it
+	check := func(cmd tea.Cmd) tea.Cmd {
+		msg, ok := cmd().(pullConfirmMsg)
+		if !ok {
+			return cmd
+		}
+		_, cmd = m.Update(msg)
+		return cmd
+	}
+	pullSelected := func() tea.Cmd {
+		_, cmd := m.handlePullModelKey()
+		return check(cmd)
+	}
+
+	// Pulling a model from the list asks first and anything but y cancels
+	_, cmd := m.handlePullModelKey()
+	if m.pullConfirm != nil || m.pulling || !strings.Contains(m.message, "Checking whether llama3:latest exists") {
+		t.Errorf("pull started or asked to confirm before the check, message %q", m.message)
+	}
+	check(cmd)
+	if m.pullConfirm == nil || m.pulling {
+		t.Fatal("pulling an existing model didn't ask to confirm")
+	}
+	if got, want := m.pullConfirmView(), "llama3:latest exists locally (17.8GB, modified 2025-01-03). Re-pull? y/N"; !strings.Contains(got, want) {
+		t.Errorf("confirmation = %q, want %q", got, want)
+	}
+	press("n")
+	if m.pullConfirm != nil || m.pulling || m.message != "Cancelled" {
+		t.Errorf("n left confirm %v, pulling %v, message %q", m.pullConfirm, m.pulling, m.message)
+	}
+	pullSelected()
+	if cmd := press("y"); cmd == nil || !m.pulling || m.pullConfirm != nil {
+		t.Error("y didn't start the pull")
+	}
+	m.endPull()
+	m.pulling = false
+
+	// A name typed in the pull new model prompt is checked against a fresh list too
+	enter := func(name string) tea.Cmd {
+		m.handlePullNewModelKey()
+		m.pullInput.SetValue(name)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return check(cmd)
+	}
+	enter("llama3")
+	if m.pullConfirm == nil || m.pulling || m.newModelPull {
+		t.Error("entering an existing model's name didn't ask to confirm")
+	}
+	press("n")
+	if cmd := enter("mistral"); cmd == nil || m.pullConfirm != nil || !m.pulling {
+		t.Error("entering a new model's name didn't start the pull")
+	}
+	m.endPull()
+	m.pulling = false
+
+	// With confirm_repull off the models aren't listed
+	m.cfg.ConfirmRepull = false
+	before := lists
+	if _, cmd := m.handlePullModelKey(); cmd == nil || m.pullConfirm != nil || !m.pulling || lists != before {
+		t.Error("pulling with confirm_repull off asked to confirm")
+	}
+	m.endPull()
+	if pulls != 0 {
+		t.Errorf("pulls = %d, want none as the pull commands weren't run", pulls)
+	}
+}
@@ -0,0 +1,185 @@
+// pull_keep_config.go pulls a model while keeping its template, system prompt and parameters. The original modelfile is
+// snapshotted to the config directory first, so if restoring it after the pull fails (e.g. against a remote host) the
+// customisations aren't lost and the restore can be retried.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// snapshotRetention is how long modelfile snapshots are kept before they're cleaned up
+const snapshotRetention = 30 * 24 * time.Hour
+
+// keepConfigPullMsg is the result of pulling a model and restoring its config, err is the restore's error
+type keepConfigPullMsg struct {
+	modelName string
+	snapshot  string
+	err       error
+}
+
+// restoreMsg is the result of retrying a restore from a snapshot
+type restoreMsg keepConfigPullMsg
+
+// pendingRestore is a snapshot whose restore failed and can be retried
+type pendingRestore struct {
+	modelName string
+	snapshot  string
+}
+
+// snapshotsDir is where modelfiles are snapshotted before a pull
+func snapshotsDir() string {
+	return filepath.Join(utils.GetConfigDir(), "snapshots")
+}
+
+// snapshotModelfile writes a model's full modelfile to a timestamped file in dir, cleaning up expired snapshots first
+func snapshotModelfile(ctx context.Context, client *api.Client, modelName, dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	cleanupSnapshots(dir, now.Add(-snapshotRetention))
+	path := filepath.Join(dir, now.Format("20060102-150405-")+modelfileFileName(modelName))
+	if err := exportModelfile(ctx, client, modelName, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// cleanupSnapshots removes the snapshots last written before cutoff
+func cleanupSnapshots(dir string, cutoff time.Time) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.modelfile"))
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil || !fi.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logging.ErrorLogger.Printf("Error removing expired snapshot %s: %v\n", path, err)
+			continue
+		}
+		logging.DebugLogger.Printf("Removed expired snapshot %s\n", path)
+	}
+}
+
+// unrestoredFields returns the template, system prompt and parameter fields of want that got doesn't have the same
+// values for, sorted
+func unrestoredFields(want, got map[string][]string) []string {
+	var fields []string
+	for field, values := range want {
+		if !slices.Equal(got[field], values) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// restoreSnapshot re-applies the template, system prompt and parameters in a snapshot to a model, then checks the
+// model's modelfile shows them
+func restoreSnapshot(ctx context.Context, client *api.Client, modelName, snapshot string) error {
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return fmt.Errorf("error reading snapshot: %w", err)
+	}
+	want := modelfileValues(string(data))
+	if len(want) == 0 {
+		return nil
+	}
+	if err := applyEdit(ctx, client, pendingEdit{Model: modelName, Values: want}); err != nil {
+		return fmt.Errorf("error restoring the config of %s: %w", modelName, err)
+	}
+
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return fmt.Errorf("error checking the restored config of %s: %w", modelName, err)
+	}
+	if fields := unrestoredFields(want, modelfileValues(show.Modelfile)); len(fields) > 0 {
+		return fmt.Errorf("%s doesn't have its original %s after restoring", modelName, strings.Join(fields, ", "))
+	}
+	recordHistory("restore", modelName, snapshot)
+	return nil
+}
+
+func (m *AppModel) handlePullKeepConfigKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("PullKeepConfig key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	snapshot, err := snapshotModelfile(context.Background(), m.client, item.Name, snapshotsDir(), time.Now())
+	if err != nil {
+		m.message = fmt.Sprintf("Error saving the modelfile of %s before pulling, not pulling: %v", item.Name, err)
+		return m, nil
+	}
+	m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Pulling model: %s (keeping its config)\n", item.Name))
+	m.pulling = true
+	m.pullProgress = 0
+	m.pullLayers = newPullLayers()
+	return m, m.pullKeepConfigCmd(item.Name, snapshot)
+}
+
+// pullKeepConfigCmd pulls the model to completion, then restores its config from the snapshot
+func (m *AppModel) pullKeepConfigCmd(modelName, snapshot string) tea.Cmd {
+	ctx, tick := m.beginPull()
+	client, layers, feed := m.client, m.pullLayers, m.pullFeed
+	return tea.Batch(tick, func() tea.Msg {
+		err := client.Pull(ctx, &api.PullRequest{Name: modelName}, trackPull(layers, feed))
+		if ctx.Err() != nil {
+			logging.InfoLogger.Printf("Pull of %s cancelled, its config wasn't restored\n", modelName)
+			return nil
+		}
+		if err != nil {
+			return pullErrorMsg{err}
+		}
+		return keepConfigPullMsg{modelName: modelName, snapshot: snapshot, err: restoreSnapshot(context.Background(), client, modelName, snapshot)}
+	})
+}
+
+func (m *AppModel) handleKeepConfigPullMsg(msg keepConfigPullMsg) (tea.Model, tea.Cmd) {
+	model, cmd := m.handlePullSuccessMsg(pullSuccessMsg{modelName: msg.modelName})
+	m.setRestoreResult(msg.modelName, msg.snapshot, msg.err)
+	return model, cmd
+}
+
+func (m *AppModel) handleRetryRestoreKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("RetryRestore key matched")
+	pending := m.pendingRestore
+	if pending == nil {
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Restoring the config of %s from %s", pending.modelName, pending.snapshot)
+	client := m.client
+	return m, func() tea.Msg {
+		return restoreMsg{modelName: pending.modelName, snapshot: pending.snapshot, err: restoreSnapshot(context.Background(), client, pending.modelName, pending.snapshot)}
+	}
+}
+
+func (m *AppModel) handleRestoreMsg(msg restoreMsg) (tea.Model, tea.Cmd) {
+	m.setRestoreResult(msg.modelName, msg.snapshot, msg.err)
+	return m, nil
+}
+
+// setRestoreResult reports the result of restoring a snapshot, keeping it to retry when the restore failed
+func (m *AppModel) setRestoreResult(modelName, snapshot string, err error) {
+	if err != nil {
+		logging.ErrorLogger.Printf("Error restoring %s from %s: %v\n", modelName, snapshot, err)
+		m.pendingRestore = &pendingRestore{modelName: modelName, snapshot: snapshot}
+		m.message = fmt.Sprintf("Pulled %s but its config wasn't restored: %v\nThe original modelfile is saved in %s, press ctrl+r to retry", modelName, err, snapshot)
+		return
+	}
+	m.pendingRestore = nil
+	m.message = fmt.Sprintf("Pulled %s and restored its template, system prompt and parameters", modelName)
+}
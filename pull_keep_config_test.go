@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+)
+
+func TestPullKeepConfigSnapshot(t *testing.T) {
+	original := "FROM /models/blobs/sha256-" + strings.Repeat("ab", 32) + "\nSYSTEM \"\"\"You are a pirate\"\"\"\nPARAMETER num_ctx 16384\n"
+	pulled := "FROM /models/blobs/sha256-" + strings.Repeat("cd", 32) + "\nPARAMETER num_ctx 2048\n"
+	modelfile := original
+	createStatus := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfile})
+		case "/api/pull":
+			modelfile = pulled
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+		case "/api/create":
+			if createStatus != http.StatusOK {
+				w.WriteHeader(createStatus)
+				json.NewEncoder(w).Encode(map[string]string{"error": "create failed"})
+				return
+			}
+			var req api.CreateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			modelfile = fmt.Sprintf("FROM x\nSYSTEM \"\"\"%s\"\"\"\nPARAMETER num_ctx %v\n", req.System, req.Parameters["num_ctx"])
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	// An expired snapshot is cleaned up when the next one is taken
+	expired := filepath.Join(dir, "20200101-000000-old.modelfile")
+	os.WriteFile(expired, []byte("FROM old"), 0o644)
+	os.Chtimes(expired, time.Now().Add(-snapshotRetention-time.Hour), time.Now().Add(-snapshotRetention-time.Hour))
+
+	snapshot, err := snapshotModelfile(context.Background(), client, "pirate:latest", dir, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("expired snapshot wasn't cleaned up")
+	}
+
+	m := &AppModel{client: client, pulling: true, pullLayers: newPullLayers()}
+	// The command batches the progress tick with the pull
+	msg, ok := m.pullKeepConfigCmd("pirate:latest", snapshot)().(tea.BatchMsg)[1]().(keepConfigPullMsg)
+	if !ok || msg.err == nil {
+		t.Fatalf("pull with a failing create = %+v, want a restore error", msg)
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil || !strings.Contains(string(data), "You are a pirate") {
+		t.Fatalf("snapshot %s = %q, %v, want the original system prompt", snapshot, data, err)
+	}
+	m.setRestoreResult(msg.modelName, msg.snapshot, msg.err)
+	if m.pendingRestore == nil || !strings.Contains(m.message, snapshot) {
+		t.Errorf("failed restore left pendingRestore %+v and message %q", m.pendingRestore, m.message)
+	}
+
+	// A create that succeeds but leaves the model without its config fails verification
+	createStatus = http.StatusOK
+	modelfile = pulled
+	if fields := unrestoredFields(modelfileValues(original), modelfileValues(pulled)); !slices.Equal(fields, []string{"num_ctx", "system"}) {
+		t.Errorf("unrestoredFields() = %v", fields)
+	}
+
+	// Retrying restores the original config
+	_, cmd := m.handleRetryRestoreKey()
+	retried := cmd().(restoreMsg)
+	if retried.err != nil {
+		t.Fatalf("retrying the restore failed: %v", retried.err)
+	}
+	m.handleRestoreMsg(retried)
+	if m.pendingRestore != nil || !strings.Contains(modelfile, "You are a pirate") {
+		t.Errorf("after retrying, pendingRestore = %+v and modelfile = %q", m.pendingRestore, modelfile)
+	}
+}
@@ -0,0 +1,283 @@
+// pull_layers.go tracks the layers seen while pulling or pushing a model so they can be shown under the progress bar.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/styles"
+)
+
+// Layer states shown in the pull panel
+const (
+	layerPending     = "pending"
+	layerDownloading = "downloading"
+	layerPushing     = "pushing"
+	layerVerifying   = "verifying"
+	layerDone        = "done"
+)
+
+// pullLayer is the progress of a single layer in a pull
+type pullLayer struct {
+	Digest    string
+	Total     int64
+	Completed int64
+	Status    string
+}
+
+// pullLayers collects layer progress from the pull callback, it's written from the pull goroutine and read when rendering
+type pullLayers struct {
+	mu     sync.Mutex
+	order  []string
+	layers map[string]*pullLayer
+	active string // status of a layer that's transferring, downloading or pushing
+	status string // the last status Ollama reported
+	rate   *transferRate
+
+	// view state, only touched from Update and View
+	expanded bool
+	offset   int
+}
+
+func newPullLayers() *pullLayers {
+	return &pullLayers{layers: make(map[string]*pullLayer), active: layerDownloading, rate: newTransferRate()}
+}
+
+// newPushLayers tracks the layers of a push, which go through the same states as a pull
+func newPushLayers() *pullLayers {
+	return &pullLayers{layers: make(map[string]*pullLayer), active: layerPushing, rate: newTransferRate()}
+}
+
+// update records a progress response from the pull callback
+func (p *pullLayers) update(resp api.ProgressResponse) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if resp.Status != "" {
+		p.status = resp.Status
+	}
+	if p.rate != nil {
+		p.rate.add(resp, time.Now())
+	}
+
+	if resp.Digest != "" {
+		layer, ok := p.layers[resp.Digest]
+		if !ok {
+			layer = &pullLayer{Digest: resp.Digest}
+			p.layers[resp.Digest] = layer
+			p.order = append(p.order, resp.Digest)
+		}
+		if resp.Total > 0 {
+			layer.Total = resp.Total
+		}
+		layer.Completed = resp.Completed
+		switch {
+		case layer.Total > 0 && layer.Completed >= layer.Total:
+			layer.Status = layerDone
+		case layer.Completed > 0:
+			layer.Status = p.active
+		default:
+			layer.Status = layerPending
+		}
+		return
+	}
+
+	// Status only responses apply to every layer that has finished downloading
+	status := strings.ToLower(resp.Status)
+	for _, layer := range p.layers {
+		switch {
+		case strings.HasPrefix(status, "verifying") && layer.Status == layerDone:
+			layer.Status = layerVerifying
+		case (strings.HasPrefix(status, "writing") || status == "success") && layer.Status == layerVerifying:
+			layer.Status = layerDone
+		}
+	}
+}
+
+// progress records a progress response and returns the overall fraction of the pull. It's computed from the sum of
+// every layer seen so far rather than the current layer, and never drops below previous, so the bar doesn't jump
+// around as the pull moves between layers of different sizes. It works on a nil pullLayers using the response alone.
+func (p *pullLayers) progress(resp api.ProgressResponse, previous float64) float64 {
+	fraction := previous
+	if p != nil {
+		p.update(resp)
+		p.mu.Lock()
+		var completed, total int64
+		for _, layer := range p.layers {
+			completed += layer.Completed
+			total += layer.Total
+		}
+		p.mu.Unlock()
+		if total > 0 {
+			fraction = float64(completed) / float64(total)
+		}
+	} else if resp.Total > 0 {
+		fraction = float64(resp.Completed) / float64(resp.Total)
+	}
+	if fraction < previous {
+		return previous
+	}
+	return fraction
+}
+
+// lastStatus returns the last status Ollama reported, e.g. "pushing manifest"
+func (p *pullLayers) lastStatus() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// rateSummary returns the speed, time left and bytes transferred for the line under the progress bar, empty before
+// Ollama has reported anything
+func (p *pullLayers) rateSummary(fraction float64) string {
+	if p == nil || p.rate == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.layers) == 0 && p.rate.status == "" {
+		return ""
+	}
+	return p.rate.summary(fraction)
+}
+
+// snapshot returns a copy of the layers in the order they were first seen
+func (p *pullLayers) snapshot() []pullLayer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	layers := make([]pullLayer, 0, len(p.order))
+	for _, digest := range p.order {
+		layers = append(layers, *p.layers[digest])
+	}
+	return layers
+}
+
+// scroll moves the panel by delta rows, keeping visible rows of it on screen
+func (p *pullLayers) scroll(delta, visible int) {
+	p.offset += delta
+	p.mu.Lock()
+	maxOffset := len(p.order) - visible
+	p.mu.Unlock()
+	if p.offset > maxOffset {
+		p.offset = maxOffset
+	}
+	if p.offset < 0 {
+		p.offset = 0
+	}
+}
+
+// pullLayerRows is how many layers the panel shows at once for the terminal height
+func (m *AppModel) pullLayerRows() int {
+	rows := m.height - 10
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// pullLayersView renders the layer panel, or a hint about how to open it when it's collapsed
+func (m *AppModel) pullLayersView() string {
+	if m.pullLayers == nil {
+		return ""
+	}
+	if !m.pullLayers.expanded {
+		return "Press d to show layer details"
+	}
+
+	layers := m.pullLayers.snapshot()
+	if len(layers) == 0 {
+		return "Waiting for layers... (d to hide)"
+	}
+
+	colours := styles.Current().Colours
+	faint := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
+	statusStyles := layerStatusStyles()
+
+	rows := m.pullLayerRows()
+	m.pullLayers.scroll(0, rows)
+	start := m.pullLayers.offset
+	end := start + rows
+	if end > len(layers) {
+		end = len(layers)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-14s %10s  %-12s %s\n", "Layer", "Size", "Status", "Progress"))
+	for _, layer := range layers[start:end] {
+		digest := strings.TrimPrefix(layer.Digest, "sha256:")
+		percent := 0.0
+		if layer.Total > 0 {
+			percent = float64(layer.Completed) / float64(layer.Total) * 100
+		}
+		b.WriteString(fmt.Sprintf("%-14s %10s  %s %5.1f%%\n",
+			truncate(digest, 12),
+			formatBytes(layer.Total),
+			statusStyles[layer.Status].Render(fmt.Sprintf("%-12s", layer.Status)),
+			percent,
+		))
+	}
+	b.WriteString(faint.Render(fmt.Sprintf("%d-%d of %d layers, up/down to scroll, d to hide", start+1, end, len(layers))))
+	return b.String()
+}
+
+// layerStatusStyles returns the style each layer status is rendered in
+func layerStatusStyles() map[string]lipgloss.Style {
+	colours := styles.Current().Colours
+	active := lipgloss.NewStyle().Foreground(colours.Highlight.TerminalColour())
+	return map[string]lipgloss.Style{
+		layerPending:     lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour()),
+		layerDownloading: active,
+		layerPushing:     active,
+		layerVerifying:   lipgloss.NewStyle().Foreground(colours.Warning.TerminalColour()),
+		layerDone:        lipgloss.NewStyle().Foreground(colours.Message.TerminalColour()),
+	}
+}
+
+// pushLayersView renders a status line for each layer of the push in progress and the last status reported
+func (m *AppModel) pushLayersView() string {
+	if m.pushLayers == nil {
+		return ""
+	}
+	statusStyles := layerStatusStyles()
+	var b strings.Builder
+	for _, layer := range m.pushLayers.snapshot() {
+		percent := 0.0
+		if layer.Total > 0 {
+			percent = float64(layer.Completed) / float64(layer.Total) * 100
+		}
+		b.WriteString(fmt.Sprintf("%-14s %10s  %s %5.1f%%\n",
+			truncate(strings.TrimPrefix(layer.Digest, "sha256:"), 12),
+			formatBytes(layer.Total),
+			statusStyles[layer.Status].Render(fmt.Sprintf("%-12s", layer.Status)),
+			percent,
+		))
+	}
+	if status := m.pushLayers.lastStatus(); status != "" {
+		b.WriteString(statusStyles[layerPending].Render(status))
+	}
+	return b.String()
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestPullLayersUpdate(t *testing.T) {
+	layers := newPullLayers()
+	responses := []api.ProgressResponse{
+		{Status: "pulling manifest"},
+		{Status: "pulling aaa", Digest: "sha256:aaa", Total: 100},
+		{Status: "pulling bbb", Digest: "sha256:bbb", Total: 10},
+		{Status: "pulling aaa", Digest: "sha256:aaa", Total: 100, Completed: 40},
+		{Status: "pulling bbb", Digest: "sha256:bbb", Total: 10, Completed: 10},
+	}
+	for _, resp := range responses {
+		layers.update(resp)
+	}
+
+	got := layers.snapshot()
+	if len(got) != 2 || got[0].Digest != "sha256:aaa" || got[1].Digest != "sha256:bbb" {
+		t.Fatalf("snapshot() = %+v, want layers aaa then bbb", got)
+	}
+	if got[0].Status != layerDownloading || got[0].Completed != 40 {
+		t.Errorf("layer aaa = %+v, want downloading at 40", got[0])
+	}
+	if got[1].Status != layerDone {
+		t.Errorf("layer bbb status = %s, want %s", got[1].Status, layerDone)
+	}
+
+	layers.update(api.ProgressResponse{Status: "pulling aaa", Digest: "sha256:aaa", Total: 100, Completed: 100})
+	layers.update(api.ProgressResponse{Status: "verifying sha256 digest"})
+	for _, layer := range layers.snapshot() {
+		if layer.Status != layerVerifying {
+			t.Errorf("layer %s status = %s after verifying, want %s", layer.Digest, layer.Status, layerVerifying)
+		}
+	}
+	layers.update(api.ProgressResponse{Status: "success"})
+	for _, layer := range layers.snapshot() {
+		if layer.Status != layerDone {
+			t.Errorf("layer %s status = %s after success, want %s", layer.Digest, layer.Status, layerDone)
+		}
+	}
+
+	// Scrolling is clamped to the layers available
+	layers.scroll(5, 1)
+	if layers.offset != 1 {
+		t.Errorf("offset = %d after scrolling past the end, want 1", layers.offset)
+	}
+	layers.scroll(-5, 1)
+	if layers.offset != 0 {
+		t.Errorf("offset = %d after scrolling past the start, want 0", layers.offset)
+	}
+
+	// A nil tracker ignores updates
+	var none *pullLayers
+	none.update(api.ProgressResponse{Digest: "sha256:aaa"})
+}
+
+func TestPullLayersProgress(t *testing.T) {
+	layers := newPullLayers()
+	steps := []struct {
+		resp api.ProgressResponse
+		want float64
+	}{
+		{api.ProgressResponse{Digest: "sha256:big", Total: 900, Completed: 450}, 0.5},
+		// A small new layer would have made the single layer bar jump to 0, the aggregate only moves forward
+		{api.ProgressResponse{Digest: "sha256:small", Total: 100, Completed: 0}, 0.5},
+		{api.ProgressResponse{Digest: "sha256:small", Total: 100, Completed: 100}, 0.55},
+		{api.ProgressResponse{Digest: "sha256:big", Total: 900, Completed: 900}, 1},
+		{api.ProgressResponse{Status: "verifying sha256 digest"}, 1},
+	}
+	progress := 0.0
+	for i, step := range steps {
+		progress = layers.progress(step.resp, progress)
+		if math.Abs(progress-step.want) > 1e-9 {
+			t.Errorf("step %d: progress = %v, want %v", i, progress, step.want)
+		}
+	}
+
+	var none *pullLayers
+	if got := none.progress(api.ProgressResponse{Total: 4, Completed: 1}, 0); got != 0.25 {
+		t.Errorf("nil tracker progress = %v, want 0.25", got)
+	}
+	if got := none.progress(api.ProgressResponse{Status: "pulling manifest"}, 0.25); got != 0.25 {
+		t.Errorf("nil tracker progress without a total = %v, want the previous 0.25", got)
+	}
+}
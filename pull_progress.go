@@ -0,0 +1,127 @@
+// pull_progress.go feeds pull progress to the UI. The pull callback only leaves the latest progress on a channel and a
+// single tick every progress_refresh_ms takes it, so the bar moves without key presses and redraws are bounded.
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// defaultProgressRefresh is the redraw interval when progress_refresh_ms isn't set to a positive number
+const defaultProgressRefresh = 250 * time.Millisecond
+
+// progressFeed holds the latest progress of a pull until the next tick takes it
+type progressFeed chan float64
+
+// pullProgressTickMsg redraws the progress of the pull feeding feed, ticks for an earlier pull are dropped
+type pullProgressTickMsg struct {
+	feed progressFeed
+}
+
+func newProgressFeed() progressFeed {
+	return make(progressFeed, 1)
+}
+
+// send replaces any progress the tick hasn't taken yet, so the pull never waits on the UI
+func (f progressFeed) send(progress float64) {
+	select {
+	case <-f:
+	default:
+	}
+	select {
+	case f <- progress:
+	default:
+	}
+}
+
+// latest returns the progress sent since it was last called, ok is false when nothing was
+func (f progressFeed) latest() (progress float64, ok bool) {
+	select {
+	case progress = <-f:
+		return progress, true
+	default:
+		return 0, false
+	}
+}
+
+// trackPull returns the pull progress callback, it works out the overall progress from the layers and feeds it on
+func trackPull(layers *pullLayers, feed progressFeed) api.PullProgressFunc {
+	var progress float64
+	return func(resp api.ProgressResponse) error {
+		progress = layers.progress(resp, progress)
+		feed.send(progress)
+		return nil
+	}
+}
+
+// trackPush is trackPull for pushes and copies to another host, whose progress is fed to the push progress bar
+func trackPush(layers *pullLayers, feed progressFeed) api.PushProgressFunc {
+	return api.PushProgressFunc(trackPull(layers, feed))
+}
+
+// progressRefresh is how often the pull and push progress bars are redrawn
+func (m *AppModel) progressRefresh() time.Duration {
+	if m.cfg == nil || m.cfg.ProgressRefreshMs <= 0 {
+		return defaultProgressRefresh
+	}
+	return time.Duration(m.cfg.ProgressRefreshMs) * time.Millisecond
+}
+
+// beginPull sets up the progress feed of a new pull, returning the context to pull in, cancelled by ctrl+c, and the
+// first tick
+func (m *AppModel) beginPull() (context.Context, tea.Cmd) {
+	m.endPull()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.pullFeed = newProgressFeed()
+	m.cancelPull = cancel
+	return ctx, m.pullProgressTick()
+}
+
+// endPull cancels the current pull if it's still running and stops its ticks
+func (m *AppModel) endPull() {
+	if m.cancelPull != nil {
+		m.cancelPull()
+	}
+	m.cancelPull = nil
+	m.pullFeed = nil
+}
+
+func (m *AppModel) pullProgressTick() tea.Cmd {
+	feed := m.pullFeed
+	return tea.Tick(m.progressRefresh(), func(time.Time) tea.Msg {
+		return pullProgressTickMsg{feed: feed}
+	})
+}
+
+func (m *AppModel) handlePullProgressTick(msg pullProgressTickMsg) (tea.Model, tea.Cmd) {
+	if !m.pulling || msg.feed == nil || msg.feed != m.pullFeed {
+		return m, nil
+	}
+	if progress, ok := m.pullFeed.latest(); ok {
+		m.pullProgress = progress
+	}
+	return m, m.pullProgressTick()
+}
+
+// cancelRunningPull cancels the pull in flight from ctrl+c or esc. Its goroutine drops its result once the pull
+// returns, so nothing arrives afterwards to change the state of the next pull.
+func (m *AppModel) cancelRunningPull() {
+	logging.InfoLogger.Println("Pull cancelled by user")
+	m.endPull()
+	m.pulling = false
+	m.newModelPull = false
+	m.pullProgress = 0
+	m.pullLayers = nil
+	m.pullInput.Reset()
+	m.message = "Pull cancelled"
+	if m.updateAll != nil {
+		m.message = fmt.Sprintf("Pull cancelled, stopped updating with %d models left", len(m.updateAll.queue)+1)
+		m.updateAll = nil
+	}
+}
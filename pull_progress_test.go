@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestPullProgressFeed(t *testing.T) {
+	feed := newProgressFeed()
+	feed.send(0.1)
+	feed.send(0.5)
+	if progress, ok := feed.latest(); !ok || progress != 0.5 {
+		t.Errorf("latest() = %v, %v, want only the last progress sent", progress, ok)
+	}
+	if _, ok := feed.latest(); ok {
+		t.Error("latest() returned progress twice")
+	}
+
+	release := make(chan struct{})
+	serverCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, completed := range []int64{10, 40, 80} {
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "pulling", Digest: "sha256:a", Total: 100, Completed: completed})
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			close(serverCancelled)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	u, _ := url.Parse(server.URL)
+
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{ProgressRefreshMs: 20}, pulling: true, pullLayers: newPullLayers()}
+	if got := m.progressRefresh(); got != 20*time.Millisecond {
+		t.Errorf("progressRefresh() = %v, want 20ms", got)
+	}
+	batch := m.startPullModel("llama3")().(tea.BatchMsg)
+	if tick, ok := batch[0]().(pullProgressTickMsg); !ok || tick.feed != m.pullFeed {
+		t.Fatalf("first command = %#v, want a tick for the pull", tick)
+	}
+	done := make(chan tea.Msg)
+	go func() { done <- batch[1]() }()
+
+	// Ticks pick up the progress without any key presses and keep ticking while pulling
+	deadline := time.Now().Add(2 * time.Second)
+	for m.pullProgress < 0.8 && time.Now().Before(deadline) {
+		if _, cmd := m.handlePullProgressTick(pullProgressTickMsg{feed: m.pullFeed}); cmd == nil {
+			t.Fatal("tick during the pull didn't schedule the next one")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.pullProgress != 0.8 {
+		t.Errorf("pullProgress = %v after the updates, want 0.8", m.pullProgress)
+	}
+	if _, cmd := m.handlePullProgressTick(pullProgressTickMsg{feed: newProgressFeed()}); cmd != nil {
+		t.Error("a tick from another pull scheduled another tick")
+	}
+
+	// ctrl+c cancels the pull's request and stops the ticks, and the pull returns no message that could change the
+	// state of a pull started since
+	feed = m.pullFeed
+	m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if m.pulling || m.message != "Pull cancelled" {
+		t.Errorf("after ctrl+c pulling = %v, message %q", m.pulling, m.message)
+	}
+	select {
+	case msg := <-done:
+		if msg != nil {
+			t.Errorf("cancelled pull returned %#v, want no message", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the pull wasn't cancelled")
+	}
+	select {
+	case <-serverCancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("the pull request wasn't cancelled")
+	}
+	if _, cmd := m.handlePullProgressTick(pullProgressTickMsg{feed: feed}); cmd != nil {
+		t.Error("tick after cancelling scheduled another tick")
+	}
+}
@@ -0,0 +1,250 @@
+// pull_tags.go completes tags in the pull new model prompt. Pressing tab after a model name lists its tags from the
+// registry with their sizes, and picking one fills in the prompt so it can still be edited before pulling.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+// registryURL is the registry tags are listed from
+var registryURL = "https://" + defaultRegistry
+
+const (
+	// registryTimeout limits listing a model's tags and their sizes, after which the prompt falls back to free text
+	registryTimeout = 15 * time.Second
+	// registryManifestFetches is how many tag manifests are fetched at once to size the tags
+	registryManifestFetches = 8
+	// tagCompletionRows is how many tags are shown at once
+	tagCompletionRows = 10
+)
+
+// registryTag is a tag of a model in the registry, Size is 0 when its manifest couldn't be fetched
+type registryTag struct {
+	Name string
+	Size int64
+}
+
+// registryTagsMsg is the result of listing a model's tags
+type registryTagsMsg struct {
+	model string
+	tags  []registryTag
+	err   error
+}
+
+// tagCompletion is the state of tag completion in the pull prompt. Tags are cached by model for the session.
+type tagCompletion struct {
+	cache   map[string][]registryTag
+	model   string // the model the tags shown are for, empty when the list is closed
+	loading string // the model tags are being fetched for
+	cursor  int
+}
+
+// registryRepository returns the registry repository of a model name without its tag, e.g. library/llama3 for
+// llama3:8b and sammcj/model for sammcj/model:q4
+func registryRepository(modelName string) string {
+	name, _ := splitModelTag(modelName)
+	name = strings.TrimPrefix(name, defaultRegistry+"/")
+	if !strings.Contains(name, "/") {
+		name = defaultNamespace + "/" + name
+	}
+	return name
+}
+
+// fetchRegistryTags lists the tags of a model's repository in the registry at baseURL, sized from their manifests
+func fetchRegistryTags(ctx context.Context, client *http.Client, baseURL, modelName string) ([]registryTag, error) {
+	repository := registryRepository(modelName)
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := getRegistryJSON(ctx, client, fmt.Sprintf("%s/v2/%s/tags/list", baseURL, repository), &list); err != nil {
+		return nil, fmt.Errorf("error listing the tags of %s: %w", repository, err)
+	}
+
+	tags := make([]registryTag, len(list.Tags))
+	sem := make(chan struct{}, registryManifestFetches)
+	var wg sync.WaitGroup
+	for i, name := range list.Tags {
+		tags[i].Name = name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var manifest modelManifest
+			if err := getRegistryJSON(ctx, client, fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, repository, name), &manifest); err != nil {
+				logging.DebugLogger.Printf("Error sizing %s:%s: %v\n", repository, name, err)
+				return
+			}
+			for _, layer := range manifest.Layers {
+				tags[i].Size += layer.Size
+			}
+		}()
+	}
+	wg.Wait()
+	return tags, nil
+}
+
+// getRegistryJSON decodes the JSON response to a GET from the registry
+func getRegistryJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotInRegistry
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// filterTags returns the tags starting with prefix
+func filterTags(tags []registryTag, prefix string) []registryTag {
+	var matches []registryTag
+	for _, tag := range tags {
+		if strings.HasPrefix(tag.Name, prefix) {
+			matches = append(matches, tag)
+		}
+	}
+	return matches
+}
+
+// splitModelTag splits what's typed into the pull prompt into the model name and any tag after it
+func splitModelTag(input string) (string, string) {
+	input = strings.TrimSpace(input)
+	if i := strings.LastIndex(input, ":"); i > strings.LastIndex(input, "/") {
+		return input[:i], input[i+1:]
+	}
+	return input, ""
+}
+
+// visibleTags are the cached tags for the model in the prompt matching the tag typed so far
+func (m *AppModel) visibleTags() []registryTag {
+	name, tag := splitModelTag(m.pullInput.Value())
+	if m.tagCompletion.model == "" || name != m.tagCompletion.model {
+		return nil
+	}
+	return filterTags(m.tagCompletion.cache[name], tag)
+}
+
+// handleTagCompletionKey handles keys for tag completion in the pull prompt, reporting whether it used the key
+func (m *AppModel) handleTagCompletionKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if msg.Type == tea.KeyTab {
+		name, _ := splitModelTag(m.pullInput.Value())
+		if name == "" {
+			return nil, true
+		}
+		if _, ok := m.tagCompletion.cache[name]; ok {
+			m.tagCompletion.model, m.tagCompletion.cursor = name, 0
+			return nil, true
+		}
+		if m.tagCompletion.loading != "" {
+			return nil, true
+		}
+		m.tagCompletion.loading = name
+		m.message = ""
+		return fetchRegistryTagsCmd(name), true
+	}
+
+	tags := m.visibleTags()
+	if len(tags) == 0 {
+		return nil, false
+	}
+	switch msg.Type {
+	case tea.KeyUp:
+		m.tagCompletion.cursor = max(m.tagCompletion.cursor-1, 0)
+	case tea.KeyDown:
+		m.tagCompletion.cursor = min(m.tagCompletion.cursor+1, len(tags)-1)
+	case tea.KeyEnter:
+		// Fill in the prompt rather than pulling so the name can still be edited
+		tag := tags[min(m.tagCompletion.cursor, len(tags)-1)]
+		m.pullInput.SetValue(m.tagCompletion.model + ":" + tag.Name)
+		m.pullInput.CursorEnd()
+		m.tagCompletion.model = ""
+	case tea.KeyEsc:
+		m.tagCompletion.model = ""
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+func fetchRegistryTagsCmd(modelName string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+		defer cancel()
+		tags, err := fetchRegistryTags(ctx, &http.Client{}, registryURL, modelName)
+		return registryTagsMsg{model: modelName, tags: tags, err: err}
+	}
+}
+
+func (m *AppModel) handleRegistryTagsMsg(msg registryTagsMsg) (tea.Model, tea.Cmd) {
+	m.tagCompletion.loading = ""
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error fetching the tags of %s: %v\n", msg.model, msg.err)
+		m.message = fmt.Sprintf("Couldn't fetch the tags of %s, enter the tag yourself: %v", msg.model, msg.err)
+		return m, nil
+	}
+	if m.tagCompletion.cache == nil {
+		m.tagCompletion.cache = make(map[string][]registryTag)
+	}
+	m.tagCompletion.cache[msg.model] = msg.tags
+	if name, _ := splitModelTag(m.pullInput.Value()); m.newModelPull && name == msg.model {
+		m.tagCompletion.model, m.tagCompletion.cursor = msg.model, 0
+	}
+	return m, nil
+}
+
+// tagCompletionView lists the tags matching the prompt below it, scrolled to keep the cursor in view
+func (m *AppModel) tagCompletionView() string {
+	if m.tagCompletion.loading != "" {
+		return "\nFetching the tags of " + m.tagCompletion.loading + "..."
+	}
+	tags := m.visibleTags()
+	if m.tagCompletion.model == "" {
+		return "\nPress tab after a model name to choose from its tags."
+	}
+	if len(tags) == 0 {
+		return "\nNo tags match."
+	}
+
+	colours := styles.Current().Colours
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.Title.TerminalColour())
+	cursor := min(m.tagCompletion.cursor, len(tags)-1)
+	start := max(0, min(cursor-tagCompletionRows/2, len(tags)-tagCompletionRows))
+	var b strings.Builder
+	b.WriteString("\n")
+	for i := start; i < min(start+tagCompletionRows, len(tags)); i++ {
+		size := "?"
+		if tags[i].Size > 0 {
+			size = formatBytes(tags[i].Size)
+		}
+		line := fmt.Sprintf("%-32s %10s", tags[i].Name, size)
+		if i == cursor {
+			b.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	fmt.Fprintf(&b, "%d of %d tags, ↑/↓ to choose, enter to use the tag, esc to close", cursor+1, len(tags))
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPullTagCompletion(t *testing.T) {
+	var tagLists int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/library/llama3/tags/list":
+			tagLists++
+			json.NewEncoder(w).Encode(map[string]any{"name": "library/llama3", "tags": []string{"8b-q4_K_M", "8b-q8_0", "latest"}})
+		case "/v2/library/llama3/manifests/8b-q4_K_M", "/v2/library/llama3/manifests/latest":
+			json.NewEncoder(w).Encode(modelManifest{Layers: []manifestLayer{{Size: 4 << 30}, {Size: 1 << 20}}})
+		case "/v2/library/llama3/manifests/8b-q8_0":
+			json.NewEncoder(w).Encode(modelManifest{Layers: []manifestLayer{{Size: 8 << 30}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { registryURL = url }(registryURL)
+	registryURL = server.URL
+
+	m := &AppModel{}
+	m.handlePullNewModelKey()
+	key := func(k tea.KeyType) tea.Cmd {
+		_, cmd := m.Update(tea.KeyMsg{Type: k})
+		return cmd
+	}
+	m.pullInput.SetValue("llama3:8b")
+	cmd := key(tea.KeyTab)
+	if cmd == nil {
+		t.Fatal("tab after a model name didn't fetch its tags")
+	}
+	m.Update(cmd())
+
+	// The tag typed so far filters the tags
+	tags := m.visibleTags()
+	want := []registryTag{{"8b-q4_K_M", 4<<30 + 1<<20}, {"8b-q8_0", 8 << 30}}
+	if !slices.Equal(tags, want) {
+		t.Fatalf("visibleTags() = %v, want %v", tags, want)
+	}
+	key(tea.KeyDown)
+	if cmd := key(tea.KeyEnter); cmd != nil || m.pullProgress != 0 || !m.newModelPull {
+		t.Fatal("choosing a tag started pulling")
+	}
+	if got := m.pullInput.Value(); got != "llama3:8b-q8_0" {
+		t.Errorf("prompt after choosing a tag = %q, want llama3:8b-q8_0", got)
+	}
+
+	// The tags are cached for the session
+	m.pullInput.SetValue("llama3")
+	if cmd := key(tea.KeyTab); cmd != nil || len(m.visibleTags()) != 3 || tagLists != 1 {
+		t.Errorf("tab again listed %d tags with %d fetches, want 3 tags from the cache", len(m.visibleTags()), tagLists)
+	}
+	key(tea.KeyEsc)
+	if m.visibleTags() != nil || !m.newModelPull {
+		t.Error("esc didn't close the tags and leave the prompt open")
+	}
+
+	// A model the registry doesn't know leaves the prompt as free text
+	m.pullInput.SetValue("nothing")
+	m.Update(key(tea.KeyTab)())
+	if m.visibleTags() != nil || !strings.Contains(m.message, "enter the tag yourself") {
+		t.Errorf("failed tag fetch shows %v, %q, want no tags and a message", m.visibleTags(), m.message)
+	}
+}
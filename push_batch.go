@@ -0,0 +1,216 @@
+// push_batch.go pushes or copies several selected models to another host in one action. After confirming the list
+// the models are processed one at a time, and ctrl+c stops the batch once the model in flight has finished.
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// Batch actions
+const (
+	batchPush     = "push"
+	batchTransfer = "transfer"
+)
+
+// pushBatch is a push or transfer of several models. index is the position of the model in flight, the results of
+// the models before it are in succeeded and failures.
+type pushBatch struct {
+	action     string
+	models     []string
+	prefix     string // registry prefix pushed models are copied under, empty to push them as they are
+	host       string // Ollama host models are transferred to
+	confirming bool
+	stopping   bool // ctrl+c was pressed, stop once the model in flight finishes
+	index      int
+	succeeded  []string
+	failures   map[string]error
+}
+
+// selectedListModels returns the models selected with space in the order they're sorted in. They're taken from
+// m.models rather than the list items, which leave out the models in collapsed groups.
+func (m *AppModel) selectedListModels() []Model {
+	var selected []Model
+	for _, model := range m.models {
+		if model.Selected {
+			selected = append(selected, model)
+		}
+	}
+	return selected
+}
+
+// newPushBatch returns a batch of the selected models waiting to be confirmed
+func newPushBatch(action string, models []Model) *pushBatch {
+	batch := &pushBatch{action: action, confirming: true, failures: make(map[string]error)}
+	for _, model := range models {
+		batch.models = append(batch.models, model.Name)
+	}
+	return batch
+}
+
+// verb describes the batch's action as it's in progress, e.g. "pushing"
+func (b *pushBatch) verb() string {
+	if b.action == batchTransfer {
+		return "copying"
+	}
+	return "pushing"
+}
+
+// destination describes where the batch is going, e.g. "to registry.internal:5000/team"
+func (b *pushBatch) destination() string {
+	switch {
+	case b.action == batchTransfer:
+		return "to " + b.host
+	case b.prefix != "":
+		return "to " + b.prefix
+	}
+	return "to their registries"
+}
+
+// label is the progress line of the model in flight, e.g. "Pushing 3/7: qwen2:7b"
+func (b *pushBatch) label() string {
+	verb := b.verb()
+	label := fmt.Sprintf("%s%s %d/%d: %s", strings.ToUpper(verb[:1]), verb[1:], b.index+1, len(b.models), b.models[b.index])
+	if b.action == batchTransfer {
+		label += " to " + b.host
+	}
+	return label
+}
+
+// summary reports how many models made it and why the others didn't, including the ones a stop skipped
+func (b *pushBatch) summary() string {
+	done := "pushed"
+	if b.action == batchTransfer {
+		done = "copied to " + b.host
+	}
+	summary := fmt.Sprintf("%d of %d models %s", len(b.succeeded), len(b.models), done)
+	if len(b.failures) > 0 {
+		names := make([]string, 0, len(b.failures))
+		for name := range b.failures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var reasons []string
+		for _, name := range names {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", name, b.failures[name]))
+		}
+		summary += fmt.Sprintf(", %d failed (%s)", len(b.failures), strings.Join(reasons, "; "))
+	}
+	if skipped := len(b.models) - b.index; skipped > 0 {
+		summary += fmt.Sprintf(", stopped before the last %d", skipped)
+	}
+	return summary
+}
+
+// withBatchIndex runs cmd and marks its result with the 1-based position of its model in the batch
+func withBatchIndex(cmd tea.Cmd, index int) tea.Cmd {
+	return func() tea.Msg {
+		switch msg := cmd().(type) {
+		case pushSuccessMsg:
+			msg.index = index
+			return msg
+		case pushErrorMsg:
+			msg.index = index
+			return msg
+		case transferMsg:
+			msg.index = index
+			return msg
+		default:
+			return msg
+		}
+	}
+}
+
+// pushBatchItemCmd returns the command pushing or copying the model in flight
+func (m *AppModel) pushBatchItemCmd() tea.Cmd {
+	b := m.pushBatch
+	name := b.models[b.index]
+	var cmd tea.Cmd
+	switch {
+	case b.action == batchTransfer:
+		cmd = m.transferModelCmd(name, b.host)
+	case registryDestination(b.prefix, name) != name:
+		cmd = m.pushToRegistryCmd(name, registryDestination(b.prefix, name))
+	default:
+		cmd = m.pushModelCmd(name)
+	}
+	return withBatchIndex(cmd, b.index+1)
+}
+
+// handlePushBatchKey confirms or cancels a batch, and once it's running lets ctrl+c stop it after the model in flight.
+// It reports whether it used the key.
+func (m *AppModel) handlePushBatchKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	b := m.pushBatch
+	if !b.confirming {
+		if msg.String() != "ctrl+c" {
+			return nil, false
+		}
+		b.stopping = true
+		m.message = fmt.Sprintf("Stopping once %s has finished %s", b.models[b.index], b.verb())
+		return nil, true
+	}
+
+	switch msg.String() {
+	case "y", "Y":
+		logging.InfoLogger.Printf("Starting batch %s of %d models %s\n", b.action, len(b.models), b.destination())
+		b.confirming = false
+		if b.action == batchTransfer {
+			m.transferHost = b.host
+		}
+		m.message = ""
+		m.showProgress = true
+		return m.startPushModel(b.models[0], m.pushBatchItemCmd()), true
+	case "n", "N", "esc", "ctrl+c":
+		m.pushBatch = nil
+		m.message = "Cancelled"
+	}
+	return nil, true
+}
+
+// handlePushBatchResult records the result of the model in flight, then starts the next one or finishes the batch
+func (m *AppModel) handlePushBatchResult(err error) (tea.Model, tea.Cmd) {
+	b := m.pushBatch
+	name := b.models[b.index]
+	if err != nil {
+		if status := m.pushLayers.lastStatus(); status != "" {
+			err = fmt.Errorf("%w (last status: %s)", err, status)
+		}
+		logging.ErrorLogger.Printf("Error %s %s: %v\n", b.verb(), name, err)
+		b.failures[name] = err
+	} else {
+		b.succeeded = append(b.succeeded, name)
+	}
+	b.index++
+
+	if b.index == len(b.models) || b.stopping {
+		m.finishPush()
+		m.transferHost = ""
+		m.message = b.summary()
+		logging.InfoLogger.Printf("Batch %s finished: %s\n", b.action, m.message)
+		m.pushBatch = nil
+		return m, nil
+	}
+	// The progress bar stays up between models, each model's push has its own layers and ticks
+	next := m.pushBatchItemCmd()
+	return m, tea.Batch(next, m.pushProgressTick())
+}
+
+// pushBatchView asks to confirm the models in a batch before any are processed
+func (m *AppModel) pushBatchView() string {
+	b := m.pushBatch
+	var list strings.Builder
+	for _, name := range b.models {
+		list.WriteString("  " + name + "\n")
+	}
+	verb := "push"
+	if b.action == batchTransfer {
+		verb = "copy"
+	}
+	return fmt.Sprintf("\nAre you sure you want to %s these %d models %s, one at a time? (Y/N)\n\n%s\nPress ctrl+c while they're running to stop after the current model.",
+		verb, len(b.models), b.destination(), list.String())
+}
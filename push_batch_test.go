@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestPushBatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var pushed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.PushRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		name := req.Model + req.Name
+		pushed = append(pushed, name)
+		if name == "broken:latest" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success"}` + "\n"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	newModel := func(name string) Model {
+		model := Model{}
+		model.Name = name
+		return model
+	}
+	models := []Model{newModel("a:latest"), newModel("broken:latest"), newModel("c:latest")}
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{}, list: list.New(nil, list.NewDefaultDelegate(), 80, 40)}
+
+	// run confirms the batch and processes each model in turn, pressing ctrl+c once the first is in flight if stop is set
+	run := func(stop bool) {
+		m.pushBatch = newPushBatch(batchPush, models)
+		if !strings.Contains(m.View(), "push these 3 models") {
+			t.Errorf("confirmation view =\n%s", m.View())
+		}
+		_, cmd := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+		if m.pushBatch.label() != "Pushing 1/3: a:latest" {
+			t.Errorf("label() = %q", m.pushBatch.label())
+		}
+		if stop {
+			m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyCtrlC})
+		}
+		next := cmd().(tea.BatchMsg)[1]
+		for next != nil {
+			msg := next()
+			// Each model after the first is started with a tick for its progress
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				msg = batch[0]()
+			}
+			_, next = m.Update(msg)
+		}
+	}
+
+	run(true)
+	if !slices.Equal(pushed, []string{"a:latest"}) || m.message != "1 of 3 models pushed, stopped before the last 2" {
+		t.Errorf("stopped batch pushed %v with message %q", pushed, m.message)
+	}
+	if m.pushBatch != nil || m.showProgress {
+		t.Error("the batch is still shown after it stopped")
+	}
+
+	pushed = nil
+	run(false)
+	if !slices.Equal(pushed, []string{"a:latest", "broken:latest", "c:latest"}) {
+		t.Errorf("pushed %v, want every model in order", pushed)
+	}
+	if !strings.HasPrefix(m.message, "2 of 3 models pushed, 1 failed (broken:latest: ") || !strings.Contains(m.message, "unauthorized") {
+		t.Errorf("message = %q, want a summary naming the failure", m.message)
+	}
+}
@@ -0,0 +1,93 @@
+// push_registry.go pushes a model to another registry by copying it to a name with the registry's prefix first.
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// registryDestination returns the name a model is copied to for pushing under a registry prefix,
+// e.g. registry.internal:5000/team and mistral:7b give registry.internal:5000/team/mistral:7b
+func registryDestination(prefix, modelName string) string {
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return modelName
+	}
+	return prefix + "/" + path.Base(modelName)
+}
+
+// registryHost returns the registry a model name is pushed to, defaultRegistry when the name doesn't include one
+func registryHost(modelName string) string {
+	host, _, found := strings.Cut(modelName, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return host
+	}
+	return defaultRegistry
+}
+
+// pushError names the registry a push failed against so authentication failures are obvious
+func pushError(modelName string, err error) error {
+	return fmt.Errorf("pushing %s to %s: %w", modelName, registryHost(modelName), err)
+}
+
+// pushErrorMessage is the message shown when a push fails, with the last status Ollama reported to show how far it got
+func pushErrorMessage(err error, lastStatus string) string {
+	if lastStatus == "" {
+		return fmt.Sprintf("Error pushing model: %v", err)
+	}
+	return fmt.Sprintf("Error pushing model: %v (last status: %s)", err, lastStatus)
+}
+
+// promptForPushRegistry asks for an optional registry prefix to push a model to, pre-filled with the last one used.
+// An empty answer pushes the model under its own name, ok is false when the prompt was cancelled.
+func promptForPushRegistry(modelName, lastPrefix string) (string, bool) {
+	prefix, ok := promptForValue(fmt.Sprintf("Pushing %s\nRegistry prefix (e.g. registry.internal:5000/team, empty to push as is): ", modelName), lastPrefix)
+	return strings.TrimSuffix(strings.TrimSpace(prefix), "/"), ok
+}
+
+// pushToRegistryCmd copies a model to its name under a registry prefix, pushes the copy and then deletes it unless
+// the config keeps it
+func (m *AppModel) pushToRegistryCmd(modelName, destination string) tea.Cmd {
+	deleteCopy := m.cfg.DeletePushCopy
+	ctx, track := m.beginPush()
+	client := m.client
+	return func() tea.Msg {
+		if err := client.Copy(ctx, &api.CopyRequest{Source: modelName, Destination: destination}); err != nil {
+			return pushErrorMsg{err: fmt.Errorf("copying %s to %s: %w", modelName, destination, err)}
+		}
+		err := client.Push(ctx, &api.PushRequest{Name: destination}, track)
+		if deleteCopy {
+			// The copy is deleted even when the push was cancelled
+			if err := client.Delete(context.Background(), &api.DeleteRequest{Name: destination}); err != nil {
+				logging.ErrorLogger.Printf("Error deleting the temporary copy %s: %v\n", destination, err)
+			}
+		}
+		if ctx.Err() != nil {
+			logging.InfoLogger.Printf("Push of %s cancelled\n", destination)
+			return nil
+		}
+		if err != nil {
+			return pushErrorMsg{err: pushError(destination, err)}
+		}
+		return pushSuccessMsg{modelName: destination}
+	}
+}
+
+// rememberPushRegistry saves the registry prefix used so it's offered again next time
+func (m *AppModel) rememberPushRegistry(prefix string) {
+	if prefix == m.cfg.DefaultPushRegistry {
+		return
+	}
+	m.cfg.DefaultPushRegistry = prefix
+	if err := config.SetValue("default_push_registry", prefix); err != nil {
+		logging.ErrorLogger.Printf("Error saving the push registry to the config: %v\n", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestPushToRegistry(t *testing.T) {
+	if got := registryDestination("registry.internal:5000/team/", "mistral:7b"); got != "registry.internal:5000/team/mistral:7b" {
+		t.Errorf("registryDestination() = %q", got)
+	}
+	if got := registryDestination("registry.internal:5000/team", "sammcj/mistral:7b"); got != "registry.internal:5000/team/mistral:7b" {
+		t.Errorf("registryDestination() with a namespace = %q", got)
+	}
+	if got := registryDestination(" ", "mistral:7b"); got != "mistral:7b" {
+		t.Errorf("registryDestination() with no prefix = %q", got)
+	}
+	for name, host := range map[string]string{
+		"registry.internal:5000/team/mistral:7b": "registry.internal:5000",
+		"sammcj/mistral:7b":                      defaultRegistry,
+		"mistral:7b":                             defaultRegistry,
+	} {
+		if got := registryHost(name); got != host {
+			t.Errorf("registryHost(%q) = %q, want %q", name, got, host)
+		}
+	}
+
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		if r.URL.Path == "/api/push" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	m := &AppModel{client: api.NewClient(u, http.DefaultClient), cfg: &config.Config{DeletePushCopy: true}}
+
+	msg := m.pushToRegistryCmd("mistral:7b", "registry.internal:5000/team/mistral:7b")()
+	pushErr, ok := msg.(pushErrorMsg)
+	if !ok || !strings.Contains(pushErr.err.Error(), "to registry.internal:5000") {
+		t.Errorf("pushToRegistryCmd() = %#v, want an error naming the registry", msg)
+	}
+	if strings.Join(calls, " ") != "/api/copy /api/push /api/delete" {
+		t.Errorf("API calls = %v, want copy, push then delete", calls)
+	}
+}
@@ -0,0 +1,211 @@
+// report.go implements gollama report, which writes the local models and their metadata as a markdown table or CSV
+// for documentation and spreadsheets, optionally grouped by family with subtotals. It only reads from the API.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/pkg/gollama"
+)
+
+// Report formats
+const (
+	reportMarkdown = "md"
+	reportCSV      = "csv"
+)
+
+// reportRow is a model as it appears in the report, Context is 0 when the model doesn't report one
+type reportRow struct {
+	Name     string
+	Params   string
+	Quant    string
+	Family   string
+	Size     int64
+	Context  int
+	Modified time.Time
+}
+
+// runReport parses the report arguments and writes the report to stdout, returning the exit code
+func runReport(ctx context.Context, client *api.Client, apiURL string, args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := flags.String("format", reportMarkdown, "Report format, md or csv")
+	groupBy := flags.String("group-by", "", "Set to family to group the models by family with subtotals")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != reportMarkdown && *format != reportCSV {
+		fmt.Fprintf(os.Stderr, "Unknown report format %q, use md or csv\n", *format)
+		return 2
+	}
+	if *groupBy != "" && *groupBy != groupByFamily {
+		fmt.Fprintf(os.Stderr, "Unknown grouping %q, use family\n", *groupBy)
+		return 2
+	}
+
+	resp, err := client.List(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching models: %v\n", err)
+		return 1
+	}
+	models := make([]Model, len(resp.Models))
+	for i, model := range gollama.ModelsFromResponse(resp) {
+		models[i] = Model{Model: model}
+	}
+	infos := fetchWideModelInfo(ctx, client, apiURL, models, wideListWorkers)
+
+	rows := make([]reportRow, len(models))
+	for i, model := range models {
+		rows[i] = reportRow{
+			Name:     model.Name,
+			Params:   model.ParameterSize,
+			Quant:    model.QuantizationLevel,
+			Family:   model.Family,
+			Size:     model.Size,
+			Context:  infos[i].Context,
+			Modified: model.Modified,
+		}
+	}
+	if err := writeReport(os.Stdout, rows, *format, *groupBy == groupByFamily); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportGroup is the models of one family, or every model when the report isn't grouped
+type reportGroup struct {
+	Family string
+	Rows   []reportRow
+	Size   int64
+}
+
+// reportGroups sorts the rows by name, and when grouped splits them into a group for each family in name order
+func reportGroups(rows []reportRow, grouped bool) []reportGroup {
+	sorted := append([]reportRow(nil), rows...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	if !grouped {
+		group := reportGroup{Rows: sorted}
+		for _, row := range sorted {
+			group.Size += row.Size
+		}
+		return []reportGroup{group}
+	}
+
+	byFamily := make(map[string]*reportGroup)
+	var families []string
+	for _, row := range sorted {
+		family := row.Family
+		if family == "" {
+			family = "unknown"
+		}
+		group, ok := byFamily[family]
+		if !ok {
+			group = &reportGroup{Family: family}
+			byFamily[family] = group
+			families = append(families, family)
+		}
+		group.Rows = append(group.Rows, row)
+		group.Size += row.Size
+	}
+	sort.Strings(families)
+	groups := make([]reportGroup, len(families))
+	for i, family := range families {
+		groups[i] = *byFamily[family]
+	}
+	return groups
+}
+
+// writeReport writes the rows as a markdown table or CSV, grouped by family with subtotals when grouped is set
+func writeReport(w io.Writer, rows []reportRow, format string, grouped bool) error {
+	groups := reportGroups(rows, grouped)
+	switch format {
+	case reportMarkdown:
+		return writeMarkdownReport(w, groups, grouped, len(rows))
+	case reportCSV:
+		return writeCSVReport(w, groups, grouped)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+var reportHeader = []string{"Name", "Params", "Quant", "Family", "Size", "Context", "Modified"}
+
+// markdownCell escapes the characters that would break a markdown table cell
+func markdownCell(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ").Replace(text)
+}
+
+func writeMarkdownReport(w io.Writer, groups []reportGroup, grouped bool, models int) error {
+	var b strings.Builder
+	var total int64
+	for i, group := range groups {
+		if grouped {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "## %s\n\n", markdownCell(group.Family))
+		}
+		b.WriteString("| " + strings.Join(reportHeader, " | ") + " |\n")
+		b.WriteString("|" + strings.Repeat(" --- |", len(reportHeader)) + "\n")
+		for _, row := range group.Rows {
+			context := "-"
+			if row.Context > 0 {
+				context = strconv.Itoa(row.Context)
+			}
+			cells := []string{row.Name, row.Params, row.Quant, row.Family, formatBytes(row.Size), context, row.Modified.Format("2006-01-02")}
+			for j := range cells {
+				cells[j] = markdownCell(cells[j])
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		if grouped {
+			fmt.Fprintf(&b, "\nSubtotal: %s, %s\n", modelCount(len(group.Rows)), formatBytes(group.Size))
+		}
+		total += group.Size
+	}
+	fmt.Fprintf(&b, "\nTotal: %s, %s\n", modelCount(models), formatBytes(total))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// modelCount returns e.g. "1 model" or "3 models"
+func modelCount(n int) string {
+	if n == 1 {
+		return "1 model"
+	}
+	return fmt.Sprintf("%d models", n)
+}
+
+// writeCSVReport writes a row for each model with the size in bytes, followed by a subtotal row for each family when
+// grouped
+func writeCSVReport(w io.Writer, groups []reportGroup, grouped bool) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"name", "parameter_size", "quantization_level", "family", "size_bytes", "context_length", "modified"})
+	for _, group := range groups {
+		for _, row := range group.Rows {
+			context := ""
+			if row.Context > 0 {
+				context = strconv.Itoa(row.Context)
+			}
+			out.Write([]string{row.Name, row.Params, row.Quant, row.Family, strconv.FormatInt(row.Size, 10), context, row.Modified.Format(time.RFC3339)})
+		}
+		if grouped {
+			out.Write([]string{"subtotal (" + modelCount(len(group.Rows)) + ")", "", "", group.Family, strconv.FormatInt(group.Size, 10), "", ""})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+func TestReportGolden(t *testing.T) {
+	modified := time.Date(2025, 1, 3, 9, 30, 0, 0, time.UTC)
+	rows := []reportRow{
+		{Name: "qwen2:7b", Params: "7.6B", Quant: "Q4_0", Family: "qwen2", Size: 4_400_000_000, Context: 32768, Modified: modified},
+		{Name: "llama3:8b", Params: "8.0B", Quant: "Q4_K_M", Family: "llama", Size: 4_920_000_000, Context: 8192, Modified: modified.AddDate(0, 1, 0)},
+		{Name: "team|a/llama3:70b", Params: "70.6B", Quant: "Q4_K_M", Family: "llama", Size: 42_500_000_000, Context: 131072, Modified: modified},
+		{Name: "nomic-embed-text:latest", Params: "137M", Quant: "F16", Family: "nomic-bert", Size: 274_000_000, Modified: modified},
+	}
+	for _, tt := range []struct {
+		golden  string
+		format  string
+		grouped bool
+	}{
+		{"report.md.golden", reportMarkdown, false},
+		{"report_family.md.golden", reportMarkdown, true},
+		{"report.csv.golden", reportCSV, false},
+		{"report_family.csv.golden", reportCSV, true},
+	} {
+		var out bytes.Buffer
+		if err := writeReport(&out, rows, tt.format, tt.grouped); err != nil {
+			t.Fatalf("writeReport(%s) error = %v", tt.golden, err)
+		}
+		path := filepath.Join("testdata", tt.golden)
+		if *updateGolden {
+			os.MkdirAll("testdata", 0755)
+			if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading golden file: %v (run go test -run TestReportGolden -update to create it)", err)
+		}
+		if out.String() != string(want) {
+			t.Errorf("writeReport() for %s =\n%s\nwant\n%s", tt.golden, out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// rewrite_from.go repairs models whose modelfile FROM is an absolute blob path from another machine by re-creating them from the local blob.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pkg/gollama"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// blobNamePattern matches a blob file name, the separator is "-" on disk and ":" in digests
+var blobNamePattern = regexp.MustCompile(`^sha256[-:]([0-9a-f]{64})$`)
+
+// danglingFrom is a model whose FROM path doesn't exist but whose blob is in the local store
+type danglingFrom struct {
+	Model  string
+	From   string
+	Digest string
+}
+
+// danglingFromDigest returns the digest of the local blob to use when from is an absolute blob path that doesn't exist.
+// It returns an empty digest with a reason when the model can't or needn't be fixed.
+func danglingFromDigest(modelsDir, from string) (string, string) {
+	if !filepath.IsAbs(from) {
+		return "", "FROM isn't an absolute path"
+	}
+	if _, err := os.Stat(from); err == nil {
+		return "", "FROM path exists"
+	}
+	match := blobNamePattern.FindStringSubmatch(filepath.Base(from))
+	if match == nil {
+		return "", "FROM path isn't a blob"
+	}
+	digest := "sha256:" + match[1]
+	if _, err := os.Stat(blobPath(modelsDir, digest)); err != nil {
+		return "", "no blob with digest " + digest + " in the local store"
+	}
+	return digest, ""
+}
+
+// typedParameters converts modelfile parameter values to the types the create API expects, stop is always a list
+func typedParameters(params map[string][]string) map[string]any {
+	typed := make(map[string]any, len(params))
+	for key, values := range params {
+		if len(values) > 1 || key == "stop" {
+			typed[key] = values
+			continue
+		}
+		value := values[0]
+		if i, err := strconv.Atoi(value); err == nil {
+			typed[key] = i
+		} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+			typed[key] = f
+		} else if b, err := strconv.ParseBool(value); err == nil {
+			typed[key] = b
+		} else {
+			typed[key] = value
+		}
+	}
+	return typed
+}
+
+// rewriteModelFrom re-creates a model from a blob digest rather than a path, keeping its template, system prompt and parameters
+func rewriteModelFrom(ctx context.Context, client *api.Client, modelName, digest string, show *api.ShowResponse) error {
+	req := &api.CreateRequest{
+		Model:      modelName,
+		Files:      map[string]string{"model.gguf": digest},
+		Template:   show.Template,
+		System:     show.System,
+		Parameters: typedParameters(gollama.ExtractParameters(show.Modelfile)),
+	}
+	err := client.Create(ctx, req, func(resp api.ProgressResponse) error {
+		logging.DebugLogger.Printf("Re-creating %s: %s\n", modelName, resp.Status)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error re-creating %s: %w", modelName, err)
+	}
+	showcache.Invalidate(client, modelName)
+	recordHistory("rewrite-from", modelName, digest)
+	return nil
+}
+
+// checkModelFrom looks up a model's FROM and works out whether it's dangling and fixable
+func checkModelFrom(ctx context.Context, client *api.Client, modelsDir, modelName string) (*danglingFrom, *api.ShowResponse, string, error) {
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error fetching modelfile for %s: %w", modelName, err)
+	}
+	from := ""
+	for _, line := range strings.Split(show.Modelfile, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 5 && strings.EqualFold(line[:5], "FROM ") {
+			from = strings.TrimSpace(line[5:])
+			break
+		}
+	}
+	if from == "" {
+		return nil, show, "no FROM line in the modelfile", nil
+	}
+	digest, reason := danglingFromDigest(modelsDir, from)
+	if digest == "" {
+		return nil, show, reason, nil
+	}
+	return &danglingFrom{Model: modelName, From: from, Digest: digest}, show, "", nil
+}
+
+// runRewriteFrom implements -rewrite-from and -scan, returning the process exit code. A single model is fixed straight away,
+// a scan lists every dangling model and asks before fixing them.
+func runRewriteFrom(ctx context.Context, client *api.Client, modelsDir string, names []string, scan bool) int {
+	var dangling []danglingFrom
+	shows := make(map[string]*api.ShowResponse)
+	failed := false
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		found, show, reason, err := checkModelFrom(ctx, client, modelsDir, name)
+		if err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+		if found == nil {
+			if !scan {
+				fmt.Printf("%s: nothing to rewrite, %s\n", name, reason)
+			}
+			continue
+		}
+		dangling = append(dangling, *found)
+		shows[name] = show
+	}
+
+	if len(dangling) == 0 {
+		if scan {
+			fmt.Println("No models with dangling FROM paths found")
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	}
+
+	for _, d := range dangling {
+		fmt.Printf("%s: FROM %s doesn't exist, the blob %s is in the local store\n", d.Model, d.From, d.Digest)
+	}
+	if scan {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Println("Run -scan from a terminal to confirm fixing these models")
+			return 1
+		}
+		fmt.Printf("Re-create %d model(s) from their local blobs? [y/N] ", len(dangling))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			fmt.Println("No models changed")
+			return 0
+		}
+	}
+
+	for _, d := range dangling {
+		if err := rewriteModelFrom(ctx, client, d.Model, d.Digest, shows[d.Model]); err != nil {
+			fmt.Println(err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Re-created %s from %s\n", d.Model, d.Digest)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDanglingFromDigest(t *testing.T) {
+	modelsDir := t.TempDir()
+	hex := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	if err := os.MkdirAll(filepath.Join(modelsDir, "blobs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blobPath(modelsDir, "sha256:"+hex), []byte("gguf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(t.TempDir(), "sha256-"+hex)
+	if err := os.WriteFile(existing, []byte("gguf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		from       string
+		wantDigest string
+	}{
+		{"missing path with local blob", "/usr/share/ollama/.ollama/models/blobs/sha256-" + hex, "sha256:" + hex},
+		{"existing path", existing, ""},
+		{"model name", "llama3:8b", ""},
+		{"missing path without local blob", "/other/blobs/sha256-" + strings.Repeat("f", 64), ""},
+		{"missing path that isn't a blob", "/other/models/model.gguf", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, reason := danglingFromDigest(modelsDir, tt.from)
+			if digest != tt.wantDigest {
+				t.Errorf("danglingFromDigest() = %q (%s), want %q", digest, reason, tt.wantDigest)
+			}
+			if digest == "" && reason == "" {
+				t.Errorf("danglingFromDigest() gave no reason for not rewriting")
+			}
+		})
+	}
+}
+
+func TestTypedParameters(t *testing.T) {
+	got := typedParameters(map[string][]string{
+		"num_ctx":     {"8192"},
+		"temperature": {"0.7"},
+		"stop":        {"<|im_end|>"},
+		"penalize_nl": {"false"},
+		"custom":      {"value"},
+	})
+	want := map[string]any{"num_ctx": 8192, "temperature": 0.7, "stop": []string{"<|im_end|>"}, "penalize_nl": false, "custom": "value"}
+	for key, value := range want {
+		if fmt.Sprintf("%#v", got[key]) != fmt.Sprintf("%#v", value) {
+			t.Errorf("typedParameters()[%s] = %#v, want %#v", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// run_external.go runs models outside gollama's terminal when run_external is set, in a new tmux, kitty or wezterm
+// window or with a template, so the list can still be browsed while chatting. If the window can't be opened the model
+// is run in gollama's terminal as it is without run_external.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// runExternalWait is how long the command opening the window has to fail before the model is taken to be running
+var runExternalWait = time.Second
+
+// runExternalMsg is sent once the command opening a window for a model has started, or failed
+type runExternalMsg struct {
+	model    string
+	override *config.RunProfile
+	err      error
+}
+
+// startRun runs the model in a new window when run_external is set and in gollama's terminal otherwise
+func (m *AppModel) startRun(model string, override *config.RunProfile) tea.Cmd {
+	if m.cfg.RunExternal == "" || m.cfg.RunExternal == "suspend" {
+		return runModel(model, m.cfg, override)
+	}
+	cfg := *m.cfg
+	return func() tea.Msg {
+		args, env, err := runCommandFor(model, &cfg, override)
+		if err == nil {
+			var command []string
+			if command, err = externalCommand(cfg.RunExternal, model, args, env); err == nil {
+				logging.DebugLogger.Printf("Running %s in a new window with %v\n", model, command)
+				err = spawnExternal(command)
+			}
+		}
+		return runExternalMsg{model: model, override: override, err: err}
+	}
+}
+
+// externalCommand returns the command opening a window running args with env added to its environment. tmux needs
+// gollama to be running inside it. Templates are split into words as a shell would, honouring quotes, and an unquoted
+// {{command}} word is replaced with the arguments. A {{command}} inside a word or quotes, e.g. --command={{command}} or
+// sh -c '{{command}}', is replaced with the arguments shell quoted as one argument.
+func externalCommand(mode, model string, args, env []string) ([]string, error) {
+	command := args
+	if len(env) > 0 {
+		// The window's environment comes from the terminal or multiplexer rather than gollama
+		command = append(append([]string{"env"}, env...), args...)
+	}
+	switch mode {
+	case "tmux":
+		if os.Getenv("TMUX") == "" {
+			return nil, errors.New("gollama isn't running inside tmux")
+		}
+		return append([]string{"tmux", "new-window", "-n", model}, command...), nil
+	case "kitty":
+		return append([]string{"kitty", "@", "launch", "--type=tab", "--tab-title", model}, command...), nil
+	case "wezterm":
+		return append([]string{"wezterm", "cli", "spawn", "--"}, command...), nil
+	}
+	words, err := config.SplitTemplate(mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run_external template: %w", err)
+	}
+	var expanded []string
+	for _, word := range words {
+		if word.Text == config.CommandPlaceholder && !word.Quoted {
+			expanded = append(expanded, command...)
+			continue
+		}
+		text := strings.ReplaceAll(word.Text, config.CommandPlaceholder, shellJoin(command))
+		expanded = append(expanded, strings.ReplaceAll(text, config.ModelPlaceholder, model))
+	}
+	return expanded, nil
+}
+
+// shellUnsafe matches the characters that need quoting in a shell word
+var shellUnsafe = regexp.MustCompile(`[^A-Za-z0-9_@%+=:,./-]`)
+
+// shellJoin joins arguments into a shell command, single quoting those that need it
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !shellUnsafe.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// spawnExternal starts the command opening the window. Those that exit straight away, like tmux, have runExternalWait
+// to fail, while those that stay running with the window, like a terminal's -e, are left to it.
+func spawnExternal(command []string) error {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("%s isn't installed or in the PATH", command[0])
+	}
+	c := exec.Command(path, command[1:]...)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %w", command[0], err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			if output := strings.TrimSpace(stderr.String()); output != "" {
+				return fmt.Errorf("%s failed: %s", command[0], output)
+			}
+			return fmt.Errorf("%s failed: %w", command[0], err)
+		}
+	case <-time.After(runExternalWait):
+	}
+	return nil
+}
+
+// handleRunExternalMsg confirms the model is running in its window, or runs it in gollama's terminal when the window
+// couldn't be opened
+func (m *AppModel) handleRunExternalMsg(msg runExternalMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Couldn't run %s in a new window: %v\n", msg.model, msg.err)
+		m.message = fmt.Sprintf("Couldn't run %s in a new window (%v), running it here instead", msg.model, msg.err)
+		return m, runModel(msg.model, m.cfg, msg.override)
+	}
+	logging.InfoLogger.Printf("Running %s in a new window\n", msg.model)
+	m.message = fmt.Sprintf("Running %s in a new window", msg.model)
+	return m, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestRunExternal(t *testing.T) {
+	args := []string{"ollama", "run", "qwen2:7b"}
+	t.Setenv("TMUX", "")
+	if _, err := externalCommand("tmux", "qwen2:7b", args, nil); err == nil {
+		t.Error("externalCommand(tmux) outside tmux succeeded")
+	}
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	tests := []struct {
+		mode string
+		env  []string
+		want string
+	}{
+		{"tmux", nil, "tmux new-window -n qwen2:7b ollama run qwen2:7b"},
+		{"tmux", []string{"OLLAMA_DEBUG=1"}, "tmux new-window -n qwen2:7b env OLLAMA_DEBUG=1 ollama run qwen2:7b"},
+		{"wezterm", nil, "wezterm cli spawn -- ollama run qwen2:7b"},
+		{"alacritty --title {{model}} -e {{command}}", nil, "alacritty --title qwen2:7b -e ollama run qwen2:7b"},
+		// Inside a field the command is one argument, quoted for the shell the terminal runs it with
+		{"foot --command={{command}}", []string{"PROMPT=hi there"}, "foot --command=env 'PROMPT=hi there' ollama run qwen2:7b"},
+	}
+	for _, tt := range tests {
+		got, err := externalCommand(tt.mode, "qwen2:7b", args, tt.env)
+		if err != nil || strings.Join(got, " ") != tt.want {
+			t.Errorf("externalCommand(%q) = %q, %v, want %q", tt.mode, got, err, tt.want)
+		}
+	}
+
+	// Quotes group words as in a shell, and a quoted {{command}} is quoted for the shell once
+	quotedTests := []struct {
+		mode string
+		env  []string
+		want []string
+	}{
+		{`alacritty -e sh -c '{{command}}'`, nil, []string{"alacritty", "-e", "sh", "-c", "ollama run qwen2:7b"}},
+		{`alacritty -e sh -c '{{command}}'`, []string{"PROMPT=it's here"}, []string{"alacritty", "-e", "sh", "-c", `env 'PROMPT=it'\''s here' ollama run qwen2:7b`}},
+		{`xterm -T "chat with {{model}}" -e sh -c "exec {{command}}"`, nil, []string{"xterm", "-T", "chat with qwen2:7b", "-e", "sh", "-c", "exec ollama run qwen2:7b"}},
+	}
+	for _, tt := range quotedTests {
+		got, err := externalCommand(tt.mode, "qwen2:7b", args, tt.env)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("externalCommand(%q) = %q, %v, want %q", tt.mode, got, err, tt.want)
+		}
+	}
+	if _, err := externalCommand(`sh -c '{{command}}`, "qwen2:7b", args, nil); err == nil {
+		t.Error("externalCommand() with an unterminated quote succeeded")
+	}
+	// The quoted command runs as the shell would run it
+	got, _ := externalCommand(`sh -c '{{command}}'`, "qwen2:7b", []string{"printf", "%s|", "it's", "a b"}, nil)
+	if out, err := exec.Command(got[0], got[1:]...).Output(); err != nil || string(out) != "it's|a b|" {
+		t.Errorf("running %q = %q, %v, want the arguments unchanged", got, out, err)
+	}
+
+	defer func(wait time.Duration) { runExternalWait = wait }(runExternalWait)
+	runExternalWait = 5 * time.Second
+	if err := spawnExternal([]string{"sh", "-c", "echo not in a session >&2; exit 1"}); err == nil || !strings.Contains(err.Error(), "not in a session") {
+		t.Errorf("spawnExternal() of a failing command error = %v", err)
+	}
+	if err := spawnExternal([]string{"gollama-missing-terminal"}); err == nil {
+		t.Error("spawnExternal() of a missing command succeeded")
+	}
+	// Commands that stay running with their window are left to it
+	runExternalWait = 50 * time.Millisecond
+	if err := spawnExternal([]string{"sleep", "1"}); err != nil {
+		t.Errorf("spawnExternal() of a long running command error = %v", err)
+	}
+
+	// A window that can't be opened falls back to running the model here
+	m := &AppModel{cfg: &config.Config{}}
+	_, cmd := m.handleRunExternalMsg(runExternalMsg{model: "qwen2:7b", err: errors.New("gollama isn't running inside tmux")})
+	if cmd == nil || !strings.Contains(m.message, "running it here instead") {
+		t.Errorf("handleRunExternalMsg() with an error = %q, command %v", m.message, cmd != nil)
+	}
+}
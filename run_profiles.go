@@ -0,0 +1,136 @@
+// run_profiles.go applies the run_profiles from the config when a model is run, adding arguments, environment and a
+// prompt file to the run command for the models matching a profile's name or glob. O runs the selected model with
+// one-off options typed in a prompt, which aren't saved.
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// envNamePattern matches the names of environment variables in the one-off options
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runOverride is the prompt for one-off options to run a model with
+type runOverride struct {
+	model string
+	input textinput.Model
+}
+
+// runCommandFor returns the command to run a model with and the environment to add for it, using the override instead
+// of the model's run profile when it isn't nil. A prompt file's contents are sent as the prompt.
+func runCommandFor(model string, cfg *config.Config, override *config.RunProfile) ([]string, []string, error) {
+	profile, _ := cfg.RunProfile(model)
+	if override != nil {
+		profile = *override
+	}
+	args, env := cfg.RunCommandWith(model, profile)
+	if profile.PromptFile != "" {
+		prompt, err := os.ReadFile(expandHome(profile.PromptFile))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading the prompt file for %s: %w", model, err)
+		}
+		args = append(args, string(prompt))
+	}
+	return args, env, nil
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + "/" + rest
+		}
+	}
+	return path
+}
+
+// formatRunOptions writes a profile the way it's typed in the one-off options prompt: the environment, the arguments
+// and the prompt file prefixed with @
+func formatRunOptions(profile config.RunProfile) string {
+	fields := append(append([]string(nil), profile.Env...), profile.Args...)
+	if profile.PromptFile != "" {
+		fields = append(fields, "@"+profile.PromptFile)
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseRunOptions reads the one-off options: NAME=value is environment, @path is the prompt file and anything else is
+// an argument. Options are split on whitespace, so they can't contain spaces.
+func parseRunOptions(text string) config.RunProfile {
+	var profile config.RunProfile
+	for _, field := range strings.Fields(text) {
+		if name, _, ok := strings.Cut(field, "="); ok && envNamePattern.MatchString(name) {
+			profile.Env = append(profile.Env, field)
+			continue
+		}
+		if file, ok := strings.CutPrefix(field, "@"); ok && file != "" {
+			profile.PromptFile = file
+			continue
+		}
+		profile.Args = append(profile.Args, field)
+	}
+	return profile
+}
+
+// runProfileLabel describes the profile a model is run with for the inspect view, empty when there isn't one
+func runProfileLabel(cfg *config.Config, model string) string {
+	if cfg == nil {
+		return ""
+	}
+	profile, key := cfg.RunProfile(model)
+	if key == "" || profile.Empty() {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", key, formatRunOptions(profile))
+}
+
+// handleRunWithOptionsKey opens the one-off options prompt for the selected model, filled in with its run profile
+func (m *AppModel) handleRunWithOptionsKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("RunWithOptions key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	profile, _ := m.cfg.RunProfile(item.Name)
+	input := textinput.New()
+	input.Prompt = "Run " + item.Name + " with: "
+	input.Placeholder = "--verbose OLLAMA_NUM_PARALLEL=2 @prompt.txt"
+	input.SetValue(formatRunOptions(profile))
+	input.CursorEnd()
+	input.Focus()
+	m.runOverride = &runOverride{model: item.Name, input: input}
+	m.message = ""
+	return m, textinput.Blink
+}
+
+// handleRunOverrideKey runs the model with the typed options on enter, esc cancels
+func (m *AppModel) handleRunOverrideKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.runOverride = nil
+		return m, nil
+	case "enter":
+		override := m.runOverride
+		m.runOverride = nil
+		profile := parseRunOptions(override.input.Value())
+		logging.InfoLogger.Printf("Running model %s with one-off options %q\n", override.model, formatRunOptions(profile))
+		recordRun(override.model)
+		return m, m.startRun(override.model, &profile)
+	}
+	var cmd tea.Cmd
+	m.runOverride.input, cmd = m.runOverride.input.Update(msg)
+	return m, cmd
+}
+
+func (m *AppModel) runOverrideView() string {
+	return "\n" + m.runOverride.input.View() + "\nOptions are NAME=value environment, @file for a prompt file and arguments for ollama run, they aren't saved.\nPress enter to run or `esc` to cancel.\n"
+}
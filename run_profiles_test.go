@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestRunCommandFor(t *testing.T) {
+	promptFile := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(promptFile, []byte("Summarise this"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{RunProfiles: map[string]config.RunProfile{
+		"qwen2*": {Args: []string{"--verbose"}, Env: []string{"OLLAMA_NUM_PARALLEL=2"}, PromptFile: promptFile},
+	}}
+
+	args, env, err := runCommandFor("qwen2:7b", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(args, "|"), "ollama|run|qwen2:7b|--verbose|Summarise this"; got != want {
+		t.Errorf("argv = %q, want %q", got, want)
+	}
+	if got := strings.Join(env, " "); got != "OLLAMA_NUM_PARALLEL=2" {
+		t.Errorf("env = %q", got)
+	}
+
+	// One-off options replace the profile for the run, typed the way the prompt shows them
+	options := formatRunOptions(cfg.RunProfiles["qwen2*"])
+	if want := "OLLAMA_NUM_PARALLEL=2 --verbose @" + promptFile; options != want {
+		t.Errorf("formatRunOptions() = %q, want %q", options, want)
+	}
+	override := parseRunOptions("OLLAMA_DEBUG=1 --nowordwrap --format=json")
+	args, env, err = runCommandFor("qwen2:7b", cfg, &override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(args, " "), "ollama run qwen2:7b --nowordwrap --format=json"; got != want {
+		t.Errorf("argv with override = %q, want %q", got, want)
+	}
+	if got := strings.Join(env, " "); got != "OLLAMA_DEBUG=1" {
+		t.Errorf("env with override = %q", got)
+	}
+	if label := runProfileLabel(cfg, "qwen2:7b"); label != "qwen2* ("+options+")" {
+		t.Errorf("runProfileLabel() = %q", label)
+	}
+	if label := runProfileLabel(cfg, "llama3"); label != "" {
+		t.Errorf("runProfileLabel() without a profile = %q", label)
+	}
+
+	missing := parseRunOptions("@" + filepath.Join(t.TempDir(), "missing.txt"))
+	if _, _, err := runCommandFor("qwen2:7b", cfg, &missing); err == nil {
+		t.Error("a missing prompt file didn't fail")
+	}
+}
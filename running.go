@@ -0,0 +1,112 @@
+// running.go reads the running model fields newer Ollama versions return that the api package doesn't decode yet.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+// runningModel is a model from /api/ps with the fields api.ProcessModelResponse is missing
+type runningModel struct {
+	api.ProcessModelResponse
+	ContextLength  int    `json:"context_length,omitempty"`  // context size the model was loaded with, zero if the server doesn't report it
+	KVCacheType    string `json:"kv_cache_type,omitempty"`   // k/v cache quantisation it was loaded with (f16, q8_0 or q4_0), empty if not reported
+	FlashAttention *bool  `json:"flash_attention,omitempty"` // whether flash attention is enabled, nil if not reported
+}
+
+// kvCacheLabel describes the k/v cache a running model was loaded with, unknown when the server doesn't report it
+// rather than implying the fp16 default, as OLLAMA_KV_CACHE_TYPE and OLLAMA_FLASH_ATTENTION may have changed it
+func (r runningModel) kvCacheLabel() string {
+	label := "unknown"
+	if r.KVCacheType != "" {
+		label = r.KVCacheType
+	}
+	if r.FlashAttention != nil && *r.FlashAttention {
+		label += " FA"
+	}
+	return label
+}
+
+// processor describes where a running model is loaded the same way `ollama ps` does
+func (r runningModel) processor() string {
+	switch {
+	case r.Size <= 0:
+		return "n/a"
+	case r.SizeVRAM <= 0:
+		return "100% CPU"
+	case r.SizeVRAM >= r.Size:
+		return "100% GPU"
+	default:
+		gpu := int(float64(r.SizeVRAM) / float64(r.Size) * 100)
+		return fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+	}
+}
+
+// formatContextLength formats the context a model was loaded with, or n/a when Ollama didn't report it
+func formatContextLength(length int) string {
+	if length <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d", length)
+}
+
+// apiHTTPClient is used for the API calls made without the api package, main gives it the authenticating transport
+var apiHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// listRunningModels returns the running models with every field /api/ps provides, sanitised like sanitiseRunningModels
+func listRunningModels(ctx context.Context, apiURL string) ([]runningModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := apiHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := config.AuthError(resp.StatusCode); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama API returned %s", resp.Status)
+	}
+
+	var decoded struct {
+		Models []runningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("error decoding running models: %w", err)
+	}
+
+	// Sanitise the standard fields and carry the extra ones over to the entries that are kept
+	extra := make(map[string]runningModel, len(decoded.Models))
+	processResp := &api.ProcessResponse{}
+	for _, model := range decoded.Models {
+		if _, ok := extra[runningModelKey(model.ProcessModelResponse)]; !ok {
+			extra[runningModelKey(model.ProcessModelResponse)] = model
+		}
+		processResp.Models = append(processResp.Models, model.ProcessModelResponse)
+	}
+	sanitised := sanitiseRunningModels(processResp)
+	models := make([]runningModel, len(sanitised))
+	for i, model := range sanitised {
+		models[i] = extra[runningModelKey(model)]
+		models[i].ProcessModelResponse = model
+	}
+	return models, nil
+}
+
+func runningModelKey(model api.ProcessModelResponse) string {
+	if model.Digest != "" {
+		return model.Digest
+	}
+	return model.Name
+}
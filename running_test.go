@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+func TestListRunningModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[
+			{"name":"llama3:8b","digest":"a","size":1000,"size_vram":1000,"context_length":8192,"kv_cache_type":"q8_0","flash_attention":true},
+			{"name":"llama3:8b","digest":"a","size":0},
+			{"name":"qwen2:7b","digest":"b","size":1000,"size_vram":250}
+		]}`))
+	}))
+	defer server.Close()
+
+	models, err := listRunningModels(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("listRunningModels() error = %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("listRunningModels() returned %d models, want the duplicate dropped", len(models))
+	}
+	if models[0].ContextLength != 8192 || models[1].ContextLength != 0 {
+		t.Errorf("context lengths = %d, %d, want 8192, 0", models[0].ContextLength, models[1].ContextLength)
+	}
+	// Servers that don't report the k/v cache show it as unknown rather than the fp16 default
+	if models[0].kvCacheLabel() != "q8_0 FA" || models[1].kvCacheLabel() != "unknown" {
+		t.Errorf("k/v caches = %q, %q, want q8_0 FA and unknown", models[0].kvCacheLabel(), models[1].kvCacheLabel())
+	}
+
+	quant, note := runningKVCache(context.Background(), server.URL, "llama3:8b")
+	if quant != vramestimator.KVCacheQ8_0 || !strings.Contains(note, "q8_0 FA") {
+		t.Errorf("runningKVCache(llama3:8b) = %q, %q, want q8_0", quant, note)
+	}
+	if quant, note := runningKVCache(context.Background(), server.URL, "qwen2:7b"); quant != "" || !strings.Contains(note, "unknown") {
+		t.Errorf("runningKVCache(qwen2:7b) = %q, %q, want an unknown k/v cache", quant, note)
+	}
+	if quant, note := runningKVCache(context.Background(), server.URL, "mistral"); quant != "" || note != "" {
+		t.Errorf("runningKVCache(mistral) = %q, %q, want nothing for a model that isn't running", quant, note)
+	}
+	if got := models[0].processor(); got != "100% GPU" {
+		t.Errorf("processor() = %q, want 100%% GPU", got)
+	}
+	if got := models[1].processor(); got != "75%/25% CPU/GPU" {
+		t.Errorf("processor() = %q, want 75%%/25%% CPU/GPU", got)
+	}
+}
@@ -0,0 +1,205 @@
+// search_query.go parses the terms of -s, e.g. "llama&size>20GB" or "re:llama.?3|qwen modified<2024-01-01".
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchOperators are the comparisons size and modified predicates accept, two character ones first
+var searchOperators = []string{">=", "<=", ">", "<", "="}
+
+// searchTerm matches a model by a substring of its name (text) or by a predicate, which only filters and is never highlighted
+type searchTerm struct {
+	text      string
+	predicate func(Model) bool
+}
+
+func (t searchTerm) matches(model Model) bool {
+	if t.predicate != nil {
+		return t.predicate(model)
+	}
+	return strings.Contains(strings.ToLower(model.Name), strings.ToLower(t.text))
+}
+
+// searchQuery holds the search terms. Each argument must match, within one "&" separates terms that must all match and
+// "|" separates alternatives, binding tighter than "&" so "a|b&c" is (a or b) and c.
+type searchQuery [][][]searchTerm
+
+// parseSearchQuery parses the -s arguments, failing on an invalid predicate
+func parseSearchQuery(args ...string) (searchQuery, error) {
+	var q searchQuery
+	for _, arg := range args {
+		terms, separators := splitSearchTerms(arg)
+		var all [][]searchTerm
+		var alternatives []searchTerm
+		for i, s := range terms {
+			if i > 0 && separators[i-1] == '&' {
+				all = append(all, alternatives)
+				alternatives = nil
+			}
+			term, err := parseSearchTerm(s)
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, term)
+		}
+		q = append(q, append(all, alternatives))
+	}
+	return q, nil
+}
+
+// splitSearchTerms splits an argument into its terms and the "&" or "|" before each term after the first. In a re:
+// term the separators inside brackets or escaped with a backslash are part of the pattern, so re:(q4|q8) is one term.
+func splitSearchTerms(arg string) (terms []string, separators []byte) {
+	start, depth, class := 0, 0, false
+	regex := strings.HasPrefix(arg, "re:")
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		switch {
+		case regex && c == '\\':
+			i++ // the escaped character is part of the pattern
+		case regex && class:
+			class = c != ']'
+		case regex && c == '[':
+			class = true
+		case regex && c == '(':
+			depth++
+		case regex && c == ')' && depth > 0:
+			depth--
+		case (c == '&' || c == '|') && depth == 0:
+			terms = append(terms, arg[start:i])
+			separators = append(separators, c)
+			start = i + 1
+			regex = strings.HasPrefix(arg[start:], "re:")
+		}
+	}
+	return append(terms, arg[start:]), separators
+}
+
+// parseSearchTerm parses re:<pattern>, size<op><size>, modified<op><YYYY-MM-DD> or a plain substring
+func parseSearchTerm(s string) (searchTerm, error) {
+	lower := strings.ToLower(s)
+	if pattern, ok := strings.CutPrefix(s, "re:"); ok {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return searchTerm{}, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return searchTerm{predicate: func(model Model) bool { return re.MatchString(model.Name) }}, nil
+	}
+	if op, value, ok := cutSearchOperator(lower, "size"); ok {
+		size, err := parseByteSize(value)
+		if err != nil {
+			return searchTerm{}, err
+		}
+		return searchTerm{predicate: func(model Model) bool { return compareMatches(op, cmp.Compare(model.Size, size)) }}, nil
+	}
+	if op, value, ok := cutSearchOperator(lower, "modified"); ok {
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return searchTerm{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD (e.g. modified<2024-01-01)", value)
+		}
+		// Dates in the same format compare correctly as strings
+		return searchTerm{predicate: func(model Model) bool {
+			return compareMatches(op, cmp.Compare(model.Modified.Local().Format("2006-01-02"), value))
+		}}, nil
+	}
+	return searchTerm{text: s}, nil
+}
+
+// cutSearchOperator splits a predicate such as "size>=20gb" into its operator and value
+func cutSearchOperator(s, field string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(s, field)
+	if !ok {
+		return "", "", false
+	}
+	for _, op := range searchOperators {
+		if value, ok := strings.CutPrefix(rest, op); ok {
+			return op, strings.TrimSpace(value), true
+		}
+	}
+	return "", "", false
+}
+
+// compareMatches reports whether the result of a comparison satisfies the operator
+func compareMatches(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+// parseByteSize parses a size like 20GB, 512mb or 1.5T in the same binary units as the sizes gollama shows
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a number followed by B, KB, MB, GB or TB (e.g. size>20GB)", s)
+	}
+	var multiplier float64
+	switch strings.TrimSuffix(s[i:], "B") {
+	case "":
+		multiplier = 1
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("invalid size %q, unknown unit %q (use B, KB, MB, GB or TB)", s, s[i:])
+	}
+	return int64(n * multiplier), nil
+}
+
+// matches reports whether a model matches every argument of the query
+func (q searchQuery) matches(model Model) bool {
+	for _, all := range q {
+		for _, alternatives := range all {
+			if !anySearchTermMatches(alternatives, model) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func anySearchTermMatches(terms []searchTerm, model Model) bool {
+	for _, term := range terms {
+		if term.matches(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// textTerms returns the plain substrings of the query, the only terms that are highlighted in the results
+func (q searchQuery) textTerms() []string {
+	var texts []string
+	for _, all := range q {
+		for _, alternatives := range all {
+			for _, term := range alternatives {
+				if term.predicate == nil && term.text != "" {
+					texts = append(texts, term.text)
+				}
+			}
+		}
+	}
+	return texts
+}
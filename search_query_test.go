@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSearchQuery(t *testing.T) {
+	newModel := func(name string, sizeGB int64, modified string) Model {
+		model := Model{}
+		model.Name, model.Size = name, sizeGB<<30
+		model.Modified, _ = time.ParseInLocation("2006-01-02", modified, time.Local)
+		return model
+	}
+	models := []Model{
+		newModel("llama3:8b", 5, "2024-05-01"),
+		newModel("llama3:70b", 40, "2023-12-01"),
+		newModel("qwen2:72b", 41, "2024-06-01"),
+		newModel("mistral:7b", 4, "2023-10-01"),
+	}
+
+	tests := []struct {
+		terms []string
+		want  []string
+	}{
+		{[]string{"llama"}, []string{"llama3:70b", "llama3:8b"}},
+		// "|" binds tighter than "&", so this is (llama or qwen) and 7
+		{[]string{"llama|qwen&7"}, []string{"llama3:70b", "qwen2:72b"}},
+		{[]string{"mistral|llama&8b"}, []string{"llama3:8b"}},
+		{[]string{"7&mistral|qwen"}, []string{"mistral:7b", "qwen2:72b"}},
+		{[]string{"llama", "70b|8b"}, []string{"llama3:70b", "llama3:8b"}},
+		{[]string{"size>20GB"}, []string{"llama3:70b", "qwen2:72b"}},
+		{[]string{"size<=5gb&size>4.5G"}, []string{"llama3:8b"}},
+		{[]string{"llama&size>20GB|modified>2024-05-31"}, []string{"llama3:70b"}},
+		{[]string{"modified<2024-01-01"}, []string{"llama3:70b", "mistral:7b"}},
+		{[]string{"modified=2024-06-01"}, []string{"qwen2:72b"}},
+		{[]string{"re:LLAMA.?3:\\d+b$"}, []string{"llama3:70b", "llama3:8b"}},
+		{[]string{"re:^q|mistral"}, []string{"mistral:7b", "qwen2:72b"}},
+		// Alternatives inside a regular expression's brackets are part of the pattern
+		{[]string{"re:(70|72)b$"}, []string{"llama3:70b", "qwen2:72b"}},
+		{[]string{"re:[|]|re:^(mistral|qwen)&size>10GB"}, []string{"qwen2:72b"}},
+		{[]string{"re:mistral\\|7b|qwen"}, []string{"qwen2:72b"}},
+	}
+	for _, tt := range tests {
+		results, err := matchingModels(models, tt.terms...)
+		if err != nil {
+			t.Errorf("matchingModels(%q) error: %v", tt.terms, err)
+			continue
+		}
+		var names []string
+		for _, model := range results {
+			names = append(names, model.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("matchingModels(%q) = %v, want %v", tt.terms, names, tt.want)
+		}
+	}
+
+	quant, err := parseSearchQuery("re:(q4|q8)")
+	if err != nil || len(quant[0]) != 1 || len(quant[0][0]) != 1 {
+		t.Fatalf("parseSearchQuery(re:(q4|q8)) = %v, %v, want a single term", quant, err)
+	}
+	if term := quant[0][0][0]; !term.matches(newModel("llama3:8b-q8_0", 8, "2024-05-01")) || term.matches(newModel("llama3:8b-fp16", 16, "2024-05-01")) {
+		t.Error("re:(q4|q8) doesn't match the q8_0 model and not the fp16 one")
+	}
+
+	for _, term := range []string{"re:llama(", "size>20XB", "size>big", "modified<2024-13-01"} {
+		if _, err := matchingModels(models, term); err == nil {
+			t.Errorf("matchingModels(%q) expected an error", term)
+		}
+	}
+
+	query, _ := parseSearchQuery("llama|re:qwen&size>1GB", "8b")
+	if got := query.textTerms(); !slices.Equal(got, []string{"llama", "8b"}) {
+		t.Errorf("textTerms() = %v, want only the plain text terms", got)
+	}
+}
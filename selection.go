@@ -0,0 +1,79 @@
+// selection.go selects models in bulk. Each action applies to the models the list currently shows, so filtering for
+// q4, selecting all and deleting clears out every q4 model.
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// setVisibleSelection sets Selected on each model the list shows to selected(model)
+func (m *AppModel) setVisibleSelection(selected func(Model) bool) tea.Cmd {
+	visible := make(map[string]bool)
+	for _, item := range m.list.VisibleItems() {
+		if model, ok := item.(Model); ok {
+			visible[model.Name] = selected(model)
+		}
+	}
+	return m.setSelection(visible)
+}
+
+// setSelection sets Selected on the models named in selection, keeping m.models and the list items in sync as
+// handleSpaceKey does. It returns the command that re-applies an active filter to the updated items.
+func (m *AppModel) setSelection(selection map[string]bool) tea.Cmd {
+	for i, model := range m.models {
+		if s, ok := selection[model.Name]; ok {
+			m.models[i].Selected = s
+		}
+	}
+	items := m.list.Items()
+	for i, item := range items {
+		if model, ok := item.(Model); ok {
+			if s, ok := selection[model.Name]; ok {
+				model.Selected = s
+				items[i] = model
+			}
+		}
+	}
+
+	index := m.list.Index()
+	cmd := m.list.SetItems(items)
+	m.list.Select(index)
+	logging.DebugLogger.Printf("Updated the selection of %d models, %s\n", len(selection), selectionSummary(m.models))
+	return cmd
+}
+
+func (m *AppModel) handleSelectAllKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("SelectAll key matched")
+	return m, m.setVisibleSelection(func(Model) bool { return true })
+}
+
+func (m *AppModel) handleInvertSelectionKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("InvertSelection key matched")
+	return m, m.setVisibleSelection(func(model Model) bool { return !model.Selected })
+}
+
+func (m *AppModel) handleDeselectAllKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("DeselectAll key matched")
+	return m, m.setVisibleSelection(func(Model) bool { return false })
+}
+
+// selectionSummary is the status line showing how many models are selected and their total size, e.g.
+// "12 selected (48.3GB)", or empty when nothing is selected
+func selectionSummary(models []Model) string {
+	var count int
+	var sizeGB float64
+	for _, model := range models {
+		if model.Selected {
+			count++
+			sizeGB += model.SizeGB()
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d selected (%.1fGB)", count, sizeGB)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBulkSelection(t *testing.T) {
+	newModel := func(name string) Model {
+		model := Model{}
+		model.Name, model.Size = name, 1<<30
+		return model
+	}
+	models := []Model{newModel("llama3:8b-q4_0"), newModel("qwen2:7b-q4_K_M"), newModel("llama3:8b-q8_0")}
+	items := make([]list.Item, len(models))
+	for i, model := range models {
+		items[i] = model
+	}
+	m := &AppModel{models: models, list: list.New(items, list.NewDefaultDelegate(), 80, 40)}
+
+	// applyFilter applies a filter as typing it and pressing enter does, running the filtering synchronously
+	applyFilter := func(text string) {
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+		m.list, _ = m.list.Update(m.list.SetItems(m.list.Items())())
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	// runCmd feeds the re-filtering a selection change returns back to the list
+	runCmd := func(cmd tea.Cmd) {
+		if cmd != nil {
+			m.list, _ = m.list.Update(cmd())
+		}
+	}
+	selected := func() []string {
+		var names []string
+		for _, model := range m.models {
+			if model.Selected {
+				names = append(names, model.Name)
+			}
+		}
+		return names
+	}
+
+	applyFilter("q4")
+	if m.list.FilterState() != list.FilterApplied || len(m.list.VisibleItems()) != 2 {
+		t.Fatalf("filter q4 shows %d models (state %v), want 2", len(m.list.VisibleItems()), m.list.FilterState())
+	}
+	_, cmd := m.handleSelectAllKey()
+	runCmd(cmd)
+	if got := selected(); !slices.Equal(got, []string{"llama3:8b-q4_0", "qwen2:7b-q4_K_M"}) {
+		t.Errorf("select all with a filter selected %v", got)
+	}
+	for _, item := range m.list.VisibleItems() {
+		if !item.(Model).Selected {
+			t.Errorf("filtered list item %s isn't selected", item.(Model).Name)
+		}
+	}
+	if got := selectionSummary(m.models); got != "2 selected (2.0GB)" {
+		t.Errorf("selectionSummary() = %q", got)
+	}
+
+	// Clearing the filter keeps the selection and invert then applies to every model
+	m.list.ResetFilter()
+	_, cmd = m.handleInvertSelectionKey()
+	runCmd(cmd)
+	if got := selected(); !slices.Equal(got, []string{"llama3:8b-q8_0"}) {
+		t.Errorf("invert without a filter selected %v", got)
+	}
+	for _, item := range m.list.Items() {
+		if model := item.(Model); model.Selected != (model.Name == "llama3:8b-q8_0") {
+			t.Errorf("list item %s Selected = %v, out of sync with the models", model.Name, model.Selected)
+		}
+	}
+
+	// Deselecting under a filter leaves the models it hides alone
+	_, cmd = m.handleSelectAllKey()
+	runCmd(cmd)
+	applyFilter("qwen")
+	_, cmd = m.handleDeselectAllKey()
+	runCmd(cmd)
+	if got := selected(); !slices.Equal(got, []string{"llama3:8b-q4_0", "llama3:8b-q8_0"}) {
+		t.Errorf("deselect all with a filter left %v selected", got)
+	}
+	m.list.ResetFilter()
+	_, cmd = m.handleDeselectAllKey()
+	runCmd(cmd)
+	if got := selectionSummary(m.models); got != "" {
+		t.Errorf("selectionSummary() with nothing selected = %q", got)
+	}
+}
@@ -0,0 +1,357 @@
+// serve.go runs `gollama serve`, a headless HTTP JSON API over the operations the TUI uses so dashboards and scripts
+// can list, pull and delete models and estimate vRAM without driving a terminal.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/pkg/gollama"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+const (
+	// defaultServeListen only accepts local connections unless --listen says otherwise
+	defaultServeListen = "127.0.0.1:8765"
+	// serveShutdownTimeout is how long requests in flight get to finish after SIGINT
+	serveShutdownTimeout = 5 * time.Second
+)
+
+// apiServer serves the HTTP API, token is the bearer token requests must send, empty to accept any request. listenHost
+// is the host it listens on, which requests must be addressed to.
+type apiServer struct {
+	client     *api.Client
+	apiURL     string
+	token      string
+	listenHost string
+}
+
+// vramEstimateRequest is the body of POST /vram-estimate, the same options as the -vram flags
+type vramEstimateRequest struct {
+	Model        string    `json:"model"`
+	FitsVRAM     float64   `json:"fits_vram"`
+	MaxContext   int       `json:"max_context"`
+	GPUs         int       `json:"gpus"`
+	TensorSplit  []float64 `json:"tensor_split"`
+	KVCacheQuant string    `json:"kv_cache_quant"`
+}
+
+// pullProgressLine is a line of the pull endpoint's newline delimited JSON, the last has a status of success or an error
+type pullProgressLine struct {
+	Status    string `json:"status,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// runServe parses the serve arguments and serves the API until SIGINT, returning the exit code
+func runServe(ctx context.Context, client *api.Client, cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", defaultServeListen, "Address to listen on")
+	token := flags.String("token", os.Getenv("GOLLAMA_API_TOKEN"), "Bearer token requests must send, defaults to GOLLAMA_API_TOKEN")
+	insecure := flags.Bool("insecure", false, "Serve on a non-loopback address without a token")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	host, _, err := net.SplitHostPort(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid listen address %s: %v\n", *listen, err)
+		return 2
+	}
+	if *token == "" && !isLoopbackHost(host) {
+		if !*insecure {
+			fmt.Fprintf(os.Stderr, "Refusing to serve on %s without a token, anyone who can reach it could delete models. Set --token or GOLLAMA_API_TOKEN, or pass --insecure\n", *listen)
+			return 2
+		}
+		logging.InfoLogger.Printf("Serving on %s without a token, anyone who can reach it can delete models\n", *listen)
+		fmt.Fprintf(os.Stderr, "Warning: serving on %s without a token, set --token or GOLLAMA_API_TOKEN\n", *listen)
+	}
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listening on %s: %v\n", *listen, err)
+		return 1
+	}
+
+	serveCtx, stopSignals := signalContext(ctx, "the API server", reloadConfig(cfg))
+	defer stopSignals()
+	server := &http.Server{Handler: newAPIServer(client, cfg.OllamaAPIURL, *token, host), ReadHeaderTimeout: 10 * time.Second}
+	// Serve returns as soon as Shutdown is called, done is closed once the requests in flight have finished
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-serveCtx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logging.ErrorLogger.Printf("Error shutting down the API server: %v\n", err)
+		}
+	}()
+
+	logging.InfoLogger.Printf("Serving the API on %s\n", listener.Addr())
+	fmt.Printf("Serving the gollama API on http://%s\n", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.ErrorLogger.Printf("API server error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error serving the API: %v\n", err)
+		return 1
+	}
+	<-done
+	return 0
+}
+
+// isLoopbackHost reports whether a listen host only accepts local connections
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newAPIServer returns the API's handler for a server listening on listenHost. Model names can contain slashes, so the
+// pull route is matched by its suffix.
+func newAPIServer(client *api.Client, apiURL, token, listenHost string) http.Handler {
+	s := &apiServer{client: client, apiURL: apiURL, token: token, listenHost: listenHost}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /models", s.handleListModels)
+	mux.HandleFunc("GET /running", s.handleRunning)
+	mux.HandleFunc("POST /models/{path...}", s.handlePull)
+	mux.HandleFunc("DELETE /models/{name...}", s.handleDelete)
+	mux.HandleFunc("POST /vram-estimate", s.handleVRAMEstimate)
+	return s.logRequests(s.rejectCrossSite(s.authorise(mux)))
+}
+
+// statusRecorder remembers the status a handler wrote for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets the pull endpoint stream through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *apiServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		logging.InfoLogger.Printf("API %s %s from %s: %d in %s\n", r.Method, r.URL.Path, r.RemoteAddr, recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// authorise rejects requests without the bearer token when one is set
+func (s *apiServer) authorise(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rejectCrossSite stops web pages the user visits from driving the API, which a token-less server on 127.0.0.1 would
+// otherwise let them do: requests from another origin are refused, so are requests addressed to another host name,
+// as a DNS rebinding attack sends, and POST bodies must be JSON, which a page can't send cross-site without asking.
+func (s *apiServer) rejectCrossSite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeAPIError(w, http.StatusForbidden, fmt.Errorf("requests must be addressed to %s, not %s", s.listenHost, r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeAPIError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests from %s aren't allowed", origin))
+				return
+			}
+		}
+		if r.Method == http.MethodPost && (r.ContentLength != 0 || r.URL.Path == "/vram-estimate") {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("the request body must be sent as application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a request's Host is the address the server listens on. On a loopback address only
+// loopback names are accepted, on a wildcard address any host is, so those servers need a token.
+func (s *apiServer) allowedHost(requestHost string) bool {
+	hostname := requestHost
+	if h, _, err := net.SplitHostPort(requestHost); err == nil {
+		hostname = h
+	}
+	hostname = strings.Trim(hostname, "[]")
+	switch {
+	case isLoopbackHost(s.listenHost):
+		return isLoopbackHost(hostname)
+	case s.listenHost == "" || net.ParseIP(s.listenHost) != nil && net.ParseIP(s.listenHost).IsUnspecified():
+		return true
+	}
+	return strings.EqualFold(hostname, s.listenHost)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.ErrorLogger.Printf("Error writing API response: %v\n", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// handleListModels returns the models in the same shape as `gollama -l -json`
+func (s *apiServer) handleListModels(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.client.List(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, fmt.Errorf("error listing models: %w", err))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, modelListEntries(parseAPIResponse(resp)))
+}
+
+func (s *apiServer) handleRunning(w http.ResponseWriter, r *http.Request) {
+	models, err := listRunningModels(r.Context(), s.apiURL)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, fmt.Errorf("error listing running models: %w", err))
+		return
+	}
+	if models == nil {
+		models = []runningModel{}
+	}
+	writeAPIJSON(w, http.StatusOK, models)
+}
+
+// handlePull pulls a model, streaming its progress as newline delimited JSON until it finishes or the client goes away
+func (s *apiServer) handlePull(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("path"), "/pull")
+	if !ok || name == "" {
+		writeAPIError(w, http.StatusNotFound, errors.New("not found, pull with POST /models/{name}/pull"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	send := func(line pullProgressLine) error {
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	// The pull stops when the progress can't be written, as no one is reading it
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	var writeErr error
+	err := s.client.Pull(ctx, &api.PullRequest{Name: name}, func(resp api.ProgressResponse) error {
+		if writeErr = send(pullProgressLine{Status: resp.Status, Digest: resp.Digest, Total: resp.Total, Completed: resp.Completed}); writeErr != nil {
+			cancel()
+		}
+		return writeErr
+	})
+	if writeErr != nil {
+		logging.ErrorLogger.Printf("Stopped pulling %s for the API, the progress couldn't be sent: %v\n", name, writeErr)
+		return
+	}
+	if err != nil {
+		logging.ErrorLogger.Printf("Error pulling %s for the API: %v\n", name, err)
+		send(pullProgressLine{Error: err.Error()})
+		return
+	}
+	recordPull(s.client, name)
+}
+
+// handleDelete deletes a model, refusing pinned ones like the bulk delete
+func (s *apiServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	results, err := gollama.BulkAction(r.Context(), s.client, apiHTTPClient, s.apiURL, gollama.ActionDelete, []string{name})
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err)
+		return
+	}
+	if results[0].Error != "" {
+		writeAPIJSON(w, http.StatusConflict, results[0])
+		return
+	}
+	recordHistory("delete", name, "")
+	writeAPIJSON(w, http.StatusOK, results[0])
+}
+
+func (s *apiServer) handleVRAMEstimate(w http.ResponseWriter, r *http.Request) {
+	var req vramEstimateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("error decoding the request: %w", err))
+		return
+	}
+	if req.Model == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("model is required"))
+		return
+	}
+	// Only model names are estimated, a local file path would let a client read files on the server
+	if vramestimator.IsGGUFPath(req.Model) || filepath.IsAbs(req.Model) || strings.HasPrefix(req.Model, ".") || strings.HasPrefix(req.Model, "~") {
+		writeAPIError(w, http.StatusBadRequest, errors.New("model must be an Ollama or HuggingFace model name, not a file path"))
+		return
+	}
+	var kvCacheQuant vramestimator.KVCacheQuantisation
+	if req.KVCacheQuant != "" {
+		var err error
+		if kvCacheQuant, err = vramestimator.ParseKVCacheQuantisation(req.KVCacheQuant); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	table, err := gollama.EstimateVRAM(gollama.EstimateOptions{
+		Model:        req.Model,
+		APIURL:       s.apiURL,
+		HTTPClient:   apiHTTPClient,
+		FitsVRAM:     req.FitsVRAM,
+		MaxContext:   req.MaxContext,
+		GPUs:         vramestimator.GPUSetup{Count: req.GPUs, Split: req.TensorSplit},
+		KVCacheQuant: kvCacheQuant,
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, table)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestServeAPI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var deleted []string
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			json.NewEncoder(w).Encode(api.ListResponse{Models: []api.ListModelResponse{{Name: "sammcj/model:q4", Digest: "0123456789abcdef", Size: 42}}})
+		case "/api/ps":
+			json.NewEncoder(w).Encode(api.ProcessResponse{})
+		case "/api/pull":
+			var req api.PullRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Model != "sammcj/model:q4" && req.Name != "sammcj/model:q4" {
+				http.Error(w, `{"error":"pull model manifest: file does not exist"}`, http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "pulling", Digest: "sha256:a", Total: 100, Completed: 50})
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+		case "/api/delete":
+			var req api.DeleteRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			deleted = append(deleted, req.Name+req.Model)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	server := httptest.NewServer(newAPIServer(api.NewClient(u, http.DefaultClient), ollama.URL, "secret", "127.0.0.1"))
+	defer server.Close()
+
+	request := func(method, path, token string, body string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := request("GET", "/models", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /models with the wrong token = %d, want 401", resp.StatusCode)
+	}
+
+	var models []modelListEntry
+	resp := request("GET", "/models", "secret", "")
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil || len(models) != 1 || models[0].Name != "sammcj/model:q4" || models[0].ID != "0123456" {
+		t.Errorf("GET /models = %d %+v, %v", resp.StatusCode, models, err)
+	}
+
+	resp = request("GET", "/running", "secret", "")
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("GET /running = %d %s, want an empty list", resp.StatusCode, body)
+	}
+
+	// Model names with a namespace keep their slash in the pull path
+	resp = request("POST", "/models/sammcj/model:q4/pull", "secret", "")
+	var lines []pullProgressLine
+	for dec := json.NewDecoder(resp.Body); dec.More(); {
+		var line pullProgressLine
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" || len(lines) != 2 || lines[0].Completed != 50 || lines[1].Status != "success" {
+		t.Errorf("pull streamed %q %+v", resp.Header.Get("Content-Type"), lines)
+	}
+	resp = request("POST", "/models/missing/pull", "secret", "")
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), `"error"`) {
+		t.Errorf("failed pull streamed %s, want an error line", body)
+	}
+
+	resp = request("DELETE", "/models/sammcj/model:q4", "secret", "")
+	if resp.StatusCode != http.StatusOK || !slices.Equal(deleted, []string{"sammcj/model:q4"}) {
+		t.Errorf("DELETE = %d, deleted %v", resp.StatusCode, deleted)
+	}
+
+	if resp := request("POST", "/vram-estimate", "secret", `{"model": ""}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /vram-estimate without a model = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServeRejectsCrossSite(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ListResponse{})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	// No token, as served on 127.0.0.1 by default
+	server := httptest.NewServer(newAPIServer(api.NewClient(u, http.DefaultClient), ollama.URL, "", "127.0.0.1"))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		host        string
+		origin      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"same origin", "GET", "/models", "", server.URL, "", "", http.StatusOK},
+		{"localhost", "GET", "/models", "localhost", "", "", "", http.StatusOK},
+		{"other origin", "POST", "/models/llama3/pull", "", "https://evil.example", "", "", http.StatusForbidden},
+		{"rebound host name", "DELETE", "/models/llama3", "evil.example", "", "", "", http.StatusForbidden},
+		{"text body", "POST", "/vram-estimate", "", "", "text/plain", `{"model": "llama3"}`, http.StatusUnsupportedMediaType},
+		{"gguf path", "POST", "/vram-estimate", "", "", "application/json", `{"model": "/home/someone/model.gguf"}`, http.StatusBadRequest},
+		{"relative path", "POST", "/vram-estimate", "", "", "application/json", `{"model": "../secrets/model"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if tt.host != "" {
+				req.Host = tt.host + ":" + u.Port()
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if code := runServe(context.Background(), api.NewClient(u, http.DefaultClient), config.Config{}, []string{"--listen", "0.0.0.0:0"}); code != 2 {
+		t.Errorf("runServe() on 0.0.0.0 without a token = %d, want 2", code)
+	}
+}
+
+// failingWriter is a response whose client has gone away, it counts the writes attempted
+type failingWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestServePullStopsWhenUnread(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range 3 {
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "pulling", Total: 100, Completed: int64(i * 10)})
+		}
+		json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	s := &apiServer{client: api.NewClient(u, http.DefaultClient), apiURL: ollama.URL}
+
+	w := &failingWriter{ResponseRecorder: httptest.NewRecorder()}
+	req := httptest.NewRequest("POST", "/models/llama3/pull", nil)
+	req.SetPathValue("path", "llama3/pull")
+	s.handlePull(w, req)
+	if w.writes != 1 {
+		t.Errorf("%d writes attempted, want the pull stopped after the first failed", w.writes)
+	}
+	if events, _ := modelHistory("llama3"); len(events) != 0 {
+		t.Errorf("history = %+v, want the stopped pull not recorded", events)
+	}
+}
+
+func TestServeWaitsForShutdown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	inFlight, release := make(chan struct{}), make(chan struct{})
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+		json.NewEncoder(w).Encode(api.ListResponse{})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	code := make(chan int)
+	go func() {
+		code <- runServe(ctx, api.NewClient(u, http.DefaultClient), config.Config{OllamaAPIURL: ollama.URL}, []string{"--listen", addr})
+	}()
+	status := make(chan int)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr + "/models")
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+			return
+		}
+	}()
+
+	<-inFlight
+	cancel()
+	select {
+	case <-code:
+		close(release)
+		t.Fatal("runServe returned with a request in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("request in flight = %d, want it finished", got)
+	}
+	if got := <-code; got != 0 {
+		t.Errorf("runServe() = %d, want 0", got)
+	}
+}
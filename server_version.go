@@ -0,0 +1,114 @@
+// server_version.go checks the version of the Ollama server gollama is connected to against the version of the Ollama
+// client library it was built with, as the create and pull APIs change between minor versions. Servers too old to have
+// /api/version, or that don't answer in time, are reported as unknown rather than holding up startup.
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// ollamaClientVersion is the version of the Ollama client library, set by the build system or read from the build info
+var ollamaClientVersion string
+
+// serverVersionTimeout limits waiting for the server's version
+const serverVersionTimeout = 3 * time.Second
+
+// unknownVersion is shown for a version that couldn't be found
+const unknownVersion = "unknown"
+
+// serverVersionMsg is the version of the connected server, fetched when the TUI starts
+type serverVersionMsg struct {
+	version string
+}
+
+// clientLibraryVersion returns the version of the Ollama client library gollama was built with, e.g. 0.5.7
+func clientLibraryVersion() string {
+	if ollamaClientVersion != "" {
+		return strings.TrimPrefix(ollamaClientVersion, "v")
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/ollama/ollama" {
+				return strings.TrimPrefix(dep.Version, "v")
+			}
+		}
+	}
+	return unknownVersion
+}
+
+// fetchServerVersion returns the version of the connected server, or unknown when it can't be fetched
+func fetchServerVersion(ctx context.Context, client *api.Client) string {
+	ctx, cancel := context.WithTimeout(ctx, serverVersionTimeout)
+	defer cancel()
+	version, err := client.Version(ctx)
+	if err != nil || version == "" {
+		logging.InfoLogger.Printf("Couldn't get the Ollama server version: %v\n", err)
+		return unknownVersion
+	}
+	logging.InfoLogger.Printf("Connected to Ollama %s, built with the %s client library\n", version, clientLibraryVersion())
+	return version
+}
+
+// majorMinor returns the major and minor parts of a version, e.g. 0.5 for v0.5.7-rc1, false if it isn't a version
+func majorMinor(version string) (string, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	for _, part := range parts[:2] {
+		if strings.Trim(part, "0123456789") != "" {
+			return "", false
+		}
+	}
+	return parts[0] + "." + parts[1], true
+}
+
+// versionWarning returns a one line warning when the server's major or minor version differs from the client
+// library's, empty when they match or either isn't known
+func versionWarning(server, client string) string {
+	serverVersion, ok := majorMinor(server)
+	if !ok {
+		return ""
+	}
+	clientVersion, ok := majorMinor(client)
+	if !ok || serverVersion == clientVersion {
+		return ""
+	}
+	return fmt.Sprintf("Ollama %s differs from the %s client gollama was built with, creates and pulls may not work as expected", server, client)
+}
+
+// checkServerVersion returns the command fetching the server's version for the footer
+func (m *AppModel) checkServerVersion() tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		return serverVersionMsg{version: fetchServerVersion(context.Background(), client)}
+	}
+}
+
+func (m *AppModel) handleServerVersionMsg(msg serverVersionMsg) (tea.Model, tea.Cmd) {
+	m.serverVersion = msg.version
+	m.versionWarning = versionWarning(msg.version, clientLibraryVersion())
+	if m.versionWarning != "" {
+		logging.InfoLogger.Println(m.versionWarning)
+	}
+	return m, nil
+}
+
+// printVersions implements -v, printing gollama's version and the connected server's
+func printVersions(ctx context.Context, client *api.Client, apiURL string) {
+	fmt.Println(Version)
+	server := fetchServerVersion(ctx, client)
+	fmt.Printf("Ollama server: %s at %s (client library %s)\n", server, apiURL, clientLibraryVersion())
+	if warning := versionWarning(server, clientLibraryVersion()); warning != "" {
+		fmt.Println("Warning: " + warning)
+	}
+}
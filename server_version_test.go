@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestServerVersionWarning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"version":"0.6.2"}`)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	if got := fetchServerVersion(context.Background(), api.NewClient(serverURL, http.DefaultClient)); got != "0.6.2" {
+		t.Errorf("fetchServerVersion() = %q, want 0.6.2", got)
+	}
+
+	// Servers without /api/version are reported as unknown
+	old := httptest.NewServer(http.NotFoundHandler())
+	defer old.Close()
+	oldURL, _ := url.Parse(old.URL)
+	if got := fetchServerVersion(context.Background(), api.NewClient(oldURL, http.DefaultClient)); got != unknownVersion {
+		t.Errorf("fetchServerVersion() without /api/version = %q, want %q", got, unknownVersion)
+	}
+
+	tests := []struct {
+		server, client string
+		warn           bool
+	}{
+		{"0.5.7", "0.5.7", false},
+		{"0.5.12", "0.5.7", false},
+		{"v0.5.7-rc1", "0.5.7", false},
+		{"0.6.2", "0.5.7", true},
+		{"1.0.0", "0.5.7", true},
+		{unknownVersion, "0.5.7", false},
+		{"0.6.2", unknownVersion, false},
+		{"0.6.2", "(devel)", false},
+	}
+	for _, tt := range tests {
+		if got := versionWarning(tt.server, tt.client); (got != "") != tt.warn {
+			t.Errorf("versionWarning(%q, %q) = %q, want a warning %v", tt.server, tt.client, got, tt.warn)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// session.go remembers the selected model and the applied filter when gollama quits, and puts them back on the next
+// start so a long list doesn't open back at the top.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// sessionState is where the list was when gollama quit, Index is the cursor position among the visible models and is
+// used when the selected model no longer exists
+type sessionState struct {
+	Selected string `json:"selected,omitempty"`
+	Index    int    `json:"index"`
+	Filter   string `json:"filter,omitempty"`
+}
+
+func sessionStatePath() string {
+	return filepath.Join(utils.GetConfigDir(), "session.json")
+}
+
+// loadSessionState returns the state saved by the last session, empty if there isn't one
+func loadSessionState() sessionState {
+	var state sessionState
+	data, err := os.ReadFile(sessionStatePath())
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		logging.ErrorLogger.Printf("Ignoring unreadable session state %s: %v\n", sessionStatePath(), err)
+		return sessionState{}
+	}
+	return state
+}
+
+func saveSessionState(state sessionState) error {
+	path := sessionStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// sessionState returns the selected model and the filter, only once it's applied, a half typed one isn't kept
+func (m *AppModel) sessionState() sessionState {
+	state := sessionState{Index: m.list.Index()}
+	if item, ok := m.list.SelectedItem().(Model); ok {
+		state.Selected = item.Name
+	}
+	if m.list.FilterState() == list.FilterApplied {
+		state.Filter = m.list.FilterValue()
+	}
+	return state
+}
+
+// quit saves the session and quits the TUI, keeping the last one when the models never loaded
+func (m *AppModel) quit() tea.Cmd {
+	if m.pendingSession != nil {
+		return tea.Quit
+	}
+	if err := saveSessionState(m.sessionState()); err != nil {
+		logging.ErrorLogger.Printf("Error saving the session state: %v\n", err)
+	}
+	return tea.Quit
+}
+
+// restoreSession reapplies a saved filter and selection to the list once the models first load. The filter is typed and
+// accepted the way the user would and its matches are worked out synchronously, so the list's filtering state stays
+// consistent. A filter that no longer matches anything is dropped by the list.
+func (m *AppModel) restoreSession(state sessionState) {
+	if state.Filter != "" {
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(state.Filter)})
+		m.list, _ = m.list.Update(m.list.SetItems(m.list.Items())())
+		m.list, _ = m.list.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	visible := m.list.VisibleItems()
+	if len(visible) == 0 {
+		return
+	}
+	for i, item := range visible {
+		if model, ok := item.(Model); ok && state.Selected != "" && model.Name == state.Selected {
+			m.list.Select(i)
+			return
+		}
+	}
+	// The model was deleted since, select the one that took its place
+	m.list.Select(min(max(state.Index, 0), len(visible)-1))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestSessionState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newApp := func(names ...string) *AppModel {
+		items := make([]list.Item, len(names))
+		for i, name := range names {
+			model := Model{}
+			model.Name = name
+			items[i] = model
+		}
+		return &AppModel{list: list.New(items, list.NewDefaultDelegate(), 80, 40)}
+	}
+	selectedName := func(m *AppModel) string {
+		return m.list.SelectedItem().(Model).Name
+	}
+
+	// Quit with a filter applied and the second match selected
+	m := newApp("llama3:8b", "qwen2:7b", "qwen2:72b", "qwen2.5:14b", "phi3:mini")
+	m.restoreSession(sessionState{Filter: "qwen2"})
+	m.list.Select(1)
+	if cmd := m.quit(); cmd == nil {
+		t.Fatal("quit returned no command")
+	}
+	state := loadSessionState()
+	if state.Filter != "qwen2" || state.Selected != selectedName(m) || state.Index != 1 {
+		t.Fatalf("saved session = %+v, want the qwen2 filter with %s selected", state, selectedName(m))
+	}
+
+	// The next session reapplies the filter and selects the same model
+	m = newApp("llama3:8b", "qwen2:7b", "qwen2:72b", "qwen2.5:14b", "phi3:mini")
+	m.restoreSession(state)
+	if m.list.FilterState() != list.FilterApplied || m.list.FilterValue() != "qwen2" {
+		t.Errorf("restored filter %q in state %v, want qwen2 applied", m.list.FilterValue(), m.list.FilterState())
+	}
+	if got := selectedName(m); got != state.Selected {
+		t.Errorf("restored selection %s, want %s", got, state.Selected)
+	}
+
+	// A deleted model falls back to the model now at its position
+	m = newApp("llama3:8b", "phi3:mini", "qwen2:7b")
+	m.restoreSession(sessionState{Selected: "mistral:7b", Index: 1})
+	if got := selectedName(m); got != "phi3:mini" {
+		t.Errorf("selection after the model was deleted = %s, want phi3:mini", got)
+	}
+	m.restoreSession(sessionState{Selected: "mistral:7b", Index: 9})
+	if got := selectedName(m); got != "qwen2:7b" {
+		t.Errorf("selection past the end of the list = %s, want qwen2:7b", got)
+	}
+
+	// A filter that no longer matches anything is dropped
+	m = newApp("llama3:8b", "phi3:mini")
+	m.restoreSession(sessionState{Filter: "zzzz", Selected: "phi3:mini"})
+	if m.list.FilterState() != list.Unfiltered || selectedName(m) != "phi3:mini" {
+		t.Errorf("stale filter left state %v with %s selected, want unfiltered with phi3:mini", m.list.FilterState(), selectedName(m))
+	}
+}
@@ -0,0 +1,113 @@
+// set_param.go changes a model's parameters, system prompt or template from the command line without opening an
+// editor, e.g. gollama -set-param qwen2:7b num_ctx=32768 in a provisioning script.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// parseParamAssignments parses key=value arguments into parameter values keyed like modelfileValues, checking known
+// numeric parameters are numbers
+func parseParamAssignments(args []string) (map[string][]string, error) {
+	values := make(map[string][]string, len(args))
+	for _, arg := range args {
+		param, value, ok := strings.Cut(arg, "=")
+		param, value = strings.TrimSpace(param), strings.TrimSpace(value)
+		if !ok || param == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected key=value (e.g. num_ctx=32768)", arg)
+		}
+		switch param {
+		case "system":
+			return nil, fmt.Errorf("use -system to set the system prompt")
+		case "template":
+			return nil, fmt.Errorf("use -template-file to set the template")
+		}
+		if err := validateParameter(param, value); err != nil {
+			return nil, err
+		}
+		values[param] = parameterValues(param, value)
+	}
+	return values, nil
+}
+
+// setModelfileValues applies new values to a model's template, system prompt and parameters with the same create
+// request editing it builds, returning the fields that changed. Nothing is sent when every value is already set.
+func setModelfileValues(ctx context.Context, client *api.Client, modelName string, set map[string][]string) ([]modelfileChange, error) {
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching modelfile for %s: %w", modelName, err)
+	}
+	current := modelfileValues(show.Modelfile)
+
+	var changes []modelfileChange
+	updated := make(map[string][]string, len(current)+len(set))
+	for field, values := range current {
+		updated[field] = values
+	}
+	for field, values := range set {
+		if !slices.Equal(current[field], values) {
+			changes = append(changes, modelfileChange{Field: field, Old: current[field], New: values})
+			updated[field] = values
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	if err := applyEdit(ctx, client, pendingEdit{Model: modelName, Values: updated}); err != nil {
+		return nil, fmt.Errorf("error updating %s: %w", modelName, err)
+	}
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	recordHistory("set-param", modelName, strings.Join(fields, ", "))
+	return changes, nil
+}
+
+// runSetParam implements -set-param, returning the process exit code
+func runSetParam(ctx context.Context, client *api.Client, modelName string, args []string, system, templateFile string) int {
+	set, err := parseParamAssignments(args)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if system != "" {
+		set["system"] = []string{system}
+	}
+	if templateFile != "" {
+		template, err := os.ReadFile(templateFile)
+		if err != nil {
+			fmt.Printf("Error reading template: %v\n", err)
+			return 1
+		}
+		set["template"] = []string{string(template)}
+	}
+	if len(set) == 0 {
+		fmt.Println("Usage: gollama [-system <prompt>] [-template-file <path>] -set-param <model> [key=value...]")
+		return 1
+	}
+
+	changes, err := setModelfileValues(ctx, client, modelName, set)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s already has these values, nothing changed\n", modelName)
+		return 0
+	}
+	for _, change := range changes {
+		fmt.Printf("Changed %s: %s -> %s\n", change.Field, formatModelfileValue(change.Old), formatModelfileValue(change.New))
+	}
+	fmt.Printf("Updated %s\n", modelName)
+	return 0
+}
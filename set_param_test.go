@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestSetModelfileValues(t *testing.T) {
+	modelfile := "FROM /models/blobs/sha256-" + strings.Repeat("ab", 32) + "\nSYSTEM \"\"\"Be helpful\"\"\"\nPARAMETER num_ctx 2048\nPARAMETER stop \"<|end|>\"\n"
+	var creates []api.CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfile})
+		case "/api/create":
+			var req api.CreateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			creates = append(creates, req)
+			json.NewEncoder(w).Encode(api.ProgressResponse{Status: "success"})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+	t.Setenv("HOME", t.TempDir())
+
+	set, err := parseParamAssignments([]string{"num_ctx=32768", "temperature=0.2", "stop=<|end|>, <|eot|>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set["system"] = []string{"Be helpful"}
+	changes, err := setModelfileValues(context.Background(), client, "phi3:mini", set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+	if !slices.Equal(fields, []string{"num_ctx", "stop", "temperature"}) {
+		t.Errorf("changed %v, want num_ctx, stop and temperature only", fields)
+	}
+	if len(creates) != 1 {
+		t.Fatalf("sent %d create requests, want 1", len(creates))
+	}
+	req := creates[0]
+	if req.From != "phi3:mini" || req.System != "Be helpful" || req.Parameters["num_ctx"] != float64(32768) || req.Parameters["temperature"] != 0.2 {
+		t.Errorf("create request = %+v", req)
+	}
+	if stop, _ := req.Parameters["stop"].([]any); len(stop) != 2 {
+		t.Errorf("stop = %v, want two values", req.Parameters["stop"])
+	}
+
+	// Values the model already has aren't sent
+	creates = nil
+	changes, err = setModelfileValues(context.Background(), client, "phi3:mini", map[string][]string{"num_ctx": {"2048"}, "system": {"Be helpful"}})
+	if err != nil || len(changes) != 0 || len(creates) != 0 {
+		t.Errorf("unchanged values gave %v, %v and %d create requests", changes, err, len(creates))
+	}
+
+	for _, args := range [][]string{{"num_ctx"}, {"num_ctx=lots"}, {"system=hi"}, {"=1"}} {
+		if _, err := parseParamAssignments(args); err == nil {
+			t.Errorf("parseParamAssignments(%q) didn't fail", args)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// showcache.go caches the show API's responses so inspecting a model, its context length, files and parameters share
+// one request rather than each asking the server within seconds of each other. Responses are checked against the
+// model's digest from the last model list, so a model changed outside gollama is fetched again once the list is
+// reloaded, and gollama invalidates the models it copies, renames, edits, pulls and deletes itself.
+package showcache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// TTL is how long a response is used for before the model is asked about again
+var TTL = 30 * time.Second
+
+// Cache holds the show responses from one Ollama server
+type Cache struct {
+	client     *api.Client
+	mu         sync.Mutex
+	entries    map[string]entry  // by model name
+	digests    map[string]string // digest of each model in the last model list
+	inflight   map[string]*call  // requests being made, shared by everyone asking for the model meanwhile
+	generation map[string]int    // bumped when a model is invalidated so requests made before aren't cached
+}
+
+type entry struct {
+	show    *api.ShowResponse
+	digest  string
+	fetched time.Time
+}
+
+type call struct {
+	done chan struct{}
+	show *api.ShowResponse
+	err  error
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   = make(map[*api.Client]*Cache)
+)
+
+// For returns the cache of the server the client talks to
+func For(client *api.Client) *Cache {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	cache, ok := caches[client]
+	if !ok {
+		cache = &Cache{
+			client:     client,
+			entries:    make(map[string]entry),
+			digests:    make(map[string]string),
+			inflight:   make(map[string]*call),
+			generation: make(map[string]int),
+		}
+		caches[client] = cache
+	}
+	return cache
+}
+
+// key is the name a model is cached under, with the latest tag Ollama assumes when there isn't one
+func key(name string) string {
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		name += ":latest"
+	}
+	return name
+}
+
+// Show returns the model's show response, from the cache when it's younger than TTL and the model's digest hasn't
+// changed. The response is shared, so it mustn't be modified.
+func (c *Cache) Show(ctx context.Context, name string) (*api.ShowResponse, error) {
+	k := key(name)
+	c.mu.Lock()
+	if e, ok := c.entries[k]; ok && time.Since(e.fetched) < TTL && e.digest == c.digests[k] {
+		c.mu.Unlock()
+		return e.show, nil
+	}
+	if inflight, ok := c.inflight[k]; ok {
+		c.mu.Unlock()
+		select {
+		case <-inflight.done:
+			return inflight.show, inflight.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	current := &call{done: make(chan struct{})}
+	c.inflight[k] = current
+	generation, digest := c.generation[k], c.digests[k]
+	c.mu.Unlock()
+
+	current.show, current.err = c.client.Show(ctx, &api.ShowRequest{Name: name})
+
+	c.mu.Lock()
+	if c.inflight[k] == current {
+		delete(c.inflight, k)
+	}
+	if current.err == nil && c.generation[k] == generation {
+		c.entries[k] = entry{show: current.show, digest: digest, fetched: time.Now()}
+	}
+	c.mu.Unlock()
+	close(current.done)
+	return current.show, current.err
+}
+
+// SetDigests records the digest of each model from a model list, dropping the responses of models whose digest has
+// changed or that are gone
+func (c *Cache) SetDigests(digests map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.digests = make(map[string]string, len(digests))
+	for name, digest := range digests {
+		c.digests[key(name)] = digest
+	}
+	for k, e := range c.entries {
+		if e.digest != c.digests[k] {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Invalidate drops the responses of models that have been changed, so they're fetched again the next time they're
+// asked for. Requests already being made for them aren't cached or shared with later callers.
+func (c *Cache) Invalidate(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		k := key(name)
+		delete(c.entries, k)
+		delete(c.inflight, k)
+		c.generation[k]++
+	}
+}
+
+// Invalidate drops the responses of models that have been changed on the server the client talks to
+func Invalidate(client *api.Client, names ...string) {
+	For(client).Invalidate(names...)
+}
@@ -0,0 +1,149 @@
+package showcache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// showServer answers show requests with the model's name and a count of the requests made, blocking each request
+// until release is closed when it isn't nil
+func showServer(t *testing.T, release chan struct{}) (*api.Client, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ShowRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		n := requests.Add(1)
+		if release != nil {
+			<-release
+		}
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM " + req.Name, Template: string(rune('0' + n))})
+	}))
+	t.Cleanup(server.Close)
+	serverURL, _ := url.Parse(server.URL)
+	return api.NewClient(serverURL, http.DefaultClient), &requests
+}
+
+func TestShowShared(t *testing.T) {
+	release := make(chan struct{})
+	client, requests := showServer(t, release)
+	cache := For(client)
+
+	// Everyone asking while the first request is being made shares it
+	var wg sync.WaitGroup
+	shows := make([]*api.ShowResponse, 20)
+	for i := range shows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			show, err := cache.Show(context.Background(), "llama3")
+			if err != nil {
+				t.Errorf("Show() error = %v", err)
+			}
+			shows[i] = show
+		}()
+	}
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests for 20 concurrent calls, want 1", n)
+	}
+	for _, show := range shows {
+		if show != shows[0] {
+			t.Fatal("concurrent calls got different responses")
+		}
+	}
+
+	// The tag Ollama assumes shares the cache entry
+	if show, _ := cache.Show(context.Background(), "llama3:latest"); show != shows[0] || requests.Load() != 1 {
+		t.Errorf("Show(llama3:latest) made another request, %d requests", requests.Load())
+	}
+}
+
+func TestShowInvalidation(t *testing.T) {
+	client, requests := showServer(t, nil)
+	cache := For(client)
+	ctx := context.Background()
+
+	first, _ := cache.Show(ctx, "llama3:8b")
+	cache.Invalidate("llama3:8b")
+	second, _ := cache.Show(ctx, "llama3:8b")
+	if second == first || requests.Load() != 2 {
+		t.Errorf("Show() after Invalidate() reused the response, %d requests", requests.Load())
+	}
+
+	// A changed digest in the model list drops the response, the same one keeps it
+	cache.SetDigests(map[string]string{"llama3:8b": "sha256:a"})
+	third, _ := cache.Show(ctx, "llama3:8b")
+	cache.SetDigests(map[string]string{"llama3:8b": "sha256:a"})
+	if show, _ := cache.Show(ctx, "llama3:8b"); show != third {
+		t.Error("Show() with the same digest made another request")
+	}
+	cache.SetDigests(map[string]string{"llama3:8b": "sha256:b"})
+	if show, _ := cache.Show(ctx, "llama3:8b"); show == third {
+		t.Error("Show() after the digest changed reused the response")
+	}
+
+	defer func(ttl time.Duration) { TTL = ttl }(TTL)
+	TTL = 0
+	before := requests.Load()
+	cache.Show(ctx, "llama3:8b")
+	if requests.Load() != before+1 {
+		t.Error("Show() reused an expired response")
+	}
+}
+
+func TestInvalidateDuringShow(t *testing.T) {
+	release := make(chan struct{})
+	client, requests := showServer(t, release)
+	cache := For(client)
+	ctx := context.Background()
+
+	done := make(chan *api.ShowResponse)
+	go func() {
+		show, _ := cache.Show(ctx, "qwen2")
+		done <- show
+	}()
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The model is edited while its details are being fetched, so the response from before mustn't be cached
+	cache.Invalidate("qwen2")
+	close(release)
+	stale := <-done
+	if show, _ := cache.Show(ctx, "qwen2"); show == stale || requests.Load() != 2 {
+		t.Errorf("Show() after an invalidation during a request reused its response, %d requests", requests.Load())
+	}
+
+	// Invalidating and reading from many goroutines at once, like the top ticker and the user's actions, is safe
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Show(ctx, "qwen2"); err != nil {
+				t.Errorf("Show() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				cache.Invalidate("qwen2")
+			} else {
+				cache.SetDigests(map[string]string{"qwen2": "sha256:c"})
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,89 @@
+// signals.go handles SIGINT, SIGTERM and SIGHUP for the long-running command line modes (the TUI handles its own keys).
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// forceExit is called when a second interrupt arrives while the first is still being handled, tests replace it
+var forceExit = os.Exit
+
+// signalContext returns a context that's cancelled by SIGINT or SIGTERM so what's running can stop cleanly,
+// what describes it for the log. SIGHUP calls reload if it's set. A second SIGINT or SIGTERM exits immediately.
+// stop must be called to release the signal handlers.
+func signalContext(parent context.Context, what string, reload func()) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		interrupted := false
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGHUP {
+					logging.InfoLogger.Printf("Received %s, reloading config\n", sig)
+					if reload != nil {
+						reload()
+					}
+					continue
+				}
+				if interrupted {
+					logging.ErrorLogger.Printf("Received %s again, exiting immediately\n", sig)
+					forceExit(130)
+					return
+				}
+				interrupted = true
+				logging.InfoLogger.Printf("Received %s, cancelling %s\n", sig, what)
+				cancel()
+			}
+		}
+	}()
+
+	var stopped bool
+	return ctx, func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// reloadConfig returns a SIGHUP handler that re-reads the config file and applies the new log level straight away.
+// A running command keeps the connection and log file it already has so changes to those are only logged. Pins and history are
+// read from disk as they're used so they don't need reloading.
+func reloadConfig(cfg config.Config) func() {
+	return func() {
+		newCfg, err := config.LoadConfig()
+		if err != nil {
+			logging.ErrorLogger.Printf("Error reloading config, keeping the current config: %v\n", err)
+			return
+		}
+		if newCfg.LogLevel != cfg.LogLevel {
+			if err := logging.SetLevel(newCfg.LogLevel); err != nil {
+				logging.ErrorLogger.Printf("Error applying reloaded log level: %v\n", err)
+				return
+			}
+		}
+		if newCfg.OllamaAPIURL != cfg.OllamaAPIURL {
+			logging.InfoLogger.Printf("Ollama API URL changed to %s, it will be used from the next run\n", newCfg.OllamaAPIURL)
+		}
+		if newCfg.LogFilePath != cfg.LogFilePath {
+			logging.InfoLogger.Printf("Log file changed to %s, it will be used from the next run\n", newCfg.LogFilePath)
+		}
+		cfg = newCfg
+		logging.InfoLogger.Printf("Reloaded config, log level %s\n", cfg.LogLevel)
+	}
+}
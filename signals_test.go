@@ -0,0 +1,57 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalContext(t *testing.T) {
+	reloaded := make(chan struct{}, 1)
+	ctx, stop := signalContext(context.Background(), "test", func() { reloaded <- struct{}{} })
+	defer stop()
+
+	exited := make(chan int, 1)
+	forceExit = func(code int) { exited <- code }
+	defer func() { forceExit = os.Exit }()
+
+	// SIGHUP reloads without cancelling
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP didn't call reload")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("SIGHUP cancelled the context")
+	}
+
+	// The first SIGTERM cancels
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM didn't cancel the context")
+	}
+
+	// A second forces an exit
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case code := <-exited:
+		if code != 130 {
+			t.Errorf("forced exit code = %d, want 130", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second SIGTERM didn't force an exit")
+	}
+}
@@ -0,0 +1,117 @@
+// sort_models.go sorts the model list. Pressing a sort key again reverses the direction, and the field and direction
+// are saved to the config so the next launch uses them.
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// sortFields are the fields the list can be sorted by, with whether each is sorted descending until it's toggled
+var sortFields = map[string]bool{
+	"name":     false,
+	"size":     true,
+	"modified": true,
+	"quant":    false,
+	"family":   false,
+}
+
+// sortDescending reports the direction the config sorts in, sort_direction when it's set or the field's default
+func sortDescending(cfg *config.Config) bool {
+	switch cfg.SortDirection {
+	case "asc":
+		return false
+	case "desc":
+		return true
+	}
+	return sortFields[cfg.SortOrder]
+}
+
+// sortModels sorts models by a field, keeping the existing order of models that compare equal. An unknown field
+// leaves them as they are.
+func sortModels(models []Model, field string, descending bool) {
+	var less func(a, b Model) bool
+	switch field {
+	case "name":
+		less = func(a, b Model) bool { return a.Name < b.Name }
+	case "size":
+		less = func(a, b Model) bool { return a.Size < b.Size }
+	case "modified":
+		less = func(a, b Model) bool { return a.Modified.Before(b.Modified) }
+	case "quant":
+		less = func(a, b Model) bool { return a.QuantizationLevel < b.QuantizationLevel }
+	case "family":
+		less = func(a, b Model) bool { return a.Family < b.Family }
+	default:
+		return
+	}
+	sort.SliceStable(models, func(i, j int) bool {
+		if descending {
+			return less(models[j], models[i])
+		}
+		return less(models[i], models[j])
+	})
+}
+
+// sortTitle is the list title showing the sort, e.g. "Ollama Models - sorted by size ↓"
+func sortTitle(field string, descending bool) string {
+	if _, ok := sortFields[field]; !ok {
+		return "Ollama Models"
+	}
+	arrow := "↑"
+	if descending {
+		arrow = "↓"
+	}
+	return fmt.Sprintf("Ollama Models - sorted by %s %s", field, arrow)
+}
+
+// handleSortKey sorts by a field, reversing the direction if the list is already sorted by it, and saves the sort
+func (m *AppModel) handleSortKey(field string) (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Printf("Sort by %s key matched\n", field)
+	descending := sortFields[field]
+	if m.cfg.SortOrder == field {
+		descending = !sortDescending(m.cfg)
+	}
+	m.setSort(field, descending)
+	if err := config.SetValues(map[string]any{"sort_order": m.cfg.SortOrder, "sort_direction": m.cfg.SortDirection}); err != nil {
+		logging.ErrorLogger.Printf("Error saving the sort order to the config: %v\n", err)
+	}
+	return m, nil
+}
+
+// setSort sorts the models and updates the list and its title
+func (m *AppModel) setSort(field string, descending bool) {
+	m.cfg.SortOrder = field
+	m.cfg.SortDirection = "asc"
+	if descending {
+		m.cfg.SortDirection = "desc"
+	}
+	sortModels(m.models, field, descending)
+	m.refreshList()
+	m.list.Title = sortTitle(field, descending)
+}
+
+func (m *AppModel) handleSortByNameKey() (tea.Model, tea.Cmd) {
+	return m.handleSortKey("name")
+}
+
+func (m *AppModel) handleSortBySizeKey() (tea.Model, tea.Cmd) {
+	return m.handleSortKey("size")
+}
+
+func (m *AppModel) handleSortByModifiedKey() (tea.Model, tea.Cmd) {
+	return m.handleSortKey("modified")
+}
+
+func (m *AppModel) handleSortByQuantKey() (tea.Model, tea.Cmd) {
+	return m.handleSortKey("quant")
+}
+
+func (m *AppModel) handleSortByFamilyKey() (tea.Model, tea.Cmd) {
+	return m.handleSortKey("family")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/utils"
+)
+
+func TestSortToggle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newModel := func(name string, size int64) Model {
+		model := Model{}
+		model.Name, model.Size = name, size
+		return model
+	}
+	models := []Model{newModel("b", 2), newModel("a", 3), newModel("c", 1)}
+	m := &AppModel{cfg: &config.Config{SortOrder: "modified"}, models: models, list: list.New(nil, list.NewDefaultDelegate(), 80, 40)}
+	names := func() string {
+		var names []string
+		for _, item := range m.list.Items() {
+			names = append(names, item.(Model).Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		field     string
+		wantNames string
+		wantTitle string
+	}{
+		{"size", "a,b,c", "Ollama Models - sorted by size ↓"},
+		{"size", "c,b,a", "Ollama Models - sorted by size ↑"},
+		{"name", "a,b,c", "Ollama Models - sorted by name ↑"},
+		{"name", "c,b,a", "Ollama Models - sorted by name ↓"},
+	}
+	for _, tt := range tests {
+		m.handleSortKey(tt.field)
+		if got := names(); got != tt.wantNames {
+			t.Errorf("after sorting by %s the list is %s, want %s", tt.field, got, tt.wantNames)
+		}
+		if m.list.Title != tt.wantTitle {
+			t.Errorf("after sorting by %s the title is %q, want %q", tt.field, m.list.Title, tt.wantTitle)
+		}
+	}
+
+	data, err := os.ReadFile(utils.GetConfigPath())
+	if err != nil {
+		t.Fatalf("reading the saved config: %v", err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("decoding the saved config: %v", err)
+	}
+	if saved["sort_order"] != "name" || saved["sort_direction"] != "desc" {
+		t.Errorf("saved sort is %v %v, want name desc", saved["sort_order"], saved["sort_direction"])
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"math"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/styles"
 )
 
 const (
@@ -15,36 +17,9 @@ const (
 	minModifiedWidth = 10
 	minIDWidth       = 10
 	minFamilyWidth   = 14
-)
+	minParamsWidth   = 8
 
-var (
-	// Define neon colours for different model families
-	familyColours = map[string]lipgloss.Color{
-		"llama":       lipgloss.Color("#FF1493"),
-		"alpaca":      lipgloss.Color("#FF00FF"),
-		"command-r":   lipgloss.Color("#FB79B4"),
-		"starcoder2":  lipgloss.Color("#EE82EE"),
-		"starcoder":   lipgloss.Color("#DD40DD"),
-		"gemma":       lipgloss.Color("#A224AA"),
-		"qwen2":       lipgloss.Color("#AAE"),
-		"phi":         lipgloss.Color("#554FFF"),
-		"granite":     lipgloss.Color("#BFBBBB"),
-		"deepseek":    lipgloss.Color("#06AFFF"),
-		"deepseek2":   lipgloss.Color("#60BFFF"),
-		"vicuna":      lipgloss.Color("#00CED1"),
-		"bert":        lipgloss.Color("#FF7A00"),
-		"nomic-bert":  lipgloss.Color("#FF8C00"),
-		"nomic":       lipgloss.Color("#FFD700"),
-		"qwen":        lipgloss.Color("#7FFF00"),
-		"placeholder": lipgloss.Color("#554AAF"),
-	}
-
-	// Define colour gradients
-	synthGradient = []string{
-		"#DDA0DD", "#DA70D6", "#BA55D3", "#9932CC", "#9400D3", "#8A2BE2",
-		"#9400D3", "#9932CC", "#BA48D3", "#DA70D6", "#DDA0DD", "#EE82EE",
-		"#FF00FF", "#FF0000",
-	}
+	contextLengthWidth = 8 // the optional context length column, e.g. "128K"
 )
 
 func quantColour(quant string) lipgloss.Color {
@@ -64,6 +39,7 @@ func quantColour(quant string) lipgloss.Color {
 		"FP16": 13, "F16": 13, "F32": 15, "FP32": 15,
 	}
 
+	synthGradient := styles.Current().Colours.Gradient
 	index, exists := quantMap[quant]
 	if !exists {
 		index = 0 // Default to lightest if unknown quant
@@ -75,6 +51,7 @@ func quantColour(quant string) lipgloss.Color {
 }
 
 func sizeColour(size float64) lipgloss.Color {
+	synthGradient := styles.Current().Colours.Gradient
 	index := int(math.Log10(size+1) * 2.5)
 	if index >= len(synthGradient) {
 		index = len(synthGradient) - 1
@@ -83,6 +60,9 @@ func sizeColour(size float64) lipgloss.Color {
 }
 
 func familyColour(family string, index int) lipgloss.Color {
+	theme := styles.Current()
+	synthGradient := theme.Colours.Gradient
+	familyColours := theme.Family
 	colour, exists := familyColours[family]
 	if !exists {
 		// Pick the colour closest matching part of the family name
@@ -96,8 +76,8 @@ func familyColour(family string, index int) lipgloss.Color {
 		}
 		// If no colour found, default to synthGradient
 		if !exists {
-			colour = lipgloss.Color(synthGradient[index%len(synthGradient)])
+			colour = synthGradient[index%len(synthGradient)]
 		}
 	}
-	return colour
+	return lipgloss.Color(colour)
 }
@@ -0,0 +1,98 @@
+// colour.go reads and writes the colours in theme files, which are either one colour or a light and dark pair picked
+// by the terminal's background, and checks they're colours lipgloss can render.
+package styles
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// hexColourPattern matches #RGB and #RRGGBB colours
+var hexColourPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Colour is a theme colour, either a single colour or a pair used on light and dark terminal backgrounds. In theme
+// files it's a string such as "#FF00FF" or "205", or {"light": "#5A00A0", "dark": "#FF00FF"}.
+type Colour struct {
+	Light string
+	Dark  string
+}
+
+// SingleColour returns a Colour that's the same on light and dark backgrounds
+func SingleColour(value string) Colour {
+	return Colour{Light: value, Dark: value}
+}
+
+// IsZero reports whether the colour is unset
+func (c Colour) IsZero() bool {
+	return c.Light == "" && c.Dark == ""
+}
+
+// TerminalColour returns the colour for lipgloss, adapting to the terminal's background when the light and dark
+// colours differ
+func (c Colour) TerminalColour() lipgloss.TerminalColor {
+	if c.Light != c.Dark && c.Light != "" && c.Dark != "" {
+		return lipgloss.AdaptiveColor{Light: c.Light, Dark: c.Dark}
+	}
+	if c.Dark != "" {
+		return lipgloss.Color(c.Dark)
+	}
+	return lipgloss.Color(c.Light)
+}
+
+// String returns the colour as written in a theme file
+func (c Colour) String() string {
+	if c.Light == c.Dark {
+		return c.Dark
+	}
+	return fmt.Sprintf("light %s, dark %s", c.Light, c.Dark)
+}
+
+func (c *Colour) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*c = SingleColour(value)
+		return nil
+	}
+	var pair struct {
+		Light string `json:"light"`
+		Dark  string `json:"dark"`
+	}
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("a colour must be a string or {\"light\": ..., \"dark\": ...}: %w", err)
+	}
+	// Only one of the pair set applies to both backgrounds
+	if pair.Light == "" {
+		pair.Light = pair.Dark
+	}
+	if pair.Dark == "" {
+		pair.Dark = pair.Light
+	}
+	*c = Colour{Light: pair.Light, Dark: pair.Dark}
+	return nil
+}
+
+// MarshalJSON writes a single colour as a string so saved themes stay readable by older versions
+func (c Colour) MarshalJSON() ([]byte, error) {
+	if c.Light == c.Dark {
+		return json.Marshal(c.Dark)
+	}
+	return json.Marshal(map[string]string{"light": c.Light, "dark": c.Dark})
+}
+
+// validColour reports whether a colour is a hex colour (#RGB or #RRGGBB) or an ANSI 256-colour number
+func validColour(value string) bool {
+	if hexColourPattern.MatchString(value) {
+		return true
+	}
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Valid reports whether every colour set is valid
+func (c Colour) Valid() bool {
+	return (c.Light == "" || validColour(c.Light)) && (c.Dark == "" || validColour(c.Dark))
+}
@@ -0,0 +1,350 @@
+// Package styles contains the colour themes used by the TUI.
+package styles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/utils"
+)
+
+// DefaultThemeName is the theme used when none is configured or the configured one can't be loaded
+const DefaultThemeName = "default"
+
+// Theme is a named set of colours for the TUI
+type Theme struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Colours     Colours `json:"colours"`
+	// Family colours model families in the list, keyed by family name
+	Family map[string]string `json:"family,omitempty"`
+}
+
+// Colours are the colours a theme sets, any left empty fall back to the default theme. The gradient and family
+// colours are single colours.
+type Colours struct {
+	Name               Colour   `json:"name"`
+	NameAlt            Colour   `json:"name_alt"`
+	ID                 Colour   `json:"id"`
+	Modified           Colour   `json:"modified"`
+	SelectedBorder     Colour   `json:"selected_border"`
+	SelectedBackground Colour   `json:"selected_background"`
+	Title              Colour   `json:"title"`
+	Message            Colour   `json:"message"`
+	Warning            Colour   `json:"warning"`
+	Error              Colour   `json:"error"`
+	Faint              Colour   `json:"faint"`
+	Highlight          Colour   `json:"highlight"`
+	HighlightBG        Colour   `json:"highlight_background"`
+	CompareAdded       Colour   `json:"compare_added"`
+	CompareRemoved     Colour   `json:"compare_removed"`
+	CompareModified    Colour   `json:"compare_modified"`
+	Gradient           []string `json:"gradient"`
+}
+
+// ThemeFile is a theme found in the themes directory, Err is set if it couldn't be loaded
+type ThemeFile struct {
+	Theme
+	Path    string
+	BuiltIn bool
+	Err     error
+}
+
+var builtInThemes = []Theme{
+	{
+		Name:        DefaultThemeName,
+		Description: "Neon synthwave, the original gollama look",
+		Colours: Colours{
+			Name:               SingleColour("#FFFFFF"),
+			NameAlt:            SingleColour("#818FA1"),
+			ID:                 SingleColour("254"),
+			Modified:           SingleColour("254"),
+			SelectedBorder:     SingleColour("125"),
+			SelectedBackground: SingleColour("92"),
+			Title:              SingleColour("#FF00FF"),
+			Message:            SingleColour("205"),
+			Warning:            SingleColour("#FFA500"),
+			Error:              SingleColour("#8B0000"),
+			Faint:              SingleColour("#666666"),
+			Highlight:          SingleColour("229"),
+			HighlightBG:        SingleColour("57"),
+			CompareAdded:       SingleColour("#00FF00"),
+			CompareRemoved:     SingleColour("#FF0000"),
+			CompareModified:    SingleColour("#FFFF00"),
+			Gradient: []string{
+				"#DDA0DD", "#DA70D6", "#BA55D3", "#9932CC", "#9400D3", "#8A2BE2",
+				"#9400D3", "#9932CC", "#BA48D3", "#DA70D6", "#DDA0DD", "#EE82EE",
+				"#FF00FF", "#FF0000",
+			},
+		},
+		Family: map[string]string{
+			"llama":       "#FF1493",
+			"alpaca":      "#FF00FF",
+			"command-r":   "#FB79B4",
+			"starcoder2":  "#EE82EE",
+			"starcoder":   "#DD40DD",
+			"gemma":       "#A224AA",
+			"qwen2":       "#AAE",
+			"phi":         "#554FFF",
+			"granite":     "#BFBBBB",
+			"deepseek":    "#06AFFF",
+			"deepseek2":   "#60BFFF",
+			"vicuna":      "#00CED1",
+			"bert":        "#FF7A00",
+			"nomic-bert":  "#FF8C00",
+			"nomic":       "#FFD700",
+			"qwen":        "#7FFF00",
+			"placeholder": "#554AAF",
+		},
+	},
+	{
+		Name:        "ocean",
+		Description: "Cool blues and greens",
+		Colours: Colours{
+			Name:               SingleColour("#E0F7FA"),
+			NameAlt:            SingleColour("#80A4B8"),
+			SelectedBorder:     SingleColour("#00ACC1"),
+			SelectedBackground: SingleColour("#01579B"),
+			Title:              SingleColour("#26C6DA"),
+			Message:            SingleColour("#4DD0E1"),
+			Highlight:          SingleColour("#E0F7FA"),
+			HighlightBG:        SingleColour("#006064"),
+			CompareAdded:       SingleColour("#9CCC65"),
+			CompareRemoved:     SingleColour("#FF7043"),
+			CompareModified:    SingleColour("#FFEE58"),
+			Gradient: []string{
+				"#B2EBF2", "#80DEEA", "#4DD0E1", "#26C6DA", "#00BCD4", "#00ACC1",
+				"#0097A7", "#00838F", "#26A69A", "#66BB6A", "#9CCC65", "#D4E157",
+				"#FFEE58", "#FF7043",
+			},
+		},
+	},
+	{
+		Name:        "mono",
+		Description: "Greyscale for terminals with limited colour",
+		Colours: Colours{
+			Name:               SingleColour("255"),
+			NameAlt:            SingleColour("248"),
+			ID:                 SingleColour("244"),
+			Modified:           SingleColour("250"),
+			SelectedBorder:     SingleColour("255"),
+			SelectedBackground: SingleColour("238"),
+			Title:              SingleColour("255"),
+			Message:            SingleColour("252"),
+			Warning:            SingleColour("255"),
+			Error:              SingleColour("255"),
+			Faint:              SingleColour("242"),
+			Highlight:          SingleColour("232"),
+			HighlightBG:        SingleColour("252"),
+			CompareAdded:       SingleColour("255"),
+			CompareRemoved:     SingleColour("244"),
+			CompareModified:    SingleColour("250"),
+			Gradient:           []string{"244", "245", "246", "247", "248", "249", "250", "251", "252", "253", "254", "255"},
+		},
+		Family: map[string]string{},
+	},
+}
+
+var (
+	mu      sync.RWMutex
+	current = resolve(builtInThemes[0])
+
+	plain           bool
+	detectedProfile termenv.Profile // the colour profile lipgloss detected, restored when plain output is turned off
+)
+
+// ThemesDir returns the directory user themes are loaded from
+func ThemesDir() string {
+	return filepath.Join(utils.GetConfigDir(), "themes")
+}
+
+// BuiltIn returns the built-in themes
+func BuiltIn() []Theme {
+	themes := make([]Theme, len(builtInThemes))
+	copy(themes, builtInThemes)
+	return themes
+}
+
+// LoadThemeFile reads a theme from a JSON file, the file name is used if the theme doesn't set a name
+func LoadThemeFile(path string) (Theme, error) {
+	var theme Theme
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return theme, fmt.Errorf("failed to read theme file: %w", err)
+	}
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return theme, fmt.Errorf("failed to parse theme file: %w", err)
+	}
+	if theme.Name == "" {
+		theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	dropInvalidColours(&theme, path)
+	return theme, nil
+}
+
+// ListThemes returns the built-in themes followed by the user themes in dir, sorted by name.
+// Themes that fail to load are still returned, with Err set, so they can be shown to the user
+func ListThemes(dir string) []ThemeFile {
+	var themes []ThemeFile
+	for _, theme := range builtInThemes {
+		themes = append(themes, ThemeFile{Theme: theme, BuiltIn: true})
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+	for _, path := range paths {
+		theme, err := LoadThemeFile(path)
+		if theme.Name == "" {
+			theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		themes = append(themes, ThemeFile{Theme: theme, Path: path, Err: err})
+	}
+	return themes
+}
+
+// FindTheme returns the named theme from the built-in themes or the themes directory
+func FindTheme(name string) (Theme, error) {
+	for _, file := range ListThemes(ThemesDir()) {
+		if file.Name != name {
+			continue
+		}
+		if file.Err != nil {
+			return Theme{}, fmt.Errorf("theme %s: %w", name, file.Err)
+		}
+		return file.Theme, nil
+	}
+	return Theme{}, fmt.Errorf("theme %s not found", name)
+}
+
+// InitTheme makes the named theme the current theme, falling back to the default theme if it can't be loaded.
+// With plain output on the theme is still loaded but renders without colours.
+func InitTheme(name string) error {
+	if name == "" {
+		name = DefaultThemeName
+	}
+	theme, err := FindTheme(name)
+	if err != nil {
+		SetTheme(builtInThemes[0])
+		return err
+	}
+	SetTheme(theme)
+	return nil
+}
+
+// SetTheme makes theme the current theme
+func SetTheme(theme Theme) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = resolve(theme)
+}
+
+// NoColourRequested reports whether the NO_COLOR environment variable asks for output without colours (https://no-color.org)
+func NoColourRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// SetPlain turns plain output on or off. When it's on every lipgloss style renders unstyled text, whatever the theme.
+func SetPlain(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if on == plain {
+		return
+	}
+	plain = on
+	if on {
+		detectedProfile = lipgloss.ColorProfile()
+		lipgloss.SetColorProfile(termenv.Ascii)
+		return
+	}
+	lipgloss.SetColorProfile(detectedProfile)
+}
+
+// Colourless reports whether output is rendered without colours, either because plain output is on or because
+// lipgloss detected a terminal that can't show them (e.g. TERM=dumb)
+func Colourless() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return plain || lipgloss.ColorProfile() == termenv.Ascii
+}
+
+// Current returns the current theme
+func Current() Theme {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// fields returns the colours keyed by their names in theme files, except the gradient
+func (c *Colours) fields() map[string]*Colour {
+	return map[string]*Colour{
+		"name": &c.Name, "name_alt": &c.NameAlt, "id": &c.ID, "modified": &c.Modified,
+		"selected_border": &c.SelectedBorder, "selected_background": &c.SelectedBackground, "title": &c.Title,
+		"message": &c.Message, "warning": &c.Warning, "error": &c.Error, "faint": &c.Faint,
+		"highlight": &c.Highlight, "highlight_background": &c.HighlightBG, "compare_added": &c.CompareAdded,
+		"compare_removed": &c.CompareRemoved, "compare_modified": &c.CompareModified,
+	}
+}
+
+// GetColour returns the colour a theme file names name (e.g. "title" or "compare_added"), an AdaptiveColor when the
+// theme sets a light and dark pair. Colours the theme doesn't set, and names it doesn't have, use the default theme's.
+func (t Theme) GetColour(name string) lipgloss.TerminalColor {
+	resolved := resolve(t)
+	if value, ok := resolved.Colours.fields()[name]; ok {
+		return value.TerminalColour()
+	}
+	return lipgloss.NoColor{}
+}
+
+// resolve fills in any colours the theme doesn't set from the default theme
+func resolve(theme Theme) Theme {
+	base := builtInThemes[0]
+	baseColours := base.Colours.fields()
+	c := &theme.Colours
+	for name, value := range c.fields() {
+		if value.IsZero() {
+			*value = *baseColours[name]
+		}
+	}
+	if len(c.Gradient) == 0 {
+		c.Gradient = base.Colours.Gradient
+	}
+	if theme.Family == nil {
+		theme.Family = base.Family
+	}
+	return theme
+}
+
+// dropInvalidColours clears the colours that aren't a hex or 256-colour value, logging a warning for each, so they
+// fall back to the built-in colour rather than rendering unpredictably
+func dropInvalidColours(theme *Theme, path string) {
+	for name, value := range theme.Colours.fields() {
+		if !value.Valid() {
+			logging.ErrorLogger.Printf("Warning: theme %s has an invalid %s colour %q, using the built-in colour\n", path, name, value.String())
+			*value = Colour{}
+		}
+	}
+	gradient := theme.Colours.Gradient[:0:0]
+	for _, value := range theme.Colours.Gradient {
+		if !validColour(value) {
+			logging.ErrorLogger.Printf("Warning: theme %s has an invalid gradient colour %q, skipping it\n", path, value)
+			continue
+		}
+		gradient = append(gradient, value)
+	}
+	theme.Colours.Gradient = gradient
+	for family, value := range theme.Family {
+		if !validColour(value) {
+			logging.ErrorLogger.Printf("Warning: theme %s has an invalid colour %q for %s, using the gradient\n", path, value, family)
+			delete(theme.Family, family)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package styles
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestListThemes(t *testing.T) {
+	dir := t.TempDir()
+	valid := `{"name": "sunset", "description": "Warm", "colours": {"title": "#FF8800"}}`
+	if err := os.WriteFile(filepath.Join(dir, "sunset.json"), []byte(valid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	themes := ListThemes(dir)
+	byName := make(map[string]ThemeFile)
+	for _, theme := range themes {
+		byName[theme.Name] = theme
+	}
+
+	tests := []struct {
+		name        string
+		wantBuiltIn bool
+		wantErr     bool
+	}{
+		{name: DefaultThemeName, wantBuiltIn: true},
+		{name: "sunset"},
+		{name: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			theme, ok := byName[tt.name]
+			if !ok {
+				t.Fatalf("theme %s not listed", tt.name)
+			}
+			if theme.BuiltIn != tt.wantBuiltIn {
+				t.Errorf("BuiltIn = %v, want %v", theme.BuiltIn, tt.wantBuiltIn)
+			}
+			if (theme.Err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, wantErr %v", theme.Err, tt.wantErr)
+			}
+		})
+	}
+
+	// Listing must never rename or remove invalid theme files
+	if _, err := os.Stat(filepath.Join(dir, "broken.json")); err != nil {
+		t.Errorf("broken theme file was moved: %v", err)
+	}
+}
+
+func TestSetThemeFillsMissingColours(t *testing.T) {
+	defer SetTheme(builtInThemes[0])
+
+	SetTheme(Theme{Name: "partial", Colours: Colours{Title: SingleColour("#123456")}})
+	current := Current()
+	if current.Colours.Title != SingleColour("#123456") {
+		t.Errorf("Title = %s, want #123456", current.Colours.Title)
+	}
+	if current.Colours.Message != builtInThemes[0].Colours.Message {
+		t.Errorf("Message = %s, want default %s", current.Colours.Message, builtInThemes[0].Colours.Message)
+	}
+	if len(current.Colours.Gradient) == 0 || current.Family == nil {
+		t.Errorf("gradient and family colours should fall back to the default theme")
+	}
+}
+
+func TestInitTheme(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer SetTheme(builtInThemes[0])
+
+	if err := InitTheme("mono"); err != nil {
+		t.Fatalf("InitTheme(mono) error = %v", err)
+	}
+	if Current().Name != "mono" {
+		t.Errorf("Current() = %s, want mono", Current().Name)
+	}
+
+	if err := InitTheme("does-not-exist"); err == nil {
+		t.Errorf("InitTheme() of a missing theme error = nil, want error")
+	}
+	if Current().Name != DefaultThemeName {
+		t.Errorf("Current() after failed InitTheme = %s, want %s", Current().Name, DefaultThemeName)
+	}
+}
+
+func TestThemeColourFormats(t *testing.T) {
+	dir := t.TempDir()
+	themes := map[string]string{
+		// The original format with a single string per colour
+		"old.json": `{"name": "old", "colours": {"title": "#FF8800", "message": "205"}}`,
+		"adaptive.json": `{"name": "adaptive", "colours": {"title": {"light": "#5A00A0", "dark": "#FF00FF"},
+			"message": {"dark": "205"}, "warning": "#FFA500"}}`,
+		"invalid.json": `{"name": "invalid", "colours": {"title": "magenta", "message": {"light": "300", "dark": "205"},
+			"faint": "#123"}, "family": {"llama": "nope", "qwen": "#AAE"}}`,
+	}
+	for name, data := range themes {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old, err := LoadThemeFile(filepath.Join(dir, "old.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Colours.Title != SingleColour("#FF8800") || old.Colours.Title.TerminalColour() != lipgloss.Color("#FF8800") {
+		t.Errorf("old format title = %+v", old.Colours.Title)
+	}
+
+	adaptive, err := LoadThemeFile(filepath.Join(dir, "adaptive.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := lipgloss.AdaptiveColor{Light: "#5A00A0", Dark: "#FF00FF"}
+	if got := adaptive.Colours.Title.TerminalColour(); got != want {
+		t.Errorf("adaptive title = %v, want %v", got, want)
+	}
+	if got := adaptive.Colours.Message.TerminalColour(); got != lipgloss.Color("205") {
+		t.Errorf("message with only dark set = %v, want 205 on both backgrounds", got)
+	}
+	if got := adaptive.Colours.Warning.TerminalColour(); got != lipgloss.Color("#FFA500") {
+		t.Errorf("plain string alongside adaptive colours = %v", got)
+	}
+	if got := adaptive.GetColour("title"); got != want {
+		t.Errorf("GetColour(title) = %v, want %v", got, want)
+	}
+	if got, want := adaptive.GetColour("error"), builtInThemes[0].Colours.Error.TerminalColour(); got != want {
+		t.Errorf("GetColour(error) unset in the theme = %v, want the default theme's %v", got, want)
+	}
+	if got := adaptive.GetColour("nope"); got != (lipgloss.NoColor{}) {
+		t.Errorf("GetColour(nope) = %v, want no colour", got)
+	}
+
+	// Invalid colours fall back to the built-in colours rather than failing the theme
+	invalid, err := LoadThemeFile(filepath.Join(dir, "invalid.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved := resolve(invalid)
+	if resolved.Colours.Title != builtInThemes[0].Colours.Title || resolved.Colours.Message != builtInThemes[0].Colours.Message {
+		t.Errorf("invalid colours weren't replaced with the built-in ones: %+v", resolved.Colours)
+	}
+	if resolved.Colours.Faint != SingleColour("#123") {
+		t.Errorf("valid short hex colour was dropped: %+v", resolved.Colours.Faint)
+	}
+	if _, ok := resolved.Family["llama"]; ok || resolved.Family["qwen"] != "#AAE" {
+		t.Errorf("family colours = %v, want only the valid one kept", resolved.Family)
+	}
+
+	// Single colours are written back as strings so existing files keep their format
+	data, err := json.Marshal(adaptive.Colours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"warning":"#FFA500"`) || !strings.Contains(string(data), `"title":{"dark":"#FF00FF","light":"#5A00A0"}`) {
+		t.Errorf("marshalled colours = %s", data)
+	}
+}
@@ -0,0 +1,236 @@
+// symlink_cleanup.go finds the broken model symlinks in the LM Studio models directory that linking cleans up, and
+// implements -cleanup-dry-run, which reports them with their targets and ages and asks before removing them. Links
+// into another filesystem whose directory is missing, like an unmounted drive, are kept with a warning rather than
+// removed, only links into an Ollama models directory whose blob has gone are treated as broken without it.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// symlinkCleanup is what linking does with the broken symlinks it finds afterwards
+type symlinkCleanup int
+
+const (
+	cleanupRemove symlinkCleanup = iota // remove them, the default
+	cleanupReport                       // only log them, with cleanup_report_only
+	cleanupSkip                         // leave them alone, with -no-cleanup
+)
+
+// cleanupMode returns the cleanup for the -no-cleanup flag and cleanup_report_only setting
+func cleanupMode(noCleanup, reportOnly bool) symlinkCleanup {
+	switch {
+	case noCleanup:
+		return cleanupSkip
+	case reportOnly:
+		return cleanupReport
+	}
+	return cleanupRemove
+}
+
+// brokenSymlink is a model symlink whose target can't be used
+type brokenSymlink struct {
+	Path        string
+	Target      string
+	Created     time.Time // modification time of the link itself
+	Unavailable bool      // the target's directory is missing outside the Ollama models directories, so it's kept
+}
+
+// inOllamaModelsDir reports whether path is inside one of the directories Ollama keeps its models in
+func inOllamaModelsDir(path, ollamaModelsDir string) bool {
+	for _, dir := range blobSearchDirs(ollamaModelsDir) {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetUnavailable reports whether a broken link's target is on a filesystem that may only be unmounted: it isn't in
+// an Ollama models directory, where a missing file means the blob was removed, and the directory it's in is missing
+// too rather than just the file
+func targetUnavailable(targetPath, ollamaModelsDir string) bool {
+	if inOllamaModelsDir(targetPath, ollamaModelsDir) {
+		return false
+	}
+	_, err := os.Stat(filepath.Dir(targetPath))
+	return os.IsNotExist(err)
+}
+
+// findBrokenSymlinks walks the LM Studio models directory for symlinks isValidSymlink rejects
+func findBrokenSymlinks(lmStudioModelsDir, ollamaModelsDir string) ([]brokenSymlink, error) {
+	var broken []brokenSymlink
+	err := filepath.Walk(lmStudioModelsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if isValidSymlink(path, target) {
+			return nil
+		}
+		broken = append(broken, brokenSymlink{
+			Path:        path,
+			Target:      target,
+			Created:     info.ModTime(),
+			Unavailable: targetUnavailable(target, ollamaModelsDir),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking LM Studio models directory: %w", err)
+	}
+	return broken, nil
+}
+
+// removeBrokenSymlinks removes the broken links except those with unavailable targets, logging each link removed, then
+// the directories left empty. It returns the links removed.
+func removeBrokenSymlinks(lmStudioModelsDir string, broken []brokenSymlink) ([]string, []error) {
+	var removed []string
+	var errs []error
+	for _, link := range broken {
+		if link.Unavailable {
+			logging.InfoLogger.Printf("Keeping symlink %s, its target %s may be on an unmounted filesystem\n", link.Path, link.Target)
+			continue
+		}
+		if err := os.Remove(link.Path); err != nil {
+			errs = append(errs, fmt.Errorf("error removing %s: %w", link.Path, err))
+			continue
+		}
+		logging.InfoLogger.Printf("Removed broken symlink %s to %s\n", link.Path, link.Target)
+		removed = append(removed, link.Path)
+	}
+	removeEmptyDirs(lmStudioModelsDir)
+	return removed, errs
+}
+
+// removeEmptyDirs removes the empty directories under dir, leaving dir itself
+func removeEmptyDirs(dir string) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || path == dir {
+			return nil
+		}
+		if files, err := os.ReadDir(path); err == nil && len(files) == 0 {
+			logging.InfoLogger.Printf("Removing empty directory: %s\n", path)
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		logging.ErrorLogger.Printf("Error removing empty directories from %s: %v\n", dir, err)
+	}
+}
+
+// cleanBrokenSymlinks removes or only logs the broken symlinks left in the LM Studio models directory after linking
+func cleanBrokenSymlinks(lmStudioModelsDir, ollamaModelsDir string, cleanup symlinkCleanup) {
+	if cleanup == cleanupSkip {
+		return
+	}
+	broken, err := findBrokenSymlinks(lmStudioModelsDir, ollamaModelsDir)
+	if err != nil {
+		logging.ErrorLogger.Printf("%v\n", err)
+		return
+	}
+	if cleanup == cleanupReport {
+		for _, link := range broken {
+			logging.InfoLogger.Printf("Found broken symlink %s to %s, not removed as cleanup_report_only is set\n", link.Path, link.Target)
+		}
+		return
+	}
+	_, errs := removeBrokenSymlinks(lmStudioModelsDir, broken)
+	for _, err := range errs {
+		logging.ErrorLogger.Printf("%v\n", err)
+	}
+}
+
+// linkAge is how long ago a link was made, in days or hours
+func linkAge(created, now time.Time) string {
+	age := now.Sub(created)
+	if age >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(age.Hours()))
+}
+
+// writeBrokenSymlinks prints each broken link with its target and age, marking those kept as their target may be
+// unmounted
+func writeBrokenSymlinks(out io.Writer, broken []brokenSymlink, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Symlink\tTarget\tAge\t")
+	for _, link := range broken {
+		note := ""
+		if link.Unavailable {
+			note = "kept, the target's filesystem may be unmounted"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", link.Path, link.Target, linkAge(link.Created, now), note)
+	}
+	return w.Flush()
+}
+
+// runCleanupDryRun implements -cleanup-dry-run, reporting the broken symlinks in the LM Studio models directory and
+// removing them once confirmed. It returns the exit code.
+func runCleanupDryRun(lmStudioModelsDir, ollamaModelsDir string, in io.Reader, out io.Writer) int {
+	broken, err := findBrokenSymlinks(lmStudioModelsDir, ollamaModelsDir)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	if len(broken) == 0 {
+		fmt.Fprintf(out, "No broken symlinks in %s\n", lmStudioModelsDir)
+		return 0
+	}
+	if err := writeBrokenSymlinks(out, broken, time.Now()); err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+
+	removable := 0
+	for _, link := range broken {
+		if !link.Unavailable {
+			removable++
+		}
+	}
+	if removable == 0 {
+		fmt.Fprintln(out, "\nNo symlinks to remove, the broken ones point to filesystems that may be unmounted")
+		return 0
+	}
+	fmt.Fprintf(out, "\nRemove %d broken symlink(s)? [y/N] ", removable)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Fprintln(out, "No symlinks removed")
+		return 0
+	}
+
+	removed, errs := removeBrokenSymlinks(lmStudioModelsDir, broken)
+	for _, err := range errs {
+		fmt.Fprintln(out, err)
+	}
+	fmt.Fprintf(out, "Removed %d symlink(s), the list is in the log\n", len(removed))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanupDryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OLLAMA_MODELS", "")
+	ollamaDir, lmStudioDir, otherDir := t.TempDir(), t.TempDir(), t.TempDir()
+	valid := filepath.Join(otherDir, "valid.gguf")
+	if err := os.WriteFile(valid, []byte("gguf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"missing-blob": filepath.Join(ollamaDir, "blobs", "sha256-gone"),
+		"unmounted":    filepath.Join(otherDir, "not-mounted", "models", "model.gguf"),
+		"valid":        valid,
+		"deleted-file": filepath.Join(otherDir, "deleted.gguf"),
+	}
+	for name, target := range links {
+		dir := filepath.Join(lmStudioDir, "publisher", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(dir, name+".gguf")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(name string) bool {
+		_, err := os.Lstat(filepath.Join(lmStudioDir, "publisher", name, name+".gguf"))
+		return err == nil
+	}
+
+	// Only reporting after linking leaves every link in place
+	cleanBrokenSymlinks(lmStudioDir, ollamaDir, cleanupReport)
+	for name := range links {
+		if !exists(name) {
+			t.Errorf("%s removed when only reporting", name)
+		}
+	}
+
+	var out bytes.Buffer
+	if code := runCleanupDryRun(lmStudioDir, ollamaDir, strings.NewReader("n\n"), &out); code != 0 {
+		t.Fatalf("runCleanupDryRun() = %d, output %s", code, out.String())
+	}
+	report := out.String()
+	for _, name := range []string{"missing-blob", "unmounted", "deleted-file"} {
+		if !strings.Contains(report, links[name]) {
+			t.Errorf("report doesn't list the target of %s:\n%s", name, report)
+		}
+	}
+	if strings.Contains(report, valid) || !strings.Contains(report, "may be unmounted") || !strings.Contains(report, "Remove 2 broken symlink(s)?") {
+		t.Errorf("report = %s, want the two removable links and the unmounted one kept", report)
+	}
+	if !exists("missing-blob") || !exists("deleted-file") {
+		t.Error("links removed without confirmation")
+	}
+
+	out.Reset()
+	if code := runCleanupDryRun(lmStudioDir, ollamaDir, strings.NewReader("y\n"), &out); code != 0 {
+		t.Fatalf("runCleanupDryRun() = %d, output %s", code, out.String())
+	}
+	if exists("missing-blob") || exists("deleted-file") || !exists("unmounted") || !exists("valid") {
+		t.Errorf("after confirming, want the broken links removed and the unmounted and valid ones kept, output %s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(lmStudioDir, "publisher", "missing-blob")); !os.IsNotExist(err) {
+		t.Error("the directory left empty wasn't removed")
+	}
+}
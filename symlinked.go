@@ -0,0 +1,68 @@
+// symlinked.go detects models whose main blob is a symlink into another directory, e.g. one linked from LM Studio,
+// as deleting them from Ollama doesn't free the space they appear to use.
+package main
+
+import (
+	"os"
+	"sync"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// modelLayerMediaType is the media type of the layer holding a model's weights
+const modelLayerMediaType = "application/vnd.ollama.image.model"
+
+// symlinkedBlobs caches whether each model digest's main blob is a symlink, blobs don't change under a digest
+var symlinkedBlobs = struct {
+	sync.Mutex
+	byDigest map[string]bool
+}{byDigest: make(map[string]bool)}
+
+// mainBlobSymlinked reports whether the blob holding a model's weights is a symlink
+func mainBlobSymlinked(modelsDir, modelName string) (bool, error) {
+	manifest, err := readManifest(modelsDir, modelName)
+	if err != nil {
+		return false, err
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != modelLayerMediaType {
+			continue
+		}
+		fi, err := os.Lstat(blobPath(modelsDir, layer.Digest))
+		if err != nil {
+			return false, err
+		}
+		return fi.Mode()&os.ModeSymlink != 0, nil
+	}
+	return false, nil
+}
+
+// markSymlinkedModels sets Symlinked on the models whose main blob is a symlink. It reads the models directory, so
+// it's only meaningful for a local host.
+func markSymlinkedModels(models []Model, modelsDir string) {
+	symlinkedBlobs.Lock()
+	defer symlinkedBlobs.Unlock()
+	for i, model := range models {
+		symlinked, ok := symlinkedBlobs.byDigest[model.Digest]
+		if !ok {
+			var err error
+			symlinked, err = mainBlobSymlinked(modelsDir, model.Name)
+			if err != nil {
+				// Not cached so a model that's mid-pull is checked again on the next refresh
+				logging.DebugLogger.Printf("Error checking whether %s is symlinked: %v\n", model.Name, err)
+				continue
+			}
+			if model.Digest != "" {
+				symlinkedBlobs.byDigest[model.Digest] = symlinked
+			}
+		}
+		models[i].Symlinked = symlinked
+	}
+}
+
+// markSymlinkedModels marks the symlinked models in the list when connected to a local host
+func (m *AppModel) markSymlinkedModels() {
+	if isLocalhost(m.cfg.OllamaAPIURL) {
+		markSymlinkedModels(m.models, m.ollamaModelsDir)
+	}
+}
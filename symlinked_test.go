@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSymlinkedModels(t *testing.T) {
+	modelsDir := t.TempDir()
+	elsewhere := filepath.Join(t.TempDir(), "model.gguf")
+	os.WriteFile(elsewhere, []byte("weights"), 0o644)
+	os.MkdirAll(filepath.Join(modelsDir, "blobs"), 0o755)
+
+	newModel := func(name, digest string, linked bool) Model {
+		blobDigest := "sha256:" + strings.Repeat(digest, 64)
+		if linked {
+			os.Symlink(elsewhere, blobPath(modelsDir, blobDigest))
+		} else {
+			os.WriteFile(blobPath(modelsDir, blobDigest), []byte("weights"), 0o644)
+		}
+		manifest, _ := json.Marshal(modelManifest{Layers: []manifestLayer{
+			{MediaType: modelLayerMediaType, Digest: blobDigest},
+			{MediaType: "application/vnd.ollama.image.template", Digest: "sha256:" + strings.Repeat("f", 64)},
+		}})
+		os.MkdirAll(filepath.Dir(manifestPath(modelsDir, name)), 0o755)
+		os.WriteFile(manifestPath(modelsDir, name), manifest, 0o644)
+
+		model := Model{}
+		model.Name, model.Digest, model.Size = name, "symlink-test-"+digest, 12<<30
+		return model
+	}
+	models := []Model{newModel("linked:latest", "c", true), newModel("regular:latest", "d", false)}
+
+	markSymlinkedModels(models, modelsDir)
+	if !models[0].Symlinked || models[1].Symlinked {
+		t.Fatalf("Symlinked = %v, %v, want only the linked model", models[0].Symlinked, models[1].Symlinked)
+	}
+	if got := models[0].SizeLabel(); got != "12.00GB ↗" {
+		t.Errorf("SizeLabel() of a symlinked model = %q", got)
+	}
+	if got := models[1].SizeLabel(); got != "12.00GB" {
+		t.Errorf("SizeLabel() of a regular model = %q", got)
+	}
+
+	plan := planDeletion(models, models)
+	if plan.Total != 24<<30 || plan.Freed != 12<<30 || plan.Shared["linked:latest"] == "" {
+		t.Errorf("planDeletion() = %+v, want only the regular model's size reclaimable", plan)
+	}
+	if summary := deletionSummary(models, models, nil, true); !strings.Contains(summary, "24.00GB in total, 12.00GB reclaimable") {
+		t.Errorf("deletionSummary() = %q", summary)
+	}
+
+	// The result is cached by digest, so it holds even once the blob is gone
+	os.Remove(blobPath(modelsDir, "sha256:"+strings.Repeat("c", 64)))
+	models[0].Symlinked = false
+	markSymlinkedModels(models, modelsDir)
+	if !models[0].Symlinked {
+		t.Error("markSymlinkedModels() didn't use the cached result")
+	}
+}
@@ -15,10 +15,22 @@ type textInputModel struct {
 	textInput textinput.Model
 	oldName   string
 	quitting  bool
+	cancelled bool         // closed with ctrl+c or esc rather than enter
+	feedback  nameFeedback // checks the value as it's typed, nil to accept anything
 }
 
-// promptForNewName displays a text input prompt for renaming a model.
-func promptForNewName(oldName string) string {
+// nameFeedback checks a name as it's typed, returning a warning to show under the prompt or an error that stops the
+// name being accepted
+type nameFeedback func(name string) (string, error)
+
+// promptForNewName displays a text input prompt for renaming a model, ok is false when it was cancelled.
+func promptForNewName(oldName string, feedback nameFeedback) (string, bool) {
+	return promptForName(oldName, "", feedback)
+}
+
+// promptForName displays a text input prompt for a new model name, pre-filled with value. enter doesn't accept a
+// name feedback reports an error for, ok is false when the prompt was cancelled.
+func promptForName(oldName, value string, feedback nameFeedback) (string, bool) {
 	ti := textinput.New()
 	// print 'renaming oldName' to the console with the oldName in purple
 	ti.Prompt = oldName + "\n" + "Name for new model: "
@@ -30,6 +42,7 @@ func promptForNewName(oldName string) string {
 	ti.ShowSuggestions = true
 	ti.CharLimit = 300
 	ti.Width = 140
+	ti.SetValue(value)
 
 	ti.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
 	ti.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
@@ -39,6 +52,7 @@ func promptForNewName(oldName string) string {
 	m := textInputModel{
 		textInput: ti,
 		oldName:   oldName,
+		feedback:  feedback,
 	}
 
 	p := tea.NewProgram(&m)
@@ -46,15 +60,42 @@ func promptForNewName(oldName string) string {
 		logging.ErrorLogger.Printf("Error starting text input program: %v\n", err)
 	}
 
+	if m.cancelled {
+		return "", false
+	}
 	newName := m.textInput.Value()
 
 	if newName == "" {
 		// error handling
 		logging.ErrorLogger.Println("No new name entered, returning old name")
-		return oldName
+		return oldName, true
 	}
 
-	return newName
+	return newName, true
+}
+
+// promptForValue displays a text input prompt pre-filled with value and returns what was entered, which may be empty.
+// ok is false when the prompt was cancelled, so the pre-filled value isn't mistaken for an answer.
+func promptForValue(prompt, value string) (string, bool) {
+	ti := textinput.New()
+	ti.Prompt = prompt
+	ti.Focus()
+	ti.CharLimit = 300
+	ti.Width = 140
+	ti.SetValue(value)
+
+	ti.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
+	ti.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF00FF"))
+
+	m := textInputModel{textInput: ti}
+	p := tea.NewProgram(&m)
+	if _, err := p.Run(); err != nil {
+		logging.ErrorLogger.Printf("Error starting text input program: %v\n", err)
+	}
+	if m.cancelled {
+		return "", false
+	}
+	return m.textInput.Value(), true
 }
 
 func (m *textInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -62,9 +103,16 @@ func (m *textInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "enter":
+		case "enter":
+			if _, err := m.check(); err != nil {
+				return m, nil
+			}
 			m.quitting = true
 			return m, tea.Quit
+		case "ctrl+c", "esc":
+			m.quitting = true
+			m.cancelled = true
+			return m, tea.Quit
 		}
 	}
 
@@ -80,9 +128,21 @@ func (m textInputModel) View() string {
 	if m.quitting {
 		return ""
 	}
-	return fmt.Sprintf(
-		"\n%s\n\n%s",
-		m.textInput.View(),
-		"(ctrl+c to cancel)",
-	)
+	view := "\n" + m.textInput.View() + "\n"
+	warning, err := m.check()
+	switch {
+	case err != nil:
+		view += lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render(err.Error()) + "\n"
+	case warning != "":
+		view += lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Render(warning) + "\n"
+	}
+	return fmt.Sprintf("%s\n%s", view, "(esc or ctrl+c to cancel)")
+}
+
+// check runs the feedback on the value typed so far, an empty value is left to the caller
+func (m textInputModel) check() (string, error) {
+	if m.feedback == nil || m.textInput.Value() == "" {
+		return "", nil
+	}
+	return m.feedback(m.textInput.Value())
 }
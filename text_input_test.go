@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTextInputCancel(t *testing.T) {
+	// A pre-filled value, like the last registry prefix, mustn't be used when the prompt is cancelled
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyCtrlC}, {Type: tea.KeyEsc}} {
+		input := textinput.New()
+		input.SetValue("registry.internal:5000/team")
+		prompt := &textInputModel{textInput: input}
+		if _, cmd := prompt.Update(key); cmd == nil || !prompt.quitting || !prompt.cancelled {
+			t.Errorf("%s didn't cancel the prompt", key)
+		}
+	}
+
+	input := textinput.New()
+	input.SetValue("registry.internal:5000/team")
+	prompt := &textInputModel{textInput: input}
+	if prompt.Update(tea.KeyMsg{Type: tea.KeyEnter}); !prompt.quitting || prompt.cancelled {
+		t.Error("enter cancelled the prompt")
+	}
+}
@@ -0,0 +1,128 @@
+// theme_picker.go contains the theme picker view, which previews themes live over the model list.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+)
+
+type themePicker struct {
+	themes   []styles.ThemeFile
+	cursor   int
+	previous styles.Theme // restored if the picker is cancelled
+}
+
+func (m *AppModel) handleThemeKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("Theme key matched")
+	// Re-scan the themes directory every time so newly added themes show up without a restart
+	current := styles.Current()
+	m.themePicker = themePicker{
+		themes:   styles.ListThemes(styles.ThemesDir()),
+		previous: current,
+	}
+	for i, theme := range m.themePicker.themes {
+		if theme.Name == current.Name && theme.Err == nil {
+			m.themePicker.cursor = i
+			break
+		}
+	}
+	m.view = ThemeView
+	return m, nil
+}
+
+// handleThemePickerKey handles all keys while the theme picker is open
+func (m *AppModel) handleThemePickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := &m.themePicker
+	switch msg.String() {
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+			m.previewTheme()
+		}
+	case "down", "j":
+		if picker.cursor < len(picker.themes)-1 {
+			picker.cursor++
+			m.previewTheme()
+		}
+	case "enter":
+		selected := picker.themes[picker.cursor]
+		if selected.Err != nil {
+			m.message = fmt.Sprintf("Can't use theme %s: %v", selected.Name, selected.Err)
+			return m, nil
+		}
+		styles.SetTheme(selected.Theme)
+		m.cfg.Theme = selected.Name
+		if err := config.SetValue("theme", selected.Name); err != nil {
+			logging.ErrorLogger.Printf("Error saving theme to config: %v\n", err)
+			m.message = fmt.Sprintf("Theme %s applied but not saved: %v", selected.Name, err)
+		} else {
+			m.message = fmt.Sprintf("Theme set to %s", selected.Name)
+		}
+		m.view = MainView
+	case "esc", "q":
+		styles.SetTheme(picker.previous)
+		m.view = MainView
+	}
+	return m, nil
+}
+
+// previewTheme applies the theme under the cursor so the list behind the picker renders with it,
+// themes that failed to load are never applied
+func (m *AppModel) previewTheme() {
+	picker := m.themePicker
+	selected := picker.themes[picker.cursor]
+	if selected.Err != nil {
+		styles.SetTheme(picker.previous)
+		return
+	}
+	styles.SetTheme(selected.Theme)
+}
+
+func (m *AppModel) themePickerView() string {
+	colours := styles.Current().Colours
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(colours.Title.TerminalColour())
+	selectedStyle := lipgloss.NewStyle().Foreground(colours.Highlight.TerminalColour()).Background(colours.HighlightBG.TerminalColour())
+	faintStyle := lipgloss.NewStyle().Foreground(colours.Faint.TerminalColour())
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#666666")).Strikethrough(true)
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Themes") + "\n")
+	for i, theme := range m.themePicker.themes {
+		line := theme.Name
+		switch {
+		case theme.Err != nil:
+			line = errorStyle.Render(line) + faintStyle.Render(fmt.Sprintf("  %v", theme.Err))
+		case theme.BuiltIn:
+			line += faintStyle.Render("  (built-in) " + theme.Description)
+		default:
+			line += faintStyle.Render("  " + theme.Description)
+		}
+		if i == m.themePicker.cursor {
+			line = selectedStyle.Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(faintStyle.Render(fmt.Sprintf("↑/↓ preview, enter to apply, esc to cancel. User themes are read from %s", styles.ThemesDir())))
+
+	picker := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colours.SelectedBorder.TerminalColour()).
+		Padding(0, 1).
+		Render(b.String())
+
+	// Render the list behind the picker in the previewed theme, shrunk so both fit on screen
+	listHeight := m.list.Height()
+	m.list.SetHeight(max(listHeight-lipgloss.Height(picker), 3))
+	view := m.list.View()
+	m.list.SetHeight(listHeight)
+	return picker + "\n" + view
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/styles"
+	"github.com/sammcj/gollama/utils"
+)
+
+func TestThemePicker(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	styles.InitTheme(styles.DefaultThemeName)
+	defer styles.InitTheme(styles.DefaultThemeName)
+	writeTheme := func(name, content string) {
+		if err := os.MkdirAll(styles.ThemesDir(), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(styles.ThemesDir(), name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTheme("sunset.json", `{"name": "sunset", "description": "Warm", "colours": {"title": "#FF8800"}}`)
+	writeTheme("broken.json", "{not json")
+
+	m := &AppModel{cfg: &config.Config{Theme: styles.DefaultThemeName}, list: list.New(nil, list.NewDefaultDelegate(), 0, 0)}
+	press := func(key tea.KeyType) { m.handleThemePickerKey(tea.KeyMsg{Type: key}) }
+	find := func(name string) int {
+		for i, theme := range m.themePicker.themes {
+			if theme.Name == name {
+				return i
+			}
+		}
+		t.Fatalf("theme %s isn't listed", name)
+		return -1
+	}
+	moveTo := func(name string) {
+		for m.themePicker.cursor < find(name) {
+			press(tea.KeyDown)
+		}
+	}
+
+	m.handleThemeKey()
+	broken := m.themePicker.themes[find("broken")]
+	if m.view != ThemeView || broken.Err == nil {
+		t.Fatalf("view = %v, broken theme error = %v, want the picker with the invalid theme listed", m.view, broken.Err)
+	}
+	if !strings.Contains(m.themePickerView(), broken.Err.Error()) {
+		t.Error("the picker doesn't show why the broken theme can't be used")
+	}
+
+	// An invalid theme is never previewed, a valid one is straight away and esc reverts it
+	moveTo("broken")
+	if styles.Current().Name != styles.DefaultThemeName {
+		t.Errorf("previewing the broken theme applied %s", styles.Current().Name)
+	}
+	press(tea.KeyEnter)
+	if m.view != ThemeView || !strings.Contains(m.message, "Can't use theme broken") {
+		t.Errorf("applying the broken theme = %q, want it refused", m.message)
+	}
+	moveTo("sunset")
+	if styles.Current().Name != "sunset" {
+		t.Errorf("previewed theme = %s, want sunset", styles.Current().Name)
+	}
+	press(tea.KeyEsc)
+	if m.view != MainView || styles.Current().Name != styles.DefaultThemeName {
+		t.Errorf("after esc the theme is %s, want the previous one restored", styles.Current().Name)
+	}
+
+	// Themes added while gollama runs are listed the next time the picker opens, and enter saves the choice
+	writeTheme("dusk.json", `{"name": "dusk", "colours": {"title": "#442266"}}`)
+	m.handleThemeKey()
+	moveTo("dusk")
+	press(tea.KeyEnter)
+	if m.view != MainView || m.cfg.Theme != "dusk" || styles.Current().Name != "dusk" {
+		t.Errorf("after enter the theme is %s with %s configured, want dusk", styles.Current().Name, m.cfg.Theme)
+	}
+	if data, err := os.ReadFile(utils.GetConfigPath()); err != nil || !strings.Contains(string(data), `"dusk"`) {
+		t.Errorf("config file = %s, %v, want the theme saved", data, err)
+	}
+}
@@ -1,8 +1,10 @@
-// top_view.go contains the TopModel struct which is used to render the top view of the application.
+// top_view.go renders the top view of the running models and contains the standalone TopModel.
 package main
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
@@ -10,24 +12,44 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/ollama/ollama/api"
 	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
 )
 
 type TopModel struct {
 	client   *api.Client
+	apiURL   string
 	table    table.Model
 	quitting bool
 }
 
-func NewTopModel(client *api.Client) *TopModel {
-	columns := []table.Column{
-		{Title: "Name", Width: 40},
-		{Title: "Size (GB)", Width: 10},
-		{Title: "VRAM (GB)", Width: 10},
-		{Title: "Until", Width: 20},
-	}
+// runningModelColumns are the columns of the running models table
+var runningModelColumns = []table.Column{
+	{Title: "Name", Width: 40},
+	{Title: "Size (GB)", Width: 10},
+	{Title: "VRAM (GB)", Width: 10},
+	{Title: "GPU%", Width: 6},
+	{Title: "Processor", Width: 16},
+	{Title: "Context", Width: 8},
+	{Title: "KV Cache", Width: 12},
+	{Title: "Until", Width: 20},
+}
+
+// gpuColumn is the index of the GPU% column, which is coloured by how much of the model is offloaded
+const gpuColumn = 3
+
+// topRefreshInterval is how often the top view refreshes the running models
+const topRefreshInterval = time.Second
 
+// runningModelsMsg carries the running models for the top view
+type runningModelsMsg struct {
+	models     []runningModel
+	err        error
+	generation int // the top view visit the refresh belongs to
+}
+
+func NewTopModel(client *api.Client, apiURL string) *TopModel {
 	t := table.New(
-		table.WithColumns(columns),
+		table.WithColumns(runningModelColumns),
 		table.WithHeight(10),
 	)
 
@@ -37,6 +59,7 @@ func NewTopModel(client *api.Client) *TopModel {
 
 	return &TopModel{
 		client: client,
+		apiURL: apiURL,
 		table:  t,
 	}
 }
@@ -76,7 +99,7 @@ func (m *TopModel) View() string {
 
 func (m *TopModel) updateRunningModels() tea.Cmd {
 	return tea.Tick(time.Second*1, func(t time.Time) tea.Msg {
-		running, err := showRunningModels(m.client)
+		running, err := showRunningModels(m.apiURL)
 		if err != nil {
 			logging.ErrorLogger.Printf("Error showing running models: %v", err)
 			return fmt.Sprintf("Error showing running models: %v", err)
@@ -85,3 +108,154 @@ func (m *TopModel) updateRunningModels() tea.Cmd {
 		return nil
 	})
 }
+
+// gpuPercent returns the percentage of a running model in VRAM, or -1 when Ollama didn't report its size
+func (r runningModel) gpuPercent() int {
+	if r.Size <= 0 {
+		return -1
+	}
+	if r.SizeVRAM >= r.Size {
+		return 100
+	}
+	return int(float64(r.SizeVRAM) / float64(r.Size) * 100)
+}
+
+// formatRunningVRAM formats the VRAM a running model uses, or CPU when it's entirely in system memory
+func formatRunningVRAM(r runningModel) string {
+	if r.Size > 0 && r.SizeVRAM <= 0 {
+		return "CPU"
+	}
+	return formatRunningSize(r.SizeVRAM)
+}
+
+func formatGPUPercent(percent int) string {
+	if percent < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d%%", percent)
+}
+
+// gpuPercentColour picks the theme colour for a GPU%, partially offloaded models get the warning colour as they
+// run much slower than ones entirely in VRAM
+func gpuPercentColour(percent int) lipgloss.TerminalColor {
+	colours := styles.Current().Colours
+	switch {
+	case percent < 0:
+		return colours.Faint.TerminalColour()
+	case percent >= 100:
+		return colours.Message.TerminalColour()
+	case percent == 0:
+		return colours.Error.TerminalColour()
+	default:
+		return colours.Warning.TerminalColour()
+	}
+}
+
+// runningModelRow returns the top view's row for a running model, in the order of runningModelColumns
+func runningModelRow(r runningModel) table.Row {
+	return table.Row{r.Name, formatRunningSize(r.Size), formatRunningVRAM(r), formatGPUPercent(r.gpuPercent()),
+		r.processor(), formatContextLength(r.ContextLength), r.kvCacheLabel(), formatExpiresAt(r.ExpiresAt)}
+}
+
+// fetchRunningModels fetches the running models for the top view after delay
+func (m *AppModel) fetchRunningModels(delay time.Duration) tea.Cmd {
+	apiURL, generation := m.cfg.OllamaAPIURL, m.topGeneration
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		models, err := listRunningModels(context.Background(), apiURL)
+		return runningModelsMsg{models: models, err: err, generation: generation}
+	})
+}
+
+// handleRunningModelsMsg stores the running models, keeping the cursor on the same model where it's still loaded,
+// and schedules the next refresh while the top view is open
+func (m *AppModel) handleRunningModelsMsg(msg runningModelsMsg) (tea.Model, tea.Cmd) {
+	m.topErr = msg.err
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error showing running models: %v", msg.err)
+	} else {
+		selected := ""
+		if m.topCursor < len(m.topModels) {
+			selected = m.topModels[m.topCursor].Name
+		}
+		m.topModels = msg.models
+		for i, model := range m.topModels {
+			if model.Name == selected {
+				m.topCursor = i
+			}
+		}
+		if m.topCursor >= len(m.topModels) {
+			m.topCursor = max(len(m.topModels)-1, 0)
+		}
+	}
+	if m.view != TopView || msg.generation != m.topGeneration {
+		return m, nil
+	}
+	return m, m.fetchRunningModels(topRefreshInterval)
+}
+
+// handleTopViewKey moves the cursor in the top view, it reports whether the key was handled
+func (m *AppModel) handleTopViewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "up":
+		if m.topCursor > 0 {
+			m.topCursor--
+		}
+		return m, nil, true
+	case "down":
+		if m.topCursor < len(m.topModels)-1 {
+			m.topCursor++
+		}
+		return m, nil, true
+	case "k":
+		model, cmd := m.handleKeepAliveKey()
+		return model, cmd, true
+	}
+	return m, nil, false
+}
+
+// topView renders the running models. It's drawn without the table component so the GPU% column can be coloured.
+func (m *AppModel) topView() string {
+	if m.topErr != nil {
+		return fmt.Sprintf("Error showing running models: %v", m.topErr)
+	}
+
+	cell := lipgloss.NewStyle().Padding(0, 1)
+	header := lipgloss.NewStyle().Bold(true).BorderStyle(lipgloss.NormalBorder()).BorderBottom(true).BorderForeground(lipgloss.Color("240"))
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
+
+	var headers []string
+	for _, column := range runningModelColumns {
+		headers = append(headers, cell.Render(lipgloss.NewStyle().Width(column.Width).MaxWidth(column.Width).Inline(true).Render(column.Title)))
+	}
+	lines := []string{header.Render(lipgloss.JoinHorizontal(lipgloss.Top, headers...))}
+
+	for i, model := range m.topModels {
+		var cells []string
+		for j, value := range runningModelRow(model) {
+			width := runningModelColumns[j].Width
+			style := lipgloss.NewStyle().Width(width).MaxWidth(width).Inline(true)
+			if i == m.topCursor {
+				style = style.Inherit(selected)
+			}
+			if j == gpuColumn {
+				style = style.Foreground(gpuPercentColour(model.gpuPercent()))
+			}
+			padding := cell
+			if i == m.topCursor {
+				padding = padding.Inherit(selected)
+			}
+			cells = append(cells, padding.Render(style.Render(truncate(value, width))))
+		}
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	if len(m.topModels) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(styles.Current().Colours.Faint.TerminalColour()).Render(" No models loaded"))
+	}
+
+	if m.topMessage != "" {
+		lines = append(lines, "", lipgloss.NewStyle().Foreground(styles.Current().Colours.Message.TerminalColour()).Render(m.topMessage))
+	}
+
+	// Render the table view
+	return "\n" + strings.Join(lines, "\n") + "\nPress 'k' to set how long the selected model stays loaded, 'q' or `esc` to return to the main view."
+}
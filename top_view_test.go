@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/styles"
+)
+
+func TestRunningModelRow(t *testing.T) {
+	running := func(name string, size, vram int64) runningModel {
+		r := runningModel{ContextLength: 8192}
+		r.Name, r.Size, r.SizeVRAM = name, size, vram
+		return r
+	}
+	tests := []struct {
+		model       runningModel
+		wantVRAM    string
+		wantPercent string
+		wantColour  lipgloss.TerminalColor
+	}{
+		{running("gpu", 4<<30, 4<<30), "4.00 GB", "100%", styles.Current().Colours.Message.TerminalColour()},
+		{running("split", 4<<30, 1<<30), "1.00 GB", "25%", styles.Current().Colours.Warning.TerminalColour()},
+		{running("cpu", 4<<30, 0), "CPU", "0%", styles.Current().Colours.Error.TerminalColour()},
+		{running("loading", 0, 0), "n/a", "n/a", styles.Current().Colours.Faint.TerminalColour()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model.Name, func(t *testing.T) {
+			row := runningModelRow(tt.model)
+			if len(row) != len(runningModelColumns) {
+				t.Fatalf("row has %d cells, want %d", len(row), len(runningModelColumns))
+			}
+			if row[2] != tt.wantVRAM || row[gpuColumn] != tt.wantPercent || row[5] != "8192" {
+				t.Errorf("row = %v, want VRAM %s and GPU%% %s", row, tt.wantVRAM, tt.wantPercent)
+			}
+			if got := gpuPercentColour(tt.model.gpuPercent()); got != tt.wantColour {
+				t.Errorf("gpuPercentColour() = %v, want %v", got, tt.wantColour)
+			}
+		})
+	}
+
+	m := &AppModel{cfg: &config.Config{}, view: TopView, topGeneration: 1}
+	m.handleRunningModelsMsg(runningModelsMsg{models: []runningModel{running("a", 1, 1), running("b", 1, 1)}, generation: 1})
+	m.topCursor = 1
+	_, cmd := m.handleRunningModelsMsg(runningModelsMsg{models: []runningModel{running("c", 1, 1), running("a", 1, 1), running("b", 1, 1)}, generation: 1})
+	if m.topCursor != 2 {
+		t.Errorf("topCursor = %d after a refresh, want it to stay on b at 2", m.topCursor)
+	}
+	if cmd == nil {
+		t.Error("handleRunningModelsMsg() didn't schedule the next refresh")
+	}
+	if _, cmd := m.handleRunningModelsMsg(runningModelsMsg{generation: 0}); cmd != nil {
+		t.Error("a refresh from an earlier visit scheduled another refresh")
+	}
+	if m.topCursor != 0 {
+		t.Errorf("topCursor = %d with no models, want 0", m.topCursor)
+	}
+}
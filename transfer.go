@@ -0,0 +1,245 @@
+// transfer.go copies a model to another Ollama host by uploading its blobs and creating it there, e.g. to move a model
+// to a server that can't reach the registry it came from.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+)
+
+// transferMsg is the result of copying a model to another host
+type transferMsg struct {
+	modelName string
+	host      string
+	err       error
+	index     int // position of the model in a batch copy counting from 1, 0 when it's copied on its own
+}
+
+// transferFileName is the name a model file is given in the create request, Ollama only looks at the extension
+// and reads whether each GGUF is the model or a projector from the file itself
+func transferFileName(i int) string {
+	if i == 0 {
+		return "model.gguf"
+	}
+	return fmt.Sprintf("projector-%d.gguf", i)
+}
+
+// blobDigest returns the digest of a model file, from its name when it's a blob in the models directory
+func blobDigest(path string) (string, error) {
+	if match := blobNamePattern.FindStringSubmatch(filepath.Base(path)); match != nil {
+		return "sha256:" + match[1], nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error hashing %s: %w", path, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteBlobExists reports whether the host already has a blob, so it isn't uploaded again
+func remoteBlobExists(ctx context.Context, httpClient *http.Client, host *url.URL, digest string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, host.JoinPath("api", "blobs", digest).String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// uploadProgress reports the bytes read from a blob as it's uploaded
+type uploadProgress struct {
+	r         io.Reader
+	digest    string
+	total     int64
+	completed int64
+	progress  func(api.ProgressResponse) error
+}
+
+func (u *uploadProgress) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.completed += int64(n)
+	if perr := u.progress(api.ProgressResponse{Status: "uploading " + u.digest, Digest: u.digest, Total: u.total, Completed: u.completed}); perr != nil {
+		return n, perr
+	}
+	return n, err
+}
+
+// transferModel copies a model to the host at destination: each of its files is uploaded unless the host already has
+// it, then the model is created there from them with the same template, system prompt and parameters. It fails before
+// uploading anything if a file can't be found locally. The host is reached with httpClient, which should have the
+// configured transport and no timeout as the uploads can take a long time.
+func transferModel(ctx context.Context, client *api.Client, httpClient *http.Client, modelName, modelsDir, destination string, progress func(api.ProgressResponse) error) error {
+	host, err := url.Parse(destination)
+	if err != nil || host.Host == "" {
+		return fmt.Errorf("invalid host URL %q", destination)
+	}
+	paths, err := getModelPaths(modelName, modelsDir, client)
+	if err != nil {
+		return err
+	}
+	show, err := client.Show(ctx, &api.ShowRequest{Name: modelName})
+	if err != nil {
+		return fmt.Errorf("error fetching modelfile for %s: %w", modelName, err)
+	}
+
+	remote := api.NewClient(host, httpClient)
+	files := make(map[string]string, len(paths))
+	for i, path := range paths {
+		digest, err := blobDigest(path)
+		if err != nil {
+			return err
+		}
+		files[transferFileName(i)] = digest
+
+		exists, err := remoteBlobExists(ctx, httpClient, host, digest)
+		if err != nil {
+			return fmt.Errorf("error checking %s for blob %s: %w", destination, digest, err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if exists {
+			logging.DebugLogger.Printf("%s already has blob %s\n", destination, digest)
+			if err := progress(api.ProgressResponse{Status: "blob exists " + digest, Digest: digest, Total: fi.Size(), Completed: fi.Size()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		logging.DebugLogger.Printf("Uploading %s to %s as blob %s\n", path, destination, digest)
+		err = remote.CreateBlob(ctx, digest, &uploadProgress{r: f, digest: digest, total: fi.Size(), progress: progress})
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("error uploading %s to %s: %w", path, destination, err)
+		}
+	}
+
+	req := modelfileCreateRequest(modelName, modelfileValues(show.Modelfile))
+	req.From, req.Files = "", files
+	if err := remote.Create(ctx, req, progress); err != nil {
+		return fmt.Errorf("error creating %s on %s: %w", modelName, destination, err)
+	}
+	return nil
+}
+
+func (m *AppModel) handleTransferModelKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("TransferModel key matched")
+	item, ok := m.list.SelectedItem().(Model)
+	if !ok {
+		return m, nil
+	}
+	// With several models selected they're copied as a batch
+	selected := m.selectedListModels()
+	description := item.Name
+	if len(selected) > 1 {
+		description = fmt.Sprintf("%d models", len(selected))
+	}
+	var suggested string
+	if len(m.cfg.RemoteHosts) > 0 {
+		suggested = m.cfg.RemoteHosts[0]
+	}
+	destination, ok := promptForValue(fmt.Sprintf("Copy %s to the Ollama host at: ", description), suggested)
+	destination = strings.TrimSpace(destination)
+	if !ok || destination == "" {
+		m.message = "Cancelled"
+		return m, nil
+	}
+	if !strings.Contains(destination, "://") {
+		destination = config.NormaliseHost(destination)
+	}
+	if strings.TrimSuffix(destination, "/") == strings.TrimSuffix(m.cfg.OllamaAPIURL, "/") {
+		m.message = "Error: that's the host gollama is connected to, choose another"
+		return m, nil
+	}
+	m.rememberRemoteHost(destination)
+	if len(selected) > 1 {
+		m.pushBatch = newPushBatch(batchTransfer, selected)
+		m.pushBatch.host = destination
+		return m, nil
+	}
+
+	m.message = lipgloss.NewStyle().Foreground(lipgloss.Color("129")).Render(fmt.Sprintf("Copying %s to %s\n", item.Name, destination))
+	m.transferHost = destination
+	m.showProgress = true
+	return m, m.startPushModel(item.Name, m.transferModelCmd(item.Name, destination))
+}
+
+// transferModelCmd copies the model in the background, reporting progress through the push progress bar
+func (m *AppModel) transferModelCmd(modelName, destination string) tea.Cmd {
+	ctx, track := m.beginPush()
+	client, modelsDir := m.client, m.ollamaModelsDir
+	httpClient := &http.Client{Transport: apiHTTPClient.Transport}
+	return func() tea.Msg {
+		err := transferModel(ctx, client, httpClient, modelName, modelsDir, destination, track)
+		if ctx.Err() != nil {
+			logging.InfoLogger.Printf("Copying %s to %s cancelled\n", modelName, destination)
+			return nil
+		}
+		return transferMsg{modelName: modelName, host: destination, err: err}
+	}
+}
+
+func (m *AppModel) handleTransferMsg(msg transferMsg) (tea.Model, tea.Cmd) {
+	if msg.index > 0 && m.pushBatch != nil {
+		if msg.err == nil {
+			recordHistory("transfer", msg.modelName, msg.host)
+		}
+		return m.handlePushBatchResult(msg.err)
+	}
+	status := m.finishPush()
+	m.transferHost = ""
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error copying %s to %s: %v\n", msg.modelName, msg.host, msg.err)
+		m.message = fmt.Sprintf("Error copying %s to %s: %v", msg.modelName, msg.host, msg.err)
+		if status != "" {
+			m.message += fmt.Sprintf(" (last status: %s)", status)
+		}
+		return m, nil
+	}
+	logging.InfoLogger.Printf("Copied %s to %s\n", msg.modelName, msg.host)
+	recordHistory("transfer", msg.modelName, msg.host)
+	m.message = fmt.Sprintf("Copied %s to %s", msg.modelName, msg.host)
+	return m, nil
+}
+
+// rememberRemoteHost moves a host to the front of remote_hosts so it's offered first next time
+func (m *AppModel) rememberRemoteHost(host string) {
+	if len(m.cfg.RemoteHosts) > 0 && m.cfg.RemoteHosts[0] == host {
+		return
+	}
+	hosts := append([]string{host}, slices.DeleteFunc(slices.Clone(m.cfg.RemoteHosts), func(h string) bool { return h == host })...)
+	m.cfg.RemoteHosts = hosts
+	if err := config.SetValue("remote_hosts", hosts); err != nil {
+		logging.ErrorLogger.Printf("Error saving remote hosts to the config: %v\n", err)
+	}
+}
@@ -0,0 +1,102 @@
+// transfer_rate.go works out the speed and time left of a pull from its progress responses, for the line under the
+// progress bar, e.g. "42% — 31.2 MB/s — ETA 4m12s — 6.1/14.5 GB".
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// rateWindow is how far back the rolling average speed looks
+const rateWindow = 5 * time.Second
+
+// rateSample is the bytes transferred across every layer at a point in time
+type rateSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// transferRate tracks the bytes transferred per layer, so layers of different sizes and a layer's Total changing
+// don't upset the speed, which is the change in the bytes transferred over the last rateWindow
+type transferRate struct {
+	completed map[string]int64
+	totals    map[string]int64
+	samples   []rateSample
+	status    string // the status of a status only response, shown until bytes are transferred again
+}
+
+func newTransferRate() *transferRate {
+	return &transferRate{completed: make(map[string]int64), totals: make(map[string]int64)}
+}
+
+// add records a progress response received at now
+func (r *transferRate) add(resp api.ProgressResponse, now time.Time) {
+	if resp.Digest == "" {
+		r.status = resp.Status
+		return
+	}
+	r.status = ""
+	if resp.Total > 0 {
+		r.totals[resp.Digest] = resp.Total
+	}
+	r.completed[resp.Digest] = resp.Completed
+
+	r.samples = append(r.samples, rateSample{at: now, bytes: r.transferred()})
+	// Keep one sample older than the window so the average always spans it
+	for len(r.samples) > 2 && now.Sub(r.samples[1].at) >= rateWindow {
+		r.samples = r.samples[1:]
+	}
+}
+
+func (r *transferRate) transferred() int64 {
+	var bytes int64
+	for _, completed := range r.completed {
+		bytes += completed
+	}
+	return bytes
+}
+
+func (r *transferRate) total() int64 {
+	var bytes int64
+	for _, total := range r.totals {
+		bytes += total
+	}
+	return bytes
+}
+
+// bytesPerSecond returns the average speed over the samples, 0 until there are two far enough apart to tell
+func (r *transferRate) bytesPerSecond() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 || last.bytes < first.bytes {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / elapsed
+}
+
+// summary describes the transfer for the line under the progress bar: the speed, time left and bytes transferred, or
+// the status while Ollama reports one without any bytes, e.g. "verifying sha256 digest"
+func (r *transferRate) summary(fraction float64) string {
+	parts := []string{fmt.Sprintf("%.0f%%", fraction*100)}
+	if r.status != "" {
+		return strings.Join(append(parts, r.status), " — ")
+	}
+	transferred, total := r.transferred(), r.total()
+	if speed := r.bytesPerSecond(); speed > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f MB/s", speed/1e6))
+		if total > transferred {
+			eta := time.Duration(float64(total-transferred) / speed * float64(time.Second))
+			parts = append(parts, "ETA "+eta.Round(time.Second).String())
+		}
+	}
+	if total > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f/%.1f GB", float64(transferred)/1e9, float64(total)/1e9))
+	}
+	return strings.Join(parts, " — ")
+}
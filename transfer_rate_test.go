@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestTransferRate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time { return start.Add(time.Duration(seconds * float64(time.Second))) }
+	rate := newTransferRate()
+
+	if got := rate.summary(0); got != "0%" {
+		t.Errorf("summary before any progress = %q", got)
+	}
+
+	// The first layer's size isn't known straight away
+	rate.add(api.ProgressResponse{Status: "pulling manifest"}, at(0))
+	if got := rate.summary(0); got != "0% — pulling manifest" {
+		t.Errorf("summary of a status only response = %q", got)
+	}
+	rate.add(api.ProgressResponse{Digest: "sha256:a", Completed: 0}, at(0))
+	rate.add(api.ProgressResponse{Digest: "sha256:a", Total: 10e9, Completed: 1e9}, at(1))
+	if got, want := rate.summary(0.1), "10% — 1000.0 MB/s — ETA 9s — 1.0/10.0 GB"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+
+	// The next layer changes the total, its Completed starting from 0 doesn't count as going backwards
+	rate.add(api.ProgressResponse{Digest: "sha256:a", Total: 10e9, Completed: 10e9}, at(4))
+	rate.add(api.ProgressResponse{Digest: "sha256:b", Total: 4.5e9, Completed: 0}, at(4))
+	rate.add(api.ProgressResponse{Digest: "sha256:b", Total: 4.5e9, Completed: 1e9}, at(5))
+	if got, want := rate.summary(0.76), "76% — 2200.0 MB/s — ETA 2s — 11.0/14.5 GB"; got != want {
+		t.Errorf("summary across layers = %q, want %q", got, want)
+	}
+
+	// The average only looks back rateWindow, so it follows a slowdown
+	rate.add(api.ProgressResponse{Digest: "sha256:b", Total: 4.5e9, Completed: 1.5e9}, at(15))
+	rate.add(api.ProgressResponse{Digest: "sha256:b", Total: 4.5e9, Completed: 2e9}, at(25))
+	if got, want := rate.summary(0.83), "83% — 50.0 MB/s — ETA 50s — 12.0/14.5 GB"; got != want {
+		t.Errorf("summary after a slowdown = %q, want %q", got, want)
+	}
+
+	// Verifying shows the status rather than a speed, until bytes move again
+	rate.add(api.ProgressResponse{Digest: "sha256:b", Total: 4.5e9, Completed: 4.5e9}, at(30))
+	rate.add(api.ProgressResponse{Status: "verifying sha256 digest"}, at(31))
+	if got, want := rate.summary(1), "100% — verifying sha256 digest"; got != want {
+		t.Errorf("summary while verifying = %q, want %q", got, want)
+	}
+}
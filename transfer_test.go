@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestTransferModel(t *testing.T) {
+	t.Setenv("OLLAMA_MODELS", "")
+	modelsDir := t.TempDir()
+	modelDigest, projectorDigest := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	modelBlob := filepath.Join(modelsDir, "blobs", "sha256-"+modelDigest)
+	projectorBlob := filepath.Join(modelsDir, "blobs", "sha256-"+projectorDigest)
+	os.MkdirAll(filepath.Join(modelsDir, "blobs"), 0o755)
+	os.WriteFile(modelBlob, []byte("model weights"), 0o644)
+	os.WriteFile(projectorBlob, []byte("projector"), 0o644)
+
+	modelfile := "FROM " + modelBlob + "\nFROM " + projectorBlob + "\nTEMPLATE \"\"\"{{ .Prompt }}\"\"\"\nPARAMETER num_ctx 8192\n"
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfile})
+	}))
+	defer local.Close()
+	u, _ := url.Parse(local.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	uploaded := make(map[string]string)
+	var created api.CreateRequest
+	// The remote host is only trusted through the client it's reached with
+	remote := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			// The remote host already has the projector
+			if strings.HasSuffix(r.URL.Path, projectorDigest) {
+				return
+			}
+			http.NotFound(w, r)
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			data, _ := io.ReadAll(r.Body)
+			uploaded[strings.TrimPrefix(r.URL.Path, "/api/blobs/")] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/api/create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"status":"success"}` + "\n"))
+		}
+	}))
+	defer remote.Close()
+
+	var updates []api.ProgressResponse
+	progress := func(resp api.ProgressResponse) error {
+		updates = append(updates, resp)
+		return nil
+	}
+	if err := transferModel(context.Background(), client, remote.Client(), "llava:7b", modelsDir, remote.URL, progress); err != nil {
+		t.Fatalf("transferModel() error: %v", err)
+	}
+	if len(uploaded) != 1 || uploaded["sha256:"+modelDigest] != "model weights" {
+		t.Errorf("uploaded blobs = %v, want only the model blob", uploaded)
+	}
+	wantFiles := map[string]string{"model.gguf": "sha256:" + modelDigest, "projector-1.gguf": "sha256:" + projectorDigest}
+	if created.Model != "llava:7b" || created.From != "" || !maps.Equal(created.Files, wantFiles) || created.Template != "{{ .Prompt }}" || created.Parameters["num_ctx"] != float64(8192) {
+		t.Errorf("create request = %+v, want the files %v with the template and parameters", created, wantFiles)
+	}
+	layers := newPushLayers()
+	var total float64
+	for _, update := range updates {
+		total = layers.progress(update, total)
+	}
+	if total != 1 {
+		t.Errorf("progress after the transfer = %v, want 1", total)
+	}
+
+	os.Remove(modelBlob)
+	uploaded = make(map[string]string)
+	err := transferModel(context.Background(), client, remote.Client(), "llava:7b", modelsDir, remote.URL, progress)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(modelsDir, "blobs")) {
+		t.Errorf("transferModel() with a missing blob error = %v, want the searched paths", err)
+	}
+	if len(uploaded) != 0 {
+		t.Errorf("uploaded blobs = %v, want nothing uploaded when a blob is missing", uploaded)
+	}
+}
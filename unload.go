@@ -0,0 +1,112 @@
+// unload.go implements -u, unloading every running model or just the one named, and reports the VRAM freed.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// matchRunningModel finds the running model a name refers to: an exact match (with or without the :latest tag),
+// otherwise the only running model whose name starts with it
+func matchRunningModel(running []api.ProcessModelResponse, name string) (api.ProcessModelResponse, error) {
+	for _, model := range running {
+		if model.Name == name || model.Name == normaliseModelName(name) {
+			return model, nil
+		}
+	}
+
+	var matches []api.ProcessModelResponse
+	for _, model := range running {
+		if strings.HasPrefix(model.Name, name) {
+			matches = append(matches, model)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return api.ProcessModelResponse{}, fmt.Errorf("%s isn't loaded", name)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, model := range matches {
+		names[i] = model.Name
+	}
+	sort.Strings(names)
+	return api.ProcessModelResponse{}, fmt.Errorf("%s matches more than one loaded model: %s", name, strings.Join(names, ", "))
+}
+
+// vramFreed returns the VRAM used by the unloaded models that are no longer running
+func vramFreed(unloaded []api.ProcessModelResponse, after []api.ProcessModelResponse) int64 {
+	stillRunning := make(map[string]bool, len(after))
+	for _, model := range after {
+		stillRunning[model.Name] = true
+	}
+	var freed int64
+	for _, model := range unloaded {
+		if !stillRunning[model.Name] {
+			freed += model.SizeVRAM
+		}
+	}
+	return freed
+}
+
+// runUnload implements -u, unloading every running model or only the one name refers to, and returns the process exit code
+func runUnload(ctx context.Context, client *api.Client, apiURL, name string, out io.Writer) int {
+	before, err := client.ListRunning(ctx)
+	if err != nil {
+		logging.ErrorLogger.Printf("Error fetching running models: %v", err)
+		fmt.Fprintf(out, "Error fetching running models: %v\n", err)
+		return 1
+	}
+	running := sanitiseRunningModels(before)
+
+	targets := running
+	if name != "" {
+		model, err := matchRunningModel(running, name)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			return 1
+		}
+		targets = []api.ProcessModelResponse{model}
+	}
+
+	var unloaded []api.ProcessModelResponse
+	var unloadedNames []string
+	failed := false
+	for _, model := range targets {
+		if _, err := unloadModel(client, apiURL, model.Name); err != nil {
+			logging.ErrorLogger.Printf("Error unloading model %s: %v\n", model.Name, err)
+			fmt.Fprintf(out, "Error unloading model %s: %v\n", model.Name, err)
+			failed = true
+			continue
+		}
+		logging.InfoLogger.Printf("Model %s unloaded\n", model.Name)
+		unloaded = append(unloaded, model)
+		unloadedNames = append(unloadedNames, lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB6C1")).Render(model.Name))
+	}
+
+	if len(unloaded) == 0 {
+		if !failed {
+			fmt.Fprintln(out, "No models to unload")
+		}
+	} else {
+		var after []api.ProcessModelResponse
+		if resp, err := client.ListRunning(ctx); err == nil {
+			after = sanitiseRunningModels(resp)
+		}
+		logging.InfoLogger.Printf("Unloaded models: %v\n", unloadedNames)
+		fmt.Fprintf(out, "Unloaded models: %v, freed %s of VRAM\n", unloadedNames, formatBytes(vramFreed(unloaded, after)))
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestMatchRunningModel(t *testing.T) {
+	running := func(names ...string) []api.ProcessModelResponse {
+		var models []api.ProcessModelResponse
+		for _, name := range names {
+			models = append(models, api.ProcessModelResponse{Name: name})
+		}
+		return models
+	}
+	models := running("qwen2:72b", "qwen2:7b", "llama3:latest", "llama3-gradient:8b")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"qwen2:72b", "qwen2:72b", ""},
+		{"llama3", "llama3:latest", ""},
+		{"llama3-g", "llama3-gradient:8b", ""},
+		{"qwen2:7", "", "qwen2:72b, qwen2:7b"},
+		{"mistral", "", "isn't loaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchRunningModel(models, tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("matchRunningModel(%q) error = %v, want it to mention %q", tt.name, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Name != tt.want {
+				t.Errorf("matchRunningModel(%q) = %s, %v, want %s", tt.name, got.Name, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUnload(t *testing.T) {
+	loaded := map[string]int64{"qwen2:72b": 40 << 30, "llama3:latest": 5 << 30}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ps":
+			var models []api.ProcessModelResponse
+			for name, vram := range loaded {
+				models = append(models, api.ProcessModelResponse{Name: name, Size: vram, SizeVRAM: vram})
+			}
+			json.NewEncoder(w).Encode(api.ProcessResponse{Models: models})
+		case "/api/show":
+			w.Write([]byte(`{"capabilities":["completion"]}`))
+		case "/api/generate":
+			var req api.GenerateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			delete(loaded, req.Model)
+			w.Write([]byte(`{"done":true}`))
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	var out strings.Builder
+	if code := runUnload(context.Background(), client, server.URL, "qwen2", &out); code != 0 {
+		t.Fatalf("runUnload() = %d, output %q", code, out.String())
+	}
+	if _, ok := loaded["qwen2:72b"]; ok || len(loaded) != 1 {
+		t.Errorf("loaded models = %v, want only llama3 left", loaded)
+	}
+	if !strings.Contains(out.String(), "freed 40.0 GiB of VRAM") {
+		t.Errorf("output = %q, want the VRAM freed", out.String())
+	}
+
+	out.Reset()
+	if code := runUnload(context.Background(), client, server.URL, "qwen2", &out); code == 0 || !strings.Contains(out.String(), "isn't loaded") {
+		t.Errorf("runUnload() of a model that isn't loaded = %d, %q, want a non-zero exit code", code, out.String())
+	}
+}
@@ -0,0 +1,424 @@
+// update_all.go implements -update-all and ctrl+u, which pull the registry models whose manifest in the registry no
+// longer matches the local one, one at a time. Models whose template, system prompt or parameters differ from the
+// registry's are skipped as pulling would replace them, unless -keep-config restores them afterwards the way ctrl+k
+// does. A model that hasn't changed since gollama last pulled it isn't customised, whatever the registry ships now.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// modelUpdate is whether a registry model has a newer version and what pulling it would download
+type modelUpdate struct {
+	Model        string
+	Stale        bool
+	LocalDigest  string    // the local manifest digest, without the sha256: prefix
+	RemoteDigest string    // the registry's manifest digest, set once the registry has been reached
+	LastPulled   time.Time // when gollama last recorded pulling the model, zero when it never has
+	Download     int64     // size of the registry's layers the model doesn't have, all of them when its manifest can't be read
+	Customised   []string  // modelfile instructions that differ from the registry's
+	Err          error
+}
+
+// updateCheckMsg is the result of checking the registry models for updates in the TUI
+type updateCheckMsg struct {
+	updates []modelUpdate
+	err     error
+}
+
+// updateAllRun is the models ctrl+u is pulling, one at a time through the pull progress bar
+type updateAllRun struct {
+	queue   []string
+	current string
+	total   int
+	pulled  int
+	updated []string // digest changes of the models pulled
+	failed  []string
+	skipped []string
+	updates map[string]modelUpdate
+}
+
+// registryManifestURL is the manifest of a model in the registry at baseURL, with the latest tag when it has none
+func registryManifestURL(baseURL, modelName string) string {
+	_, tag := splitModelTag(modelName)
+	if tag == "" {
+		tag = "latest"
+	}
+	return fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL, registryRepository(modelName), tag)
+}
+
+// headManifestDigest returns the digest the registry reports for a manifest without fetching it, empty when the
+// registry doesn't send one
+func headManifestDigest(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errNotInRegistry
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry returned %s", resp.Status)
+	}
+	return strings.TrimPrefix(resp.Header.Get("Docker-Content-Digest"), "sha256:"), nil
+}
+
+// getManifest fetches a manifest from the registry and returns it with its digest, which Ollama takes from the
+// manifest's bytes as the registry sent them
+func getManifest(ctx context.Context, client *http.Client, url string) (string, *modelManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil, errNotInRegistry
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("registry returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	var manifest modelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil, fmt.Errorf("error decoding manifest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), &manifest, nil
+}
+
+// checkModelUpdate compares a model's digest with the registry's, and for a stale model works out the download and
+// whether its config has been changed from the registry's
+func checkModelUpdate(ctx context.Context, client *api.Client, registryClient *http.Client, baseURL, modelsDir string, model Model) modelUpdate {
+	local := strings.TrimPrefix(model.Digest, "sha256:")
+	update := modelUpdate{Model: model.Name, LocalDigest: local}
+	pulls, _ := pullHistory(model.Name, 1)
+	if len(pulls) > 0 {
+		update.LastPulled = pulls[0].Time
+	}
+	url := registryManifestURL(baseURL, model.Name)
+	digest, err := headManifestDigest(ctx, registryClient, url)
+	if err != nil {
+		update.Err = err
+		return update
+	}
+	update.RemoteDigest = digest
+	if digest == local {
+		return update
+	}
+	digest, manifest, err := getManifest(ctx, registryClient, url)
+	if err != nil {
+		update.Err = err
+		return update
+	}
+	update.RemoteDigest = digest
+	if digest == local {
+		return update
+	}
+	update.Stale = true
+
+	have := make(map[string]bool)
+	if current, err := readManifest(modelsDir, model.Name); err == nil {
+		for _, layer := range append(current.Layers, current.Config) {
+			have[layer.Digest] = true
+		}
+	}
+	for _, layer := range append(manifest.Layers, manifest.Config) {
+		if !have[layer.Digest] {
+			update.Download += layer.Size
+		}
+	}
+
+	if len(pulls) > 0 && strings.TrimPrefix(pulls[0].Digest, "sha256:") == local {
+		return update
+	}
+	show, err := showcache.For(client).Show(ctx, model.Name)
+	if err != nil {
+		update.Err = fmt.Errorf("error fetching the modelfile: %w", err)
+		return update
+	}
+	registry, err := fetchRegistryModelfileValues(ctx, registryClient, baseURL, model.Name)
+	if err != nil {
+		update.Err = fmt.Errorf("error fetching the registry's modelfile: %w", err)
+		return update
+	}
+	for _, diff := range compareModelfiles(modelfileValues(show.Modelfile), registry) {
+		update.Customised = append(update.Customised, diff.Command)
+	}
+	return update
+}
+
+// checkModelUpdates checks the registry models among models for updates, a few at a time, in the order given
+func checkModelUpdates(ctx context.Context, client *api.Client, registryClient *http.Client, baseURL, modelsDir string, models []Model) []modelUpdate {
+	var registryModels []Model
+	for _, model := range models {
+		if isRegistryModel(model.Name) {
+			registryModels = append(registryModels, model)
+		}
+	}
+	updates := make([]modelUpdate, len(registryModels))
+	sem := make(chan struct{}, registryManifestFetches)
+	var wg sync.WaitGroup
+	for i, model := range registryModels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			updates[i] = checkModelUpdate(ctx, client, registryClient, baseURL, modelsDir, model)
+		}()
+	}
+	wg.Wait()
+	return updates
+}
+
+// customisedNote explains why a stale model is skipped
+func customisedNote(update modelUpdate) string {
+	return "customised " + strings.Join(update.Customised, ", ")
+}
+
+// lastPulledLabel is the date gollama last pulled the model, or "-" when it never has
+func lastPulledLabel(update modelUpdate) string {
+	if update.LastPulled.IsZero() {
+		return "-"
+	}
+	return update.LastPulled.Format("2006-01-02")
+}
+
+// digestChange describes how pulling a stale model changes its digest, e.g. "llama3:8b 365c0bd3c000 -> 1ebb7b4e9bc0"
+func digestChange(update modelUpdate) string {
+	change := fmt.Sprintf("%s %s -> %s", update.Model, truncate(update.LocalDigest, 12), truncate(update.RemoteDigest, 12))
+	if !update.LastPulled.IsZero() {
+		change += ", last pulled " + lastPulledLabel(update)
+	}
+	return change
+}
+
+// runUpdateAll implements -update-all and returns the exit code
+func runUpdateAll(ctx context.Context, client *api.Client, cfg *config.Config, models []Model, modelsDir string, dryRun, keepConfig bool) int {
+	registryClient, err := config.NewHTTPClient(cfg, registryTimeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	updates := checkModelUpdates(ctx, client, registryClient, registryURL, modelsDir, models)
+	var stale []modelUpdate
+	var skipped []string
+	checkFailed := 0
+	for _, update := range updates {
+		switch {
+		case update.Err != nil:
+			checkFailed++
+			fmt.Fprintf(os.Stderr, "%s: couldn't check for an update: %v\n", update.Model, update.Err)
+		case !update.Stale:
+		case len(update.Customised) > 0 && !keepConfig:
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", update.Model, customisedNote(update)))
+		default:
+			stale = append(stale, update)
+		}
+	}
+	fmt.Printf("%d of %d registry models have updates\n", len(stale)+len(skipped), len(updates))
+	printSkipped := func() {
+		if len(skipped) > 0 {
+			fmt.Printf("\nSkipped %d customised models, pass -keep-config to update them and restore their config:\n  %s\n", len(skipped), strings.Join(skipped, "\n  "))
+		}
+	}
+
+	if dryRun {
+		if len(stale) > 0 {
+			fmt.Println()
+			var total int64
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "Model\tLocal\tRemote\tLast pulled\tDownload\tNote")
+			for _, update := range stale {
+				note := "-"
+				if len(update.Customised) > 0 {
+					note = "keeps its " + strings.Join(update.Customised, ", ")
+				}
+				total += update.Download
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.2fGB\t%s\n", update.Model, truncate(update.LocalDigest, 12), truncate(update.RemoteDigest, 12),
+					lastPulledLabel(update), float64(update.Download)/(1024*1024*1024), note)
+			}
+			w.Flush()
+			fmt.Printf("\nWould update %d models, downloading about %.2fGB\n", len(stale), float64(total)/(1024*1024*1024))
+		}
+		printSkipped()
+		return 0
+	}
+
+	out := func(format string, args ...any) { fmt.Printf(format, args...) }
+	var results []batchPullResult
+	for _, update := range stale {
+		if len(update.Customised) == 0 {
+			results = append(results, pullOne(ctx, client, update.Model, out))
+			continue
+		}
+		snapshot, err := snapshotModelfile(ctx, client, update.Model, snapshotsDir(), time.Now())
+		if err != nil {
+			results = append(results, batchPullResult{Model: update.Model, Err: fmt.Errorf("error saving its modelfile, not pulled: %w", err)})
+			continue
+		}
+		result := pullOne(ctx, client, update.Model, out)
+		if result.Err == nil {
+			if err := restoreSnapshot(ctx, client, update.Model, snapshot); err != nil {
+				result.Err = fmt.Errorf("pulled, but its config wasn't restored, it's saved in %s: %w", snapshot, err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	if len(results) > 0 {
+		fmt.Println()
+		failed = printPullSummary(os.Stdout, results)
+	}
+	printSkipped()
+	if ctx.Err() != nil {
+		fmt.Println("Updating was cancelled, run the same command again to resume")
+		return 130
+	}
+	if failed > 0 || checkFailed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (m *AppModel) handleUpdateAllKey() (tea.Model, tea.Cmd) {
+	logging.DebugLogger.Println("UpdateAll key matched")
+	if m.pulling || m.updateAll != nil {
+		return m, nil
+	}
+	m.message = "Checking the registry models for updates..."
+	client, cfg, modelsDir, models := m.client, m.cfg, m.ollamaModelsDir, m.models
+	return m, func() tea.Msg {
+		registryClient, err := config.NewHTTPClient(cfg, registryTimeout)
+		if err != nil {
+			return updateCheckMsg{err: err}
+		}
+		return updateCheckMsg{updates: checkModelUpdates(context.Background(), client, registryClient, registryURL, modelsDir, models)}
+	}
+}
+
+// handleUpdateCheckMsg starts pulling the stale models that aren't customised
+func (m *AppModel) handleUpdateCheckMsg(msg updateCheckMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Error checking for updates: %v", msg.err)
+		return m, nil
+	}
+	run := &updateAllRun{updates: make(map[string]modelUpdate)}
+	m.staleModels, m.updateCheckedAt = nil, time.Now()
+	for _, update := range msg.updates {
+		if update.Stale && update.Err == nil {
+			m.staleModels = append(m.staleModels, update)
+		}
+		switch {
+		case update.Err != nil:
+			logging.ErrorLogger.Printf("Couldn't check %s for an update: %v\n", update.Model, update.Err)
+			run.failed = append(run.failed, fmt.Sprintf("%s: %v", update.Model, update.Err))
+		case !update.Stale:
+		case len(update.Customised) > 0:
+			run.skipped = append(run.skipped, fmt.Sprintf("%s (%s)", update.Model, customisedNote(update)))
+		default:
+			run.queue = append(run.queue, update.Model)
+			run.updates[update.Model] = update
+		}
+	}
+	run.total = len(run.queue)
+	if run.total == 0 || m.pulling {
+		m.message = run.summary(len(msg.updates))
+		return m, nil
+	}
+	m.updateAll = run
+	return m, m.startNextUpdate()
+}
+
+// clearStale forgets a model the last update check found stale once it has been pulled
+func (m *AppModel) clearStale(modelName string) {
+	m.staleModels = slices.DeleteFunc(m.staleModels, func(update modelUpdate) bool { return update.Model == modelName })
+}
+
+// startNextUpdate pulls the next model in the queue
+func (m *AppModel) startNextUpdate() tea.Cmd {
+	run := m.updateAll
+	run.current, run.queue = run.queue[0], run.queue[1:]
+	m.message = fmt.Sprintf("Updating %d/%d: %s", run.total-len(run.queue), run.total, run.current)
+	m.pulling = true
+	m.pullProgress = 0
+	m.pullLayers = newPullLayers()
+	return m.startPullModel(run.current)
+}
+
+// continueUpdateAll records how the pull of the current model went and starts the next, or shows the summary once
+// they're all done. It does nothing when ctrl+u isn't updating models.
+func (m *AppModel) continueUpdateAll(err error) tea.Cmd {
+	run := m.updateAll
+	if run == nil {
+		return nil
+	}
+	if err != nil {
+		run.failed = append(run.failed, fmt.Sprintf("%s: %v", run.current, err))
+	} else {
+		run.pulled++
+		run.updated = append(run.updated, digestChange(run.updates[run.current]))
+	}
+	if len(run.queue) > 0 {
+		return m.startNextUpdate()
+	}
+	m.updateAll = nil
+	m.message = run.summary(0)
+	return nil
+}
+
+// summary describes the models updated, failed and skipped, checked is the number of models checked when none needed
+// updating
+func (r *updateAllRun) summary(checked int) string {
+	var parts []string
+	if r.total == 0 && len(r.failed) == 0 {
+		parts = append(parts, fmt.Sprintf("All %d registry models are up to date", checked))
+	} else {
+		updated := fmt.Sprintf("Updated %d of %d models", r.pulled, r.total)
+		if len(r.updated) > 0 {
+			updated += " (" + strings.Join(r.updated, "; ") + ")"
+		}
+		parts = append(parts, updated)
+	}
+	if len(r.failed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed (%s)", len(r.failed), strings.Join(r.failed, "; ")))
+	}
+	if len(r.skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("skipped %s, use ctrl+k to pull them keeping their config", strings.Join(r.skipped, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+	"github.com/sammcj/gollama/history"
+)
+
+func TestCheckModelUpdates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manifest := func(layers ...manifestLayer) []byte {
+		data, _ := json.Marshal(modelManifest{Config: manifestLayer{Digest: "sha256:config", Size: 1}, Layers: layers})
+		return data
+	}
+	digestOf := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	weights := manifestLayer{MediaType: modelLayerMediaType, Digest: "sha256:weights", Size: 4000}
+	newWeights := manifestLayer{MediaType: modelLayerMediaType, Digest: "sha256:weights2", Size: 5000}
+	system := manifestLayer{MediaType: systemMediaType, Digest: "sha256:system", Size: 10}
+	current := manifest(weights)
+	manifests := map[string][]byte{
+		"/v2/library/llama3/manifests/8b":   current,
+		"/v2/library/qwen2/manifests/7b":    manifest(newWeights),
+		"/v2/library/mistral/manifests/7b":  manifest(newWeights, system),
+		"/v2/library/phi3/manifests/latest": manifest(newWeights),
+	}
+	var mu sync.Mutex
+	var heads []string
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/library/mistral/blobs/sha256:system" {
+			fmt.Fprint(w, "You are a pirate.")
+			return
+		}
+		data, ok := manifests[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodHead {
+			mu.Lock()
+			heads = append(heads, r.URL.Path)
+			mu.Unlock()
+			// Only some manifests come with their digest, the rest are fetched and hashed
+			if !strings.Contains(r.URL.Path, "llama3") {
+				w.Header().Set("Docker-Content-Digest", "sha256:"+digestOf(data))
+			}
+			return
+		}
+		w.Write(data)
+	}))
+	defer registry.Close()
+
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ShowRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		modelfile := "FROM /blobs/sha256-weights\n"
+		if req.Name == "mistral:7b" {
+			modelfile += `SYSTEM "You are a helpful assistant."` + "\n"
+		}
+		json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: modelfile})
+	}))
+	defer ollama.Close()
+	u, _ := url.Parse(ollama.URL)
+	client := api.NewClient(u, http.DefaultClient)
+
+	// qwen2 already has the config blob, so only the new weights are downloaded
+	modelsDir := t.TempDir()
+	path := manifestPath(modelsDir, "qwen2:7b")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, current, 0644)
+
+	newModel := func(name, digest string) Model {
+		model := Model{}
+		model.Name, model.Digest = name, digest
+		return model
+	}
+	models := []Model{
+		newModel("llama3:8b", digestOf(current)),
+		newModel("qwen2:7b", digestOf(current)),
+		newModel("mistral:7b", digestOf(current)),
+		newModel("registry.internal:5000/team/model:1", "abc"),
+		newModel("gone:latest", "abc"),
+	}
+	updates := checkModelUpdates(context.Background(), client, registry.Client(), registry.URL, modelsDir, models)
+	if len(updates) != 4 {
+		t.Fatalf("checked %d models, want the 4 registry models", len(updates))
+	}
+	if u := updates[0]; u.Stale || u.Err != nil {
+		t.Errorf("llama3:8b = %+v, want up to date", u)
+	}
+	if u := updates[1]; !u.Stale || u.Download != 5000 || len(u.Customised) != 0 || u.Err != nil {
+		t.Errorf("qwen2:7b = %+v, want stale downloading 5000 bytes", u)
+	}
+	newDigest := digestOf(manifest(newWeights))
+	if u := updates[1]; u.LocalDigest != digestOf(current) || u.RemoteDigest != newDigest || !u.LastPulled.IsZero() {
+		t.Errorf("qwen2:7b digests = %q -> %q last pulled %v, want %q -> %q never pulled", u.LocalDigest, u.RemoteDigest, u.LastPulled, digestOf(current), newDigest)
+	}
+	if u := updates[2]; !u.Stale || u.Download != 5011 || !slices.Equal(u.Customised, []string{"SYSTEM"}) {
+		t.Errorf("mistral:7b = %+v, want stale with a customised system prompt", u)
+	}
+	if u := updates[3]; !errors.Is(u.Err, errNotInRegistry) {
+		t.Errorf("gone:latest error = %v, want errNotInRegistry", u.Err)
+	}
+	if len(heads) != 3 {
+		t.Errorf("HEAD requests %q, want one per model in the registry", heads)
+	}
+
+	// A model that hasn't changed since gollama pulled it isn't customised, whatever the registry ships now
+	history.RecordPull("mistral:7b", digestOf(current))
+	if u := checkModelUpdate(context.Background(), client, registry.Client(), registry.URL, modelsDir, models[2]); !u.Stale || len(u.Customised) != 0 || u.LastPulled.IsZero() {
+		t.Errorf("mistral:7b after a recorded pull = %+v, want stale, not customised and last pulled set", u)
+	}
+
+	// -update-all -dry-run shows each model's digests and when it was last pulled
+	defer func(url string) { registryURL = url }(registryURL)
+	registryURL = registry.URL
+	stdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	code := runUpdateAll(context.Background(), client, &config.Config{}, models[:3], modelsDir, true, false)
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+	if code != 0 {
+		t.Errorf("runUpdateAll() dry run = %d, want 0", code)
+	}
+	for _, want := range []string{"Local", "Remote", "Last pulled", "qwen2:7b", digestOf(current)[:12], newDigest[:12],
+		time.Now().Format("2006-01-02")} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("dry run output missing %q:\n%s", want, out)
+		}
+	}
+
+	// ctrl+u pulls the stale models one at a time, skipping customised ones, then sums up
+	m := &AppModel{client: client, cfg: &config.Config{}}
+	pulled := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	phi3 := modelUpdate{Model: "phi3:latest", Stale: true, LocalDigest: "1111111111111111", RemoteDigest: "2222222222222222", LastPulled: pulled}
+	m.handleUpdateCheckMsg(updateCheckMsg{updates: append(updates, phi3)})
+	if m.updateAll == nil || m.updateAll.current != "qwen2:7b" || !m.pulling || m.message != "Updating 1/2: qwen2:7b" {
+		t.Fatalf("after the check %+v pulling %v, message %q", m.updateAll, m.pulling, m.message)
+	}
+	m.handlePullErrorMsg(pullErrorMsg{errors.New("disk full")})
+	if m.updateAll == nil || m.updateAll.current != "phi3:latest" || m.message != "Updating 2/2: phi3:latest" {
+		t.Fatalf("after a failed pull %+v, message %q", m.updateAll, m.message)
+	}
+	m.handlePullSuccessMsg(pullSuccessMsg{modelName: "phi3:latest"})
+	if m.updateAll != nil || !strings.HasPrefix(m.message, "Updated 1 of 2 models (phi3:latest 111111111111 -> 222222222222, last pulled 2026-03-01), 2 failed (gone:latest: ") ||
+		!strings.Contains(m.message, "qwen2:7b: disk full") || !strings.Contains(m.message, "skipped mistral:7b (customised SYSTEM)") {
+		t.Errorf("summary = %q", m.message)
+	}
+}
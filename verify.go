@@ -0,0 +1,318 @@
+// verify.go checks that every layer a model's manifest references is present and intact, e.g. after an interrupted pull.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// defaultRegistry and defaultNamespace are used for manifest paths when a model name doesn't include them
+const (
+	defaultRegistry  = "registry.ollama.ai"
+	defaultNamespace = "library"
+)
+
+// manifestLayer is a blob referenced by a model manifest
+type manifestLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type modelManifest struct {
+	Config manifestLayer   `json:"config"`
+	Layers []manifestLayer `json:"layers"`
+}
+
+// layerProblem is a manifest layer that's missing or doesn't match its blob
+type layerProblem struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"media_type"`
+	Issue     string `json:"issue"`
+}
+
+// verifyResult is the outcome of verifying one model
+type verifyResult struct {
+	Model    string         `json:"model"`
+	Method   string         `json:"method"` // "blobs" for local hosts, "load" for remote hosts
+	Problems []layerProblem `json:"problems,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// OK reports whether the model verified cleanly
+func (r verifyResult) OK() bool {
+	return len(r.Problems) == 0 && r.Error == ""
+}
+
+// manifestPath returns where a model's manifest lives under the models directory
+func manifestPath(modelsDir, modelName string) string {
+	name, tag := normaliseModelName(modelName), "latest"
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		parts = []string{defaultRegistry, defaultNamespace, parts[0]}
+	case 2:
+		parts = []string{defaultRegistry, parts[0], parts[1]}
+	}
+	return filepath.Join(append([]string{modelsDir, "manifests"}, append(parts, tag)...)...)
+}
+
+// blobPath returns where the blob for a digest lives under the models directory
+func blobPath(modelsDir, digest string) string {
+	return filepath.Join(modelsDir, "blobs", strings.Replace(digest, ":", "-", 1))
+}
+
+func readManifest(modelsDir, modelName string) (*modelManifest, error) {
+	data, err := os.ReadFile(manifestPath(modelsDir, modelName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest modelManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error decoding manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// hashProgress reports how far hashing has got through a model's layers, layer counts from 1
+type hashProgress func(layer, layers int, hashed, size int64)
+
+// fileSHA256 returns the hex SHA256 of the file at path, streaming it and calling progress with the bytes hashed so far
+func fileSHA256(ctx context.Context, path string, progress func(hashed int64)) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	buf := make([]byte, 4*1024*1024)
+	var hashed int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		hashed += int64(n)
+		if progress != nil && n > 0 {
+			progress(hashed)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyModelBlobs checks each layer in a model's manifest exists in the blobs directory with the expected size,
+// and when deep is set that its contents hash to its digest
+func verifyModelBlobs(modelsDir, modelName string, deep bool) verifyResult {
+	return verifyModelBlobsContext(context.Background(), modelsDir, modelName, deep, nil)
+}
+
+// verifyModelBlobsContext is verifyModelBlobs with hashing that reports its progress and stops when ctx is
+// cancelled, the result is incomplete once it has been
+func verifyModelBlobsContext(ctx context.Context, modelsDir, modelName string, deep bool, progress hashProgress) verifyResult {
+	result := verifyResult{Model: modelName, Method: "blobs"}
+
+	manifest, err := readManifest(modelsDir, modelName)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read manifest: %v", err)
+		return result
+	}
+
+	layers := manifest.Layers
+	if manifest.Config.Digest != "" {
+		layers = append([]manifestLayer{manifest.Config}, layers...)
+	}
+
+	for i, layer := range layers {
+		problem := layerProblem{Digest: layer.Digest, MediaType: layer.MediaType}
+		path := blobPath(modelsDir, layer.Digest)
+
+		fi, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			problem.Issue = "missing"
+		case err != nil:
+			problem.Issue = err.Error()
+		case fi.Size() != layer.Size:
+			problem.Issue = fmt.Sprintf("size mismatch: expected %d bytes, found %d", layer.Size, fi.Size())
+		case deep:
+			var report func(int64)
+			if progress != nil {
+				report = func(hashed int64) { progress(i+1, len(layers), hashed, layer.Size) }
+			}
+			sum, err := fileSHA256(ctx, path, report)
+			if ctx.Err() != nil {
+				return result
+			}
+			if err != nil {
+				problem.Issue = err.Error()
+			} else if "sha256:"+sum != layer.Digest {
+				problem.Issue = "hash mismatch"
+			}
+		}
+
+		if problem.Issue != "" {
+			logging.DebugLogger.Printf("Layer %s of %s: %s\n", layer.Digest, modelName, problem.Issue)
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+	return result
+}
+
+// verifyModelLoad is the fallback for remote hosts where the blobs aren't reachable: it asks the server to load
+// the model without generating anything and reports whether that failed
+func verifyModelLoad(ctx context.Context, client *api.Client, modelName string) verifyResult {
+	result := verifyResult{Model: modelName, Method: "load"}
+	req := &api.GenerateRequest{
+		Model:     modelName,
+		KeepAlive: &api.Duration{Duration: 0},
+		Options:   map[string]interface{}{"num_predict": 0},
+	}
+	err := client.Generate(ctx, req, func(api.GenerateResponse) error { return nil })
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to load model: %v", err)
+	}
+	return result
+}
+
+// verifyModels verifies each model, checking the blobs directly for local hosts and falling back to a load for remote ones,
+// it stops early if ctx is cancelled
+func verifyModels(ctx context.Context, client *api.Client, modelsDir string, local bool, names []string, deep bool) []verifyResult {
+	results := make([]verifyResult, 0, len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		if local {
+			result := verifyModelBlobsContext(ctx, modelsDir, name, deep, nil)
+			if ctx.Err() != nil {
+				break
+			}
+			results = append(results, result)
+		} else {
+			results = append(results, verifyModelLoad(ctx, client, name))
+		}
+	}
+	return results
+}
+
+// verifyStatus summarises a model's integrity for the inspect view, it only checks existence and size so it's cheap to call
+func (m *AppModel) verifyStatus(modelName string) string {
+	if m.isRemoteHost() != "" || !m.modelsDir.Available {
+		return ""
+	}
+	result := verifyModelBlobs(m.ollamaModelsDir, modelName, false)
+	switch {
+	case result.Error != "":
+		return "Unknown (" + result.Error + ")"
+	case !result.OK():
+		return fmt.Sprintf("%d layer(s) missing or corrupt, run gollama -verify %s", len(result.Problems), modelName)
+	default:
+		return "OK"
+	}
+}
+
+// runVerify implements the -verify flag, printing results and returning the process exit code
+func runVerify(ctx context.Context, client *api.Client, modelsDir string, local bool, names []string, deep, jsonOutput bool) int {
+	results := verifyModels(ctx, client, modelsDir, local, names, deep)
+
+	var broken []string
+	for _, result := range results {
+		if !result.OK() {
+			broken = append(broken, result.Model)
+		}
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding results: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, result := range results {
+			if result.OK() {
+				fmt.Printf("%s: OK\n", result.Model)
+				continue
+			}
+			fmt.Printf("%s: FAILED\n", result.Model)
+			if result.Error != "" {
+				fmt.Printf("  %s\n", result.Error)
+			}
+			for _, problem := range result.Problems {
+				fmt.Printf("  %s (%s): %s\n", problem.Digest, problem.MediaType, problem.Issue)
+			}
+		}
+		fmt.Printf("\n%d of %d models verified OK\n", len(results)-len(broken), len(results))
+
+		// Offer to repair interactively, scripts get the exit code instead
+		if len(broken) > 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+			reader := bufio.NewReader(os.Stdin)
+			for _, name := range broken {
+				fmt.Printf("Re-pull %s to repair it? [y/N] ", name)
+				answer, _ := reader.ReadString('\n')
+				if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+					continue
+				}
+				if err := repullModel(ctx, client, name); err != nil {
+					fmt.Printf("Failed to re-pull %s: %v\n", name, err)
+				} else {
+					fmt.Printf("Re-pulled %s\n", name)
+				}
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Println("Verification was cancelled")
+		return 130
+	}
+	if len(broken) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// repullModel pulls a model again so any missing layers are downloaded, if it's cancelled Ollama keeps the
+// partial download and resumes it on the next pull
+func repullModel(ctx context.Context, client *api.Client, modelName string) error {
+	lastPercent := -1
+	err := client.Pull(ctx, &api.PullRequest{Name: modelName}, func(resp api.ProgressResponse) error {
+		if resp.Total > 0 {
+			if percent := int(resp.Completed * 100 / resp.Total); percent/10 != lastPercent/10 {
+				lastPercent = percent
+				fmt.Printf("  %s: %d%%\n", resp.Status, percent)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	recordPull(client, modelName)
+	return nil
+}
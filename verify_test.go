@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestPath(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"llama3", "manifests/registry.ollama.ai/library/llama3/latest"},
+		{"llama3:8b", "manifests/registry.ollama.ai/library/llama3/8b"},
+		{"sammcj/model:q4", "manifests/registry.ollama.ai/sammcj/model/q4"},
+		{"hf.co/org/model:Q8_0", "manifests/hf.co/org/model/Q8_0"},
+		{"localhost:5000/ns/model", "manifests/localhost:5000/ns/model/latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := manifestPath("/models", tt.model); got != filepath.Join("/models", tt.want) {
+				t.Errorf("manifestPath(%q) = %s, want %s", tt.model, got, filepath.Join("/models", tt.want))
+			}
+		})
+	}
+}
+
+func TestVerifyModelBlobs(t *testing.T) {
+	writeBlob := func(dir string, data []byte) manifestLayer {
+		sum := sha256.Sum256(data)
+		layer := manifestLayer{MediaType: "application/vnd.ollama.image.model", Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+		path := blobPath(dir, layer.Digest)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return layer
+	}
+	writeManifest := func(dir, model string, layers ...manifestLayer) {
+		path := manifestPath(dir, model)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(modelManifest{Layers: layers})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(dir string) []manifestLayer
+		deep      bool
+		wantIssue string
+		wantError bool
+	}{
+		{
+			name: "intact",
+			setup: func(dir string) []manifestLayer {
+				return []manifestLayer{writeBlob(dir, []byte("weights")), writeBlob(dir, []byte("template"))}
+			},
+			deep: true,
+		},
+		{
+			name: "missing layer",
+			setup: func(dir string) []manifestLayer {
+				layer := writeBlob(dir, []byte("weights"))
+				os.Remove(blobPath(dir, layer.Digest))
+				return []manifestLayer{layer}
+			},
+			wantIssue: "missing",
+		},
+		{
+			name: "truncated layer",
+			setup: func(dir string) []manifestLayer {
+				layer := writeBlob(dir, []byte("weights"))
+				os.WriteFile(blobPath(dir, layer.Digest), []byte("wei"), 0644)
+				return []manifestLayer{layer}
+			},
+			wantIssue: "size mismatch: expected 7 bytes, found 3",
+		},
+		{
+			name: "corrupt layer only found with deep",
+			setup: func(dir string) []manifestLayer {
+				layer := writeBlob(dir, []byte("weights"))
+				os.WriteFile(blobPath(dir, layer.Digest), []byte("WEIGHTS"), 0644)
+				return []manifestLayer{layer}
+			},
+			deep:      true,
+			wantIssue: "hash mismatch",
+		},
+		{
+			name:      "no manifest",
+			setup:     func(dir string) []manifestLayer { return nil },
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if layers := tt.setup(dir); layers != nil {
+				writeManifest(dir, "test:latest", layers...)
+			}
+			result := verifyModelBlobs(dir, "test", tt.deep)
+			if (result.Error != "") != tt.wantError {
+				t.Fatalf("verifyModelBlobs() error = %q, wantError %v", result.Error, tt.wantError)
+			}
+			switch {
+			case tt.wantIssue == "" && len(result.Problems) != 0:
+				t.Errorf("verifyModelBlobs() problems = %+v, want none", result.Problems)
+			case tt.wantIssue != "" && (len(result.Problems) != 1 || result.Problems[0].Issue != tt.wantIssue):
+				t.Errorf("verifyModelBlobs() problems = %+v, want one with issue %q", result.Problems, tt.wantIssue)
+			}
+			if result.OK() != (tt.wantIssue == "" && !tt.wantError) {
+				t.Errorf("OK() = %v", result.OK())
+			}
+		})
+	}
+}
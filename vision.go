@@ -0,0 +1,117 @@
+// vision.go finds the files a model's modelfile loads. Vision models have a projector in a second FROM line that's
+// loaded alongside the weights, so the inspect view shows it and vRAM estimates include it.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/showcache"
+)
+
+// modelFiles are the blobs a model loads, its weights followed by any vision projectors, with their sizes in bytes.
+// A size is 0 when the blob isn't in a local models directory, e.g. on a remote host.
+type modelFiles struct {
+	Paths []string
+	Sizes []int64
+}
+
+// fromPaths returns the paths in the FROM lines of a modelfile, in order
+func fromPaths(modelfile string) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(modelfile), "\n") {
+		if strings.HasPrefix(line, "FROM ") {
+			paths = append(paths, strings.TrimSpace(line[5:]))
+		}
+	}
+	return paths
+}
+
+// readModelFiles resolves the FROM lines of a modelfile against dirs and reads the size of each blob
+func readModelFiles(modelfile string, dirs []string) modelFiles {
+	var files modelFiles
+	for _, path := range fromPaths(modelfile) {
+		var size int64
+		if resolved, err := resolveBlobPath(path, dirs); err == nil {
+			path = resolved
+			if fi, err := os.Stat(resolved); err == nil {
+				size = fi.Size()
+			}
+		} else {
+			logging.DebugLogger.Printf("Not reading the size of %s: %v\n", path, err)
+		}
+		files.Paths = append(files.Paths, path)
+		files.Sizes = append(files.Sizes, size)
+	}
+	return files
+}
+
+// getModelFiles returns the blobs a model's modelfile loads
+func getModelFiles(modelName, ollamaModelsDir string, client *api.Client) (modelFiles, error) {
+	resp, err := showcache.For(client).Show(context.Background(), modelName)
+	if err != nil {
+		return modelFiles{}, fmt.Errorf("error getting the modelfile of %s: %w", modelName, err)
+	}
+	return readModelFiles(resp.Modelfile, blobSearchDirs(ollamaModelsDir)), nil
+}
+
+// Vision reports whether the model has a vision projector
+func (f modelFiles) Vision() bool {
+	return len(f.Paths) > 1
+}
+
+// ModelSize is the size of the model's weights
+func (f modelFiles) ModelSize() int64 {
+	if len(f.Sizes) == 0 {
+		return 0
+	}
+	return f.Sizes[0]
+}
+
+// ProjectorSize is the combined size of the model's vision projectors
+func (f modelFiles) ProjectorSize() int64 {
+	var size int64
+	for _, s := range f.Sizes[min(1, len(f.Sizes)):] {
+		size += s
+	}
+	return size
+}
+
+// TotalSize is the size of every file the model loads
+func (f modelFiles) TotalSize() int64 {
+	return f.ModelSize() + f.ProjectorSize()
+}
+
+// inspectFiles are the files of the inspected model, fetched when the inspect view opens rather than on each render
+type inspectFiles struct {
+	model string
+	files modelFiles
+	err   error
+}
+
+type inspectFilesMsg inspectFiles
+
+// fetchInspectFiles reads the files of the inspected model in the background
+func (m *AppModel) fetchInspectFiles(modelName string) tea.Cmd {
+	modelsDir, client := m.ollamaModelsDir, m.client
+	return func() tea.Msg {
+		files, err := getModelFiles(modelName, modelsDir, client)
+		return inspectFilesMsg{model: modelName, files: files, err: err}
+	}
+}
+
+func (m *AppModel) handleInspectFilesMsg(msg inspectFilesMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logging.ErrorLogger.Printf("Error getting model files: %v\n", msg.err)
+	}
+	if m.inspecting && m.inspectedModel.Name == msg.model {
+		m.inspectFiles = inspectFiles(msg)
+	}
+	return m, nil
+}
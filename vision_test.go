@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ollama/ollama/api"
+
+	"github.com/sammcj/gollama/config"
+)
+
+func TestReadModelFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, "blobs", name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, make([]byte, size), 0o644)
+		return path
+	}
+	model, projector, extra := write("sha256-model", 1000), write("sha256-projector", 300), write("sha256-extra", 20)
+
+	tests := []struct {
+		name          string
+		modelfile     string
+		wantVision    bool
+		wantModel     int64
+		wantProjector int64
+	}{
+		{"no FROM lines", "TEMPLATE {{ .Prompt }}\n", false, 0, 0},
+		{"one FROM line", "FROM " + model + "\nPARAMETER num_ctx 8192\n", false, 1000, 0},
+		{"vision model", "FROM " + model + "\nFROM " + projector + "\n", true, 1000, 300},
+		{"several projectors", "FROM " + model + "\nFROM " + projector + "\nFROM " + extra + "\n", true, 1000, 320},
+		// Blobs stored by the server elsewhere are found in the models directory, ones that aren't anywhere count as 0
+		{"moved blobs", "FROM /srv/ollama/blobs/sha256-model\nFROM /srv/ollama/blobs/sha256-missing\n", true, 1000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := readModelFiles(tt.modelfile, []string{dir})
+			if files.Vision() != tt.wantVision || files.ModelSize() != tt.wantModel || files.ProjectorSize() != tt.wantProjector {
+				t.Errorf("readModelFiles() vision %v, model %d, projector %d, want %v, %d, %d",
+					files.Vision(), files.ModelSize(), files.ProjectorSize(), tt.wantVision, tt.wantModel, tt.wantProjector)
+			}
+			if got := files.TotalSize(); got != tt.wantModel+tt.wantProjector {
+				t.Errorf("TotalSize() = %d, want %d", got, tt.wantModel+tt.wantProjector)
+			}
+		})
+	}
+}
+
+func TestInspectVisionRows(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "blobs"), 0o755)
+	os.WriteFile(filepath.Join(dir, "blobs", "sha256-model"), make([]byte, 1000), 0o644)
+	os.WriteFile(filepath.Join(dir, "blobs", "sha256-projector"), make([]byte, 300), 0o644)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(api.ShowResponse{Modelfile: "FROM /srv/blobs/sha256-model\nFROM /srv/blobs/sha256-projector\n"})
+		default:
+			json.NewEncoder(w).Encode(api.ProcessResponse{})
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	model := Model{}
+	model.Name = "llava:latest"
+	m := &AppModel{
+		cfg:             &config.Config{OllamaAPIURL: server.URL},
+		client:          api.NewClient(u, http.DefaultClient),
+		ollamaModelsDir: dir,
+		list:            list.New([]list.Item{model}, list.NewDefaultDelegate(), 80, 40),
+	}
+
+	_, cmd := m.handleInspectModelKey()
+	hasVision := func() bool {
+		rows, _ := m.inspectRows(m.inspectedModel)
+		return slices.ContainsFunc(rows, func(row table.Row) bool { return row[1] == "📷 vision" })
+	}
+	if hasVision() {
+		t.Error("vision rows shown before the files were fetched")
+	}
+	// The files are read once in the background when the view opens
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(inspectFilesMsg); ok {
+			m.Update(msg)
+		}
+	}
+	if !hasVision() {
+		t.Errorf("inspect rows don't show the projector once the files are fetched: %+v", m.inspectFiles)
+	}
+}
@@ -0,0 +1,252 @@
+// vram.go renders vRAM estimation tables for the command line, the estimation itself lives in the vramestimator package.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/sammcj/gollama/logging"
+	"github.com/sammcj/gollama/styles"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+const vramDescription = `
+VRAM Estimation Format:
+For context sizes ≥ 16K: F16(Q8_0,Q4_0)
+- F16: Base model with FP16 KV cache
+- Q8_0: Model with Q8_0 KV cache quantisation
+- Q4_0: Model with Q4_0 KV cache quantisation
+
+For context sizes < 16K: Single F16 value shown
+`
+
+const vramSingleKVCacheDescription = `
+VRAM Estimation Format:
+GB with a %s K/V cache at each context size
+`
+
+var vramColourMap = []string{
+	"#ff0000", // red
+	"#00ff00", // green
+}
+
+// formatVRAMTable renders a vRAM estimation table for the terminal
+func formatVRAMTable(table vramestimator.QuantResultTable) string {
+	var buf bytes.Buffer
+
+	// Add the description header
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#87CEEB")). // Light blue for better readability
+		Bold(true)
+
+	description := vramDescription
+	if table.KVCacheQuant != "" {
+		description = fmt.Sprintf(vramSingleKVCacheDescription, strings.ToUpper(string(table.KVCacheQuant)))
+	}
+	buf.WriteString(headerStyle.Render(description))
+	buf.WriteString("\n")
+
+	tw := tablewriter.NewWriter(&buf)
+
+	// Get context sizes from the first result
+	var contextSizes []int
+	if len(table.Results) > 0 {
+		for context := range table.Results[0].Contexts {
+			contextSizes = append(contextSizes, context)
+		}
+		sort.Ints(contextSizes)
+	}
+
+	// Set table header
+	header := []string{"QUANT", "BPW"}
+	for _, context := range contextSizes {
+		header = append(header, contextLabel(context))
+	}
+	// The largest context each quant fits at is only worked out for a memory constraint that was asked for
+	showMaxContext := table.FitsGiven && table.FitsVRAM > 0
+	if showMaxContext {
+		header = append(header, fmt.Sprintf("max ctx @ %.1fGB", table.FitsVRAM))
+	}
+	tw.SetHeader(header)
+
+	// Update table style for better readability
+	tw.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	tw.SetCenterSeparator("|")
+	tw.SetColumnSeparator("|")
+	tw.SetRowSeparator("-")
+	tw.SetAutoWrapText(false)
+	tw.SetAutoFormatHeaders(true)
+
+	// Enhanced header colours, tablewriter writes its own escape sequences so they're left out for plain output
+	if !styles.Colourless() {
+		headerColours := make([]tablewriter.Colors, len(header))
+		for i := range headerColours {
+			headerColours[i] = tablewriter.Colors{tablewriter.FgHiWhiteColor, tablewriter.Bold}
+		}
+		tw.SetHeaderColor(headerColours...)
+	}
+
+	// Prepare data rows with improved formatting
+	for _, result := range table.Results {
+		quantType := result.QuantType
+		if result.ExceedsSmallestGPU {
+			quantType += " ⚠"
+		}
+		row := []string{
+			quantType,
+			fmt.Sprintf("%.2f", result.BPW),
+		}
+
+		// Add VRAM estimates for each context size with improved formatting
+		for _, context := range contextSizes {
+			vram, ok := result.Contexts[context]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+
+			if table.KVCacheQuant != "" {
+				estimate := vram.ForKVCache(table.KVCacheQuant)
+				row = append(row, getColouredVRAM(estimate, fmt.Sprintf("%.1f", estimate), table.FitsVRAM))
+				continue
+			}
+
+			fp16Str := getColouredVRAM(vram.VRAM, fmt.Sprintf("%.1f", vram.VRAM), table.FitsVRAM)
+
+			if context >= 16384 {
+				q8Str := getColouredVRAM(vram.VRAMQ8_0, fmt.Sprintf("%.1f", vram.VRAMQ8_0), table.FitsVRAM)
+				q4Str := getColouredVRAM(vram.VRAMQ4_0, fmt.Sprintf("%.1f", vram.VRAMQ4_0), table.FitsVRAM)
+				combinedStr := fmt.Sprintf("%s(%s,%s)", fp16Str, q8Str, q4Str)
+				row = append(row, combinedStr)
+			} else {
+				row = append(row, fp16Str)
+			}
+		}
+		if showMaxContext {
+			row = append(row, maxContextLabel(result.MaxContext))
+		}
+
+		tw.Append(row)
+	}
+
+	tw.Render()
+
+	// Add model info and memory constraint
+	modelInfo := fmt.Sprintf("📊 VRAM Estimation for Model: %s", table.ModelID)
+	if table.FitsVRAM > 0 {
+		modelInfo += fmt.Sprintf(" (Memory Constraint: %.1f GB)", table.FitsVRAM)
+	}
+	if table.KVCacheQuant != "" {
+		modelInfo += fmt.Sprintf(" (K/V Cache: %s)", table.KVCacheQuant)
+	}
+	if table.ContextSource != "" && len(contextSizes) > 0 {
+		modelInfo += fmt.Sprintf("\nContext up to %s from %s", contextLabel(contextSizes[len(contextSizes)-1]), table.ContextSource)
+	}
+	if gpus := table.GPUs.NumGPUs(); gpus > 1 {
+		modelInfo += fmt.Sprintf("\nSplit across %d GPUs (smallest %.1f GB), estimates are the combined VRAM including the overhead and compute buffer on each GPU",
+			gpus, table.FitsVRAM*table.GPUs.SmallestShare())
+		for _, result := range table.Results {
+			if result.ExceedsSmallestGPU {
+				modelInfo += "\n⚠ a single layer doesn't fit on the smallest GPU"
+				break
+			}
+		}
+	}
+	if warning := weightsWarning(table); warning != "" {
+		modelInfo += "\n" + warning
+	}
+
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#ffffff")).
+		Render(fmt.Sprintf("%s\n\n%s", modelInfo, buf.String()))
+}
+
+func getColouredVRAM(vram float64, vramStr string, fitsVRAM float64) string {
+	var colorIndex int
+	if fitsVRAM > 0 {
+		if vram > fitsVRAM {
+			colorIndex = 0 // Red
+		} else {
+			colorIndex = len(vramColourMap) - 1 // Green
+		}
+	} else {
+		// Calculate color index based on VRAM usage
+		if vram <= 4 {
+			colorIndex = len(vramColourMap) - 1
+		} else if vram >= 24 {
+			colorIndex = 0
+		} else {
+			// Interpolate between 4 and 24 GB
+			colorIndex = len(vramColourMap) - 1 - int((vram-4)/(24-4)*float64(len(vramColourMap)-1))
+		}
+	}
+
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(vramColourMap[colorIndex]))
+	return style.Render(vramStr)
+}
+
+// maxContextLabel formats the largest context a quant fits at, "-" when not even the smallest does
+func maxContextLabel(context int) string {
+	if context <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", context)
+}
+
+// weightsWarning warns about the quants whose weights alone are larger than the memory constraint, as no context
+// will make them fit
+func weightsWarning(table vramestimator.QuantResultTable) string {
+	if table.FitsVRAM <= 0 {
+		return ""
+	}
+	var quants []string
+	for _, result := range table.Results {
+		if result.WeightsSize > table.FitsVRAM {
+			quants = append(quants, result.QuantType)
+		}
+	}
+	switch {
+	case len(quants) == 0:
+		return ""
+	case len(quants) == len(table.Results):
+		return fmt.Sprintf("⚠ the weights alone of every quant are larger than %.1f GB, the model won't fit at any context", table.FitsVRAM)
+	}
+	return fmt.Sprintf("⚠ the weights alone of %s are larger than %.1f GB, they won't fit at any context", strings.Join(quants, ", "), table.FitsVRAM)
+}
+
+// contextLabel formats a context size for a column header, sizes that aren't a whole number of K are shown exactly
+func contextLabel(context int) string {
+	if context >= 1024 && context%1024 == 0 {
+		return fmt.Sprintf("%dK", context/1024)
+	}
+	return fmt.Sprintf("%d", context)
+}
+
+// runningKVCache returns the k/v cache quantisation a model is running with and a note about it for the estimate. The
+// note is empty when the model isn't running, and the quantisation when the server doesn't report it, as Ollama only
+// does in newer versions.
+func runningKVCache(ctx context.Context, apiURL, modelName string) (vramestimator.KVCacheQuantisation, string) {
+	running, err := listRunningModels(ctx, apiURL)
+	if err != nil {
+		logging.DebugLogger.Printf("Not checking the k/v cache of %s: %v\n", modelName, err)
+		return "", ""
+	}
+	model := findRunningModel(running, modelName)
+	if model == nil {
+		return "", ""
+	}
+	if model.KVCacheType == "" {
+		return "", fmt.Sprintf("%s is running with an unknown k/v cache, the server doesn't report it, so its VRAM use may differ from these estimates", modelName)
+	}
+	quant, err := vramestimator.ParseKVCacheQuantisation(model.KVCacheType)
+	if err != nil {
+		return "", fmt.Sprintf("%s is running with a %s k/v cache, which isn't estimated", modelName, model.kvCacheLabel())
+	}
+	return quant, fmt.Sprintf("%s is running with a %s k/v cache", modelName, model.kvCacheLabel())
+}
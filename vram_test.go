@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sammcj/gollama/styles"
+	"github.com/sammcj/gollama/vramestimator"
+)
+
+func TestFormatVRAMTableSingleKVCache(t *testing.T) {
+	table := vramestimator.QuantResultTable{
+		ModelID:      "llama3",
+		KVCacheQuant: vramestimator.KVCacheQ8_0,
+		Results: []vramestimator.QuantResult{{
+			QuantType: "Q4_K_M",
+			BPW:       4.85,
+			Contexts:  map[int]vramestimator.ContextVRAM{32768: {VRAMQ8_0: 7.25}},
+		}},
+	}
+	out := formatVRAMTable(table)
+	if !strings.Contains(out, "7.2") || strings.Contains(out, "(0.0") || !strings.Contains(out, "K/V Cache: q8_0") {
+		t.Errorf("formatVRAMTable() with a q8_0 k/v cache =\n%s\nwant a single q8_0 value per context and the k/v cache in the header", out)
+	}
+
+	table.KVCacheQuant = ""
+	table.Results[0].Contexts[32768] = vramestimator.ContextVRAM{VRAM: 9, VRAMQ8_0: 7.25, VRAMQ4_0: 6}
+	if out := formatVRAMTable(table); !strings.Contains(out, "9.0(7.2,6.0)") {
+		t.Errorf("formatVRAMTable() without a k/v cache quantisation should keep the combined values:\n%s", out)
+	}
+}
+
+func TestFormatVRAMTableSplitWarning(t *testing.T) {
+	table := vramestimator.QuantResultTable{
+		ModelID:  "llama3",
+		FitsVRAM: 48,
+		GPUs:     vramestimator.GPUSetup{Count: 2},
+		Results: []vramestimator.QuantResult{
+			{QuantType: "Q4_K_M", BPW: 4.85, Contexts: map[int]vramestimator.ContextVRAM{8192: {VRAM: 6}}},
+		},
+	}
+	if out := formatVRAMTable(table); strings.Contains(out, "single layer") {
+		t.Errorf("formatVRAMTable() =\n%s\nwarned that a layer doesn't fit when every layer does", out)
+	}
+
+	table.Results[0].ExceedsSmallestGPU = true
+	if out := formatVRAMTable(table); !strings.Contains(out, "single layer doesn't fit on the smallest GPU") {
+		t.Errorf("formatVRAMTable() =\n%s\nwant a warning that a layer doesn't fit on the smallest GPU", out)
+	}
+}
+
+func TestFormatVRAMTableMaxContext(t *testing.T) {
+	defer styles.SetPlain(false)
+	styles.SetPlain(true)
+	table := vramestimator.QuantResultTable{
+		ModelID:   "llama3",
+		FitsVRAM:  8,
+		FitsGiven: true,
+		Results: []vramestimator.QuantResult{
+			{QuantType: "Q4_K_M", BPW: 4.85, WeightsSize: 4.5, MaxContext: 27392, Contexts: map[int]vramestimator.ContextVRAM{8192: {VRAM: 6}}},
+			{QuantType: "F16", BPW: 16, WeightsSize: 14.9, Contexts: map[int]vramestimator.ContextVRAM{8192: {VRAM: 16.4}}},
+		},
+	}
+	out := formatVRAMTable(table)
+	if !strings.Contains(out, "MAX CTX @ 8.0GB") || !strings.Contains(out, "27392") {
+		t.Errorf("formatVRAMTable() =\n%s\nwant a max context column", out)
+	}
+	if !strings.Contains(out, "the weights alone of F16 are larger than 8.0 GB") {
+		t.Errorf("formatVRAMTable() =\n%s\nwant a warning that F16 can't fit", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if cells := strings.Split(line, "|"); len(cells) > 4 && strings.TrimSpace(cells[1]) == "F16" && strings.TrimSpace(cells[4]) != "-" {
+			t.Errorf("F16 row = %q, want - as its max context", line)
+		}
+	}
+
+	table.Results = table.Results[1:]
+	if out := formatVRAMTable(table); !strings.Contains(out, "every quant") {
+		t.Errorf("formatVRAMTable() =\n%s\nwant a warning that no quant fits", out)
+	}
+}
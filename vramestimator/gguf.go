@@ -0,0 +1,254 @@
+package vramestimator
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ggufMagic starts every GGUF file, "GGUF" read as a little endian uint32
+const ggufMagic = 0x46554747
+
+// maxGGUFString bounds the strings read from a GGUF header so a corrupt length can't allocate gigabytes
+const maxGGUFString = 64 * 1024 * 1024
+
+// GGUF metadata value types
+const (
+	ggufUint8 uint32 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// ggufFileTypes maps general.file_type, llama.cpp's llama_ftype, to the quantisation it names
+var ggufFileTypes = map[uint32]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1",
+	10: "Q2_K", 11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M",
+	16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K", 19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K",
+	22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S", 27: "IQ3_M",
+	28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M", 32: "BF16",
+}
+
+// IsGGUFPath reports whether a -vram model is a GGUF file on disk rather than an Ollama or HuggingFace model
+func IsGGUFPath(model string) bool {
+	return strings.HasSuffix(strings.ToLower(model), ".gguf")
+}
+
+// ReadGGUFModelInfo reads the metadata of a local GGUF file into the same shape as the Ollama show API, so it can be
+// estimated like a pulled model. Only the header is read: the key/values and the tensor shapes, which give the
+// parameter count when general.parameter_count isn't set.
+func ReadGGUFModelInfo(path string) (*OllamaModelInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := readGGUFModelInfo(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the GGUF metadata of %s: %w", path, err)
+	}
+	return info, nil
+}
+
+func readGGUFModelInfo(r *bufio.Reader) (*OllamaModelInfo, error) {
+	var header struct {
+		Magic       uint32
+		Version     uint32
+		TensorCount uint64
+		KVCount     uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != ggufMagic {
+		return nil, errors.New("not a GGUF file")
+	}
+	if header.Version < 2 {
+		return nil, fmt.Errorf("GGUF version %d isn't supported", header.Version)
+	}
+
+	info := &OllamaModelInfo{ModelInfo: make(map[string]interface{})}
+	var tokens uint64
+	for i := uint64(0); i < header.KVCount; i++ {
+		key, err := readGGUFString(r)
+		if err != nil {
+			return nil, err
+		}
+		var valueType uint32
+		if err := binary.Read(r, binary.LittleEndian, &valueType); err != nil {
+			return nil, err
+		}
+		if valueType == ggufArray {
+			count, err := skipGGUFArray(r)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %w", key, err)
+			}
+			if key == "tokenizer.ggml.tokens" {
+				tokens = count
+			}
+			continue
+		}
+		value, err := readGGUFValue(r, valueType)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", key, err)
+		}
+		info.ModelInfo[key] = value
+	}
+
+	arch, _ := info.ModelInfo["general.architecture"].(string)
+	info.Details.Family = arch
+	if fileType, ok := info.ModelInfo["general.file_type"].(float64); ok {
+		info.Details.QuantizationLevel = ggufFileTypes[uint32(fileType)]
+	}
+	if _, ok := extractModelInfo(info.ModelInfo, "vocab_size"); !ok && tokens > 0 {
+		info.ModelInfo[arch+".vocab_size"] = float64(tokens)
+	}
+	if _, ok := info.ModelInfo["general.parameter_count"]; !ok {
+		params, err := countGGUFParameters(r, header.TensorCount)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the tensor shapes: %w", err)
+		}
+		info.ModelInfo["general.parameter_count"] = float64(params)
+	}
+	return info, nil
+}
+
+// countGGUFParameters adds up the elements of each tensor from the tensor infos that follow the key/values
+func countGGUFParameters(r *bufio.Reader, tensorCount uint64) (uint64, error) {
+	var params uint64
+	for i := uint64(0); i < tensorCount; i++ {
+		if _, err := readGGUFString(r); err != nil {
+			return 0, err
+		}
+		var dims uint32
+		if err := binary.Read(r, binary.LittleEndian, &dims); err != nil {
+			return 0, err
+		}
+		elements := uint64(1)
+		for d := uint32(0); d < dims; d++ {
+			var size uint64
+			if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+				return 0, err
+			}
+			elements *= size
+		}
+		// The tensor's type and the offset of its data
+		if _, err := r.Discard(4 + 8); err != nil {
+			return 0, err
+		}
+		params += elements
+	}
+	return params, nil
+}
+
+func readGGUFString(r *bufio.Reader) (string, error) {
+	var length uint64
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if length > maxGGUFString {
+		return "", fmt.Errorf("string of %d bytes is too long", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readGGUFValue reads a scalar or string value, numbers are returned as float64 like the show API's JSON
+func readGGUFValue(r *bufio.Reader, valueType uint32) (interface{}, error) {
+	switch valueType {
+	case ggufString:
+		return readGGUFString(r)
+	case ggufBool:
+		v, err := readGGUFNumber[uint8](r)
+		return v != 0, err
+	case ggufUint8:
+		return readGGUFNumber[uint8](r)
+	case ggufInt8:
+		return readGGUFNumber[int8](r)
+	case ggufUint16:
+		return readGGUFNumber[uint16](r)
+	case ggufInt16:
+		return readGGUFNumber[int16](r)
+	case ggufUint32:
+		return readGGUFNumber[uint32](r)
+	case ggufInt32:
+		return readGGUFNumber[int32](r)
+	case ggufFloat32:
+		return readGGUFNumber[float32](r)
+	case ggufUint64:
+		return readGGUFNumber[uint64](r)
+	case ggufInt64:
+		return readGGUFNumber[int64](r)
+	case ggufFloat64:
+		return readGGUFNumber[float64](r)
+	}
+	return nil, fmt.Errorf("unknown value type %d", valueType)
+}
+
+func readGGUFNumber[T uint8 | int8 | uint16 | int16 | uint32 | int32 | float32 | uint64 | int64 | float64](r io.Reader) (float64, error) {
+	var v T
+	err := binary.Read(r, binary.LittleEndian, &v)
+	return float64(v), err
+}
+
+// ggufValueSize is the size of a fixed size value type, 0 for strings and arrays
+func ggufValueSize(valueType uint32) int {
+	switch valueType {
+	case ggufUint8, ggufInt8, ggufBool:
+		return 1
+	case ggufUint16, ggufInt16:
+		return 2
+	case ggufUint32, ggufInt32, ggufFloat32:
+		return 4
+	case ggufUint64, ggufInt64, ggufFloat64:
+		return 8
+	}
+	return 0
+}
+
+// skipGGUFArray reads past an array without keeping it, such as the tokenizer's vocabulary, and returns its length
+func skipGGUFArray(r *bufio.Reader) (uint64, error) {
+	var header struct {
+		Type  uint32
+		Count uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+	if size := ggufValueSize(header.Type); size > 0 {
+		_, err := io.CopyN(io.Discard, r, int64(size)*int64(header.Count))
+		return header.Count, err
+	}
+	for i := uint64(0); i < header.Count; i++ {
+		var err error
+		switch header.Type {
+		case ggufString:
+			_, err = readGGUFString(r)
+		case ggufArray:
+			_, err = skipGGUFArray(r)
+		default:
+			err = fmt.Errorf("unknown array type %d", header.Type)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return header.Count, nil
+}
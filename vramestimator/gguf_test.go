@@ -0,0 +1,82 @@
+package vramestimator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ggufFixture writes a tiny GGUF file: a llama model's key/values, a tokenizer vocabulary and two tensor infos
+func ggufFixture(t *testing.T) string {
+	t.Helper()
+	var b bytes.Buffer
+	write := func(vs ...any) {
+		for _, v := range vs {
+			if s, ok := v.(string); ok {
+				binary.Write(&b, binary.LittleEndian, uint64(len(s)))
+				b.WriteString(s)
+				continue
+			}
+			binary.Write(&b, binary.LittleEndian, v)
+		}
+	}
+
+	write(uint32(ggufMagic), uint32(3), uint64(2), uint64(8))
+	write("general.architecture", ggufString, "llama")
+	write("general.file_type", ggufUint32, uint32(15))
+	write("llama.block_count", ggufUint32, uint32(2))
+	write("llama.context_length", ggufUint32, uint32(4096))
+	write("llama.embedding_length", ggufUint32, uint32(64))
+	write("llama.attention.head_count", ggufUint32, uint32(4))
+	write("tokenizer.ggml.tokens", ggufArray, ggufString, uint64(3), "<s>", "</s>", "hello")
+	write("tokenizer.ggml.token_type", ggufArray, ggufInt32, uint64(3), int32(3), int32(3), int32(1))
+	// Tensor infos: name, dimensions, shape, type and data offset
+	write("token_embd.weight", uint32(2), uint64(64), uint64(3), uint32(12), uint64(0))
+	write("blk.0.attn_q.weight", uint32(2), uint64(64), uint64(64), uint32(12), uint64(192))
+	// Tensor data isn't read
+	b.Write(make([]byte, 32))
+
+	path := filepath.Join(t.TempDir(), "tiny.gguf")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadGGUFModelInfo(t *testing.T) {
+	info, err := ReadGGUFModelInfo(ggufFixture(t))
+	if err != nil {
+		t.Fatalf("ReadGGUFModelInfo() error = %v", err)
+	}
+	if info.Details.Family != "llama" || info.Details.QuantizationLevel != "Q4_K_M" {
+		t.Errorf("details = %+v, want the llama family at Q4_K_M", info.Details)
+	}
+	if got := info.ContextLength(); got != 4096 {
+		t.Errorf("ContextLength() = %d, want 4096", got)
+	}
+
+	config, err := resolveModelConfig("tiny.gguf", info)
+	if err != nil {
+		t.Fatalf("resolveModelConfig() error = %v", err)
+	}
+	want := ModelConfig{NumParams: 4288 / 1e9, MaxPositionEmbeddings: 4096, NumHiddenLayers: 2, HiddenSize: 64, NumKeyValueHeads: 4, NumAttentionHeads: 4, IntermediateSize: 256, VocabSize: 3}
+	if config != want {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+
+	if _, err := CalculateVRAM("tiny.gguf", 0, 2048, "", info); err != nil {
+		t.Errorf("CalculateVRAM() error = %v", err)
+	}
+}
+
+func TestReadGGUFModelInfoNotGGUF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, []byte("not a model, just some text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadGGUFModelInfo(path); err == nil {
+		t.Error("ReadGGUFModelInfo() of a text file succeeded, want an error")
+	}
+}
@@ -0,0 +1,251 @@
+package vramestimator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sammcj/gollama/logging"
+)
+
+// hfBaseURL is where HuggingFace repos and its API are fetched from
+var hfBaseURL = "https://huggingface.co"
+
+// hfClient fetches the file list and GGUF headers of HuggingFace repos
+var hfClient = &http.Client{Timeout: 30 * time.Second}
+
+// errNoGGUFFiles is returned when a repo has no GGUF file to read the metadata of
+var errNoGGUFFiles = errors.New("no GGUF files")
+
+// GGUF metadata is read in ranges starting at ggufFirstRange bytes and doubling up to ggufMaxRange, giving up after
+// maxGGUFHeader bytes so a file with a corrupt header isn't downloaded whole
+const (
+	ggufFirstRange = 1024 * 1024
+	ggufMaxRange   = 16 * 1024 * 1024
+	maxGGUFHeader  = 256 * 1024 * 1024
+)
+
+// ggufConfigFile caches the configuration read from a repo's GGUF metadata next to the downloaded config.json files
+const ggufConfigFile = "gguf_config.json"
+
+// ggufSplitPattern matches the parts of a model split across files, e.g. model-00002-of-00003.gguf
+var ggufSplitPattern = regexp.MustCompile(`-(\d{5})-of-\d{5}\.gguf$`)
+
+// hfRepoID returns the repo of a HuggingFace model ID and the quantisation of its tag, accepting the hf.co/ and
+// huggingface.co/ prefixes Ollama pulls them with, e.g. hf.co/bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M
+func hfRepoID(modelID string) (string, string) {
+	repo := strings.TrimPrefix(strings.TrimPrefix(modelID, "https://"), "http://")
+	for _, host := range []string{"hf.co/", "huggingface.co/"} {
+		repo = strings.TrimPrefix(repo, host)
+	}
+	repo, quant, _ := strings.Cut(repo, ":")
+	return strings.Trim(repo, "/"), quant
+}
+
+// hfHeaders returns the headers that authenticate HuggingFace requests with GetHuggingFaceToken, if there's a token
+func hfHeaders() map[string]string {
+	headers := make(map[string]string)
+	if accessToken := GetHuggingFaceToken(); accessToken != "" {
+		headers["Authorization"] = "Bearer " + accessToken
+	}
+	return headers
+}
+
+// hfStatusError describes a failed HuggingFace request, suggesting a token when the repo is gated or private
+func hfStatusError(what string, resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s: %s, the repo may be gated or private, set HF_TOKEN to a HuggingFace token with access", what, resp.Status)
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: not found", what)
+	}
+	return fmt.Errorf("%s: bad status: %s", what, resp.Status)
+}
+
+// ggufModelConfig builds a repo's configuration from the metadata of one of its GGUF files, for GGUF-only repos
+// without a config.json. Only the file's header is fetched, with range requests, and the result is cached in baseDir.
+func ggufModelConfig(repo, quant, baseDir string, headers map[string]string) (ModelConfig, error) {
+	cachePath := filepath.Join(baseDir, ggufConfigFile)
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var config ModelConfig
+		if err := json.Unmarshal(data, &config); err == nil {
+			logging.DebugLogger.Printf("Using the cached GGUF config of %s\n", repo)
+			return config, nil
+		}
+	}
+
+	files, err := hfRepoFiles(repo, headers)
+	if err != nil {
+		return ModelConfig{}, err
+	}
+	file, err := pickGGUFFile(files, quant)
+	if err != nil {
+		return ModelConfig{}, err
+	}
+	logging.DebugLogger.Printf("Reading the GGUF metadata of %s from %s\n", repo, file)
+
+	reader := &rangeReader{url: hfFileURL(repo, file), headers: headers}
+	info, err := readGGUFModelInfo(bufio.NewReader(reader))
+	if err != nil {
+		return ModelConfig{}, fmt.Errorf("error reading the GGUF metadata of %s/%s: %w", repo, file, err)
+	}
+	config := modelConfigFromInfo(info)
+
+	if data, err := json.Marshal(config); err == nil {
+		if err := os.MkdirAll(baseDir, 0755); err == nil {
+			err = os.WriteFile(cachePath, data, 0644)
+		}
+		if err != nil {
+			logging.DebugLogger.Printf("Couldn't cache the GGUF config of %s: %v\n", repo, err)
+		}
+	}
+	return config, nil
+}
+
+// hfRepoFiles lists the files in a repo with the HuggingFace API
+func hfRepoFiles(repo string, headers map[string]string) ([]string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/models/%s", hfBaseURL, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := hfClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the files of %s: %w", repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, hfStatusError("error listing the files of "+repo, resp)
+	}
+
+	var model struct {
+		Siblings []struct {
+			Filename string `json:"rfilename"`
+		} `json:"siblings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&model); err != nil {
+		return nil, fmt.Errorf("error decoding the files of %s: %w", repo, err)
+	}
+	files := make([]string, len(model.Siblings))
+	for i, sibling := range model.Siblings {
+		files[i] = sibling.Filename
+	}
+	return files, nil
+}
+
+// pickGGUFFile picks the GGUF file to read the metadata of: the one named with the quantisation when there is one,
+// otherwise Q4_K_M or the first by name. Vision projectors are skipped and only the first part of a split model is
+// considered, as it holds the metadata.
+func pickGGUFFile(files []string, quant string) (string, error) {
+	var candidates []string
+	for _, file := range files {
+		lower := strings.ToLower(file)
+		if !strings.HasSuffix(lower, ".gguf") || strings.Contains(lower, "mmproj") {
+			continue
+		}
+		if part := ggufSplitPattern.FindStringSubmatch(lower); part != nil && part[1] != "00001" {
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+	if len(candidates) == 0 {
+		return "", errNoGGUFFiles
+	}
+	sort.Strings(candidates)
+
+	want := strings.ToUpper(quant)
+	if want == "" {
+		want = "Q4_K_M"
+	}
+	for _, file := range candidates {
+		if strings.Contains(strings.ToUpper(filepath.Base(file)), want) {
+			return file, nil
+		}
+	}
+	if quant != "" {
+		return "", fmt.Errorf("no GGUF file for %s, the repo has %s", quant, strings.Join(candidates, ", "))
+	}
+	return candidates[0], nil
+}
+
+// hfFileURL returns the download URL of a file in a repo
+func hfFileURL(repo, file string) string {
+	segments := strings.Split(file, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("%s/%s/resolve/main/%s", hfBaseURL, repo, strings.Join(segments, "/"))
+}
+
+// rangeReader reads a remote file from the start with range requests, fetching the next range when the last one has
+// been read so only as much of the file as the reader needs is downloaded
+type rangeReader struct {
+	url     string
+	headers map[string]string
+	offset  int64
+	size    int64
+	buf     []byte
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *rangeReader) fetch() error {
+	if r.offset >= maxGGUFHeader {
+		return fmt.Errorf("the GGUF metadata is larger than %d MB", maxGGUFHeader/1024/1024)
+	}
+	r.size = min(max(r.size*2, ggufFirstRange), ggufMaxRange)
+
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range r.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.offset, r.offset+r.size-1))
+	resp, err := hfClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return io.EOF
+	case http.StatusOK:
+		return errors.New("the server doesn't support range requests")
+	default:
+		return hfStatusError("error fetching "+r.url, resp)
+	}
+	r.buf, err = io.ReadAll(io.LimitReader(resp.Body, r.size))
+	if err != nil {
+		return err
+	}
+	if len(r.buf) == 0 {
+		return io.EOF
+	}
+	r.offset += int64(len(r.buf))
+	return nil
+}
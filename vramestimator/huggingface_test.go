@@ -0,0 +1,108 @@
+package vramestimator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetModelConfigGGUFOnlyRepo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("HF_TOKEN", "hf_test")
+	fixture, err := os.ReadFile(ggufFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer hf_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/models/owner/tiny-GGUF":
+			json.NewEncoder(w).Encode(map[string]any{"siblings": []map[string]string{
+				{"rfilename": "README.md"}, {"rfilename": "mmproj-tiny-f16.gguf"},
+				{"rfilename": "tiny.Q8_0.gguf"}, {"rfilename": "tiny.Q4_K_M.gguf"},
+			}})
+		case "/api/models/owner/empty":
+			json.NewEncoder(w).Encode(map[string]any{"siblings": []map[string]string{{"rfilename": "README.md"}}})
+		case "/owner/tiny-GGUF/resolve/main/tiny.Q4_K_M.gguf":
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mu.Unlock()
+			http.ServeContent(w, r, "tiny.Q4_K_M.gguf", time.Time{}, bytes.NewReader(fixture))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(base string) { hfBaseURL = base }(hfBaseURL)
+	hfBaseURL = server.URL
+
+	config, err := GetModelConfig("hf.co/owner/tiny-GGUF")
+	if err != nil {
+		t.Fatalf("GetModelConfig() error = %v", err)
+	}
+	want := ModelConfig{NumParams: 4288 / 1e9, MaxPositionEmbeddings: 4096, NumHiddenLayers: 2, HiddenSize: 64, NumKeyValueHeads: 4, NumAttentionHeads: 4, IntermediateSize: 256, VocabSize: 3}
+	if config != want {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=0-1048575" {
+		t.Errorf("GGUF requests with ranges %q, want one for the first MB", ranges)
+	}
+
+	// A new process uses the config cached on disk instead of fetching the file again
+	cacheMutex.Lock()
+	delete(modelConfigCache, "hf.co/owner/tiny-GGUF")
+	cacheMutex.Unlock()
+	if config, err := GetModelConfig("owner/tiny-GGUF"); err != nil || config != want {
+		t.Errorf("GetModelConfig() from the disk cache = %+v, %v", config, err)
+	}
+	if len(ranges) != 1 {
+		t.Errorf("the GGUF file was fetched again, ranges %q", ranges)
+	}
+
+	if _, err := GetModelConfig("owner/empty"); err == nil || !strings.Contains(err.Error(), "no GGUF files") || !strings.Contains(err.Error(), "config.json") {
+		t.Errorf("GetModelConfig() of a repo without config.json or GGUF files error = %v", err)
+	}
+}
+
+func TestPickGGUFFile(t *testing.T) {
+	files := []string{
+		"README.md",
+		"mmproj-model-f16.gguf",
+		"model-Q8_0.gguf",
+		"Q4_K_M/model-Q4_K_M-00002-of-00002.gguf",
+		"Q4_K_M/model-Q4_K_M-00001-of-00002.gguf",
+		"model-IQ4_XS.gguf",
+	}
+	tests := []struct {
+		quant string
+		want  string
+	}{
+		{"", "Q4_K_M/model-Q4_K_M-00001-of-00002.gguf"},
+		{"q8_0", "model-Q8_0.gguf"},
+		{"IQ4_XS", "model-IQ4_XS.gguf"},
+	}
+	for _, tt := range tests {
+		if got, err := pickGGUFFile(files, tt.quant); err != nil || got != tt.want {
+			t.Errorf("pickGGUFFile(%q) = %q, %v, want %q", tt.quant, got, err, tt.want)
+		}
+	}
+	if _, err := pickGGUFFile(files, "Q2_K"); err == nil {
+		t.Error("pickGGUFFile() of a missing quantisation succeeded")
+	}
+	if _, err := pickGGUFFile([]string{"model.safetensors"}, ""); !errors.Is(err, errNoGGUFFiles) {
+		t.Errorf("pickGGUFFile() without GGUF files error = %v, want errNoGGUFFiles", err)
+	}
+}
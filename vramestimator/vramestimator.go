@@ -3,6 +3,7 @@ package vramestimator
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,8 +18,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/charmbracelet/lipgloss"
-	"github.com/olekukonko/tablewriter"
+	"github.com/sammcj/gollama/config"
 	"github.com/sammcj/gollama/logging"
 	"github.com/sammcj/gollama/utils"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -48,38 +48,115 @@ type BPWValues struct {
 
 // Update the QuantResult struct
 type QuantResult struct {
-	QuantType string
-	BPW       float64
-	Contexts  map[int]ContextVRAM
+	QuantType          string
+	BPW                float64
+	Contexts           map[int]ContextVRAM
+	LayerSize          float64 // GB of the largest single layer (weights and k/v cache at the top context, FP16 unless the table is for another)
+	ExceedsSmallestGPU bool    // a single layer doesn't fit on the smallest GPU, so the model can't be split across them
+	WeightsSize        float64 // GB of the weights alone
+	MaxContext         int     // largest context that fits in the table's memory (FP16 k/v cache unless the table is for another), 0 when not even minContextSize does
 }
 
+// ContextVRAM is the estimated VRAM in GB at one context size with each k/v cache quantisation, a table limited to a
+// single k/v cache quantisation only fills in that one
 type ContextVRAM struct {
-	VRAM     float64
+	VRAM     float64 // FP16 k/v cache
 	VRAMQ8_0 float64
 	VRAMQ4_0 float64
 }
 
+// ForKVCache returns the estimate with the given k/v cache quantisation
+func (c ContextVRAM) ForKVCache(kvCacheQuant KVCacheQuantisation) float64 {
+	switch kvCacheQuant {
+	case KVCacheQ8_0:
+		return c.VRAMQ8_0
+	case KVCacheQ4_0:
+		return c.VRAMQ4_0
+	}
+	return c.VRAM
+}
+
 // QuantResultTable represents a table of VRAM estimation results
 type QuantResultTable struct {
-	ModelID  string
-	Results  []QuantResult
-	FitsVRAM float64
+	ModelID       string
+	Results       []QuantResult
+	FitsVRAM      float64 // combined memory of all the GPUs
+	FitsGiven     bool    // FitsVRAM was asked for rather than detected, the largest contexts are only worked out then
+	ContextSource string  // where the largest context size came from, for display
+	GPUs          GPUSetup
+	KVCacheQuant  KVCacheQuantisation // the only k/v cache quantisation estimated, empty for all of them
+}
+
+// GPUSetup is the GPUs a model is split across, the zero value is a single GPU
+type GPUSetup struct {
+	Count int       // number of GPUs
+	Split []float64 // share of the model on each GPU like llama.cpp's --tensor-split (e.g. 24,24), an even split when empty
+}
+
+// NumGPUs returns the number of GPUs, at least one
+func (g GPUSetup) NumGPUs() int {
+	if len(g.Split) > g.Count {
+		return len(g.Split)
+	}
+	return max(g.Count, 1)
+}
+
+// SmallestShare returns the smallest fraction of the model any GPU takes
+func (g GPUSetup) SmallestShare() float64 {
+	if len(g.Split) == 0 {
+		return 1 / float64(g.NumGPUs())
+	}
+	var total float64
+	smallest := math.MaxFloat64
+	for _, share := range g.Split {
+		total += share
+		smallest = math.Min(smallest, share)
+	}
+	return smallest / total
+}
+
+// ParseTensorSplit parses a comma separated tensor split such as "24,24" or "3,1"
+func ParseTensorSplit(split string) ([]float64, error) {
+	var shares []float64
+	for _, field := range strings.Split(split, ",") {
+		share, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || share <= 0 {
+			return nil, fmt.Errorf("invalid tensor split %q, expected positive numbers such as 24,24", split)
+		}
+		shares = append(shares, share)
+	}
+	return shares, nil
 }
 
+// DefaultMaxContext is the largest context estimated when the model's own maximum isn't known
+const DefaultMaxContext = 65536
+
+// MaxAutoContext caps the maximum context taken from a model so million token models don't produce enormous tables
+const MaxAutoContext = 262144
+
 const (
 	KVCacheFP16 KVCacheQuantisation = "fp16"
 	KVCacheQ8_0 KVCacheQuantisation = "q8_0"
 	KVCacheQ4_0 KVCacheQuantisation = "q4_0"
 )
 
+// ParseKVCacheQuantisation parses a k/v cache quantisation as Ollama's OLLAMA_KV_CACHE_TYPE names them (fp16, q8_0 or q4_0)
+func ParseKVCacheQuantisation(s string) (KVCacheQuantisation, error) {
+	switch q := KVCacheQuantisation(strings.ToLower(strings.TrimSpace(s))); q {
+	case KVCacheFP16, KVCacheQ8_0, KVCacheQ4_0:
+		return q, nil
+	case "f16":
+		return KVCacheFP16, nil
+	}
+	return "", fmt.Errorf("invalid k/v cache quantisation %q, expected fp16, q8_0 or q4_0", s)
+}
+
 const (
-	CUDASize = 500 * 1024 * 1024 // 500 MB
+	CUDASize = 500 * 1024 * 1024 // 500 MB, allocated on every GPU
 )
 
-var colourMap = []string{
-	"#ff0000", // red
-	"#00ff00", // green
-}
+// computeBatchSize is the number of tokens llama.cpp processes at once, which sizes the compute buffer each GPU allocates
+const computeBatchSize = 512
 
 // GGUFMapping maps GGUF quantisation types to their corresponding bits per weight
 var GGUFMapping = map[string]float64{
@@ -127,16 +204,6 @@ var (
 )
 
 // Add description of vRAM estimation types at the top
-const vramDescription = `
-VRAM Estimation Format:
-For context sizes ≥ 16K: F16(Q8_0,Q4_0)
-- F16: Base model with FP16 KV cache
-- Q8_0: Model with Q8_0 KV cache quantisation
-- Q4_0: Model with Q4_0 KV cache quantisation
-
-For context sizes < 16K: Single F16 value shown
-`
-
 func init() {
 	for i := 6.0; i >= 2.0; i -= 0.05 {
 		EXL2Options = append(EXL2Options, math.Round(i*100)/100)
@@ -164,42 +231,64 @@ func GetSystemRAM() (float64, error) {
 }
 
 func GetAvailableMemory() (float64, error) {
+	memory, _, err := DetectAvailableMemory()
+	return memory, err
+}
+
+// DetectAvailableMemory returns the memory in GB models can be loaded into, and whether it's system RAM. GPU detection
+// is unavailable until the CUDA package builds again, so it's always system RAM for now.
+func DetectAvailableMemory() (float64, bool, error) {
 	// will fix this soon
 	// if checkNVMLAvailable() {
 	// 	// Try to get CUDA
 	// 	vram, err := cuda.GetCUDAVRAM()
 	// 	if err == nil {
 	// 		logging.InfoLogger.Printf("Using CUDA VRAM: %.2f GB", vram)
-	// 		return vram, nil
-	// 	}
-
-	// 	// If CUDA is not available, fall back to system RAM
-	// 	ram, err := GetSystemRAM()
-	// 	if err != nil {
-	// 		return 0, fmt.Errorf("failed to get system RAM: %v", err)
+	// 		return vram, false, nil
 	// 	}
-
-	// 	logging.InfoLogger.Printf("Using system RAM: %.2f GB", ram)
-	// 	return ram, nil
-	// } else {
+	// }
 	ram, err := GetSystemRAM()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get system RAM: %v", err)
+		return 0, true, fmt.Errorf("failed to get system RAM: %v", err)
 	}
 
-	logging.InfoLogger.Printf("Using system RAM: %.2f GB", ram)
-	return ram, nil
-	// }
+	logging.InfoLogger.Printf("GPU detection unavailable, using system RAM: %.2f GB", ram)
+	return ram, true, nil
 }
 
+// OllamaModelInfo is the subset of the Ollama show API response used for estimation.
+// JSON field names follow the Ollama API, which uses American spelling (e.g. "quantization_level").
 type OllamaModelInfo struct {
-	Details struct {
-		ParameterSize     string   `json:"parameter_size"`
-		QuantizationLevel string   `json:"quantisation_level"`
-		Family            string   `json:"family"`
-		Families          []string `json:"families"`
-	} `json:"details"`
-	ModelInfo map[string]interface{} `json:"model_info"`
+	Details       OllamaModelDetails     `json:"details"`
+	ModelInfo     map[string]interface{} `json:"model_info"`
+	ProjectorSize int64                  `json:"-"` // Bytes of vision projectors loaded alongside the model, set by the caller
+}
+
+// OllamaModelDetails holds the model details from the show API.
+// The canonical key is "quantization_level", "quantisation_level" is accepted as a deprecated alias.
+type OllamaModelDetails struct {
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+}
+
+// UnmarshalJSON accepts the British spelled "quantisation_level" key for files written by older versions of gollama
+func (d *OllamaModelDetails) UnmarshalJSON(data []byte) error {
+	type details OllamaModelDetails
+	var aux struct {
+		details
+		QuantisationLevel string `json:"quantisation_level"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*d = OllamaModelDetails(aux.details)
+	if d.QuantizationLevel == "" && aux.QuantisationLevel != "" {
+		logging.InfoLogger.Println("Deprecated: the \"quantisation_level\" key is deprecated, use \"quantization_level\" instead")
+		d.QuantizationLevel = aux.QuantisationLevel
+	}
+	return nil
 }
 
 func extractModelInfo(info map[string]interface{}, key string) (float64, bool) {
@@ -218,16 +307,68 @@ func extractModelInfo(info map[string]interface{}, key string) (float64, bool) {
 	return 0, false
 }
 
-func FetchOllamaModelInfo(apiURL, modelName string) (*OllamaModelInfo, error) {
+// ContextLength returns the model's maximum context length from the show API model info, or 0 if it isn't reported
+func (i *OllamaModelInfo) ContextLength() int {
+	if i == nil {
+		return 0
+	}
+	length, ok := extractModelInfo(i.ModelInfo, ".context_length")
+	if !ok {
+		return 0
+	}
+	return int(length)
+}
+
+// ProjectorGB returns the GB of the model's vision projectors, which are loaded into vRAM as they are
+func (i *OllamaModelInfo) ProjectorGB() float64 {
+	if i == nil {
+		return 0
+	}
+	return float64(i.ProjectorSize) / (1024 * 1024 * 1024)
+}
+
+// ModelContextLimit returns the largest context to estimate for a model and a description of where it came from.
+// Ollama models use context_length from the show API and HuggingFace models use max_position_embeddings from
+// config.json, both capped at MaxAutoContext. DefaultMaxContext is used if neither is available.
+func ModelContextLimit(modelID string, ollamaModelInfo *OllamaModelInfo) (int, string) {
+	var limit int
+	var source string
+	if ollamaModelInfo != nil {
+		limit, source = ollamaModelInfo.ContextLength(), "the model's context_length"
+	} else if config, err := GetModelConfig(modelID); err == nil {
+		limit, source = config.MaxPositionEmbeddings, "max_position_embeddings in the model's config.json"
+	} else {
+		logging.DebugLogger.Printf("Couldn't get the model config for %s to find its maximum context: %v\n", modelID, err)
+	}
+
+	switch {
+	case limit <= 0:
+		return DefaultMaxContext, "default, the model's maximum context isn't known"
+	case limit > MaxAutoContext:
+		return MaxAutoContext, fmt.Sprintf("%s of %d, capped at %d", source, limit, MaxAutoContext)
+	default:
+		return limit, source
+	}
+}
+
+// FetchOllamaModelInfo reads a model's details from the Ollama show API with httpClient, which should authenticate when
+// the API needs it (see config.NewHTTPClient), nil uses http.DefaultClient
+func FetchOllamaModelInfo(httpClient *http.Client, apiURL, modelName string) (*OllamaModelInfo, error) {
 	url := fmt.Sprintf("%s/api/show", apiURL)
 	payload := []byte(fmt.Sprintf(`{"name": "%s"}`, modelName))
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, fmt.Errorf("error making request to Ollama API: %v", err)
 	}
 	defer resp.Body.Close()
 
+	if err := config.AuthError(resp.StatusCode); err != nil {
+		return nil, err
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("ollama API returned non-OK status: %d", resp.StatusCode)
 	}
@@ -237,7 +378,7 @@ func FetchOllamaModelInfo(apiURL, modelName string) (*OllamaModelInfo, error) {
 		return nil, fmt.Errorf("error reading Ollama API response: %v", err)
 	}
 
-	logging.DebugLogger.Printf("Raw Ollama API response: %s", string(body))
+	logging.TraceLogger.Printf("Raw Ollama API response: %s", string(body))
 
 	var modelInfo OllamaModelInfo
 	if err := json.Unmarshal(body, &modelInfo); err != nil {
@@ -247,28 +388,29 @@ func FetchOllamaModelInfo(apiURL, modelName string) (*OllamaModelInfo, error) {
 	return &modelInfo, nil
 }
 
-func EstimateVRAM(modelIdentifier, apiURL string, fitsVRAM float64) error {
+// EstimateVRAM generates the quantisation table for a model, fetching its details from Ollama with httpClient when given
+// an Ollama model name
+func EstimateVRAM(httpClient *http.Client, modelIdentifier, apiURL string, fitsVRAM float64) (QuantResultTable, error) {
 	var ollamaModelInfo *OllamaModelInfo
 	var err error
 
 	// Check if the modelIdentifier is an Ollama model name
 	if strings.Contains(modelIdentifier, ":") {
-		ollamaModelInfo, err = FetchOllamaModelInfo(apiURL, modelIdentifier)
+		ollamaModelInfo, err = FetchOllamaModelInfo(httpClient, apiURL, modelIdentifier)
 		if err != nil {
-			return fmt.Errorf("error fetching Ollama model info: %v", err)
+			return QuantResultTable{}, fmt.Errorf("error fetching Ollama model info: %v", err)
 		}
 	}
 
-	// Generate the quantisation table
-	table, err := GenerateQuantTable(modelIdentifier, fitsVRAM, ollamaModelInfo, 65536)
+	// Generate the quantisation table up to the model's own maximum context
+	topContext, source := ModelContextLimit(modelIdentifier, ollamaModelInfo)
+	table, err := GenerateQuantTable(modelIdentifier, fitsVRAM, ollamaModelInfo, topContext, GPUSetup{})
 	if err != nil {
-		return fmt.Errorf("error generating quantisation table: %v", err)
+		return QuantResultTable{}, fmt.Errorf("error generating quantisation table: %v", err)
 	}
+	table.ContextSource = source
 
-	// Print the formatted table
-	fmt.Println(PrintFormattedTable(table))
-
-	return nil
+	return table, nil
 }
 
 // CalculateVRAMRaw calculates the raw VRAM usage
@@ -313,11 +455,37 @@ func CalculateVRAMRaw(config ModelConfig, bpwValues BPWValues, context int, numG
 
 	outputSize := lmHeadBytesPerParam * float64(context*config.VocabSize)
 
-	vramBits := cudaSize + paramsSize + activationsSize + outputSize + kvCacheSize
+	// llama.cpp allocates a compute buffer on every GPU, so each extra GPU duplicates one
+	duplicatedSize := float64(numGPUs-1) * computeBufferSize(config)
+
+	vramBits := cudaSize + paramsSize + activationsSize + outputSize + kvCacheSize + duplicatedSize
 
 	return bitsToGB(vramBits)
 }
 
+// computeBufferSize estimates the compute buffer llama.cpp allocates on each GPU: FP32 activations and logits for one batch
+func computeBufferSize(config ModelConfig) float64 {
+	return float64(computeBatchSize*(config.HiddenSize+config.IntermediateSize+config.VocabSize)) * 4
+}
+
+// layerSize estimates the GB of one layer: its share of the weights plus its k/v cache at the given context
+func layerSize(config ModelConfig, bpwValues BPWValues, context int) float64 {
+	if config.NumHiddenLayers == 0 {
+		return 0
+	}
+	paramsSize := config.NumParams * 1e9 * (bpwValues.BPW / 8) / float64(config.NumHiddenLayers)
+	kvCacheSize := float64(context*2*config.HiddenSize) * (bpwValues.KVCacheBPW / 8)
+	if config.NumAttentionHeads > 0 {
+		kvCacheSize *= float64(config.NumKeyValueHeads) / float64(config.NumAttentionHeads)
+	}
+	return bitsToGB(paramsSize + kvCacheSize)
+}
+
+// weightsSize estimates the GB of the weights alone at a bits per weight
+func weightsSize(config ModelConfig, bpw float64) float64 {
+	return bitsToGB(config.NumParams * 1e9 * (bpw / 8))
+}
+
 // bitsToGB converts bits to gigabytes
 func bitsToGB(bits float64) float64 {
 	return bits / math.Pow(2, 30)
@@ -387,7 +555,8 @@ func GetHuggingFaceToken() string {
 	return accessToken
 }
 
-// GetModelConfig retrieves and parses the model configuration
+// GetModelConfig retrieves and parses the model configuration of a HuggingFace repo from its config.json, or from the
+// metadata of one of its GGUF files when the repo is GGUF-only
 func GetModelConfig(modelID string) (ModelConfig, error) {
 	cacheMutex.RLock()
 	if config, ok := modelConfigCache[modelID]; ok {
@@ -396,20 +565,38 @@ func GetModelConfig(modelID string) (ModelConfig, error) {
 	}
 	cacheMutex.RUnlock()
 
-	baseDir := filepath.Join(utils.GetHomeDir(), ".cache/huggingface/hub", modelID)
-	configPath := filepath.Join(baseDir, "config.json")
-	indexPath := filepath.Join(baseDir, "model.safetensors.index.json")
+	repo, quant := hfRepoID(modelID)
+	baseDir := filepath.Join(utils.GetHomeDir(), ".cache/huggingface/hub", repo)
+	headers := hfHeaders()
 
-	configURL := fmt.Sprintf("https://huggingface.co/%s/raw/main/config.json", modelID)
-	indexURL := fmt.Sprintf("https://huggingface.co/%s/raw/main/model.safetensors.index.json", modelID)
+	config, err := safetensorsModelConfig(repo, baseDir, headers)
+	if err != nil {
+		logging.DebugLogger.Printf("Couldn't get the config.json of %s, trying its GGUF files: %v\n", repo, err)
+		var ggufErr error
+		config, ggufErr = ggufModelConfig(repo, quant, baseDir, headers)
+		if errors.Is(ggufErr, errNoGGUFFiles) {
+			return ModelConfig{}, fmt.Errorf("%s has no GGUF files and its config.json couldn't be fetched: %w", repo, err)
+		}
+		if ggufErr != nil {
+			return ModelConfig{}, ggufErr
+		}
+	}
 
-	headers := make(map[string]string)
+	cacheMutex.Lock()
+	modelConfigCache[modelID] = config
+	cacheMutex.Unlock()
 
-	accessToken := GetHuggingFaceToken()
+	return config, nil
+}
 
-	if accessToken != "" {
-		headers["Authorization"] = "Bearer " + accessToken
-	}
+// safetensorsModelConfig downloads a repo's config.json and model.safetensors.index.json to baseDir and reads the
+// configuration from them
+func safetensorsModelConfig(repo, baseDir string, headers map[string]string) (ModelConfig, error) {
+	configPath := filepath.Join(baseDir, "config.json")
+	indexPath := filepath.Join(baseDir, "model.safetensors.index.json")
+
+	configURL := fmt.Sprintf("%s/%s/raw/main/config.json", hfBaseURL, repo)
+	indexURL := fmt.Sprintf("%s/%s/raw/main/model.safetensors.index.json", hfBaseURL, repo)
 
 	if err := DownloadFile(configURL, configPath, headers); err != nil {
 		return ModelConfig{}, err
@@ -444,11 +631,6 @@ func GetModelConfig(modelID string) (ModelConfig, error) {
 	}
 
 	config.NumParams = index.Metadata.TotalSize / 2 / 1e9
-
-	cacheMutex.Lock()
-	modelConfigCache[modelID] = config
-	cacheMutex.Unlock()
-
 	return config, nil
 }
 
@@ -489,69 +671,25 @@ func GetBPWValues(bpw float64, kvCacheQuant KVCacheQuantisation) BPWValues {
 	}
 }
 
-// CalculateVRAM calculates the VRAM usage for a given model and configuration
+// CalculateVRAM calculates the VRAM usage for a given model and configuration on a single GPU
 func CalculateVRAM(modelID string, bpw float64, context int, kvCacheQuant KVCacheQuantisation, ollamaModelInfo *OllamaModelInfo) (float64, error) {
-	logging.DebugLogger.Println("Calculating VRAM usage...")
-
-	var config ModelConfig
-	var err error
-
-	if ollamaModelInfo != nil {
-		// Use Ollama model information
-		paramCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "parameter_count")
-		contextLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "context_length")
-		blockCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "block_count")
-		embeddingLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "embedding_length")
-		headCountKV, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "attention.head_count_kv")
-		headCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "attention.head_count")
-		feedForwardLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "feed_forward_length")
-		vocabSize, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "vocab_size")
-
-		config = ModelConfig{
-			NumParams:             paramCount / 1e9, // Convert to billions
-			MaxPositionEmbeddings: int(contextLength),
-			NumHiddenLayers:       int(blockCount),
-			HiddenSize:            int(embeddingLength),
-			NumKeyValueHeads:      int(headCountKV),
-			NumAttentionHeads:     int(headCount),
-			IntermediateSize:      int(feedForwardLength),
-			VocabSize:             int(vocabSize),
-		}
+	return CalculateVRAMForGPUs(modelID, bpw, context, kvCacheQuant, ollamaModelInfo, GPUSetup{})
+}
 
-		// Estimate missing values
-		if config.HiddenSize == 0 {
-			config.HiddenSize = int(math.Sqrt(paramCount / 1000))
-		}
-		if config.NumHiddenLayers == 0 {
-			config.NumHiddenLayers = int(math.Round(config.NumParams * 1e9 / (12 * float64(config.HiddenSize) * float64(config.HiddenSize))))
-		}
-		if config.NumAttentionHeads == 0 {
-			config.NumAttentionHeads = config.HiddenSize / 64 // Assuming 64 dimension per head
-		}
-		if config.NumKeyValueHeads == 0 {
-			config.NumKeyValueHeads = config.NumAttentionHeads
-		}
-		if config.IntermediateSize == 0 {
-			config.IntermediateSize = 4 * config.HiddenSize
-		}
-		if config.VocabSize == 0 {
-			config.VocabSize = 32000 // A common default value
-		}
+// CalculateVRAMForGPUs calculates the combined VRAM usage for a given model and configuration split across GPUs
+func CalculateVRAMForGPUs(modelID string, bpw float64, context int, kvCacheQuant KVCacheQuantisation, ollamaModelInfo *OllamaModelInfo, gpus GPUSetup) (float64, error) {
+	logging.DebugLogger.Println("Calculating VRAM usage...")
 
-		// Parse BPW from quantisation level if not provided
-		if bpw == 0 {
-			bpw, err = ParseBPWOrQuant(ollamaModelInfo.Details.QuantizationLevel)
-			if err != nil {
-				return 0, fmt.Errorf("error parsing BPW from Ollama quantisation level: %v", err)
-			}
-		}
+	config, err := resolveModelConfig(modelID, ollamaModelInfo)
+	if err != nil {
+		return 0, err
+	}
 
-		logging.DebugLogger.Printf("Processed Ollama Model Config: %+v", config)
-	} else {
-		// Use Hugging Face model information
-		config, err = GetModelConfig(modelID)
+	// Parse BPW from quantisation level if not provided
+	if bpw == 0 && ollamaModelInfo != nil {
+		bpw, err = ParseBPWOrQuant(ollamaModelInfo.Details.QuantizationLevel)
 		if err != nil {
-			return 0, err
+			return 0, fmt.Errorf("error parsing BPW from Ollama quantisation level: %v", err)
 		}
 	}
 
@@ -572,12 +710,78 @@ func CalculateVRAM(modelID string, bpw float64, context int, kvCacheQuant KVCach
 		context = 2048 // Default context if not provided
 	}
 
-	vram := CalculateVRAMRaw(config, bpwValues, context, 1, true)
+	vram := CalculateVRAMRaw(config, bpwValues, context, gpus.NumGPUs(), true) + ollamaModelInfo.ProjectorGB()
 	return math.Round(vram*100) / 100, nil
 }
 
-// CalculateContext calculates the maximum context for a given memory constraint
+// resolveModelConfig returns the model configuration from the Ollama model info, or from HuggingFace when there isn't any
+func resolveModelConfig(modelID string, ollamaModelInfo *OllamaModelInfo) (ModelConfig, error) {
+	if ollamaModelInfo == nil {
+		// Use Hugging Face model information
+		return GetModelConfig(modelID)
+	}
+	config := modelConfigFromInfo(ollamaModelInfo)
+	logging.TraceLogger.Printf("Processed Ollama Model Config: %+v", config)
+	return config, nil
+}
+
+// modelConfigFromInfo builds the model configuration from the show API's model info, or a GGUF file's metadata in the
+// same shape, estimating the values it doesn't have
+func modelConfigFromInfo(ollamaModelInfo *OllamaModelInfo) ModelConfig {
+	paramCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "parameter_count")
+	contextLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "context_length")
+	blockCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "block_count")
+	embeddingLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "embedding_length")
+	headCountKV, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "attention.head_count_kv")
+	headCount, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "attention.head_count")
+	feedForwardLength, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "feed_forward_length")
+	vocabSize, _ := extractModelInfo(ollamaModelInfo.ModelInfo, "vocab_size")
+
+	config := ModelConfig{
+		NumParams:             paramCount / 1e9, // Convert to billions
+		MaxPositionEmbeddings: int(contextLength),
+		NumHiddenLayers:       int(blockCount),
+		HiddenSize:            int(embeddingLength),
+		NumKeyValueHeads:      int(headCountKV),
+		NumAttentionHeads:     int(headCount),
+		IntermediateSize:      int(feedForwardLength),
+		VocabSize:             int(vocabSize),
+	}
+
+	// Estimate missing values
+	if config.HiddenSize == 0 {
+		config.HiddenSize = int(math.Sqrt(paramCount / 1000))
+	}
+	if config.NumHiddenLayers == 0 {
+		config.NumHiddenLayers = int(math.Round(config.NumParams * 1e9 / (12 * float64(config.HiddenSize) * float64(config.HiddenSize))))
+	}
+	if config.NumAttentionHeads == 0 {
+		config.NumAttentionHeads = config.HiddenSize / 64 // Assuming 64 dimension per head
+	}
+	if config.NumKeyValueHeads == 0 {
+		config.NumKeyValueHeads = config.NumAttentionHeads
+	}
+	if config.IntermediateSize == 0 {
+		config.IntermediateSize = 4 * config.HiddenSize
+	}
+	if config.VocabSize == 0 {
+		config.VocabSize = 32000 // A common default value
+	}
+	return config
+}
+
+// minContextSize is the smallest context CalculateContext searches from
+const minContextSize = 512
+
+// CalculateContext calculates the maximum context for a given memory constraint with the given k/v cache
+// quantisation, FP16 when it's empty
 func CalculateContext(modelID string, memory, bpw float64, kvCacheQuant KVCacheQuantisation, ollamaModelInfo *OllamaModelInfo, topContext int) (int, error) {
+	return CalculateContextForGPUs(modelID, memory, bpw, kvCacheQuant, ollamaModelInfo, topContext, GPUSetup{})
+}
+
+// CalculateContextForGPUs calculates the maximum context for the combined memory of the GPUs a model is split across.
+// The result is below minContextSize when not even that fits.
+func CalculateContextForGPUs(modelID string, memory, bpw float64, kvCacheQuant KVCacheQuantisation, ollamaModelInfo *OllamaModelInfo, topContext int, gpus GPUSetup) (int, error) {
 	logging.DebugLogger.Println("Calculating context...")
 
 	var maxContext int
@@ -602,11 +806,10 @@ func CalculateContext(modelID string, memory, bpw float64, kvCacheQuant KVCacheQ
 		maxContext = topContext
 	}
 
-	minContext := 512
-	low, high := minContext, maxContext
+	low, high := minContextSize, maxContext
 	for low < high {
 		mid := (low + high + 1) / 2
-		vram, err := CalculateVRAM(modelID, bpw, mid, kvCacheQuant, ollamaModelInfo)
+		vram, err := CalculateVRAMForGPUs(modelID, bpw, mid, kvCacheQuant, ollamaModelInfo, gpus)
 		if err != nil {
 			return 0, err
 		}
@@ -619,7 +822,7 @@ func CalculateContext(modelID string, memory, bpw float64, kvCacheQuant KVCacheQ
 
 	context := low
 	for context <= maxContext {
-		vram, err := CalculateVRAM(modelID, bpw, context, kvCacheQuant, ollamaModelInfo)
+		vram, err := CalculateVRAMForGPUs(modelID, bpw, context, kvCacheQuant, ollamaModelInfo, gpus)
 		if err != nil {
 			return 0, err
 		}
@@ -731,8 +934,17 @@ func levenshteinDistance(s1, s2 string) int {
 	return d[m][n]
 }
 
-func GenerateQuantTable(modelID string, fitsVRAM float64, ollamaModelInfo *OllamaModelInfo, topContext int) (QuantResultTable, error) {
-	if fitsVRAM == 0 {
+// GenerateQuantTable estimates the VRAM for each quantisation and context size with the model split across gpus,
+// fitsVRAM is the combined memory of the GPUs
+func GenerateQuantTable(modelID string, fitsVRAM float64, ollamaModelInfo *OllamaModelInfo, topContext int, gpus GPUSetup) (QuantResultTable, error) {
+	return GenerateQuantTableForKVCache(modelID, fitsVRAM, ollamaModelInfo, topContext, gpus, "")
+}
+
+// GenerateQuantTableForKVCache is GenerateQuantTable limited to a single k/v cache quantisation, or every one of them
+// when kvCacheQuant is empty
+func GenerateQuantTableForKVCache(modelID string, fitsVRAM float64, ollamaModelInfo *OllamaModelInfo, topContext int, gpus GPUSetup, kvCacheQuant KVCacheQuantisation) (QuantResultTable, error) {
+	fitsGiven := fitsVRAM > 0
+	if !fitsGiven {
 		var err error
 		fitsVRAM, err = GetAvailableMemory()
 		if err != nil {
@@ -742,42 +954,49 @@ func GenerateQuantTable(modelID string, fitsVRAM float64, ollamaModelInfo *Ollam
 		log.Printf("Using %.2f GB as available memory for VRAM estimation", fitsVRAM)
 	}
 
-	table := QuantResultTable{ModelID: modelID, FitsVRAM: fitsVRAM}
+	table := QuantResultTable{ModelID: modelID, FitsVRAM: fitsVRAM, FitsGiven: fitsGiven, GPUs: gpus, KVCacheQuant: kvCacheQuant}
+	kvCacheQuants := []KVCacheQuantisation{KVCacheFP16, KVCacheQ8_0, KVCacheQ4_0}
+	if kvCacheQuant != "" {
+		kvCacheQuants = []KVCacheQuantisation{kvCacheQuant}
+	}
 
 	// Generate context sizes based on the topContext
 	contextSizes := generateContextSizes(topContext)
 
-	if ollamaModelInfo == nil {
-		_, err := GetModelConfig(modelID)
-		if err != nil {
-			return QuantResultTable{}, err
-		}
+	config, err := resolveModelConfig(modelID, ollamaModelInfo)
+	if err != nil {
+		return QuantResultTable{}, err
 	}
+	smallestGPU := fitsVRAM * gpus.SmallestShare()
 
 	for quantType, bpw := range GGUFMapping {
 		var result QuantResult
 		result.QuantType = quantType
 		result.BPW = bpw
 		result.Contexts = make(map[int]ContextVRAM)
+		result.WeightsSize = weightsSize(config, bpw)
+		if gpus.NumGPUs() > 1 {
+			result.LayerSize = layerSize(config, GetBPWValues(bpw, kvCacheQuant), topContext)
+			result.ExceedsSmallestGPU = result.LayerSize+bitsToGB(CUDASize+computeBufferSize(config)) > smallestGPU
+		}
 
 		for _, context := range contextSizes {
-			vramFP16, err := CalculateVRAM(modelID, bpw, context, KVCacheFP16, ollamaModelInfo)
-			if err != nil {
-				return QuantResultTable{}, err
-			}
-			vramQ8_0, err := CalculateVRAM(modelID, bpw, context, KVCacheQ8_0, ollamaModelInfo)
-			if err != nil {
-				return QuantResultTable{}, err
-			}
-			vramQ4_0, err := CalculateVRAM(modelID, bpw, context, KVCacheQ4_0, ollamaModelInfo)
-			if err != nil {
-				return QuantResultTable{}, err
-			}
-			result.Contexts[context] = ContextVRAM{
-				VRAM:     vramFP16,
-				VRAMQ8_0: vramQ8_0,
-				VRAMQ4_0: vramQ4_0,
+			var vram ContextVRAM
+			for _, kv := range kvCacheQuants {
+				estimate, err := CalculateVRAMForGPUs(modelID, bpw, context, kv, ollamaModelInfo, gpus)
+				if err != nil {
+					return QuantResultTable{}, err
+				}
+				switch kv {
+				case KVCacheQ8_0:
+					vram.VRAMQ8_0 = estimate
+				case KVCacheQ4_0:
+					vram.VRAMQ4_0 = estimate
+				default:
+					vram.VRAM = estimate
+				}
 			}
+			result.Contexts[context] = vram
 		}
 		table.Results = append(table.Results, result)
 	}
@@ -787,113 +1006,60 @@ func GenerateQuantTable(modelID string, fitsVRAM float64, ollamaModelInfo *Ollam
 		return table.Results[i].BPW < table.Results[j].BPW
 	})
 
+	// The largest contexts are only shown for a memory constraint that was asked for, not the detected memory
+	if fitsGiven {
+		if err := fillMaxContexts(table.Results, modelID, fitsVRAM, kvCacheQuant, ollamaModelInfo, topContext, gpus); err != nil {
+			return QuantResultTable{}, err
+		}
+	}
 	return table, nil
 }
 
-// generateContextSizes generates a slice of context sizes based on the topContext
-func generateContextSizes(topContext int) []int {
-	sizes := []int{2048, 8192}
-	current := 16384
-	for current <= topContext {
-		sizes = append(sizes, current)
-		current *= 2
+// fillMaxContexts works out the largest context each quantisation fits in memory at. Each is a binary search over the
+// estimate, so they're shared out between a pool of workers.
+func fillMaxContexts(results []QuantResult, modelID string, memory float64, kvCacheQuant KVCacheQuantisation, ollamaModelInfo *OllamaModelInfo, topContext int, gpus GPUSetup) error {
+	jobs := make(chan int)
+	errs := make(chan error, len(results))
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(results)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				context, err := CalculateContextForGPUs(modelID, memory, results[i].BPW, kvCacheQuant, ollamaModelInfo, topContext, gpus)
+				if err != nil {
+					errs <- fmt.Errorf("error calculating the largest context for %s: %w", results[i].QuantType, err)
+					continue
+				}
+				if context < minContextSize {
+					context = 0
+				}
+				results[i].MaxContext = context
+			}
+		}()
 	}
-	if current/2 < topContext {
-		sizes = append(sizes, topContext)
+	for i := range results {
+		jobs <- i
 	}
-	return sizes
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	return <-errs
 }
 
-// PrintFormattedTable updates the table formatting with better descriptions
-func PrintFormattedTable(table QuantResultTable) string {
-	var buf bytes.Buffer
-
-	// Add the description header
-	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#87CEEB")). // Light blue for better readability
-		Bold(true)
-
-	buf.WriteString(headerStyle.Render(vramDescription))
-	buf.WriteString("\n")
-
-	tw := tablewriter.NewWriter(&buf)
-
-	// Get context sizes from the first result
-	var contextSizes []int
-	if len(table.Results) > 0 {
-		for context := range table.Results[0].Contexts {
-			contextSizes = append(contextSizes, context)
-		}
-		sort.Ints(contextSizes)
-	}
-
-	// Set table header
-	header := []string{"QUANT", "BPW"}
-	for _, context := range contextSizes {
-		if context >= 1024 {
-			header = append(header, fmt.Sprintf("%dK", context/1024))
-		} else {
-			header = append(header, fmt.Sprintf("%d", context))
-		}
-	}
-	tw.SetHeader(header)
-
-	// Update table style for better readability
-	tw.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	tw.SetCenterSeparator("|")
-	tw.SetColumnSeparator("|")
-	tw.SetRowSeparator("-")
-	tw.SetAutoWrapText(false)
-	tw.SetAutoFormatHeaders(true)
-
-	// Enhanced header colours
-	headerColours := make([]tablewriter.Colors, len(header))
-	for i := range headerColours {
-		headerColours[i] = tablewriter.Colors{tablewriter.FgHiWhiteColor, tablewriter.Bold}
-	}
-	tw.SetHeaderColor(headerColours...)
-
-	// Prepare data rows with improved formatting
-	for _, result := range table.Results {
-		row := []string{
-			result.QuantType,
-			fmt.Sprintf("%.2f", result.BPW),
-		}
-
-		// Add VRAM estimates for each context size with improved formatting
-		for _, context := range contextSizes {
-			vram, ok := result.Contexts[context]
-			if !ok {
-				row = append(row, "-")
-				continue
-			}
-
-			fp16Str := getColouredVRAM(vram.VRAM, fmt.Sprintf("%.1f", vram.VRAM), table.FitsVRAM)
-
-			if context >= 16384 {
-				q8Str := getColouredVRAM(vram.VRAMQ8_0, fmt.Sprintf("%.1f", vram.VRAMQ8_0), table.FitsVRAM)
-				q4Str := getColouredVRAM(vram.VRAMQ4_0, fmt.Sprintf("%.1f", vram.VRAMQ4_0), table.FitsVRAM)
-				combinedStr := fmt.Sprintf("%s(%s,%s)", fp16Str, q8Str, q4Str)
-				row = append(row, combinedStr)
-			} else {
-				row = append(row, fp16Str)
-			}
+// generateContextSizes generates the context sizes up to topContext, which is always the last size even when
+// it isn't a power of two
+func generateContextSizes(topContext int) []int {
+	var sizes []int
+	for _, size := range []int{2048, 8192} {
+		if size < topContext {
+			sizes = append(sizes, size)
 		}
-
-		tw.Append(row)
 	}
-
-	tw.Render()
-
-	// Add model info and memory constraint
-	modelInfo := fmt.Sprintf("📊 VRAM Estimation for Model: %s", table.ModelID)
-	if table.FitsVRAM > 0 {
-		modelInfo += fmt.Sprintf(" (Memory Constraint: %.1f GB)", table.FitsVRAM)
+	for current := 16384; current < topContext; current *= 2 {
+		sizes = append(sizes, current)
 	}
-
-	return lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffffff")).
-		Render(fmt.Sprintf("%s\n\n%s", modelInfo, buf.String()))
+	return append(sizes, topContext)
 }
 
 // ParseModelIdentifier parses a model identifier into its base name and quantisation level.
@@ -935,27 +1101,3 @@ func ParseModelIdentifier(modelID string) (string, string, error) {
 
 	return baseName, quantLevel, nil
 }
-
-func getColouredVRAM(vram float64, vramStr string, fitsVRAM float64) string {
-	var colorIndex int
-	if fitsVRAM > 0 {
-		if vram > fitsVRAM {
-			colorIndex = 0 // Red
-		} else {
-			colorIndex = len(colourMap) - 1 // Green
-		}
-	} else {
-		// Calculate color index based on VRAM usage
-		if vram <= 4 {
-			colorIndex = len(colourMap) - 1
-		} else if vram >= 24 {
-			colorIndex = 0
-		} else {
-			// Interpolate between 4 and 24 GB
-			colorIndex = len(colourMap) - 1 - int((vram-4)/(24-4)*float64(len(colourMap)-1))
-		}
-	}
-
-	style := lipgloss.NewStyle().Foreground(lipgloss.Color(colourMap[colorIndex]))
-	return style.Render(vramStr)
-}
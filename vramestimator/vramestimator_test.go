@@ -0,0 +1,275 @@
+package vramestimator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOllamaModelDetailsSpelling(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Canonical American spelling",
+			input: `{"details": {"quantization_level": "Q4_K_M", "family": "llama"}}`,
+			want:  "Q4_K_M",
+		},
+		{
+			name:  "Deprecated British spelling",
+			input: `{"details": {"quantisation_level": "Q8_0", "family": "llama"}}`,
+			want:  "Q8_0",
+		},
+		{
+			name:  "Canonical wins when both are present",
+			input: `{"details": {"quantization_level": "Q4_0", "quantisation_level": "Q8_0"}}`,
+			want:  "Q4_0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info OllamaModelInfo
+			if err := json.Unmarshal([]byte(tt.input), &info); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if info.Details.QuantizationLevel != tt.want {
+				t.Errorf("QuantizationLevel = %q, want %q", info.Details.QuantizationLevel, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaModelDetailsRoundTrip(t *testing.T) {
+	var info OllamaModelInfo
+	if err := json.Unmarshal([]byte(`{"details": {"quantisation_level": "Q6_K", "family": "qwen2", "families": ["qwen2"]}}`), &info); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	out, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(out), `"quantization_level":"Q6_K"`) {
+		t.Errorf("Marshal() = %s, want canonical quantization_level key", out)
+	}
+	if strings.Contains(string(out), "quantisation_level") {
+		t.Errorf("Marshal() = %s, should not write the deprecated key", out)
+	}
+
+	var again OllamaModelInfo
+	if err := json.Unmarshal(out, &again); err != nil {
+		t.Fatalf("Unmarshal() of written JSON error = %v", err)
+	}
+	if again.Details.QuantizationLevel != "Q6_K" || again.Details.Family != "qwen2" {
+		t.Errorf("round trip lost details: %+v", again.Details)
+	}
+}
+
+func TestGenerateContextSizes(t *testing.T) {
+	tests := []struct {
+		name       string
+		topContext int
+		want       []int
+	}{
+		{"small model", 4096, []int{2048, 4096}},
+		{"power of two", 65536, []int{2048, 8192, 16384, 32768, 65536}},
+		{"not a power of two", 40960, []int{2048, 8192, 16384, 32768, 40960}},
+		{"tiny", 2048, []int{2048}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateContextSizes(tt.topContext); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("generateContextSizes(%d) = %v, want %v", tt.topContext, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelContextLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		modelInfo map[string]interface{}
+		want      int
+	}{
+		{"context length", map[string]interface{}{"llama.context_length": float64(131072)}, 131072},
+		{"capped", map[string]interface{}{"qwen2.context_length": float64(1048576)}, MaxAutoContext},
+		{"unknown", map[string]interface{}{"general.architecture": "llama"}, DefaultMaxContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := ModelContextLimit("llama3:8b", &OllamaModelInfo{ModelInfo: tt.modelInfo})
+			if got != tt.want {
+				t.Errorf("ModelContextLimit() = %d, want %d", got, tt.want)
+			}
+			if source == "" {
+				t.Errorf("ModelContextLimit() returned no source")
+			}
+		})
+	}
+}
+
+func TestGPUSetup(t *testing.T) {
+	tests := []struct {
+		name         string
+		gpus         GPUSetup
+		wantGPUs     int
+		wantSmallest float64
+	}{
+		{"zero value is one GPU", GPUSetup{}, 1, 1},
+		{"even split", GPUSetup{Count: 2}, 2, 0.5},
+		{"split sets the count", GPUSetup{Split: []float64{24, 12}}, 2, 1.0 / 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.gpus.NumGPUs(); got != tt.wantGPUs {
+				t.Errorf("NumGPUs() = %d, want %d", got, tt.wantGPUs)
+			}
+			if got := tt.gpus.SmallestShare(); got != tt.wantSmallest {
+				t.Errorf("SmallestShare() = %v, want %v", got, tt.wantSmallest)
+			}
+		})
+	}
+
+	if split, err := ParseTensorSplit("24, 24"); err != nil || !reflect.DeepEqual(split, []float64{24, 24}) {
+		t.Errorf("ParseTensorSplit(24, 24) = %v, %v", split, err)
+	}
+	for _, invalid := range []string{"", "24,x", "24,0"} {
+		if _, err := ParseTensorSplit(invalid); err == nil {
+			t.Errorf("ParseTensorSplit(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestCalculateVRAMRawMultiGPU(t *testing.T) {
+	config := ModelConfig{
+		NumParams:         8,
+		NumHiddenLayers:   32,
+		HiddenSize:        4096,
+		NumKeyValueHeads:  8,
+		NumAttentionHeads: 32,
+		IntermediateSize:  14336,
+		VocabSize:         128256,
+	}
+	bpw := GetBPWValues(4.85, KVCacheFP16)
+
+	single := CalculateVRAMRaw(config, bpw, 8192, 1, true)
+	dual := CalculateVRAMRaw(config, bpw, 8192, 2, true)
+	want := bitsToGB(CUDASize + computeBufferSize(config))
+	if got := dual - single; got < want*0.999 || got > want*1.001 {
+		t.Errorf("second GPU adds %.3f GB, want %.3f GB of overhead and compute buffer", got, want)
+	}
+
+	if size := layerSize(config, bpw, 8192); size <= 0 || size >= single/float64(config.NumHiddenLayers)*2 {
+		t.Errorf("layerSize() = %.3f GB, expected a little over 1/%d of %.2f GB", size, config.NumHiddenLayers, single)
+	}
+}
+
+func TestKVCacheQuantisation(t *testing.T) {
+	for input, want := range map[string]KVCacheQuantisation{"q8_0": KVCacheQ8_0, " Q4_0 ": KVCacheQ4_0, "fp16": KVCacheFP16, "f16": KVCacheFP16} {
+		if got, err := ParseKVCacheQuantisation(input); err != nil || got != want {
+			t.Errorf("ParseKVCacheQuantisation(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	for _, invalid := range []string{"", "q8", "q5_0"} {
+		if _, err := ParseKVCacheQuantisation(invalid); err == nil {
+			t.Errorf("ParseKVCacheQuantisation(%q) expected an error", invalid)
+		}
+	}
+
+	vram := ContextVRAM{VRAM: 10, VRAMQ8_0: 8, VRAMQ4_0: 7}
+	for kv, want := range map[KVCacheQuantisation]float64{"": 10, KVCacheFP16: 10, KVCacheQ8_0: 8, KVCacheQ4_0: 7} {
+		if got := vram.ForKVCache(kv); got != want {
+			t.Errorf("ForKVCache(%q) = %v, want %v", kv, got, want)
+		}
+	}
+}
+
+func TestCalculateVRAMWithProjector(t *testing.T) {
+	info := &OllamaModelInfo{
+		Details: OllamaModelDetails{QuantizationLevel: "Q4_K_M"},
+		ModelInfo: map[string]interface{}{
+			"llama.parameter_count":     float64(8e9),
+			"llama.context_length":      float64(8192),
+			"llama.block_count":         float64(32),
+			"llama.embedding_length":    float64(4096),
+			"llama.feed_forward_length": float64(14336),
+		},
+	}
+	without, err := CalculateVRAM("llava", 0, 4096, KVCacheFP16, info)
+	if err != nil {
+		t.Fatalf("CalculateVRAM() error = %v", err)
+	}
+	info.ProjectorSize = 600 * 1024 * 1024
+	with, err := CalculateVRAM("llava", 0, 4096, KVCacheFP16, info)
+	if err != nil {
+		t.Fatalf("CalculateVRAM() with a projector error = %v", err)
+	}
+	if diff := with - without; diff < 0.57 || diff > 0.60 {
+		t.Errorf("a 600MB projector adds %.2f GB, want about 0.59 GB", diff)
+	}
+}
+
+func TestGenerateQuantTableMaxContext(t *testing.T) {
+	info := &OllamaModelInfo{
+		Details: OllamaModelDetails{QuantizationLevel: "Q4_K_M"},
+		ModelInfo: map[string]interface{}{
+			"llama.parameter_count":         float64(8e9),
+			"llama.context_length":          float64(131072),
+			"llama.block_count":             float64(32),
+			"llama.embedding_length":        float64(4096),
+			"llama.feed_forward_length":     float64(14336),
+			"llama.attention.head_count":    float64(32),
+			"llama.attention.head_count_kv": float64(8),
+		},
+	}
+	table, err := GenerateQuantTable("llama3", 8, info, 131072, GPUSetup{})
+	if err != nil {
+		t.Fatalf("GenerateQuantTable() error = %v", err)
+	}
+
+	fits, tooBig := 0, 0
+	for _, result := range table.Results {
+		if result.MaxContext == 0 {
+			tooBig++
+			if vram, _ := CalculateVRAM("llama3", result.BPW, minContextSize, KVCacheFP16, info); vram < 8 {
+				t.Errorf("%s has no max context but needs %.2f GB at %d", result.QuantType, vram, minContextSize)
+			}
+			continue
+		}
+		fits++
+		if vram, _ := CalculateVRAM("llama3", result.BPW, result.MaxContext, KVCacheFP16, info); vram > 8 {
+			t.Errorf("%s max context %d needs %.2f GB, more than 8 GB", result.QuantType, result.MaxContext, vram)
+		}
+		if result.MaxContext < 131072-100 {
+			if vram, _ := CalculateVRAM("llama3", result.BPW, result.MaxContext+200, KVCacheFP16, info); vram <= 8 {
+				t.Errorf("%s max context %d isn't the largest, %d needs only %.2f GB", result.QuantType, result.MaxContext, result.MaxContext+200, vram)
+			}
+		}
+	}
+	if fits == 0 || tooBig == 0 {
+		t.Errorf("%d quants fit and %d don't in 8 GB, want an 8B model's small quants to fit and F16 not to", fits, tooBig)
+	}
+	for _, result := range table.Results {
+		if result.QuantType == "F16" && (result.WeightsSize < 14 || result.WeightsSize > 15) {
+			t.Errorf("F16 weights = %.2f GB, want about 14.9 GB for 8B parameters", result.WeightsSize)
+		}
+	}
+
+	// Without a memory constraint the detected memory is used, which the largest contexts aren't worked out for
+	table, err = GenerateQuantTable("llama3", 0, info, 131072, GPUSetup{})
+	if err != nil {
+		t.Fatalf("GenerateQuantTable() error = %v", err)
+	}
+	for _, result := range table.Results {
+		if result.MaxContext != 0 {
+			t.Errorf("%s max context = %d without a memory constraint, want 0", result.QuantType, result.MaxContext)
+		}
+	}
+	if table.FitsGiven {
+		t.Error("FitsGiven is set for the detected memory")
+	}
+}